@@ -16,6 +16,9 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -47,6 +50,17 @@ const (
 	// annotation is present and status is empty, BMO will reconstruct BMH Status
 	// from the status annotation.
 	StatusAnnotation = "baremetalhost.metal3.io/status"
+
+	// DiscoveredAnnotation is the annotation of the hosts created by
+	// BMC discovery, whose value is the URL of the BMC. Such a host
+	// stays unmanaged until its BMC credentials are set.
+	DiscoveredAnnotation = "baremetalhost.metal3.io/discovered"
+
+	// CredentialsRotationIntervalAnnotation is the annotation of a
+	// namespace enabling the rotation of the BMC credentials of the
+	// hosts in the namespace that do not have their own rotation
+	// policy. The value is a duration such as "720h".
+	CredentialsRotationIntervalAnnotation = "bmc.metal3.io/credentials-rotation-interval"
 )
 
 // RootDeviceHints holds the hints for specifying the storage location
@@ -129,6 +143,16 @@ const (
 	// OperationalStatusDetached is the status value when the host is
 	// marked unmanaged via the detached annotation
 	OperationalStatusDetached OperationalStatus = "detached"
+
+	// OperationalStatusNeedsOperator is the status value for when the
+	// host has failed more times than its retry policy allows, and the
+	// operator will not retry until asked to.
+	OperationalStatusNeedsOperator OperationalStatus = "needs operator"
+
+	// OperationalStatusServicing is the status value for when changes
+	// to the firmware or RAID configuration are being applied to a
+	// provisioned host.
+	OperationalStatusServicing OperationalStatus = "servicing"
 )
 
 // ErrorType indicates the class of problem that has caused the Host resource
@@ -158,6 +182,16 @@ const (
 	// DetachError is an error condition occurring when the
 	// controller is unable to detatch the host from the provisioner
 	DetachError ErrorType = "detach error"
+	// TimeoutError is an error condition occurring when the host
+	// stays in a provisioning state for longer than allowed.
+	TimeoutError ErrorType = "timeout error"
+	// ServicingError is an error condition occurring when the
+	// controller fails to apply changes to a provisioned host.
+	ServicingError ErrorType = "servicing error"
+	// ImageVerificationError is an error condition occurring when the
+	// checksum of the image to provision cannot be found in its
+	// checksum file, or the signature of that file is invalid.
+	ImageVerificationError ErrorType = "image verification error"
 )
 
 // ProvisioningState defines the states the provisioner will report
@@ -222,15 +256,93 @@ type BMCDetails struct {
 	Address string `json:"address"`
 
 	// The name of the secret containing the BMC credentials (requires
-	// keys "username" and "password").
+	// keys "username" and "password"). When CredentialsProvider is
+	// set, the name of the credentials in the provider instead.
 	CredentialsName string `json:"credentialsName"`
 
+	// CredentialsProvider is the name of a credentials provider
+	// configured in the operator to fetch the BMC credentials from,
+	// instead of a Secret. The provider looks the credentials up by
+	// the namespace of the host and CredentialsName.
+	// +optional
+	CredentialsProvider string `json:"credentialsProvider,omitempty"`
+
 	// DisableCertificateVerification disables verification of server
 	// certificates when using HTTPS to connect to the BMC. This is
 	// required when the server certificate is self-signed, but is
 	// insecure because it allows a man-in-the-middle to intercept the
 	// connection.
 	DisableCertificateVerification bool `json:"disableCertificateVerification,omitempty"`
+
+	// TrustedCertificates references the CA certificates, or the
+	// pinned certificate, the certificate of the BMC is verified
+	// against when using HTTPS, instead of the system trust store.
+	// +optional
+	TrustedCertificates *BMCTrustedCertificates `json:"trustedCertificates,omitempty"`
+
+	// CredentialsRotation enables the periodic rotation of the BMC
+	// credentials by the operator. The credentials of a second BMC
+	// account are validated and written to the credentials secret
+	// before the account named in it is disabled.
+	// +optional
+	CredentialsRotation *CredentialsRotationPolicy `json:"credentialsRotation,omitempty"`
+}
+
+// DefaultBMCCABundleKey is the key holding the CA bundle in the
+// ConfigMap or Secret referenced by BMCTrustedCertificates, when no
+// key is given.
+const DefaultBMCCABundleKey = "ca.crt"
+
+// BMCTrustedCertificates references a ConfigMap or a Secret, in the
+// namespace of the host, holding either a bundle of PEM-encoded CA
+// certificates or the SHA-256 fingerprint of the certificate of the
+// BMC. Exactly one of ConfigMapName and SecretName must be set, and
+// at most one of CABundleKey and FingerprintKey.
+type BMCTrustedCertificates struct {
+	// ConfigMapName is the name of the ConfigMap holding the
+	// certificates.
+	// +optional
+	ConfigMapName string `json:"configMapName,omitempty"`
+
+	// SecretName is the name of the Secret holding the certificates.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// CABundleKey is the key holding a bundle of PEM-encoded CA
+	// certificates. Defaults to ca.crt when FingerprintKey is not set.
+	// +optional
+	CABundleKey string `json:"caBundleKey,omitempty"`
+
+	// FingerprintKey is the key holding the SHA-256 fingerprint of
+	// the certificate of the BMC, which is then trusted as is, without
+	// verifying its issuer or names. Only supported by the redfish
+	// provisioner.
+	// +optional
+	FingerprintKey string `json:"fingerprintKey,omitempty"`
+}
+
+// MinCredentialsRotationInterval is the shortest interval allowed
+// between two rotations of the BMC credentials.
+const MinCredentialsRotationInterval = time.Hour
+
+// DefaultCredentialsRotationPasswordLength is the length of the
+// passwords generated when rotating the BMC credentials.
+const DefaultCredentialsRotationPasswordLength = 16
+
+// CredentialsRotationPolicy defines how often the BMC credentials of a
+// host are rotated.
+type CredentialsRotationPolicy struct {
+	// Interval between two rotations of the BMC password, such as
+	// "720h". The first rotation happens one interval after the
+	// creation of the host.
+	Interval metav1.Duration `json:"interval"`
+
+	// The length of the generated passwords. Some BMCs limit the
+	// length of the passwords to 20 characters.
+	// +kubebuilder:validation:Minimum=8
+	// +kubebuilder:validation:Maximum=64
+	// +optional
+	PasswordLength int `json:"passwordLength,omitempty"`
 }
 
 // HardwareRAIDVolume defines the desired configuration of volume in hardware RAID
@@ -389,6 +501,13 @@ type BareMetalHostSpec struct {
 	// (e.g. meta_data.json) which is passed to the Config Drive.
 	MetaData *corev1.SecretReference `json:"metaData,omitempty"`
 
+	// Network is the network configuration of the host, rendered into
+	// the network_data.json passed to the Config Drive if not overridden
+	// by specifying NetworkData, and to the preprovisioning image if
+	// not overridden by specifying PreprovisioningNetworkDataName.
+	// +optional
+	Network *NetworkConfig `json:"network,omitempty"`
+
 	// Description is a human-entered text used to help identify the host
 	Description string `json:"description,omitempty"`
 
@@ -408,6 +527,51 @@ type BareMetalHostSpec struct {
 	// A custom deploy procedure.
 	// +optional
 	CustomDeploy *CustomDeploy `json:"customDeploy,omitempty"`
+
+	// RetryPolicies override the operator-wide policies for retrying
+	// failed operations on this host.
+	// +optional
+	RetryPolicies []RetryPolicy `json:"retryPolicies,omitempty"`
+
+	// When set to true, changes to the RAID, firmware settings and
+	// firmware components of a provisioned host are applied by
+	// servicing the host, without deprovisioning it. The host is
+	// powered off while it is being serviced.
+	// +optional
+	AllowServicing bool `json:"allowServicing,omitempty"`
+
+	// Provisioner is the name of the provisioning backend managing
+	// the host, such as "ironic" or "redfish". When empty the default
+	// backend of the operator is used. It can not be changed once the
+	// host is created.
+	// +optional
+	Provisioner string `json:"provisioner,omitempty"`
+}
+
+// RetryPolicy controls how failed operations are retried.
+type RetryPolicy struct {
+	// ErrorType is the type of error the policy applies to. A policy
+	// without an ErrorType applies to all errors that do not have a
+	// more specific policy.
+	// +kubebuilder:validation:Enum=provisioned registration error;registration error;inspection error;preparation error;provisioning error;power management error;detach error;timeout error;servicing error;image verification error
+	// +optional
+	ErrorType ErrorType `json:"errorType,omitempty"`
+
+	// MaxAttempts is the number of consecutive failures after which
+	// the operator stops retrying and the host needs an operator. Zero
+	// means the operation is retried forever.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+
+	// BackoffBase is the delay before retrying after the first
+	// failure. The delay doubles with each consecutive failure.
+	// +optional
+	BackoffBase *metav1.Duration `json:"backoffBase,omitempty"`
+
+	// BackoffCap is the maximum delay between two retries.
+	// +optional
+	BackoffCap *metav1.Duration `json:"backoffCap,omitempty"`
 }
 
 // AutomatedCleaningMode is the interface to enable/disable automated cleaning
@@ -438,16 +602,31 @@ const (
 // Image holds the details of an image either to provisioned or that
 // has been provisioned.
 type Image struct {
-	// URL is a location of an image to deploy.
+	// URL is a location of an image to deploy. Images stored in an OCI
+	// registry are referenced as oci://registry/repository[:tag]@digest,
+	// and their digest is used as the checksum.
 	URL string `json:"url"`
 
-	// Checksum is the checksum for the image.
+	// Checksum is the checksum for the image. It must not be set for
+	// images in an OCI registry.
 	Checksum string `json:"checksum,omitempty"`
 
+	// ChecksumURL is the http(s) location of a checksum file, such as
+	// SHA256SUMS, listing the checksum of the image under the name of
+	// the file at URL. It replaces Checksum, and the checksum found in
+	// the file is recorded in the status once provisioned.
+	// +optional
+	ChecksumURL string `json:"checksumURL,omitempty"`
+
 	// ChecksumType is the checksum algorithm for the image.
 	// e.g md5, sha256, sha512
 	ChecksumType ChecksumType `json:"checksumType,omitempty"`
 
+	// Signature is the detached signature of the checksum file, which
+	// is verified before the image is provisioned.
+	// +optional
+	Signature *ImageSignature `json:"signature,omitempty"`
+
 	// DiskFormat contains the format of the image (raw, qcow2, ...).
 	// Needs to be set to raw for raw images streaming.
 	// Note live-iso means an iso referenced by the url will be live-booted
@@ -455,12 +634,132 @@ type Image struct {
 	// are not required and if specified will be ignored.
 	// +kubebuilder:validation:Enum=raw;qcow2;vdi;vmdk;live-iso
 	DiskFormat *string `json:"format,omitempty"`
+
+	// ImagePullSecret is a reference to a secret of type
+	// kubernetes.io/dockerconfigjson holding the credentials for
+	// pulling an image from an OCI registry. It must be in the
+	// namespace of the host.
+	// +optional
+	ImagePullSecret *corev1.SecretReference `json:"imagePullSecret,omitempty"`
+}
+
+// ImageSignatureType is the format of the signature of a checksum
+// file.
+// +kubebuilder:validation:Enum=gpg;cosign
+type ImageSignatureType string
+
+const (
+	// GPGSignature is a detached OpenPGP signature, either binary or
+	// ASCII armored, such as created by gpg --detach-sign.
+	GPGSignature ImageSignatureType = "gpg"
+
+	// CosignSignature is a base64-encoded signature created by
+	// cosign sign-blob with a key pair.
+	CosignSignature ImageSignatureType = "cosign"
+)
+
+// ImageSignaturePublicKey is the key of the public key in the data of
+// the secret referenced by an ImageSignature.
+const ImageSignaturePublicKey = "publicKey"
+
+// ImageSignature holds the location of the signature of the checksum
+// file of an image, and the key that signed it.
+type ImageSignature struct {
+	// URL is the http(s) location of the detached signature of the
+	// file at the ChecksumURL of the image.
+	URL string `json:"url"`
+
+	// Type is the format of the signature.
+	Type ImageSignatureType `json:"type"`
+
+	// PublicKey is a reference to a secret holding the public key
+	// verifying the signature under the publicKey key, as an ASCII
+	// armored OpenPGP key for gpg signatures or a PEM-encoded key for
+	// cosign signatures. It must be in the namespace of the host.
+	PublicKey corev1.SecretReference `json:"publicKey"`
 }
 
 func (image *Image) IsLiveISO() bool {
 	return image != nil && image.DiskFormat != nil && *image.DiskFormat == "live-iso"
 }
 
+// OCIScheme is the scheme of the URL of images stored in an OCI
+// registry.
+const OCIScheme = "oci://"
+
+// IsOCI returns true if the image is stored in an OCI registry.
+func (image *Image) IsOCI() bool {
+	return image != nil && strings.HasPrefix(image.URL, OCIScheme)
+}
+
+// OCIReference is a reference to an image stored in an OCI registry.
+type OCIReference struct {
+	// Registry is the host name, and optionally the port, of the
+	// registry.
+	Registry string
+	// Repository is the path of the image in the registry.
+	Repository string
+	// Tag is the optional tag of the image.
+	Tag string
+	// Digest is the digest of the manifest of the image, such as
+	// sha256:<hex>.
+	Digest string
+}
+
+// ociDigestLengths holds the length of the hex-encoded digests of the
+// supported algorithms.
+var ociDigestLengths = map[ChecksumType]int{
+	SHA256: 64,
+	SHA512: 128,
+}
+
+var (
+	ociRepositoryRegexp = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	ociTagRegexp        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	ociHexRegexp        = regexp.MustCompile(`^[a-f0-9]+$`)
+)
+
+// ParseOCIReference parses an oci://registry/repository[:tag]@digest
+// image URL. The digest is required, so that the image cannot change
+// once provisioned.
+func ParseOCIReference(imageURL string) (*OCIReference, error) {
+	ref := strings.TrimPrefix(imageURL, OCIScheme)
+	if ref == imageURL {
+		return nil, fmt.Errorf("%s is not an OCI image reference", imageURL)
+	}
+
+	registry, path, found := strings.Cut(ref, "/")
+	if !found || registry == "" {
+		return nil, fmt.Errorf("OCI image reference %s has no registry", imageURL)
+	}
+	result := &OCIReference{Registry: registry}
+
+	path, result.Digest, found = strings.Cut(path, "@")
+	if !found {
+		return nil, fmt.Errorf("OCI image reference %s has no digest", imageURL)
+	}
+	algorithm, hex, _ := strings.Cut(result.Digest, ":")
+	length, supported := ociDigestLengths[ChecksumType(algorithm)]
+	if !supported {
+		return nil, fmt.Errorf("OCI image reference %s has an unsupported digest algorithm %q", imageURL, algorithm)
+	}
+	if len(hex) != length || !ociHexRegexp.MatchString(hex) {
+		return nil, fmt.Errorf("OCI image reference %s has an invalid digest", imageURL)
+	}
+
+	if i := strings.LastIndex(path, ":"); i >= 0 {
+		path, result.Tag = path[:i], path[i+1:]
+		if !ociTagRegexp.MatchString(result.Tag) {
+			return nil, fmt.Errorf("OCI image reference %s has an invalid tag", imageURL)
+		}
+	}
+	if !ociRepositoryRegexp.MatchString(path) {
+		return nil, fmt.Errorf("OCI image reference %s has an invalid repository", imageURL)
+	}
+	result.Repository = path
+	return result, nil
+}
+
 // Custom deploy is a description of a customized deploy process.
 type CustomDeploy struct {
 	// Custom deploy method name.
@@ -469,6 +768,183 @@ type CustomDeploy struct {
 	Method string `json:"method"`
 }
 
+// NetworkConfig describes the interfaces of a host and their IP
+// configuration.
+type NetworkConfig struct {
+	// Links are the physical interfaces of the host.
+	// +optional
+	Links []NetworkLink `json:"links,omitempty"`
+
+	// Bonds aggregate links into a single interface.
+	// +optional
+	Bonds []NetworkBond `json:"bonds,omitempty"`
+
+	// VLANs are tagged interfaces on top of links or bonds.
+	// +optional
+	VLANs []NetworkVLAN `json:"vlans,omitempty"`
+
+	// Addresses configure the IP addresses of the links, bonds and
+	// VLANs.
+	// +optional
+	Addresses []NetworkAddress `json:"addresses,omitempty"`
+
+	// Routes are static routes, through gateways in the subnet of a
+	// static address.
+	// +optional
+	Routes []NetworkRoute `json:"routes,omitempty"`
+
+	// DNS configures name resolution.
+	// +optional
+	DNS *NetworkDNS `json:"dns,omitempty"`
+}
+
+// NetworkLink is a physical interface of the host, given either by the
+// name of a NIC found by inspection or by its MAC address.
+type NetworkLink struct {
+	// Name identifies the interface in the bonds, VLANs and addresses.
+	Name string `json:"name"`
+
+	// NIC is the name of the NIC in the hardware details of the host.
+	// +optional
+	NIC string `json:"nic,omitempty"`
+
+	// MACAddress is the MAC address of the NIC.
+	// +kubebuilder:validation:Pattern=`[0-9a-fA-F]{2}(:[0-9a-fA-F]{2}){5}`
+	// +optional
+	MACAddress string `json:"macAddress,omitempty"`
+
+	// MTU of the interface.
+	// +kubebuilder:validation:Minimum=68
+	// +optional
+	MTU int `json:"mtu,omitempty"`
+}
+
+// BondMode is the aggregation mode of a bond.
+// +kubebuilder:validation:Enum=balance-rr;active-backup;balance-xor;broadcast;"802.3ad";balance-tlb;balance-alb
+type BondMode string
+
+// NetworkBond aggregates links into a single interface.
+type NetworkBond struct {
+	// Name identifies the interface in the VLANs and addresses.
+	Name string `json:"name"`
+
+	// Links are the names of the links aggregated by the bond.
+	// +kubebuilder:validation:MinItems=1
+	Links []string `json:"links"`
+
+	// Mode is the aggregation mode of the bond.
+	Mode BondMode `json:"mode"`
+
+	// MIIMon is the interval in milliseconds at which the links are
+	// monitored.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MIIMon int `json:"miimon,omitempty"`
+
+	// XmitHashPolicy selects the link of each packet in the
+	// balance-xor, 802.3ad and balance-tlb modes.
+	// +kubebuilder:validation:Enum=layer2;layer2+3;layer3+4;encap2+3;encap3+4
+	// +optional
+	XmitHashPolicy string `json:"xmitHashPolicy,omitempty"`
+
+	// MACAddress of the bond. Defaults to the MAC address of its first
+	// link.
+	// +kubebuilder:validation:Pattern=`[0-9a-fA-F]{2}(:[0-9a-fA-F]{2}){5}`
+	// +optional
+	MACAddress string `json:"macAddress,omitempty"`
+
+	// MTU of the interface.
+	// +kubebuilder:validation:Minimum=68
+	// +optional
+	MTU int `json:"mtu,omitempty"`
+}
+
+// NetworkVLAN is a tagged interface on top of a link or a bond.
+type NetworkVLAN struct {
+	// Name identifies the interface in the addresses.
+	Name string `json:"name"`
+
+	// Link is the name of the link or bond carrying the VLAN.
+	Link string `json:"link"`
+
+	// ID is the 802.1Q VLAN identifier.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=4094
+	ID int `json:"id"`
+
+	// MACAddress of the VLAN interface. Defaults to the MAC address of
+	// its link.
+	// +kubebuilder:validation:Pattern=`[0-9a-fA-F]{2}(:[0-9a-fA-F]{2}){5}`
+	// +optional
+	MACAddress string `json:"macAddress,omitempty"`
+
+	// MTU of the interface.
+	// +kubebuilder:validation:Minimum=68
+	// +optional
+	MTU int `json:"mtu,omitempty"`
+}
+
+// NetworkAddressType is how an interface gets an IP address.
+// +kubebuilder:validation:Enum=static;dhcp4;dhcp6;slaac
+type NetworkAddressType string
+
+const (
+	// StaticAddress assigns the IPv4 or IPv6 Address.
+	StaticAddress NetworkAddressType = "static"
+
+	// DHCP4Address gets an IPv4 address over DHCP.
+	DHCP4Address NetworkAddressType = "dhcp4"
+
+	// DHCP6Address gets an IPv6 address over DHCPv6.
+	DHCP6Address NetworkAddressType = "dhcp6"
+
+	// SLAACAddress gets an IPv6 address by stateless address
+	// autoconfiguration.
+	SLAACAddress NetworkAddressType = "slaac"
+)
+
+// NetworkAddress configures an IP address of an interface.
+type NetworkAddress struct {
+	// Interface is the name of the link, bond or VLAN.
+	Interface string `json:"interface"`
+
+	// Type is how the interface gets the address.
+	// +kubebuilder:default:=static
+	// +optional
+	Type NetworkAddressType `json:"type,omitempty"`
+
+	// Address is the static address in CIDR notation, such as
+	// 192.168.111.20/24.
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// IPPool is the name of an IPPool, in the namespace of the host,
+	// the static address is allocated from instead of setting Address.
+	// +optional
+	IPPool string `json:"ipPool,omitempty"`
+}
+
+// NetworkRoute is a static route.
+type NetworkRoute struct {
+	// Destination is the destination network in CIDR notation, such as
+	// 0.0.0.0/0 for the default route.
+	Destination string `json:"destination"`
+
+	// Gateway is the address of the next hop.
+	Gateway string `json:"gateway"`
+}
+
+// NetworkDNS configures name resolution.
+type NetworkDNS struct {
+	// Nameservers are the addresses of the DNS servers.
+	// +optional
+	Nameservers []string `json:"nameservers,omitempty"`
+
+	// Search are the search domains.
+	// +optional
+	Search []string `json:"search,omitempty"`
+}
+
 // FIXME(dhellmann): We probably want some other module to own these
 // data structures.
 
@@ -646,6 +1122,94 @@ type CredentialsStatus struct {
 	Version   string                  `json:"credentialsVersion,omitempty"`
 }
 
+// CredentialsRotationStatus holds the state of the rotation of the
+// BMC credentials.
+type CredentialsRotationStatus struct {
+	// The time the BMC credentials were last rotated.
+	// +optional
+	LastRotated *metav1.Time `json:"lastRotated,omitempty"`
+
+	// The time of the last failed rotation. Rotation is retried an
+	// hour after a failure.
+	// +optional
+	LastFailure *metav1.Time `json:"lastFailure,omitempty"`
+
+	// Details of the last failed rotation.
+	// +optional
+	FailureMessage string `json:"failureMessage,omitempty"`
+}
+
+// BMCCapability is a feature of the host that the driver of its BMC can
+// manage.
+// +kubebuilder:validation:Enum=VirtualMedia;SecureBoot;HardwareRAID;BIOSSettings;FirmwareUpdate;EventSubscriptions;SoftPowerOff
+type BMCCapability string
+
+// HealthState is the health of the hardware of a host as reported by
+// its BMC.
+// +kubebuilder:validation:Enum=OK;Warning;Critical
+type HealthState string
+
+const (
+	// HealthOK is the health of hardware working normally
+	HealthOK HealthState = "OK"
+	// HealthWarning is the health of hardware needing attention, for
+	// example a degraded disk of a RAID volume
+	HealthWarning HealthState = "Warning"
+	// HealthCritical is the health of hardware needing immediate
+	// attention, for example a failed power supply
+	HealthCritical HealthState = "Critical"
+)
+
+// severity orders the health states from the best to the worst
+func (h HealthState) severity() int {
+	switch h {
+	case HealthOK:
+		return 1
+	case HealthWarning:
+		return 2
+	case HealthCritical:
+		return 3
+	}
+	return 0
+}
+
+// ComponentHealth is the health of a component of the host.
+type ComponentHealth struct {
+	// Name of the component, for example "Processors", "Memory" or
+	// "Chassis".
+	Name string `json:"name"`
+
+	// Health of the component, including the components it contains.
+	Health HealthState `json:"health"`
+}
+
+// HardwareHealth holds the health of the hardware of the host, as last
+// reported by its BMC.
+type HardwareHealth struct {
+	// Health is the worst health of the components.
+	// +optional
+	Health HealthState `json:"health,omitempty"`
+
+	// Components holds the health of each component.
+	// +optional
+	Components []ComponentHealth `json:"components,omitempty"`
+
+	// LastUpdated is when the health of a component last changed.
+	// +optional
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+// WorstHealth returns the worst health of the components, or an empty
+// string when there are none.
+func WorstHealth(components []ComponentHealth) (health HealthState) {
+	for _, component := range components {
+		if component.Health.severity() > health.severity() {
+			health = component.Health
+		}
+	}
+	return
+}
+
 // RebootMode defines known variations of reboot modes
 type RebootMode string
 
@@ -713,22 +1277,61 @@ func (om OperationMetric) Duration() time.Duration {
 type OperationHistory struct {
 	Register    OperationMetric `json:"register,omitempty"`
 	Inspect     OperationMetric `json:"inspect,omitempty"`
+	Prepare     OperationMetric `json:"prepare,omitempty"`
 	Provision   OperationMetric `json:"provision,omitempty"`
 	Deprovision OperationMetric `json:"deprovision,omitempty"`
 }
 
+// HostConditionType is the type of a condition in the
+// BareMetalHostStatus.
+type HostConditionType string
+
+const (
+	// HostRegistered indicates that the host has been registered with
+	// the provisioner and its BMC credentials have been validated.
+	HostRegistered HostConditionType = "Registered"
+
+	// HostInspected indicates that hardware details are available for
+	// the host.
+	HostInspected HostConditionType = "Inspected"
+
+	// HostPrepared indicates that the host has been prepared (cleaned
+	// and configured) and can be provisioned.
+	HostPrepared HostConditionType = "Prepared"
+
+	// HostProvisioned indicates that an image has been written to the
+	// host, or that the host was provisioned externally.
+	HostProvisioned HostConditionType = "Provisioned"
+
+	// HostPowerStateSynced indicates that the power state of the host
+	// matches the Online field of the Spec.
+	HostPowerStateSynced HostConditionType = "PowerStateSynced"
+
+	// HostReady indicates that the host is in a steady state with no
+	// errors and with its power state matching the Spec.
+	HostReady HostConditionType = "Ready"
+
+	// HostNeedsOperator indicates that the host has failed more times
+	// than its retry policy allows and is no longer being retried.
+	HostNeedsOperator HostConditionType = "NeedsOperator"
+
+	// HostHealthy indicates that the BMC of the host reports all of
+	// its hardware components as working normally.
+	HostHealthy HostConditionType = "Healthy"
+)
+
 // BareMetalHostStatus defines the observed state of BareMetalHost
 type BareMetalHostStatus struct {
 	// Important: Run "make generate manifests" to regenerate code
 	// after modifying this file
 
 	// OperationalStatus holds the status of the host
-	// +kubebuilder:validation:Enum="";OK;discovered;error;delayed;detached
+	// +kubebuilder:validation:Enum="";OK;discovered;error;delayed;detached;needs operator;servicing
 	OperationalStatus OperationalStatus `json:"operationalStatus"`
 
 	// ErrorType indicates the type of failure encountered when the
 	// OperationalStatus is OperationalStatusError
-	// +kubebuilder:validation:Enum=provisioned registration error;registration error;inspection error;preparation error;provisioning error;power management error
+	// +kubebuilder:validation:Enum=provisioned registration error;registration error;inspection error;preparation error;provisioning error;power management error;timeout error;servicing error;image verification error
 	ErrorType ErrorType `json:"errorType,omitempty"`
 
 	// LastUpdated identifies when this status was last observed.
@@ -750,6 +1353,18 @@ type BareMetalHostStatus struct {
 	// the last credentials we sent to the provisioning backend
 	TriedCredentials CredentialsStatus `json:"triedCredentials,omitempty"`
 
+	// the state of the rotation of the BMC credentials, when enabled
+	// +optional
+	CredentialsRotation *CredentialsRotationStatus `json:"credentialsRotation,omitempty"`
+
+	// the features of the host the driver of its BMC can manage
+	// +optional
+	BMCCapabilities []BMCCapability `json:"bmcCapabilities,omitempty"`
+
+	// the health of the hardware of the host, as reported by its BMC
+	// +optional
+	HardwareHealth *HardwareHealth `json:"hardwareHealth,omitempty"`
+
 	// the last error message reported by the provisioning subsystem
 	ErrorMessage string `json:"errorMessage"`
 
@@ -763,6 +1378,40 @@ type BareMetalHostStatus struct {
 	// ErrorCount records how many times the host has encoutered an error since the last successful operation
 	// +kubebuilder:default:=0
 	ErrorCount int `json:"errorCount"`
+
+	// Progress of the inspection, provisioning or deprovisioning
+	// running on the host, as reported by the provisioner.
+	// +optional
+	Progress *OperationProgress `json:"progress,omitempty"`
+
+	// Conditions describe the progress of the host through its
+	// lifecycle and whether it is ready for use
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+}
+
+// OperationProgress describes how far the operation running on the
+// host got.
+type OperationProgress struct {
+	// Step is the state of the host in the provisioning backend, for
+	// example "wait call-back" while waiting for the agent running
+	// on the host to boot.
+	// +optional
+	Step string `json:"step,omitempty"`
+
+	// CurrentStep is the name of the deploy or clean step being run,
+	// for example "deploy.write_image".
+	// +optional
+	CurrentStep string `json:"currentStep,omitempty"`
+
+	// LastHeartbeat is the time the agent running on the host last
+	// reported to the provisioning backend.
+	// +optional
+	LastHeartbeat *metav1.Time `json:"lastHeartbeat,omitempty"`
 }
 
 // ProvisionStatus holds the state information for a single target.
@@ -1031,6 +1680,8 @@ func (host *BareMetalHost) OperationMetricForState(operation ProvisioningState)
 		metric = &history.Register
 	case StateInspecting:
 		metric = &history.Inspect
+	case StatePreparing:
+		metric = &history.Prepare
 	case StateProvisioning:
 		metric = &history.Provision
 	case StateDeprovisioning:
@@ -1051,8 +1702,20 @@ func (image *Image) GetChecksum() (checksum, checksumType string, ok bool) {
 		return
 	}
 
+	if image.IsOCI() {
+		// The digest of the manifest is the checksum of OCI images
+		ref, err := ParseOCIReference(image.URL)
+		if err != nil {
+			return
+		}
+		checksumType, checksum, _ = strings.Cut(ref.Digest, ":")
+		ok = true
+		return
+	}
+
 	if image.Checksum == "" {
-		// Return empty if checksum is not provided
+		// Return empty if checksum is not provided, including when
+		// it still has to be read from the ChecksumURL
 		return
 	}
 
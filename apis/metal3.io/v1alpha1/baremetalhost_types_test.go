@@ -441,6 +441,36 @@ func TestGetImageChecksum(t *testing.T) {
 			},
 			Expected: false,
 		},
+		{
+			Scenario: "OCI image",
+			Host: BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "myhost",
+					Namespace: "myns",
+				},
+				Spec: BareMetalHostSpec{
+					Image: &Image{
+						URL: "oci://quay.io/metal3/ubuntu@" + testOCIDigest,
+					},
+				},
+			},
+			Expected: true,
+		},
+		{
+			Scenario: "OCI image without digest",
+			Host: BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "myhost",
+					Namespace: "myns",
+				},
+				Spec: BareMetalHostSpec{
+					Image: &Image{
+						URL: "oci://quay.io/metal3/ubuntu:22.04",
+					},
+				},
+			},
+			Expected: false,
+		},
 	} {
 		t.Run(tc.Scenario, func(t *testing.T) {
 			_, _, actual := tc.Host.Spec.Image.GetChecksum()
@@ -451,6 +481,83 @@ func TestGetImageChecksum(t *testing.T) {
 	}
 }
 
+const testOCIDigest = "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+func TestGetOCIImageChecksum(t *testing.T) {
+	image := &Image{URL: "oci://quay.io/metal3/ubuntu:22.04@" + testOCIDigest}
+
+	checksum, checksumType, ok := image.GetChecksum()
+
+	assert.True(t, ok)
+	assert.Equal(t, "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae", checksum)
+	assert.Equal(t, "sha256", checksumType)
+}
+
+func TestParseOCIReference(t *testing.T) {
+	for _, tc := range []struct {
+		Scenario      string
+		URL           string
+		Expected      *OCIReference
+		ExpectedError string
+	}{
+		{
+			Scenario: "digest",
+			URL:      "oci://quay.io/metal3/ubuntu@" + testOCIDigest,
+			Expected: &OCIReference{Registry: "quay.io", Repository: "metal3/ubuntu", Digest: testOCIDigest},
+		},
+		{
+			Scenario: "tag and digest",
+			URL:      "oci://quay.io/metal3/ubuntu:22.04@" + testOCIDigest,
+			Expected: &OCIReference{Registry: "quay.io", Repository: "metal3/ubuntu", Tag: "22.04", Digest: testOCIDigest},
+		},
+		{
+			Scenario: "registry port",
+			URL:      "oci://registry.example.com:5000/os/rhel-9.4:latest@" + testOCIDigest,
+			Expected: &OCIReference{Registry: "registry.example.com:5000", Repository: "os/rhel-9.4", Tag: "latest", Digest: testOCIDigest},
+		},
+		{
+			Scenario:      "not OCI",
+			URL:           "http://example.com/ubuntu.qcow2",
+			ExpectedError: "http://example.com/ubuntu.qcow2 is not an OCI image reference",
+		},
+		{
+			Scenario:      "no registry",
+			URL:           "oci://ubuntu@" + testOCIDigest,
+			ExpectedError: "OCI image reference oci://ubuntu@" + testOCIDigest + " has no registry",
+		},
+		{
+			Scenario:      "no digest",
+			URL:           "oci://quay.io/metal3/ubuntu:22.04",
+			ExpectedError: "OCI image reference oci://quay.io/metal3/ubuntu:22.04 has no digest",
+		},
+		{
+			Scenario:      "unsupported algorithm",
+			URL:           "oci://quay.io/metal3/ubuntu@md5:d41d8cd98f00b204e9800998ecf8427e",
+			ExpectedError: "OCI image reference oci://quay.io/metal3/ubuntu@md5:d41d8cd98f00b204e9800998ecf8427e has an unsupported digest algorithm \"md5\"",
+		},
+		{
+			Scenario:      "short digest",
+			URL:           "oci://quay.io/metal3/ubuntu@sha256:abcd",
+			ExpectedError: "OCI image reference oci://quay.io/metal3/ubuntu@sha256:abcd has an invalid digest",
+		},
+		{
+			Scenario:      "invalid repository",
+			URL:           "oci://quay.io/Metal3/ubuntu@" + testOCIDigest,
+			ExpectedError: "OCI image reference oci://quay.io/Metal3/ubuntu@" + testOCIDigest + " has an invalid repository",
+		},
+	} {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			ref, err := ParseOCIReference(tc.URL)
+			if tc.ExpectedError != "" {
+				assert.EqualError(t, err, tc.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.Expected, ref)
+		})
+	}
+}
+
 func TestBootMode(t *testing.T) {
 	for _, tc := range []struct {
 		Scenario  string
@@ -488,3 +595,45 @@ func TestBootMode(t *testing.T) {
 		})
 	}
 }
+
+func TestWorstHealth(t *testing.T) {
+	for _, tc := range []struct {
+		Scenario   string
+		Components []ComponentHealth
+		Expected   HealthState
+	}{
+		{
+			Scenario: "no components",
+			Expected: "",
+		},
+		{
+			Scenario: "healthy",
+			Components: []ComponentHealth{
+				{Name: "System", Health: HealthOK},
+				{Name: "Memory", Health: HealthOK},
+			},
+			Expected: HealthOK,
+		},
+		{
+			Scenario: "warning",
+			Components: []ComponentHealth{
+				{Name: "System", Health: HealthOK},
+				{Name: "Memory", Health: HealthWarning},
+			},
+			Expected: HealthWarning,
+		},
+		{
+			Scenario: "critical",
+			Components: []ComponentHealth{
+				{Name: "Chassis", Health: HealthCritical},
+				{Name: "Memory", Health: HealthWarning},
+				{Name: "System", Health: HealthOK},
+			},
+			Expected: HealthCritical,
+		},
+	} {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, WorstHealth(tc.Components))
+		})
+	}
+}
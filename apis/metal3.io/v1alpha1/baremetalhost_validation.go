@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/url"
@@ -9,6 +10,10 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
@@ -17,8 +22,12 @@ import (
 // log is for logging in this package.
 var log = logf.Log.WithName("baremetalhost-validation")
 
+// ObjectGetter reads the object of the given namespace and name into
+// obj, returning a NotFound error when it does not exist.
+type ObjectGetter func(ctx context.Context, namespace, name string, obj runtime.Object) error
+
 // validateHost validates BareMetalHost resource for creation
-func (host *BareMetalHost) validateHost() []error {
+func (v *BareMetalHostValidator) validateHost(ctx context.Context, host *BareMetalHost) []error {
 	log.Info("validate create", "name", host.Name)
 	var errs []error
 	var bmcAccess bmc.AccessDetails
@@ -35,7 +44,7 @@ func (host *BareMetalHost) validateHost() []error {
 		errs = append(errs, raid_errors...)
 	}
 
-	errs = append(errs, validateBMCAccess(host.Spec, bmcAccess)...)
+	errs = append(errs, v.validateBMCAccess(ctx, host, bmcAccess)...)
 
 	if err := validateBMHName(host.Name); err != nil {
 		errs = append(errs, err)
@@ -53,18 +62,40 @@ func (host *BareMetalHost) validateHost() []error {
 		if err := validateImageURL(host.Spec.Image.URL); err != nil {
 			errs = append(errs, err)
 		}
+		errs = append(errs, validateOCIImage(host.Namespace, host.Spec.Image)...)
+		errs = append(errs, validateImageChecksum(host.Namespace, host.Spec.Image)...)
+	}
+
+	errs = append(errs, validateRetryPolicies(host.Spec.RetryPolicies)...)
+
+	if host.Spec.Network != nil {
+		errs = append(errs, validateNetworkConfig(host.Spec.Network)...)
+	}
+
+	if err := v.validateProvisioner(host.Spec.Provisioner); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := validateCredentialsName(host.Spec.BMC.CredentialsName); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := validateCredentialsRotation(host.Spec.BMC.CredentialsRotation); err != nil {
+		errs = append(errs, err)
 	}
 
+	errs = append(errs, v.validateTrustedCertificates(ctx, host.Namespace, host.Spec.BMC, bmcAccess)...)
+
 	return errs
 }
 
 // validateChanges validates BareMetalHost resource on changes
 // but also covers the validations of creation
-func (host *BareMetalHost) validateChanges(old *BareMetalHost) []error {
+func (v *BareMetalHostValidator) validateChanges(ctx context.Context, host, old *BareMetalHost) []error {
 	log.Info("validate update", "name", host.Name)
 	var errs []error
 
-	if err := host.validateHost(); err != nil {
+	if err := v.validateHost(ctx, host); err != nil {
 		errs = append(errs, err...)
 	}
 
@@ -76,24 +107,45 @@ func (host *BareMetalHost) validateChanges(old *BareMetalHost) []error {
 		errs = append(errs, fmt.Errorf("bootMACAddress can not be changed once it is set"))
 	}
 
+	if err := validateConsumerRefChange(old.Spec.ConsumerRef, host.Spec.ConsumerRef); err != nil {
+		errs = append(errs, err)
+	}
+
+	if host.Spec.Provisioner != old.Spec.Provisioner {
+		errs = append(errs, fmt.Errorf("provisioner can not be changed"))
+	}
+
 	return errs
 }
 
-func validateBMCAccess(s BareMetalHostSpec, bmcAccess bmc.AccessDetails) []error {
+// validateBMCAccess checks that the driver of the BMC can manage every
+// feature of the host the spec requires.
+func (v *BareMetalHostValidator) validateBMCAccess(ctx context.Context, host *BareMetalHost, bmcAccess bmc.AccessDetails) []error {
 	var errs []error
 
 	if bmcAccess == nil {
 		return errs
 	}
 
+	s := host.Spec
+	capabilities := bmcAccess.Capabilities()
+
 	if s.RAID != nil && len(s.RAID.HardwareRAIDVolumes) > 0 {
-		if bmcAccess.RAIDInterface() == "no-raid" {
+		if !capabilities.HardwareRAID {
 			errs = append(errs, fmt.Errorf("BMC driver %s does not support configuring RAID", bmcAccess.Type()))
 		}
 	}
 
 	if s.Firmware != nil {
-		if _, err := bmcAccess.BuildBIOSSettings((*bmc.FirmwareConfig)(s.Firmware)); err != nil {
+		if !capabilities.BIOSSettings {
+			errs = append(errs, fmt.Errorf("BMC driver %s does not support BIOS settings", bmcAccess.Type()))
+		} else if _, err := bmcAccess.BuildBIOSSettings((*bmc.FirmwareConfig)(s.Firmware)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if !capabilities.FirmwareUpdate {
+		if err := v.validateFirmwareUpdates(ctx, host, bmcAccess); err != nil {
 			errs = append(errs, err)
 		}
 	}
@@ -109,13 +161,33 @@ func validateBMCAccess(s BareMetalHostSpec, bmcAccess bmc.AccessDetails) []error
 		}
 	}
 
-	if s.BootMode == UEFISecureBoot && !bmcAccess.SupportsSecureBoot() {
+	if s.BootMode == UEFISecureBoot && !capabilities.SecureBoot {
 		errs = append(errs, fmt.Errorf("BMC driver %s does not support secure boot", bmcAccess.Type()))
 	}
 
 	return errs
 }
 
+// validateFirmwareUpdates rejects a host whose HostFirmwareComponents
+// request firmware updates, for a BMC driver that can not apply them.
+func (v *BareMetalHostValidator) validateFirmwareUpdates(ctx context.Context, host *BareMetalHost, bmcAccess bmc.AccessDetails) error {
+	if v.HostFirmwareComponentsGetter == nil {
+		return nil
+	}
+
+	hfc := &HostFirmwareComponents{}
+	if err := v.HostFirmwareComponentsGetter(ctx, host.Namespace, host.Name, hfc); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to read the host firmware components %s", host.Name)
+	}
+	if len(hfc.Spec.Updates) > 0 {
+		return fmt.Errorf("BMC driver %s does not support the firmware updates of the host firmware components", bmcAccess.Type())
+	}
+	return nil
+}
+
 func validateRAID(r *RAIDConfig) []error {
 	var errors []error
 
@@ -177,6 +249,11 @@ func validateDNSName(hostaddress string) error {
 
 func validateImageURL(imageURL string) error {
 
+	if strings.HasPrefix(imageURL, OCIScheme) {
+		_, err := ParseOCIReference(imageURL)
+		return err
+	}
+
 	_, err := url.ParseRequestURI(imageURL)
 	if err != nil {
 		return fmt.Errorf("Image URL %s is an invalid URL", imageURL)
@@ -185,6 +262,189 @@ func validateImageURL(imageURL string) error {
 	return nil
 }
 
+// validateOCIImage checks the fields that only apply to images stored
+// in an OCI registry, or that do not apply to them.
+func validateOCIImage(namespace string, image *Image) (errs []error) {
+	if !image.IsOCI() {
+		if image.ImagePullSecret != nil {
+			errs = append(errs, fmt.Errorf("imagePullSecret is only supported for OCI images"))
+		}
+		return
+	}
+
+	if image.Checksum != "" || image.ChecksumURL != "" || image.ChecksumType != "" || image.Signature != nil {
+		errs = append(errs, fmt.Errorf("the checksum of an OCI image is its digest and cannot be set"))
+	}
+	if image.IsLiveISO() {
+		errs = append(errs, fmt.Errorf("live-iso images cannot be stored in an OCI registry"))
+	}
+	if image.ImagePullSecret != nil && image.ImagePullSecret.Name == "" {
+		errs = append(errs, fmt.Errorf("imagePullSecret must have a name"))
+	}
+	if image.ImagePullSecret != nil && !isLocalSecret(namespace, *image.ImagePullSecret) {
+		errs = append(errs, fmt.Errorf("imagePullSecret must be in the namespace of the host"))
+	}
+	return
+}
+
+// validateImageChecksum checks the checksum URL and the signature of an
+// image.
+func validateImageChecksum(namespace string, image *Image) (errs []error) {
+	if image.ChecksumURL != "" {
+		if image.Checksum != "" {
+			errs = append(errs, fmt.Errorf("checksum and checksumURL cannot both be set"))
+		}
+		if !isHTTPURL(image.ChecksumURL) {
+			errs = append(errs, fmt.Errorf("checksumURL %s is not an http or https URL", image.ChecksumURL))
+		}
+	}
+
+	signature := image.Signature
+	if signature == nil || image.IsOCI() {
+		return
+	}
+	if image.ChecksumURL == "" {
+		errs = append(errs, fmt.Errorf("a signature requires the checksumURL of the signed checksum file"))
+	}
+	if !isHTTPURL(signature.URL) {
+		errs = append(errs, fmt.Errorf("signature URL %s is not an http or https URL", signature.URL))
+	}
+	switch signature.Type {
+	case GPGSignature, CosignSignature:
+	default:
+		errs = append(errs, fmt.Errorf("unknown signature type %q", signature.Type))
+	}
+	if signature.PublicKey.Name == "" {
+		errs = append(errs, fmt.Errorf("the public key of a signature must have a name"))
+	}
+	if !isLocalSecret(namespace, signature.PublicKey) {
+		errs = append(errs, fmt.Errorf("the public key of a signature must be in the namespace of the host"))
+	}
+	return
+}
+
+// isLocalSecret returns whether a secret reference does not reach
+// outside of the namespace of the host.
+func isLocalSecret(namespace string, ref corev1.SecretReference) bool {
+	return ref.Namespace == "" || ref.Namespace == namespace
+}
+
+// isHTTPURL returns true if the value is an absolute http or https URL.
+func isHTTPURL(value string) bool {
+	u, err := url.ParseRequestURI(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func validateNetworkConfig(config *NetworkConfig) (errs []error) {
+	// interfaces maps the name of each interface to its kind
+	interfaces := map[string]string{}
+	addInterface := func(name, kind string) {
+		if name == "" {
+			errs = append(errs, fmt.Errorf("network %s must have a name", kind))
+			return
+		}
+		if _, exists := interfaces[name]; exists {
+			errs = append(errs, fmt.Errorf("duplicate network interface name %q", name))
+			return
+		}
+		interfaces[name] = kind
+	}
+
+	for _, link := range config.Links {
+		addInterface(link.Name, "link")
+		if link.NIC == "" && link.MACAddress == "" {
+			errs = append(errs, fmt.Errorf("network link %q must have a nic or a macAddress", link.Name))
+		}
+	}
+	for _, bond := range config.Bonds {
+		addInterface(bond.Name, "bond")
+	}
+	for _, vlan := range config.VLANs {
+		addInterface(vlan.Name, "vlan")
+	}
+
+	bonded := map[string]string{}
+	for _, bond := range config.Bonds {
+		if len(bond.Links) == 0 {
+			errs = append(errs, fmt.Errorf("network bond %q must have links", bond.Name))
+		}
+		for _, link := range bond.Links {
+			if interfaces[link] != "link" {
+				errs = append(errs, fmt.Errorf("network bond %q references unknown link %q", bond.Name, link))
+				continue
+			}
+			if other, exists := bonded[link]; exists {
+				errs = append(errs, fmt.Errorf("network link %q is in both bonds %q and %q", link, other, bond.Name))
+			}
+			bonded[link] = bond.Name
+		}
+	}
+	for _, vlan := range config.VLANs {
+		if kind := interfaces[vlan.Link]; kind != "link" && kind != "bond" {
+			errs = append(errs, fmt.Errorf("network vlan %q references unknown link or bond %q", vlan.Name, vlan.Link))
+		}
+	}
+
+	var subnets []*net.IPNet
+	pooled := false
+	for _, address := range config.Addresses {
+		if _, exists := interfaces[address.Interface]; !exists {
+			errs = append(errs, fmt.Errorf("network address references unknown interface %q", address.Interface))
+		}
+		switch address.Type {
+		case StaticAddress, "":
+			if address.IPPool != "" {
+				if address.Address != "" {
+					errs = append(errs, fmt.Errorf("network address of interface %q can not set both an address and an ipPool", address.Interface))
+				}
+				pooled = true
+				continue
+			}
+			_, subnet, err := net.ParseCIDR(address.Address)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("static network address %q of interface %q is not in CIDR notation", address.Address, address.Interface))
+				continue
+			}
+			subnets = append(subnets, subnet)
+		case DHCP4Address, DHCP6Address, SLAACAddress:
+			if address.Address != "" || address.IPPool != "" {
+				errs = append(errs, fmt.Errorf("%s network address of interface %q can not set an address or an ipPool", address.Type, address.Interface))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("unknown network address type %q", address.Type))
+		}
+	}
+
+	for _, route := range config.Routes {
+		if _, _, err := net.ParseCIDR(route.Destination); err != nil {
+			errs = append(errs, fmt.Errorf("network route destination %q is not in CIDR notation", route.Destination))
+		}
+		gateway := net.ParseIP(route.Gateway)
+		if gateway == nil {
+			errs = append(errs, fmt.Errorf("network route gateway %q is not an IP address", route.Gateway))
+			continue
+		}
+		// The subnets of the pools are only known once the addresses
+		// are allocated
+		reachable := pooled
+		for _, subnet := range subnets {
+			reachable = reachable || subnet.Contains(gateway)
+		}
+		if !reachable {
+			errs = append(errs, fmt.Errorf("network route gateway %s is not in the subnet of a static address", route.Gateway))
+		}
+	}
+
+	if config.DNS != nil {
+		for _, nameserver := range config.DNS.Nameservers {
+			if net.ParseIP(nameserver) == nil {
+				errs = append(errs, fmt.Errorf("DNS nameserver %q is not an IP address", nameserver))
+			}
+		}
+	}
+	return errs
+}
+
 func validateRootDeviceHints(rdh *RootDeviceHints) error {
 	if rdh == nil || rdh.DeviceName == "" {
 		return nil
@@ -201,3 +461,165 @@ func validateRootDeviceHints(rdh *RootDeviceHints) error {
 	}
 	return nil
 }
+
+// validateConsumerRefChange prevents a host bound to a claim from being
+// taken by another consumer, and a claim from taking a consumed host.
+func validateConsumerRefChange(old, ref *corev1.ObjectReference) error {
+	if old == nil || ref == nil {
+		return nil
+	}
+	if old.Kind != BareMetalHostClaimKind && ref.Kind != BareMetalHostClaimKind {
+		return nil
+	}
+	if old.Kind != ref.Kind || old.Namespace != ref.Namespace || old.Name != ref.Name {
+		return fmt.Errorf("host is already consumed by %s %s/%s", old.Kind, old.Namespace, old.Name)
+	}
+	return nil
+}
+
+func (v *BareMetalHostValidator) validateProvisioner(name string) error {
+	if name == "" || len(v.Provisioners) == 0 {
+		return nil
+	}
+	for _, registered := range v.Provisioners {
+		if name == registered {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown provisioner \"%s\", must be one of %s", name,
+		strings.Join(v.Provisioners, ", "))
+}
+
+// validateCredentialsName checks that the credentials name is a valid
+// object name, as credentials providers use it to locate the
+// credentials within the namespace of the host.
+func validateCredentialsName(name string) error {
+	if name == "" {
+		return nil
+	}
+	if msgs := validation.IsDNS1123Subdomain(name); len(msgs) > 0 {
+		return fmt.Errorf("invalid BMC credentialsName %q: %s", name, strings.Join(msgs, ", "))
+	}
+	return nil
+}
+
+func validateCredentialsRotation(policy *CredentialsRotationPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.Interval.Duration < MinCredentialsRotationInterval {
+		return fmt.Errorf("interval of credentials rotation must be at least %s", MinCredentialsRotationInterval)
+	}
+	return nil
+}
+
+func (v *BareMetalHostValidator) validateTrustedCertificates(ctx context.Context, namespace string, details BMCDetails, bmcAccess bmc.AccessDetails) []error {
+	trusted := details.TrustedCertificates
+	if trusted == nil {
+		return nil
+	}
+
+	var errs []error
+	if (trusted.ConfigMapName == "") == (trusted.SecretName == "") {
+		errs = append(errs, fmt.Errorf("trusted certificates must reference exactly one of a ConfigMap or a Secret"))
+	}
+	if trusted.CABundleKey != "" && trusted.FingerprintKey != "" {
+		errs = append(errs, fmt.Errorf("trusted certificates can not set both caBundleKey and fingerprintKey"))
+	}
+	if details.DisableCertificateVerification {
+		errs = append(errs, fmt.Errorf("trusted certificates can not be used when certificate verification is disabled"))
+	}
+	if bmcAccess != nil && bmc.CACertificatesField(bmcAccess) == "" {
+		errs = append(errs, fmt.Errorf("BMC driver %s does not support trusted certificates", bmcAccess.Type()))
+	}
+	if len(errs) == 0 {
+		if err := v.validateTrustedCertificatesData(ctx, namespace, trusted); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// validateTrustedCertificatesData checks the CA bundle or the
+// fingerprint held by the ConfigMap or Secret referenced by the
+// trusted certificates. A reference to an object or a key that does
+// not exist yet is accepted, and reported by the controller until the
+// data is created.
+func (v *BareMetalHostValidator) validateTrustedCertificatesData(ctx context.Context, namespace string, trusted *BMCTrustedCertificates) error {
+	if v.TrustedCertificatesGetter == nil {
+		return nil
+	}
+
+	key := trusted.FingerprintKey
+	if key == "" {
+		key = trusted.CABundleKey
+	}
+	if key == "" {
+		key = DefaultBMCCABundleKey
+	}
+
+	var data []byte
+	if trusted.SecretName != "" {
+		secret := &corev1.Secret{}
+		err := v.TrustedCertificatesGetter(ctx, namespace, trusted.SecretName, secret)
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				return nil
+			}
+			return errors.Wrapf(err, "failed to read the trusted certificates secret %s", trusted.SecretName)
+		}
+		data = secret.Data[key]
+	} else {
+		configMap := &corev1.ConfigMap{}
+		err := v.TrustedCertificatesGetter(ctx, namespace, trusted.ConfigMapName, configMap)
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				return nil
+			}
+			return errors.Wrapf(err, "failed to read the trusted certificates config map %s", trusted.ConfigMapName)
+		}
+		if value, ok := configMap.Data[key]; ok {
+			data = []byte(value)
+		} else {
+			data = configMap.BinaryData[key]
+		}
+	}
+	if data == nil {
+		return nil
+	}
+
+	if trusted.FingerprintKey != "" {
+		if _, err := bmc.ParseCertificateFingerprint(string(data)); err != nil {
+			return fmt.Errorf("trusted certificates key %s: %w", key, err)
+		}
+		return nil
+	}
+	if _, err := bmc.ParseCABundle(data); err != nil {
+		return fmt.Errorf("trusted certificates key %s: %w", key, err)
+	}
+	return nil
+}
+
+func validateRetryPolicies(policies []RetryPolicy) []error {
+	var errs []error
+	seen := make(map[ErrorType]bool, len(policies))
+
+	for _, policy := range policies {
+		if seen[policy.ErrorType] {
+			errs = append(errs, fmt.Errorf("duplicate retry policy for error type \"%s\"", policy.ErrorType))
+		}
+		seen[policy.ErrorType] = true
+
+		if policy.BackoffBase != nil && policy.BackoffBase.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("backoffBase of retry policy must be positive"))
+		}
+		if policy.BackoffCap != nil && policy.BackoffCap.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("backoffCap of retry policy must be positive"))
+		}
+		if policy.BackoffBase != nil && policy.BackoffCap != nil &&
+			policy.BackoffCap.Duration < policy.BackoffBase.Duration {
+			errs = append(errs, fmt.Errorf("backoffCap of retry policy can not be shorter than backoffBase"))
+		}
+	}
+	return errs
+}
@@ -1,10 +1,17 @@
 package v1alpha1
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func errorArrContains(out []error, want string) bool {
@@ -43,6 +50,7 @@ func TestValidateCreate(t *testing.T) {
 		Namespace: "test-namespace",
 	}
 	enable := true
+	liveISO := "live-iso"
 
 	// for RAID validation test cases
 	numberOfPhysicalDisks := 3
@@ -216,7 +224,78 @@ func TestValidateCreate(t *testing.T) {
 					},
 				}},
 			oldBMH:    nil,
-			wantedErr: "firmware settings for ipmi are not supported",
+			wantedErr: "BMC driver ipmi does not support BIOS settings",
+		},
+		{
+			name: "LiveISOWithPXEBMC",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					Image: &Image{
+						URL:        "https://example.com/live.iso",
+						DiskFormat: &liveISO,
+					},
+					BMC: BMCDetails{
+						Address:         "ipmi://127.0.1.1",
+						CredentialsName: "test1",
+					},
+				}},
+			oldBMH:    nil,
+			wantedErr: "",
+		},
+		{
+			name: "PreprovisioningNetworkDataWithPXEBMC",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					PreprovisioningNetworkDataName: "network-data",
+					BMC: BMCDetails{
+						Address:         "ipmi://127.0.1.1",
+						CredentialsName: "test1",
+					},
+				}},
+			oldBMH:    nil,
+			wantedErr: "",
+		},
+		{
+			name: "HardwareRAIDWithSupportBMC",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					RAID: &RAIDConfig{
+						HardwareRAIDVolumes: []HardwareRAIDVolume{
+							{
+								Level: "1",
+							},
+						},
+					},
+					BootMACAddress: "01:02:03:04:05:06",
+					BMC: BMCDetails{
+						Address:         "idrac-redfish://127.0.1.1/redfish/v1/Systems/System.Embedded.1",
+						CredentialsName: "test1",
+					},
+				}},
+			oldBMH:    nil,
+			wantedErr: "",
+		},
+		{
+			name: "SecureBootWithUnsupportBMC",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BootMACAddress: "01:02:03:04:05:06",
+					BootMode:       UEFISecureBoot,
+					BMC: BMCDetails{
+						Address:         "supermicro-virtualmedia://127.0.1.1/redfish/v1/Systems/1",
+						CredentialsName: "test1",
+					},
+				}},
+			oldBMH:    nil,
+			wantedErr: "BMC driver supermicro-virtualmedia does not support secure boot",
 		},
 		{
 			name: "BootMACAddressRequiredWithoutBootMACAddress",
@@ -511,63 +590,969 @@ func TestValidateCreate(t *testing.T) {
 			oldBMH:    nil,
 			wantedErr: "Image URL test1 is an invalid URL",
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if err := tt.newBMH.validateHost(); !errorArrContains(err, tt.wantedErr) {
-				t.Errorf("BareMetalHost.ValidateBareMetalHost() error = %v, wantErr %v", err, tt.wantedErr)
-			}
-		})
-	}
-}
-
-func TestValidateUpdate(t *testing.T) {
-	tm := metav1.TypeMeta{
-		Kind:       "BareMetalHost",
-		APIVersion: "metal3.io/v1alpha1",
-	}
-
-	om := metav1.ObjectMeta{
-		Name:      "test",
-		Namespace: "test-namespace",
-	}
-
-	tests := []struct {
-		name      string
-		newBMH    *BareMetalHost
-		oldBMH    *BareMetalHost
-		wantedErr string
-	}{
 		{
-			name: "updateAddress",
+			name: "validOCIImage",
 			newBMH: &BareMetalHost{
 				TypeMeta:   tm,
 				ObjectMeta: om,
 				Spec: BareMetalHostSpec{
 					BMC: BMCDetails{
-						Address: "test-address-changed"}}},
-			oldBMH: &BareMetalHost{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Image: &Image{
+						URL: "oci://quay.io/metal3/ubuntu:22.04@sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+						ImagePullSecret: &corev1.SecretReference{
+							Name: "pull-secret",
+						},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "",
+		},
+		{
+			name: "OCIImageWithoutDigest",
+			newBMH: &BareMetalHost{
 				TypeMeta:   tm,
 				ObjectMeta: om,
 				Spec: BareMetalHostSpec{
 					BMC: BMCDetails{
-						Address: "test-address"}}},
-			wantedErr: "BMC address can not be changed once it is set",
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Image: &Image{
+						URL: "oci://quay.io/metal3/ubuntu:22.04",
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "OCI image reference oci://quay.io/metal3/ubuntu:22.04 has no digest",
 		},
 		{
-			name: "updateBootMAC",
+			name: "OCIImageWithChecksum",
 			newBMH: &BareMetalHost{
-				TypeMeta: tm, ObjectMeta: om, Spec: BareMetalHostSpec{BootMACAddress: "test-mac-changed"}},
-			oldBMH: &BareMetalHost{
-				TypeMeta: tm, ObjectMeta: om, Spec: BareMetalHostSpec{BootMACAddress: "test-mac"}},
-			wantedErr: "bootMACAddress can not be changed once it is set",
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Image: &Image{
+						URL:          "oci://quay.io/metal3/ubuntu:22.04@sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+						Checksum:     "bbbb",
+						ChecksumType: SHA256,
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "the checksum of an OCI image is its digest and cannot be set",
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if err := tt.newBMH.validateChanges(tt.oldBMH); !errorArrContains(err, tt.wantedErr) {
+		{
+			name: "imagePullSecretWithHTTPImage",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Image: &Image{
+						URL:      "http://example.com/ubuntu.qcow2",
+						Checksum: "bbbb",
+						ImagePullSecret: &corev1.SecretReference{
+							Name: "pull-secret",
+						},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "imagePullSecret is only supported for OCI images",
+		},
+		{
+			name: "imagePullSecretInOtherNamespace",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Image: &Image{
+						URL: "oci://quay.io/metal3/ubuntu:22.04@sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+						ImagePullSecret: &corev1.SecretReference{
+							Name:      "pull-secret",
+							Namespace: "other-namespace",
+						},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "imagePullSecret must be in the namespace of the host",
+		},
+		{
+			name: "signaturePublicKeyInOtherNamespace",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Image: &Image{
+						URL:         "https://example.com/ubuntu.qcow2",
+						ChecksumURL: "https://example.com/SHA256SUMS",
+						Signature: &ImageSignature{
+							URL:       "https://example.com/SHA256SUMS.asc",
+							Type:      GPGSignature,
+							PublicKey: corev1.SecretReference{Name: "image-key", Namespace: "other-namespace"},
+						},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "the public key of a signature must be in the namespace of the host",
+		},
+		{
+			name: "validSignedChecksumURL",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Image: &Image{
+						URL:         "https://example.com/ubuntu.qcow2",
+						ChecksumURL: "https://example.com/SHA256SUMS",
+						Signature: &ImageSignature{
+							URL:       "https://example.com/SHA256SUMS.asc",
+							Type:      GPGSignature,
+							PublicKey: corev1.SecretReference{Name: "image-key"},
+						},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "",
+		},
+		{
+			name: "checksumAndChecksumURL",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Image: &Image{
+						URL:         "https://example.com/ubuntu.qcow2",
+						Checksum:    "bbbb",
+						ChecksumURL: "https://example.com/SHA256SUMS",
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "checksum and checksumURL cannot both be set",
+		},
+		{
+			name: "invalidChecksumURL",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Image: &Image{
+						URL:         "https://example.com/ubuntu.qcow2",
+						ChecksumURL: "file:///SHA256SUMS",
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "checksumURL file:///SHA256SUMS is not an http or https URL",
+		},
+		{
+			name: "signatureWithoutChecksumURL",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Image: &Image{
+						URL:      "https://example.com/ubuntu.qcow2",
+						Checksum: "bbbb",
+						Signature: &ImageSignature{
+							URL:       "https://example.com/SHA256SUMS.asc",
+							Type:      GPGSignature,
+							PublicKey: corev1.SecretReference{Name: "image-key"},
+						},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "a signature requires the checksumURL of the signed checksum file",
+		},
+		{
+			name: "signatureWithUnknownType",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Image: &Image{
+						URL:         "https://example.com/ubuntu.qcow2",
+						ChecksumURL: "https://example.com/SHA256SUMS",
+						Signature: &ImageSignature{
+							URL:       "https://example.com/SHA256SUMS.sig",
+							Type:      "x509",
+							PublicKey: corev1.SecretReference{Name: "image-key"},
+						},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "unknown signature type \"x509\"",
+		},
+		{
+			name: "signatureWithoutPublicKey",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Image: &Image{
+						URL:         "https://example.com/ubuntu.qcow2",
+						ChecksumURL: "https://example.com/SHA256SUMS",
+						Signature: &ImageSignature{
+							URL:  "https://example.com/SHA256SUMS.sig",
+							Type: CosignSignature,
+						},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "the public key of a signature must have a name",
+		},
+		{
+			name: "validRetryPolicies",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					RetryPolicies: []RetryPolicy{
+						{
+							MaxAttempts: 10,
+						},
+						{
+							ErrorType:   InspectionError,
+							MaxAttempts: 3,
+							BackoffBase: &metav1.Duration{Duration: time.Minute},
+							BackoffCap:  &metav1.Duration{Duration: time.Hour},
+						},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "",
+		},
+		{
+			name: "duplicateRetryPolicy",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					RetryPolicies: []RetryPolicy{
+						{
+							ErrorType:   InspectionError,
+							MaxAttempts: 3,
+						},
+						{
+							ErrorType:   InspectionError,
+							MaxAttempts: 5,
+						},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "duplicate retry policy for error type \"inspection error\"",
+		},
+		{
+			name: "invalidRetryPolicyBackoff",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					RetryPolicies: []RetryPolicy{
+						{
+							BackoffBase: &metav1.Duration{Duration: time.Hour},
+							BackoffCap:  &metav1.Duration{Duration: time.Minute},
+						},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "backoffCap of retry policy can not be shorter than backoffBase",
+		},
+		{
+			name: "validNetwork",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Network: &NetworkConfig{
+						Links: []NetworkLink{
+							{Name: "eth0", NIC: "eno1"},
+							{Name: "eth1", MACAddress: "00:11:22:33:44:55"},
+						},
+						Bonds: []NetworkBond{
+							{Name: "bond0", Links: []string{"eth0", "eth1"}, Mode: "802.3ad"},
+						},
+						VLANs: []NetworkVLAN{
+							{Name: "bond0.100", Link: "bond0", ID: 100},
+						},
+						Addresses: []NetworkAddress{
+							{Interface: "bond0", Type: DHCP4Address},
+							{Interface: "bond0.100", Address: "192.168.111.20/24"},
+						},
+						Routes: []NetworkRoute{
+							{Destination: "0.0.0.0/0", Gateway: "192.168.111.1"},
+						},
+						DNS: &NetworkDNS{Nameservers: []string{"192.168.111.1"}},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "",
+		},
+		{
+			name: "networkLinkWithoutNIC",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Network: &NetworkConfig{
+						Links: []NetworkLink{{Name: "eth0"}},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "network link \"eth0\" must have a nic or a macAddress",
+		},
+		{
+			name: "networkDuplicateInterface",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Network: &NetworkConfig{
+						Links: []NetworkLink{{Name: "eth0", NIC: "eno1"}},
+						VLANs: []NetworkVLAN{{Name: "eth0", Link: "eth0", ID: 100}},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "duplicate network interface name \"eth0\"",
+		},
+		{
+			name: "networkBondUnknownLink",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Network: &NetworkConfig{
+						Links: []NetworkLink{{Name: "eth0", NIC: "eno1"}},
+						Bonds: []NetworkBond{{Name: "bond0", Links: []string{"eth0", "eth1"}, Mode: "active-backup"}},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "network bond \"bond0\" references unknown link \"eth1\"",
+		},
+		{
+			name: "networkStaticAddressNotCIDR",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Network: &NetworkConfig{
+						Links:     []NetworkLink{{Name: "eth0", NIC: "eno1"}},
+						Addresses: []NetworkAddress{{Interface: "eth0", Address: "192.168.111.20"}},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "static network address \"192.168.111.20\" of interface \"eth0\" is not in CIDR notation",
+		},
+		{
+			name: "networkUnreachableGateway",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Network: &NetworkConfig{
+						Links:     []NetworkLink{{Name: "eth0", NIC: "eno1"}},
+						Addresses: []NetworkAddress{{Interface: "eth0", Address: "192.168.111.20/24"}},
+						Routes:    []NetworkRoute{{Destination: "0.0.0.0/0", Gateway: "10.0.0.1"}},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "network route gateway 10.0.0.1 is not in the subnet of a static address",
+		},
+		{
+			name: "validNetworkIPPool",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Network: &NetworkConfig{
+						Links:     []NetworkLink{{Name: "eth0", NIC: "eno1"}},
+						Addresses: []NetworkAddress{{Interface: "eth0", IPPool: "provisioning"}},
+						Routes:    []NetworkRoute{{Destination: "10.0.0.0/8", Gateway: "192.168.111.254"}},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "",
+		},
+		{
+			name: "networkAddressAndIPPool",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Network: &NetworkConfig{
+						Links:     []NetworkLink{{Name: "eth0", NIC: "eno1"}},
+						Addresses: []NetworkAddress{{Interface: "eth0", Address: "192.168.111.20/24", IPPool: "provisioning"}},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "network address of interface \"eth0\" can not set both an address and an ipPool",
+		},
+		{
+			name: "networkDHCPIPPool",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:         "idrac://127.0.0.1",
+						CredentialsName: "test1",
+					},
+					Network: &NetworkConfig{
+						Links:     []NetworkLink{{Name: "eth0", NIC: "eno1"}},
+						Addresses: []NetworkAddress{{Interface: "eth0", Type: DHCP4Address, IPPool: "provisioning"}},
+					},
+				},
+			},
+			oldBMH:    nil,
+			wantedErr: "dhcp4 network address of interface \"eth0\" can not set an address or an ipPool",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (&BareMetalHostValidator{}).validateHost(context.TODO(), tt.newBMH); !errorArrContains(err, tt.wantedErr) {
+				t.Errorf("BareMetalHost.ValidateBareMetalHost() error = %v, wantErr %v", err, tt.wantedErr)
+			}
+		})
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	tm := metav1.TypeMeta{
+		Kind:       "BareMetalHost",
+		APIVersion: "metal3.io/v1alpha1",
+	}
+
+	om := metav1.ObjectMeta{
+		Name:      "test",
+		Namespace: "test-namespace",
+	}
+
+	liveISO := "live-iso"
+
+	tests := []struct {
+		name      string
+		newBMH    *BareMetalHost
+		oldBMH    *BareMetalHost
+		wantedErr string
+	}{
+		{
+			name: "updateAddress",
+			newBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address: "test-address-changed"}}},
+			oldBMH: &BareMetalHost{
+				TypeMeta:   tm,
+				ObjectMeta: om,
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address: "test-address"}}},
+			wantedErr: "BMC address can not be changed once it is set",
+		},
+		{
+			name: "updateBootMAC",
+			newBMH: &BareMetalHost{
+				TypeMeta: tm, ObjectMeta: om, Spec: BareMetalHostSpec{BootMACAddress: "test-mac-changed"}},
+			oldBMH: &BareMetalHost{
+				TypeMeta: tm, ObjectMeta: om, Spec: BareMetalHostSpec{BootMACAddress: "test-mac"}},
+			wantedErr: "bootMACAddress can not be changed once it is set",
+		},
+		{
+			name: "claimConsumedHost",
+			newBMH: &BareMetalHost{
+				TypeMeta: tm, ObjectMeta: om, Spec: BareMetalHostSpec{
+					ConsumerRef: &corev1.ObjectReference{Kind: "BareMetalHostClaim", Namespace: "test-namespace", Name: "claim"}}},
+			oldBMH: &BareMetalHost{
+				TypeMeta: tm, ObjectMeta: om, Spec: BareMetalHostSpec{
+					ConsumerRef: &corev1.ObjectReference{Kind: "Metal3Machine", Namespace: "test-namespace", Name: "machine"}}},
+			wantedErr: "host is already consumed by Metal3Machine test-namespace/machine",
+		},
+		{
+			name: "stealClaimedHost",
+			newBMH: &BareMetalHost{
+				TypeMeta: tm, ObjectMeta: om, Spec: BareMetalHostSpec{
+					ConsumerRef: &corev1.ObjectReference{Kind: "BareMetalHostClaim", Namespace: "test-namespace", Name: "other"}}},
+			oldBMH: &BareMetalHost{
+				TypeMeta: tm, ObjectMeta: om, Spec: BareMetalHostSpec{
+					ConsumerRef: &corev1.ObjectReference{Kind: "BareMetalHostClaim", Namespace: "test-namespace", Name: "claim"}}},
+			wantedErr: "host is already consumed by BareMetalHostClaim test-namespace/claim",
+		},
+		{
+			name: "releaseClaimedHost",
+			newBMH: &BareMetalHost{
+				TypeMeta: tm, ObjectMeta: om, Spec: BareMetalHostSpec{}},
+			oldBMH: &BareMetalHost{
+				TypeMeta: tm, ObjectMeta: om, Spec: BareMetalHostSpec{
+					ConsumerRef: &corev1.ObjectReference{Kind: "BareMetalHostClaim", Namespace: "test-namespace", Name: "claim"}}},
+			wantedErr: "",
+		},
+		{
+			name: "updateProvisioner",
+			newBMH: &BareMetalHost{
+				TypeMeta: tm, ObjectMeta: om, Spec: BareMetalHostSpec{Provisioner: "redfish"}},
+			oldBMH: &BareMetalHost{
+				TypeMeta: tm, ObjectMeta: om, Spec: BareMetalHostSpec{}},
+			wantedErr: "provisioner can not be changed",
+		},
+		{
+			name: "updateLiveISOHostWithPXEBMC",
+			newBMH: &BareMetalHost{
+				TypeMeta: tm, ObjectMeta: om, Spec: BareMetalHostSpec{
+					BMC:    BMCDetails{Address: "ipmi://127.0.1.1", CredentialsName: "test1"},
+					Image:  &Image{URL: "https://example.com/live.iso", DiskFormat: &liveISO},
+					Online: true}},
+			oldBMH: &BareMetalHost{
+				TypeMeta: tm, ObjectMeta: om, Spec: BareMetalHostSpec{
+					BMC:   BMCDetails{Address: "ipmi://127.0.1.1", CredentialsName: "test1"},
+					Image: &Image{URL: "https://example.com/live.iso", DiskFormat: &liveISO}}},
+			wantedErr: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (&BareMetalHostValidator{}).validateChanges(context.TODO(), tt.newBMH, tt.oldBMH); !errorArrContains(err, tt.wantedErr) {
+				t.Errorf("BareMetalHost.ValidateBareMetalHost() error = %v, wantErr %v", err, tt.wantedErr)
+			}
+		})
+	}
+}
+
+func TestValidateProvisioner(t *testing.T) {
+	tests := []struct {
+		name       string
+		registered []string
+		value      string
+		wantedErr  string
+	}{
+		{
+			name:       "default",
+			registered: []string{"ironic"},
+			value:      "",
+		},
+		{
+			name:       "registered",
+			registered: []string{"ironic", "redfish"},
+			value:      "redfish",
+		},
+		{
+			name:       "unknown",
+			registered: []string{"ironic", "redfish"},
+			value:      "fake",
+			wantedErr:  "unknown provisioner \"fake\", must be one of ironic, redfish",
+		},
+		{
+			name:  "nothing registered",
+			value: "fake",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &BareMetalHostValidator{Provisioners: tt.registered}
+			host := &BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace"},
+				Spec:       BareMetalHostSpec{Provisioner: tt.value},
+			}
+			if err := validator.validateHost(context.TODO(), host); !errorArrContains(err, tt.wantedErr) {
+				t.Errorf("BareMetalHost.ValidateBareMetalHost() error = %v, wantErr %v", err, tt.wantedErr)
+			}
+		})
+	}
+}
+
+func TestValidateCredentialsName(t *testing.T) {
+	tests := []struct {
+		name            string
+		credentialsName string
+		wantedErr       string
+	}{
+		{
+			name:            "valid",
+			credentialsName: "bmc-creds.host-0",
+		},
+		{
+			name:            "path traversal",
+			credentialsName: "../other-namespace/host-0",
+			wantedErr:       `invalid BMC credentialsName "../other-namespace/host-0": a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host := &BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace"},
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{CredentialsName: tt.credentialsName},
+				},
+			}
+			if err := (&BareMetalHostValidator{}).validateHost(context.TODO(), host); !errorArrContains(err, tt.wantedErr) {
+				t.Errorf("BareMetalHost.ValidateBareMetalHost() error = %v, wantErr %v", err, tt.wantedErr)
+			}
+		})
+	}
+}
+
+func TestValidateCredentialsRotation(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    *CredentialsRotationPolicy
+		wantedErr string
+	}{
+		{
+			name: "disabled",
+		},
+		{
+			name:   "valid",
+			policy: &CredentialsRotationPolicy{Interval: metav1.Duration{Duration: 720 * time.Hour}},
+		},
+		{
+			name:      "too short",
+			policy:    &CredentialsRotationPolicy{Interval: metav1.Duration{Duration: time.Minute}},
+			wantedErr: "interval of credentials rotation must be at least 1h0m0s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host := &BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace"},
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{CredentialsRotation: tt.policy},
+				},
+			}
+			if err := (&BareMetalHostValidator{}).validateHost(context.TODO(), host); !errorArrContains(err, tt.wantedErr) {
+				t.Errorf("BareMetalHost.ValidateBareMetalHost() error = %v, wantErr %v", err, tt.wantedErr)
+			}
+		})
+	}
+}
+
+func TestValidateTrustedCertificates(t *testing.T) {
+	tests := []struct {
+		name      string
+		bmc       BMCDetails
+		wantedErr string
+	}{
+		{
+			name: "config map",
+			bmc: BMCDetails{
+				Address:             "redfish://127.0.0.1/redfish/v1/Systems/1",
+				TrustedCertificates: &BMCTrustedCertificates{ConfigMapName: "bmc-ca"},
+			},
+		},
+		{
+			name: "pinned in secret",
+			bmc: BMCDetails{
+				Address:             "idrac://127.0.0.1",
+				TrustedCertificates: &BMCTrustedCertificates{SecretName: "bmc-ca", FingerprintKey: "sha256"},
+			},
+		},
+		{
+			name: "no source",
+			bmc: BMCDetails{
+				Address:             "redfish://127.0.0.1/redfish/v1/Systems/1",
+				TrustedCertificates: &BMCTrustedCertificates{},
+			},
+			wantedErr: "trusted certificates must reference exactly one of a ConfigMap or a Secret",
+		},
+		{
+			name: "two sources",
+			bmc: BMCDetails{
+				Address:             "redfish://127.0.0.1/redfish/v1/Systems/1",
+				TrustedCertificates: &BMCTrustedCertificates{ConfigMapName: "bmc-ca", SecretName: "bmc-ca"},
+			},
+			wantedErr: "trusted certificates must reference exactly one of a ConfigMap or a Secret",
+		},
+		{
+			name: "bundle and fingerprint",
+			bmc: BMCDetails{
+				Address: "redfish://127.0.0.1/redfish/v1/Systems/1",
+				TrustedCertificates: &BMCTrustedCertificates{
+					ConfigMapName:  "bmc-ca",
+					CABundleKey:    "ca.crt",
+					FingerprintKey: "sha256",
+				},
+			},
+			wantedErr: "trusted certificates can not set both caBundleKey and fingerprintKey",
+		},
+		{
+			name: "verification disabled",
+			bmc: BMCDetails{
+				Address:                        "redfish://127.0.0.1/redfish/v1/Systems/1",
+				DisableCertificateVerification: true,
+				TrustedCertificates:            &BMCTrustedCertificates{ConfigMapName: "bmc-ca"},
+			},
+			wantedErr: "trusted certificates can not be used when certificate verification is disabled",
+		},
+		{
+			name: "ipmi",
+			bmc: BMCDetails{
+				Address:             "ipmi://127.0.0.1",
+				TrustedCertificates: &BMCTrustedCertificates{ConfigMapName: "bmc-ca"},
+			},
+			wantedErr: "BMC driver ipmi does not support trusted certificates",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host := &BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace"},
+				Spec: BareMetalHostSpec{
+					BMC:            tt.bmc,
+					BootMACAddress: "01:02:03:04:05:06",
+				},
+			}
+			if err := (&BareMetalHostValidator{}).validateHost(context.TODO(), host); !errorArrContains(err, tt.wantedErr) {
+				t.Errorf("BareMetalHost.ValidateBareMetalHost() error = %v, wantErr %v", err, tt.wantedErr)
+			}
+		})
+	}
+}
+
+// getObject returns copies of the ConfigMaps and Secrets of objects.
+func getObject(objects ...runtime.Object) ObjectGetter {
+	return func(ctx context.Context, namespace, name string, obj runtime.Object) error {
+		for _, o := range objects {
+			meta, _ := o.(metav1.Object)
+			if meta.GetName() != name || meta.GetNamespace() != namespace {
+				continue
+			}
+			switch dest := obj.(type) {
+			case *corev1.ConfigMap:
+				if src, ok := o.(*corev1.ConfigMap); ok {
+					src.DeepCopyInto(dest)
+					return nil
+				}
+			case *corev1.Secret:
+				if src, ok := o.(*corev1.Secret); ok {
+					src.DeepCopyInto(dest)
+					return nil
+				}
+			case *HostFirmwareComponents:
+				if src, ok := o.(*HostFirmwareComponents); ok {
+					src.DeepCopyInto(dest)
+					return nil
+				}
+			}
+		}
+		return k8serrors.NewNotFound(schema.GroupResource{}, name)
+	}
+}
+
+func TestValidateTrustedCertificatesData(t *testing.T) {
+	validator := &BareMetalHostValidator{TrustedCertificatesGetter: getObject(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "bad-bundle", Namespace: "test-namespace"},
+			Data:       map[string]string{"ca.crt": "not a certificate"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bad-fingerprint", Namespace: "test-namespace"},
+			Data:       map[string][]byte{"sha256": []byte("abcd")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "fingerprint", Namespace: "test-namespace"},
+			Data:       map[string][]byte{"sha256": []byte(strings.Repeat("ab:", 31) + "ab")},
+		},
+	)}
+
+	tests := []struct {
+		name      string
+		trusted   *BMCTrustedCertificates
+		wantedErr string
+	}{
+		{
+			name:      "malformed bundle",
+			trusted:   &BMCTrustedCertificates{ConfigMapName: "bad-bundle"},
+			wantedErr: "trusted certificates key ca.crt: CA bundle holds data that is not PEM-encoded",
+		},
+		{
+			name:      "malformed fingerprint",
+			trusted:   &BMCTrustedCertificates{SecretName: "bad-fingerprint", FingerprintKey: "sha256"},
+			wantedErr: `trusted certificates key sha256: invalid SHA-256 certificate fingerprint "abcd"`,
+		},
+		{
+			name:    "fingerprint",
+			trusted: &BMCTrustedCertificates{SecretName: "fingerprint", FingerprintKey: "sha256"},
+		},
+		{
+			name:    "missing config map",
+			trusted: &BMCTrustedCertificates{ConfigMapName: "bmc-ca"},
+		},
+		{
+			name:    "missing key",
+			trusted: &BMCTrustedCertificates{ConfigMapName: "bad-bundle", CABundleKey: "bundle.pem"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host := &BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace"},
+				Spec: BareMetalHostSpec{
+					BMC: BMCDetails{
+						Address:             "redfish://127.0.0.1/redfish/v1/Systems/1",
+						TrustedCertificates: tt.trusted,
+					},
+					BootMACAddress: "01:02:03:04:05:06",
+				},
+			}
+			if err := validator.validateHost(context.TODO(), host); !errorArrContains(err, tt.wantedErr) {
+				t.Errorf("BareMetalHost.ValidateBareMetalHost() error = %v, wantErr %v", err, tt.wantedErr)
+			}
+		})
+	}
+}
+
+func TestValidateFirmwareUpdates(t *testing.T) {
+	validator := &BareMetalHostValidator{HostFirmwareComponentsGetter: getObject(
+		&HostFirmwareComponents{
+			ObjectMeta: metav1.ObjectMeta{Name: "updates", Namespace: "test-namespace"},
+			Spec: HostFirmwareComponentsSpec{
+				Updates: []FirmwareUpdate{{Component: "bios", Version: "2.0", URL: "https://example.com/bios.bin"}},
+			},
+		},
+		&HostFirmwareComponents{
+			ObjectMeta: metav1.ObjectMeta{Name: "no-updates", Namespace: "test-namespace"},
+		},
+	)}
+
+	tests := []struct {
+		name      string
+		host      string
+		address   string
+		wantedErr string
+	}{
+		{
+			name:      "updates with unsupported driver",
+			host:      "updates",
+			address:   "ipmi://127.0.0.1",
+			wantedErr: "BMC driver ipmi does not support the firmware updates of the host firmware components",
+		},
+		{
+			name:    "updates with supported driver",
+			host:    "updates",
+			address: "redfish://127.0.0.1/redfish/v1/Systems/1",
+		},
+		{
+			name:    "no updates",
+			host:    "no-updates",
+			address: "ipmi://127.0.0.1",
+		},
+		{
+			name:    "no host firmware components",
+			host:    "test",
+			address: "ipmi://127.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host := &BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{Name: tt.host, Namespace: "test-namespace"},
+				Spec: BareMetalHostSpec{
+					BMC:            BMCDetails{Address: tt.address},
+					BootMACAddress: "01:02:03:04:05:06",
+				},
+			}
+			if err := validator.validateHost(context.TODO(), host); !errorArrContains(err, tt.wantedErr) {
 				t.Errorf("BareMetalHost.ValidateBareMetalHost() error = %v, wantErr %v", err, tt.wantedErr)
 			}
 		})
@@ -13,6 +13,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
 	"fmt"
 
 	"k8s.io/apimachinery/pkg/util/errors"
@@ -26,24 +27,68 @@ var baremetalhostlog = logf.Log.WithName("baremetalhost-resource")
 
 //+kubebuilder:webhook:verbs=create;update,path=/validate-metal3-io-v1alpha1-baremetalhost,mutating=false,failurePolicy=fail,sideEffects=none,admissionReviewVersions=v1;v1beta,groups=metal3.io,resources=baremetalhosts,versions=v1alpha1,name=baremetalhost.metal3.io
 
-// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
-func (r *BareMetalHost) ValidateCreate() error {
-	baremetalhostlog.Info("validate create", "name", r.Name)
-	return errors.NewAggregate(r.validateHost())
+// BareMetalHostValidator validates BareMetalHosts against the
+// configuration of the operator. It is registered as the webhook of the
+// type with the WithValidator option of the webhook builder.
+type BareMetalHostValidator struct {
+	// Provisioners holds the names of the provisioning backends
+	// available to the operator. When empty, any backend is accepted.
+	Provisioners []string
+	// TrustedCertificatesGetter reads the ConfigMaps and Secrets
+	// referenced by the trusted certificates of hosts, so that
+	// malformed CA bundles and fingerprints are rejected. Their content
+	// is not checked when it is not set.
+	TrustedCertificatesGetter ObjectGetter
+	// HostFirmwareComponentsGetter reads the HostFirmwareComponents of
+	// hosts, so that firmware updates the BMC driver of a host can not
+	// apply are rejected. They are not checked when it is not set.
+	HostFirmwareComponentsGetter ObjectGetter
 }
 
-// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
-func (r *BareMetalHost) ValidateUpdate(old runtime.Object) error {
-	baremetalhostlog.Info("validate update", "name", r.Name)
-	bmh, casted := old.(*BareMetalHost)
+// ValidateCreate implements admission.CustomValidator
+func (v *BareMetalHostValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	host, casted := obj.(*BareMetalHost)
+	if !casted {
+		return fmt.Errorf("expected a BareMetalHost but got a %T", obj)
+	}
+	baremetalhostlog.Info("validate create", "name", host.Name)
+	return errors.NewAggregate(v.validateHost(ctx, host))
+}
+
+// ValidateUpdate implements admission.CustomValidator
+func (v *BareMetalHostValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	host, casted := newObj.(*BareMetalHost)
+	if !casted {
+		return fmt.Errorf("expected a BareMetalHost but got a %T", newObj)
+	}
+	baremetalhostlog.Info("validate update", "name", host.Name)
+	old, casted := oldObj.(*BareMetalHost)
 	if !casted {
 		baremetalhostlog.Error(fmt.Errorf("old object conversion error"), "validate update error")
 		return nil
 	}
-	return errors.NewAggregate(r.validateChanges(bmh))
+	return errors.NewAggregate(v.validateChanges(ctx, host, old))
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *BareMetalHostValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// ValidateCreate checks the host without the configuration of the
+// operator, which the BareMetalHostValidator of the webhook also checks.
+func (r *BareMetalHost) ValidateCreate() error {
+	return (&BareMetalHostValidator{}).ValidateCreate(context.TODO(), r)
+}
+
+// ValidateUpdate checks the changes to the host without the
+// configuration of the operator, which the BareMetalHostValidator of the
+// webhook also checks.
+func (r *BareMetalHost) ValidateUpdate(old runtime.Object) error {
+	return (&BareMetalHostValidator{}).ValidateUpdate(context.TODO(), old, r)
 }
 
-// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+// ValidateDelete accepts the deletion of any host
 func (r *BareMetalHost) ValidateDelete() error {
 	return nil
 }
@@ -0,0 +1,192 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// BareMetalHostClaimFinalizer is the name of the finalizer added to
+	// claims to block delete operations until the claimed host is
+	// released.
+	BareMetalHostClaimFinalizer string = "baremetalhostclaim.metal3.io"
+
+	// BareMetalHostClaimKind is the kind set in the ConsumerRef of the
+	// hosts bound to a claim.
+	BareMetalHostClaimKind string = "BareMetalHostClaim"
+)
+
+// ClaimPhase describes the binding of a claim to a host.
+type ClaimPhase string
+
+const (
+	// ClaimPhasePending means no available host matching the claim
+	// has been found yet.
+	ClaimPhasePending ClaimPhase = "Pending"
+
+	// ClaimPhaseBound means the claim is bound to a host.
+	ClaimPhaseBound ClaimPhase = "Bound"
+
+	// ClaimPhaseLost means the host bound to the claim was deleted or
+	// is no longer consumed by the claim.
+	ClaimPhaseLost ClaimPhase = "Lost"
+)
+
+// HardwareSelector holds the hardware requirements for the host of a
+// claim, evaluated against the inspected hardware details of the host.
+// Only the requirements that are set are checked.
+type HardwareSelector struct {
+	// The minimum number of CPU threads.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinCPUs int `json:"minCPUs,omitempty"`
+
+	// The minimum amount of RAM, in MiB.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinRAMMebibytes int `json:"minRAMMebibytes,omitempty"`
+
+	// The architecture of the CPU, such as x86_64.
+	// +optional
+	CPUArch string `json:"cpuArch,omitempty"`
+
+	// The type of disk the host must have at least one of.
+	// +kubebuilder:validation:Enum=HDD;SSD;NVME
+	// +optional
+	DiskType DiskType `json:"diskType,omitempty"`
+
+	// The minimum size of the disk, in GiB. Combined with DiskType, a
+	// disk of that type must have at least this size.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinDiskSizeGibibytes int `json:"minDiskSizeGibibytes,omitempty"`
+}
+
+// BareMetalHostClaimSpec defines the host requested by a consumer and
+// what to deploy on it.
+type BareMetalHostClaimSpec struct {
+	// HostSelector selects the hosts that may be bound to the claim by
+	// their labels. All hosts of the namespace match when it is empty.
+	// +optional
+	HostSelector *metav1.LabelSelector `json:"hostSelector,omitempty"`
+
+	// HardwareSelector selects the hosts that may be bound to the
+	// claim by their inspected hardware.
+	// +optional
+	HardwareSelector *HardwareSelector `json:"hardwareSelector,omitempty"`
+
+	// Image holds the details of the image to be provisioned on the
+	// bound host.
+	// +optional
+	Image *Image `json:"image,omitempty"`
+
+	// UserData holds the reference to the Secret containing the user
+	// data to be passed to the bound host.
+	// +optional
+	UserData *corev1.SecretReference `json:"userData,omitempty"`
+
+	// NetworkData holds the reference to the Secret containing network
+	// configuration for the bound host.
+	// +optional
+	NetworkData *corev1.SecretReference `json:"networkData,omitempty"`
+}
+
+// BareMetalHostClaimStatus defines the observed state of BareMetalHostClaim
+type BareMetalHostClaimStatus struct {
+	// Phase of the binding of the claim
+	// +optional
+	Phase ClaimPhase `json:"phase,omitempty"`
+
+	// HostName is the name of the host bound to the claim, in the
+	// namespace of the claim.
+	// +optional
+	HostName string `json:"hostName,omitempty"`
+
+	// Message explains the current phase
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:shortName=bmhc
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Binding phase"
+//+kubebuilder:printcolumn:name="Host",type="string",JSONPath=".status.hostName",description="Bound host"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of BareMetalHostClaim"
+
+// BareMetalHostClaim is the Schema for the baremetalhostclaims API
+type BareMetalHostClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BareMetalHostClaimSpec   `json:"spec,omitempty"`
+	Status BareMetalHostClaimStatus `json:"status,omitempty"`
+}
+
+// Matches returns whether the hardware satisfies the selector.
+func (selector *HardwareSelector) Matches(details *HardwareDetails) bool {
+	if details == nil {
+		return false
+	}
+	if details.CPU.Count < selector.MinCPUs {
+		return false
+	}
+	if details.RAMMebibytes < selector.MinRAMMebibytes {
+		return false
+	}
+	if selector.CPUArch != "" && details.CPU.Arch != selector.CPUArch {
+		return false
+	}
+	if selector.DiskType == "" && selector.MinDiskSizeGibibytes == 0 {
+		return true
+	}
+	minSize := Capacity(selector.MinDiskSizeGibibytes) * GibiByte
+	for _, disk := range details.Storage {
+		if selector.DiskType != "" && disk.Type != selector.DiskType {
+			continue
+		}
+		if disk.SizeBytes >= minSize {
+			return true
+		}
+	}
+	return false
+}
+
+// ConsumedBy returns whether the host is consumed by the claim. A
+// consumer reference recording a UID only matches the claim of that
+// UID, and not a later claim of the same name.
+func (claim *BareMetalHostClaim) ConsumedBy(host *BareMetalHost) bool {
+	ref := host.Spec.ConsumerRef
+	return ref != nil && ref.Kind == BareMetalHostClaimKind &&
+		ref.Namespace == claim.Namespace && ref.Name == claim.Name &&
+		(ref.UID == "" || ref.UID == claim.UID)
+}
+
+//+kubebuilder:object:root=true
+
+// BareMetalHostClaimList contains a list of BareMetalHostClaim
+type BareMetalHostClaimList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BareMetalHostClaim `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BareMetalHostClaim{}, &BareMetalHostClaimList{})
+}
@@ -0,0 +1,91 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHardwareSelectorMatches(t *testing.T) {
+	details := &HardwareDetails{
+		CPU: CPU{
+			Arch:  "x86_64",
+			Count: 32,
+		},
+		RAMMebibytes: 192 * 1024,
+		Storage: []Storage{
+			{Name: "/dev/sda", Type: HDD, SizeBytes: 4 * TebiByte},
+			{Name: "/dev/nvme0n1", Type: NVME, SizeBytes: 960 * GigaByte},
+		},
+	}
+
+	testCases := []struct {
+		Scenario string
+		Selector HardwareSelector
+		Details  *HardwareDetails
+		Expected bool
+	}{
+		{
+			Scenario: "empty",
+			Details:  details,
+			Expected: true,
+		},
+		{
+			Scenario: "not inspected",
+			Expected: false,
+		},
+		{
+			Scenario: "satisfied",
+			Selector: HardwareSelector{
+				MinCPUs:         16,
+				MinRAMMebibytes: 128 * 1024,
+				CPUArch:         "x86_64",
+				DiskType:        NVME,
+			},
+			Details:  details,
+			Expected: true,
+		},
+		{
+			Scenario: "not enough CPUs",
+			Selector: HardwareSelector{MinCPUs: 64},
+			Details:  details,
+			Expected: false,
+		},
+		{
+			Scenario: "not enough RAM",
+			Selector: HardwareSelector{MinRAMMebibytes: 256 * 1024},
+			Details:  details,
+			Expected: false,
+		},
+		{
+			Scenario: "other arch",
+			Selector: HardwareSelector{CPUArch: "aarch64"},
+			Details:  details,
+			Expected: false,
+		},
+		{
+			Scenario: "missing disk type",
+			Selector: HardwareSelector{DiskType: SSD},
+			Details:  details,
+			Expected: false,
+		},
+		{
+			Scenario: "disk type too small",
+			Selector: HardwareSelector{DiskType: NVME, MinDiskSizeGibibytes: 1024},
+			Details:  details,
+			Expected: false,
+		},
+		{
+			Scenario: "any disk large enough",
+			Selector: HardwareSelector{MinDiskSizeGibibytes: 1024},
+			Details:  details,
+			Expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, tc.Selector.Matches(tc.Details))
+		})
+	}
+}
@@ -0,0 +1,158 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"path"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// HardwareProfileMatch holds the rules the inspected hardware of a host
+// has to satisfy for the profile to be selected. Only the rules that
+// are set are checked.
+type HardwareProfileMatch struct {
+	// Manufacturer of the system, as a shell pattern such as "Dell*".
+	// +optional
+	Manufacturer string `json:"manufacturer,omitempty"`
+
+	// ProductName of the system, as a shell pattern such as
+	// "PowerEdge R6*".
+	// +optional
+	ProductName string `json:"productName,omitempty"`
+
+	// The minimum amount of RAM of the host, in MiB.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinRAMMebibytes *int `json:"minRAMMebibytes,omitempty"`
+
+	// The maximum amount of RAM of the host, in MiB.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxRAMMebibytes *int `json:"maxRAMMebibytes,omitempty"`
+
+	// The minimum number of disks of the host.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinDisks *int `json:"minDisks,omitempty"`
+
+	// The maximum number of disks of the host.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxDisks *int `json:"maxDisks,omitempty"`
+}
+
+// HardwareProfileSpec defines the settings for a class of hardware
+type HardwareProfileSpec struct {
+	// RootDeviceHints holds the suggestions for placing the storage
+	// for the root filesystem.
+	// +optional
+	RootDeviceHints *RootDeviceHints `json:"rootDeviceHints,omitempty"`
+
+	// RootGB is the size of the root volume in GB
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RootGB int `json:"rootGB,omitempty"`
+
+	// LocalGB is the size of the local storage in GB
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	LocalGB int `json:"localGB,omitempty"`
+
+	// CPUArch is the architecture of the CPU.
+	// +optional
+	CPUArch string `json:"cpuArch,omitempty"`
+
+	// Match holds the rules used to select the profile for hosts
+	// that do not name a profile, once their hardware is inspected.
+	// Profiles without rules are only used by hosts naming them.
+	// +optional
+	Match *HardwareProfileMatch `json:"match,omitempty"`
+
+	// Priority orders the profiles matching the same host, the
+	// profile with the highest priority is selected.
+	// +optional
+	Priority int `json:"priority,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster,shortName=hwp
+//+kubebuilder:printcolumn:name="Root Device",type="string",JSONPath=".spec.rootDeviceHints.deviceName",description="Root device name"
+//+kubebuilder:printcolumn:name="Arch",type="string",JSONPath=".spec.cpuArch",description="CPU architecture"
+//+kubebuilder:printcolumn:name="Priority",type="integer",JSONPath=".spec.priority",description="Matching priority"
+
+// HardwareProfile is the Schema for the hardwareprofiles API
+type HardwareProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec HardwareProfileSpec `json:"spec,omitempty"`
+}
+
+// Matches returns whether the hardware satisfies the match rules of
+// the profile. A profile without rules never matches.
+func (profile *HardwareProfile) Matches(details *HardwareDetails) bool {
+	match := profile.Spec.Match
+	if match == nil || details == nil {
+		return false
+	}
+
+	if !matchPattern(match.Manufacturer, details.SystemVendor.Manufacturer) ||
+		!matchPattern(match.ProductName, details.SystemVendor.ProductName) {
+		return false
+	}
+
+	if match.MinRAMMebibytes != nil && details.RAMMebibytes < *match.MinRAMMebibytes {
+		return false
+	}
+	if match.MaxRAMMebibytes != nil && details.RAMMebibytes > *match.MaxRAMMebibytes {
+		return false
+	}
+
+	disks := len(details.Storage)
+	if match.MinDisks != nil && disks < *match.MinDisks {
+		return false
+	}
+	if match.MaxDisks != nil && disks > *match.MaxDisks {
+		return false
+	}
+
+	return true
+}
+
+func matchPattern(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, err := path.Match(pattern, value)
+	return err == nil && matched
+}
+
+//+kubebuilder:object:root=true
+
+// HardwareProfileList contains a list of HardwareProfile
+type HardwareProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HardwareProfile `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HardwareProfile{}, &HardwareProfileList{})
+}
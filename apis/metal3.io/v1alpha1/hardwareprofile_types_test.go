@@ -0,0 +1,118 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHardwareProfileMatches(t *testing.T) {
+	min := 64 * 1024
+	max := 256 * 1024
+	two := 2
+
+	details := &HardwareDetails{
+		SystemVendor: HardwareSystemVendor{
+			Manufacturer: "Dell Inc.",
+			ProductName:  "PowerEdge R640",
+		},
+		RAMMebibytes: 192 * 1024,
+		Storage: []Storage{
+			{Name: "/dev/sda"},
+			{Name: "/dev/sdb"},
+		},
+	}
+
+	testCases := []struct {
+		Scenario string
+		Match    *HardwareProfileMatch
+		Details  *HardwareDetails
+		Expected bool
+	}{
+		{
+			Scenario: "no rules",
+			Details:  details,
+			Expected: false,
+		},
+		{
+			Scenario: "no details",
+			Match:    &HardwareProfileMatch{Manufacturer: "Dell*"},
+			Expected: false,
+		},
+		{
+			Scenario: "empty rules",
+			Match:    &HardwareProfileMatch{},
+			Details:  details,
+			Expected: true,
+		},
+		{
+			Scenario: "vendor and product",
+			Match: &HardwareProfileMatch{
+				Manufacturer: "Dell*",
+				ProductName:  "PowerEdge R6*",
+			},
+			Details:  details,
+			Expected: true,
+		},
+		{
+			Scenario: "other product",
+			Match: &HardwareProfileMatch{
+				Manufacturer: "Dell*",
+				ProductName:  "PowerEdge R7*",
+			},
+			Details:  details,
+			Expected: false,
+		},
+		{
+			Scenario: "RAM in range",
+			Match: &HardwareProfileMatch{
+				MinRAMMebibytes: &min,
+				MaxRAMMebibytes: &max,
+			},
+			Details:  details,
+			Expected: true,
+		},
+		{
+			Scenario: "not enough RAM",
+			Match: &HardwareProfileMatch{
+				MinRAMMebibytes: &max,
+			},
+			Details:  details,
+			Expected: false,
+		},
+		{
+			Scenario: "disk count",
+			Match: &HardwareProfileMatch{
+				MinDisks: &two,
+				MaxDisks: &two,
+			},
+			Details:  details,
+			Expected: true,
+		},
+		{
+			Scenario: "too few disks",
+			Match: &HardwareProfileMatch{
+				MinDisks: &two,
+			},
+			Details:  &HardwareDetails{Storage: []Storage{{Name: "/dev/sda"}}},
+			Expected: false,
+		},
+		{
+			Scenario: "invalid pattern",
+			Match: &HardwareProfileMatch{
+				Manufacturer: "[Dell",
+			},
+			Details:  details,
+			Expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			profile := &HardwareProfile{
+				Spec: HardwareProfileSpec{Match: tc.Match},
+			}
+			assert.Equal(t, tc.Expected, profile.Matches(tc.Details))
+		})
+	}
+}
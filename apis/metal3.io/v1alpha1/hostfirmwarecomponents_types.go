@@ -0,0 +1,135 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// FirmwareUpdate defines a firmware image to flash onto a component.
+type FirmwareUpdate struct {
+	// Component is the firmware component to update: bios, bmc or
+	// nic:<id> for a network interface.
+	// +kubebuilder:validation:Pattern=`^(bios|bmc|nic:.+)$`
+	Component string `json:"component"`
+
+	// Version is the firmware version provided by the image.
+	// +kubebuilder:validation:MinLength=1
+	Version string `json:"version"`
+
+	// URL is the location of the firmware image.
+	// +kubebuilder:validation:MinLength=1
+	URL string `json:"url"`
+
+	// Checksum of the firmware image, if required by the BMC.
+	// +optional
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// FirmwareComponentStatus defines the status of a firmware component.
+type FirmwareComponentStatus struct {
+	// Component is the name of the firmware component.
+	Component string `json:"component"`
+
+	// InitialVersion is the version found on the component when it was
+	// first discovered.
+	// +optional
+	InitialVersion string `json:"initialVersion,omitempty"`
+
+	// CurrentVersion is the version currently running on the component.
+	// +optional
+	CurrentVersion string `json:"currentVersion,omitempty"`
+
+	// LastVersionFlashed is the version of the last firmware image
+	// successfully applied to the component.
+	// +optional
+	LastVersionFlashed string `json:"lastVersionFlashed,omitempty"`
+
+	// UpdatedAt is the time the component was last updated.
+	// +optional
+	UpdatedAt *metav1.Time `json:"updatedAt,omitempty"`
+}
+
+type UpdatesConditionType string
+
+const (
+	// Indicates that the updates in the Spec are different than Status
+	HostFirmwareComponentsChangeDetected UpdatesConditionType = "ChangeDetected"
+
+	// Indicates if the updates are valid and can be applied to the host
+	HostFirmwareComponentsValid UpdatesConditionType = "Valid"
+)
+
+// HostFirmwareComponentsSpec defines the desired state of HostFirmwareComponents
+type HostFirmwareComponentsSpec struct {
+
+	// Updates are the firmware images to apply to the host components.
+	// +optional
+	Updates []FirmwareUpdate `json:"updates,omitempty"`
+}
+
+// HostFirmwareComponentsStatus defines the observed state of HostFirmwareComponents
+type HostFirmwareComponentsStatus struct {
+	// Updates are the firmware images last applied to the host components.
+	// +optional
+	Updates []FirmwareUpdate `json:"updates,omitempty"`
+
+	// Components are the firmware components of the host and their
+	// versions.
+	// +optional
+	Components []FirmwareComponentStatus `json:"components,omitempty"`
+
+	// Time that the status was last updated
+	// +optional
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+
+	// Track whether updates stored in the spec are valid
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:shortName=hfc
+//+kubebuilder:subresource:status
+
+// HostFirmwareComponents is the Schema for the hostfirmwarecomponents API
+type HostFirmwareComponents struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HostFirmwareComponentsSpec   `json:"spec,omitempty"`
+	Status HostFirmwareComponentsStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// HostFirmwareComponentsList contains a list of HostFirmwareComponents
+type HostFirmwareComponentsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HostFirmwareComponents `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HostFirmwareComponents{}, &HostFirmwareComponentsList{})
+}
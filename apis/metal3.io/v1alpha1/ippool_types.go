@@ -0,0 +1,124 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IPPoolSpec defines the subnet the addresses of a pool are allocated
+// from, and the settings of the hosts using them.
+type IPPoolSpec struct {
+	// CIDR is the subnet of the pool, such as 192.168.111.0/24.
+	CIDR string `json:"cidr"`
+
+	// Start is the first address allocated. Defaults to the first
+	// address of the subnet.
+	// +optional
+	Start string `json:"start,omitempty"`
+
+	// End is the last address allocated. Defaults to the last address
+	// of the subnet.
+	// +optional
+	End string `json:"end,omitempty"`
+
+	// Gateway is the default gateway of the hosts. It is never
+	// allocated.
+	// +optional
+	Gateway string `json:"gateway,omitempty"`
+
+	// DNSServers are the addresses of the DNS servers of the hosts.
+	// +optional
+	DNSServers []string `json:"dnsServers,omitempty"`
+
+	// Reservations are addresses that are only allocated to a given
+	// host, or never allocated.
+	// +optional
+	Reservations []IPReservation `json:"reservations,omitempty"`
+}
+
+// IPReservation reserves an address of a pool.
+type IPReservation struct {
+	// Address is the reserved address.
+	Address string `json:"address"`
+
+	// Host is the name of the BareMetalHost the address is allocated
+	// to. The address is never allocated when it is empty.
+	// +optional
+	Host string `json:"host,omitempty"`
+}
+
+// IPAllocation is an address of a pool allocated to a host.
+type IPAllocation struct {
+	// Host is the name of the BareMetalHost, in the namespace of the
+	// pool.
+	Host string `json:"host"`
+
+	// Address is the allocated address.
+	Address string `json:"address"`
+}
+
+// IPPoolStatus defines the observed state of IPPool
+type IPPoolStatus struct {
+	// Allocations are the addresses allocated to the hosts.
+	// +optional
+	Allocations []IPAllocation `json:"allocations,omitempty"`
+
+	// Message explains why addresses could not be allocated.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:shortName=ipp
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="CIDR",type="string",JSONPath=".spec.cidr",description="Subnet of the pool"
+//+kubebuilder:printcolumn:name="Gateway",type="string",JSONPath=".spec.gateway",description="Default gateway"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Time duration since creation of IPPool"
+
+// IPPool is the Schema for the ippools API
+type IPPool struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IPPoolSpec   `json:"spec,omitempty"`
+	Status IPPoolStatus `json:"status,omitempty"`
+}
+
+// AllocatedAddress returns the address allocated to the host, or an
+// empty string when none is.
+func (pool *IPPool) AllocatedAddress(hostName string) string {
+	for _, allocation := range pool.Status.Allocations {
+		if allocation.Host == hostName {
+			return allocation.Address
+		}
+	}
+	return ""
+}
+
+//+kubebuilder:object:root=true
+
+// IPPoolList contains a list of IPPool
+type IPPoolList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IPPool `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IPPool{}, &IPPoolList{})
+}
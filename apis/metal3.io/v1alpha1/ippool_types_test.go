@@ -0,0 +1,20 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocatedAddress(t *testing.T) {
+	pool := &IPPool{
+		Status: IPPoolStatus{
+			Allocations: []IPAllocation{
+				{Host: "host-0", Address: "192.168.111.20"},
+				{Host: "host-1", Address: "192.168.111.21"},
+			},
+		},
+	}
+	assert.Equal(t, "192.168.111.21", pool.AllocatedAddress("host-1"))
+	assert.Empty(t, pool.AllocatedAddress("host-2"))
+}
@@ -85,7 +85,22 @@ func init() {
 	}
 }
 
-// GetProfile returns the named profile
+// FromHardwareProfile returns the profile described by a
+// HardwareProfile resource
+func FromHardwareProfile(hwProf *metal3v1alpha1.HardwareProfile) Profile {
+	profile := Profile{
+		Name:    hwProf.Name,
+		RootGB:  hwProf.Spec.RootGB,
+		LocalGB: hwProf.Spec.LocalGB,
+		CPUArch: hwProf.Spec.CPUArch,
+	}
+	if hwProf.Spec.RootDeviceHints != nil {
+		profile.RootDeviceHints = *hwProf.Spec.RootDeviceHints.DeepCopy()
+	}
+	return profile
+}
+
+// GetProfile returns the named built-in profile
 func GetProfile(name string) (Profile, error) {
 	profile, ok := profiles[name]
 	if !ok {
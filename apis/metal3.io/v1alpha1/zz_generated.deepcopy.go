@@ -45,6 +45,16 @@ func (in *BIOS) DeepCopy() *BIOS {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BMCDetails) DeepCopyInto(out *BMCDetails) {
 	*out = *in
+	if in.TrustedCertificates != nil {
+		in, out := &in.TrustedCertificates, &out.TrustedCertificates
+		*out = new(BMCTrustedCertificates)
+		**out = **in
+	}
+	if in.CredentialsRotation != nil {
+		in, out := &in.CredentialsRotation, &out.CredentialsRotation
+		*out = new(CredentialsRotationPolicy)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BMCDetails.
@@ -151,6 +161,21 @@ func (in *BMCEventSubscriptionStatus) DeepCopy() *BMCEventSubscriptionStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BMCTrustedCertificates) DeepCopyInto(out *BMCTrustedCertificates) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BMCTrustedCertificates.
+func (in *BMCTrustedCertificates) DeepCopy() *BMCTrustedCertificates {
+	if in == nil {
+		return nil
+	}
+	out := new(BMCTrustedCertificates)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BareMetalHost) DeepCopyInto(out *BareMetalHost) {
 	*out = *in
@@ -178,6 +203,120 @@ func (in *BareMetalHost) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BareMetalHostClaim) DeepCopyInto(out *BareMetalHostClaim) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalHostClaim.
+func (in *BareMetalHostClaim) DeepCopy() *BareMetalHostClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(BareMetalHostClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BareMetalHostClaim) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BareMetalHostClaimList) DeepCopyInto(out *BareMetalHostClaimList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BareMetalHostClaim, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalHostClaimList.
+func (in *BareMetalHostClaimList) DeepCopy() *BareMetalHostClaimList {
+	if in == nil {
+		return nil
+	}
+	out := new(BareMetalHostClaimList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BareMetalHostClaimList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BareMetalHostClaimSpec) DeepCopyInto(out *BareMetalHostClaimSpec) {
+	*out = *in
+	if in.HostSelector != nil {
+		in, out := &in.HostSelector, &out.HostSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HardwareSelector != nil {
+		in, out := &in.HardwareSelector, &out.HardwareSelector
+		*out = new(HardwareSelector)
+		**out = **in
+	}
+	if in.Image != nil {
+		in, out := &in.Image, &out.Image
+		*out = new(Image)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UserData != nil {
+		in, out := &in.UserData, &out.UserData
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+	if in.NetworkData != nil {
+		in, out := &in.NetworkData, &out.NetworkData
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalHostClaimSpec.
+func (in *BareMetalHostClaimSpec) DeepCopy() *BareMetalHostClaimSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BareMetalHostClaimSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BareMetalHostClaimStatus) DeepCopyInto(out *BareMetalHostClaimStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalHostClaimStatus.
+func (in *BareMetalHostClaimStatus) DeepCopy() *BareMetalHostClaimStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BareMetalHostClaimStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BareMetalHostList) DeepCopyInto(out *BareMetalHostList) {
 	*out = *in
@@ -220,7 +359,7 @@ func (in *BareMetalHostSpec) DeepCopyInto(out *BareMetalHostSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	out.BMC = in.BMC
+	in.BMC.DeepCopyInto(&out.BMC)
 	if in.RAID != nil {
 		in, out := &in.RAID, &out.RAID
 		*out = new(RAIDConfig)
@@ -261,11 +400,23 @@ func (in *BareMetalHostSpec) DeepCopyInto(out *BareMetalHostSpec) {
 		*out = new(v1.SecretReference)
 		**out = **in
 	}
+	if in.Network != nil {
+		in, out := &in.Network, &out.Network
+		*out = new(NetworkConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.CustomDeploy != nil {
 		in, out := &in.CustomDeploy, &out.CustomDeploy
 		*out = new(CustomDeploy)
 		**out = **in
 	}
+	if in.RetryPolicies != nil {
+		in, out := &in.RetryPolicies, &out.RetryPolicies
+		*out = make([]RetryPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalHostSpec.
@@ -293,7 +444,34 @@ func (in *BareMetalHostStatus) DeepCopyInto(out *BareMetalHostStatus) {
 	in.Provisioning.DeepCopyInto(&out.Provisioning)
 	in.GoodCredentials.DeepCopyInto(&out.GoodCredentials)
 	in.TriedCredentials.DeepCopyInto(&out.TriedCredentials)
+	if in.CredentialsRotation != nil {
+		in, out := &in.CredentialsRotation, &out.CredentialsRotation
+		*out = new(CredentialsRotationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BMCCapabilities != nil {
+		in, out := &in.BMCCapabilities, &out.BMCCapabilities
+		*out = make([]BMCCapability, len(*in))
+		copy(*out, *in)
+	}
+	if in.HardwareHealth != nil {
+		in, out := &in.HardwareHealth, &out.HardwareHealth
+		*out = new(HardwareHealth)
+		(*in).DeepCopyInto(*out)
+	}
 	in.OperationHistory.DeepCopyInto(&out.OperationHistory)
+	if in.Progress != nil {
+		in, out := &in.Progress, &out.Progress
+		*out = new(OperationProgress)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BareMetalHostStatus.
@@ -326,6 +504,60 @@ func (in *CPU) DeepCopy() *CPU {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentHealth) DeepCopyInto(out *ComponentHealth) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentHealth.
+func (in *ComponentHealth) DeepCopy() *ComponentHealth {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentHealth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialsRotationPolicy) DeepCopyInto(out *CredentialsRotationPolicy) {
+	*out = *in
+	out.Interval = in.Interval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialsRotationPolicy.
+func (in *CredentialsRotationPolicy) DeepCopy() *CredentialsRotationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialsRotationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialsRotationStatus) DeepCopyInto(out *CredentialsRotationStatus) {
+	*out = *in
+	if in.LastRotated != nil {
+		in, out := &in.LastRotated, &out.LastRotated
+		*out = (*in).DeepCopy()
+	}
+	if in.LastFailure != nil {
+		in, out := &in.LastFailure, &out.LastFailure
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialsRotationStatus.
+func (in *CredentialsRotationStatus) DeepCopy() *CredentialsRotationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialsRotationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CredentialsStatus) DeepCopyInto(out *CredentialsStatus) {
 	*out = *in
@@ -413,6 +645,25 @@ func (in *Firmware) DeepCopy() *Firmware {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirmwareComponentStatus) DeepCopyInto(out *FirmwareComponentStatus) {
+	*out = *in
+	if in.UpdatedAt != nil {
+		in, out := &in.UpdatedAt, &out.UpdatedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirmwareComponentStatus.
+func (in *FirmwareComponentStatus) DeepCopy() *FirmwareComponentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FirmwareComponentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FirmwareConfig) DeepCopyInto(out *FirmwareConfig) {
 	*out = *in
@@ -523,6 +774,21 @@ func (in *FirmwareSchemaSpec) DeepCopy() *FirmwareSchemaSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirmwareUpdate) DeepCopyInto(out *FirmwareUpdate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirmwareUpdate.
+func (in *FirmwareUpdate) DeepCopy() *FirmwareUpdate {
+	if in == nil {
+		return nil
+	}
+	out := new(FirmwareUpdate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HardwareData) DeepCopyInto(out *HardwareData) {
 	*out = *in
@@ -632,76 +898,49 @@ func (in *HardwareDetails) DeepCopy() *HardwareDetails {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HardwareRAIDVolume) DeepCopyInto(out *HardwareRAIDVolume) {
+func (in *HardwareHealth) DeepCopyInto(out *HardwareHealth) {
 	*out = *in
-	if in.SizeGibibytes != nil {
-		in, out := &in.SizeGibibytes, &out.SizeGibibytes
-		*out = new(int)
-		**out = **in
-	}
-	if in.Rotational != nil {
-		in, out := &in.Rotational, &out.Rotational
-		*out = new(bool)
-		**out = **in
-	}
-	if in.NumberOfPhysicalDisks != nil {
-		in, out := &in.NumberOfPhysicalDisks, &out.NumberOfPhysicalDisks
-		*out = new(int)
-		**out = **in
-	}
-	if in.PhysicalDisks != nil {
-		in, out := &in.PhysicalDisks, &out.PhysicalDisks
-		*out = make([]string, len(*in))
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]ComponentHealth, len(*in))
 		copy(*out, *in)
 	}
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HardwareRAIDVolume.
-func (in *HardwareRAIDVolume) DeepCopy() *HardwareRAIDVolume {
-	if in == nil {
-		return nil
+	if in.LastUpdated != nil {
+		in, out := &in.LastUpdated, &out.LastUpdated
+		*out = (*in).DeepCopy()
 	}
-	out := new(HardwareRAIDVolume)
-	in.DeepCopyInto(out)
-	return out
-}
-
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HardwareSystemVendor) DeepCopyInto(out *HardwareSystemVendor) {
-	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HardwareSystemVendor.
-func (in *HardwareSystemVendor) DeepCopy() *HardwareSystemVendor {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HardwareHealth.
+func (in *HardwareHealth) DeepCopy() *HardwareHealth {
 	if in == nil {
 		return nil
 	}
-	out := new(HardwareSystemVendor)
+	out := new(HardwareHealth)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HostFirmwareSettings) DeepCopyInto(out *HostFirmwareSettings) {
+func (in *HardwareProfile) DeepCopyInto(out *HardwareProfile) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostFirmwareSettings.
-func (in *HostFirmwareSettings) DeepCopy() *HostFirmwareSettings {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HardwareProfile.
+func (in *HardwareProfile) DeepCopy() *HardwareProfile {
 	if in == nil {
 		return nil
 	}
-	out := new(HostFirmwareSettings)
+	out := new(HardwareProfile)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HostFirmwareSettings) DeepCopyObject() runtime.Object {
+func (in *HardwareProfile) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -709,31 +948,31 @@ func (in *HostFirmwareSettings) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HostFirmwareSettingsList) DeepCopyInto(out *HostFirmwareSettingsList) {
+func (in *HardwareProfileList) DeepCopyInto(out *HardwareProfileList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]HostFirmwareSettings, len(*in))
+		*out = make([]HardwareProfile, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostFirmwareSettingsList.
-func (in *HostFirmwareSettingsList) DeepCopy() *HostFirmwareSettingsList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HardwareProfileList.
+func (in *HardwareProfileList) DeepCopy() *HardwareProfileList {
 	if in == nil {
 		return nil
 	}
-	out := new(HostFirmwareSettingsList)
+	out := new(HardwareProfileList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *HostFirmwareSettingsList) DeepCopyObject() runtime.Object {
+func (in *HardwareProfileList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -741,18 +980,319 @@ func (in *HostFirmwareSettingsList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *HostFirmwareSettingsSpec) DeepCopyInto(out *HostFirmwareSettingsSpec) {
+func (in *HardwareProfileMatch) DeepCopyInto(out *HardwareProfileMatch) {
 	*out = *in
-	if in.Settings != nil {
-		in, out := &in.Settings, &out.Settings
-		*out = make(DesiredSettingsMap, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
+	if in.MinRAMMebibytes != nil {
+		in, out := &in.MinRAMMebibytes, &out.MinRAMMebibytes
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxRAMMebibytes != nil {
+		in, out := &in.MaxRAMMebibytes, &out.MaxRAMMebibytes
+		*out = new(int)
+		**out = **in
+	}
+	if in.MinDisks != nil {
+		in, out := &in.MinDisks, &out.MinDisks
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxDisks != nil {
+		in, out := &in.MaxDisks, &out.MaxDisks
+		*out = new(int)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostFirmwareSettingsSpec.
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HardwareProfileMatch.
+func (in *HardwareProfileMatch) DeepCopy() *HardwareProfileMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(HardwareProfileMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HardwareProfileSpec) DeepCopyInto(out *HardwareProfileSpec) {
+	*out = *in
+	if in.RootDeviceHints != nil {
+		in, out := &in.RootDeviceHints, &out.RootDeviceHints
+		*out = new(RootDeviceHints)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Match != nil {
+		in, out := &in.Match, &out.Match
+		*out = new(HardwareProfileMatch)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HardwareProfileSpec.
+func (in *HardwareProfileSpec) DeepCopy() *HardwareProfileSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HardwareProfileSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HardwareRAIDVolume) DeepCopyInto(out *HardwareRAIDVolume) {
+	*out = *in
+	if in.SizeGibibytes != nil {
+		in, out := &in.SizeGibibytes, &out.SizeGibibytes
+		*out = new(int)
+		**out = **in
+	}
+	if in.Rotational != nil {
+		in, out := &in.Rotational, &out.Rotational
+		*out = new(bool)
+		**out = **in
+	}
+	if in.NumberOfPhysicalDisks != nil {
+		in, out := &in.NumberOfPhysicalDisks, &out.NumberOfPhysicalDisks
+		*out = new(int)
+		**out = **in
+	}
+	if in.PhysicalDisks != nil {
+		in, out := &in.PhysicalDisks, &out.PhysicalDisks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HardwareRAIDVolume.
+func (in *HardwareRAIDVolume) DeepCopy() *HardwareRAIDVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(HardwareRAIDVolume)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HardwareSelector) DeepCopyInto(out *HardwareSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HardwareSelector.
+func (in *HardwareSelector) DeepCopy() *HardwareSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(HardwareSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HardwareSystemVendor) DeepCopyInto(out *HardwareSystemVendor) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HardwareSystemVendor.
+func (in *HardwareSystemVendor) DeepCopy() *HardwareSystemVendor {
+	if in == nil {
+		return nil
+	}
+	out := new(HardwareSystemVendor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostFirmwareComponents) DeepCopyInto(out *HostFirmwareComponents) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostFirmwareComponents.
+func (in *HostFirmwareComponents) DeepCopy() *HostFirmwareComponents {
+	if in == nil {
+		return nil
+	}
+	out := new(HostFirmwareComponents)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HostFirmwareComponents) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostFirmwareComponentsList) DeepCopyInto(out *HostFirmwareComponentsList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HostFirmwareComponents, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostFirmwareComponentsList.
+func (in *HostFirmwareComponentsList) DeepCopy() *HostFirmwareComponentsList {
+	if in == nil {
+		return nil
+	}
+	out := new(HostFirmwareComponentsList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HostFirmwareComponentsList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostFirmwareComponentsSpec) DeepCopyInto(out *HostFirmwareComponentsSpec) {
+	*out = *in
+	if in.Updates != nil {
+		in, out := &in.Updates, &out.Updates
+		*out = make([]FirmwareUpdate, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostFirmwareComponentsSpec.
+func (in *HostFirmwareComponentsSpec) DeepCopy() *HostFirmwareComponentsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HostFirmwareComponentsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostFirmwareComponentsStatus) DeepCopyInto(out *HostFirmwareComponentsStatus) {
+	*out = *in
+	if in.Updates != nil {
+		in, out := &in.Updates, &out.Updates
+		*out = make([]FirmwareUpdate, len(*in))
+		copy(*out, *in)
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]FirmwareComponentStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastUpdated != nil {
+		in, out := &in.LastUpdated, &out.LastUpdated
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostFirmwareComponentsStatus.
+func (in *HostFirmwareComponentsStatus) DeepCopy() *HostFirmwareComponentsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HostFirmwareComponentsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostFirmwareSettings) DeepCopyInto(out *HostFirmwareSettings) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostFirmwareSettings.
+func (in *HostFirmwareSettings) DeepCopy() *HostFirmwareSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(HostFirmwareSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HostFirmwareSettings) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostFirmwareSettingsList) DeepCopyInto(out *HostFirmwareSettingsList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HostFirmwareSettings, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostFirmwareSettingsList.
+func (in *HostFirmwareSettingsList) DeepCopy() *HostFirmwareSettingsList {
+	if in == nil {
+		return nil
+	}
+	out := new(HostFirmwareSettingsList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HostFirmwareSettingsList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostFirmwareSettingsSpec) DeepCopyInto(out *HostFirmwareSettingsSpec) {
+	*out = *in
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make(DesiredSettingsMap, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostFirmwareSettingsSpec.
 func (in *HostFirmwareSettingsSpec) DeepCopy() *HostFirmwareSettingsSpec {
 	if in == nil {
 		return nil
@@ -800,14 +1340,158 @@ func (in *HostFirmwareSettingsStatus) DeepCopy() *HostFirmwareSettingsStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPAllocation) DeepCopyInto(out *IPAllocation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPAllocation.
+func (in *IPAllocation) DeepCopy() *IPAllocation {
+	if in == nil {
+		return nil
+	}
+	out := new(IPAllocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPPool) DeepCopyInto(out *IPPool) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPPool.
+func (in *IPPool) DeepCopy() *IPPool {
+	if in == nil {
+		return nil
+	}
+	out := new(IPPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IPPool) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPPoolList) DeepCopyInto(out *IPPoolList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]IPPool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPPoolList.
+func (in *IPPoolList) DeepCopy() *IPPoolList {
+	if in == nil {
+		return nil
+	}
+	out := new(IPPoolList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IPPoolList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPPoolSpec) DeepCopyInto(out *IPPoolSpec) {
+	*out = *in
+	if in.DNSServers != nil {
+		in, out := &in.DNSServers, &out.DNSServers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Reservations != nil {
+		in, out := &in.Reservations, &out.Reservations
+		*out = make([]IPReservation, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPPoolSpec.
+func (in *IPPoolSpec) DeepCopy() *IPPoolSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IPPoolSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPPoolStatus) DeepCopyInto(out *IPPoolStatus) {
+	*out = *in
+	if in.Allocations != nil {
+		in, out := &in.Allocations, &out.Allocations
+		*out = make([]IPAllocation, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPPoolStatus.
+func (in *IPPoolStatus) DeepCopy() *IPPoolStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IPPoolStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPReservation) DeepCopyInto(out *IPReservation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPReservation.
+func (in *IPReservation) DeepCopy() *IPReservation {
+	if in == nil {
+		return nil
+	}
+	out := new(IPReservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Image) DeepCopyInto(out *Image) {
 	*out = *in
+	if in.Signature != nil {
+		in, out := &in.Signature, &out.Signature
+		*out = new(ImageSignature)
+		**out = **in
+	}
 	if in.DiskFormat != nil {
 		in, out := &in.DiskFormat, &out.DiskFormat
 		*out = new(string)
 		**out = **in
 	}
+	if in.ImagePullSecret != nil {
+		in, out := &in.ImagePullSecret, &out.ImagePullSecret
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Image.
@@ -820,6 +1504,22 @@ func (in *Image) DeepCopy() *Image {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSignature) DeepCopyInto(out *ImageSignature) {
+	*out = *in
+	out.PublicKey = in.PublicKey
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSignature.
+func (in *ImageSignature) DeepCopy() *ImageSignature {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSignature)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NIC) DeepCopyInto(out *NIC) {
 	*out = *in
@@ -840,11 +1540,179 @@ func (in *NIC) DeepCopy() *NIC {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkAddress) DeepCopyInto(out *NetworkAddress) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkAddress.
+func (in *NetworkAddress) DeepCopy() *NetworkAddress {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkAddress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkBond) DeepCopyInto(out *NetworkBond) {
+	*out = *in
+	if in.Links != nil {
+		in, out := &in.Links, &out.Links
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkBond.
+func (in *NetworkBond) DeepCopy() *NetworkBond {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkBond)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkConfig) DeepCopyInto(out *NetworkConfig) {
+	*out = *in
+	if in.Links != nil {
+		in, out := &in.Links, &out.Links
+		*out = make([]NetworkLink, len(*in))
+		copy(*out, *in)
+	}
+	if in.Bonds != nil {
+		in, out := &in.Bonds, &out.Bonds
+		*out = make([]NetworkBond, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VLANs != nil {
+		in, out := &in.VLANs, &out.VLANs
+		*out = make([]NetworkVLAN, len(*in))
+		copy(*out, *in)
+	}
+	if in.Addresses != nil {
+		in, out := &in.Addresses, &out.Addresses
+		*out = make([]NetworkAddress, len(*in))
+		copy(*out, *in)
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]NetworkRoute, len(*in))
+		copy(*out, *in)
+	}
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = new(NetworkDNS)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkConfig.
+func (in *NetworkConfig) DeepCopy() *NetworkConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkDNS) DeepCopyInto(out *NetworkDNS) {
+	*out = *in
+	if in.Nameservers != nil {
+		in, out := &in.Nameservers, &out.Nameservers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Search != nil {
+		in, out := &in.Search, &out.Search
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkDNS.
+func (in *NetworkDNS) DeepCopy() *NetworkDNS {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkDNS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkLink) DeepCopyInto(out *NetworkLink) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkLink.
+func (in *NetworkLink) DeepCopy() *NetworkLink {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkLink)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkRoute) DeepCopyInto(out *NetworkRoute) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkRoute.
+func (in *NetworkRoute) DeepCopy() *NetworkRoute {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkRoute)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkVLAN) DeepCopyInto(out *NetworkVLAN) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkVLAN.
+func (in *NetworkVLAN) DeepCopy() *NetworkVLAN {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkVLAN)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OCIReference) DeepCopyInto(out *OCIReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OCIReference.
+func (in *OCIReference) DeepCopy() *OCIReference {
+	if in == nil {
+		return nil
+	}
+	out := new(OCIReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OperationHistory) DeepCopyInto(out *OperationHistory) {
 	*out = *in
 	in.Register.DeepCopyInto(&out.Register)
 	in.Inspect.DeepCopyInto(&out.Inspect)
+	in.Prepare.DeepCopyInto(&out.Prepare)
 	in.Provision.DeepCopyInto(&out.Provision)
 	in.Deprovision.DeepCopyInto(&out.Deprovision)
 }
@@ -876,6 +1744,25 @@ func (in *OperationMetric) DeepCopy() *OperationMetric {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperationProgress) DeepCopyInto(out *OperationProgress) {
+	*out = *in
+	if in.LastHeartbeat != nil {
+		in, out := &in.LastHeartbeat, &out.LastHeartbeat
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperationProgress.
+func (in *OperationProgress) DeepCopy() *OperationProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(OperationProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PreprovisioningImage) DeepCopyInto(out *PreprovisioningImage) {
 	*out = *in
@@ -1058,6 +1945,31 @@ func (in *RebootAnnotationArguments) DeepCopy() *RebootAnnotationArguments {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
+	*out = *in
+	if in.BackoffBase != nil {
+		in, out := &in.BackoffBase, &out.BackoffBase
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.BackoffCap != nil {
+		in, out := &in.BackoffCap, &out.BackoffCap
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
+func (in *RetryPolicy) DeepCopy() *RetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RootDeviceHints) DeepCopyInto(out *RootDeviceHints) {
 	*out = *in
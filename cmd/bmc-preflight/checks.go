@@ -0,0 +1,227 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/metal3-io/baremetal-operator/cmd/make-bm-worker/inventory"
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+)
+
+const (
+	statusOK          = "ok"
+	statusFailed      = "failed"
+	statusSkipped     = "skipped"
+	statusUnsupported = "unsupported"
+
+	ipmiPort = "623"
+)
+
+// rmcpPing is an RMCP/ASF presence ping, which any IPMI BMC answers
+// without authentication.
+var rmcpPing = []byte{0x06, 0x00, 0xff, 0x06, 0x00, 0x00, 0x11, 0xbe, 0x80, 0x00, 0x00, 0x00}
+
+// result holds the outcome of the checks of a host.
+type result struct {
+	Name       string            `json:"name"`
+	Address    string            `json:"address"`
+	Driver     string            `json:"driver,omitempty"`
+	Interfaces map[string]string `json:"interfaces,omitempty"`
+	Reachable  string            `json:"reachable,omitempty"`
+	TLS        string            `json:"tls,omitempty"`
+	Auth       string            `json:"authentication,omitempty"`
+	Errors     []string          `json:"errors,omitempty"`
+	OK         bool              `json:"ok"`
+}
+
+func (r *result) fail(format string, args ...interface{}) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+// checker runs the checks of the hosts.
+type checker struct {
+	timeout   time.Duration
+	checkAuth bool
+}
+
+// check parses the BMC address of the host the way the operator does,
+// and checks that the BMC can be reached and, optionally, that the
+// credentials are accepted.
+func (c checker) check(host inventory.Host) (res result) {
+	res = result{Name: host.Name, Address: host.Address}
+	defer func() { res.OK = len(res.Errors) == 0 }()
+
+	access, err := bmc.NewAccessDetails(host.Address, host.DisableCertificateVerification)
+	if err != nil {
+		res.fail("%s", err)
+		return res
+	}
+	res.Driver = access.Driver()
+	res.Interfaces = map[string]string{
+		"bios":       access.BIOSInterface(),
+		"boot":       access.BootInterface(),
+		"management": access.ManagementInterface(),
+		"power":      access.PowerInterface(),
+		"raid":       access.RAIDInterface(),
+		"vendor":     access.VendorInterface(),
+	}
+	if access.NeedsMAC() && host.BootMAC == "" {
+		res.fail("BMC driver %s requires a boot MAC address", access.Type())
+	}
+
+	parsedURL, err := bmc.GetParsedURL(host.Address)
+	if err != nil {
+		res.fail("%s", err)
+		return res
+	}
+
+	if access.Driver() == "ipmi" {
+		res.TLS = statusUnsupported
+		res.Auth = statusUnsupported
+		if err := c.pingIPMI(parsedURL.Hostname(), parsedURL.Port()); err != nil {
+			res.Reachable = statusFailed
+			res.fail("%s", err)
+			return res
+		}
+		res.Reachable = statusOK
+		return res
+	}
+
+	scheme := "https"
+	if strings.HasSuffix(access.Type(), "+http") {
+		scheme = "http"
+	}
+	port := parsedURL.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[scheme]
+	}
+	endpoint := net.JoinHostPort(parsedURL.Hostname(), port)
+
+	conn, err := net.DialTimeout("tcp", endpoint, c.timeout)
+	if err != nil {
+		res.Reachable = statusFailed
+		res.fail("cannot connect to %s: %s", endpoint, err)
+		return res
+	}
+	conn.Close()
+	res.Reachable = statusOK
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: host.DisableCertificateVerification} // #nosec
+	switch {
+	case scheme == "http":
+		res.TLS = statusUnsupported
+	case host.DisableCertificateVerification:
+		res.TLS = statusSkipped
+	default:
+		if err := c.checkTLS(endpoint, parsedURL.Hostname(), tlsConfig); err != nil {
+			res.TLS = statusFailed
+			res.fail("%s", err)
+		} else {
+			res.TLS = statusOK
+		}
+	}
+
+	if !c.checkAuth {
+		res.Auth = statusSkipped
+		return res
+	}
+	driverInfo := access.DriverInfo(bmc.Credentials{Username: host.User, Password: host.Password})
+	address, _ := driverInfo["redfish_address"].(string)
+	systemPath, _ := driverInfo["redfish_system_id"].(string)
+	if address == "" || systemPath == "" {
+		res.Auth = statusUnsupported
+		return res
+	}
+	if err := c.checkRedfishAuth(address+systemPath, host, tlsConfig); err != nil {
+		res.Auth = statusFailed
+		res.fail("%s", err)
+		return res
+	}
+	res.Auth = statusOK
+	return res
+}
+
+// pingIPMI sends an RMCP presence ping to the BMC and waits for the
+// pong.
+func (c checker) pingIPMI(hostname, port string) error {
+	if port == "" {
+		port = ipmiPort
+	}
+	endpoint := net.JoinHostPort(hostname, port)
+	conn, err := net.DialTimeout("udp", endpoint, c.timeout)
+	if err != nil {
+		return fmt.Errorf("cannot reach %s: %w", endpoint, err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return err
+	}
+	if _, err := conn.Write(rmcpPing); err != nil {
+		return fmt.Errorf("cannot reach %s: %w", endpoint, err)
+	}
+	pong := make([]byte, 64)
+	n, err := conn.Read(pong)
+	if err != nil {
+		return fmt.Errorf("no RMCP answer from %s: %w", endpoint, err)
+	}
+	if n < 12 || pong[8] != 0x40 {
+		return fmt.Errorf("unexpected RMCP answer from %s", endpoint)
+	}
+	return nil
+}
+
+// checkTLS completes a TLS handshake with the BMC, verifying its
+// certificate against the system trust store.
+func (c checker) checkTLS(endpoint, hostname string, config *tls.Config) error {
+	config = config.Clone()
+	config.ServerName = hostname
+	dialer := &net.Dialer{Timeout: c.timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", endpoint, config)
+	if err != nil {
+		var authorityErr x509.UnknownAuthorityError
+		var hostnameErr x509.HostnameError
+		var invalidErr x509.CertificateInvalidError
+		if errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+			return fmt.Errorf("the BMC certificate is not trusted, set trusted certificates or disable certificate verification: %w", err)
+		}
+		return fmt.Errorf("TLS handshake with %s failed: %w", endpoint, err)
+	}
+	return conn.Close()
+}
+
+// checkRedfishAuth reads the system from the Redfish API of the BMC
+// with the credentials of the host.
+func (c checker) checkRedfishAuth(systemURL string, host inventory.Host, config *tls.Config) error {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	client := &http.Client{Transport: transport, Timeout: c.timeout}
+
+	req, err := http.NewRequest(http.MethodGet, systemURL, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(host.User, host.Password)
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Redfish request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("the BMC rejected the credentials of user %s", host.User)
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("the system %s does not exist", systemURL)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return fmt.Errorf("Redfish request to %s failed: %s", systemURL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/metal3-io/baremetal-operator/cmd/make-bm-worker/inventory"
+)
+
+func newRedfishServer(t *testing.T) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		if user != "admin" || password != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/redfish/v1/Systems/1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"Id": "1"}`))
+	}))
+	// The untrusted certificate test aborts handshakes.
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newIPMIResponder(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if bytes.Equal(buf[:n], rmcpPing) {
+				pong := append([]byte{}, rmcpPing...)
+				pong[8] = 0x40
+				conn.WriteTo(pong, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestCheck(t *testing.T) {
+	server := newRedfishServer(t)
+	redfishAddress := "redfish+" + server.URL + "/redfish/v1/Systems/1"
+	ipmiAddress := "ipmi://" + newIPMIResponder(t)
+
+	// A closed port, to test unreachable BMCs.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddress := "redfish+https://" + listener.Addr().String() + "/redfish/v1/Systems/1"
+	listener.Close()
+
+	for _, tc := range []struct {
+		Scenario          string
+		Host              inventory.Host
+		ExpectedDriver    string
+		ExpectedReachable string
+		ExpectedTLS       string
+		ExpectedAuth      string
+		ExpectedError     string
+	}{
+		{
+			Scenario: "redfish",
+			Host: inventory.Host{
+				Address: redfishAddress, User: "admin", Password: "password",
+				BootMAC: "00:11:22:33:44:55", DisableCertificateVerification: true,
+			},
+			ExpectedDriver:    "redfish",
+			ExpectedReachable: statusOK,
+			ExpectedTLS:       statusSkipped,
+			ExpectedAuth:      statusOK,
+		},
+		{
+			Scenario: "bad password",
+			Host: inventory.Host{
+				Address: redfishAddress, User: "admin", Password: "wrong",
+				BootMAC: "00:11:22:33:44:55", DisableCertificateVerification: true,
+			},
+			ExpectedDriver:    "redfish",
+			ExpectedReachable: statusOK,
+			ExpectedTLS:       statusSkipped,
+			ExpectedAuth:      statusFailed,
+			ExpectedError:     "the BMC rejected the credentials of user admin",
+		},
+		{
+			Scenario: "untrusted certificate",
+			Host: inventory.Host{
+				Address: redfishAddress, User: "admin", Password: "password",
+				BootMAC: "00:11:22:33:44:55",
+			},
+			ExpectedDriver:    "redfish",
+			ExpectedReachable: statusOK,
+			ExpectedTLS:       statusFailed,
+			ExpectedAuth:      statusFailed,
+			ExpectedError:     "the BMC certificate is not trusted",
+		},
+		{
+			Scenario: "missing boot MAC",
+			Host: inventory.Host{
+				Address: redfishAddress, User: "admin", Password: "password",
+				DisableCertificateVerification: true,
+			},
+			ExpectedDriver:    "redfish",
+			ExpectedReachable: statusOK,
+			ExpectedTLS:       statusSkipped,
+			ExpectedAuth:      statusOK,
+			ExpectedError:     "requires a boot MAC address",
+		},
+		{
+			Scenario: "unreachable",
+			Host: inventory.Host{
+				Address: closedAddress, User: "admin", Password: "password",
+				BootMAC: "00:11:22:33:44:55",
+			},
+			ExpectedDriver:    "redfish",
+			ExpectedReachable: statusFailed,
+			ExpectedError:     "cannot connect to",
+		},
+		{
+			Scenario:      "unknown type",
+			Host:          inventory.Host{Address: "foo://10.0.0.1"},
+			ExpectedError: "Unknown BMC type 'foo'",
+		},
+		{
+			Scenario:          "ipmi",
+			Host:              inventory.Host{Address: ipmiAddress, User: "root", Password: "calvin"},
+			ExpectedDriver:    "ipmi",
+			ExpectedReachable: statusOK,
+			ExpectedTLS:       statusUnsupported,
+			ExpectedAuth:      statusUnsupported,
+		},
+	} {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			c := checker{timeout: 2 * time.Second, checkAuth: true}
+			tc.Host.Name = "host"
+			res := c.check(tc.Host)
+
+			if res.Driver != tc.ExpectedDriver {
+				t.Errorf("expected driver %q but got %q", tc.ExpectedDriver, res.Driver)
+			}
+			if res.Reachable != tc.ExpectedReachable {
+				t.Errorf("expected reachable %q but got %q", tc.ExpectedReachable, res.Reachable)
+			}
+			if res.TLS != tc.ExpectedTLS {
+				t.Errorf("expected TLS %q but got %q", tc.ExpectedTLS, res.TLS)
+			}
+			if res.Auth != tc.ExpectedAuth {
+				t.Errorf("expected auth %q but got %q", tc.ExpectedAuth, res.Auth)
+			}
+			if tc.ExpectedError == "" {
+				if !res.OK || len(res.Errors) != 0 {
+					t.Errorf("unexpected errors %v", res.Errors)
+				}
+				return
+			}
+			if res.OK || !strings.Contains(strings.Join(res.Errors, "; "), tc.ExpectedError) {
+				t.Errorf("expected error %q but got %v", tc.ExpectedError, res.Errors)
+			}
+		})
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	results := []result{
+		{
+			Name: "worker-0", Driver: "redfish",
+			Interfaces: map[string]string{"boot": "ipxe", "power": ""},
+			Reachable:  statusOK, TLS: statusOK, Auth: statusSkipped, OK: true,
+		},
+		{
+			Name:   "worker-1",
+			Errors: []string{"Unknown BMC type 'foo' for address foo://10.0.0.1"},
+		},
+	}
+	buf := &bytes.Buffer{}
+	if err := writeTable(buf, results); err != nil {
+		t.Fatal(err)
+	}
+	expected := `NAME      DRIVER   BOOT  POWER    MANAGEMENT  REACHABLE  TLS  AUTH     RESULT
+worker-0  redfish  ipxe  default  default     ok         ok   skipped  ok
+worker-1  -        -     -        -           -          -    -        Unknown BMC type 'foo' for address foo://10.0.0.1
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected table:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,153 @@
+// bmc-preflight checks the BMCs of the hosts of an inventory before
+// they are enrolled: it reports the driver and interfaces the operator
+// would use for each BMC address, and whether the BMC can be reached
+// and, optionally, accepts the credentials.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/metal3-io/baremetal-operator/cmd/make-bm-worker/inventory"
+)
+
+func main() {
+	var inventoryFile = flag.String("inventory", "", "CSV or YAML inventory of the hosts to check")
+	var username = flag.String("user", "", "username for BMC, when checking a single host")
+	var password = flag.String("password", "", "password for BMC, when checking a single host")
+	var bmcAddress = flag.String("address", "", "address URL for BMC, when checking a single host")
+	var macAddress = flag.String("boot-mac", "", "boot-mac for bootMACAddress, when checking a single host")
+	var disableCertificateVerification = flag.Bool("disableCertificateVerification", false, "will skip certificate validation when true")
+	var checkAuth = flag.Bool("check-auth", false, "check that the BMC accepts the credentials (Redfish only)")
+	var output = flag.String("output", "table", "output format (table or json)")
+	var timeout = flag.Duration("timeout", 5*time.Second, "timeout of each check")
+	var parallel = flag.Int("parallel", 10, "number of hosts checked in parallel")
+
+	flag.Parse()
+
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "Invalid output format %q, use \"table\" or \"json\"\n", *output)
+		os.Exit(1)
+	}
+	if *parallel < 1 {
+		fmt.Fprintf(os.Stderr, "Invalid -parallel value %d\n", *parallel)
+		os.Exit(1)
+	}
+
+	var hosts []inventory.Host
+	if *inventoryFile != "" {
+		var err error
+		hosts, err = inventory.ReadFile(*inventoryFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+			os.Exit(1)
+		}
+	} else {
+		hostName := flag.Arg(0)
+		if hostName == "" {
+			fmt.Fprintf(os.Stderr, "Missing -inventory or name argument\n")
+			os.Exit(1)
+		}
+		if *bmcAddress == "" {
+			fmt.Fprintf(os.Stderr, "Missing -address argument\n")
+			os.Exit(1)
+		}
+		hosts = []inventory.Host{{
+			Name:                           hostName,
+			Address:                        *bmcAddress,
+			User:                           *username,
+			Password:                       *password,
+			BootMAC:                        *macAddress,
+			DisableCertificateVerification: *disableCertificateVerification,
+		}}
+	}
+
+	c := checker{timeout: *timeout, checkAuth: *checkAuth}
+	results := c.checkAll(hosts, *parallel)
+
+	var err error
+	if *output == "json" {
+		err = writeJSON(os.Stdout, results)
+	} else {
+		err = writeTable(os.Stdout, results)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+
+	for _, res := range results {
+		if !res.OK {
+			os.Exit(2)
+		}
+	}
+}
+
+// checkAll checks the hosts, up to parallel at a time, and returns the
+// results in the order of the hosts.
+func (c checker) checkAll(hosts []inventory.Host, parallel int) []result {
+	results := make([]result, len(hosts))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i := range hosts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			results[i] = c.check(hosts[i])
+			<-sem
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+func writeJSON(w io.Writer, results []result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+func writeTable(w io.Writer, results []result) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tDRIVER\tBOOT\tPOWER\tMANAGEMENT\tREACHABLE\tTLS\tAUTH\tRESULT")
+	for _, res := range results {
+		status := "ok"
+		if !res.OK {
+			status = strings.Join(res.Errors, "; ")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			res.Name, orDash(res.Driver),
+			orDefault(res.Driver, res.Interfaces["boot"]),
+			orDefault(res.Driver, res.Interfaces["power"]),
+			orDefault(res.Driver, res.Interfaces["management"]),
+			orDash(res.Reachable), orDash(res.TLS), orDash(res.Auth), status)
+	}
+	return tw.Flush()
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// orDefault returns the name of an interface, which the driver picks
+// when empty.
+func orDefault(driver, value string) string {
+	if driver == "" {
+		return "-"
+	}
+	if value == "" {
+		return "default"
+	}
+	return value
+}
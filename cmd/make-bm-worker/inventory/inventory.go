@@ -0,0 +1,224 @@
+// Package inventory reads the hosts to enroll from a CSV or YAML file,
+// such as an export of a vendor spreadsheet. The columns of the CSV
+// file, or the keys of each item of the YAML list, are named after the
+// make-bm-worker flags setting the same values. The labels and the
+// root device hints of a host are lists of key=value pairs in CSV
+// files, such as "rack=r1,role=worker", and maps in YAML files.
+package inventory
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+// Host is an entry of the inventory.
+type Host struct {
+	Name                           string `json:"name"`
+	Address                        string `json:"address"`
+	User                           string `json:"user"`
+	Password                       string `json:"password"`
+	BootMAC                        string `json:"boot-mac,omitempty"`
+	DisableCertificateVerification bool   `json:"disableCertificateVerification,omitempty"`
+	BootMode                       string `json:"boot-mode,omitempty"`
+	AutomatedCleaningMode          string `json:"automatedCleaningMode,omitempty"`
+	ImageURL                       string `json:"image-url,omitempty"`
+	ImageChecksum                  string `json:"image-checksum,omitempty"`
+	ImageChecksumType              string `json:"image-checksum-type,omitempty"`
+	ImageFormat                    string `json:"image-format,omitempty"`
+
+	Labels          map[string]string               `json:"labels,omitempty"`
+	RootDeviceHints *metal3v1alpha1.RootDeviceHints `json:"root-device-hints,omitempty"`
+}
+
+// columns maps the name of each CSV column to the function setting its
+// value on a host.
+var columns = map[string]func(host *Host, value string) error{
+	"name":     func(host *Host, value string) error { host.Name = value; return nil },
+	"address":  func(host *Host, value string) error { host.Address = value; return nil },
+	"user":     func(host *Host, value string) error { host.User = value; return nil },
+	"password": func(host *Host, value string) error { host.Password = value; return nil },
+	"boot-mac": func(host *Host, value string) error { host.BootMAC = value; return nil },
+	"disableCertificateVerification": func(host *Host, value string) (err error) {
+		if value != "" {
+			host.DisableCertificateVerification, err = strconv.ParseBool(value)
+		}
+		return
+	},
+	"boot-mode":             func(host *Host, value string) error { host.BootMode = value; return nil },
+	"automatedCleaningMode": func(host *Host, value string) error { host.AutomatedCleaningMode = value; return nil },
+	"image-url":             func(host *Host, value string) error { host.ImageURL = value; return nil },
+	"image-checksum":        func(host *Host, value string) error { host.ImageChecksum = value; return nil },
+	"image-checksum-type":   func(host *Host, value string) error { host.ImageChecksumType = value; return nil },
+	"image-format":          func(host *Host, value string) error { host.ImageFormat = value; return nil },
+	"labels": func(host *Host, value string) (err error) {
+		host.Labels, err = parsePairs(value)
+		return
+	},
+	"root-device-hints": func(host *Host, value string) error {
+		pairs, err := parsePairs(value)
+		if err != nil || pairs == nil {
+			return err
+		}
+		host.RootDeviceHints = &metal3v1alpha1.RootDeviceHints{}
+		for key, value := range pairs {
+			if err := setRootDeviceHint(host.RootDeviceHints, key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// setRootDeviceHint sets the value of a root device hint, named as in
+// the BareMetalHost spec.
+func setRootDeviceHint(hints *metal3v1alpha1.RootDeviceHints, key, value string) (err error) {
+	switch key {
+	case "deviceName":
+		hints.DeviceName = value
+	case "hctl":
+		hints.HCTL = value
+	case "model":
+		hints.Model = value
+	case "vendor":
+		hints.Vendor = value
+	case "serialNumber":
+		hints.SerialNumber = value
+	case "wwn":
+		hints.WWN = value
+	case "wwnWithExtension":
+		hints.WWNWithExtension = value
+	case "wwnVendorExtension":
+		hints.WWNVendorExtension = value
+	case "minSizeGigabytes":
+		hints.MinSizeGigabytes, err = strconv.Atoi(value)
+	case "rotational":
+		var rotational bool
+		rotational, err = strconv.ParseBool(value)
+		hints.Rotational = &rotational
+	default:
+		return fmt.Errorf("unknown root device hint %q", key)
+	}
+	return errors.Wrapf(err, "invalid root device hint %s", key)
+}
+
+// parsePairs parses a comma separated list of key=value pairs.
+func parsePairs(value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	pairs := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		key, value, found := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("%q is not a key=value pair", pair)
+		}
+		pairs[key] = strings.TrimSpace(value)
+	}
+	return pairs, nil
+}
+
+// ReadFile reads an inventory, whose format is selected by the
+// extension of the file: .csv for CSV, and .yaml, .yml or .json for
+// YAML.
+func ReadFile(path string) ([]Host, error) {
+	file, err := os.Open(path) // #nosec
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ParseCSV(file)
+	case ".yaml", ".yml", ".json":
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+		return ParseYAML(data)
+	default:
+		return nil, fmt.Errorf("unknown inventory format %q, use .csv, .yaml or .json", filepath.Ext(path))
+	}
+}
+
+// ParseCSV parses a CSV inventory. The first row holds the names of the
+// columns, and each of the following rows a host.
+func ParseCSV(r io.Reader) ([]Host, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the inventory header")
+	}
+	setters := make([]func(*Host, string) error, len(header))
+	for i, name := range header {
+		setter, ok := columns[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown inventory column %q", name)
+		}
+		setters[i] = setter
+	}
+
+	var hosts []Host
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the inventory")
+		}
+		line, _ := reader.FieldPos(0)
+
+		host := Host{}
+		for i, value := range record {
+			if err := setters[i](&host, strings.TrimSpace(value)); err != nil {
+				return nil, errors.Wrapf(err, "line %d: invalid %s", line, header[i])
+			}
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, validate(hosts)
+}
+
+// ParseYAML parses a YAML inventory, holding a list of hosts.
+func ParseYAML(data []byte) ([]Host, error) {
+	var hosts []Host
+	if err := yaml.UnmarshalStrict(data, &hosts); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the inventory")
+	}
+	return hosts, validate(hosts)
+}
+
+func validate(hosts []Host) error {
+	names := make(map[string]bool, len(hosts))
+	for i, host := range hosts {
+		switch {
+		case host.Name == "":
+			return fmt.Errorf("host %d: missing name", i+1)
+		case names[host.Name]:
+			return fmt.Errorf("host %s: duplicate name", host.Name)
+		case host.Address == "":
+			return fmt.Errorf("host %s: missing address", host.Name)
+		case host.User == "":
+			return fmt.Errorf("host %s: missing user", host.Name)
+		case host.Password == "":
+			return fmt.Errorf("host %s: missing password", host.Name)
+		}
+		names[host.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package inventory
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+var expectedHosts = []Host{
+	{
+		Name:     "worker-0",
+		Address:  "redfish://10.0.0.10/redfish/v1/Systems/1",
+		User:     "admin",
+		Password: "password",
+		BootMAC:  "00:11:22:33:44:55",
+		BootMode: "UEFI",
+		ImageURL: "http://example.com/image.qcow2",
+		Labels:   map[string]string{"rack": "r1", "role": "worker"},
+		RootDeviceHints: &metal3v1alpha1.RootDeviceHints{
+			DeviceName:       "/dev/sda",
+			MinSizeGigabytes: 200,
+		},
+	},
+	{
+		Name:                           "worker-1",
+		Address:                        "ipmi://10.0.0.11",
+		User:                           "root",
+		Password:                       "calvin",
+		DisableCertificateVerification: true,
+	},
+}
+
+func TestParseCSV(t *testing.T) {
+	data := `name,address,user,password,boot-mac,disableCertificateVerification,boot-mode,image-url,labels,root-device-hints
+# rack 1
+worker-0,redfish://10.0.0.10/redfish/v1/Systems/1,admin,password,00:11:22:33:44:55,,UEFI,http://example.com/image.qcow2,"rack=r1, role=worker","deviceName=/dev/sda,minSizeGigabytes=200"
+worker-1, ipmi://10.0.0.11,root,calvin,,true,,,,
+`
+	hosts, err := ParseCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(hosts, expectedHosts) {
+		t.Fatalf("unexpected hosts %+v", hosts)
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	data := `
+- name: worker-0
+  address: redfish://10.0.0.10/redfish/v1/Systems/1
+  user: admin
+  password: password
+  boot-mac: 00:11:22:33:44:55
+  boot-mode: UEFI
+  image-url: http://example.com/image.qcow2
+  labels:
+    rack: r1
+    role: worker
+  root-device-hints:
+    deviceName: /dev/sda
+    minSizeGigabytes: 200
+- name: worker-1
+  address: ipmi://10.0.0.11
+  user: root
+  password: calvin
+  disableCertificateVerification: true
+`
+	hosts, err := ParseYAML([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(hosts, expectedHosts) {
+		t.Fatalf("unexpected hosts %+v", hosts)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		Scenario string
+		CSV      string
+		Expected string
+	}{
+		{
+			Scenario: "unknown column",
+			CSV:      "name,address,mac\n",
+			Expected: `unknown inventory column "mac"`,
+		},
+		{
+			Scenario: "invalid boolean",
+			CSV:      "name,address,user,password,disableCertificateVerification\nworker-0,ipmi://10.0.0.10,root,calvin,maybe\n",
+			Expected: "line 2: invalid disableCertificateVerification",
+		},
+		{
+			Scenario: "invalid labels",
+			CSV:      "name,address,user,password,labels\nworker-0,ipmi://10.0.0.10,root,calvin,rack\n",
+			Expected: `line 2: invalid labels: "rack" is not a key=value pair`,
+		},
+		{
+			Scenario: "unknown root device hint",
+			CSV:      "name,address,user,password,root-device-hints\nworker-0,ipmi://10.0.0.10,root,calvin,size=10\n",
+			Expected: `unknown root device hint "size"`,
+		},
+		{
+			Scenario: "invalid root device hint",
+			CSV:      "name,address,user,password,root-device-hints\nworker-0,ipmi://10.0.0.10,root,calvin,rotational=maybe\n",
+			Expected: "invalid root device hint rotational",
+		},
+		{
+			Scenario: "missing address",
+			CSV:      "name,address,user,password\nworker-0,,root,calvin\n",
+			Expected: "host worker-0: missing address",
+		},
+		{
+			Scenario: "duplicate name",
+			CSV:      "name,address,user,password\nworker-0,ipmi://10.0.0.10,root,calvin\nworker-0,ipmi://10.0.0.11,root,calvin\n",
+			Expected: "host worker-0: duplicate name",
+		},
+		{
+			Scenario: "missing column",
+			CSV:      "name,address,user,password\nworker-0,ipmi://10.0.0.10,root\n",
+			Expected: "wrong number of fields",
+		},
+	} {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			_, err := ParseCSV(strings.NewReader(tc.CSV))
+			if err == nil || !strings.Contains(err.Error(), tc.Expected) {
+				t.Fatalf("expected error %q but got %v", tc.Expected, err)
+			}
+		})
+	}
+
+	_, err := ParseYAML([]byte("- name: worker-0\n  adress: ipmi://10.0.0.10\n"))
+	if err == nil || !strings.Contains(err.Error(), `unknown field "adress"`) {
+		t.Fatalf("expected an unknown field error but got %v", err)
+	}
+}
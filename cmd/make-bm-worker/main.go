@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/metal3-io/baremetal-operator/cmd/make-bm-worker/inventory"
 	"github.com/metal3-io/baremetal-operator/cmd/make-bm-worker/templates"
 )
 
@@ -30,9 +31,44 @@ func main() {
 		"image-checksum-type", "", "checksum algorithm for the image (md5, sha256 or sha512)")
 	var imageFormat = flag.String(
 		"image-format", "", "format of the image (raw, qcow2, vdi, vmdk, or live-iso)")
+	var inventoryFile = flag.String(
+		"inventory", "", "CSV or YAML inventory of hosts, using the other flags as defaults")
 
 	flag.Parse()
 
+	defaults := templates.Template{
+		BMCAddress:                     *bmcAddress,
+		DisableCertificateVerification: *disableCertificateVerification,
+		Username:                       *username,
+		Password:                       *password,
+		HardwareProfile:                *hardwareProfile,
+		BootMacAddress:                 *macAddress,
+		BootMode:                       *bootMode,
+		Consumer:                       strings.TrimSpace(*consumer),
+		ConsumerNamespace:              strings.TrimSpace(*consumerNamespace),
+		AutomatedCleaningMode:          *automatedCleaningMode,
+		ImageURL:                       *imageURL,
+		ImageChecksum:                  *imageChecksum,
+		ImageChecksumType:              *imageChecksumType,
+		ImageFormat:                    *imageFormat,
+	}
+
+	if *inventoryFile != "" {
+		if flag.Arg(0) != "" {
+			fmt.Fprintf(os.Stderr, "The name argument can not be used with -inventory\n")
+			os.Exit(1)
+		}
+		// These flags identify a single host.
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "user", "password", "address", "boot-mac", "consumer", "consumer-namespace":
+				fmt.Fprintf(os.Stderr, "The -%s argument can not be used with -inventory\n", f.Name)
+				os.Exit(1)
+			}
+		})
+		os.Exit(renderInventory(*inventoryFile, defaults, *verbose))
+	}
+
 	hostName := flag.Arg(0)
 	if hostName == "" {
 		fmt.Fprintf(os.Stderr, "Missing name argument\n")
@@ -51,58 +87,116 @@ func main() {
 		os.Exit(1)
 	}
 
-	if *bootMode != "" && *bootMode != "UEFI" && *bootMode != "UEFISecureBoot" && *bootMode != "legacy" {
-		fmt.Fprintf(os.Stderr, "Invalid boot mode %q, use \"UEFI\", \"UEFISecureBoot\" or \"legacy\"\n", *bootMode)
+	template := defaults
+	template.Name = strings.Replace(hostName, "_", "-", -1)
+	if err := validateFlags(template); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
 		os.Exit(1)
 	}
+	if *verbose {
+		fmt.Fprintf(os.Stderr, "%v", template)
+	}
 
-	if *automatedCleaningMode != "" && *automatedCleaningMode != "metadata" && *automatedCleaningMode != "disabled" {
-		fmt.Fprintf(os.Stderr, "Invalid automatic cleaning mode %q, use \"metadata\" or \"disabled\"\n", *automatedCleaningMode)
+	result, err := template.Render()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
 		os.Exit(1)
+	} else {
+		fmt.Fprint(os.Stdout, result)
 	}
+}
 
-	switch *imageChecksumType {
-	case "", "md5", "sha256", "sha512":
+// validateFlags checks the values make-bm-worker accepts for the
+// fields of a host.
+func validateFlags(template templates.Template) error {
+	switch template.BootMode {
+	case "", "UEFI", "UEFISecureBoot", "legacy":
 	default:
-		fmt.Fprintf(os.Stderr, "Invalid image checksum type %q, use \"md5\", \"sha256\" or \"sha512\"\n", *imageChecksumType)
-		os.Exit(1)
+		return fmt.Errorf("Invalid boot mode %q, use \"UEFI\", \"UEFISecureBoot\" or \"legacy\"", template.BootMode)
 	}
 
-	switch *imageFormat {
-	case "", "raw", "qcow2", "vdi", "vmdk", "live-iso":
+	switch template.AutomatedCleaningMode {
+	case "", "metadata", "disabled":
 	default:
-		fmt.Fprintf(os.Stderr, "Invalid image format %q, use \"raw\", \"qcow2\", \"vdi\", \"vmdk\" or \"live-iso\"\n", *imageFormat)
-		os.Exit(1)
+		return fmt.Errorf("Invalid automatic cleaning mode %q, use \"metadata\" or \"disabled\"", template.AutomatedCleaningMode)
 	}
 
-	template := templates.Template{
-		Name:                           strings.Replace(hostName, "_", "-", -1),
-		BMCAddress:                     *bmcAddress,
-		DisableCertificateVerification: *disableCertificateVerification,
-		Username:                       *username,
-		Password:                       *password,
-		HardwareProfile:                *hardwareProfile,
-		BootMacAddress:                 *macAddress,
-		Consumer:                       strings.TrimSpace(*consumer),
-		ConsumerNamespace:              strings.TrimSpace(*consumerNamespace),
-		AutomatedCleaningMode:          *automatedCleaningMode,
-		ImageURL:                       *imageURL,
-		ImageChecksum:                  *imageChecksum,
-		ImageChecksumType:              *imageChecksumType,
-		ImageFormat:                    *imageFormat,
+	switch template.ImageChecksumType {
+	case "", "md5", "sha256", "sha512":
+	default:
+		return fmt.Errorf("Invalid image checksum type %q, use \"md5\", \"sha256\" or \"sha512\"", template.ImageChecksumType)
 	}
-	if bootMode != nil {
-		template.BootMode = *bootMode
+
+	switch template.ImageFormat {
+	case "", "raw", "qcow2", "vdi", "vmdk", "live-iso":
+	default:
+		return fmt.Errorf("Invalid image format %q, use \"raw\", \"qcow2\", \"vdi\", \"vmdk\" or \"live-iso\"", template.ImageFormat)
 	}
-	if *verbose {
-		fmt.Fprintf(os.Stderr, "%v", template)
+	return nil
+}
+
+// inventoryTemplate returns the template of a host of the inventory,
+// whose empty fields are taken from the defaults.
+func inventoryTemplate(host inventory.Host, defaults templates.Template) templates.Template {
+	template := defaults
+	template.Name = strings.Replace(host.Name, "_", "-", -1)
+	template.BMCAddress = host.Address
+	template.Username = host.User
+	template.Password = host.Password
+	template.BootMacAddress = host.BootMAC
+	template.DisableCertificateVerification = host.DisableCertificateVerification || defaults.DisableCertificateVerification
+	template.Labels = host.Labels
+	template.RootDeviceHints = host.RootDeviceHints
+	for _, field := range []struct {
+		value  string
+		target *string
+	}{
+		{host.BootMode, &template.BootMode},
+		{host.AutomatedCleaningMode, &template.AutomatedCleaningMode},
+		{host.ImageURL, &template.ImageURL},
+		{host.ImageChecksum, &template.ImageChecksum},
+		{host.ImageChecksumType, &template.ImageChecksumType},
+		{host.ImageFormat, &template.ImageFormat},
+	} {
+		if field.value != "" {
+			*field.target = field.value
+		}
 	}
+	return template
+}
 
-	result, err := template.Render()
+// renderInventory prints the secrets and hosts of an inventory, after
+// validating all of them, and returns the exit status.
+func renderInventory(path string, defaults templates.Template, verbose bool) int {
+	hosts, err := inventory.ReadFile(path)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
-		os.Exit(1)
-	} else {
-		fmt.Fprint(os.Stdout, result)
+		return 1
 	}
+
+	results := make([]string, len(hosts))
+	failed := false
+	for i, host := range hosts {
+		template := inventoryTemplate(host, defaults)
+		if verbose {
+			fmt.Fprintf(os.Stderr, "%v\n", template)
+		}
+		err := validateFlags(template)
+		if err == nil {
+			err = template.Validate()
+		}
+		if err == nil {
+			results[i], err = template.Render()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: host %s: %s\n", host.Name, err)
+			failed = true
+		}
+	}
+	if failed {
+		return 1
+	}
+
+	fmt.Fprint(os.Stdout, strings.Join(results, "\n"))
+	return 0
 }
@@ -3,8 +3,13 @@ package templates
 import (
 	"bytes"
 	"encoding/base64"
+	"strings"
 
 	"github.com/google/safetext/yamltemplate"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 )
 
 var templateBody = `---
@@ -62,6 +67,39 @@ spec:
 {{- end}}
     url: {{ .ImageURL}}
 {{- end}}
+{{- with .RootDeviceHints }}
+  rootDeviceHints:
+{{- if .DeviceName }}
+    deviceName: {{ printf "%q" .DeviceName }}
+{{- end }}
+{{- if .HCTL }}
+    hctl: {{ printf "%q" .HCTL }}
+{{- end }}
+{{- if .Model }}
+    model: {{ printf "%q" .Model }}
+{{- end }}
+{{- if .Vendor }}
+    vendor: {{ printf "%q" .Vendor }}
+{{- end }}
+{{- if .SerialNumber }}
+    serialNumber: {{ printf "%q" .SerialNumber }}
+{{- end }}
+{{- if .MinSizeGigabytes }}
+    minSizeGigabytes: {{ .MinSizeGigabytes }}
+{{- end }}
+{{- if .WWN }}
+    wwn: {{ printf "%q" .WWN }}
+{{- end }}
+{{- if .WWNWithExtension }}
+    wwnWithExtension: {{ printf "%q" .WWNWithExtension }}
+{{- end }}
+{{- if .WWNVendorExtension }}
+    wwnVendorExtension: {{ printf "%q" .WWNVendorExtension }}
+{{- end }}
+{{- if .Rotational }}
+    rotational: {{ .Rotational }}
+{{- end }}
+{{- end }}
 `
 
 // Template holds the arguments to pass to the template.
@@ -81,6 +119,8 @@ type Template struct {
 	ImageChecksum                  string
 	ImageChecksumType              string
 	ImageFormat                    string
+	Labels                         map[string]string
+	RootDeviceHints                *metal3v1alpha1.RootDeviceHints
 }
 
 // EncodedUsername returns the username in the format needed to store
@@ -104,6 +144,35 @@ func encodeToSecret(input string) string {
 func (t Template) Render() (string, error) {
 	buf := new(bytes.Buffer)
 	tmpl := yamltemplate.Must(yamltemplate.New("yaml_out").Parse(templateBody))
-	err := tmpl.Execute(buf, t)
-	return buf.String(), err
+	if err := tmpl.Execute(buf, t); err != nil {
+		return buf.String(), err
+	}
+	if len(t.Labels) == 0 {
+		return buf.String(), nil
+	}
+
+	// The template would reject label keys as an injection, so the
+	// labels are marshalled separately and added to the host metadata.
+	labels, err := yaml.Marshal(map[string]map[string]string{"labels": t.Labels})
+	if err != nil {
+		return "", err
+	}
+	hostMetadata := "kind: BareMetalHost\nmetadata:\n  name: " + t.Name + "\n"
+	labelsBlock := "  " + strings.ReplaceAll(strings.TrimSuffix(string(labels), "\n"), "\n", "\n  ") + "\n"
+	return strings.Replace(buf.String(), hostMetadata, hostMetadata+labelsBlock, 1), nil
+}
+
+// Validate renders the template and checks the BareMetalHost with the
+// rules applied by the admission webhook when the host is created.
+func (t Template) Validate() error {
+	rendered, err := t.Render()
+	if err != nil {
+		return err
+	}
+	documents := strings.Split(rendered, "\n---\n")
+	host := &metal3v1alpha1.BareMetalHost{}
+	if err := yaml.UnmarshalStrict([]byte(documents[len(documents)-1]), host); err != nil {
+		return errors.Wrap(err, "failed to parse the rendered BareMetalHost")
+	}
+	return host.ValidateCreate()
 }
@@ -3,6 +3,8 @@ package templates
 import (
 	"strings"
 	"testing"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 )
 
 func compareStrings(t *testing.T, s1, s2 string) bool {
@@ -321,3 +323,113 @@ spec:
 		t.Fail()
 	}
 }
+
+func TestWithLabelsAndRootDeviceHints(t *testing.T) {
+	rotational := false
+	template := Template{
+		Name:       "hostname",
+		BMCAddress: "bmcAddress",
+		Username:   "username",
+		Password:   "password",
+		Labels:     map[string]string{"rack": "r1", "metal3.io/role": "worker"},
+		RootDeviceHints: &metal3v1alpha1.RootDeviceHints{
+			DeviceName:       "/dev/sda",
+			WWN:              "0x5000c500a1b2c3d4",
+			MinSizeGigabytes: 200,
+			Rotational:       &rotational,
+		},
+	}
+	actual, _ := template.Render()
+	expected := `---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: hostname-bmc-secret
+type: Opaque
+data:
+  username: dXNlcm5hbWU=
+  password: cGFzc3dvcmQ=
+
+---
+apiVersion: metal3.io/v1alpha1
+kind: BareMetalHost
+metadata:
+  name: hostname
+  labels:
+    metal3.io/role: worker
+    rack: r1
+spec:
+  online: true
+  bmc:
+    address: bmcAddress
+    credentialsName: hostname-bmc-secret
+  rootDeviceHints:
+    deviceName: "/dev/sda"
+    minSizeGigabytes: 200
+    wwn: "0x5000c500a1b2c3d4"
+    rotational: false
+`
+	if !compareStrings(t, expected, actual) {
+		t.Fail()
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		Scenario string
+		Template Template
+		Expected string
+	}{
+		{
+			Scenario: "valid",
+			Template: Template{
+				Name:            "worker-0",
+				BMCAddress:      "redfish://10.0.0.10/redfish/v1/Systems/1",
+				BootMacAddress:  "00:11:22:33:44:55",
+				ImageURL:        "http://example.com/image.qcow2",
+				RootDeviceHints: &metal3v1alpha1.RootDeviceHints{DeviceName: "/dev/sda"},
+			},
+		},
+		{
+			Scenario: "missing boot MAC",
+			Template: Template{
+				Name:       "worker-0",
+				BMCAddress: "redfish://10.0.0.10/redfish/v1/Systems/1",
+			},
+			Expected: "BMC driver redfish requires a BootMACAddress value",
+		},
+		{
+			Scenario: "invalid root device hints",
+			Template: Template{
+				Name:            "worker-0",
+				BMCAddress:      "ipmi://10.0.0.10",
+				RootDeviceHints: &metal3v1alpha1.RootDeviceHints{DeviceName: "sda"},
+			},
+			Expected: `Device Name of root device hint must be a /dev/ path, not "sda"`,
+		},
+		{
+			Scenario: "invalid image URL",
+			Template: Template{
+				Name:       "worker-0",
+				BMCAddress: "ipmi://10.0.0.10",
+				ImageURL:   "image.qcow2",
+			},
+			Expected: "Image URL image.qcow2 is an invalid URL",
+		},
+	} {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			tc.Template.Username = "username"
+			tc.Template.Password = "password"
+			err := tc.Template.Validate()
+			if tc.Expected == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.Expected) {
+				t.Fatalf("expected error %q but got %v", tc.Expected, err)
+			}
+		})
+	}
+}
@@ -16,6 +16,11 @@ import (
 // timeout will not exceed (roughly) 8 hours
 const maxBackOffCount = 9
 
+// The delays used when no retry policy sets them
+const defaultBackoffBase = time.Minute
+
+var defaultBackoffCap = defaultBackoffBase * time.Duration(math.Exp2(maxBackOffCount))
+
 func init() {
 	rand.Seed(time.Now().UTC().UnixNano())
 }
@@ -122,6 +127,13 @@ type actionFailed struct {
 	dirty      bool
 	ErrorType  metal3.ErrorType
 	errorCount int
+	// backoffBase and backoffCap come from the retry policy for the
+	// error. The defaults are used when they are not set.
+	backoffBase time.Duration
+	backoffCap  time.Duration
+	// needsOperator is set when the retry policy has been exhausted,
+	// in which case the action is not retried.
+	needsOperator bool
 }
 
 // Distribution sample for errorCount values:
@@ -135,19 +147,29 @@ type actionFailed struct {
 // 8  [2h8m, 4h16m]
 // 9  [4h16m, 8h32m]
 func calculateBackoff(errorCount int) time.Duration {
+	return calculateBackoffWithLimits(errorCount, defaultBackoffBase, defaultBackoffCap)
+}
 
-	if errorCount > maxBackOffCount {
-		errorCount = maxBackOffCount
+// calculateBackoffWithLimits doubles the base delay for each error,
+// up to the cap, and applies a jitter of up to half the delay.
+func calculateBackoffWithLimits(errorCount int, base, backoffCap time.Duration) time.Duration {
+	backOff := float64(base) * math.Exp2(float64(errorCount))
+	if backOff > float64(backoffCap) {
+		backOff = float64(backoffCap)
 	}
-
-	base := math.Exp2(float64(errorCount))
-	backOff := base - (rand.Float64() * base * 0.5) // #nosec
-	backOffDuration := time.Duration(float64(time.Minute) * backOff)
-	return backOffDuration
+	backOff -= rand.Float64() * backOff * 0.5 // #nosec
+	return time.Duration(backOff)
 }
 
 func (r actionFailed) Result() (result reconcile.Result, err error) {
-	result.RequeueAfter = calculateBackoff(r.errorCount)
+	if r.needsOperator {
+		return
+	}
+	if r.backoffBase == 0 || r.backoffCap == 0 {
+		result.RequeueAfter = calculateBackoff(r.errorCount)
+		return
+	}
+	result.RequeueAfter = calculateBackoffWithLimits(r.errorCount, r.backoffBase, r.backoffCap)
 	return
 }
 
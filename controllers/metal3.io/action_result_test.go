@@ -28,3 +28,14 @@ func TestMaxBackoffDuration(t *testing.T) {
 	assert.LessOrEqual(t, calculateBackoff(maxBackOffCount+1).Milliseconds(), maxBackOffDuration)
 	assert.LessOrEqual(t, calculateBackoff(maxBackOffCount+100).Milliseconds(), maxBackOffDuration)
 }
+
+func TestBackoffWithLimits(t *testing.T) {
+
+	base := time.Second * 10
+	backoffCap := time.Minute * 5
+
+	assert.LessOrEqual(t, calculateBackoffWithLimits(1, base, backoffCap), base*2)
+	assert.GreaterOrEqual(t, calculateBackoffWithLimits(1, base, backoffCap), base)
+	assert.LessOrEqual(t, calculateBackoffWithLimits(100, base, backoffCap), backoffCap)
+	assert.GreaterOrEqual(t, calculateBackoffWithLimits(100, base, backoffCap), backoffCap/2)
+}
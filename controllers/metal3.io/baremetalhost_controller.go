@@ -24,6 +24,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -35,17 +36,23 @@ import (
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 	"github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1/profile"
 	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/imagecache"
 	"github.com/metal3-io/baremetal-operator/pkg/imageprovider"
+	"github.com/metal3-io/baremetal-operator/pkg/imageverify"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
 	"github.com/metal3-io/baremetal-operator/pkg/secretutils"
 	"github.com/metal3-io/baremetal-operator/pkg/utils"
@@ -57,8 +64,11 @@ const (
 	preprovImageRetryDelay        = time.Minute * 5
 	provisionerNotReadyRetryDelay = time.Second * 30
 	subResourceNotReadyRetryDelay = time.Second * 60
+	imageCacheRetryDelay          = time.Second * 30
+	ipAllocationRetryDelay        = time.Second * 10
 	rebootAnnotationPrefix        = "reboot.metal3.io"
 	inspectAnnotationPrefix       = "inspect.metal3.io"
+	timeoutAnnotationPrefix       = "timeout.metal3.io"
 	hardwareDetailsAnnotation     = inspectAnnotationPrefix + "/hardwaredetails"
 	clarifySoftPoweroffFailure    = "Continuing with hard poweroff after soft poweroff fails. More details: "
 	hardwareDataFinalizer         = metal3v1alpha1.BareMetalHostFinalizer + "/hardwareData"
@@ -70,6 +80,26 @@ type BareMetalHostReconciler struct {
 	Log                logr.Logger
 	ProvisionerFactory provisioner.Factory
 	APIReader          client.Reader
+	// StateTimeouts holds the maximum time a host may spend in each
+	// provisioning state, unless overridden by an annotation on the
+	// host. States without an entry never time out.
+	StateTimeouts map[metal3v1alpha1.ProvisioningState]time.Duration
+	// RetryPolicies are the policies for retrying failed operations
+	// on hosts that do not set their own.
+	RetryPolicies []metal3v1alpha1.RetryPolicy
+	// CredentialsProviders are the providers hosts may fetch their
+	// BMC credentials from instead of a Secret.
+	CredentialsProviders secretutils.CredentialsProviders
+	// BMCEvents, when set, delivers the hosts to reconcile because
+	// their BMC reported a change.
+	BMCEvents <-chan event.GenericEvent
+	// ImageCache, when set, downloads the images once for all the
+	// hosts, which then download them from the cache.
+	ImageCache *imagecache.Cache
+
+	// certificateExpiryWarnings records when the expiry of the
+	// trusted certificates of each host was last reported.
+	certificateExpiryWarnings sync.Map
 }
 
 // Instead of passing a zillion arguments to the action of a phase,
@@ -82,6 +112,19 @@ type reconcileInfo struct {
 	events            []corev1.Event
 	errorMessage      string
 	postSaveCallbacks []func()
+	retryPolicies     []metal3v1alpha1.RetryPolicy
+	hardwareProfiles  []metal3v1alpha1.HardwareProfile
+}
+
+// getProfile returns the named hardware profile. HardwareProfile
+// resources take precedence over the built-in profiles.
+func (info *reconcileInfo) getProfile(name string) (profile.Profile, error) {
+	for i := range info.hardwareProfiles {
+		if info.hardwareProfiles[i].Name == name {
+			return profile.FromHardwareProfile(&info.hardwareProfiles[i]), nil
+		}
+	}
+	return profile.GetProfile(name)
 }
 
 // match the provisioner.EventPublisher interface
@@ -95,13 +138,19 @@ func (info *reconcileInfo) publishEvent(reason, message string) {
 // +kubebuilder:rbac:groups=metal3.io,resources=preprovisioningimages,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=metal3.io,resources=hardwaredata,verbs=get;list;watch;create;delete;patch;update
 // +kubebuilder:rbac:groups=metal3.io,resources=hardware/finalizers,verbs=update
-// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;update;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get
 // +kubebuilder:rbac:groups="",resources=events,verbs=get;list;watch;create;update;patch
 
 // Allow for managing hostfirmwaresettings and firmwareschema
 //+kubebuilder:rbac:groups=metal3.io,resources=hostfirmwaresettings,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=metal3.io,resources=hostfirmwarecomponents,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=metal3.io,resources=hostfirmwarecomponents/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=metal3.io,resources=firmwareschemas,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups=metal3.io,resources=bmceventsubscriptions,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=metal3.io,resources=hardwareprofiles,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal3.io,resources=ippools,verbs=get;list;watch
 
 // Reconcile handles changes to BareMetalHost resources
 func (r *BareMetalHostReconciler) Reconcile(ctx context.Context, request ctrl.Request) (result ctrl.Result, err error) {
@@ -200,15 +249,42 @@ func (r *BareMetalHostReconciler) Reconcile(ctx context.Context, request ctrl.Re
 		}
 	}
 
+	var bmcCACertificates, bmcCertificateFingerprint []byte
+	if haveCreds {
+		bmcCACertificates, bmcCertificateFingerprint, err = r.bmcTrustedCertificates(host)
+		if err != nil {
+			return r.credentialsErrorResult(err, request, host)
+		}
+	}
+
+	hardwareProfiles := &metal3v1alpha1.HardwareProfileList{}
+	if err = r.List(ctx, hardwareProfiles); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to list hardware profiles")
+	}
+
 	initialState := host.Status.Provisioning.State
 	info := &reconcileInfo{
-		log:            reqLogger.WithValues("provisioningState", initialState),
-		host:           host,
-		request:        request,
-		bmcCredsSecret: bmcCredsSecret,
+		log:              reqLogger.WithValues("provisioningState", initialState),
+		host:             host,
+		request:          request,
+		bmcCredsSecret:   bmcCredsSecret,
+		retryPolicies:    r.RetryPolicies,
+		hardwareProfiles: hardwareProfiles.Items,
+	}
+
+	r.checkBMCCertificatesExpiry(info, bmcCACertificates, time.Now())
+
+	provHostData := provisioner.BuildHostData(*host, *bmcCreds)
+	provHostData.BMCCACertificates = bmcCACertificates
+	provHostData.BMCCertificateFingerprint = bmcCertificateFingerprint
+	prov, err := r.ProvisionerFactory.NewProvisioner(provHostData, info.publishEvent)
+	if errors.As(err, &provisioner.UnknownProvisionerError{}) {
+		// Nothing can be done until the operator is restarted with
+		// the backend registered, which triggers a new reconcile.
+		reqLogger.Info("host selects an unavailable provisioner", "provisioner", host.Spec.Provisioner)
+		info.publishEvent("UnknownProvisioner", err.Error())
+		return ctrl.Result{}, nil
 	}
-
-	prov, err := r.ProvisionerFactory.NewProvisioner(provisioner.BuildHostData(*host, *bmcCreds), info.publishEvent)
 	if err != nil {
 		return ctrl.Result{}, errors.Wrap(err, "failed to create provisioner")
 	}
@@ -322,6 +398,9 @@ func recordActionFailure(info *reconcileInfo, errorType metal3v1alpha1.ErrorType
 		metal3v1alpha1.InspectionError:              "InspectionError",
 		metal3v1alpha1.ProvisioningError:            "ProvisioningError",
 		metal3v1alpha1.PowerManagementError:         "PowerManagementError",
+		metal3v1alpha1.TimeoutError:                 "TimeoutError",
+		metal3v1alpha1.ServicingError:               "ServicingError",
+		metal3v1alpha1.ImageVerificationError:       "ImageVerificationError",
 	}[errorType]
 
 	counter := actionFailureCounters.WithLabelValues(eventType)
@@ -329,7 +408,28 @@ func recordActionFailure(info *reconcileInfo, errorType metal3v1alpha1.ErrorType
 
 	info.publishEvent(eventType, errorMessage)
 
-	return actionFailed{dirty: true, ErrorType: errorType, errorCount: info.host.Status.ErrorCount}
+	policy := retryPolicyFor(info.host, info.retryPolicies, errorType)
+	result := actionFailed{
+		dirty:       true,
+		ErrorType:   errorType,
+		errorCount:  info.host.Status.ErrorCount,
+		backoffBase: policy.BackoffBase.Duration,
+		backoffCap:  policy.BackoffCap.Duration,
+	}
+
+	if retriesExhausted(policy, info.host.Status.ErrorCount) {
+		info.log.Info("giving up after too many failures",
+			"errorType", errorType, "errorCount", info.host.Status.ErrorCount)
+		info.host.Status.OperationalStatus = metal3v1alpha1.OperationalStatusNeedsOperator
+		info.publishEvent("NeedsOperator",
+			fmt.Sprintf("Giving up after %d failures, add the %s annotation to retry",
+				info.host.Status.ErrorCount, retryAnnotation))
+		info.postSaveCallbacks = append(info.postSaveCallbacks,
+			hostsNeedingOperator.WithLabelValues(eventType).Inc)
+		result.needsOperator = true
+	}
+
+	return result
 }
 
 func recordActionDelayed(info *reconcileInfo, state metal3v1alpha1.ProvisioningState) actionResult {
@@ -352,7 +452,7 @@ func (r *BareMetalHostReconciler) credentialsErrorResult(err error, request ctrl
 	// In the event a credential secret is defined, but we cannot find it
 	// we requeue the host as we will not know if they create the secret
 	// at some point in the future.
-	case *ResolveBMCSecretRefError:
+	case *ResolveBMCSecretRefError, *CredentialsProviderError:
 		credentialsMissing.Inc()
 		saveErr := r.setErrorCondition(request, host, metal3v1alpha1.RegistrationError, err.Error())
 		if saveErr != nil {
@@ -360,6 +460,16 @@ func (r *BareMetalHostReconciler) credentialsErrorResult(err error, request ctrl
 		}
 		r.publishEvent(request, host.NewEvent("BMCCredentialError", err.Error()))
 
+		return ctrl.Result{Requeue: true, RequeueAfter: hostErrorRetryDelay}, nil
+	// The trusted certificates may be created or fixed later, and
+	// changes to a ConfigMap or Secret do not trigger a reconcile.
+	case *BMCCertificatesError:
+		saveErr := r.setErrorCondition(request, host, metal3v1alpha1.RegistrationError, err.Error())
+		if saveErr != nil {
+			return ctrl.Result{Requeue: true}, saveErr
+		}
+		r.publishEvent(request, host.NewEvent("BMCCertificateError", err.Error()))
+
 		return ctrl.Result{Requeue: true, RequeueAfter: hostErrorRetryDelay}, nil
 	// If a managed Host is missing a BMC address or secret, or
 	// we have found the secret but it is missing the required fields,
@@ -463,7 +573,13 @@ func hasInspectAnnotation(host *metal3v1alpha1.BareMetalHost) bool {
 
 // clearError removes any existing error message.
 func clearError(host *metal3v1alpha1.BareMetalHost) (dirty bool) {
-	dirty = host.SetOperationalStatus(metal3v1alpha1.OperationalStatusOK)
+	return clearErrorWithStatus(host, metal3v1alpha1.OperationalStatusOK)
+}
+
+// clearErrorWithStatus removes any existing error message and sets the
+// operational status of the host.
+func clearErrorWithStatus(host *metal3v1alpha1.BareMetalHost, status metal3v1alpha1.OperationalStatus) (dirty bool) {
+	dirty = host.SetOperationalStatus(status)
 	var emptyErrType metal3v1alpha1.ErrorType
 	if host.Status.ErrorType != emptyErrType {
 		host.Status.ErrorType = emptyErrType
@@ -485,6 +601,49 @@ func setErrorMessage(host *metal3v1alpha1.BareMetalHost, errType metal3v1alpha1.
 	host.Status.ErrorCount++
 }
 
+// restartOnFailure returns true if the last operation on the host
+// failed with the given error type or timed out, in which case the
+// provisioner should restart the operation.
+func restartOnFailure(host *metal3v1alpha1.BareMetalHost, errType metal3v1alpha1.ErrorType) bool {
+	return host.Status.ErrorType == errType ||
+		host.Status.ErrorType == metal3v1alpha1.TimeoutError
+}
+
+// stateTimeout returns how long the host may stay in the given
+// provisioning state. A timeout set on the host by annotation takes
+// precedence over the operator-wide value. Zero means no timeout.
+func (r *BareMetalHostReconciler) stateTimeout(info *reconcileInfo, state metal3v1alpha1.ProvisioningState) time.Duration {
+	if value, ok := info.host.Annotations[timeoutAnnotationPrefix+"/"+string(state)]; ok {
+		timeout, err := time.ParseDuration(value)
+		if err == nil {
+			return timeout
+		}
+		info.log.Info("ignoring invalid timeout annotation",
+			"state", state, "value", value)
+	}
+	return r.StateTimeouts[state]
+}
+
+// Abort the current operation of a host that has been in the same
+// provisioning state for longer than allowed and mark it as failed.
+func (r *BareMetalHostReconciler) actionTimedOut(prov provisioner.Provisioner, info *reconcileInfo, timeout time.Duration) actionResult {
+	state := info.host.Status.Provisioning.State
+	info.log.Info("operation timed out", "timeout", timeout)
+
+	provResult, err := prov.Abort()
+	if err != nil {
+		return actionError{errors.Wrap(err, "failed to abort operation")}
+	}
+	if provResult.Dirty {
+		return actionContinue{provResult.RequeueAfter}
+	}
+
+	info.postSaveCallbacks = append(info.postSaveCallbacks,
+		stateTimeouts.WithLabelValues(string(state)).Inc)
+	return recordActionFailure(info, metal3v1alpha1.TimeoutError,
+		fmt.Sprintf("host has been %s for longer than %s", state, timeout))
+}
+
 // Manage deletion of the host
 func (r *BareMetalHostReconciler) actionDeleting(prov provisioner.Provisioner, info *reconcileInfo) actionResult {
 	info.log.Info(
@@ -521,12 +680,13 @@ func (r *BareMetalHostReconciler) actionDeleting(prov provisioner.Provisioner, i
 	if err := r.Update(context.Background(), info.host); err != nil {
 		return actionError{errors.Wrap(err, "failed to remove finalizer")}
 	}
+	hardwareHealth.DeletePartialMatch(hostMetricLabels(info.request))
 
 	return deleteComplete{}
 }
 
 func (r *BareMetalHostReconciler) actionUnmanaged(prov provisioner.Provisioner, info *reconcileInfo) actionResult {
-	if info.host.HasBMCDetails() {
+	if info.host.HasBMCDetails() && !awaitingBMCCredentials(info.host) {
 		return actionComplete{}
 	}
 	return actionContinue{unmanagedRetryDelay}
@@ -654,12 +814,13 @@ func (r *BareMetalHostReconciler) preprovImageAvailable(info *reconcileInfo, ima
 	return false, nil
 }
 
-func getHostArchitecture(host *metal3v1alpha1.BareMetalHost) string {
+func getHostArchitecture(info *reconcileInfo) string {
+	host := info.host
 	if host.Status.HardwareDetails != nil &&
 		host.Status.HardwareDetails.CPU.Arch != "" {
 		return host.Status.HardwareDetails.CPU.Arch
 	}
-	if hwprof, err := profile.GetProfile(host.Status.HardwareProfile); err == nil {
+	if hwprof, err := info.getProfile(host.Status.HardwareProfile); err == nil {
 		return hwprof.CPUArch
 	}
 	return ""
@@ -675,9 +836,14 @@ func (r *BareMetalHostReconciler) getPreprovImage(info *reconcileInfo, formats [
 		return nil, imageBuildError{"no acceptable formats for preprovisioning image"}
 	}
 
+	networkDataName, err := r.preprovisioningNetworkDataName(info)
+	if err != nil {
+		return nil, err
+	}
+
 	expectedSpec := metal3v1alpha1.PreprovisioningImageSpec{
-		NetworkDataName: info.host.Spec.PreprovisioningNetworkDataName,
-		Architecture:    getHostArchitecture(info.host),
+		NetworkDataName: networkDataName,
+		Architecture:    getHostArchitecture(info),
 		AcceptFormats:   formats,
 	}
 
@@ -686,7 +852,7 @@ func (r *BareMetalHostReconciler) getPreprovImage(info *reconcileInfo, formats [
 		Name:      info.host.Name,
 		Namespace: info.host.Namespace,
 	}
-	err := r.Get(context.TODO(), key, &preprovImage)
+	err = r.Get(context.TODO(), key, &preprovImage)
 	if k8serrors.IsNotFound(err) {
 		info.log.Info("creating new PreprovisioningImage")
 		preprovImage = metal3v1alpha1.PreprovisioningImage{
@@ -869,12 +1035,96 @@ func (r *BareMetalHostReconciler) registerHost(prov provisioner.Provisioner, inf
 		dirty = clearError(info.host)
 	}
 
+	if updateBMCCapabilities(info.host) {
+		dirty = true
+	}
+
 	if dirty {
 		return actionComplete{}
 	}
 	return nil
 }
 
+// updateBMCCapabilities records in the status the features of the host
+// the driver of its BMC can manage.
+func updateBMCCapabilities(host *metal3v1alpha1.BareMetalHost) (dirty bool) {
+	var capabilities []metal3v1alpha1.BMCCapability
+	bmcAccess, err := bmc.NewAccessDetails(host.Spec.BMC.Address, host.Spec.BMC.DisableCertificateVerification)
+	if err == nil {
+		for _, capability := range bmcAccess.Capabilities().List() {
+			capabilities = append(capabilities, metal3v1alpha1.BMCCapability(capability))
+		}
+	}
+	if reflect.DeepEqual(capabilities, host.Status.BMCCapabilities) {
+		return false
+	}
+	host.Status.BMCCapabilities = capabilities
+	return true
+}
+
+// checkHardwareHealth records the health of the hardware reported by
+// the provisioner along with the power state. The health is unknown
+// when the provisioner cannot determine it, rather than the one last
+// reported. It returns true if the status changed.
+func checkHardwareHealth(info *reconcileInfo, hwState provisioner.HardwareState) (dirty bool) {
+	if hwState.Health == nil {
+		return clearHardwareHealth(info)
+	}
+	return updateHardwareHealth(info, hwState.Health)
+}
+
+// clearHardwareHealth forgets the health of the hardware, which is then
+// reported as unknown. It returns true if the status changed.
+func clearHardwareHealth(info *reconcileInfo) (dirty bool) {
+	hardwareHealth.DeletePartialMatch(hostMetricLabels(info.request))
+	if info.host.Status.HardwareHealth == nil {
+		return false
+	}
+	info.log.Info("hardware health changed", "health", "unknown")
+	info.host.Status.HardwareHealth = nil
+	return true
+}
+
+// updateHardwareHealth records in the status and in the metrics the
+// health of the hardware components of the host reported by the
+// provisioner. It returns true if the status changed.
+func updateHardwareHealth(info *reconcileInfo, components []metal3v1alpha1.ComponentHealth) (dirty bool) {
+	existing := info.host.Status.HardwareHealth
+	changed := existing == nil || !reflect.DeepEqual(existing.Components, components)
+
+	metricLabels := hostMetricLabels(info.request)
+	if changed {
+		// Forget the components the BMC no longer reports
+		hardwareHealth.DeletePartialMatch(metricLabels)
+	}
+	for _, component := range components {
+		metricLabels[labelComponent] = component.Name
+		hardwareHealth.With(metricLabels).Set(healthMetricValue[component.Health])
+	}
+
+	health := metal3v1alpha1.WorstHealth(components)
+	if !changed && existing.Health == health {
+		return false
+	}
+
+	if existing == nil || existing.Health != health {
+		info.log.Info("hardware health changed", "health", health)
+		switch {
+		case health == metal3v1alpha1.HealthWarning, health == metal3v1alpha1.HealthCritical:
+			info.publishEvent("HardwareUnhealthy", fmt.Sprintf("Hardware health is %s", health))
+		case health == metal3v1alpha1.HealthOK && existing != nil:
+			info.publishEvent("HardwareHealthy", fmt.Sprintf("Hardware health is %s", health))
+		}
+	}
+	now := metav1.Now()
+	info.host.Status.HardwareHealth = &metal3v1alpha1.HardwareHealth{
+		Health:      health,
+		Components:  components,
+		LastUpdated: &now,
+	}
+	return true
+}
+
 func updateRootDeviceHints(host *metal3v1alpha1.BareMetalHost, info *reconcileInfo) (dirty bool, err error) {
 	// Ensure the root device hints we're going to use are stored.
 	//
@@ -882,7 +1132,7 @@ func updateRootDeviceHints(host *metal3v1alpha1.BareMetalHost, info *reconcileIn
 	// precedence. Otherwise use the values from the hardware profile.
 	hintSource := host.Spec.RootDeviceHints
 	if hintSource == nil {
-		hwProf, err := profile.GetProfile(host.HardwareProfile())
+		hwProf, err := info.getProfile(host.HardwareProfile())
 		if err != nil {
 			return false, errors.Wrap(err, "failed to update root device hints")
 		}
@@ -915,7 +1165,7 @@ func (r *BareMetalHostReconciler) actionInspecting(prov provisioner.Provisioner,
 		provisioner.InspectData{
 			BootMode: info.host.Status.Provisioning.BootMode,
 		},
-		info.host.Status.ErrorType == metal3v1alpha1.InspectionError,
+		restartOnFailure(info.host, metal3v1alpha1.InspectionError),
 		refresh,
 		forceReboot)
 	if err != nil {
@@ -950,7 +1200,11 @@ func (r *BareMetalHostReconciler) actionInspecting(prov provisioner.Provisioner,
 
 	if provResult.Dirty || details == nil {
 		result := actionContinue{provResult.RequeueAfter}
+		dirty := updateProgress(info, provResult.Progress)
 		if clearError(info.host) {
+			dirty = true
+		}
+		if dirty {
 			return actionUpdate{result}
 		}
 		return result
@@ -959,6 +1213,14 @@ func (r *BareMetalHostReconciler) actionInspecting(prov provisioner.Provisioner,
 	clearError(info.host)
 	info.host.Status.HardwareDetails = details
 
+	if err := r.reportFirmwareVersions(prov, info); err != nil {
+		return actionError{errors.Wrap(err, "could not save the firmware component versions")}
+	}
+
+	if _, err := matchInspectedProfile(info); err != nil {
+		return actionError{errors.Wrap(err, "failed to match hardware profile")}
+	}
+
 	// Create HardwareData with the same name and namesapce as BareMetalHost
 	hardwareData := &metal3v1alpha1.HardwareData{}
 	hardwareDataKey := client.ObjectKey{
@@ -1024,7 +1286,12 @@ func getHardwareProfileName(host *metal3v1alpha1.BareMetalHost) string {
 		// Profile name supplied by user
 		return host.Spec.HardwareProfile
 	}
+	return builtinProfileName(host)
+}
 
+// builtinProfileName returns the name of the built-in profile of a host
+// that does not name one.
+func builtinProfileName(host *metal3v1alpha1.BareMetalHost) string {
 	// FIXME(dhellmann): Insert more robust logic to match
 	// hardware profiles here.
 	if strings.HasPrefix(host.Spec.BMC.Address, "libvirt") {
@@ -1033,11 +1300,30 @@ func getHardwareProfileName(host *metal3v1alpha1.BareMetalHost) string {
 	return profile.DefaultProfileName
 }
 
+// fallbackProfileName returns the profile to use instead of one that
+// no longer exists: the profile named by the user, or else the
+// HardwareProfile matching the inspected hardware, or else the
+// built-in profile.
+func fallbackProfileName(info *reconcileInfo) string {
+	if info.host.Spec.HardwareProfile != "" {
+		return info.host.Spec.HardwareProfile
+	}
+	if best := bestInspectedProfile(info); best != nil {
+		return best.Name
+	}
+	return builtinProfileName(info.host)
+}
+
 func (r *BareMetalHostReconciler) matchProfile(info *reconcileInfo) (dirty bool, err error) {
 	hardwareProfile := getHardwareProfileName(info.host)
+	if _, err := info.getProfile(hardwareProfile); err != nil && hardwareProfile != info.host.Spec.HardwareProfile {
+		// The HardwareProfile matched earlier was deleted
+		info.log.Info("hardware profile no longer exists", "profile", hardwareProfile)
+		hardwareProfile = fallbackProfileName(info)
+	}
 	info.log.Info("using hardware profile", "profile", hardwareProfile)
 
-	_, err = profile.GetProfile(hardwareProfile)
+	_, err = info.getProfile(hardwareProfile)
 	if err != nil {
 		info.log.Info("invalid hardware profile", "profile", hardwareProfile)
 		return
@@ -1058,25 +1344,43 @@ func (r *BareMetalHostReconciler) matchProfile(info *reconcileInfo) (dirty bool,
 	return
 }
 
-func (r *BareMetalHostReconciler) actionPreparing(prov provisioner.Provisioner, info *reconcileInfo) actionResult {
-	info.log.Info("preparing")
+// hostChanges holds the configuration changes to apply to a host with
+// manual cleaning or servicing steps.
+type hostChanges struct {
+	prepareData provisioner.PrepareData
+	bmhDirty    bool
+	hfsDirty    bool
+	hfcDirty    bool
+	hfc         *metal3v1alpha1.HostFirmwareComponents
+}
+
+func (c *hostChanges) dirty() bool {
+	return c.bmhDirty || c.hfsDirty || c.hfcDirty
+}
 
+// getHostChanges collects the RAID, firmware settings and firmware updates
+// that differ from what was last applied to the host. A non-nil
+// actionResult is returned when the changes cannot be determined yet.
+func (r *BareMetalHostReconciler) getHostChanges(info *reconcileInfo, errType metal3v1alpha1.ErrorType) (*hostChanges, actionResult) {
 	bmhDirty, newStatus, err := getHostProvisioningSettings(info.host, info)
 	if err != nil {
-		return actionError{err}
+		return nil, actionError{err}
 	}
 
-	prepareData := provisioner.PrepareData{
-		TargetRAIDConfig: newStatus.Provisioning.RAID.DeepCopy(),
-		ActualRAIDConfig: info.host.Status.Provisioning.RAID.DeepCopy(),
-		RootDeviceHints:  newStatus.Provisioning.RootDeviceHints.DeepCopy(),
-		FirmwareConfig:   newStatus.Provisioning.Firmware.DeepCopy(),
+	changes := &hostChanges{
+		prepareData: provisioner.PrepareData{
+			TargetRAIDConfig: newStatus.Provisioning.RAID.DeepCopy(),
+			ActualRAIDConfig: info.host.Status.Provisioning.RAID.DeepCopy(),
+			RootDeviceHints:  newStatus.Provisioning.RootDeviceHints.DeepCopy(),
+			FirmwareConfig:   newStatus.Provisioning.Firmware.DeepCopy(),
+		},
+		bmhDirty: bmhDirty,
 	}
 	// When manual cleaning fails, we think that the existing RAID configuration
 	// is invalid and needs to be reconfigured.
-	if info.host.Status.ErrorType == metal3v1alpha1.PreparationError {
-		prepareData.ActualRAIDConfig = nil
-		bmhDirty = true
+	if info.host.Status.ErrorType == errType {
+		changes.prepareData.ActualRAIDConfig = nil
+		changes.bmhDirty = true
 	}
 
 	// The hfsDirty flag is used to push the new settings to Ironic as part of the clean steps.
@@ -1086,28 +1390,42 @@ func (r *BareMetalHostReconciler) actionPreparing(prov provisioner.Provisioner,
 
 	if err != nil {
 		// wait until hostFirmwareSettings are ready
-		return actionContinue{subResourceNotReadyRetryDelay}
+		return nil, actionContinue{subResourceNotReadyRetryDelay}
 	}
 	if hfsDirty {
-		prepareData.ActualFirmwareSettings = hfs.Status.Settings.DeepCopy()
-		prepareData.TargetFirmwareSettings = hfs.Spec.Settings.DeepCopy()
+		changes.hfsDirty = true
+		changes.prepareData.ActualFirmwareSettings = hfs.Status.Settings.DeepCopy()
+		changes.prepareData.TargetFirmwareSettings = hfs.Spec.Settings.DeepCopy()
 	}
 
-	provResult, started, err := prov.Prepare(prepareData, bmhDirty || hfsDirty,
-		info.host.Status.ErrorType == metal3v1alpha1.PreparationError)
-
+	// Pending firmware updates are flashed as part of the clean steps and
+	// recorded in the HFC Status once cleaning has started.
+	hfcUpdates, hfc, err := r.getHostFirmwareComponents(info)
 	if err != nil {
-		return actionError{errors.Wrap(err, "error preparing host")}
+		return nil, actionError{err}
 	}
+	changes.hfc = hfc
+	changes.hfcDirty = len(hfcUpdates) != 0
+	changes.prepareData.TargetFirmwareComponents = hfcUpdates
 
+	return changes, nil
+}
+
+// handleHostChangesResult records the outcome of applying the changes to
+// the host. It returns nil once the provisioner has no more work to do.
+func (r *BareMetalHostReconciler) handleHostChangesResult(prov provisioner.Provisioner, info *reconcileInfo, changes *hostChanges, provResult provisioner.Result, started bool, errType metal3v1alpha1.ErrorType) actionResult {
 	if provResult.ErrorMessage != "" {
-		if bmhDirty {
+		if changes.bmhDirty {
 			info.log.Info("handling cleaning error in controller")
 			clearHostProvisioningSettings(info.host)
 		}
-		return recordActionFailure(info, metal3v1alpha1.PreparationError, provResult.ErrorMessage)
+		if err := r.clearFirmwareUpdates(changes.hfc); err != nil {
+			return actionError{errors.Wrap(err, "could not clear the firmware updates")}
+		}
+		return recordActionFailure(info, errType, provResult.ErrorMessage)
 	}
 
+	bmhDirty := changes.bmhDirty
 	if bmhDirty && started {
 		info.log.Info("saving host provisioning settings")
 		_, err := saveHostProvisioningSettings(info.host, info)
@@ -1116,8 +1434,24 @@ func (r *BareMetalHostReconciler) actionPreparing(prov provisioner.Provisioner,
 		}
 	}
 
-	if started && clearError(info.host) {
-		bmhDirty = true
+	if changes.hfcDirty && started {
+		info.log.Info("saving firmware updates")
+		// Record the versions installed before the updates are flashed
+		updateFirmwareVersions(&changes.hfc.Status, firmwareVersions(prov, info))
+		if err := r.saveFirmwareUpdates(changes.hfc); err != nil {
+			return actionError{errors.Wrap(err, "could not save the firmware updates")}
+		}
+	}
+
+	if started {
+		// A host being serviced keeps reporting it until servicing completes
+		status := metal3v1alpha1.OperationalStatusOK
+		if errType == metal3v1alpha1.ServicingError {
+			status = metal3v1alpha1.OperationalStatusServicing
+		}
+		if clearErrorWithStatus(info.host, status) {
+			bmhDirty = true
+		}
 	}
 	if provResult.Dirty {
 		result := actionContinue{provResult.RequeueAfter}
@@ -1127,9 +1461,125 @@ func (r *BareMetalHostReconciler) actionPreparing(prov provisioner.Provisioner,
 		return result
 	}
 
+	if err := r.completeFirmwareUpdates(prov, info, changes.hfc); err != nil {
+		return actionError{errors.Wrap(err, "could not save the firmware component versions")}
+	}
+
+	return nil
+}
+
+func (r *BareMetalHostReconciler) actionPreparing(prov provisioner.Provisioner, info *reconcileInfo) actionResult {
+	info.log.Info("preparing")
+
+	changes, result := r.getHostChanges(info, metal3v1alpha1.PreparationError)
+	if result != nil {
+		return result
+	}
+
+	provResult, started, err := prov.Prepare(changes.prepareData, changes.dirty(),
+		restartOnFailure(info.host, metal3v1alpha1.PreparationError))
+
+	if err != nil {
+		return actionError{errors.Wrap(err, "error preparing host")}
+	}
+
+	if result := r.handleHostChangesResult(prov, info, changes, provResult, started, metal3v1alpha1.PreparationError); result != nil {
+		return result
+	}
+
 	return actionComplete{}
 }
 
+// servicingNeeded reports whether the configuration of a provisioned host
+// differs from what was last applied to it.
+func (r *BareMetalHostReconciler) servicingNeeded(info *reconcileInfo) (bool, error) {
+	if dirty, _, err := getHostProvisioningSettings(info.host, info); err != nil || dirty {
+		return dirty, err
+	}
+
+	if dirty, _, err := r.getHostFirmwareSettings(info); err != nil {
+		// the settings are checked again once they are ready
+		info.log.Info("hostFirmwareSettings not ready", "error", err.Error())
+	} else if dirty {
+		return true, nil
+	}
+
+	updates, _, err := r.getHostFirmwareComponents(info)
+	return len(updates) != 0, err
+}
+
+// Apply RAID, firmware settings and firmware updates to a provisioned
+// host without deprovisioning it.
+func (r *BareMetalHostReconciler) actionServicing(prov provisioner.Provisioner, info *reconcileInfo) actionResult {
+	info.log.Info("servicing")
+
+	if info.host.Status.ErrorType != metal3v1alpha1.ServicingError &&
+		info.host.SetOperationalStatus(metal3v1alpha1.OperationalStatusServicing) {
+		return actionUpdate{}
+	}
+
+	changes, result := r.getHostChanges(info, metal3v1alpha1.ServicingError)
+	if result != nil {
+		return result
+	}
+
+	provResult, started, err := prov.Service(changes.prepareData, changes.dirty(),
+		restartOnFailure(info.host, metal3v1alpha1.ServicingError))
+	if err != nil {
+		return actionError{errors.Wrap(err, "error servicing host")}
+	}
+
+	if result := r.handleHostChangesResult(prov, info, changes, provResult, started, metal3v1alpha1.ServicingError); result != nil {
+		return result
+	}
+
+	info.log.Info("servicing complete")
+	clearError(info.host)
+	info.host.Status.ErrorCount = 0
+	info.publishEvent("ServicingComplete", "Host configuration changes applied")
+	return actionUpdate{}
+}
+
+// matchInspectedProfile selects the HardwareProfile matching the
+// inspected hardware of a host that does not name a profile. When
+// several profiles match, the one with the highest priority is used.
+func matchInspectedProfile(info *reconcileInfo) (dirty bool, err error) {
+	if info.host.Spec.HardwareProfile != "" {
+		return
+	}
+
+	best := bestInspectedProfile(info)
+	if best == nil {
+		return
+	}
+
+	if info.host.SetHardwareProfile(best.Name) {
+		dirty = true
+		info.log.Info("updating hardware profile", "profile", best.Name)
+		info.publishEvent("ProfileSet", fmt.Sprintf("Hardware profile matched: %s", best.Name))
+	}
+
+	hintsDirty, err := updateRootDeviceHints(info.host, info)
+	dirty = dirty || hintsDirty
+	return
+}
+
+// bestInspectedProfile returns the HardwareProfile with the highest
+// priority matching the inspected hardware of the host, or nil.
+func bestInspectedProfile(info *reconcileInfo) (best *metal3v1alpha1.HardwareProfile) {
+	for i := range info.hardwareProfiles {
+		candidate := &info.hardwareProfiles[i]
+		if !candidate.Matches(info.host.Status.HardwareDetails) {
+			continue
+		}
+		if best == nil || candidate.Spec.Priority > best.Spec.Priority ||
+			(candidate.Spec.Priority == best.Spec.Priority && candidate.Name < best.Name) {
+			best = candidate
+		}
+	}
+	return best
+}
+
 // Start/continue provisioning if we need to.
 func (r *BareMetalHostReconciler) actionProvisioning(prov provisioner.Provisioner, info *reconcileInfo) actionResult {
 	hostConf := &hostConfigData{
@@ -1139,7 +1589,7 @@ func (r *BareMetalHostReconciler) actionProvisioning(prov provisioner.Provisione
 	}
 	info.log.Info("provisioning")
 
-	hwProf, err := profile.GetProfile(info.host.HardwareProfile())
+	hwProf, err := info.getProfile(info.host.HardwareProfile())
 	if err != nil {
 		return actionError{errors.Wrap(err,
 			fmt.Sprintf("could not start provisioning with bad hardware profile %s",
@@ -1153,6 +1603,59 @@ func (r *BareMetalHostReconciler) actionProvisioning(prov provisioner.Provisione
 		image = *info.host.Spec.Image.DeepCopy()
 	}
 
+	if info.host.Spec.NetworkData == nil && info.host.Spec.Network != nil {
+		poolAddresses, allocated, err := r.poolAddresses(info.host)
+		if err != nil {
+			if k8serrors.IsNotFound(errors.Cause(err)) {
+				return recordActionFailure(info, metal3v1alpha1.ProvisioningError, err.Error())
+			}
+			return actionError{err}
+		}
+		if !allocated {
+			info.log.Info("waiting for the IP pools to allocate addresses")
+			return actionContinue{ipAllocationRetryDelay}
+		}
+		hostConf.poolAddresses = poolAddresses
+		if _, err := renderNetworkData(info.host, poolAddresses); err != nil {
+			return recordActionFailure(info, metal3v1alpha1.ProvisioningError, err.Error())
+		}
+	}
+
+	pullSecret, err := imagePullSecret(hostConf.secretManager, info.host, &image)
+	if err != nil {
+		return actionError{errors.Wrap(err, "could not read the image pull secret")}
+	}
+
+	if stored, found := storedImageChecksum(info.host); found {
+		image = stored
+	} else if image.ChecksumURL != "" && image.Checksum == "" {
+		if err := resolveImageChecksum(hostConf.secretManager, info.host, &image); err != nil {
+			if errors.As(err, &imageverify.VerificationError{}) {
+				return recordActionFailure(info, metal3v1alpha1.ImageVerificationError, err.Error())
+			}
+			return actionError{errors.Wrap(err, "could not read the image checksum")}
+		}
+		info.log.Info("resolved image checksum", "checksum", image.Checksum)
+		info.host.Status.Provisioning.Image = image
+		return actionUpdate{}
+	}
+
+	var cachedImageURL string
+	if r.ImageCache != nil && info.host.Spec.CustomDeploy == nil {
+		var pending bool
+		cachedImageURL, pending, err = r.ImageCache.Lookup(&image)
+		if err != nil {
+			if errors.As(err, &imageverify.VerificationError{}) {
+				return recordActionFailure(info, metal3v1alpha1.ImageVerificationError, err.Error())
+			}
+			return actionError{errors.Wrap(err, "could not cache the image")}
+		}
+		if pending {
+			info.log.Info("waiting for the image cache to download the image")
+			return actionContinue{imageCacheRetryDelay}
+		}
+	}
+
 	provResult, err := prov.Provision(provisioner.ProvisionData{
 		Image:           image,
 		CustomDeploy:    info.host.Spec.CustomDeploy.DeepCopy(),
@@ -1160,6 +1663,8 @@ func (r *BareMetalHostReconciler) actionProvisioning(prov provisioner.Provisione
 		BootMode:        info.host.Status.Provisioning.BootMode,
 		HardwareProfile: hwProf,
 		RootDeviceHints: info.host.Status.Provisioning.RootDeviceHints.DeepCopy(),
+		ImagePullSecret: pullSecret,
+		CachedImageURL:  cachedImageURL,
 	}, forceReboot)
 	if err != nil {
 		return actionError{errors.Wrap(err, "failed to provision")}
@@ -1182,16 +1687,23 @@ func (r *BareMetalHostReconciler) actionProvisioning(prov provisioner.Provisione
 		// to return false, indicating that it has no more work to
 		// do.
 		result := actionContinue{provResult.RequeueAfter}
+		dirty := updateProgress(info, provResult.Progress)
 		if clearError(info.host) {
+			dirty = true
+		}
+		if dirty {
 			return actionUpdate{result}
 		}
 		return result
 	}
 
 	// If the provisioner had no work, ensure the image settings match.
-	if info.host.Spec.Image != nil && info.host.Status.Provisioning.Image != *(info.host.Spec.Image) {
+	// The image holds the checksum read from its checksum URL, so that
+	// the host can be registered again with the image it runs even if
+	// the checksum file changes.
+	if info.host.Spec.Image != nil && !reflect.DeepEqual(info.host.Status.Provisioning.Image, image) {
 		info.log.Info("updating deployed image in status")
-		info.host.Status.Provisioning.Image = *(info.host.Spec.Image)
+		info.host.Status.Provisioning.Image = image
 	}
 
 	if info.host.Spec.CustomDeploy != nil && (info.host.Status.Provisioning.CustomDeploy == nil || !reflect.DeepEqual(*info.host.Spec.CustomDeploy, *info.host.Status.Provisioning.CustomDeploy)) {
@@ -1204,6 +1716,44 @@ func (r *BareMetalHostReconciler) actionProvisioning(prov provisioner.Provisione
 	return actionComplete{}
 }
 
+// updateProgress stores the progress reported by the provisioner in the
+// host status, and publishes an event when the host enters a new step.
+func updateProgress(info *reconcileInfo, progress *provisioner.Progress) (dirty bool) {
+	if progress == nil {
+		return false
+	}
+
+	newProgress := &metal3v1alpha1.OperationProgress{
+		Step:        progress.Step,
+		CurrentStep: progress.CurrentStep,
+	}
+	if progress.LastHeartbeat != nil {
+		// The API only keeps seconds
+		heartbeat := metav1.NewTime(progress.LastHeartbeat.Truncate(time.Second))
+		newProgress.LastHeartbeat = &heartbeat
+	}
+
+	oldProgress := info.host.Status.Progress
+	if oldProgress != nil && oldProgress.Step == newProgress.Step &&
+		oldProgress.CurrentStep == newProgress.CurrentStep &&
+		oldProgress.LastHeartbeat.Equal(newProgress.LastHeartbeat) {
+		return false
+	}
+
+	if oldProgress == nil || oldProgress.Step != newProgress.Step ||
+		oldProgress.CurrentStep != newProgress.CurrentStep {
+		message := newProgress.Step
+		if newProgress.CurrentStep != "" {
+			message = fmt.Sprintf("%s: %s", message, newProgress.CurrentStep)
+		}
+		info.log.Info("operation progress", "step", newProgress.Step, "currentStep", newProgress.CurrentStep)
+		info.publishEvent("Progress", message)
+	}
+
+	info.host.Status.Progress = newProgress
+	return true
+}
+
 // clearHostProvisioningSettings removes the values related to
 // provisioning that do not trigger re-provisioning from the status
 // fields of a host.
@@ -1240,7 +1790,7 @@ func (r *BareMetalHostReconciler) actionDeprovisioning(prov provisioner.Provisio
 
 	info.log.Info("deprovisioning")
 
-	provResult, err := prov.Deprovision(info.host.Status.ErrorType == metal3v1alpha1.ProvisioningError)
+	provResult, err := prov.Deprovision(restartOnFailure(info.host, metal3v1alpha1.ProvisioningError))
 	if err != nil {
 		return actionError{errors.Wrap(err, "failed to deprovision")}
 	}
@@ -1251,7 +1801,11 @@ func (r *BareMetalHostReconciler) actionDeprovisioning(prov provisioner.Provisio
 
 	if provResult.Dirty {
 		result := actionContinue{provResult.RequeueAfter}
+		dirty := updateProgress(info, provResult.Progress)
 		if clearError(info.host) {
+			dirty = true
+		}
+		if dirty {
 			return actionUpdate{result}
 		}
 		return result
@@ -1283,6 +1837,8 @@ func (r *BareMetalHostReconciler) manageHostPower(prov provisioner.Provisioner,
 		return actionError{errors.Wrap(err, "failed to update the host power status")}
 	}
 
+	healthChanged := checkHardwareHealth(info, hwState)
+
 	if hwState.PoweredOn != nil && *hwState.PoweredOn != info.host.Status.PoweredOn {
 		info.log.Info("updating power status", "discovered", *hwState.PoweredOn)
 		info.host.Status.PoweredOn = *hwState.PoweredOn
@@ -1290,6 +1846,10 @@ func (r *BareMetalHostReconciler) manageHostPower(prov provisioner.Provisioner,
 		return actionUpdate{}
 	}
 
+	if healthChanged {
+		return actionUpdate{}
+	}
+
 	desiredPowerOnState := info.host.Spec.Online
 
 	if !info.host.Status.PoweredOn {
@@ -1534,9 +2094,125 @@ func (r *BareMetalHostReconciler) getHostFirmwareSettings(info *reconcileInfo) (
 	return false, nil, nil
 }
 
+// Get the firmware updates that are pending for the host, if they are valid
+func (r *BareMetalHostReconciler) getHostFirmwareComponents(info *reconcileInfo) (updates []metal3v1alpha1.FirmwareUpdate, hfc *metal3v1alpha1.HostFirmwareComponents, err error) {
+
+	hfc = &metal3v1alpha1.HostFirmwareComponents{}
+	if err = r.Get(context.TODO(), info.request.NamespacedName, hfc); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			// Error reading the object
+			return nil, nil, errors.Wrap(err, "could not load host firmware components")
+		}
+		return nil, nil, nil
+	}
+
+	updates = pendingFirmwareUpdates(hfc)
+	if len(updates) == 0 {
+		return nil, hfc, nil
+	}
+
+	// Only apply updates that have been validated in their current generation
+	valid := meta.FindStatusCondition(hfc.Status.Conditions, string(metal3v1alpha1.HostFirmwareComponentsValid))
+	if valid == nil || valid.ObservedGeneration != hfc.Generation || valid.Status != metav1.ConditionTrue {
+		info.log.Info("hostFirmwareComponents not valid", "namespacename", info.request.NamespacedName)
+		return nil, hfc, nil
+	}
+
+	info.log.Info("hostFirmwareComponents has pending updates", "namespacename", info.request.NamespacedName)
+	return updates, hfc, nil
+}
+
+// reportFirmwareVersions records the installed versions of the firmware
+// components of the host in its HostFirmwareComponents, if it has one.
+func (r *BareMetalHostReconciler) reportFirmwareVersions(prov provisioner.Provisioner, info *reconcileInfo) error {
+	hfc := &metal3v1alpha1.HostFirmwareComponents{}
+	if err := r.Get(context.TODO(), info.request.NamespacedName, hfc); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrap(err, "could not load host firmware components")
+	}
+
+	if !updateFirmwareVersions(&hfc.Status, firmwareVersions(prov, info)) {
+		return nil
+	}
+	t := metav1.Now()
+	hfc.Status.LastUpdated = &t
+	return r.Status().Update(context.TODO(), hfc)
+}
+
+// Record the firmware updates being applied to the host
+func (r *BareMetalHostReconciler) saveFirmwareUpdates(hfc *metal3v1alpha1.HostFirmwareComponents) error {
+	hfc.Status.Updates = append([]metal3v1alpha1.FirmwareUpdate{}, hfc.Spec.Updates...)
+	t := metav1.Now()
+	hfc.Status.LastUpdated = &t
+	return r.Status().Update(context.TODO(), hfc)
+}
+
+// Forget the firmware updates that were being applied to the host, so
+// that they are retried
+func (r *BareMetalHostReconciler) clearFirmwareUpdates(hfc *metal3v1alpha1.HostFirmwareComponents) error {
+	if hfc == nil || len(hfc.Status.Updates) == 0 {
+		return nil
+	}
+	hfc.Status.Updates = nil
+	t := metav1.Now()
+	hfc.Status.LastUpdated = &t
+	return r.Status().Update(context.TODO(), hfc)
+}
+
+// firmwareVersions returns the installed versions of the firmware
+// components of the host. They are only informational, so a failure to
+// read them is logged and no version is returned.
+func firmwareVersions(prov provisioner.Provisioner, info *reconcileInfo) map[string]string {
+	versions, err := prov.GetFirmwareComponents()
+	if err != nil {
+		info.log.Info("could not read the firmware versions", "error", err.Error())
+		return nil
+	}
+	return versions
+}
+
+// Record the versions of the firmware components that have been flashed,
+// and the versions read back from the host
+func (r *BareMetalHostReconciler) completeFirmwareUpdates(prov provisioner.Provisioner, info *reconcileInfo, hfc *metal3v1alpha1.HostFirmwareComponents) error {
+	if hfc == nil {
+		return nil
+	}
+
+	dirty := false
+	t := metav1.Now()
+	for _, update := range hfc.Status.Updates {
+		component := findFirmwareComponent(hfc.Status.Components, update.Component)
+		if component == nil {
+			hfc.Status.Components = append(hfc.Status.Components,
+				metal3v1alpha1.FirmwareComponentStatus{Component: update.Component})
+			component = &hfc.Status.Components[len(hfc.Status.Components)-1]
+		}
+		if component.LastVersionFlashed == update.Version {
+			continue
+		}
+		// The installed version is unknown unless the host reports it
+		component.CurrentVersion = ""
+		component.LastVersionFlashed = update.Version
+		component.UpdatedAt = &t
+		dirty = true
+	}
+	if dirty && updateFirmwareVersions(&hfc.Status, firmwareVersions(prov, info)) {
+		dirty = true
+	}
+
+	if !dirty {
+		return nil
+	}
+	hfc.Status.LastUpdated = &t
+	return r.Status().Update(context.TODO(), hfc)
+}
+
 func (r *BareMetalHostReconciler) saveHostStatus(host *metal3v1alpha1.BareMetalHost) error {
 	t := metav1.Now()
 	host.Status.LastUpdated = &t
+	updateHostConditions(host)
 
 	return r.Status().Update(context.TODO(), host)
 }
@@ -1639,6 +2315,10 @@ func credentialsFromSecret(bmcCredsSecret *corev1.Secret) *bmc.Credentials {
 // to use the credentials.
 func (r *BareMetalHostReconciler) buildAndValidateBMCCredentials(request ctrl.Request, host *metal3v1alpha1.BareMetalHost) (bmcCreds *bmc.Credentials, bmcCredsSecret *corev1.Secret, err error) {
 
+	if host.Spec.BMC.CredentialsProvider != "" {
+		return r.buildBMCCredentialsFromProvider(host)
+	}
+
 	// Retrieve the BMC secret from Kubernetes for this host
 	bmcCredsSecret, err = r.getBMCSecretAndSetOwner(request, host)
 	if err != nil {
@@ -1662,6 +2342,52 @@ func (r *BareMetalHostReconciler) buildAndValidateBMCCredentials(request ctrl.Re
 	return bmcCreds, bmcCredsSecret, nil
 }
 
+// Fetch the credentials for the management controller from the
+// credentials provider of the host. The credentials are returned with
+// a Secret that is not stored in the cluster, and only carries the
+// version reported by the provider, so that changes to the credentials
+// are tracked through GoodCredentials and TriedCredentials as for a
+// real Secret.
+func (r *BareMetalHostReconciler) buildBMCCredentialsFromProvider(host *metal3v1alpha1.BareMetalHost) (*bmc.Credentials, *corev1.Secret, error) {
+	if host.Spec.BMC.CredentialsName == "" {
+		return nil, nil, &EmptyBMCSecretError{message: "The BMC credentials name is empty"}
+	}
+	// The name locates the credentials of the host within its
+	// namespace, so it must not be able to reach outside of it.
+	if msgs := validation.IsDNS1123Subdomain(host.Spec.BMC.CredentialsName); len(msgs) > 0 {
+		return nil, nil, &CredentialsProviderError{message: fmt.Sprintf("invalid credentials name %q: %s",
+			host.Spec.BMC.CredentialsName, strings.Join(msgs, ", "))}
+	}
+
+	providerName := host.Spec.BMC.CredentialsProvider
+	provider, ok := r.CredentialsProviders[providerName]
+	if !ok {
+		return nil, nil, &CredentialsProviderError{message: fmt.Sprintf("unknown credentials provider %q", providerName)}
+	}
+
+	creds, version, err := provider.Credentials(host.Namespace, host.Spec.BMC.CredentialsName)
+	if err != nil {
+		return nil, nil, &CredentialsProviderError{message: fmt.Sprintf("%s: %s", providerName, err)}
+	}
+
+	bmcCredsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            host.Spec.BMC.CredentialsName,
+			Namespace:       host.Namespace,
+			ResourceVersion: providerName + ":" + version,
+		},
+	}
+
+	if host.Spec.BMC.Address == "" {
+		return nil, nil, &EmptyBMCAddressError{message: "Missing BMC connection detail 'Address'"}
+	}
+
+	if err := creds.Validate(); err != nil {
+		return nil, bmcCredsSecret, err
+	}
+	return &creds, bmcCredsSecret, nil
+}
+
 func (r *BareMetalHostReconciler) publishEvent(request ctrl.Request, event corev1.Event) {
 	reqLogger := r.Log.WithValues("baremetalhost", request.NamespacedName)
 	reqLogger.Info("publishing event", "reason", event.Reason, "message", event.Message)
@@ -1747,9 +2473,38 @@ func (r *BareMetalHostReconciler) SetupWithManager(mgr ctrl.Manager, preprovImgE
 		controller.Owns(&metal3v1alpha1.PreprovisioningImage{})
 	}
 
+	if r.BMCEvents != nil {
+		controller.Watches(&source.Channel{Source: r.BMCEvents}, &handler.EnqueueRequestForObject{})
+	}
+
+	// The hardware profiles are read from the cache of this watch
+	controller.Watches(&source.Kind{Type: &metal3v1alpha1.HardwareProfile{}},
+		handler.EnqueueRequestsFromMapFunc(r.hostsForHardwareProfile))
+
 	return controller.Complete(r)
 }
 
+// hostsForHardwareProfile enqueues the hosts using a hardware profile,
+// so that they pick up its changes, or another profile once it is
+// deleted.
+func (r *BareMetalHostReconciler) hostsForHardwareProfile(obj client.Object) []ctrl.Request {
+	hosts := &metal3v1alpha1.BareMetalHostList{}
+	if err := r.List(context.Background(), hosts); err != nil {
+		r.Log.Error(err, "failed to list hosts", "hardwareProfile", obj.GetName())
+		return nil
+	}
+
+	var requests []ctrl.Request
+	for _, host := range hosts.Items {
+		if host.Status.HardwareProfile == obj.GetName() {
+			requests = append(requests, ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: host.Namespace, Name: host.Name},
+			})
+		}
+	}
+	return requests
+}
+
 func (r *BareMetalHostReconciler) reconciletHostData(ctx context.Context, host *metal3v1alpha1.BareMetalHost, request ctrl.Request) (result ctrl.Result, err error) {
 
 	reqLogger := r.Log.WithValues("baremetalhost", request.NamespacedName)
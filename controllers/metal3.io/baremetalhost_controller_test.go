@@ -3,17 +3,24 @@ package controllers
 import (
 	"context"
 	goctx "context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/stretchr/testify/assert"
 
 	corev1 "k8s.io/api/core/v1"
 
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -29,6 +36,8 @@ import (
 
 	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/imagecache"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner/fixture"
 	"github.com/metal3-io/baremetal-operator/pkg/secretutils"
 	"github.com/metal3-io/baremetal-operator/pkg/utils"
@@ -481,6 +490,43 @@ func TestPause(t *testing.T) {
 	)
 }
 
+// TestProvisionerSelection ensures that hosts are managed by the
+// provisioner they select.
+func TestProvisionerSelection(t *testing.T) {
+	defaultFixture := &fixture.Fixture{}
+	defaultFixture.SetValidateError("managed by the wrong provisioner")
+	registry := provisioner.NewRegistry()
+	assert.NoError(t, registry.Register("default", defaultFixture))
+	assert.NoError(t, registry.Register("other", &fixture.Fixture{}))
+
+	host := newDefaultHost(t)
+	host.Spec.Provisioner = "other"
+	r := newTestReconciler(host)
+	r.ProvisionerFactory = registry
+
+	waitForProvisioningState(t, r, host, metal3v1alpha1.StatePreparing)
+	assert.Empty(t, host.Status.ErrorMessage)
+}
+
+// TestUnknownProvisioner ensures that hosts selecting a provisioner
+// that is not registered are left alone.
+func TestUnknownProvisioner(t *testing.T) {
+	registry := provisioner.NewRegistry()
+	assert.NoError(t, registry.Register("default", &fixture.Fixture{}))
+
+	host := newDefaultHost(t)
+	host.Spec.Provisioner = "missing"
+	r := newTestReconciler(host)
+	r.ProvisionerFactory = registry
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return !result.Requeue && result.RequeueAfter == 0
+		},
+	)
+	assert.Equal(t, metal3v1alpha1.StateNone, host.Status.Provisioning.State)
+}
+
 // TestInspectDisabled ensures that Inspection is skipped when disabled
 func TestInspectDisabled(t *testing.T) {
 	host := newDefaultHost(t)
@@ -871,6 +917,69 @@ func TestUpdateCredentialsSecretSuccessFields(t *testing.T) {
 
 }
 
+// TestBMCCapabilities ensures that the capabilities of the BMC driver
+// are published in the status once the host is registered.
+func TestBMCCapabilities(t *testing.T) {
+	host := newDefaultHost(t)
+	r := newTestReconciler(host)
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return len(host.Status.BMCCapabilities) != 0
+		},
+	)
+	assert.Equal(t, []metal3v1alpha1.BMCCapability{"SoftPowerOff"}, host.Status.BMCCapabilities)
+}
+
+// TestHardwareHealth ensures that the health of the hardware reported
+// by the provisioner is recorded in the status and conditions.
+func TestHardwareHealth(t *testing.T) {
+	fix := &fixture.Fixture{
+		Health: []metal3v1alpha1.ComponentHealth{
+			{Name: "System", Health: metal3v1alpha1.HealthOK},
+			{Name: "Chassis", Health: metal3v1alpha1.HealthCritical},
+		},
+	}
+	host := newDefaultHost(t)
+	r := newTestReconcilerWithFixture(fix, host)
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.HardwareHealth != nil &&
+				meta.FindStatusCondition(host.Status.Conditions, string(metal3v1alpha1.HostHealthy)) != nil
+		},
+	)
+	assert.Equal(t, metal3v1alpha1.HealthCritical, host.Status.HardwareHealth.Health)
+	assert.Equal(t, fix.Health, host.Status.HardwareHealth.Components)
+	assert.NotNil(t, host.Status.HardwareHealth.LastUpdated)
+	cond := meta.FindStatusCondition(host.Status.Conditions, string(metal3v1alpha1.HostHealthy))
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, string(reasonHostHealthCritical), cond.Reason)
+	assert.Equal(t, "Chassis is Critical", cond.Message)
+
+	// The health is updated along with the power state
+	fix.Health = []metal3v1alpha1.ComponentHealth{
+		{Name: "System", Health: metal3v1alpha1.HealthOK},
+		{Name: "Chassis", Health: metal3v1alpha1.HealthOK},
+	}
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return meta.IsStatusConditionTrue(host.Status.Conditions, string(metal3v1alpha1.HostHealthy))
+		},
+	)
+	assert.Equal(t, metal3v1alpha1.HealthOK, host.Status.HardwareHealth.Health)
+
+	// The health is unknown when the provisioner cannot determine it
+	fix.Health = nil
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.HardwareHealth == nil
+		},
+	)
+	cond = meta.FindStatusCondition(host.Status.Conditions, string(metal3v1alpha1.HostHealthy))
+	assert.Equal(t, metav1.ConditionUnknown, cond.Status)
+}
+
 // TestUpdateGoodCredentialsOnNewSecret ensures that the
 // GoodCredentials fields are updated when the secret for a host is
 // changed to another secret that is also good.
@@ -1310,6 +1419,52 @@ func TestProvision(t *testing.T) {
 	)
 }
 
+// TestProvisionImageCache ensures that hosts are provisioned from the
+// image cache once it has downloaded the image.
+func TestProvisionImageCache(t *testing.T) {
+	content := []byte("the content of the image")
+	checksum := sha256.Sum256(content)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer upstream.Close()
+
+	host := newDefaultHost(t)
+	host.Spec.Image = &metal3v1alpha1.Image{
+		URL:          upstream.URL + "/image.qcow2",
+		Checksum:     hex.EncodeToString(checksum[:]),
+		ChecksumType: metal3v1alpha1.SHA256,
+	}
+	host.Spec.Online = true
+	fix := fixture.Fixture{}
+	r := newTestReconcilerWithFixture(&fix, host)
+	cache, err := imagecache.NewCache(t.TempDir(), "", "http://image-cache:8090", r.Client, logr.Discard())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.ImageCache = cache
+
+	// Provisioning waits for the download of the image
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.Provisioning.State == metal3v1alpha1.StateProvisioning
+		},
+	)
+	assert.Empty(t, fix.CachedImageURL)
+	assert.Eventually(t, func() bool {
+		_, pending, err := cache.Lookup(host.Spec.Image)
+		return !pending && err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.Provisioning.Image.URL != ""
+		},
+	)
+	assert.Regexp(t, "^http://image-cache:8090/images/[0-9a-f]+/image.qcow2$", fix.CachedImageURL)
+	assert.Equal(t, host.Spec.Image.URL, host.Status.Provisioning.Image.URL)
+}
+
 // TestProvisionCustomDeploy ensures that the Provisioning.CustomDeploy portion
 // of the status block is filled in for provisioned hosts.
 func TestProvisionCustomDeploy(t *testing.T) {
@@ -2206,6 +2361,103 @@ func TestCredentialsFromSecret(t *testing.T) {
 	}
 }
 
+type stubCredentialsProvider struct {
+	creds   bmc.Credentials
+	version string
+	err     error
+}
+
+func (p stubCredentialsProvider) Credentials(namespace, name string) (bmc.Credentials, string, error) {
+	return p.creds, p.version, p.err
+}
+
+func TestBuildBMCCredentialsFromProvider(t *testing.T) {
+	testCases := []struct {
+		Scenario        string
+		Provider        string
+		CredentialsName string
+		Providers       secretutils.CredentialsProviders
+		ExpectedVersion string
+		ExpectedError   string
+	}{
+		{
+			Scenario: "valid",
+			Provider: "vault",
+			Providers: secretutils.CredentialsProviders{
+				"vault": stubCredentialsProvider{
+					creds:   bmc.Credentials{Username: "admin", Password: "password"},
+					version: "3",
+				},
+			},
+			ExpectedVersion: "vault:3",
+		},
+		{
+			Scenario:      "unknown provider",
+			Provider:      "vault",
+			Providers:     secretutils.CredentialsProviders{},
+			ExpectedError: "BMC credentials provider failed unknown credentials provider \"vault\"",
+		},
+		{
+			Scenario: "provider failure",
+			Provider: "vault",
+			Providers: secretutils.CredentialsProviders{
+				"vault": stubCredentialsProvider{
+					err: secretutils.CredentialsNotFoundError{Namespace: namespace, Name: defaultSecretName},
+				},
+			},
+			ExpectedError: "BMC credentials provider failed vault: no credentials test-namespace/bmc-creds-valid found",
+		},
+		{
+			Scenario: "invalid credentials",
+			Provider: "vault",
+			Providers: secretutils.CredentialsProviders{
+				"vault": stubCredentialsProvider{
+					creds:   bmc.Credentials{Username: "admin"},
+					version: "1",
+				},
+			},
+			ExpectedError: "Validation error with BMC credentials: Missing BMC connection details 'password' in credentials",
+		},
+		{
+			Scenario:        "credentials name outside of the namespace",
+			Provider:        "vault",
+			CredentialsName: "../other-namespace/host",
+			Providers: secretutils.CredentialsProviders{
+				"vault": stubCredentialsProvider{
+					creds:   bmc.Credentials{Username: "admin", Password: "password"},
+					version: "3",
+				},
+			},
+			ExpectedError: "BMC credentials provider failed invalid credentials name \"../other-namespace/host\": " +
+				"a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', " +
+				"and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is " +
+				"'[a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			host := newDefaultHost(t)
+			host.Spec.BMC.CredentialsProvider = tc.Provider
+			if tc.CredentialsName != "" {
+				host.Spec.BMC.CredentialsName = tc.CredentialsName
+			}
+			r := newTestReconciler(host)
+			r.CredentialsProviders = tc.Providers
+
+			creds, secret, err := r.buildAndValidateBMCCredentials(newRequest(host), host)
+			if tc.ExpectedError != "" {
+				assert.EqualError(t, err, tc.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "admin", creds.Username)
+			assert.Equal(t, tc.ExpectedVersion, secret.ResourceVersion)
+			assert.Equal(t, defaultSecretName, secret.Name)
+		})
+	}
+}
+
 func TestGetHardwareProfileName(t *testing.T) {
 	testCases := []struct {
 		Scenario      string
@@ -2259,14 +2511,214 @@ func TestGetHardwareProfileName(t *testing.T) {
 
 func TestGetHostArchitecture(t *testing.T) {
 	host := newDefaultHost(t)
-	assert.Equal(t, "x86_64", getHostArchitecture(host))
+	info := &reconcileInfo{host: host}
+	assert.Equal(t, "x86_64", getHostArchitecture(info))
+
+	host.Status.HardwareProfile = "arm"
+	info.hardwareProfiles = []metal3v1alpha1.HardwareProfile{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "arm"},
+			Spec:       metal3v1alpha1.HardwareProfileSpec{CPUArch: "aarch64"},
+		},
+	}
+	assert.Equal(t, "aarch64", getHostArchitecture(info))
 
+	host.Status.HardwareProfile = ""
 	host.Status.HardwareDetails = &metal3v1alpha1.HardwareDetails{
 		CPU: metal3v1alpha1.CPU{
 			Arch: "aarch64",
 		},
 	}
-	assert.Equal(t, "aarch64", getHostArchitecture(host))
+	assert.Equal(t, "aarch64", getHostArchitecture(info))
+}
+
+func TestMatchInspectedProfile(t *testing.T) {
+	hardwareProfiles := []metal3v1alpha1.HardwareProfile{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "manual"},
+			Spec: metal3v1alpha1.HardwareProfileSpec{
+				RootDeviceHints: &metal3v1alpha1.RootDeviceHints{DeviceName: "/dev/sdz"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "dell-generic"},
+			Spec: metal3v1alpha1.HardwareProfileSpec{
+				RootDeviceHints: &metal3v1alpha1.RootDeviceHints{DeviceName: "/dev/sda"},
+				Match:           &metal3v1alpha1.HardwareProfileMatch{Manufacturer: "Dell*"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "dell-r640"},
+			Spec: metal3v1alpha1.HardwareProfileSpec{
+				RootDeviceHints: &metal3v1alpha1.RootDeviceHints{HCTL: "0:2:0:0"},
+				Match: &metal3v1alpha1.HardwareProfileMatch{
+					Manufacturer: "Dell*",
+					ProductName:  "PowerEdge R640",
+				},
+				Priority: 10,
+			},
+		},
+	}
+
+	testCases := []struct {
+		Scenario        string
+		ProductName     string
+		SpecProfile     string
+		ExpectedProfile string
+		ExpectedHints   *metal3v1alpha1.RootDeviceHints
+		ExpectedDirty   bool
+	}{
+		{
+			Scenario:        "highest priority",
+			ProductName:     "PowerEdge R640",
+			ExpectedProfile: "dell-r640",
+			ExpectedHints:   &metal3v1alpha1.RootDeviceHints{HCTL: "0:2:0:0"},
+			ExpectedDirty:   true,
+		},
+		{
+			Scenario:        "generic",
+			ProductName:     "PowerEdge R740",
+			ExpectedProfile: "dell-generic",
+			ExpectedHints:   &metal3v1alpha1.RootDeviceHints{DeviceName: "/dev/sda"},
+			ExpectedDirty:   true,
+		},
+		{
+			Scenario:        "named by the user",
+			ProductName:     "PowerEdge R640",
+			SpecProfile:     "manual",
+			ExpectedProfile: "manual",
+			ExpectedDirty:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			host := newDefaultHost(t)
+			host.Spec.HardwareProfile = tc.SpecProfile
+			host.Spec.RootDeviceHints = nil
+			host.Status.HardwareProfile = ""
+			host.Status.HardwareProfile = getHardwareProfileName(host)
+			host.Status.HardwareDetails = &metal3v1alpha1.HardwareDetails{
+				SystemVendor: metal3v1alpha1.HardwareSystemVendor{
+					Manufacturer: "Dell Inc.",
+					ProductName:  tc.ProductName,
+				},
+			}
+			info := &reconcileInfo{
+				log:              logf.Log.WithName("test"),
+				host:             host,
+				hardwareProfiles: hardwareProfiles,
+			}
+
+			dirty, err := matchInspectedProfile(info)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.ExpectedDirty, dirty)
+			assert.Equal(t, tc.ExpectedProfile, host.Status.HardwareProfile)
+			if tc.ExpectedHints != nil {
+				assert.Equal(t, tc.ExpectedHints, host.Status.Provisioning.RootDeviceHints)
+			}
+		})
+	}
+}
+
+// TestInspectMatchesHardwareProfile ensures that a host is assigned
+// the HardwareProfile matching its hardware once inspected.
+func TestInspectMatchesHardwareProfile(t *testing.T) {
+	minRAM := 64 * 1024
+	hwProfile := &metal3v1alpha1.HardwareProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "large"},
+		Spec: metal3v1alpha1.HardwareProfileSpec{
+			RootDeviceHints: &metal3v1alpha1.RootDeviceHints{DeviceName: "/dev/nvme0n1"},
+			Match: &metal3v1alpha1.HardwareProfileMatch{
+				MinRAMMebibytes: &minRAM,
+			},
+		},
+	}
+	host := newDefaultHost(t)
+	host.Spec.HardwareProfile = ""
+	host.Spec.RootDeviceHints = nil
+	host.Status.HardwareProfile = ""
+	r := newTestReconciler(host, hwProfile)
+
+	waitForProvisioningState(t, r, host, metal3v1alpha1.StatePreparing)
+	assert.Equal(t, "large", host.Status.HardwareProfile)
+	assert.Equal(t, "/dev/nvme0n1", host.Status.Provisioning.RootDeviceHints.DeviceName)
+}
+
+// TestMatchProfileDeleted ensures that a host whose matched
+// HardwareProfile was deleted falls back to another profile, while a
+// missing profile named by the user is an error.
+func TestMatchProfileDeleted(t *testing.T) {
+	small := metal3v1alpha1.HardwareProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "small"},
+		Spec: metal3v1alpha1.HardwareProfileSpec{
+			RootDeviceHints: &metal3v1alpha1.RootDeviceHints{DeviceName: "/dev/sdb"},
+			Match:           &metal3v1alpha1.HardwareProfileMatch{Manufacturer: "Dell*"},
+		},
+	}
+
+	testCases := []struct {
+		Scenario        string
+		SpecProfile     string
+		Inspected       bool
+		ExpectedProfile string
+		ExpectedError   bool
+	}{
+		{
+			Scenario:        "built-in profile",
+			ExpectedProfile: "unknown",
+		},
+		{
+			Scenario:        "matched again",
+			Inspected:       true,
+			ExpectedProfile: "small",
+		},
+		{
+			Scenario:      "named by the user",
+			SpecProfile:   "large",
+			ExpectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			host := newDefaultHost(t)
+			host.Spec.HardwareProfile = tc.SpecProfile
+			host.Spec.RootDeviceHints = nil
+			host.Status.HardwareProfile = "large"
+			if tc.Inspected {
+				host.Status.HardwareDetails = &metal3v1alpha1.HardwareDetails{
+					SystemVendor: metal3v1alpha1.HardwareSystemVendor{Manufacturer: "Dell Inc."},
+				}
+			}
+			info := &reconcileInfo{
+				log:              logf.Log.WithName("test"),
+				host:             host,
+				hardwareProfiles: []metal3v1alpha1.HardwareProfile{small},
+			}
+
+			dirty, err := newTestReconciler().matchProfile(info)
+			if tc.ExpectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, dirty)
+			assert.Equal(t, tc.ExpectedProfile, host.Status.HardwareProfile)
+		})
+	}
+}
+
+func TestHostsForHardwareProfile(t *testing.T) {
+	matched := newDefaultNamedHost("matched", t)
+	matched.Status.HardwareProfile = "large"
+	other := newDefaultNamedHost("other", t)
+	r := newTestReconciler(matched, other)
+
+	requests := r.hostsForHardwareProfile(&metal3v1alpha1.HardwareProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "large"},
+	})
+	assert.Equal(t, []ctrl.Request{{NamespacedName: client.ObjectKeyFromObject(matched)}}, requests)
 }
 
 func TestGetPreprovImageNoFormats(t *testing.T) {
@@ -2855,3 +3307,49 @@ func TestHFSEmptyStatusSettings(t *testing.T) {
 		},
 	)
 }
+
+func TestUpdateProgress(t *testing.T) {
+	heartbeat := time.Date(2023, 5, 4, 10, 11, 12, 345000, time.UTC)
+	later := heartbeat.Add(30 * time.Second)
+
+	host := newDefaultHost(t)
+	info := &reconcileInfo{
+		log:  logf.Log.WithName("test"),
+		host: host,
+	}
+
+	assert.False(t, updateProgress(info, nil))
+	assert.Nil(t, host.Status.Progress)
+
+	assert.True(t, updateProgress(info, &provisioner.Progress{
+		Step:          "wait call-back",
+		LastHeartbeat: &heartbeat,
+	}))
+	assert.Equal(t, "wait call-back", host.Status.Progress.Step)
+	assert.Equal(t, heartbeat.Truncate(time.Second), host.Status.Progress.LastHeartbeat.Time)
+	assert.Len(t, info.events, 1)
+	assert.Equal(t, "wait call-back", info.events[0].Message)
+
+	// The same progress does not need to be saved again
+	assert.False(t, updateProgress(info, &provisioner.Progress{
+		Step:          "wait call-back",
+		LastHeartbeat: &heartbeat,
+	}))
+
+	// A new heartbeat is saved without an event
+	assert.True(t, updateProgress(info, &provisioner.Progress{
+		Step:          "wait call-back",
+		LastHeartbeat: &later,
+	}))
+	assert.Equal(t, later.Truncate(time.Second), host.Status.Progress.LastHeartbeat.Time)
+	assert.Len(t, info.events, 1)
+
+	assert.True(t, updateProgress(info, &provisioner.Progress{
+		Step:          "deploying",
+		CurrentStep:   "deploy.write_image",
+		LastHeartbeat: &later,
+	}))
+	assert.Equal(t, "deploy.write_image", host.Status.Progress.CurrentStep)
+	assert.Len(t, info.events, 2)
+	assert.Equal(t, "deploying: deploy.write_image", info.events[1].Message)
+}
@@ -0,0 +1,333 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+// BareMetalHostClaimReconciler binds BareMetalHostClaims to available
+// BareMetalHosts matching their selectors
+type BareMetalHostClaimReconciler struct {
+	client.Client
+	Log logr.Logger
+}
+
+//+kubebuilder:rbac:groups=metal3.io,resources=baremetalhostclaims,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=metal3.io,resources=baremetalhostclaims/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=metal3.io,resources=baremetalhostclaims/finalizers,verbs=update
+
+// Reconcile binds a claim to a matching available host, keeps the
+// image and user data of the bound host in sync with the claim, and
+// releases the host when the claim is deleted.
+func (r *BareMetalHostClaimReconciler) Reconcile(ctx context.Context, request ctrl.Request) (result ctrl.Result, err error) {
+	reqLogger := r.Log.WithValues("baremetalhostclaim", request.NamespacedName)
+	reqLogger.Info("start")
+
+	claim := &metal3v1alpha1.BareMetalHostClaim{}
+	if err = r.Get(ctx, request.NamespacedName, claim); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return ctrl.Result{}, errors.Wrap(err, "could not load claim")
+	}
+
+	if !claim.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.release(ctx, reqLogger, claim)
+	}
+
+	if !controllerutil.ContainsFinalizer(claim, metal3v1alpha1.BareMetalHostClaimFinalizer) {
+		controllerutil.AddFinalizer(claim, metal3v1alpha1.BareMetalHostClaimFinalizer)
+		if err = r.Update(ctx, claim); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to add finalizer")
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	if claim.Status.HostName != "" {
+		return ctrl.Result{}, r.syncBoundHost(ctx, reqLogger, claim)
+	}
+
+	return r.bind(ctx, reqLogger, claim)
+}
+
+// bind looks for an available host matching the claim and consumes it.
+// Updating the host fails on a conflict if it was changed since it was
+// listed, so a host can not be bound to two claims. A host already
+// consumed by the claim, whose binding was not recorded in the status
+// of the claim, is adopted instead.
+func (r *BareMetalHostClaimReconciler) bind(ctx context.Context, log logr.Logger, claim *metal3v1alpha1.BareMetalHostClaim) (ctrl.Result, error) {
+	consumed, err := r.findConsumedHost(ctx, claim)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if consumed != nil {
+		log.Info("adopting host consumed by the claim", "host", consumed.Name)
+		return ctrl.Result{}, r.setStatus(ctx, claim, metal3v1alpha1.ClaimPhaseBound, consumed.Name, "")
+	}
+
+	candidates, err := r.findCandidates(ctx, claim)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	for i := range candidates {
+		host := &candidates[i]
+		host.Spec.ConsumerRef = &corev1.ObjectReference{
+			APIVersion: metal3v1alpha1.GroupVersion.String(),
+			Kind:       metal3v1alpha1.BareMetalHostClaimKind,
+			Namespace:  claim.Namespace,
+			Name:       claim.Name,
+			UID:        claim.UID,
+		}
+		copyClaimToHost(claim, host)
+		if err = r.Update(ctx, host); err != nil {
+			if k8serrors.IsConflict(err) {
+				log.Info("host changed while binding, trying the next one", "host", host.Name)
+				continue
+			}
+			return ctrl.Result{}, errors.Wrap(err, "failed to bind host")
+		}
+
+		log.Info("bound host", "host", host.Name)
+		return ctrl.Result{}, r.setStatus(ctx, claim, metal3v1alpha1.ClaimPhaseBound, host.Name, "")
+	}
+
+	if len(candidates) != 0 {
+		// All the candidates were taken concurrently, look again
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	log.Info("no available host matches the claim")
+	return ctrl.Result{}, r.setStatus(ctx, claim, metal3v1alpha1.ClaimPhasePending, "",
+		"no available host matches the claim")
+}
+
+// findConsumedHost returns the host consumed by the claim, if any.
+func (r *BareMetalHostClaimReconciler) findConsumedHost(ctx context.Context, claim *metal3v1alpha1.BareMetalHostClaim) (*metal3v1alpha1.BareMetalHost, error) {
+	hosts := &metal3v1alpha1.BareMetalHostList{}
+	if err := r.List(ctx, hosts, client.InNamespace(claim.Namespace)); err != nil {
+		return nil, errors.Wrap(err, "failed to list hosts")
+	}
+	for i := range hosts.Items {
+		if claim.ConsumedBy(&hosts.Items[i]) {
+			return &hosts.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// findCandidates returns the available hosts matching the claim,
+// sorted by name.
+func (r *BareMetalHostClaimReconciler) findCandidates(ctx context.Context, claim *metal3v1alpha1.BareMetalHostClaim) ([]metal3v1alpha1.BareMetalHost, error) {
+	selector := labels.Everything()
+	if claim.Spec.HostSelector != nil {
+		var err error
+		selector, err = metav1.LabelSelectorAsSelector(claim.Spec.HostSelector)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid host selector")
+		}
+	}
+
+	hosts := &metal3v1alpha1.BareMetalHostList{}
+	if err := r.List(ctx, hosts, client.InNamespace(claim.Namespace),
+		client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, errors.Wrap(err, "failed to list hosts")
+	}
+
+	var candidates []metal3v1alpha1.BareMetalHost
+	for _, host := range hosts.Items {
+		if !hostIsClaimable(&host) {
+			continue
+		}
+		if claim.Spec.HardwareSelector != nil &&
+			!claim.Spec.HardwareSelector.Matches(host.Status.HardwareDetails) {
+			continue
+		}
+		candidates = append(candidates, host)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Name < candidates[j].Name
+	})
+	return candidates, nil
+}
+
+// hostIsClaimable returns whether the host is available and not
+// consumed yet.
+func hostIsClaimable(host *metal3v1alpha1.BareMetalHost) bool {
+	return host.Spec.ConsumerRef == nil &&
+		host.DeletionTimestamp.IsZero() &&
+		host.Status.Provisioning.State == metal3v1alpha1.StateAvailable &&
+		host.Status.OperationalStatus == metal3v1alpha1.OperationalStatusOK &&
+		!hasDetachedAnnotation(host)
+}
+
+func copyClaimToHost(claim *metal3v1alpha1.BareMetalHostClaim, host *metal3v1alpha1.BareMetalHost) (dirty bool) {
+	if !reflect.DeepEqual(host.Spec.Image, claim.Spec.Image) {
+		host.Spec.Image = claim.Spec.Image.DeepCopy()
+		dirty = true
+	}
+	if !reflect.DeepEqual(host.Spec.UserData, claim.Spec.UserData) {
+		host.Spec.UserData = claim.Spec.UserData.DeepCopy()
+		dirty = true
+	}
+	if !reflect.DeepEqual(host.Spec.NetworkData, claim.Spec.NetworkData) {
+		host.Spec.NetworkData = claim.Spec.NetworkData.DeepCopy()
+		dirty = true
+	}
+	if !host.Spec.Online && claim.Spec.Image != nil {
+		host.Spec.Online = true
+		dirty = true
+	}
+	return
+}
+
+// syncBoundHost keeps the bound host up to date with the claim, and
+// marks the claim as lost when the host is gone.
+func (r *BareMetalHostClaimReconciler) syncBoundHost(ctx context.Context, log logr.Logger, claim *metal3v1alpha1.BareMetalHostClaim) error {
+	host := &metal3v1alpha1.BareMetalHost{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: claim.Namespace, Name: claim.Status.HostName}, host)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return errors.Wrap(err, "could not load bound host")
+	}
+	if k8serrors.IsNotFound(err) || !claim.ConsumedBy(host) {
+		log.Info("bound host lost", "host", claim.Status.HostName)
+		return r.setStatus(ctx, claim, metal3v1alpha1.ClaimPhaseLost, claim.Status.HostName,
+			fmt.Sprintf("host %s is no longer bound to the claim", claim.Status.HostName))
+	}
+
+	if copyClaimToHost(claim, host) {
+		log.Info("updating bound host", "host", host.Name)
+		if err := r.Update(ctx, host); err != nil {
+			return errors.Wrap(err, "failed to update bound host")
+		}
+	}
+	return r.setStatus(ctx, claim, metal3v1alpha1.ClaimPhaseBound, host.Name, "")
+}
+
+// release clears the consumer, image and user data of the bound host
+// so that it is deprovisioned and becomes available again, then lets
+// the claim be deleted.
+func (r *BareMetalHostClaimReconciler) release(ctx context.Context, log logr.Logger, claim *metal3v1alpha1.BareMetalHostClaim) error {
+	if !controllerutil.ContainsFinalizer(claim, metal3v1alpha1.BareMetalHostClaimFinalizer) {
+		return nil
+	}
+
+	// The host may have been bound without the status of the claim
+	// being updated
+	host, err := r.findConsumedHost(ctx, claim)
+	if err != nil {
+		return err
+	}
+	if host != nil {
+		log.Info("releasing host", "host", host.Name)
+		host.Spec.ConsumerRef = nil
+		host.Spec.Image = nil
+		host.Spec.UserData = nil
+		host.Spec.NetworkData = nil
+		if err := r.Update(ctx, host); err != nil {
+			return errors.Wrap(err, "failed to release host")
+		}
+	}
+
+	controllerutil.RemoveFinalizer(claim, metal3v1alpha1.BareMetalHostClaimFinalizer)
+	if err := r.Update(ctx, claim); err != nil {
+		return errors.Wrap(err, "failed to remove finalizer")
+	}
+	return nil
+}
+
+func (r *BareMetalHostClaimReconciler) setStatus(ctx context.Context, claim *metal3v1alpha1.BareMetalHostClaim, phase metal3v1alpha1.ClaimPhase, hostName, message string) error {
+	newStatus := metal3v1alpha1.BareMetalHostClaimStatus{
+		Phase:    phase,
+		HostName: hostName,
+		Message:  message,
+	}
+	if claim.Status == newStatus {
+		return nil
+	}
+	claim.Status = newStatus
+	if err := r.Status().Update(ctx, claim); err != nil {
+		return errors.Wrap(err, "failed to update claim status")
+	}
+	return nil
+}
+
+// pendingClaimsForHost enqueues the pending claims of the namespace of
+// a host, so that they are bound as soon as a host becomes available,
+// and the claim consuming the host, if any.
+func (r *BareMetalHostClaimReconciler) pendingClaimsForHost(obj client.Object) []reconcile.Request {
+	host, ok := obj.(*metal3v1alpha1.BareMetalHost)
+	if !ok {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	if ref := host.Spec.ConsumerRef; ref != nil && ref.Kind == metal3v1alpha1.BareMetalHostClaimKind {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name},
+		})
+	}
+
+	if !hostIsClaimable(host) {
+		return requests
+	}
+
+	claims := &metal3v1alpha1.BareMetalHostClaimList{}
+	if err := r.List(context.Background(), claims, client.InNamespace(host.Namespace)); err != nil {
+		r.Log.Error(err, "failed to list claims", "namespace", host.Namespace)
+		return requests
+	}
+	for _, claim := range claims.Items {
+		if claim.Status.HostName == "" {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name},
+			})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager registers the reconciler to be run by the manager
+func (r *BareMetalHostClaimReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&metal3v1alpha1.BareMetalHostClaim{}).
+		Watches(&source.Kind{Type: &metal3v1alpha1.BareMetalHost{}},
+			handler.EnqueueRequestsFromMapFunc(r.pendingClaimsForHost)).
+		Complete(r)
+}
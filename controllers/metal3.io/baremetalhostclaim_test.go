@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+func newClaimableHost(name string, ramMebibytes int, hostLabels map[string]string) *metal3v1alpha1.BareMetalHost {
+	host := newHost(name, &metal3v1alpha1.BareMetalHostSpec{})
+	host.Labels = hostLabels
+	host.Status.Provisioning.State = metal3v1alpha1.StateAvailable
+	host.Status.OperationalStatus = metal3v1alpha1.OperationalStatusOK
+	host.Status.HardwareDetails = &metal3v1alpha1.HardwareDetails{
+		CPU:          metal3v1alpha1.CPU{Arch: "x86_64", Count: 32},
+		RAMMebibytes: ramMebibytes,
+		Storage: []metal3v1alpha1.Storage{
+			{Name: "/dev/nvme0n1", Type: metal3v1alpha1.NVME, SizeBytes: 960 * metal3v1alpha1.GigaByte},
+		},
+	}
+	return host
+}
+
+func newClaim(name string, spec metal3v1alpha1.BareMetalHostClaimSpec) *metal3v1alpha1.BareMetalHostClaim {
+	return &metal3v1alpha1.BareMetalHostClaim{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "BareMetalHostClaim",
+			APIVersion: "metal3.io/v1alpha1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: spec,
+	}
+}
+
+func newTestClaimReconciler(initObjs ...runtime.Object) *BareMetalHostClaimReconciler {
+	return &BareMetalHostClaimReconciler{
+		Client: fakeclient.NewClientBuilder().WithRuntimeObjects(initObjs...).Build(),
+		Log:    ctrl.Log.WithName("controllers").WithName("BareMetalHostClaim"),
+	}
+}
+
+func reconcileClaim(t *testing.T, r *BareMetalHostClaimReconciler, name string) *metal3v1alpha1.BareMetalHostClaim {
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	for i := 0; i < 5; i++ {
+		result, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key})
+		require.NoError(t, err)
+		if !result.Requeue {
+			break
+		}
+	}
+
+	claim := &metal3v1alpha1.BareMetalHostClaim{}
+	if err := r.Get(context.TODO(), key, claim); k8serrors.IsNotFound(err) {
+		return nil
+	}
+	return claim
+}
+
+func getTestHost(t *testing.T, r *BareMetalHostClaimReconciler, name string) *metal3v1alpha1.BareMetalHost {
+	host := &metal3v1alpha1.BareMetalHost{}
+	require.NoError(t, r.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, host))
+	return host
+}
+
+func TestBareMetalHostClaimBind(t *testing.T) {
+	provisioned := newClaimableHost("host-0", 256*1024, nil)
+	provisioned.Status.Provisioning.State = metal3v1alpha1.StateProvisioned
+	small := newClaimableHost("host-1", 64*1024, nil)
+	otherRack := newClaimableHost("host-2", 256*1024, map[string]string{"rack": "r13"})
+	matching := newClaimableHost("host-3", 256*1024, map[string]string{"rack": "r12"})
+
+	image := &metal3v1alpha1.Image{URL: "http://example.test/image.qcow2", Checksum: "abc"}
+	userData := &corev1.SecretReference{Name: "user-data"}
+	claim := newClaim("claim", metal3v1alpha1.BareMetalHostClaimSpec{
+		HostSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"rack": "r12"},
+		},
+		HardwareSelector: &metal3v1alpha1.HardwareSelector{
+			MinCPUs:         16,
+			MinRAMMebibytes: 128 * 1024,
+			DiskType:        metal3v1alpha1.NVME,
+		},
+		Image:    image,
+		UserData: userData,
+	})
+
+	r := newTestClaimReconciler(provisioned, small, otherRack, matching, claim)
+	claim = reconcileClaim(t, r, "claim")
+
+	assert.Equal(t, metal3v1alpha1.ClaimPhaseBound, claim.Status.Phase)
+	assert.Equal(t, "host-3", claim.Status.HostName)
+	assert.Contains(t, claim.Finalizers, metal3v1alpha1.BareMetalHostClaimFinalizer)
+
+	host := getTestHost(t, r, "host-3")
+	assert.True(t, claim.ConsumedBy(host))
+	assert.Equal(t, image, host.Spec.Image)
+	assert.Equal(t, userData, host.Spec.UserData)
+	assert.True(t, host.Spec.Online)
+
+	for _, name := range []string{"host-0", "host-1", "host-2"} {
+		assert.Nil(t, getTestHost(t, r, name).Spec.ConsumerRef, name)
+	}
+}
+
+func TestBareMetalHostClaimPending(t *testing.T) {
+	consumed := newClaimableHost("host-0", 256*1024, nil)
+	consumed.Spec.ConsumerRef = &corev1.ObjectReference{Kind: "Metal3Machine", Name: "machine"}
+	claim := newClaim("claim", metal3v1alpha1.BareMetalHostClaimSpec{})
+
+	r := newTestClaimReconciler(consumed, claim)
+	claim = reconcileClaim(t, r, "claim")
+
+	assert.Equal(t, metal3v1alpha1.ClaimPhasePending, claim.Status.Phase)
+	assert.Empty(t, claim.Status.HostName)
+	assert.Equal(t, "machine", getTestHost(t, r, "host-0").Spec.ConsumerRef.Name)
+}
+
+func TestBareMetalHostClaimNoDoubleBinding(t *testing.T) {
+	host := newClaimableHost("host-0", 256*1024, nil)
+	first := newClaim("first", metal3v1alpha1.BareMetalHostClaimSpec{})
+	second := newClaim("second", metal3v1alpha1.BareMetalHostClaimSpec{})
+
+	r := newTestClaimReconciler(host, first, second)
+	first = reconcileClaim(t, r, "first")
+	second = reconcileClaim(t, r, "second")
+
+	assert.Equal(t, metal3v1alpha1.ClaimPhaseBound, first.Status.Phase)
+	assert.Equal(t, metal3v1alpha1.ClaimPhasePending, second.Status.Phase)
+	assert.True(t, first.ConsumedBy(getTestHost(t, r, "host-0")))
+}
+
+func TestBareMetalHostClaimRelease(t *testing.T) {
+	host := newClaimableHost("host-0", 256*1024, nil)
+	claim := newClaim("claim", metal3v1alpha1.BareMetalHostClaimSpec{
+		Image: &metal3v1alpha1.Image{URL: "http://example.test/image.qcow2", Checksum: "abc"},
+	})
+
+	r := newTestClaimReconciler(host, claim)
+	claim = reconcileClaim(t, r, "claim")
+	require.Equal(t, metal3v1alpha1.ClaimPhaseBound, claim.Status.Phase)
+
+	require.NoError(t, r.Delete(context.TODO(), claim))
+	assert.Nil(t, reconcileClaim(t, r, "claim"))
+
+	host = getTestHost(t, r, "host-0")
+	assert.Nil(t, host.Spec.ConsumerRef)
+	assert.Nil(t, host.Spec.Image)
+}
+
+// TestBareMetalHostClaimAdopt ensures that a host consumed by a claim
+// whose status was not updated is adopted instead of binding another
+// host.
+func TestBareMetalHostClaimAdopt(t *testing.T) {
+	claim := newClaim("claim", metal3v1alpha1.BareMetalHostClaimSpec{})
+	claim.UID = "claim-uid"
+	claim.Finalizers = []string{metal3v1alpha1.BareMetalHostClaimFinalizer}
+	consumed := newClaimableHost("host-1", 256*1024, nil)
+	consumed.Spec.ConsumerRef = &corev1.ObjectReference{
+		Kind:      metal3v1alpha1.BareMetalHostClaimKind,
+		Namespace: namespace,
+		Name:      "claim",
+		UID:       "claim-uid",
+	}
+	available := newClaimableHost("host-0", 256*1024, nil)
+
+	r := newTestClaimReconciler(available, consumed, claim)
+	claim = reconcileClaim(t, r, "claim")
+
+	assert.Equal(t, metal3v1alpha1.ClaimPhaseBound, claim.Status.Phase)
+	assert.Equal(t, "host-1", claim.Status.HostName)
+	assert.Nil(t, getTestHost(t, r, "host-0").Spec.ConsumerRef)
+}
+
+// TestBareMetalHostClaimReleaseWithoutStatus ensures that the host
+// bound to a claim is released even if the binding was not recorded
+// in the status of the claim.
+func TestBareMetalHostClaimReleaseWithoutStatus(t *testing.T) {
+	claim := newClaim("claim", metal3v1alpha1.BareMetalHostClaimSpec{})
+	claim.Finalizers = []string{metal3v1alpha1.BareMetalHostClaimFinalizer}
+	host := newClaimableHost("host-0", 256*1024, nil)
+	host.Spec.ConsumerRef = &corev1.ObjectReference{
+		Kind:      metal3v1alpha1.BareMetalHostClaimKind,
+		Namespace: namespace,
+		Name:      "claim",
+	}
+
+	r := newTestClaimReconciler(host, claim)
+	require.NoError(t, r.Delete(context.TODO(), claim))
+	assert.Nil(t, reconcileClaim(t, r, "claim"))
+	assert.Nil(t, getTestHost(t, r, "host-0").Spec.ConsumerRef)
+}
+
+func TestBareMetalHostClaimLost(t *testing.T) {
+	host := newClaimableHost("host-0", 256*1024, nil)
+	claim := newClaim("claim", metal3v1alpha1.BareMetalHostClaimSpec{})
+
+	r := newTestClaimReconciler(host, claim)
+	claim = reconcileClaim(t, r, "claim")
+	require.Equal(t, metal3v1alpha1.ClaimPhaseBound, claim.Status.Phase)
+
+	host = getTestHost(t, r, "host-0")
+	host.Spec.ConsumerRef = nil
+	require.NoError(t, r.Update(context.TODO(), host))
+
+	claim = reconcileClaim(t, r, "claim")
+	assert.Equal(t, metal3v1alpha1.ClaimPhaseLost, claim.Status.Phase)
+	assert.Equal(t, "host-0", claim.Status.HostName)
+}
+
+func TestBareMetalHostClaimSyncImage(t *testing.T) {
+	host := newClaimableHost("host-0", 256*1024, nil)
+	claim := newClaim("claim", metal3v1alpha1.BareMetalHostClaimSpec{
+		Image: &metal3v1alpha1.Image{URL: "http://example.test/image.qcow2", Checksum: "abc"},
+	})
+
+	r := newTestClaimReconciler(host, claim)
+	claim = reconcileClaim(t, r, "claim")
+
+	newImage := &metal3v1alpha1.Image{URL: "http://example.test/image-2.qcow2", Checksum: "def"}
+	claim.Spec.Image = newImage
+	require.NoError(t, r.Update(context.TODO(), claim))
+	reconcileClaim(t, r, "claim")
+
+	assert.Equal(t, newImage, getTestHost(t, r, "host-0").Spec.Image)
+}
+
+func TestPendingClaimsForHost(t *testing.T) {
+	pending := newClaim("pending", metal3v1alpha1.BareMetalHostClaimSpec{})
+	bound := newClaim("bound", metal3v1alpha1.BareMetalHostClaimSpec{})
+	bound.Status.HostName = "host-1"
+	r := newTestClaimReconciler(pending, bound)
+
+	available := newClaimableHost("host-0", 256*1024, nil)
+	requests := r.pendingClaimsForHost(available)
+	assert.Len(t, requests, 1)
+	assert.Equal(t, "pending", requests[0].Name)
+
+	consumed := newClaimableHost("host-1", 256*1024, nil)
+	consumed.Spec.ConsumerRef = &corev1.ObjectReference{
+		Kind:      metal3v1alpha1.BareMetalHostClaimKind,
+		Namespace: namespace,
+		Name:      "bound",
+	}
+	requests = r.pendingClaimsForHost(consumed)
+	assert.Len(t, requests, 1)
+	assert.Equal(t, "bound", requests[0].Name)
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/secretutils"
+)
+
+const (
+	// bmcCertificateExpiryWarning is how long before the expiry of a
+	// trusted certificate of a BMC it starts being reported.
+	bmcCertificateExpiryWarning = 30 * 24 * time.Hour
+
+	// bmcCertificateExpiryInterval is how often the expiry of the
+	// trusted certificates of a host is reported.
+	bmcCertificateExpiryInterval = 24 * time.Hour
+)
+
+// bmcCertificatesData returns the data at the key of the ConfigMap or
+// Secret referenced by the trusted certificates of the host.
+func bmcCertificatesData(secretManager secretutils.SecretManager, apiReader client.Reader, host *metal3v1alpha1.BareMetalHost, key string) (string, error) {
+	trusted := host.Spec.BMC.TrustedCertificates
+
+	if trusted.SecretName != "" {
+		secretKey := types.NamespacedName{Namespace: host.Namespace, Name: trusted.SecretName}
+		secret, err := secretManager.ObtainSecret(secretKey)
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				return "", &BMCCertificatesError{message: fmt.Sprintf("the secret %s does not exist", secretKey)}
+			}
+			return "", err
+		}
+		data, ok := secret.Data[key]
+		if !ok {
+			return "", &BMCCertificatesError{message: fmt.Sprintf("the secret %s has no key %s", secretKey, key)}
+		}
+		return string(data), nil
+	}
+
+	configMapKey := types.NamespacedName{Namespace: host.Namespace, Name: trusted.ConfigMapName}
+	configMap := &corev1.ConfigMap{}
+	if err := apiReader.Get(context.TODO(), configMapKey, configMap); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return "", &BMCCertificatesError{message: fmt.Sprintf("the config map %s does not exist", configMapKey)}
+		}
+		return "", err
+	}
+	if data, ok := configMap.Data[key]; ok {
+		return data, nil
+	}
+	if data, ok := configMap.BinaryData[key]; ok {
+		return string(data), nil
+	}
+	return "", &BMCCertificatesError{message: fmt.Sprintf("the config map %s has no key %s", configMapKey, key)}
+}
+
+// loadBMCTrustedCertificates returns the PEM-encoded CA certificates
+// the certificate of the BMC of the host is verified against, or the
+// SHA-256 fingerprint it is pinned to. Both are nil when the host
+// relies on the system trust store.
+func loadBMCTrustedCertificates(secretManager secretutils.SecretManager, apiReader client.Reader, host *metal3v1alpha1.BareMetalHost) (caCertificates, fingerprint []byte, err error) {
+	trusted := host.Spec.BMC.TrustedCertificates
+	if trusted == nil || host.Spec.BMC.DisableCertificateVerification {
+		return nil, nil, nil
+	}
+
+	if trusted.FingerprintKey != "" {
+		data, err := bmcCertificatesData(secretManager, apiReader, host, trusted.FingerprintKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		fingerprint, err := bmc.ParseCertificateFingerprint(data)
+		if err != nil {
+			return nil, nil, &BMCCertificatesError{message: err.Error()}
+		}
+		return nil, fingerprint, nil
+	}
+
+	key := trusted.CABundleKey
+	if key == "" {
+		key = metal3v1alpha1.DefaultBMCCABundleKey
+	}
+	data, err := bmcCertificatesData(secretManager, apiReader, host, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := bmc.ParseCABundle([]byte(data)); err != nil {
+		return nil, nil, &BMCCertificatesError{message: err.Error()}
+	}
+	return []byte(data), nil, nil
+}
+
+// bmcTrustedCertificates returns the trusted certificates of the BMC of
+// the host.
+func (r *BareMetalHostReconciler) bmcTrustedCertificates(host *metal3v1alpha1.BareMetalHost) (caCertificates, fingerprint []byte, err error) {
+	return loadBMCTrustedCertificates(r.secretManager(r.Log), r.APIReader, host)
+}
+
+// checkBMCCertificatesExpiry publishes an event when a trusted
+// certificate of the BMC of the host has expired or is about to
+// expire. The event is repeated daily until the certificate is
+// replaced.
+func (r *BareMetalHostReconciler) checkBMCCertificatesExpiry(info *reconcileInfo, caCertificates []byte, now time.Time) {
+	key := info.request.NamespacedName.String()
+	if len(caCertificates) == 0 {
+		r.certificateExpiryWarnings.Delete(key)
+		return
+	}
+
+	// The certificates were validated when they were loaded.
+	certs, _ := bmc.ParseCABundle(caCertificates)
+	var expiring *x509.Certificate
+	for _, cert := range certs {
+		if expiring == nil || cert.NotAfter.Before(expiring.NotAfter) {
+			expiring = cert
+		}
+	}
+	if expiring == nil || now.Add(bmcCertificateExpiryWarning).Before(expiring.NotAfter) {
+		r.certificateExpiryWarnings.Delete(key)
+		return
+	}
+
+	if last, ok := r.certificateExpiryWarnings.Load(key); ok && now.Sub(last.(time.Time)) < bmcCertificateExpiryInterval {
+		return
+	}
+	r.certificateExpiryWarnings.Store(key, now)
+
+	if now.After(expiring.NotAfter) {
+		info.publishEvent("BMCCertificateExpired",
+			fmt.Sprintf("Trusted BMC certificate %q expired on %s",
+				expiring.Subject.CommonName, expiring.NotAfter.Format(time.RFC3339)))
+		return
+	}
+	info.publishEvent("BMCCertificateExpiring",
+		fmt.Sprintf("Trusted BMC certificate %q expires on %s",
+			expiring.Subject.CommonName, expiring.NotAfter.Format(time.RFC3339)))
+}
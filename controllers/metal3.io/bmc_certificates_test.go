@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+func newTestCertificate(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "bmc-ca"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestBMCCACertificates(t *testing.T) {
+	cert := newTestCertificate(t, time.Now().Add(365*24*time.Hour))
+
+	testCases := []struct {
+		Scenario      string
+		Trusted       *metal3v1alpha1.BMCTrustedCertificates
+		Objects       []corev1.ConfigMap
+		Expected      []byte
+		ExpectedError string
+	}{
+		{
+			Scenario: "system trust store",
+		},
+		{
+			Scenario: "config map",
+			Trusted:  &metal3v1alpha1.BMCTrustedCertificates{ConfigMapName: "bmc-ca"},
+			Objects: []corev1.ConfigMap{{
+				ObjectMeta: metav1.ObjectMeta{Name: "bmc-ca", Namespace: namespace},
+				Data:       map[string]string{"ca.crt": string(cert)},
+			}},
+			Expected: cert,
+		},
+		{
+			Scenario: "custom key",
+			Trusted:  &metal3v1alpha1.BMCTrustedCertificates{ConfigMapName: "bmc-ca", CABundleKey: "bundle.pem"},
+			Objects: []corev1.ConfigMap{{
+				ObjectMeta: metav1.ObjectMeta{Name: "bmc-ca", Namespace: namespace},
+				BinaryData: map[string][]byte{"bundle.pem": cert},
+			}},
+			Expected: cert,
+		},
+		{
+			Scenario:      "missing config map",
+			Trusted:       &metal3v1alpha1.BMCTrustedCertificates{ConfigMapName: "bmc-ca"},
+			ExpectedError: "BMC trusted certificates failed the config map test-namespace/bmc-ca does not exist",
+		},
+		{
+			Scenario: "missing key",
+			Trusted:  &metal3v1alpha1.BMCTrustedCertificates{ConfigMapName: "bmc-ca"},
+			Objects: []corev1.ConfigMap{{
+				ObjectMeta: metav1.ObjectMeta{Name: "bmc-ca", Namespace: namespace},
+			}},
+			ExpectedError: "BMC trusted certificates failed the config map test-namespace/bmc-ca has no key ca.crt",
+		},
+		{
+			Scenario: "malformed bundle",
+			Trusted:  &metal3v1alpha1.BMCTrustedCertificates{ConfigMapName: "bmc-ca"},
+			Objects: []corev1.ConfigMap{{
+				ObjectMeta: metav1.ObjectMeta{Name: "bmc-ca", Namespace: namespace},
+				Data:       map[string]string{"ca.crt": "not a certificate"},
+			}},
+			ExpectedError: "BMC trusted certificates failed CA bundle holds data that is not PEM-encoded",
+		},
+		{
+			Scenario: "malformed fingerprint",
+			Trusted:  &metal3v1alpha1.BMCTrustedCertificates{ConfigMapName: "bmc-ca", FingerprintKey: "sha256"},
+			Objects: []corev1.ConfigMap{{
+				ObjectMeta: metav1.ObjectMeta{Name: "bmc-ca", Namespace: namespace},
+				Data:       map[string]string{"sha256": "abcd"},
+			}},
+			ExpectedError: "BMC trusted certificates failed invalid SHA-256 certificate fingerprint \"abcd\"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			host := newDefaultHost(t)
+			host.Spec.BMC.TrustedCertificates = tc.Trusted
+			r := newTestReconciler(host)
+			for i := range tc.Objects {
+				assert.NoError(t, r.Create(context.TODO(), &tc.Objects[i]))
+			}
+
+			certs, fingerprint, err := r.bmcTrustedCertificates(host)
+			if tc.ExpectedError != "" {
+				assert.EqualError(t, err, tc.ExpectedError)
+				assert.IsType(t, &BMCCertificatesError{}, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.Expected, certs)
+			assert.Nil(t, fingerprint)
+		})
+	}
+}
+
+func TestBMCCACertificatesFromSecret(t *testing.T) {
+	cert := newTestCertificate(t, time.Now().Add(365*24*time.Hour))
+	host := newDefaultHost(t)
+	host.Spec.BMC.TrustedCertificates = &metal3v1alpha1.BMCTrustedCertificates{SecretName: "bmc-ca"}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bmc-ca", Namespace: namespace},
+		Data:       map[string][]byte{"ca.crt": cert},
+	}
+	r := newTestReconciler(host, secret)
+
+	certs, _, err := r.bmcTrustedCertificates(host)
+	assert.NoError(t, err)
+	assert.Equal(t, cert, certs)
+}
+
+func TestCheckBMCCertificatesExpiry(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		Scenario       string
+		NotAfter       time.Time
+		ExpectedReason string
+	}{
+		{
+			Scenario: "valid",
+			NotAfter: now.Add(90 * 24 * time.Hour),
+		},
+		{
+			Scenario:       "expiring",
+			NotAfter:       now.Add(7 * 24 * time.Hour),
+			ExpectedReason: "BMCCertificateExpiring",
+		},
+		{
+			Scenario:       "expired",
+			NotAfter:       now.Add(-time.Hour),
+			ExpectedReason: "BMCCertificateExpired",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			host := newDefaultHost(t)
+			r := newTestReconciler(host)
+			certs := newTestCertificate(t, tc.NotAfter)
+			info := &reconcileInfo{
+				log:     logf.Log.WithName("test"),
+				host:    host,
+				request: ctrl.Request{NamespacedName: client.ObjectKeyFromObject(host)},
+			}
+
+			r.checkBMCCertificatesExpiry(info, certs, now)
+			if tc.ExpectedReason == "" {
+				assert.Empty(t, info.events)
+				return
+			}
+			assert.Len(t, info.events, 1)
+			assert.Equal(t, tc.ExpectedReason, info.events[0].Reason)
+
+			// The event is only repeated daily.
+			r.checkBMCCertificatesExpiry(info, certs, now.Add(time.Hour))
+			assert.Len(t, info.events, 1)
+			r.checkBMCCertificatesExpiry(info, certs, now.Add(25*time.Hour))
+			assert.Len(t, info.events, 2)
+		})
+	}
+}
+
+func TestBMCPinnedCertificate(t *testing.T) {
+	host := newDefaultHost(t)
+	host.Spec.BMC.TrustedCertificates = &metal3v1alpha1.BMCTrustedCertificates{ConfigMapName: "bmc-ca", FingerprintKey: "sha256"}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "bmc-ca", Namespace: namespace},
+		Data:       map[string]string{"sha256": strings.Repeat("ab:", 31) + "ab"},
+	}
+	r := newTestReconciler(host, configMap)
+
+	// The BMC is not contacted, its certificate is checked against the
+	// fingerprint by the provisioner when connecting.
+	certs, fingerprint, err := r.bmcTrustedCertificates(host)
+	assert.NoError(t, err)
+	assert.Nil(t, certs)
+	assert.Equal(t, bytes.Repeat([]byte{0xab}, 32), fingerprint)
+}
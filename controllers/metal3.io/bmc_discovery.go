@@ -0,0 +1,292 @@
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish"
+)
+
+const (
+	// maxDiscoveryAddresses is the largest number of addresses a
+	// discovery network may hold.
+	maxDiscoveryAddresses = 1 << 16
+
+	discoveryParallelism = 32
+	discoveryTimeout     = 5 * time.Second
+)
+
+var invalidNameCharacters = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// DiscoveryNetworkList is a list of management networks to scan for
+// BMCs that can be set with a command line flag. Each occurrence of the
+// flag adds a network, given in CIDR notation.
+type DiscoveryNetworkList []*net.IPNet
+
+func (l *DiscoveryNetworkList) String() string {
+	networks := make([]string, 0, len(*l))
+	for _, network := range *l {
+		networks = append(networks, network.String())
+	}
+	return strings.Join(networks, ",")
+}
+
+// Set parses a network and adds it to the list.
+func (l *DiscoveryNetworkList) Set(value string) error {
+	_, network, err := net.ParseCIDR(strings.TrimSpace(value))
+	if err != nil {
+		return err
+	}
+	ones, bits := network.Mask.Size()
+	if bits-ones > 16 {
+		return fmt.Errorf("discovery network %s holds more than %d addresses", network, maxDiscoveryAddresses)
+	}
+	*l = append(*l, network)
+	return nil
+}
+
+// BMCDiscoverer periodically scans management networks for Redfish
+// BMCs, and creates an unmanaged BareMetalHost without credentials for
+// each system found whose BMC is not used by any host yet.
+type BMCDiscoverer struct {
+	client.Client
+	Log       logr.Logger
+	APIReader client.Reader
+
+	// Namespace holds the hosts created by the discovery.
+	Namespace string
+	Networks  DiscoveryNetworkList
+	// Port is the port of the Redfish service of the BMCs.
+	Port int
+	// HTTP disables TLS, such as for Redfish emulators. The
+	// credentials are not sent to the BMCs then.
+	HTTP bool
+	// CACertificates verifies the certificates of the BMCs, instead of
+	// the system trust store. The credentials are only sent to BMCs
+	// whose certificate is verified.
+	CACertificates *x509.CertPool
+	Interval       time.Duration
+	// CredentialsName is the name of a Secret in Namespace holding
+	// credentials used to read the systems of the BMCs. They are not
+	// copied to the hosts.
+	CredentialsName string
+}
+
+//+kubebuilder:rbac:groups=metal3.io,resources=baremetalhosts,verbs=list;create
+
+// NeedLeaderElection makes the discovery run only on the leader.
+func (d *BMCDiscoverer) NeedLeaderElection() bool {
+	return true
+}
+
+// Start scans the networks every interval, until the context is done.
+func (d *BMCDiscoverer) Start(ctx context.Context) error {
+	d.Log.Info("starting BMC discovery", "networks", d.Networks.String(), "interval", d.Interval)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := d.Discover(ctx); err != nil {
+			d.Log.Error(err, "BMC discovery failed")
+		}
+	}, d.Interval)
+	return nil
+}
+
+// Discover scans the networks once, and creates hosts for the new
+// systems found.
+func (d *BMCDiscoverer) Discover(ctx context.Context) error {
+	creds, err := d.credentials(ctx)
+	if err != nil {
+		return err
+	}
+
+	hosts := &metal3v1alpha1.BareMetalHostList{}
+	if err := d.List(ctx, hosts, client.InNamespace(d.Namespace)); err != nil {
+		return errors.Wrap(err, "failed to list hosts")
+	}
+	known := map[string]bool{}
+	for _, host := range hosts.Items {
+		if parsedURL, err := bmc.GetParsedURL(host.Spec.BMC.Address); err == nil && host.Spec.BMC.Address != "" {
+			known[parsedURL.Hostname()] = true
+		}
+	}
+
+	found := d.scan(creds, known)
+	created := 0
+	for _, bmcFound := range found {
+		for _, host := range d.hostsFor(bmcFound) {
+			err := d.Create(ctx, host)
+			if k8serrors.IsAlreadyExists(err) {
+				continue
+			}
+			if err != nil {
+				return errors.Wrapf(err, "failed to create host %s", host.Name)
+			}
+			d.Log.Info("created discovered host", "host", host.Name, "bmc", host.Spec.BMC.Address,
+				"untrustedCertificate", bmcFound.UntrustedCertificate)
+			created++
+		}
+	}
+	d.Log.Info("BMC discovery complete", "bmcs", len(found), "created", created)
+	return nil
+}
+
+// credentials returns the credentials used to read the systems of the
+// BMCs, if any.
+func (d *BMCDiscoverer) credentials(ctx context.Context) (bmc.Credentials, error) {
+	if d.CredentialsName == "" {
+		return bmc.Credentials{}, nil
+	}
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: d.Namespace, Name: d.CredentialsName}
+	if err := d.APIReader.Get(ctx, key, secret); err != nil {
+		return bmc.Credentials{}, errors.Wrap(err, "failed to read the discovery credentials")
+	}
+	return bmc.Credentials{
+		Username: string(secret.Data["username"]),
+		Password: string(secret.Data["password"]),
+	}, nil
+}
+
+// scan probes every address of the networks that is not the BMC of a
+// known host for a Redfish service.
+func (d *BMCDiscoverer) scan(creds bmc.Credentials, known map[string]bool) []*redfish.DiscoveredBMC {
+	scheme := "https"
+	if d.HTTP {
+		scheme = "http"
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	var found []*redfish.DiscoveredBMC
+	sem := make(chan struct{}, discoveryParallelism)
+	for _, network := range d.Networks {
+		for _, ip := range networkAddresses(network) {
+			if known[ip.String()] {
+				continue
+			}
+			endpoint := fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(ip.String(), strconv.Itoa(d.Port)))
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				bmcFound, err := redfish.Discover(endpoint, creds, d.CACertificates, discoveryTimeout)
+				if err != nil {
+					d.Log.V(1).Info("no Redfish BMC found", "endpoint", endpoint, "error", err.Error())
+					return
+				}
+				lock.Lock()
+				found = append(found, bmcFound)
+				lock.Unlock()
+			}()
+		}
+	}
+	wg.Wait()
+	return found
+}
+
+// networkAddresses returns the addresses of a network, without the
+// network and broadcast addresses of IPv4 networks.
+func networkAddresses(network *net.IPNet) (addresses []net.IP) {
+	for ip := network.IP.Mask(network.Mask); network.Contains(ip); ip = nextIP(ip) {
+		addresses = append(addresses, ip)
+	}
+	ones, bits := network.Mask.Size()
+	if network.IP.To4() != nil && bits-ones > 1 {
+		addresses = addresses[1 : len(addresses)-1]
+	}
+	return addresses
+}
+
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+// hostsFor returns the hosts to create for the systems of a BMC. When
+// the systems could not be listed, a single host is returned, letting
+// the provisioner pick the system of the BMC once credentials are set.
+func (d *BMCDiscoverer) hostsFor(found *redfish.DiscoveredBMC) []*metal3v1alpha1.BareMetalHost {
+	systems := found.Systems
+	if len(systems) == 0 {
+		systems = []redfish.DiscoveredSystem{{}}
+	}
+
+	endpoint, _ := bmc.GetParsedURL(found.Endpoint)
+	baseName := "discovered-" + invalidNameCharacters.ReplaceAllString(strings.ToLower(endpoint.Hostname()), "-")
+
+	hosts := make([]*metal3v1alpha1.BareMetalHost, 0, len(systems))
+	for _, system := range systems {
+		name := baseName
+		if len(systems) > 1 {
+			systemID := system.Path[strings.LastIndex(system.Path, "/")+1:]
+			name += "-" + invalidNameCharacters.ReplaceAllString(strings.ToLower(systemID), "-")
+		}
+
+		host := &metal3v1alpha1.BareMetalHost{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: d.Namespace,
+				Annotations: map[string]string{
+					metal3v1alpha1.DiscoveredAnnotation: found.Endpoint,
+				},
+			},
+		}
+
+		bmcType := "redfish"
+		if system.HardwareDetails != nil {
+			for _, nic := range system.HardwareDetails.NIC {
+				if nic.MAC != "" {
+					host.Spec.BootMACAddress = nic.MAC
+					break
+				}
+			}
+			if details, err := json.Marshal(system.HardwareDetails); err == nil {
+				host.Annotations[hardwareDetailsAnnotation] = string(details)
+			}
+		}
+		if host.Spec.BootMACAddress == "" {
+			// Virtual media boot does not need the MAC address
+			bmcType = "redfish-virtualmedia"
+		}
+		if d.HTTP {
+			bmcType += "+http"
+		}
+		host.Spec.BMC.Address = fmt.Sprintf("%s://%s%s", bmcType, endpoint.Host, system.Path)
+
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// awaitingBMCCredentials returns true for a discovered host whose BMC
+// credentials have not been set yet.
+func awaitingBMCCredentials(host *metal3v1alpha1.BareMetalHost) bool {
+	_, discovered := host.Annotations[metal3v1alpha1.DiscoveredAnnotation]
+	return discovered && host.Spec.BMC.CredentialsName == "" && host.Spec.BMC.CredentialsProvider == ""
+}
@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish/testserver"
+)
+
+func TestDiscoveryNetworkList(t *testing.T) {
+	networks := DiscoveryNetworkList{}
+	assert.NoError(t, networks.Set("192.168.111.0/24"))
+	assert.NoError(t, networks.Set(" fd00::/112"))
+	assert.Equal(t, "192.168.111.0/24,fd00::/112", networks.String())
+
+	assert.EqualError(t, networks.Set("10.0.0.0/8"), "discovery network 10.0.0.0/8 holds more than 65536 addresses")
+	assert.Error(t, networks.Set("10.0.0.1"))
+}
+
+func TestNetworkAddresses(t *testing.T) {
+	testCases := []struct {
+		Network  string
+		Expected []string
+	}{
+		{
+			Network:  "192.168.111.0/30",
+			Expected: []string{"192.168.111.1", "192.168.111.2"},
+		},
+		{
+			Network:  "192.168.111.20/32",
+			Expected: []string{"192.168.111.20"},
+		},
+		{
+			Network:  "fd00::/127",
+			Expected: []string{"fd00::", "fd00::1"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Network, func(t *testing.T) {
+			_, network, err := net.ParseCIDR(tc.Network)
+			require.NoError(t, err)
+			addresses := []string{}
+			for _, ip := range networkAddresses(network) {
+				addresses = append(addresses, ip.String())
+			}
+			assert.Equal(t, tc.Expected, addresses)
+		})
+	}
+}
+
+func newTestDiscoverer(t *testing.T, mock *testserver.RedfishMock, objs ...client.Object) *BMCDiscoverer {
+	endpoint, err := url.Parse(mock.Endpoint())
+	require.NoError(t, err)
+	port, err := strconv.Atoi(endpoint.Port())
+	require.NoError(t, err)
+	_, network, err := net.ParseCIDR(endpoint.Hostname() + "/32")
+	require.NoError(t, err)
+
+	c := fakeclient.NewClientBuilder().WithObjects(objs...).Build()
+	return &BMCDiscoverer{
+		Client:    c,
+		APIReader: c,
+		Log:       ctrl.Log.WithName("controllers").WithName("BMCDiscovery"),
+		Namespace: namespace,
+		Networks:  DiscoveryNetworkList{network},
+		Port:      port,
+		HTTP:      true,
+		Interval:  time.Hour,
+	}
+}
+
+func TestBMCDiscovery(t *testing.T) {
+	mock := testserver.NewRedfish(t).WithCredentials("admin", "password").StartTLS()
+	defer mock.Stop()
+	endpoint, _ := url.Parse(mock.Endpoint())
+	hostName := "discovered-127-0-0-1"
+
+	testCases := []struct {
+		Scenario        string
+		CredentialsName string
+		Untrusted       bool
+		ExpectedAddress string
+		ExpectedMAC     string
+		ExpectedVendor  string
+	}{
+		{
+			Scenario:        "with credentials",
+			CredentialsName: "discovery-creds",
+			ExpectedAddress: "redfish://" + endpoint.Host + testserver.SystemPath,
+			ExpectedMAC:     "00:5c:52:31:3a:9c",
+			ExpectedVendor:  "Dell Inc.",
+		},
+		{
+			Scenario:        "without credentials",
+			ExpectedAddress: "redfish-virtualmedia://" + endpoint.Host,
+		},
+		{
+			Scenario:        "untrusted certificate",
+			CredentialsName: "discovery-creds",
+			Untrusted:       true,
+			ExpectedAddress: "redfish-virtualmedia://" + endpoint.Host,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "discovery-creds", Namespace: namespace},
+				Data:       map[string][]byte{"username": []byte("admin"), "password": []byte("password")},
+			}
+			d := newTestDiscoverer(t, mock, secret)
+			d.HTTP = false
+			if !tc.Untrusted {
+				d.CACertificates = mock.CertPool()
+			}
+			d.CredentialsName = tc.CredentialsName
+			mock.CredentialsReceived = false
+
+			require.NoError(t, d.Discover(context.TODO()))
+
+			host := &metal3v1alpha1.BareMetalHost{}
+			require.NoError(t, d.Get(context.TODO(), client.ObjectKey{Namespace: namespace, Name: hostName}, host))
+			assert.Equal(t, tc.ExpectedAddress, host.Spec.BMC.Address)
+			assert.Empty(t, host.Spec.BMC.CredentialsName)
+			assert.Equal(t, tc.ExpectedMAC, host.Spec.BootMACAddress)
+			assert.False(t, host.Spec.Online)
+			assert.Equal(t, mock.Endpoint(), host.Annotations[metal3v1alpha1.DiscoveredAnnotation])
+			assert.False(t, host.Spec.BMC.DisableCertificateVerification)
+			assert.Equal(t, tc.ExpectedVendor != "", mock.CredentialsReceived)
+
+			if tc.ExpectedVendor == "" {
+				assert.NotContains(t, host.Annotations, hardwareDetailsAnnotation)
+				return
+			}
+			details := &metal3v1alpha1.HardwareDetails{}
+			require.NoError(t, json.Unmarshal([]byte(host.Annotations[hardwareDetailsAnnotation]), details))
+			assert.Equal(t, tc.ExpectedVendor, details.SystemVendor.Manufacturer)
+			assert.Equal(t, "CN7475189J0123", details.SystemVendor.SerialNumber)
+		})
+	}
+}
+
+func TestBMCDiscoverySkipsKnownBMCs(t *testing.T) {
+	mock := testserver.NewRedfish(t).Start()
+	defer mock.Stop()
+
+	existing := newHost("worker-0", &metal3v1alpha1.BareMetalHostSpec{
+		BMC: metal3v1alpha1.BMCDetails{
+			Address:         mock.BMCAddress(),
+			CredentialsName: defaultSecretName,
+		},
+	})
+	d := newTestDiscoverer(t, mock, existing)
+	require.NoError(t, d.Discover(context.TODO()))
+
+	hosts := &metal3v1alpha1.BareMetalHostList{}
+	require.NoError(t, d.List(context.TODO(), hosts))
+	assert.Len(t, hosts.Items, 1)
+	assert.NotContains(t, mock.Requests, "GET /redfish/v1/")
+}
+
+func TestDiscoveredHostAwaitsCredentials(t *testing.T) {
+	host := newHost("discovered-192-168-111-20", &metal3v1alpha1.BareMetalHostSpec{
+		BMC: metal3v1alpha1.BMCDetails{
+			Address: "redfish://192.168.111.20/redfish/v1/Systems/1",
+		},
+		BootMACAddress: "00:5c:52:31:3a:9c",
+	})
+	host.Annotations = map[string]string{
+		metal3v1alpha1.DiscoveredAnnotation: "https://192.168.111.20:443",
+	}
+	r := newTestReconciler(host)
+
+	waitForStatus(t, r, host, metal3v1alpha1.OperationalStatusDiscovered)
+	assert.Equal(t, metal3v1alpha1.StateUnmanaged, host.Status.Provisioning.State)
+	assert.Empty(t, host.Status.ErrorType)
+
+	host.Spec.BMC.CredentialsName = defaultSecretName
+	require.NoError(t, r.Update(context.TODO(), host))
+	waitForProvisioningState(t, r, host, metal3v1alpha1.StateRegistering)
+}
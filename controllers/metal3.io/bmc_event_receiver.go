@@ -0,0 +1,297 @@
+package controllers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/flowcontrol"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+const (
+	// BMCEventsPath is the path under which the BMC event receiver
+	// accepts the events of a host, as BMCEventsPath/<namespace>/<host>.
+	BMCEventsPath = "/bmc-events/"
+
+	maxBMCEventsSize = 1 << 20
+
+	// maxBMCEventsPerDelivery is the largest number of events of a
+	// delivery recorded as Kubernetes events, the others are dropped.
+	maxBMCEventsPerDelivery = 16
+
+	// The rate and burst of the Kubernetes events recorded per host.
+	bmcEventsPerSecond = 0.2
+	bmcEventsBurst     = 20
+)
+
+// bmcEventKind is the kind of change a BMC event reports.
+type bmcEventKind string
+
+const (
+	bmcEventPower     bmcEventKind = "power"
+	bmcEventHealth    bmcEventKind = "health"
+	bmcEventInventory bmcEventKind = "inventory"
+	bmcEventOther     bmcEventKind = "other"
+)
+
+// redfishEvents is the payload of a Redfish event delivery.
+type redfishEvents struct {
+	Events []redfishEvent `json:"Events"`
+}
+
+type redfishEvent struct {
+	EventType         string `json:"EventType"`
+	MessageID         string `json:"MessageId"`
+	Message           string `json:"Message"`
+	Severity          string `json:"Severity"`
+	MessageSeverity   string `json:"MessageSeverity"`
+	OriginOfCondition struct {
+		ID string `json:"@odata.id"`
+	} `json:"OriginOfCondition"`
+}
+
+// severity returns the severity of the event, preferring the
+// MessageSeverity of newer Redfish versions.
+func (e redfishEvent) severity() string {
+	if e.MessageSeverity != "" {
+		return e.MessageSeverity
+	}
+	return e.Severity
+}
+
+// kind classifies the event by the key of its message in the message
+// registry, falling back to its (deprecated) event type.
+func (e redfishEvent) kind() bmcEventKind {
+	key := e.MessageID[strings.LastIndex(e.MessageID, ".")+1:]
+	switch {
+	case strings.Contains(key, "PowerState"), strings.HasPrefix(key, "SystemPower"):
+		return bmcEventPower
+	case strings.HasPrefix(key, "ResourceStatusChanged"), strings.HasPrefix(key, "ResourceErrors"),
+		strings.HasPrefix(key, "ResourceWarning"), strings.HasPrefix(key, "ResourceFailure"):
+		return bmcEventHealth
+	case key == "ResourceCreated", key == "ResourceRemoved", key == "ResourceAdded":
+		return bmcEventInventory
+	}
+	switch e.EventType {
+	case "StatusChange", "Alert":
+		return bmcEventHealth
+	case "ResourceAdded", "ResourceRemoved":
+		return bmcEventInventory
+	}
+	if severity := e.severity(); severity == "Warning" || severity == "Critical" {
+		return bmcEventHealth
+	}
+	return bmcEventOther
+}
+
+// BMCEventReceiver accepts the events BMCs deliver to the destination
+// of a BMCEventSubscription pointing at the operator. Each event is
+// recorded as a Kubernetes event of the host, and power, health and
+// inventory changes trigger an immediate reconcile of the host.
+type BMCEventReceiver struct {
+	client.Client
+	Log logr.Logger
+
+	// Addr is the address the receiver listens on.
+	Addr string
+	// CertFile and KeyFile hold the certificate served over HTTPS.
+	// The receiver serves plain HTTP when they are not set.
+	CertFile string
+	KeyFile  string
+	// Key derives the token the BMC of each host must send as a
+	// bearer token, see BMCEventToken.
+	Key []byte
+	// Events receives the hosts to reconcile.
+	Events chan<- event.GenericEvent
+
+	limitersLock sync.Mutex
+	limiters     map[types.NamespacedName]flowcontrol.RateLimiter
+}
+
+// BMCEventToken returns the token the BMC of a host sends with its
+// events, which is only valid for the events of that host.
+func BMCEventToken(key []byte, host types.NamespacedName) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(host.String()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// isBMCEventDestination returns whether a subscription destination
+// points at the path of the BMC event receiver for the host.
+func isBMCEventDestination(destination string, host types.NamespacedName) bool {
+	parsed, err := url.Parse(destination)
+	if err != nil {
+		return false
+	}
+	return strings.TrimSuffix(parsed.Path, "/") == BMCEventsPath+host.String()
+}
+
+// NeedLeaderElection makes the receiver run only on the leader, whose
+// controller consumes the events.
+func (r *BMCEventReceiver) NeedLeaderElection() bool {
+	return true
+}
+
+// Start serves the events until the context is done.
+func (r *BMCEventReceiver) Start(ctx context.Context) error {
+	if len(r.Key) == 0 {
+		return fmt.Errorf("the BMC event receiver requires a key")
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(BMCEventsPath, r)
+	server := &http.Server{
+		Addr:              r.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		r.Log.Info("starting BMC event receiver", "address", r.Addr)
+		if r.CertFile != "" {
+			errs <- server.ListenAndServeTLS(r.CertFile, r.KeyFile)
+		} else {
+			errs <- server.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
+}
+
+// ServeHTTP handles the delivery of the events of a host.
+func (r *BMCEventReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	namespace, name, found := strings.Cut(strings.TrimPrefix(req.URL.Path, BMCEventsPath), "/")
+	if !found || namespace == "" || name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, req)
+		return
+	}
+	hostName := types.NamespacedName{Namespace: namespace, Name: name}
+
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if len(r.Key) == 0 || !hmac.Equal([]byte(token), []byte(BMCEventToken(r.Key, hostName))) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	log := r.Log.WithValues("baremetalhost", hostName)
+
+	host := &metal3v1alpha1.BareMetalHost{}
+	if err := r.Get(req.Context(), hostName, host); err != nil {
+		if k8serrors.IsNotFound(err) {
+			http.NotFound(w, req)
+			return
+		}
+		log.Error(err, "failed to read the host of BMC events")
+		http.Error(w, "failed to read the host", http.StatusInternalServerError)
+		return
+	}
+
+	payload := redfishEvents{}
+	if err := json.NewDecoder(io.LimitReader(req.Body, maxBMCEventsSize)).Decode(&payload); err != nil {
+		http.Error(w, fmt.Sprintf("invalid Redfish events: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	reconcile := false
+	dropped := 0
+	limiter := r.limiter(hostName)
+	for i, bmcEvent := range payload.Events {
+		kind := bmcEvent.kind()
+		bmcEventsReceived.WithLabelValues(string(kind)).Inc()
+		if kind != bmcEventOther {
+			reconcile = true
+		}
+		if i >= maxBMCEventsPerDelivery || !limiter.TryAccept() {
+			dropped++
+			continue
+		}
+		log.Info("received BMC event", "kind", kind, "messageID", bmcEvent.MessageID,
+			"severity", bmcEvent.severity(), "message", bmcEvent.Message)
+		r.recordEvent(req.Context(), log, host, kind, bmcEvent)
+	}
+	if dropped != 0 {
+		log.Info("too many BMC events, some were not recorded", "dropped", dropped)
+	}
+
+	if reconcile {
+		select {
+		case r.Events <- event.GenericEvent{Object: host}:
+		default:
+			// A reconcile of the host is already pending
+			log.Info("dropping reconcile request for BMC events, the queue is full")
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// limiter returns the rate limiter of the events recorded for a host.
+func (r *BMCEventReceiver) limiter(host types.NamespacedName) flowcontrol.RateLimiter {
+	r.limitersLock.Lock()
+	defer r.limitersLock.Unlock()
+	if r.limiters == nil {
+		r.limiters = map[types.NamespacedName]flowcontrol.RateLimiter{}
+	}
+	limiter, ok := r.limiters[host]
+	if !ok {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(bmcEventsPerSecond, bmcEventsBurst)
+		r.limiters[host] = limiter
+	}
+	return limiter
+}
+
+// recordEvent records a BMC event as a Kubernetes event of the host.
+func (r *BMCEventReceiver) recordEvent(ctx context.Context, log logr.Logger, host *metal3v1alpha1.BareMetalHost, kind bmcEventKind, bmcEvent redfishEvent) {
+	reason := map[bmcEventKind]string{
+		bmcEventPower:     "BMCPowerEvent",
+		bmcEventHealth:    "BMCHealthEvent",
+		bmcEventInventory: "BMCInventoryEvent",
+		bmcEventOther:     "BMCEvent",
+	}[kind]
+
+	message := bmcEvent.Message
+	if message == "" {
+		message = bmcEvent.MessageID
+	}
+	if origin := bmcEvent.OriginOfCondition.ID; origin != "" {
+		message = fmt.Sprintf("%s (%s)", message, origin)
+	}
+
+	hostEvent := host.NewEvent(reason, message)
+	if severity := bmcEvent.severity(); severity == "Warning" || severity == "Critical" {
+		hostEvent.Type = corev1.EventTypeWarning
+	}
+	if err := r.Create(ctx, &hostEvent); err != nil {
+		log.Info("failed to record BMC event, ignoring", "reason", reason, "error", err.Error())
+	}
+}
@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestBMCEventKind(t *testing.T) {
+	testCases := []struct {
+		Scenario string
+		Event    redfishEvent
+		Expected bmcEventKind
+	}{
+		{
+			Scenario: "power state changed",
+			Event:    redfishEvent{MessageID: "ResourceEvent.1.0.ResourcePowerStateChanged"},
+			Expected: bmcEventPower,
+		},
+		{
+			Scenario: "status changed",
+			Event:    redfishEvent{MessageID: "ResourceEvent.1.2.ResourceStatusChangedCritical"},
+			Expected: bmcEventHealth,
+		},
+		{
+			Scenario: "resource created",
+			Event:    redfishEvent{MessageID: "ResourceEvent.1.0.ResourceCreated"},
+			Expected: bmcEventInventory,
+		},
+		{
+			Scenario: "alert event type",
+			Event:    redfishEvent{EventType: "Alert", MessageID: "iDRAC.2.8.PSU0003"},
+			Expected: bmcEventHealth,
+		},
+		{
+			Scenario: "resource added event type",
+			Event:    redfishEvent{EventType: "ResourceAdded"},
+			Expected: bmcEventInventory,
+		},
+		{
+			Scenario: "critical vendor message",
+			Event:    redfishEvent{MessageID: "Vendor.1.0.FanFailed", MessageSeverity: "Critical"},
+			Expected: bmcEventHealth,
+		},
+		{
+			Scenario: "other",
+			Event:    redfishEvent{MessageID: "Base.1.8.Success", Severity: "OK"},
+			Expected: bmcEventOther,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, tc.Event.kind())
+		})
+	}
+}
+
+var (
+	testBMCEventKey   = []byte("0123456789abcdef0123456789abcdef")
+	testBMCEventToken = BMCEventToken(testBMCEventKey, types.NamespacedName{Namespace: namespace, Name: "myhost"})
+)
+
+func TestBMCEventReceiver(t *testing.T) {
+	testCases := []struct {
+		Scenario          string
+		Method            string
+		Path              string
+		Token             string
+		Body              string
+		ExpectedStatus    int
+		ExpectedReconcile bool
+		ExpectedReason    string
+		ExpectedType      string
+	}{
+		{
+			Scenario:          "power event",
+			Method:            http.MethodPost,
+			Path:              BMCEventsPath + namespace + "/myhost",
+			Token:             testBMCEventToken,
+			Body:              `{"Events": [{"MessageId": "ResourceEvent.1.0.ResourcePowerStateChanged", "Message": "The power state changed to Off.", "MessageSeverity": "OK", "OriginOfCondition": {"@odata.id": "/redfish/v1/Systems/1"}}]}`,
+			ExpectedStatus:    http.StatusNoContent,
+			ExpectedReconcile: true,
+			ExpectedReason:    "BMCPowerEvent",
+			ExpectedType:      corev1.EventTypeNormal,
+		},
+		{
+			Scenario:          "critical health event",
+			Method:            http.MethodPost,
+			Path:              BMCEventsPath + namespace + "/myhost",
+			Token:             testBMCEventToken,
+			Body:              `{"Events": [{"EventType": "Alert", "MessageId": "iDRAC.2.8.PSU0003", "Message": "Power supply 1 lost input.", "Severity": "Critical"}]}`,
+			ExpectedStatus:    http.StatusNoContent,
+			ExpectedReconcile: true,
+			ExpectedReason:    "BMCHealthEvent",
+			ExpectedType:      corev1.EventTypeWarning,
+		},
+		{
+			Scenario:       "other event",
+			Method:         http.MethodPost,
+			Path:           BMCEventsPath + namespace + "/myhost",
+			Token:          testBMCEventToken,
+			Body:           `{"Events": [{"MessageId": "Base.1.8.Success", "Message": "Done."}]}`,
+			ExpectedStatus: http.StatusNoContent,
+			ExpectedReason: "BMCEvent",
+			ExpectedType:   corev1.EventTypeNormal,
+		},
+		{
+			Scenario:       "bad token",
+			Method:         http.MethodPost,
+			Path:           BMCEventsPath + namespace + "/myhost",
+			Token:          "wrong",
+			Body:           `{"Events": []}`,
+			ExpectedStatus: http.StatusUnauthorized,
+		},
+		{
+			Scenario:       "token of another host",
+			Method:         http.MethodPost,
+			Path:           BMCEventsPath + namespace + "/otherhost",
+			Token:          testBMCEventToken,
+			Body:           `{"Events": [{"MessageId": "Base.1.8.Success", "Message": "Done."}]}`,
+			ExpectedStatus: http.StatusUnauthorized,
+		},
+		{
+			Scenario:       "unknown host",
+			Method:         http.MethodPost,
+			Path:           BMCEventsPath + namespace + "/otherhost",
+			Token:          BMCEventToken(testBMCEventKey, types.NamespacedName{Namespace: namespace, Name: "otherhost"}),
+			Body:           `{"Events": []}`,
+			ExpectedStatus: http.StatusNotFound,
+		},
+		{
+			Scenario:       "invalid payload",
+			Method:         http.MethodPost,
+			Path:           BMCEventsPath + namespace + "/myhost",
+			Token:          testBMCEventToken,
+			Body:           `not json`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Scenario:       "get",
+			Method:         http.MethodGet,
+			Path:           BMCEventsPath + namespace + "/myhost",
+			Token:          testBMCEventToken,
+			ExpectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			host := newDefaultNamedHost("myhost", t)
+			events := make(chan event.GenericEvent, 1)
+			receiver := &BMCEventReceiver{
+				Client: fakeclient.NewClientBuilder().WithObjects(host).Build(),
+				Log:    ctrl.Log.WithName("controllers").WithName("BMCEventReceiver"),
+				Key:    testBMCEventKey,
+				Events: events,
+			}
+
+			req := httptest.NewRequest(tc.Method, tc.Path, strings.NewReader(tc.Body))
+			req.Header.Set("Authorization", "Bearer "+tc.Token)
+			w := httptest.NewRecorder()
+			receiver.ServeHTTP(w, req)
+			assert.Equal(t, tc.ExpectedStatus, w.Code)
+
+			select {
+			case e := <-events:
+				assert.True(t, tc.ExpectedReconcile, "unexpected reconcile")
+				assert.Equal(t, "myhost", e.Object.GetName())
+			default:
+				assert.False(t, tc.ExpectedReconcile, "missing reconcile")
+			}
+
+			hostEvents := &corev1.EventList{}
+			require.NoError(t, receiver.List(context.TODO(), hostEvents))
+			if tc.ExpectedReason == "" {
+				assert.Empty(t, hostEvents.Items)
+				return
+			}
+			require.Len(t, hostEvents.Items, 1)
+			assert.Equal(t, tc.ExpectedReason, hostEvents.Items[0].Reason)
+			assert.Equal(t, tc.ExpectedType, hostEvents.Items[0].Type)
+			assert.Equal(t, "myhost", hostEvents.Items[0].InvolvedObject.Name)
+		})
+	}
+}
+
+func TestBMCEventReceiverLimits(t *testing.T) {
+	host := newDefaultNamedHost("myhost", t)
+	receiver := &BMCEventReceiver{
+		Client: fakeclient.NewClientBuilder().WithObjects(host).Build(),
+		Log:    ctrl.Log.WithName("controllers").WithName("BMCEventReceiver"),
+		Key:    testBMCEventKey,
+		Events: make(chan event.GenericEvent, 1),
+	}
+
+	deliver := func(count int) {
+		bmcEvents := make([]string, count)
+		for i := range bmcEvents {
+			bmcEvents[i] = `{"MessageId": "Base.1.8.Success", "Message": "Done."}`
+		}
+		req := httptest.NewRequest(http.MethodPost, BMCEventsPath+namespace+"/myhost",
+			strings.NewReader(`{"Events": [`+strings.Join(bmcEvents, ",")+`]}`))
+		req.Header.Set("Authorization", "Bearer "+testBMCEventToken)
+		w := httptest.NewRecorder()
+		receiver.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	}
+	recorded := func() int {
+		hostEvents := &corev1.EventList{}
+		require.NoError(t, receiver.List(context.TODO(), hostEvents))
+		return len(hostEvents.Items)
+	}
+
+	// Only the first events of a delivery are recorded
+	deliver(100)
+	assert.Equal(t, maxBMCEventsPerDelivery, recorded())
+
+	// The events of the host are rate limited across deliveries
+	deliver(maxBMCEventsPerDelivery)
+	assert.Equal(t, bmcEventsBurst, recorded())
+}
+
+func TestBMCEventDestination(t *testing.T) {
+	host := types.NamespacedName{Namespace: namespace, Name: "myhost"}
+	assert.True(t, isBMCEventDestination("https://bmo.example.com:9445/bmc-events/"+namespace+"/myhost", host))
+	assert.True(t, isBMCEventDestination("https://bmo.example.com:9445/bmc-events/"+namespace+"/myhost/", host))
+	assert.False(t, isBMCEventDestination("https://bmo.example.com:9445/bmc-events/"+namespace+"/otherhost", host))
+	assert.False(t, isBMCEventDestination("https://collector.example.com/events", host))
+}
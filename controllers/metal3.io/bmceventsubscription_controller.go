@@ -50,6 +50,9 @@ type BMCEventSubscriptionReconciler struct {
 	Log                logr.Logger
 	ProvisionerFactory provisioner.Factory
 	APIReader          client.Reader
+	// EventReceiverKey derives the tokens sent by the BMCs to the BMC
+	// event receiver, when it is enabled.
+	EventReceiverKey []byte
 }
 
 //+kubebuilder:rbac:groups=metal3.io,resources=bmceventsubscriptions,verbs=get;list;watch;create;update;patch;delete
@@ -187,9 +190,22 @@ func (r *BMCEventSubscriptionReconciler) createSubscription(ctx context.Context,
 		return err
 	}
 
-	if _, err := prov.AddBMCEventSubscriptionForNode(subscription, headers); err != nil {
+	// Subscriptions delivering the events to the receiver of the
+	// operator authenticate with the token of their host
+	hostName := types.NamespacedName{Namespace: subscription.Namespace, Name: subscription.Spec.HostName}
+	if len(r.EventReceiverKey) != 0 && isBMCEventDestination(subscription.Spec.Destination, hostName) {
+		headers = append(headers, map[string]string{
+			"Authorization": "Bearer " + BMCEventToken(r.EventReceiverKey, hostName),
+		})
+	}
+
+	provResult, err := prov.AddBMCEventSubscriptionForNode(subscription, headers)
+	if err != nil {
 		return errors.Wrap(err, "failed to create subscription")
 	}
+	// The provisioner may not support subscriptions, which is not
+	// retried
+	subscription.Status.Error = provResult.ErrorMessage
 
 	return r.Status().Update(ctx, subscription)
 }
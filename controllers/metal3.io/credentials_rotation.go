@@ -0,0 +1,416 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+	"github.com/metal3-io/baremetal-operator/pkg/secretutils"
+)
+
+const (
+	// credentialsRotationRetryDelay is how long to wait before trying
+	// again after a failed rotation of the BMC credentials.
+	credentialsRotationRetryDelay = time.Hour
+
+	// rotatedUsernameSuffix is added to or removed from the user name
+	// of the BMC account at each rotation of the credentials.
+	rotatedUsernameSuffix = "-r"
+
+	passwordCharacters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// credentialsRotationPolicy returns the rotation policy of the BMC
+// credentials of the host, falling back to the annotation of its
+// namespace. It returns nil when rotation is not enabled.
+func (r *BareMetalHostReconciler) credentialsRotationPolicy(host *metal3v1alpha1.BareMetalHost) (*metal3v1alpha1.CredentialsRotationPolicy, error) {
+	if host.Spec.BMC.CredentialsRotation != nil {
+		return host.Spec.BMC.CredentialsRotation, nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := r.Get(context.TODO(), types.NamespacedName{Name: host.Namespace}, ns); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to get namespace")
+	}
+
+	value, ok := ns.Annotations[metal3v1alpha1.CredentialsRotationIntervalAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s annotation on namespace %s",
+			metal3v1alpha1.CredentialsRotationIntervalAnnotation, host.Namespace)
+	}
+	if interval < metal3v1alpha1.MinCredentialsRotationInterval {
+		return nil, errors.Errorf("%s annotation on namespace %s must be at least %s",
+			metal3v1alpha1.CredentialsRotationIntervalAnnotation, host.Namespace,
+			metal3v1alpha1.MinCredentialsRotationInterval)
+	}
+	return &metal3v1alpha1.CredentialsRotationPolicy{
+		Interval: metav1.Duration{Duration: interval},
+	}, nil
+}
+
+// credentialsRotationFailedRecently returns whether the last attempt
+// to rotate the BMC credentials of the host failed too recently to try
+// again.
+func credentialsRotationFailedRecently(host *metal3v1alpha1.BareMetalHost, now time.Time) bool {
+	status := host.Status.CredentialsRotation
+	return status != nil && status.LastFailure != nil &&
+		now.Before(status.LastFailure.Add(credentialsRotationRetryDelay))
+}
+
+// credentialsRotationDue returns whether the BMC credentials of the
+// host must be rotated. The first rotation happens one interval after
+// the creation of the host.
+func credentialsRotationDue(host *metal3v1alpha1.BareMetalHost, policy *metal3v1alpha1.CredentialsRotationPolicy, now time.Time) bool {
+	if credentialsRotationFailedRecently(host, now) {
+		return false
+	}
+	last := host.CreationTimestamp.Time
+	if status := host.Status.CredentialsRotation; status != nil {
+		if status.LastRotated != nil {
+			last = status.LastRotated.Time
+		}
+	}
+	return !now.Before(last.Add(policy.Interval.Duration))
+}
+
+// generatePassword returns a random password containing lower and
+// upper case letters and digits, as required by most BMCs.
+func generatePassword(length int) (string, error) {
+	max := big.NewInt(int64(len(passwordCharacters)))
+	for {
+		password := make([]byte, length)
+		for i := range password {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			password[i] = passwordCharacters[n.Int64()]
+		}
+		if strings.ContainsAny(string(password), passwordCharacters[:26]) &&
+			strings.ContainsAny(string(password), passwordCharacters[26:52]) &&
+			strings.ContainsAny(string(password), passwordCharacters[52:]) {
+			return string(password), nil
+		}
+	}
+}
+
+func pendingCredentialsKey(host *metal3v1alpha1.BareMetalHost) types.NamespacedName {
+	return types.NamespacedName{
+		Namespace: host.Namespace,
+		Name:      host.Name + "-bmc-secret-rotation",
+	}
+}
+
+// rotatedUsername returns the user name of the BMC account the
+// credentials of the account with the user name are rotated to.
+// Rotations alternate between the two accounts, so that the previous
+// one keeps working until the new credentials are validated and
+// stored.
+func rotatedUsername(username string) string {
+	if trimmed := strings.TrimSuffix(username, rotatedUsernameSuffix); trimmed != username {
+		return trimmed
+	}
+	return username + rotatedUsernameSuffix
+}
+
+// getPendingCredentials returns the secret holding the BMC credentials
+// being rotated to, or nil when no rotation is in progress.
+func (r *BareMetalHostReconciler) getPendingCredentials(host *metal3v1alpha1.BareMetalHost) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := r.Get(context.TODO(), pendingCredentialsKey(host), secret)
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get pending BMC credentials")
+	}
+	return secret, nil
+}
+
+// createPendingCredentials starts a new rotation by storing the
+// credentials of the BMC account to rotate to, along with the user name
+// of the account to retire. They are stored before the account is
+// created, so that they are not lost if the rotation is interrupted.
+func (r *BareMetalHostReconciler) createPendingCredentials(info *reconcileInfo, policy *metal3v1alpha1.CredentialsRotationPolicy) (*corev1.Secret, error) {
+	length := policy.PasswordLength
+	if length == 0 {
+		length = metal3v1alpha1.DefaultCredentialsRotationPasswordLength
+	}
+	password, err := generatePassword(length)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate BMC password")
+	}
+
+	username := string(info.bmcCredsSecret.Data["username"])
+	key := pendingCredentialsKey(info.host)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+			Labels: map[string]string{
+				secretutils.LabelEnvironmentName: secretutils.LabelEnvironmentValue,
+			},
+		},
+		Data: map[string][]byte{
+			"username":         []byte(rotatedUsername(username)),
+			"password":         []byte(password),
+			"previousUsername": []byte(username),
+		},
+	}
+	if err := controllerutil.SetOwnerReference(info.host, secret, r.Scheme()); err != nil {
+		return nil, errors.Wrap(err, "failed to set owner of pending BMC credentials")
+	}
+	if err := r.Create(context.TODO(), secret); err != nil {
+		return nil, errors.Wrap(err, "failed to store pending BMC credentials")
+	}
+	info.log.Info("starting rotation of BMC credentials")
+	return secret, nil
+}
+
+// checkCredentialsRotation rotates the BMC credentials of a host in a
+// steady state when its rotation policy requires it, and completes a
+// rotation in progress. Rotation only starts from credentials that
+// were validated, to avoid locking the operator out of the BMC.
+// Credentials fetched from a credentials provider are managed outside
+// of the cluster and never rotated.
+func (hsm *hostStateMachine) checkCredentialsRotation(info *reconcileInfo) actionResult {
+	switch hsm.Host.Status.Provisioning.State {
+	case metal3v1alpha1.StateAvailable, metal3v1alpha1.StateReady,
+		metal3v1alpha1.StateProvisioned, metal3v1alpha1.StateExternallyProvisioned:
+	default:
+		return nil
+	}
+	if !hsm.haveCreds || !hsm.Host.DeletionTimestamp.IsZero() ||
+		hsm.Host.Spec.BMC.CredentialsProvider != "" ||
+		hsm.Host.Status.ErrorType != "" ||
+		hsm.Host.OperationalStatus() != metal3v1alpha1.OperationalStatusOK ||
+		!hsm.Host.Status.GoodCredentials.Match(*info.bmcCredsSecret) ||
+		!hsm.Host.Status.TriedCredentials.Match(*info.bmcCredsSecret) {
+		return nil
+	}
+
+	pending, err := hsm.Reconciler.getPendingCredentials(hsm.Host)
+	if err != nil {
+		return actionError{err}
+	}
+	if pending != nil {
+		if credentialsRotationFailedRecently(hsm.Host, time.Now()) {
+			return nil
+		}
+		return hsm.Reconciler.actionRotateCredentials(hsm.Provisioner, info, pending)
+	}
+
+	policy, err := hsm.Reconciler.credentialsRotationPolicy(hsm.Host)
+	if err != nil {
+		return actionError{err}
+	}
+	if policy == nil || !credentialsRotationDue(hsm.Host, policy, time.Now()) {
+		return nil
+	}
+	pending, err = hsm.Reconciler.createPendingCredentials(info, policy)
+	if err != nil {
+		return actionError{err}
+	}
+	return hsm.Reconciler.actionRotateCredentials(hsm.Provisioner, info, pending)
+}
+
+// actionRotateCredentials rotates the BMC credentials to the pending
+// ones. The new BMC account is created and validated before it is
+// written to the credentials secret of the host, and the previous
+// account is only disabled once the secret holds the new credentials.
+func (r *BareMetalHostReconciler) actionRotateCredentials(prov provisioner.Provisioner, info *reconcileInfo, pending *corev1.Secret) actionResult {
+	newCreds := bmc.Credentials{
+		Username: string(pending.Data["username"]),
+		Password: string(pending.Data["password"]),
+	}
+	previousUsername := string(pending.Data["previousUsername"])
+
+	if info.host.Status.CredentialsRotation == nil {
+		info.host.Status.CredentialsRotation = &metal3v1alpha1.CredentialsRotationStatus{}
+	}
+	status := info.host.Status.CredentialsRotation
+
+	if string(info.bmcCredsSecret.Data["username"]) != newCreds.Username ||
+		string(info.bmcCredsSecret.Data["password"]) != newCreds.Password {
+		return r.storeRotatedCredentials(prov, info, pending, newCreds)
+	}
+
+	// The secret holds the new credentials, so the provisioner uses
+	// them to retire the previous account.
+	provResult, err := prov.DisableBMCAccount(previousUsername)
+	if err != nil {
+		return actionError{errors.Wrap(err, "failed to disable previous BMC account")}
+	}
+	if provResult.ErrorMessage != "" {
+		// The rotation is kept pending so that disabling the previous
+		// account is tried again later.
+		info.log.Info("failed to disable previous BMC account", "error", provResult.ErrorMessage)
+		now := metav1.Now()
+		status.LastFailure = &now
+		status.FailureMessage = provResult.ErrorMessage
+		info.publishEvent("CredentialsRotationFailed", provResult.ErrorMessage)
+		return actionUpdate{}
+	}
+	if provResult.Dirty {
+		return actionContinue{provResult.RequeueAfter}
+	}
+
+	if err := r.Delete(context.TODO(), pending); err != nil && !k8serrors.IsNotFound(err) {
+		return actionError{errors.Wrap(err, "failed to delete pending BMC credentials")}
+	}
+
+	info.log.Info("rotated BMC credentials")
+	now := metav1.Now()
+	status.LastRotated = &now
+	status.LastFailure = nil
+	status.FailureMessage = ""
+	info.publishEvent("CredentialsRotated",
+		fmt.Sprintf("Rotated the BMC credentials to account %s", newCreds.Username))
+	return actionUpdate{}
+}
+
+// credentialsSecretShared returns whether other hosts use the BMC
+// credentials secret of the host.
+func (r *BareMetalHostReconciler) credentialsSecretShared(host *metal3v1alpha1.BareMetalHost) (bool, error) {
+	hosts := &metal3v1alpha1.BareMetalHostList{}
+	if err := r.List(context.TODO(), hosts, client.InNamespace(host.Namespace)); err != nil {
+		return false, errors.Wrap(err, "failed to list hosts")
+	}
+	for _, other := range hosts.Items {
+		if other.Name != host.Name && other.Spec.BMC.CredentialsName == host.Spec.BMC.CredentialsName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// storeRotatedCredentials creates the BMC account of the new
+// credentials, validates them and writes them to the credentials
+// secret of the host. The secret is not written when other hosts use
+// it, since they would be given credentials only valid on this BMC.
+func (r *BareMetalHostReconciler) storeRotatedCredentials(prov provisioner.Provisioner, info *reconcileInfo, pending *corev1.Secret, newCreds bmc.Credentials) actionResult {
+	shared, err := r.credentialsSecretShared(info.host)
+	if err != nil {
+		return actionError{err}
+	}
+	if shared {
+		return r.credentialsRotationFailed(info, pending,
+			fmt.Sprintf("BMC credentials secret %s is used by other hosts and can not be rotated",
+				info.host.Spec.BMC.CredentialsName))
+	}
+
+	provResult, err := prov.CreateBMCAccount(newCreds)
+	if err != nil {
+		return actionError{errors.Wrap(err, "failed to create BMC account")}
+	}
+	if provResult.ErrorMessage != "" {
+		return r.credentialsRotationFailed(info, pending, provResult.ErrorMessage)
+	}
+	if provResult.Dirty {
+		return actionContinue{provResult.RequeueAfter}
+	}
+
+	provResult, err = r.validateRotatedCredentials(info, newCreds)
+	if err != nil {
+		return actionError{errors.Wrap(err, "failed to validate rotated BMC credentials")}
+	}
+	if provResult.ErrorMessage != "" {
+		// The provisioner may have been given the new credentials, so
+		// the current ones are registered again.
+		info.host.Status.GoodCredentials = metal3v1alpha1.CredentialsStatus{}
+		info.host.Status.TriedCredentials = metal3v1alpha1.CredentialsStatus{}
+		if disableResult, err := prov.DisableBMCAccount(newCreds.Username); err != nil || disableResult.ErrorMessage != "" {
+			info.log.Info("failed to disable rejected BMC account", "error", err, "message", disableResult.ErrorMessage)
+		}
+		return r.credentialsRotationFailed(info, pending, provResult.ErrorMessage)
+	}
+	if provResult.Dirty {
+		return actionContinue{provResult.RequeueAfter}
+	}
+
+	secret := info.bmcCredsSecret.DeepCopy()
+	secret.Data["username"] = []byte(newCreds.Username)
+	secret.Data["password"] = []byte(newCreds.Password)
+	if err := r.Update(context.TODO(), secret); err != nil {
+		return actionError{errors.Wrap(err, "failed to update BMC credentials")}
+	}
+	info.bmcCredsSecret = secret
+	info.host.UpdateGoodCredentials(*secret)
+	info.host.UpdateTriedCredentials(*secret)
+	info.log.Info("stored rotated BMC credentials", "username", newCreds.Username)
+	return actionUpdate{}
+}
+
+// validateRotatedCredentials validates the access to the BMC of the
+// host with the new credentials.
+func (r *BareMetalHostReconciler) validateRotatedCredentials(info *reconcileInfo, newCreds bmc.Credentials) (provisioner.Result, error) {
+	hostData := provisioner.BuildHostData(*info.host, newCreds)
+	caCertificates, fingerprint, err := r.bmcTrustedCertificates(info.host)
+	if err != nil {
+		return provisioner.Result{}, err
+	}
+	hostData.BMCCACertificates = caCertificates
+	hostData.BMCCertificateFingerprint = fingerprint
+	prov, err := r.ProvisionerFactory.NewProvisioner(hostData, info.publishEvent)
+	if err != nil {
+		return provisioner.Result{}, errors.Wrap(err, "failed to create provisioner")
+	}
+
+	var preprovImgFormats []metal3v1alpha1.ImageFormat
+	if info.host.Status.Provisioning.State != metal3v1alpha1.StateExternallyProvisioned {
+		if preprovImgFormats, err = prov.PreprovisioningImageFormats(); err != nil {
+			return provisioner.Result{}, err
+		}
+	}
+	preprovImg, err := r.getPreprovImage(info, preprovImgFormats)
+	if err != nil {
+		return provisioner.Result{}, err
+	}
+
+	provResult, _, err := prov.ValidateManagementAccess(
+		provisioner.ManagementAccessData{
+			BootMode:              info.host.Status.Provisioning.BootMode,
+			AutomatedCleaningMode: info.host.Spec.AutomatedCleaningMode,
+			State:                 info.host.Status.Provisioning.State,
+			CurrentImage:          getCurrentImage(info.host),
+			PreprovisioningImage:  preprovImg,
+			HasCustomDeploy:       hasCustomDeploy(info.host),
+		},
+		true, false)
+	return provResult, err
+}
+
+// credentialsRotationFailed abandons a rotation whose new credentials
+// were not accepted, so that new ones are tried at the next attempt.
+func (r *BareMetalHostReconciler) credentialsRotationFailed(info *reconcileInfo, pending *corev1.Secret, message string) actionResult {
+	info.log.Info("failed to rotate BMC credentials", "error", message)
+	if err := r.Delete(context.TODO(), pending); err != nil && !k8serrors.IsNotFound(err) {
+		return actionError{errors.Wrap(err, "failed to delete pending BMC credentials")}
+	}
+	now := metav1.Now()
+	info.host.Status.CredentialsRotation.LastFailure = &now
+	info.host.Status.CredentialsRotation.FailureMessage = message
+	info.publishEvent("CredentialsRotationFailed", message)
+	return actionUpdate{}
+}
@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/fixture"
+)
+
+func TestCredentialsRotationDue(t *testing.T) {
+	now := time.Now()
+	ago := func(d time.Duration) *metav1.Time {
+		t := metav1.NewTime(now.Add(-d))
+		return &t
+	}
+	policy := &metal3v1alpha1.CredentialsRotationPolicy{
+		Interval: metav1.Duration{Duration: 24 * time.Hour},
+	}
+
+	testCases := []struct {
+		Scenario string
+		Created  *metav1.Time
+		Status   *metal3v1alpha1.CredentialsRotationStatus
+		Expected bool
+	}{
+		{
+			Scenario: "new host",
+			Created:  ago(time.Hour),
+			Expected: false,
+		},
+		{
+			Scenario: "old host",
+			Created:  ago(48 * time.Hour),
+			Expected: true,
+		},
+		{
+			Scenario: "recently rotated",
+			Created:  ago(48 * time.Hour),
+			Status:   &metal3v1alpha1.CredentialsRotationStatus{LastRotated: ago(time.Hour)},
+			Expected: false,
+		},
+		{
+			Scenario: "rotated long ago",
+			Created:  ago(96 * time.Hour),
+			Status:   &metal3v1alpha1.CredentialsRotationStatus{LastRotated: ago(48 * time.Hour)},
+			Expected: true,
+		},
+		{
+			Scenario: "recent failure",
+			Created:  ago(48 * time.Hour),
+			Status:   &metal3v1alpha1.CredentialsRotationStatus{LastFailure: ago(time.Minute)},
+			Expected: false,
+		},
+		{
+			Scenario: "old failure",
+			Created:  ago(48 * time.Hour),
+			Status:   &metal3v1alpha1.CredentialsRotationStatus{LastFailure: ago(2 * time.Hour)},
+			Expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			host := newDefaultHost(t)
+			host.CreationTimestamp = *tc.Created
+			host.Status.CredentialsRotation = tc.Status
+			assert.Equal(t, tc.Expected, credentialsRotationDue(host, policy, now))
+		})
+	}
+}
+
+func TestCredentialsRotationPolicy(t *testing.T) {
+	testCases := []struct {
+		Scenario         string
+		HostPolicy       *metal3v1alpha1.CredentialsRotationPolicy
+		Annotation       string
+		ExpectedInterval time.Duration
+		ExpectedError    bool
+	}{
+		{
+			Scenario: "disabled",
+		},
+		{
+			Scenario:         "host",
+			HostPolicy:       &metal3v1alpha1.CredentialsRotationPolicy{Interval: metav1.Duration{Duration: 2 * time.Hour}},
+			Annotation:       "720h",
+			ExpectedInterval: 2 * time.Hour,
+		},
+		{
+			Scenario:         "namespace",
+			Annotation:       "720h",
+			ExpectedInterval: 720 * time.Hour,
+		},
+		{
+			Scenario:      "invalid annotation",
+			Annotation:    "monthly",
+			ExpectedError: true,
+		},
+		{
+			Scenario:      "annotation too short",
+			Annotation:    "5m",
+			ExpectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+			if tc.Annotation != "" {
+				ns.Annotations = map[string]string{
+					metal3v1alpha1.CredentialsRotationIntervalAnnotation: tc.Annotation,
+				}
+			}
+			host := newDefaultHost(t)
+			host.Spec.BMC.CredentialsRotation = tc.HostPolicy
+			r := newTestReconciler(ns)
+
+			policy, err := r.credentialsRotationPolicy(host)
+			if tc.ExpectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if tc.ExpectedInterval == 0 {
+				assert.Nil(t, policy)
+			} else {
+				assert.Equal(t, tc.ExpectedInterval, policy.Interval.Duration)
+			}
+		})
+	}
+}
+
+func TestGeneratePassword(t *testing.T) {
+	password, err := generatePassword(16)
+	assert.NoError(t, err)
+	assert.Len(t, password, 16)
+	assert.Regexp(t, "[a-z]", password)
+	assert.Regexp(t, "[A-Z]", password)
+	assert.Regexp(t, "[0-9]", password)
+
+	other, err := generatePassword(16)
+	assert.NoError(t, err)
+	assert.NotEqual(t, password, other)
+}
+
+func TestRotatedUsername(t *testing.T) {
+	assert.Equal(t, "admin-r", rotatedUsername("admin"))
+	assert.Equal(t, "admin", rotatedUsername("admin-r"))
+}
+
+// newRotationTest returns a state machine and reconcile info for a
+// provisioned host whose BMC credentials must be rotated.
+func newRotationTest(t *testing.T, fix *fixture.Fixture) (*hostStateMachine, *reconcileInfo) {
+	host := newDefaultHost(t)
+	host.CreationTimestamp = metav1.NewTime(time.Now().Add(-48 * time.Hour))
+	host.Spec.BMC.CredentialsRotation = &metal3v1alpha1.CredentialsRotationPolicy{
+		Interval: metav1.Duration{Duration: 24 * time.Hour},
+	}
+	host.Status.Provisioning.State = metal3v1alpha1.StateProvisioned
+	host.Status.Provisioning.ID = "fake-id"
+	host.Status.OperationalStatus = metal3v1alpha1.OperationalStatusOK
+	r := newTestReconcilerWithFixture(fix, host)
+
+	secret := &corev1.Secret{}
+	require.NoError(t, r.Get(context.TODO(), host.CredentialsKey(), secret))
+	host.UpdateGoodCredentials(*secret)
+	host.UpdateTriedCredentials(*secret)
+
+	prov, err := fix.NewProvisioner(provisioner.BuildHostData(*host, bmc.Credentials{}), nil)
+	require.NoError(t, err)
+
+	info := &reconcileInfo{
+		log:            logf.Log.WithName("test"),
+		host:           host,
+		bmcCredsSecret: secret,
+	}
+	return newHostStateMachine(host, r, prov, true), info
+}
+
+func TestRotateCredentials(t *testing.T) {
+	fix := &fixture.Fixture{}
+	hsm, info := newRotationTest(t, fix)
+	username := string(info.bmcCredsSecret.Data["username"])
+
+	// The new account is created, validated and stored
+	result := hsm.checkCredentialsRotation(info)
+	assert.Equal(t, actionUpdate{}, result)
+	password, created := fix.BMCAccounts[username+"-r"]
+	require.True(t, created)
+
+	secret := &corev1.Secret{}
+	require.NoError(t, hsm.Reconciler.Get(context.TODO(), info.host.CredentialsKey(), secret))
+	assert.Equal(t, username+"-r", string(secret.Data["username"]))
+	assert.Equal(t, password, string(secret.Data["password"]))
+	assert.True(t, info.host.Status.GoodCredentials.Match(*secret))
+	assert.Empty(t, fix.DisabledBMCAccounts)
+	assert.Empty(t, info.events)
+
+	// The previous account is retired once the secret is updated
+	result = hsm.checkCredentialsRotation(info)
+	assert.Equal(t, actionUpdate{}, result)
+	assert.Equal(t, []string{username}, fix.DisabledBMCAccounts)
+
+	err := hsm.Reconciler.Get(context.TODO(), pendingCredentialsKey(info.host), &corev1.Secret{})
+	assert.True(t, k8serrors.IsNotFound(err))
+
+	assert.NotNil(t, info.host.Status.CredentialsRotation.LastRotated)
+	assert.Len(t, info.events, 1)
+	assert.Equal(t, "CredentialsRotated", info.events[0].Reason)
+
+	// The next rotation is not due yet
+	assert.Nil(t, hsm.checkCredentialsRotation(info))
+}
+
+func TestRotateCredentialsResumesPendingAccount(t *testing.T) {
+	fix := &fixture.Fixture{}
+	hsm, info := newRotationTest(t, fix)
+	info.host.Spec.BMC.CredentialsRotation = nil
+
+	pending := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pendingCredentialsKey(info.host).Name,
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			"username":         []byte("admin-r"),
+			"password":         []byte("Pending"),
+			"previousUsername": info.bmcCredsSecret.Data["username"],
+		},
+	}
+	require.NoError(t, hsm.Reconciler.Create(context.TODO(), pending))
+
+	assert.Equal(t, actionUpdate{}, hsm.checkCredentialsRotation(info))
+	assert.Equal(t, "Pending", fix.BMCAccounts["admin-r"])
+	assert.Equal(t, "Pending", string(info.bmcCredsSecret.Data["password"]))
+}
+
+func TestRotateCredentialsFailure(t *testing.T) {
+	fix := &fixture.Fixture{}
+	fix.SetCreateAccountError("password too weak")
+	hsm, info := newRotationTest(t, fix)
+	username := string(info.bmcCredsSecret.Data["username"])
+	oldPassword := string(info.bmcCredsSecret.Data["password"])
+
+	result := hsm.checkCredentialsRotation(info)
+	assert.Equal(t, actionUpdate{}, result)
+
+	secret := &corev1.Secret{}
+	require.NoError(t, hsm.Reconciler.Get(context.TODO(), info.host.CredentialsKey(), secret))
+	assert.Equal(t, username, string(secret.Data["username"]))
+	assert.Equal(t, oldPassword, string(secret.Data["password"]))
+
+	err := hsm.Reconciler.Get(context.TODO(), pendingCredentialsKey(info.host), &corev1.Secret{})
+	assert.True(t, k8serrors.IsNotFound(err))
+
+	status := info.host.Status.CredentialsRotation
+	assert.Nil(t, status.LastRotated)
+	assert.NotNil(t, status.LastFailure)
+	assert.Equal(t, "password too weak", status.FailureMessage)
+	assert.Equal(t, "CredentialsRotationFailed", info.events[0].Reason)
+
+	// Rotation is not retried immediately
+	assert.Nil(t, hsm.checkCredentialsRotation(info))
+}
+
+func TestRotateCredentialsValidationFailure(t *testing.T) {
+	fix := &fixture.Fixture{}
+	fix.SetValidateError("invalid credentials")
+	hsm, info := newRotationTest(t, fix)
+	username := string(info.bmcCredsSecret.Data["username"])
+	oldPassword := string(info.bmcCredsSecret.Data["password"])
+
+	result := hsm.checkCredentialsRotation(info)
+	assert.Equal(t, actionUpdate{}, result)
+
+	secret := &corev1.Secret{}
+	require.NoError(t, hsm.Reconciler.Get(context.TODO(), info.host.CredentialsKey(), secret))
+	assert.Equal(t, username, string(secret.Data["username"]))
+	assert.Equal(t, oldPassword, string(secret.Data["password"]))
+	assert.Equal(t, []string{username + "-r"}, fix.DisabledBMCAccounts)
+
+	// The current credentials are registered again
+	assert.False(t, info.host.Status.GoodCredentials.Match(*secret))
+	assert.False(t, info.host.Status.TriedCredentials.Match(*secret))
+
+	status := info.host.Status.CredentialsRotation
+	assert.Nil(t, status.LastRotated)
+	assert.Equal(t, "invalid credentials", status.FailureMessage)
+}
+
+func TestRotateCredentialsSharedSecret(t *testing.T) {
+	fix := &fixture.Fixture{}
+	hsm, info := newRotationTest(t, fix)
+	username := string(info.bmcCredsSecret.Data["username"])
+	oldPassword := string(info.bmcCredsSecret.Data["password"])
+
+	other := newDefaultNamedHost("other-host", t)
+	require.NoError(t, hsm.Reconciler.Create(context.TODO(), other))
+
+	result := hsm.checkCredentialsRotation(info)
+	assert.Equal(t, actionUpdate{}, result)
+	assert.Empty(t, fix.BMCAccounts)
+
+	secret := &corev1.Secret{}
+	require.NoError(t, hsm.Reconciler.Get(context.TODO(), info.host.CredentialsKey(), secret))
+	assert.Equal(t, username, string(secret.Data["username"]))
+	assert.Equal(t, oldPassword, string(secret.Data["password"]))
+
+	err := hsm.Reconciler.Get(context.TODO(), pendingCredentialsKey(info.host), &corev1.Secret{})
+	assert.True(t, k8serrors.IsNotFound(err))
+
+	status := info.host.Status.CredentialsRotation
+	assert.Nil(t, status.LastRotated)
+	assert.Contains(t, status.FailureMessage, "used by other hosts")
+	assert.Equal(t, "CredentialsRotationFailed", info.events[0].Reason)
+}
+
+func TestRotateCredentialsRequiresGoodCredentials(t *testing.T) {
+	fix := &fixture.Fixture{}
+	hsm, info := newRotationTest(t, fix)
+	info.host.Status.GoodCredentials = metal3v1alpha1.CredentialsStatus{}
+
+	assert.Nil(t, hsm.checkCredentialsRotation(info))
+	assert.Empty(t, fix.BMCAccounts)
+
+	err := hsm.Reconciler.Get(context.TODO(), pendingCredentialsKey(info.host), &corev1.Secret{})
+	assert.True(t, k8serrors.IsNotFound(err))
+}
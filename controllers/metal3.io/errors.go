@@ -37,6 +37,28 @@ func (e ResolveBMCSecretRefError) Error() string {
 		e.message)
 }
 
+// CredentialsProviderError is returned when the BMC credentials
+// of a host cannot be fetched from its credentials provider
+type CredentialsProviderError struct {
+	message string
+}
+
+func (e CredentialsProviderError) Error() string {
+	return fmt.Sprintf("BMC credentials provider failed %s",
+		e.message)
+}
+
+// BMCCertificatesError is returned when the trusted certificates
+// of the BMC of a host cannot be loaded
+type BMCCertificatesError struct {
+	message string
+}
+
+func (e BMCCertificatesError) Error() string {
+	return fmt.Sprintf("BMC trusted certificates failed %s",
+		e.message)
+}
+
 // NoDataInSecretError is returned when host configuration
 // data were not found in referenced secret
 type NoDataInSecretError struct {
@@ -0,0 +1,297 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+// hostConditionReason is the reason recorded on a BareMetalHost
+// condition.
+type hostConditionReason string
+
+const (
+	reasonHostUnmanaged             hostConditionReason = "Unmanaged"
+	reasonHostRegistering           hostConditionReason = "Registering"
+	reasonHostRegistered            hostConditionReason = "Registered"
+	reasonHostDetached              hostConditionReason = "Detached"
+	reasonHostInspecting            hostConditionReason = "Inspecting"
+	reasonHostInspected             hostConditionReason = "Inspected"
+	reasonHostInspectionDisabled    hostConditionReason = "InspectionDisabled"
+	reasonHostNotInspected          hostConditionReason = "NotInspected"
+	reasonHostPreparing             hostConditionReason = "Preparing"
+	reasonHostPrepared              hostConditionReason = "Prepared"
+	reasonHostNotPrepared           hostConditionReason = "NotPrepared"
+	reasonHostProvisioning          hostConditionReason = "Provisioning"
+	reasonHostProvisioned           hostConditionReason = "Provisioned"
+	reasonHostExternallyProvisioned hostConditionReason = "ExternallyProvisioned"
+	reasonHostDeprovisioning        hostConditionReason = "Deprovisioning"
+	reasonHostServicing             hostConditionReason = "Servicing"
+	reasonHostNotProvisioned        hostConditionReason = "NotProvisioned"
+	reasonHostPowerSynced           hostConditionReason = "Synced"
+	reasonHostPowerChangePending    hostConditionReason = "PowerChangePending"
+	reasonHostPowerNotManaged       hostConditionReason = "PowerNotManaged"
+	reasonHostAvailable             hostConditionReason = "Available"
+	reasonHostDelayed               hostConditionReason = "Delayed"
+	reasonHostDeleting              hostConditionReason = "Deleting"
+	reasonHostInProgress            hostConditionReason = "InProgress"
+	reasonHostRetrying              hostConditionReason = "Retrying"
+	reasonHostNoError               hostConditionReason = "NoError"
+	reasonHostHealthy               hostConditionReason = "Healthy"
+	reasonHostHealthWarning         hostConditionReason = "HealthWarning"
+	reasonHostHealthCritical        hostConditionReason = "HealthCritical"
+	reasonHostHealthNotReported     hostConditionReason = "HealthNotReported"
+)
+
+// errorConditionReasons maps the ErrorType of a host to the reason
+// used for the conditions it affects.
+var errorConditionReasons = map[metal3v1alpha1.ErrorType]hostConditionReason{
+	metal3v1alpha1.ProvisionedRegistrationError: "ProvisionedRegistrationError",
+	metal3v1alpha1.RegistrationError:            "RegistrationError",
+	metal3v1alpha1.InspectionError:              "InspectionError",
+	metal3v1alpha1.PreparationError:             "PreparationError",
+	metal3v1alpha1.ProvisioningError:            "ProvisioningError",
+	metal3v1alpha1.PowerManagementError:         "PowerManagementError",
+	metal3v1alpha1.DetachError:                  "DetachError",
+	metal3v1alpha1.TimeoutError:                 "TimeoutError",
+	metal3v1alpha1.ServicingError:               "ServicingError",
+	metal3v1alpha1.ImageVerificationError:       "ImageVerificationError",
+}
+
+func errorConditionReason(errType metal3v1alpha1.ErrorType) hostConditionReason {
+	if reason, ok := errorConditionReasons[errType]; ok {
+		return reason
+	}
+	return "Error"
+}
+
+// hostInSteadyState returns true when the host is in one of the states
+// in which it waits for the user rather than for an operation.
+func hostInSteadyState(host *metal3v1alpha1.BareMetalHost) bool {
+	switch host.Status.Provisioning.State {
+	case metal3v1alpha1.StateAvailable, metal3v1alpha1.StateReady,
+		metal3v1alpha1.StateProvisioned, metal3v1alpha1.StateExternallyProvisioned:
+		return true
+	}
+	return false
+}
+
+func newHostCondition(condType metal3v1alpha1.HostConditionType, status metav1.ConditionStatus, reason hostConditionReason, message string) metav1.Condition {
+	return metav1.Condition{
+		Type:    string(condType),
+		Status:  status,
+		Reason:  string(reason),
+		Message: message,
+	}
+}
+
+// timedOutIn returns true if the host has timed out in the given state.
+func timedOutIn(host *metal3v1alpha1.BareMetalHost, state metal3v1alpha1.ProvisioningState) bool {
+	return host.Status.ErrorType == metal3v1alpha1.TimeoutError &&
+		host.Status.Provisioning.State == state
+}
+
+func hostErrorCondition(host *metal3v1alpha1.BareMetalHost, condType metal3v1alpha1.HostConditionType) metav1.Condition {
+	return newHostCondition(condType, metav1.ConditionFalse,
+		errorConditionReason(host.Status.ErrorType), host.Status.ErrorMessage)
+}
+
+func registeredCondition(host *metal3v1alpha1.BareMetalHost) metav1.Condition {
+	cond := metal3v1alpha1.HostRegistered
+	switch {
+	case host.Status.ErrorType == metal3v1alpha1.RegistrationError,
+		host.Status.ErrorType == metal3v1alpha1.ProvisionedRegistrationError:
+		return hostErrorCondition(host, cond)
+	case host.Status.OperationalStatus == metal3v1alpha1.OperationalStatusDetached:
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostDetached, "")
+	}
+
+	switch host.Status.Provisioning.State {
+	case metal3v1alpha1.StateUnmanaged:
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostUnmanaged, "")
+	case metal3v1alpha1.StateNone, metal3v1alpha1.StateRegistering:
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostRegistering, "")
+	}
+	return newHostCondition(cond, metav1.ConditionTrue, reasonHostRegistered, "")
+}
+
+func inspectedCondition(host *metal3v1alpha1.BareMetalHost) metav1.Condition {
+	cond := metal3v1alpha1.HostInspected
+	switch {
+	case host.Status.ErrorType == metal3v1alpha1.InspectionError,
+		timedOutIn(host, metal3v1alpha1.StateInspecting):
+		return hostErrorCondition(host, cond)
+	case host.Status.Provisioning.State == metal3v1alpha1.StateInspecting:
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostInspecting, "")
+	case host.Status.HardwareDetails != nil:
+		return newHostCondition(cond, metav1.ConditionTrue, reasonHostInspected, "")
+	case inspectionDisabled(host):
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostInspectionDisabled, "")
+	}
+	return newHostCondition(cond, metav1.ConditionFalse, reasonHostNotInspected, "")
+}
+
+func preparedCondition(host *metal3v1alpha1.BareMetalHost) metav1.Condition {
+	cond := metal3v1alpha1.HostPrepared
+	if host.Status.ErrorType == metal3v1alpha1.PreparationError ||
+		timedOutIn(host, metal3v1alpha1.StatePreparing) {
+		return hostErrorCondition(host, cond)
+	}
+
+	switch host.Status.Provisioning.State {
+	case metal3v1alpha1.StatePreparing:
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostPreparing, "")
+	case metal3v1alpha1.StateAvailable, metal3v1alpha1.StateReady,
+		metal3v1alpha1.StateProvisioning, metal3v1alpha1.StateProvisioned:
+		return newHostCondition(cond, metav1.ConditionTrue, reasonHostPrepared, "")
+	}
+	return newHostCondition(cond, metav1.ConditionFalse, reasonHostNotPrepared, "")
+}
+
+func provisionedCondition(host *metal3v1alpha1.BareMetalHost) metav1.Condition {
+	cond := metal3v1alpha1.HostProvisioned
+	if host.Status.ErrorType == metal3v1alpha1.ProvisioningError ||
+		host.Status.ErrorType == metal3v1alpha1.ImageVerificationError ||
+		timedOutIn(host, metal3v1alpha1.StateProvisioning) ||
+		timedOutIn(host, metal3v1alpha1.StateDeprovisioning) {
+		return hostErrorCondition(host, cond)
+	}
+
+	switch host.Status.Provisioning.State {
+	case metal3v1alpha1.StateProvisioned:
+		return newHostCondition(cond, metav1.ConditionTrue, reasonHostProvisioned, "")
+	case metal3v1alpha1.StateExternallyProvisioned:
+		return newHostCondition(cond, metav1.ConditionTrue, reasonHostExternallyProvisioned, "")
+	case metal3v1alpha1.StateProvisioning:
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostProvisioning, "")
+	case metal3v1alpha1.StateDeprovisioning:
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostDeprovisioning, "")
+	}
+	return newHostCondition(cond, metav1.ConditionFalse, reasonHostNotProvisioned, "")
+}
+
+func powerStateSyncedCondition(host *metal3v1alpha1.BareMetalHost) metav1.Condition {
+	cond := metal3v1alpha1.HostPowerStateSynced
+	switch {
+	case host.Status.ErrorType == metal3v1alpha1.PowerManagementError:
+		return hostErrorCondition(host, cond)
+	case !hostInSteadyState(host),
+		host.Status.OperationalStatus == metal3v1alpha1.OperationalStatusDetached:
+		return newHostCondition(cond, metav1.ConditionUnknown, reasonHostPowerNotManaged, "")
+	case host.Status.PoweredOn != host.Spec.Online:
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostPowerChangePending,
+			fmt.Sprintf("host is powered %s, expected %s",
+				powerStateName(host.Status.PoweredOn), powerStateName(host.Spec.Online)))
+	}
+	return newHostCondition(cond, metav1.ConditionTrue, reasonHostPowerSynced, "")
+}
+
+func powerStateName(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+func readyCondition(host *metal3v1alpha1.BareMetalHost) metav1.Condition {
+	cond := metal3v1alpha1.HostReady
+	switch host.Status.OperationalStatus {
+	case metal3v1alpha1.OperationalStatusError, metal3v1alpha1.OperationalStatusNeedsOperator:
+		return hostErrorCondition(host, cond)
+	case metal3v1alpha1.OperationalStatusDetached:
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostDetached, "")
+	case metal3v1alpha1.OperationalStatusDelayed:
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostDelayed, "")
+	case metal3v1alpha1.OperationalStatusServicing:
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostServicing, "")
+	}
+
+	state := host.Status.Provisioning.State
+	switch {
+	case !host.DeletionTimestamp.IsZero(), state == metal3v1alpha1.StateDeleting:
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostDeleting, "")
+	case state == metal3v1alpha1.StateUnmanaged:
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostUnmanaged, "")
+	case !hostInSteadyState(host):
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostInProgress,
+			fmt.Sprintf("host is %s", state))
+	case host.Status.PoweredOn != host.Spec.Online:
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostPowerChangePending, "")
+	case state == metal3v1alpha1.StateProvisioned:
+		return newHostCondition(cond, metav1.ConditionTrue, reasonHostProvisioned, "")
+	case state == metal3v1alpha1.StateExternallyProvisioned:
+		return newHostCondition(cond, metav1.ConditionTrue, reasonHostExternallyProvisioned, "")
+	}
+	return newHostCondition(cond, metav1.ConditionTrue, reasonHostAvailable, "")
+}
+
+func needsOperatorCondition(host *metal3v1alpha1.BareMetalHost) metav1.Condition {
+	cond := metal3v1alpha1.HostNeedsOperator
+	switch {
+	case host.Status.OperationalStatus == metal3v1alpha1.OperationalStatusNeedsOperator:
+		return newHostCondition(cond, metav1.ConditionTrue,
+			errorConditionReason(host.Status.ErrorType), host.Status.ErrorMessage)
+	case host.Status.ErrorType != "":
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostRetrying, "")
+	}
+	return newHostCondition(cond, metav1.ConditionFalse, reasonHostNoError, "")
+}
+
+func healthyCondition(host *metal3v1alpha1.BareMetalHost) metav1.Condition {
+	cond := metal3v1alpha1.HostHealthy
+	health := host.Status.HardwareHealth
+	if health == nil || len(health.Components) == 0 {
+		return newHostCondition(cond, metav1.ConditionUnknown, reasonHostHealthNotReported, "")
+	}
+
+	unhealthy := []string{}
+	for _, component := range health.Components {
+		if component.Health != metal3v1alpha1.HealthOK {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s is %s", component.Name, component.Health))
+		}
+	}
+	message := strings.Join(unhealthy, ", ")
+
+	switch health.Health {
+	case metal3v1alpha1.HealthCritical:
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostHealthCritical, message)
+	case metal3v1alpha1.HealthWarning:
+		return newHostCondition(cond, metav1.ConditionFalse, reasonHostHealthWarning, message)
+	}
+	return newHostCondition(cond, metav1.ConditionTrue, reasonHostHealthy, "")
+}
+
+// updateHostConditions recomputes the status conditions of the host
+// from its provisioning state, operational status, error, power state
+// and hardware health. It returns true if any of the conditions changed.
+func updateHostConditions(host *metal3v1alpha1.BareMetalHost) (changed bool) {
+	conditions := []metav1.Condition{
+		registeredCondition(host),
+		inspectedCondition(host),
+		preparedCondition(host),
+		provisionedCondition(host),
+		powerStateSyncedCondition(host),
+		readyCondition(host),
+		needsOperatorCondition(host),
+		healthyCondition(host),
+	}
+
+	for _, cond := range conditions {
+		cond.ObservedGeneration = host.Generation
+		existing := meta.FindStatusCondition(host.Status.Conditions, cond.Type)
+		if existing != nil &&
+			existing.Status == cond.Status &&
+			existing.Reason == cond.Reason &&
+			existing.Message == cond.Message &&
+			existing.ObservedGeneration == cond.ObservedGeneration {
+			continue
+		}
+		meta.SetStatusCondition(&host.Status.Conditions, cond)
+		changed = true
+	}
+	return
+}
@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+func TestUpdateHostConditions(t *testing.T) {
+	testCases := []struct {
+		Scenario string
+		Host     *metal3v1alpha1.BareMetalHost
+		Expected map[metal3v1alpha1.HostConditionType]metav1.ConditionStatus
+		Reasons  map[metal3v1alpha1.HostConditionType]hostConditionReason
+	}{
+		{
+			Scenario: "registering",
+			Host:     host(metal3v1alpha1.StateRegistering).build(),
+			Expected: map[metal3v1alpha1.HostConditionType]metav1.ConditionStatus{
+				metal3v1alpha1.HostRegistered:       metav1.ConditionFalse,
+				metal3v1alpha1.HostInspected:        metav1.ConditionFalse,
+				metal3v1alpha1.HostPrepared:         metav1.ConditionFalse,
+				metal3v1alpha1.HostProvisioned:      metav1.ConditionFalse,
+				metal3v1alpha1.HostPowerStateSynced: metav1.ConditionUnknown,
+				metal3v1alpha1.HostReady:            metav1.ConditionFalse,
+			},
+			Reasons: map[metal3v1alpha1.HostConditionType]hostConditionReason{
+				metal3v1alpha1.HostRegistered: reasonHostRegistering,
+				metal3v1alpha1.HostReady:      reasonHostInProgress,
+			},
+		},
+		{
+			Scenario: "inspection-disabled",
+			Host:     host(metal3v1alpha1.StatePreparing).DisableInspection().build(),
+			Expected: map[metal3v1alpha1.HostConditionType]metav1.ConditionStatus{
+				metal3v1alpha1.HostRegistered: metav1.ConditionTrue,
+				metal3v1alpha1.HostInspected:  metav1.ConditionFalse,
+				metal3v1alpha1.HostPrepared:   metav1.ConditionFalse,
+			},
+			Reasons: map[metal3v1alpha1.HostConditionType]hostConditionReason{
+				metal3v1alpha1.HostInspected: reasonHostInspectionDisabled,
+				metal3v1alpha1.HostPrepared:  reasonHostPreparing,
+			},
+		},
+		{
+			Scenario: "inspection-error",
+			Host: host(metal3v1alpha1.StateInspecting).
+				SetStatusError(metal3v1alpha1.OperationalStatusError, metal3v1alpha1.InspectionError, "boom", 1).
+				build(),
+			Expected: map[metal3v1alpha1.HostConditionType]metav1.ConditionStatus{
+				metal3v1alpha1.HostRegistered: metav1.ConditionTrue,
+				metal3v1alpha1.HostInspected:  metav1.ConditionFalse,
+				metal3v1alpha1.HostReady:      metav1.ConditionFalse,
+			},
+			Reasons: map[metal3v1alpha1.HostConditionType]hostConditionReason{
+				metal3v1alpha1.HostInspected: "InspectionError",
+				metal3v1alpha1.HostReady:     "InspectionError",
+			},
+		},
+		{
+			Scenario: "available",
+			Host:     host(metal3v1alpha1.StateAvailable).SetOnline(false).SetStatusPoweredOn(false).build(),
+			Expected: map[metal3v1alpha1.HostConditionType]metav1.ConditionStatus{
+				metal3v1alpha1.HostRegistered:       metav1.ConditionTrue,
+				metal3v1alpha1.HostPrepared:         metav1.ConditionTrue,
+				metal3v1alpha1.HostProvisioned:      metav1.ConditionFalse,
+				metal3v1alpha1.HostPowerStateSynced: metav1.ConditionTrue,
+				metal3v1alpha1.HostReady:            metav1.ConditionTrue,
+			},
+			Reasons: map[metal3v1alpha1.HostConditionType]hostConditionReason{
+				metal3v1alpha1.HostProvisioned: reasonHostNotProvisioned,
+				metal3v1alpha1.HostReady:       reasonHostAvailable,
+			},
+		},
+		{
+			Scenario: "provisioned-power-pending",
+			Host:     host(metal3v1alpha1.StateProvisioned).SetOnline(true).SetStatusPoweredOn(false).build(),
+			Expected: map[metal3v1alpha1.HostConditionType]metav1.ConditionStatus{
+				metal3v1alpha1.HostProvisioned:      metav1.ConditionTrue,
+				metal3v1alpha1.HostPowerStateSynced: metav1.ConditionFalse,
+				metal3v1alpha1.HostReady:            metav1.ConditionFalse,
+			},
+			Reasons: map[metal3v1alpha1.HostConditionType]hostConditionReason{
+				metal3v1alpha1.HostPowerStateSynced: reasonHostPowerChangePending,
+				metal3v1alpha1.HostReady:            reasonHostPowerChangePending,
+			},
+		},
+		{
+			Scenario: "provisioned",
+			Host:     host(metal3v1alpha1.StateProvisioned).build(),
+			Expected: map[metal3v1alpha1.HostConditionType]metav1.ConditionStatus{
+				metal3v1alpha1.HostRegistered:       metav1.ConditionTrue,
+				metal3v1alpha1.HostPrepared:         metav1.ConditionTrue,
+				metal3v1alpha1.HostProvisioned:      metav1.ConditionTrue,
+				metal3v1alpha1.HostPowerStateSynced: metav1.ConditionTrue,
+				metal3v1alpha1.HostReady:            metav1.ConditionTrue,
+				metal3v1alpha1.HostHealthy:          metav1.ConditionUnknown,
+			},
+		},
+		{
+			Scenario: "externally-provisioned",
+			Host:     host(metal3v1alpha1.StateExternallyProvisioned).SetExternallyProvisioned().build(),
+			Expected: map[metal3v1alpha1.HostConditionType]metav1.ConditionStatus{
+				metal3v1alpha1.HostProvisioned: metav1.ConditionTrue,
+				metal3v1alpha1.HostReady:       metav1.ConditionTrue,
+			},
+			Reasons: map[metal3v1alpha1.HostConditionType]hostConditionReason{
+				metal3v1alpha1.HostProvisioned: reasonHostExternallyProvisioned,
+			},
+		},
+		{
+			Scenario: "hardware-healthy",
+			Host: host(metal3v1alpha1.StateProvisioned).SetHardwareHealth(
+				metal3v1alpha1.ComponentHealth{Name: "System", Health: metal3v1alpha1.HealthOK},
+			).build(),
+			Expected: map[metal3v1alpha1.HostConditionType]metav1.ConditionStatus{
+				metal3v1alpha1.HostHealthy: metav1.ConditionTrue,
+			},
+			Reasons: map[metal3v1alpha1.HostConditionType]hostConditionReason{
+				metal3v1alpha1.HostHealthy: reasonHostHealthy,
+			},
+		},
+		{
+			Scenario: "hardware-warning",
+			Host: host(metal3v1alpha1.StateProvisioned).SetHardwareHealth(
+				metal3v1alpha1.ComponentHealth{Name: "System", Health: metal3v1alpha1.HealthOK},
+				metal3v1alpha1.ComponentHealth{Name: "Memory", Health: metal3v1alpha1.HealthWarning},
+			).build(),
+			Expected: map[metal3v1alpha1.HostConditionType]metav1.ConditionStatus{
+				metal3v1alpha1.HostReady:   metav1.ConditionTrue,
+				metal3v1alpha1.HostHealthy: metav1.ConditionFalse,
+			},
+			Reasons: map[metal3v1alpha1.HostConditionType]hostConditionReason{
+				metal3v1alpha1.HostHealthy: reasonHostHealthWarning,
+			},
+		},
+		{
+			Scenario: "provisioning-error",
+			Host: host(metal3v1alpha1.StateProvisioning).
+				SetStatusError(metal3v1alpha1.OperationalStatusError, metal3v1alpha1.ProvisioningError, "boom", 1).
+				build(),
+			Expected: map[metal3v1alpha1.HostConditionType]metav1.ConditionStatus{
+				metal3v1alpha1.HostPrepared:    metav1.ConditionTrue,
+				metal3v1alpha1.HostProvisioned: metav1.ConditionFalse,
+				metal3v1alpha1.HostReady:       metav1.ConditionFalse,
+			},
+			Reasons: map[metal3v1alpha1.HostConditionType]hostConditionReason{
+				metal3v1alpha1.HostProvisioned: "ProvisioningError",
+			},
+		},
+		{
+			Scenario: "detached",
+			Host:     host(metal3v1alpha1.StateProvisioned).SetOperationalStatus(metal3v1alpha1.OperationalStatusDetached).build(),
+			Expected: map[metal3v1alpha1.HostConditionType]metav1.ConditionStatus{
+				metal3v1alpha1.HostRegistered:       metav1.ConditionFalse,
+				metal3v1alpha1.HostProvisioned:      metav1.ConditionTrue,
+				metal3v1alpha1.HostPowerStateSynced: metav1.ConditionUnknown,
+				metal3v1alpha1.HostReady:            metav1.ConditionFalse,
+			},
+			Reasons: map[metal3v1alpha1.HostConditionType]hostConditionReason{
+				metal3v1alpha1.HostRegistered: reasonHostDetached,
+				metal3v1alpha1.HostReady:      reasonHostDetached,
+			},
+		},
+		{
+			Scenario: "servicing",
+			Host:     host(metal3v1alpha1.StateProvisioned).SetOperationalStatus(metal3v1alpha1.OperationalStatusServicing).build(),
+			Expected: map[metal3v1alpha1.HostConditionType]metav1.ConditionStatus{
+				metal3v1alpha1.HostProvisioned: metav1.ConditionTrue,
+				metal3v1alpha1.HostReady:       metav1.ConditionFalse,
+			},
+			Reasons: map[metal3v1alpha1.HostConditionType]hostConditionReason{
+				metal3v1alpha1.HostReady: reasonHostServicing,
+			},
+		},
+		{
+			Scenario: "needs-operator",
+			Host: host(metal3v1alpha1.StatePreparing).
+				SetStatusError(metal3v1alpha1.OperationalStatusNeedsOperator, metal3v1alpha1.PreparationError, "boom", 5).
+				build(),
+			Expected: map[metal3v1alpha1.HostConditionType]metav1.ConditionStatus{
+				metal3v1alpha1.HostPrepared:      metav1.ConditionFalse,
+				metal3v1alpha1.HostReady:         metav1.ConditionFalse,
+				metal3v1alpha1.HostNeedsOperator: metav1.ConditionTrue,
+			},
+			Reasons: map[metal3v1alpha1.HostConditionType]hostConditionReason{
+				metal3v1alpha1.HostNeedsOperator: "PreparationError",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			tc.Host.Generation = 3
+			tc.Host.Status.Conditions = nil
+
+			assert.True(t, updateHostConditions(tc.Host))
+			assert.Len(t, tc.Host.Status.Conditions, 8)
+			for condType, status := range tc.Expected {
+				cond := meta.FindStatusCondition(tc.Host.Status.Conditions, string(condType))
+				if assert.NotNil(t, cond, "missing condition %s", condType) {
+					assert.Equal(t, status, cond.Status, "status of %s", condType)
+					assert.Equal(t, int64(3), cond.ObservedGeneration)
+				}
+			}
+			for condType, reason := range tc.Reasons {
+				cond := meta.FindStatusCondition(tc.Host.Status.Conditions, string(condType))
+				if assert.NotNil(t, cond, "missing condition %s", condType) {
+					assert.Equal(t, string(reason), cond.Reason, "reason of %s", condType)
+				}
+			}
+
+			// A second pass with nothing changed is a no-op
+			assert.False(t, updateHostConditions(tc.Host))
+		})
+	}
+}
+
+func TestUpdateHostConditionsObservedGeneration(t *testing.T) {
+	host := host(metal3v1alpha1.StateProvisioned).build()
+	assert.False(t, updateHostConditions(host))
+
+	host.Generation++
+	assert.True(t, updateHostConditions(host))
+	for _, cond := range host.Status.Conditions {
+		assert.Equal(t, host.Generation, cond.ObservedGeneration)
+	}
+}
+
+func TestConditionChangeMarksResultDirty(t *testing.T) {
+	host := host(metal3v1alpha1.StateProvisioned).build()
+	host.Status.Conditions = nil
+
+	prov := newMockProvisioner()
+	hsm := newHostStateMachine(host, testNewReconciler(host), prov, true)
+	result := hsm.ReconcileState(makeDefaultReconcileInfo(host))
+
+	assert.True(t, result.Dirty())
+	assert.True(t, meta.IsStatusConditionTrue(host.Status.Conditions, string(metal3v1alpha1.HostReady)))
+
+	// Once the conditions are up to date the steady state is not dirty
+	result = hsm.ReconcileState(makeDefaultReconcileInfo(host))
+	assert.False(t, result.Dirty())
+}
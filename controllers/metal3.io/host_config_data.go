@@ -1,11 +1,17 @@
 package controllers
 
 import (
+	"encoding/json"
+	"strings"
+
 	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
 
 	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/networkdata"
 	"github.com/metal3-io/baremetal-operator/pkg/secretutils"
 )
 
@@ -15,6 +21,7 @@ type hostConfigData struct {
 	host          *metal3v1alpha1.BareMetalHost
 	log           logr.Logger
 	secretManager secretutils.SecretManager
+	poolAddresses map[string]networkdata.PoolAddress
 }
 
 // Generic method for data extraction from a Secret. Function uses dataKey
@@ -66,6 +73,9 @@ func (hcd *hostConfigData) UserData() (string, error) {
 // NetworkData get network configuration
 func (hcd *hostConfigData) NetworkData() (string, error) {
 	networkData := hcd.host.Spec.NetworkData
+	if networkData == nil && hcd.host.Spec.Network != nil {
+		return renderNetworkData(hcd.host, hcd.poolAddresses)
+	}
 	if networkData == nil && hcd.host.Spec.PreprovisioningNetworkDataName != "" {
 		networkData = &corev1.SecretReference{
 			Name: hcd.host.Spec.PreprovisioningNetworkDataName,
@@ -98,15 +108,43 @@ func (hcd *hostConfigData) NetworkData() (string, error) {
 func (hcd *hostConfigData) MetaData() (string, error) {
 	if hcd.host.Spec.MetaData == nil {
 		hcd.log.Info("MetaData is not set returning empty(nil) data")
-		return "", nil
+		return hcd.poolMetaData(nil)
 	}
 	namespace := hcd.host.Spec.MetaData.Namespace
 	if namespace == "" {
 		namespace = hcd.host.Namespace
 	}
-	return hcd.getSecretData(
+	metaData, err := hcd.getSecretData(
 		hcd.host.Spec.MetaData.Name,
 		namespace,
 		"metaData",
 	)
+	if err != nil || len(hcd.poolAddresses) == 0 {
+		return metaData, err
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(metaData), &values); err != nil {
+		return "", errors.Wrap(err, "failed to unmarshal metadata from secret")
+	}
+	return hcd.poolMetaData(values)
+}
+
+// poolMetaData adds the addresses allocated to the host from IP pools to
+// its metadata, as metal3-ip-<pool> keys the metadata secret does not
+// already set.
+func (hcd *hostConfigData) poolMetaData(values map[string]interface{}) (string, error) {
+	if len(hcd.poolAddresses) == 0 {
+		return "", nil
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	for pool, address := range hcd.poolAddresses {
+		key := "metal3-ip-" + pool
+		if _, exists := values[key]; !exists {
+			values[key] = strings.Split(address.Address, "/")[0]
+		}
+	}
+	metaData, err := json.Marshal(values)
+	return string(metaData), err
 }
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/networkdata"
 	"github.com/metal3-io/baremetal-operator/pkg/secretutils"
 
 	corev1 "k8s.io/api/core/v1"
@@ -214,6 +215,57 @@ func TestProvisionWithHostConfig(t *testing.T) {
 			ExpectedNetworkData: base64.StdEncoding.EncodeToString([]byte("key: value")),
 			ErrNetworkData:      false,
 		},
+		{
+			Scenario: "host with network configuration",
+			Host: newHost("host-network",
+				&metal3v1alpha1.BareMetalHostSpec{
+					BMC: metal3v1alpha1.BMCDetails{
+						Address:         "ipmi://192.168.122.1:6233",
+						CredentialsName: defaultSecretName,
+					},
+					Network: &metal3v1alpha1.NetworkConfig{
+						Links:     []metal3v1alpha1.NetworkLink{{Name: "eth0", MACAddress: "00:11:22:33:44:55"}},
+						Addresses: []metal3v1alpha1.NetworkAddress{{Interface: "eth0", Type: metal3v1alpha1.DHCP4Address}},
+					},
+				}),
+			ExpectedNetworkData: `{"links":[{"id":"eth0","type":"phy","ethernet_mac_address":"00:11:22:33:44:55"}],` +
+				`"networks":[{"id":"network0","type":"ipv4_dhcp","link":"eth0","network_id":"network0"}]}`,
+			ErrNetworkData: false,
+		},
+		{
+			Scenario: "host with network data and network configuration",
+			Host: newHost("host-network",
+				&metal3v1alpha1.BareMetalHostSpec{
+					BMC: metal3v1alpha1.BMCDetails{
+						Address:         "ipmi://192.168.122.1:6233",
+						CredentialsName: defaultSecretName,
+					},
+					NetworkData: &corev1.SecretReference{
+						Name: "net-data",
+					},
+					Network: &metal3v1alpha1.NetworkConfig{
+						Links: []metal3v1alpha1.NetworkLink{{Name: "eth0", MACAddress: "00:11:22:33:44:55"}},
+					},
+				}),
+			NetworkDataSecret:   newSecret("net-data", map[string]string{"networkData": "key: value"}),
+			ExpectedNetworkData: base64.StdEncoding.EncodeToString([]byte("key: value")),
+			ErrNetworkData:      false,
+		},
+		{
+			Scenario: "host with network configuration of NICs not inspected",
+			Host: newHost("host-network",
+				&metal3v1alpha1.BareMetalHostSpec{
+					BMC: metal3v1alpha1.BMCDetails{
+						Address:         "ipmi://192.168.122.1:6233",
+						CredentialsName: defaultSecretName,
+					},
+					Network: &metal3v1alpha1.NetworkConfig{
+						Links: []metal3v1alpha1.NetworkLink{{Name: "eth0", NIC: "eno1"}},
+					},
+				}),
+			ExpectedNetworkData: "",
+			ErrNetworkData:      true,
+		},
 		{
 			Scenario: "host with metadata only",
 			Host: newHost("host-meta-data",
@@ -372,3 +424,48 @@ func TestProvisionWithHostConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestPoolMetaData(t *testing.T) {
+	poolAddresses := map[string]networkdata.PoolAddress{
+		"provisioning": {Address: "192.168.111.20/24"},
+		"storage":      {Address: "10.0.0.20/16"},
+	}
+	testCases := []struct {
+		name     string
+		metaData string
+		expected string
+	}{
+		{
+			name:     "no metadata secret",
+			expected: `{"metal3-ip-provisioning": "192.168.111.20", "metal3-ip-storage": "10.0.0.20"}`,
+		},
+		{
+			name:     "metadata secret",
+			metaData: "key: value\nmetal3-ip-storage: 10.0.0.99",
+			expected: `{"key": "value", "metal3-ip-provisioning": "192.168.111.20", "metal3-ip-storage": "10.0.0.99"}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			host := newHost("host", &metal3v1alpha1.BareMetalHostSpec{})
+			c := fakeclient.NewClientBuilder().Build()
+			if tc.metaData != "" {
+				host.Spec.MetaData = &corev1.SecretReference{Name: "meta-data", Namespace: namespace}
+				secret := newSecret("meta-data", nil)
+				secret.Data = map[string][]byte{"metaData": []byte(tc.metaData)}
+				c.Create(context.TODO(), secret)
+			}
+			baselog := ctrl.Log.WithName("controllers").WithName("BareMetalHost")
+			hcd := &hostConfigData{
+				host:          host,
+				log:           baselog.WithName("host_config_data"),
+				secretManager: secretutils.NewSecretManager(baselog, c, c),
+				poolAddresses: poolAddresses,
+			}
+
+			metaData, err := hcd.MetaData()
+			assert.NoError(t, err)
+			assert.JSONEq(t, tc.expected, metaData)
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
@@ -120,6 +122,7 @@ func (hsm *hostStateMachine) updateHostStateFrom(initialState metal3v1alpha1.Pro
 			stateChanges.With(stateChangeMetricLabels(initialState, hsm.NextState)).Inc()
 		})
 		hsm.Host.Status.Provisioning.State = hsm.NextState
+		hsm.Host.Status.Progress = nil
 		// Here we assume that if we're being asked to change the
 		// state, the return value of ReconcileState (our caller) is
 		// set up to ensure the change in the host is written back to
@@ -170,13 +173,76 @@ func (hsm *hostStateMachine) checkDelayedHost(info *reconcileInfo) actionResult
 	return nil
 }
 
+func (hsm *hostStateMachine) checkStateTimeout(info *reconcileInfo) actionResult {
+	state := hsm.Host.Status.Provisioning.State
+	metric := hsm.Host.OperationMetricForState(state)
+	if metric == nil || metric.Start.IsZero() {
+		return nil
+	}
+
+	timeout := hsm.Reconciler.stateTimeout(info, state)
+	if timeout <= 0 {
+		return nil
+	}
+
+	switch hsm.Host.Status.ErrorType {
+	case "":
+	case metal3v1alpha1.TimeoutError:
+		// The start time is reset by restartStateTimeout once the
+		// operation is retried
+		return nil
+	default:
+		// Other failures are handled by the state itself
+		return nil
+	}
+
+	if time.Since(metric.Start.Time) < timeout {
+		return nil
+	}
+	return hsm.Reconciler.actionTimedOut(hsm.Provisioner, info, timeout)
+}
+
+// restartStateTimeout resets the start time of the current state when
+// its timeout error was cleared without leaving the state, so that the
+// new attempt gets the full timeout. It returns true if the start time
+// was reset.
+func restartStateTimeout(host *metal3v1alpha1.BareMetalHost, initialState metal3v1alpha1.ProvisioningState, initialErrorType metal3v1alpha1.ErrorType) bool {
+	if initialErrorType != metal3v1alpha1.TimeoutError ||
+		host.Status.ErrorType == metal3v1alpha1.TimeoutError ||
+		host.Status.Provisioning.State != initialState {
+		return false
+	}
+	metric := host.OperationMetricForState(initialState)
+	if metric == nil || metric.Start.IsZero() {
+		return false
+	}
+	metric.Start = metav1.Now()
+	return true
+}
+
 func (hsm *hostStateMachine) ReconcileState(info *reconcileInfo) (actionRes actionResult) {
 	initialState := hsm.Host.Status.Provisioning.State
+	initialErrorType := hsm.Host.Status.ErrorType
 
 	defer func() {
 		if overrideAction := hsm.updateHostStateFrom(initialState, info); overrideAction != nil {
 			actionRes = overrideAction
 		}
+
+		timeoutRestarted := restartStateTimeout(hsm.Host, initialState, initialErrorType)
+
+		// Make sure changes to the conditions and to the start time of
+		// the state get written back to the API even when the action
+		// itself did not change the host.
+		if updateHostConditions(hsm.Host) || timeoutRestarted {
+			switch r := actionRes.(type) {
+			case actionContinue:
+				actionRes = actionUpdate{r}
+			case actionFailed:
+				r.dirty = true
+				actionRes = r
+			}
+		}
 	}()
 
 	if delayedResult := hsm.checkDelayedHost(info); delayedResult != nil {
@@ -192,11 +258,23 @@ func (hsm *hostStateMachine) ReconcileState(info *reconcileInfo) (actionRes acti
 		return detachedResult
 	}
 
+	if needsOperatorResult := hsm.checkNeedsOperator(info); needsOperatorResult != nil {
+		return needsOperatorResult
+	}
+
 	if registerResult := hsm.ensureRegistered(info); registerResult != nil {
 		hostRegistrationRequired.Inc()
 		return registerResult
 	}
 
+	if timeoutResult := hsm.checkStateTimeout(info); timeoutResult != nil {
+		return timeoutResult
+	}
+
+	if rotationResult := hsm.checkCredentialsRotation(info); rotationResult != nil {
+		return rotationResult
+	}
+
 	if stateHandler, found := hsm.handlers()[initialState]; found {
 		return stateHandler(info)
 	}
@@ -306,6 +384,39 @@ func (hsm *hostStateMachine) checkDetachedHost(info *reconcileInfo) (result acti
 	return nil
 }
 
+// checkNeedsOperator stops any further action on a host that has
+// exhausted its retry policy, until the retry annotation is added.
+// Deleting the host is still allowed.
+func (hsm *hostStateMachine) checkNeedsOperator(info *reconcileInfo) actionResult {
+	if hsm.Host.OperationalStatus() != metal3v1alpha1.OperationalStatusNeedsOperator ||
+		!hsm.Host.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	if _, retry := hsm.Host.Annotations[retryAnnotation]; !retry {
+		info.log.Info("host needs an operator, not retrying",
+			"errorType", hsm.Host.Status.ErrorType)
+		return actionFailed{
+			ErrorType:     hsm.Host.Status.ErrorType,
+			errorCount:    hsm.Host.Status.ErrorCount,
+			needsOperator: true,
+		}
+	}
+
+	info.log.Info("retrying at the request of the operator")
+	delete(hsm.Host.Annotations, retryAnnotation)
+	if err := hsm.Reconciler.Update(context.TODO(), hsm.Host); err != nil {
+		return actionError{errors.Wrap(err, "failed to remove retry annotation")}
+	}
+	info.publishEvent("RetryRequested", "Resuming retries at the request of the operator")
+
+	// Keep the error so that the failed operation is restarted, but
+	// start counting failures from scratch.
+	hsm.Host.SetOperationalStatus(metal3v1alpha1.OperationalStatusError)
+	hsm.Host.Status.ErrorCount = 0
+	return actionUpdate{}
+}
+
 func (hsm *hostStateMachine) ensureRegistered(info *reconcileInfo) (result actionResult) {
 	if !hsm.haveCreds {
 		// If we are in the process of deletion (which may start with
@@ -350,7 +461,7 @@ func (hsm *hostStateMachine) ensureRegistered(info *reconcileInfo) (result actio
 
 func (hsm *hostStateMachine) handleNone(info *reconcileInfo) actionResult {
 	// No state is set, so immediately move to either Registering or Unmanaged
-	if hsm.Host.HasBMCDetails() {
+	if hsm.Host.HasBMCDetails() && !awaitingBMCCredentials(hsm.Host) {
 		hsm.NextState = metal3v1alpha1.StateRegistering
 	} else {
 		info.publishEvent("Discovered", "Discovered host with no BMC details")
@@ -452,6 +563,14 @@ func (hsm *hostStateMachine) handleAvailable(info *reconcileInfo) actionResult {
 		return actionComplete{}
 	}
 
+	// Check if hostFirmwareComponents have pending updates
+	if updates, _, err := hsm.Reconciler.getHostFirmwareComponents(info); err != nil {
+		return actionError{err}
+	} else if len(updates) != 0 {
+		hsm.NextState = metal3v1alpha1.StatePreparing
+		return actionComplete{}
+	}
+
 	// ErrorCount is cleared when appropriate inside actionManageAvailable
 	actResult := hsm.Reconciler.actionManageAvailable(hsm.Provisioner, info)
 	if _, complete := actResult.(actionComplete); complete {
@@ -518,6 +637,21 @@ func (hsm *hostStateMachine) handleProvisioned(info *reconcileInfo) actionResult
 		return actionComplete{}
 	}
 
+	if hsm.Host.OperationalStatus() == metal3v1alpha1.OperationalStatusServicing ||
+		hsm.Host.Status.ErrorType == metal3v1alpha1.ServicingError {
+		return hsm.Reconciler.actionServicing(hsm.Provisioner, info)
+	}
+
+	if hsm.Host.Spec.AllowServicing {
+		if needed, err := hsm.Reconciler.servicingNeeded(info); err != nil {
+			return actionError{err}
+		} else if needed {
+			info.log.Info("configuration changed, servicing host")
+			info.publishEvent("ServicingStarted", "Applying configuration changes to the host")
+			return hsm.Reconciler.actionServicing(hsm.Provisioner, info)
+		}
+	}
+
 	// ErrorCount is cleared when appropriate inside actionManageSteadyState
 	return hsm.Reconciler.actionManageSteadyState(hsm.Provisioner, info)
 }
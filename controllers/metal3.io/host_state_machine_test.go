@@ -1138,6 +1138,255 @@ func TestDeleteWaitsForDetach(t *testing.T) {
 	}
 }
 
+func TestStateTimeout(t *testing.T) {
+	testCases := []struct {
+		Scenario          string
+		Host              *metal3v1alpha1.BareMetalHost
+		Elapsed           time.Duration
+		Timeout           time.Duration
+		Annotation        string
+		AbortResult       *provisioner.Result
+		ExpectedTimeout   bool
+		ExpectedAbort     bool
+		ExpectedErrorType metal3v1alpha1.ErrorType
+	}{
+		{
+			Scenario: "inspecting-within-deadline",
+			Host:     host(metal3v1alpha1.StateInspecting).build(),
+			Elapsed:  time.Minute * 30,
+			Timeout:  time.Hour,
+		},
+		{
+			Scenario:          "inspecting-past-deadline",
+			Host:              host(metal3v1alpha1.StateInspecting).build(),
+			Elapsed:           time.Hour * 2,
+			Timeout:           time.Hour,
+			ExpectedTimeout:   true,
+			ExpectedAbort:     true,
+			ExpectedErrorType: metal3v1alpha1.TimeoutError,
+		},
+		{
+			Scenario:          "preparing-past-deadline",
+			Host:              host(metal3v1alpha1.StatePreparing).build(),
+			Elapsed:           time.Hour * 2,
+			Timeout:           time.Hour,
+			ExpectedTimeout:   true,
+			ExpectedAbort:     true,
+			ExpectedErrorType: metal3v1alpha1.TimeoutError,
+		},
+		{
+			Scenario: "no-timeout-configured",
+			Host:     host(metal3v1alpha1.StateProvisioning).build(),
+			Elapsed:  time.Hour * 24,
+		},
+		{
+			Scenario:          "annotation-overrides-default",
+			Host:              host(metal3v1alpha1.StateProvisioning).build(),
+			Elapsed:           time.Minute * 45,
+			Timeout:           time.Hour,
+			Annotation:        "30m",
+			ExpectedTimeout:   true,
+			ExpectedAbort:     true,
+			ExpectedErrorType: metal3v1alpha1.TimeoutError,
+		},
+		{
+			Scenario:   "annotation-disables-timeout",
+			Host:       host(metal3v1alpha1.StateDeprovisioning).build(),
+			Elapsed:    time.Hour * 2,
+			Timeout:    time.Hour,
+			Annotation: "0",
+		},
+		{
+			Scenario:   "invalid-annotation-ignored",
+			Host:       host(metal3v1alpha1.StateInspecting).build(),
+			Elapsed:    time.Minute * 30,
+			Timeout:    time.Hour,
+			Annotation: "soon",
+		},
+		{
+			Scenario:        "abort-in-progress",
+			Host:            host(metal3v1alpha1.StateInspecting).build(),
+			Elapsed:         time.Hour * 2,
+			Timeout:         time.Hour,
+			AbortResult:     &provisioner.Result{Dirty: true},
+			ExpectedTimeout: true,
+		},
+		{
+			Scenario: "other-error-not-timed-out",
+			Host: host(metal3v1alpha1.StateInspecting).
+				SetStatusError(metal3v1alpha1.OperationalStatusError, metal3v1alpha1.InspectionError, "boom", 1).
+				build(),
+			Elapsed:           time.Hour * 2,
+			Timeout:           time.Hour,
+			ExpectedErrorType: metal3v1alpha1.InspectionError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			state := tc.Host.Status.Provisioning.State
+			tc.Host.OperationMetricForState(state).Start = metav1.NewTime(time.Now().Add(-tc.Elapsed))
+			if tc.Annotation != "" {
+				tc.Host.Annotations = map[string]string{
+					timeoutAnnotationPrefix + "/" + string(state): tc.Annotation,
+				}
+			}
+
+			prov := newMockProvisioner()
+			if tc.AbortResult != nil {
+				prov.nextResults["Abort"] = *tc.AbortResult
+			}
+			reconciler := testNewReconciler(tc.Host)
+			if tc.Timeout != 0 {
+				reconciler.StateTimeouts = map[metal3v1alpha1.ProvisioningState]time.Duration{
+					state: tc.Timeout,
+				}
+			}
+			hsm := newHostStateMachine(tc.Host, reconciler, prov, true)
+			info := makeDefaultReconcileInfo(tc.Host)
+
+			result := hsm.checkStateTimeout(info)
+
+			assert.Equal(t, tc.ExpectedTimeout, result != nil)
+			assert.Equal(t, tc.ExpectedAbort, prov.calledNoError("Abort"))
+			assert.Equal(t, tc.ExpectedErrorType, tc.Host.Status.ErrorType)
+			assert.Equal(t, state, tc.Host.Status.Provisioning.State)
+		})
+	}
+}
+
+func TestStateTimeoutRetryGetsFullDeadline(t *testing.T) {
+	host := host(metal3v1alpha1.StateInspecting).
+		SetStatusError(metal3v1alpha1.OperationalStatusError, metal3v1alpha1.TimeoutError, "timed out", 1).
+		build()
+	host.Status.OperationHistory.Inspect.Start = metav1.NewTime(time.Now().Add(-time.Hour * 2))
+
+	reconciler := testNewReconciler(host)
+	reconciler.StateTimeouts = map[metal3v1alpha1.ProvisioningState]time.Duration{
+		metal3v1alpha1.StateInspecting: time.Hour,
+	}
+	prov := newMockProvisioner()
+	hsm := newHostStateMachine(host, reconciler, prov, true)
+	start := host.Status.OperationHistory.Inspect.Start
+
+	// The start time is kept while the host waits for the retry
+	assert.Nil(t, hsm.checkStateTimeout(makeDefaultReconcileInfo(host)))
+	assert.False(t, prov.calledNoError("Abort"))
+	assert.Equal(t, start, host.Status.OperationHistory.Inspect.Start)
+	assert.False(t, restartStateTimeout(host, metal3v1alpha1.StateInspecting, metal3v1alpha1.TimeoutError))
+	assert.Equal(t, start, host.Status.OperationHistory.Inspect.Start)
+
+	// and reset once the error is cleared by the retry
+	clearError(host)
+	assert.True(t, restartStateTimeout(host, metal3v1alpha1.StateInspecting, metal3v1alpha1.TimeoutError))
+	assert.WithinDuration(t, time.Now(), host.Status.OperationHistory.Inspect.Start.Time, time.Minute)
+	assert.Nil(t, hsm.checkStateTimeout(makeDefaultReconcileInfo(host)))
+}
+
+func TestStateTimeoutNotRestartedOnStateChange(t *testing.T) {
+	host := host(metal3v1alpha1.StateInspecting).
+		SetStatusError(metal3v1alpha1.OperationalStatusError, metal3v1alpha1.TimeoutError, "timed out", 1).
+		build()
+	start := metav1.NewTime(time.Now().Add(-time.Hour * 2))
+	host.Status.OperationHistory.Inspect.Start = start
+
+	// The error is cleared when the host moves on to the next state
+	clearError(host)
+	host.Status.Provisioning.State = metal3v1alpha1.StateAvailable
+	assert.False(t, restartStateTimeout(host, metal3v1alpha1.StateInspecting, metal3v1alpha1.TimeoutError))
+	assert.Equal(t, start, host.Status.OperationHistory.Inspect.Start)
+}
+
+func TestProvisionedServicing(t *testing.T) {
+	enabled := true
+	firmware := &metal3v1alpha1.FirmwareConfig{VirtualizationEnabled: &enabled}
+
+	testCases := []struct {
+		Scenario                  string
+		Host                      *metal3v1alpha1.BareMetalHost
+		ServiceResult             *provisioner.Result
+		ExpectedService           bool
+		ExpectedOperationalStatus metal3v1alpha1.OperationalStatus
+		ExpectedErrorType         metal3v1alpha1.ErrorType
+		ExpectedFirmware          *metal3v1alpha1.FirmwareConfig
+	}{
+		{
+			Scenario:                  "servicing-not-allowed",
+			Host:                      host(metal3v1alpha1.StateProvisioned).SaveHostProvisioningSettings().SetFirmware(firmware).build(),
+			ExpectedOperationalStatus: metal3v1alpha1.OperationalStatusOK,
+		},
+		{
+			Scenario:                  "no-changes",
+			Host:                      host(metal3v1alpha1.StateProvisioned).SaveHostProvisioningSettings().AllowServicing().build(),
+			ExpectedOperationalStatus: metal3v1alpha1.OperationalStatusOK,
+		},
+		{
+			Scenario:                  "changes-detected",
+			Host:                      host(metal3v1alpha1.StateProvisioned).SaveHostProvisioningSettings().AllowServicing().SetFirmware(firmware).build(),
+			ExpectedOperationalStatus: metal3v1alpha1.OperationalStatusServicing,
+		},
+		{
+			Scenario: "servicing-started",
+			Host: host(metal3v1alpha1.StateProvisioned).SaveHostProvisioningSettings().AllowServicing().SetFirmware(firmware).
+				SetOperationalStatus(metal3v1alpha1.OperationalStatusServicing).build(),
+			ServiceResult:             &provisioner.Result{Dirty: true},
+			ExpectedService:           true,
+			ExpectedOperationalStatus: metal3v1alpha1.OperationalStatusServicing,
+			ExpectedFirmware:          firmware,
+		},
+		{
+			Scenario: "servicing-complete",
+			Host: host(metal3v1alpha1.StateProvisioned).AllowServicing().SetFirmware(firmware).SaveHostProvisioningSettings().
+				SetOperationalStatus(metal3v1alpha1.OperationalStatusServicing).build(),
+			ExpectedService:           true,
+			ExpectedOperationalStatus: metal3v1alpha1.OperationalStatusOK,
+			ExpectedFirmware:          firmware,
+		},
+		{
+			Scenario: "servicing-failed",
+			Host: host(metal3v1alpha1.StateProvisioned).SaveHostProvisioningSettings().AllowServicing().SetFirmware(firmware).
+				SetOperationalStatus(metal3v1alpha1.OperationalStatusServicing).build(),
+			ServiceResult:             &provisioner.Result{ErrorMessage: "update failed"},
+			ExpectedService:           true,
+			ExpectedOperationalStatus: metal3v1alpha1.OperationalStatusError,
+			ExpectedErrorType:         metal3v1alpha1.ServicingError,
+		},
+		{
+			Scenario: "servicing-retried",
+			Host: host(metal3v1alpha1.StateProvisioned).SaveHostProvisioningSettings().SetFirmware(firmware).
+				SetStatusError(metal3v1alpha1.OperationalStatusError, metal3v1alpha1.ServicingError, "update failed", 1).build(),
+			ServiceResult:             &provisioner.Result{Dirty: true},
+			ExpectedService:           true,
+			ExpectedOperationalStatus: metal3v1alpha1.OperationalStatusServicing,
+			ExpectedFirmware:          firmware,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			prov := newMockProvisioner()
+			if tc.ServiceResult != nil {
+				prov.nextResults["Service"] = *tc.ServiceResult
+			}
+			reconciler := testNewReconciler(tc.Host)
+			hsm := newHostStateMachine(tc.Host, reconciler, prov, true)
+			info := makeDefaultReconcileInfo(tc.Host)
+
+			hsm.handleProvisioned(info)
+
+			if tc.ExpectedService {
+				assert.Equal(t, firmware, prov.prepareData.FirmwareConfig)
+			} else {
+				assert.Nil(t, prov.prepareData.FirmwareConfig)
+			}
+			assert.Equal(t, tc.ExpectedOperationalStatus, tc.Host.Status.OperationalStatus)
+			assert.Equal(t, tc.ExpectedErrorType, tc.Host.Status.ErrorType)
+			assert.Equal(t, tc.ExpectedFirmware, tc.Host.Status.Provisioning.Firmware)
+			assert.Equal(t, metal3v1alpha1.StateProvisioned, tc.Host.Status.Provisioning.State)
+		})
+	}
+}
+
 type hostBuilder struct {
 	metal3v1alpha1.BareMetalHost
 }
@@ -1188,6 +1437,9 @@ func host(state metal3v1alpha1.ProvisioningState) *hostBuilder {
 }
 
 func (hb *hostBuilder) build() *metal3v1alpha1.BareMetalHost {
+	// Start from conditions matching the rest of the status, as they
+	// would be for a host that has already been reconciled.
+	updateHostConditions(&hb.BareMetalHost)
 	return &hb.BareMetalHost
 }
 
@@ -1197,6 +1449,16 @@ func (hb *hostBuilder) SaveHostProvisioningSettings() *hostBuilder {
 	return hb
 }
 
+func (hb *hostBuilder) SetFirmware(firmware *metal3v1alpha1.FirmwareConfig) *hostBuilder {
+	hb.Spec.Firmware = firmware
+	return hb
+}
+
+func (hb *hostBuilder) AllowServicing() *hostBuilder {
+	hb.Spec.AllowServicing = true
+	return hb
+}
+
 func (hb *hostBuilder) SetTriedCredentials() *hostBuilder {
 	hb.Status.TriedCredentials = hb.Status.GoodCredentials
 	return hb
@@ -1238,6 +1500,14 @@ func (hb *hostBuilder) SetOnline(status bool) *hostBuilder {
 	return hb
 }
 
+func (hb *hostBuilder) SetHardwareHealth(components ...metal3v1alpha1.ComponentHealth) *hostBuilder {
+	hb.Status.HardwareHealth = &metal3v1alpha1.HardwareHealth{
+		Health:     metal3v1alpha1.WorstHealth(components),
+		Components: components,
+	}
+	return hb
+}
+
 func (hb *hostBuilder) SetOperationalStatus(status metal3v1alpha1.OperationalStatus) *hostBuilder {
 	hb.Status.OperationalStatus = status
 	return hb
@@ -1292,6 +1562,9 @@ type mockProvisioner struct {
 	hasCapacity  bool
 	nextResults  map[string]provisioner.Result
 	callsNoError map[string]bool
+	prepareData  provisioner.PrepareData
+
+	firmwareVersions map[string]string
 }
 
 func (m *mockProvisioner) getNextResultByMethod(name string) (result provisioner.Result) {
@@ -1343,9 +1616,15 @@ func (m *mockProvisioner) UpdateHardwareState() (hwState provisioner.HardwareSta
 }
 
 func (m *mockProvisioner) Prepare(data provisioner.PrepareData, unprepared bool, force bool) (result provisioner.Result, started bool, err error) {
+	m.prepareData = data
 	return m.getNextResultByMethod("Prepare"), m.nextResults["Prepare"].Dirty, err
 }
 
+func (m *mockProvisioner) Service(data provisioner.PrepareData, unprepared bool, force bool) (result provisioner.Result, started bool, err error) {
+	m.prepareData = data
+	return m.getNextResultByMethod("Service"), m.nextResults["Service"].Dirty, err
+}
+
 func (m *mockProvisioner) Adopt(data provisioner.AdoptData, force bool) (result provisioner.Result, err error) {
 	return m.getNextResultByMethod("Adopt"), err
 }
@@ -1367,6 +1646,10 @@ func (m *mockProvisioner) Detach() (result provisioner.Result, err error) {
 	return res, err
 }
 
+func (m *mockProvisioner) Abort() (result provisioner.Result, err error) {
+	return m.getNextResultByMethod("Abort"), err
+}
+
 func (m *mockProvisioner) PowerOn(force bool) (result provisioner.Result, err error) {
 	return m.getNextResultByMethod("PowerOn"), err
 }
@@ -1391,6 +1674,18 @@ func (m *mockProvisioner) RemoveBMCEventSubscriptionForNode(subscription metal3v
 	return result, nil
 }
 
+func (m *mockProvisioner) GetFirmwareComponents() (versions map[string]string, err error) {
+	return m.firmwareVersions, nil
+}
+
+func (m *mockProvisioner) CreateBMCAccount(creds bmc.Credentials) (result provisioner.Result, err error) {
+	return m.getNextResultByMethod("CreateBMCAccount"), err
+}
+
+func (m *mockProvisioner) DisableBMCAccount(username string) (result provisioner.Result, err error) {
+	return m.getNextResultByMethod("DisableBMCAccount"), err
+}
+
 func TestUpdateBootModeStatus(t *testing.T) {
 	testCases := []struct {
 		Scenario       string
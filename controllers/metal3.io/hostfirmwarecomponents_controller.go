@@ -0,0 +1,273 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+)
+
+const (
+	biosComponent = "bios"
+)
+
+// HostFirmwareComponentsReconciler reconciles a HostFirmwareComponents object
+type HostFirmwareComponentsReconciler struct {
+	client.Client
+	Log logr.Logger
+}
+
+//+kubebuilder:rbac:groups=metal3.io,resources=hostfirmwarecomponents,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=metal3.io,resources=hostfirmwarecomponents/status,verbs=get;update;patch
+
+// Reconcile validates the firmware updates requested for a host and
+// reports the versions of its firmware components. The updates are
+// applied by the BareMetalHostReconciler while the host is preparing.
+func (r *HostFirmwareComponentsReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+
+	reqLogger := r.Log.WithValues("hostfirmwarecomponents", req.NamespacedName)
+	reqLogger.Info("start")
+
+	// Get the corresponding baremetalhost in this namespace, if one doesn't exist don't continue processing
+	bmh := &metal3v1alpha1.BareMetalHost{}
+	if err = r.Get(ctx, req.NamespacedName, bmh); err != nil {
+		reqLogger.Info("could not get baremetalhost, not running reconciler")
+		if k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{Requeue: true, RequeueAfter: resourceNotAvailableRetryDelay}, nil
+	}
+
+	if hasDetachedAnnotation(bmh) {
+		reqLogger.Info("the host is detached, not running reconciler")
+		return ctrl.Result{Requeue: true, RequeueAfter: unmanagedRetryDelay}, nil
+	}
+
+	hfc := &metal3v1alpha1.HostFirmwareComponents{}
+	if err = r.Get(ctx, req.NamespacedName, hfc); err != nil {
+		if k8serrors.IsNotFound(err) {
+			reqLogger.Info("hostFirmwareComponents not found")
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return ctrl.Result{}, errors.Wrap(err, "could not load hostFirmwareComponents")
+	}
+
+	if err = r.updateStatus(reqLogger, hfc, bmh); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "could not update hostFirmwareComponents")
+	}
+
+	// requeue to pick up new versions from the host hardware details
+	if meta.IsStatusConditionTrue(hfc.Status.Conditions, string(metal3v1alpha1.HostFirmwareComponentsChangeDetected)) {
+		return ctrl.Result{Requeue: true, RequeueAfter: reconcilerRequeueDelayChangeDetected}, nil
+	}
+	return ctrl.Result{Requeue: true, RequeueAfter: reconcilerRequeueDelay}, nil
+}
+
+// Update the HostFirmwareComponents status with the component versions
+// and the result of validating the updates
+func (r *HostFirmwareComponentsReconciler) updateStatus(log logr.Logger, hfc *metal3v1alpha1.HostFirmwareComponents, bmh *metal3v1alpha1.BareMetalHost) error {
+
+	newStatus := hfc.Status.DeepCopy()
+	newStatus.Components = discoverFirmwareComponents(newStatus.Components, bmh)
+
+	generation := hfc.GetGeneration()
+	if len(pendingFirmwareUpdates(hfc)) != 0 {
+		setUpdatesCondition(generation, newStatus, metal3v1alpha1.HostFirmwareComponentsChangeDetected, metav1.ConditionTrue, reasonSuccess, "")
+	} else {
+		setUpdatesCondition(generation, newStatus, metal3v1alpha1.HostFirmwareComponentsChangeDetected, metav1.ConditionFalse, reasonSuccess, "")
+	}
+
+	if errs := validateFirmwareUpdates(hfc.Spec.Updates, bmh); len(errs) != 0 {
+		msgs := make([]string, 0, len(errs))
+		for _, e := range errs {
+			msgs = append(msgs, e.Error())
+		}
+		log.Info("invalid firmware updates", "errors", msgs)
+		setUpdatesCondition(generation, newStatus, metal3v1alpha1.HostFirmwareComponentsValid, metav1.ConditionFalse, reasonConfigurationError, strings.Join(msgs, "; "))
+	} else {
+		setUpdatesCondition(generation, newStatus, metal3v1alpha1.HostFirmwareComponentsValid, metav1.ConditionTrue, reasonSuccess, "")
+	}
+
+	// Update Status if it has changed
+	if reflect.DeepEqual(hfc.Status, *newStatus) {
+		return nil
+	}
+	log.Info("Status has changed")
+	hfc.Status = *newStatus
+	t := metav1.Now()
+	hfc.Status.LastUpdated = &t
+	return r.Status().Update(context.TODO(), hfc)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HostFirmwareComponentsReconciler) SetupWithManager(mgr ctrl.Manager) error {
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&metal3v1alpha1.HostFirmwareComponents{}).
+		WithEventFilter(
+			predicate.Funcs{
+				UpdateFunc: r.updateEventHandler,
+			}).
+		Complete(r)
+}
+
+func (r *HostFirmwareComponentsReconciler) updateEventHandler(e event.UpdateEvent) bool {
+	// Only process the resource if the updates in the Spec have changed
+	return e.ObjectNew.GetGeneration() != e.ObjectOld.GetGeneration()
+}
+
+// discoverFirmwareComponents fills in the version of the BIOS reported in
+// the host hardware details, for hosts whose versions are not read from
+// the BMC. The BareMetalHostReconciler records the versions of all the
+// components read from the BMC after inspection and firmware updates.
+func discoverFirmwareComponents(components []metal3v1alpha1.FirmwareComponentStatus, bmh *metal3v1alpha1.BareMetalHost) []metal3v1alpha1.FirmwareComponentStatus {
+	if bmh.Status.HardwareDetails == nil || bmh.Status.HardwareDetails.Firmware.BIOS.Version == "" {
+		return components
+	}
+	version := bmh.Status.HardwareDetails.Firmware.BIOS.Version
+
+	component := findFirmwareComponent(components, biosComponent)
+	if component == nil {
+		components = append(components, metal3v1alpha1.FirmwareComponentStatus{Component: biosComponent})
+		component = &components[len(components)-1]
+	}
+	if component.InitialVersion == "" {
+		component.InitialVersion = version
+	}
+	// Once the component has been flashed the hardware details are
+	// stale until the host is inspected again
+	if component.LastVersionFlashed == "" {
+		component.CurrentVersion = version
+	}
+	return components
+}
+
+// updateFirmwareVersions records the installed versions of the firmware
+// components read from the host, and returns whether the status changed
+func updateFirmwareVersions(status *metal3v1alpha1.HostFirmwareComponentsStatus, versions map[string]string) (dirty bool) {
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		component := findFirmwareComponent(status.Components, name)
+		if component == nil {
+			status.Components = append(status.Components, metal3v1alpha1.FirmwareComponentStatus{Component: name})
+			component = &status.Components[len(status.Components)-1]
+		}
+		if component.InitialVersion == "" {
+			component.InitialVersion = versions[name]
+			dirty = true
+		}
+		if component.CurrentVersion != versions[name] {
+			component.CurrentVersion = versions[name]
+			dirty = true
+		}
+	}
+	return dirty
+}
+
+func findFirmwareComponent(components []metal3v1alpha1.FirmwareComponentStatus, name string) *metal3v1alpha1.FirmwareComponentStatus {
+	for i := range components {
+		if components[i].Component == name {
+			return &components[i]
+		}
+	}
+	return nil
+}
+
+// pendingFirmwareUpdates returns the updates in the Spec that have not
+// been applied to the host
+func pendingFirmwareUpdates(hfc *metal3v1alpha1.HostFirmwareComponents) (updates []metal3v1alpha1.FirmwareUpdate) {
+	for _, update := range hfc.Spec.Updates {
+		applied := false
+		for _, statusUpdate := range hfc.Status.Updates {
+			if update == statusUpdate {
+				applied = true
+				break
+			}
+		}
+		if !applied {
+			updates = append(updates, update)
+		}
+	}
+	return
+}
+
+// Validate the HostFirmwareComponents updates against the host
+func validateFirmwareUpdates(updates []metal3v1alpha1.FirmwareUpdate, bmh *metal3v1alpha1.BareMetalHost) []error {
+	if len(updates) == 0 {
+		return nil
+	}
+
+	var errs []error
+
+	bmcAccess, err := bmc.NewAccessDetails(bmh.Spec.BMC.Address, bmh.Spec.BMC.DisableCertificateVerification)
+	if err != nil {
+		errs = append(errs, err)
+	} else if !bmcAccess.Capabilities().FirmwareUpdate {
+		errs = append(errs, fmt.Errorf("firmware updates are not supported by the %s driver", bmcAccess.Driver()))
+	}
+
+	components := map[string]bool{}
+	for _, update := range updates {
+		if components[update.Component] {
+			errs = append(errs, fmt.Errorf("duplicate update for component %s", update.Component))
+		}
+		components[update.Component] = true
+
+		if u, err := url.Parse(update.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid URL %q for component %s", update.URL, update.Component))
+		}
+	}
+
+	return errs
+}
+
+func setUpdatesCondition(generation int64, status *metal3v1alpha1.HostFirmwareComponentsStatus,
+	cond metal3v1alpha1.UpdatesConditionType, newStatus metav1.ConditionStatus,
+	reason conditionReason, message string) {
+	newCondition := metav1.Condition{
+		Type:               string(cond),
+		Status:             newStatus,
+		ObservedGeneration: generation,
+		Reason:             string(reason),
+		Message:            message,
+	}
+	meta.SetStatusCondition(&status.Conditions, newCondition)
+}
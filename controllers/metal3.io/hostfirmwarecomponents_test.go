@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+)
+
+func getTestFirmwareUpdates() []metal3v1alpha1.FirmwareUpdate {
+	return []metal3v1alpha1.FirmwareUpdate{
+		{
+			Component: "bios",
+			Version:   "2.15.1",
+			URL:       "http://example.com/bios.bin",
+		},
+		{
+			Component: "bmc",
+			Version:   "6.10",
+			URL:       "http://example.com/bmc.bin",
+		},
+	}
+}
+
+func getHFC(updates []metal3v1alpha1.FirmwareUpdate) *metal3v1alpha1.HostFirmwareComponents {
+	return &metal3v1alpha1.HostFirmwareComponents{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "HostFirmwareComponents",
+			APIVersion: "metal3.io/v1alpha1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      hostName,
+			Namespace: hostNamespace,
+		},
+		Spec: metal3v1alpha1.HostFirmwareComponentsSpec{
+			Updates: updates,
+		},
+	}
+}
+
+func TestHostFirmwareComponentsReconcile(t *testing.T) {
+	testCases := []struct {
+		Scenario           string
+		BMCAddress         string
+		Updates            []metal3v1alpha1.FirmwareUpdate
+		AppliedUpdates     []metal3v1alpha1.FirmwareUpdate
+		ExpectedValid      metav1.ConditionStatus
+		ExpectedChange     metav1.ConditionStatus
+		ExpectedComponents []metal3v1alpha1.FirmwareComponentStatus
+	}{
+		{
+			Scenario:       "pending-updates",
+			BMCAddress:     "redfish://example.test/redfish/v1/Systems/1",
+			Updates:        getTestFirmwareUpdates(),
+			ExpectedValid:  metav1.ConditionTrue,
+			ExpectedChange: metav1.ConditionTrue,
+			ExpectedComponents: []metal3v1alpha1.FirmwareComponentStatus{
+				{Component: "bios", InitialVersion: "1.0", CurrentVersion: "1.0"},
+			},
+		},
+		{
+			Scenario:       "applied-updates",
+			BMCAddress:     "redfish://example.test/redfish/v1/Systems/1",
+			Updates:        getTestFirmwareUpdates(),
+			AppliedUpdates: getTestFirmwareUpdates(),
+			ExpectedValid:  metav1.ConditionTrue,
+			ExpectedChange: metav1.ConditionFalse,
+			ExpectedComponents: []metal3v1alpha1.FirmwareComponentStatus{
+				{Component: "bios", InitialVersion: "1.0", CurrentVersion: "1.0"},
+			},
+		},
+		{
+			Scenario:       "no-updates",
+			BMCAddress:     "ipmi://example.test",
+			ExpectedValid:  metav1.ConditionTrue,
+			ExpectedChange: metav1.ConditionFalse,
+			ExpectedComponents: []metal3v1alpha1.FirmwareComponentStatus{
+				{Component: "bios", InitialVersion: "1.0", CurrentVersion: "1.0"},
+			},
+		},
+		{
+			Scenario:       "unsupported-driver",
+			BMCAddress:     "ipmi://example.test",
+			Updates:        getTestFirmwareUpdates(),
+			ExpectedValid:  metav1.ConditionFalse,
+			ExpectedChange: metav1.ConditionTrue,
+			ExpectedComponents: []metal3v1alpha1.FirmwareComponentStatus{
+				{Component: "bios", InitialVersion: "1.0", CurrentVersion: "1.0"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			bmh := &metal3v1alpha1.BareMetalHost{
+				ObjectMeta: metav1.ObjectMeta{Name: hostName, Namespace: hostNamespace},
+				Spec: metal3v1alpha1.BareMetalHostSpec{
+					BMC: metal3v1alpha1.BMCDetails{Address: tc.BMCAddress},
+				},
+				Status: metal3v1alpha1.BareMetalHostStatus{
+					HardwareDetails: &metal3v1alpha1.HardwareDetails{
+						Firmware: metal3v1alpha1.Firmware{
+							BIOS: metal3v1alpha1.BIOS{Version: "1.0"},
+						},
+					},
+				},
+			}
+			hfc := getHFC(tc.Updates)
+			hfc.Status.Updates = tc.AppliedUpdates
+
+			r := &HostFirmwareComponentsReconciler{
+				Client: fakeclient.NewFakeClient(bmh, hfc),
+				Log:    ctrl.Log.WithName("test_reconciler").WithName("HostFirmwareComponents"),
+			}
+			key := types.NamespacedName{Namespace: hostNamespace, Name: hostName}
+
+			_, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key})
+			assert.NoError(t, err)
+
+			actual := &metal3v1alpha1.HostFirmwareComponents{}
+			assert.NoError(t, r.Get(context.TODO(), key, actual))
+
+			valid := meta.FindStatusCondition(actual.Status.Conditions, string(metal3v1alpha1.HostFirmwareComponentsValid))
+			if assert.NotNil(t, valid) {
+				assert.Equal(t, tc.ExpectedValid, valid.Status)
+			}
+			change := meta.FindStatusCondition(actual.Status.Conditions, string(metal3v1alpha1.HostFirmwareComponentsChangeDetected))
+			if assert.NotNil(t, change) {
+				assert.Equal(t, tc.ExpectedChange, change.Status)
+			}
+			assert.Equal(t, tc.ExpectedComponents, actual.Status.Components)
+		})
+	}
+}
+
+func TestValidateFirmwareUpdates(t *testing.T) {
+	testCases := []struct {
+		Scenario       string
+		BMCAddress     string
+		Updates        []metal3v1alpha1.FirmwareUpdate
+		ExpectedErrors []string
+	}{
+		{
+			Scenario:   "valid",
+			BMCAddress: "redfish-virtualmedia://example.test/redfish/v1/Systems/1",
+			Updates:    getTestFirmwareUpdates(),
+		},
+		{
+			Scenario:   "unsupported-driver",
+			BMCAddress: "ilo5://example.test",
+			Updates:    getTestFirmwareUpdates(),
+			ExpectedErrors: []string{
+				"firmware updates are not supported by the ilo5 driver",
+			},
+		},
+		{
+			Scenario:   "duplicate-component",
+			BMCAddress: "redfish://example.test/redfish/v1/Systems/1",
+			Updates: append(getTestFirmwareUpdates(), metal3v1alpha1.FirmwareUpdate{
+				Component: "bios",
+				Version:   "2.16.0",
+				URL:       "http://example.com/bios-2.16.0.bin",
+			}),
+			ExpectedErrors: []string{
+				"duplicate update for component bios",
+			},
+		},
+		{
+			Scenario:   "invalid-url",
+			BMCAddress: "redfish://example.test/redfish/v1/Systems/1",
+			Updates: []metal3v1alpha1.FirmwareUpdate{
+				{
+					Component: "nic:NIC.Integrated.1",
+					Version:   "22.31.6",
+					URL:       "ftp://example.com/nic.bin",
+				},
+			},
+			ExpectedErrors: []string{
+				"invalid URL \"ftp://example.com/nic.bin\" for component nic:NIC.Integrated.1",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			bmh := &metal3v1alpha1.BareMetalHost{
+				Spec: metal3v1alpha1.BareMetalHostSpec{
+					BMC: metal3v1alpha1.BMCDetails{Address: tc.BMCAddress},
+				},
+			}
+
+			errs := validateFirmwareUpdates(tc.Updates, bmh)
+			messages := []string{}
+			for _, err := range errs {
+				messages = append(messages, err.Error())
+			}
+			assert.ElementsMatch(t, tc.ExpectedErrors, messages)
+		})
+	}
+}
+
+func TestPreparingFirmwareUpdates(t *testing.T) {
+	host := host(metal3v1alpha1.StatePreparing).build()
+	reconciler := testNewReconciler(host)
+	key := types.NamespacedName{Namespace: host.Namespace, Name: host.Name}
+
+	hfc := getHFC(getTestFirmwareUpdates())
+	hfc.ObjectMeta = metav1.ObjectMeta{Name: host.Name, Namespace: host.Namespace}
+	assert.NoError(t, reconciler.Create(context.TODO(), hfc))
+	setUpdatesCondition(hfc.Generation, &hfc.Status, metal3v1alpha1.HostFirmwareComponentsValid, metav1.ConditionTrue, reasonSuccess, "")
+	assert.NoError(t, reconciler.Status().Update(context.TODO(), hfc))
+
+	info := makeDefaultReconcileInfo(host)
+	info.request = ctrl.Request{NamespacedName: key}
+	prov := newMockProvisioner()
+	prov.firmwareVersions = map[string]string{"bios": "2.12.2", "bmc": "5.10"}
+
+	// Cleaning starts, the updates and the installed versions are recorded
+	prov.nextResults["Prepare"] = provisioner.Result{Dirty: true}
+	result := reconciler.actionPreparing(prov, info)
+	assert.IsType(t, actionContinue{}, result)
+	assert.Equal(t, getTestFirmwareUpdates(), prov.prepareData.TargetFirmwareComponents)
+	assert.NoError(t, reconciler.Get(context.TODO(), key, hfc))
+	assert.Equal(t, getTestFirmwareUpdates(), hfc.Status.Updates)
+	if assert.Len(t, hfc.Status.Components, 2) {
+		assert.Equal(t, "2.12.2", hfc.Status.Components[0].InitialVersion)
+		assert.Equal(t, "5.10", hfc.Status.Components[1].CurrentVersion)
+		assert.Empty(t, hfc.Status.Components[1].LastVersionFlashed)
+	}
+
+	// Cleaning fails, the updates are forgotten so they are retried
+	prov.nextResults["Prepare"] = provisioner.Result{ErrorMessage: "clean failed"}
+	reconciler.actionPreparing(prov, info)
+	assert.Empty(t, prov.prepareData.TargetFirmwareComponents)
+	assert.NoError(t, reconciler.Get(context.TODO(), client.ObjectKeyFromObject(hfc), hfc))
+	assert.Empty(t, hfc.Status.Updates)
+
+	prov.nextResults["Prepare"] = provisioner.Result{Dirty: true}
+	reconciler.actionPreparing(prov, info)
+	assert.Equal(t, getTestFirmwareUpdates(), prov.prepareData.TargetFirmwareComponents)
+	assert.NoError(t, reconciler.Get(context.TODO(), key, hfc))
+	assert.Equal(t, getTestFirmwareUpdates(), hfc.Status.Updates)
+
+	// Cleaning completes, the flashed versions and the versions read back
+	// from the host are recorded; the BMC update did not take effect
+	prov.firmwareVersions = map[string]string{"bios": "2.15.1", "bmc": "5.10", "nic:NIC.Slot.1": "22.31.6"}
+	delete(prov.nextResults, "Prepare")
+	result = reconciler.actionPreparing(prov, info)
+	assert.IsType(t, actionComplete{}, result)
+	assert.Empty(t, prov.prepareData.TargetFirmwareComponents)
+	assert.NoError(t, reconciler.Get(context.TODO(), key, hfc))
+	if assert.Len(t, hfc.Status.Components, 3) {
+		for i, update := range getTestFirmwareUpdates() {
+			assert.Equal(t, update.Component, hfc.Status.Components[i].Component)
+			assert.Equal(t, update.Version, hfc.Status.Components[i].LastVersionFlashed)
+			assert.NotNil(t, hfc.Status.Components[i].UpdatedAt)
+		}
+		assert.Equal(t, "2.12.2", hfc.Status.Components[0].InitialVersion)
+		assert.Equal(t, "2.15.1", hfc.Status.Components[0].CurrentVersion)
+		assert.Equal(t, "5.10", hfc.Status.Components[1].CurrentVersion)
+		assert.Equal(t, "nic:NIC.Slot.1", hfc.Status.Components[2].Component)
+		assert.Equal(t, "22.31.6", hfc.Status.Components[2].CurrentVersion)
+	}
+}
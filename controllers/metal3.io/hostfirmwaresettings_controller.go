@@ -41,6 +41,7 @@ import (
 
 	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+	"github.com/metal3-io/baremetal-operator/pkg/secretutils"
 )
 
 const (
@@ -55,6 +56,7 @@ type HostFirmwareSettingsReconciler struct {
 	client.Client
 	Log                logr.Logger
 	ProvisionerFactory provisioner.Factory
+	APIReader          client.Reader
 }
 
 type rInfo struct {
@@ -144,8 +146,13 @@ func (r *HostFirmwareSettingsReconciler) Reconcile(ctx context.Context, req ctrl
 		return ctrl.Result{}, errors.Wrap(err, "could not load hostFirmwareSettings")
 	}
 
-	// Create a provisioner that can access Ironic API
-	prov, err := r.ProvisionerFactory.NewProvisioner(provisioner.BuildHostDataNoBMC(*bmh), info.publishEvent)
+	hostData, err := r.hostData(bmh)
+	if err != nil {
+		reqLogger.Info("could not read the BMC details of the host", "Error", err.Error(), "RequeueAfter", provisionerRetryDelay)
+		return ctrl.Result{Requeue: true, RequeueAfter: provisionerRetryDelay}, nil
+	}
+
+	prov, err := r.ProvisionerFactory.NewProvisioner(hostData, info.publishEvent)
 	if err != nil {
 		return ctrl.Result{}, errors.Wrap(err, "failed to create provisioner")
 	}
@@ -187,6 +194,41 @@ func (r *HostFirmwareSettingsReconciler) Reconcile(ctx context.Context, req ctrl
 	return ctrl.Result{Requeue: true, RequeueAfter: reconcilerRequeueDelay}, nil
 }
 
+// hostData returns the data of the host for its provisioner. The BMC
+// details of the host are only read when the provisioner manages the
+// host directly through its BMC, as the Ironic provisioner reaches the
+// host through its node.
+func (r *HostFirmwareSettingsReconciler) hostData(bmh *metal3v1alpha1.BareMetalHost) (provisioner.HostData, error) {
+	hostData := provisioner.BuildHostDataNoBMC(*bmh)
+	if !provisioner.RequiresBMCDetails(r.ProvisionerFactory, hostData) {
+		return hostData, nil
+	}
+
+	if bmh.Spec.BMC.CredentialsProvider != "" {
+		return hostData, fmt.Errorf("the BMC credentials of credentials provider %q are only available to the host controller",
+			bmh.Spec.BMC.CredentialsProvider)
+	}
+	secretManager := secretutils.NewSecretManager(r.Log, r.Client, r.APIReader)
+	secret, err := secretManager.ObtainSecret(bmh.CredentialsKey())
+	if err != nil {
+		return hostData, errors.Wrap(err, "failed to read the BMC credentials")
+	}
+	bmcCreds := credentialsFromSecret(secret)
+	if err := bmcCreds.Validate(); err != nil {
+		return hostData, err
+	}
+
+	caCertificates, fingerprint, err := loadBMCTrustedCertificates(secretManager, r.APIReader, bmh)
+	if err != nil {
+		return hostData, err
+	}
+
+	hostData = provisioner.BuildHostData(*bmh, *bmcCreds)
+	hostData.BMCCACertificates = caCertificates
+	hostData.BMCCertificateFingerprint = fingerprint
+	return hostData, nil
+}
+
 // Get the firmware settings from the provisioner and update hostFirmwareSettings
 func (r *HostFirmwareSettingsReconciler) updateHostFirmwareSettings(currentSettings metal3v1alpha1.SettingsMap, schema map[string]metal3v1alpha1.SettingSchema, info *rInfo) (err error) {
 
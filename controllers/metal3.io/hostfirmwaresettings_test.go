@@ -8,7 +8,11 @@ import (
 
 	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/fixture"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish/testserver"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -79,6 +83,10 @@ func (m *hsfMockProvisioner) Prepare(data provisioner.PrepareData, unprepared bo
 	return
 }
 
+func (m *hsfMockProvisioner) Service(data provisioner.PrepareData, unprepared bool, force bool) (result provisioner.Result, started bool, err error) {
+	return
+}
+
 func (m *hsfMockProvisioner) Adopt(data provisioner.AdoptData, force bool) (result provisioner.Result, err error) {
 	return
 }
@@ -99,6 +107,10 @@ func (m *hsfMockProvisioner) Detach() (result provisioner.Result, err error) {
 	return
 }
 
+func (m *hsfMockProvisioner) Abort() (result provisioner.Result, err error) {
+	return
+}
+
 func (m *hsfMockProvisioner) PowerOn(force bool) (result provisioner.Result, err error) {
 	return
 }
@@ -726,3 +738,48 @@ func TestValidateHostFirmwareSettings(t *testing.T) {
 		})
 	}
 }
+
+// Test that the firmware settings of a host managed by the redfish
+// provisioner are read through its BMC
+func TestHostFirmwareSettingsRedfish(t *testing.T) {
+	mock := testserver.NewRedfish(t).WithCredentials("admin", "password").Start()
+	defer mock.Stop()
+
+	bmh := &metal3v1alpha1.BareMetalHost{
+		ObjectMeta: metav1.ObjectMeta{Name: hostName, Namespace: hostNamespace},
+		Spec: metal3v1alpha1.BareMetalHostSpec{
+			BMC: metal3v1alpha1.BMCDetails{
+				Address:         mock.BMCAddress(),
+				CredentialsName: "bmc-creds",
+			},
+			Provisioner: "redfish",
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bmc-creds", Namespace: hostNamespace},
+		Data:       map[string][]byte{"username": []byte("admin"), "password": []byte("password")},
+	}
+	hfs := &metal3v1alpha1.HostFirmwareSettings{
+		ObjectMeta: metav1.ObjectMeta{Name: hostName, Namespace: hostNamespace},
+	}
+
+	registry := provisioner.NewRegistry()
+	assert.NoError(t, registry.Register("fixture", &fixture.Fixture{}))
+	assert.NoError(t, registry.Register("redfish", redfish.NewProvisionerFactory(logf.Log)))
+
+	c := fakeclient.NewClientBuilder().WithObjects(bmh, secret, hfs).Build()
+	r := &HostFirmwareSettingsReconciler{
+		Client:             c,
+		APIReader:          c,
+		Log:                ctrl.Log.WithName("test_reconciler").WithName("HostFirmwareSettings"),
+		ProvisionerFactory: registry,
+	}
+
+	key := client.ObjectKey{Namespace: hostNamespace, Name: hostName}
+	_, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key})
+	assert.NoError(t, err)
+	assert.True(t, mock.CredentialsReceived)
+
+	assert.NoError(t, c.Get(context.TODO(), key, hfs))
+	assert.Equal(t, metal3v1alpha1.SettingsMap{"ProcVirtualization": "Enabled", "BootDelay": "5"}, hfs.Status.Settings)
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"reflect"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/imageverify"
+	"github.com/metal3-io/baremetal-operator/pkg/secretutils"
+)
+
+// imageSignatureKey returns the public key verifying the signature of
+// the checksum file of an image.
+func imageSignatureKey(secretManager secretutils.SecretManager, host *metal3v1alpha1.BareMetalHost, signature *metal3v1alpha1.ImageSignature) ([]byte, error) {
+	secretName, err := localSecretName(host, signature.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := secretManager.ObtainSecret(secretName)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok := secret.Data[metal3v1alpha1.ImageSignaturePublicKey]
+	if !ok {
+		return nil, NoDataInSecretError{secret: secret.Name, key: metal3v1alpha1.ImageSignaturePublicKey}
+	}
+	return publicKey, nil
+}
+
+// resolveImageChecksum sets the checksum of an image with a checksum
+// URL to the one listed for it in the checksum file, once the signature
+// of the file is verified. An imageverify.VerificationError is returned
+// when the file does not verify the image.
+func resolveImageChecksum(secretManager secretutils.SecretManager, host *metal3v1alpha1.BareMetalHost, image *metal3v1alpha1.Image) error {
+	if image.ChecksumURL == "" || image.Checksum != "" {
+		return nil
+	}
+
+	checksums, err := imageverify.Fetch(image.ChecksumURL)
+	if err != nil {
+		return err
+	}
+
+	if image.Signature != nil {
+		publicKey, err := imageSignatureKey(secretManager, host, image.Signature)
+		if err != nil {
+			return err
+		}
+		signature, err := imageverify.Fetch(image.Signature.URL)
+		if err != nil {
+			return err
+		}
+		if err := imageverify.VerifySignature(checksums, signature, image.Signature.Type, publicKey); err != nil {
+			return err
+		}
+	}
+
+	checksum, checksumType, err := imageverify.SelectChecksum(checksums, image.URL, image.ChecksumType)
+	if err != nil {
+		return err
+	}
+	image.Checksum = checksum
+	image.ChecksumType = checksumType
+	return nil
+}
+
+// storedImageChecksum returns the image of the host with the checksum
+// read from its checksum URL when provisioning started. It is kept in
+// the status of the host, so that the checksum file is only read once
+// and the host is provisioned with the checksum that was verified.
+func storedImageChecksum(host *metal3v1alpha1.BareMetalHost) (metal3v1alpha1.Image, bool) {
+	stored := host.Status.Provisioning.Image
+	if host.Spec.Image == nil || host.Spec.Image.Checksum != "" ||
+		stored.ChecksumURL == "" || stored.Checksum == "" {
+		return metal3v1alpha1.Image{}, false
+	}
+
+	// The stored image is only used while the spec still asks for it
+	unresolved := *stored.DeepCopy()
+	unresolved.Checksum = host.Spec.Image.Checksum
+	unresolved.ChecksumType = host.Spec.Image.ChecksumType
+	if !reflect.DeepEqual(unresolved, *host.Spec.Image) {
+		return metal3v1alpha1.Image{}, false
+	}
+	return *stored.DeepCopy(), true
+}
@@ -0,0 +1,332 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/imageverify"
+	"github.com/metal3-io/baremetal-operator/pkg/secretutils"
+)
+
+// The checksum file and its signatures are those used to test the
+// imageverify package.
+const imageVerifyTestData = "../../pkg/imageverify/testdata"
+
+const testImageChecksum = "4a5c6b2e1d8f7e3a9b0c1d2e3f405162738495a6b7c8d9e0f1a2b3c4d5e6f708"
+
+func readImageVerifyTestData(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(filepath.Join(imageVerifyTestData, name))
+	require.NoError(t, err)
+	return data
+}
+
+// newChecksumServer serves the files of the imageverify test data, with
+// a SHA256SUMS.tampered file whose content does not match the
+// signatures.
+func newChecksumServer(t *testing.T) *httptest.Server {
+	tampered := readImageVerifyTestData(t, "SHA256SUMS")
+	tampered[0] ^= 1
+	files := http.FileServer(http.Dir(imageVerifyTestData))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/SHA256SUMS.tampered" {
+			w.Write(tampered)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newImageKeySecret(t *testing.T, name string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "image-key", Namespace: namespace},
+		Data: map[string][]byte{
+			metal3v1alpha1.ImageSignaturePublicKey: readImageVerifyTestData(t, name),
+		},
+	}
+}
+
+func TestResolveImageChecksum(t *testing.T) {
+	server := newChecksumServer(t)
+
+	testCases := []struct {
+		Scenario             string
+		Image                metal3v1alpha1.Image
+		PublicKeyFile        string
+		ExpectedChecksum     string
+		ExpectedType         metal3v1alpha1.ChecksumType
+		ExpectedError        string
+		ExpectedVerification bool
+	}{
+		{
+			Scenario:         "literal checksum",
+			Image:            metal3v1alpha1.Image{URL: "https://example.com/ubuntu-22.04.qcow2", Checksum: "12345"},
+			ExpectedChecksum: "12345",
+		},
+		{
+			Scenario: "checksum URL",
+			Image: metal3v1alpha1.Image{
+				URL:         "https://example.com/ubuntu-22.04.qcow2",
+				ChecksumURL: server.URL + "/SHA256SUMS",
+			},
+			ExpectedChecksum: testImageChecksum,
+			ExpectedType:     metal3v1alpha1.SHA256,
+		},
+		{
+			Scenario: "gpg signature",
+			Image: metal3v1alpha1.Image{
+				URL:         "https://example.com/ubuntu-22.04.qcow2",
+				ChecksumURL: server.URL + "/SHA256SUMS",
+				Signature: &metal3v1alpha1.ImageSignature{
+					URL:       server.URL + "/SHA256SUMS.rsa.asc",
+					Type:      metal3v1alpha1.GPGSignature,
+					PublicKey: corev1.SecretReference{Name: "image-key"},
+				},
+			},
+			PublicKeyFile:    "gpg-rsa.pub",
+			ExpectedChecksum: testImageChecksum,
+			ExpectedType:     metal3v1alpha1.SHA256,
+		},
+		{
+			Scenario: "cosign signature",
+			Image: metal3v1alpha1.Image{
+				URL:         "https://example.com/ubuntu-22.04.qcow2",
+				ChecksumURL: server.URL + "/SHA256SUMS",
+				Signature: &metal3v1alpha1.ImageSignature{
+					URL:       server.URL + "/SHA256SUMS.cosign.sig",
+					Type:      metal3v1alpha1.CosignSignature,
+					PublicKey: corev1.SecretReference{Name: "image-key"},
+				},
+			},
+			PublicKeyFile:    "cosign.pub",
+			ExpectedChecksum: testImageChecksum,
+			ExpectedType:     metal3v1alpha1.SHA256,
+		},
+		{
+			Scenario: "tampered checksum file",
+			Image: metal3v1alpha1.Image{
+				URL:         "https://example.com/ubuntu-22.04.qcow2",
+				ChecksumURL: server.URL + "/SHA256SUMS.tampered",
+				Signature: &metal3v1alpha1.ImageSignature{
+					URL:       server.URL + "/SHA256SUMS.cosign.sig",
+					Type:      metal3v1alpha1.CosignSignature,
+					PublicKey: corev1.SecretReference{Name: "image-key"},
+				},
+			},
+			PublicKeyFile:        "cosign.pub",
+			ExpectedError:        "invalid cosign signature",
+			ExpectedVerification: true,
+		},
+		{
+			Scenario: "image not listed",
+			Image: metal3v1alpha1.Image{
+				URL:         "https://example.com/debian-12.qcow2",
+				ChecksumURL: server.URL + "/SHA256SUMS",
+			},
+			ExpectedError:        "no checksum for debian-12.qcow2 in the checksum file",
+			ExpectedVerification: true,
+		},
+		{
+			Scenario: "missing checksum file",
+			Image: metal3v1alpha1.Image{
+				URL:         "https://example.com/ubuntu-22.04.qcow2",
+				ChecksumURL: server.URL + "/MD5SUMS",
+			},
+			ExpectedError: "404 Not Found",
+		},
+		{
+			Scenario: "missing key",
+			Image: metal3v1alpha1.Image{
+				URL:         "https://example.com/ubuntu-22.04.qcow2",
+				ChecksumURL: server.URL + "/SHA256SUMS",
+				Signature: &metal3v1alpha1.ImageSignature{
+					URL:       server.URL + "/SHA256SUMS.cosign.sig",
+					Type:      metal3v1alpha1.CosignSignature,
+					PublicKey: corev1.SecretReference{Name: "other-key"},
+				},
+			},
+			ExpectedError: "not found",
+		},
+		{
+			Scenario: "key in another namespace",
+			Image: metal3v1alpha1.Image{
+				URL:         "https://example.com/ubuntu-22.04.qcow2",
+				ChecksumURL: server.URL + "/SHA256SUMS",
+				Signature: &metal3v1alpha1.ImageSignature{
+					URL:       server.URL + "/SHA256SUMS.cosign.sig",
+					Type:      metal3v1alpha1.CosignSignature,
+					PublicKey: corev1.SecretReference{Name: "image-key", Namespace: "other-namespace"},
+				},
+			},
+			PublicKeyFile: "cosign.pub",
+			ExpectedError: "secret other-namespace/image-key is not in the namespace of the host",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			host := newDefaultHost(t)
+			builder := fakeclient.NewClientBuilder()
+			if tc.PublicKeyFile != "" {
+				builder = builder.WithObjects(newImageKeySecret(t, tc.PublicKeyFile))
+			}
+			c := builder.Build()
+			secretManager := secretutils.NewSecretManager(logr.Discard(), c, c)
+
+			image := tc.Image.DeepCopy()
+			err := resolveImageChecksum(secretManager, host, image)
+			if tc.ExpectedError != "" {
+				assert.ErrorContains(t, err, tc.ExpectedError)
+				assert.Equal(t, tc.ExpectedVerification, errors.As(err, &imageverify.VerificationError{}))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.ExpectedChecksum, image.Checksum)
+			assert.Equal(t, tc.ExpectedType, image.ChecksumType)
+		})
+	}
+}
+
+// TestProvisionChecksumURL ensures that the checksum read from the
+// checksum file is recorded in the status of provisioned hosts.
+func TestProvisionChecksumURL(t *testing.T) {
+	server := newChecksumServer(t)
+	host := newDefaultHost(t)
+	host.Spec.Image = &metal3v1alpha1.Image{
+		URL:         "https://example.com/ubuntu-22.04.qcow2",
+		ChecksumURL: server.URL + "/SHA256SUMS",
+	}
+	host.Spec.Online = true
+	r := newTestReconciler(host)
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.Provisioning.Image.URL != ""
+		},
+	)
+	assert.Equal(t, testImageChecksum, host.Status.Provisioning.Image.Checksum)
+	assert.Equal(t, metal3v1alpha1.SHA256, host.Status.Provisioning.Image.ChecksumType)
+	assert.Equal(t, host.Spec.Image.ChecksumURL, host.Status.Provisioning.Image.ChecksumURL)
+}
+
+// TestProvisionChecksumURLReadOnce ensures that the checksum file is
+// read once when provisioning starts, and not at every reconcile.
+func TestProvisionChecksumURLReadOnce(t *testing.T) {
+	checksums := readImageVerifyTestData(t, "SHA256SUMS")
+	reads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reads++
+		w.Write(checksums)
+	}))
+	defer server.Close()
+
+	host := newDefaultHost(t)
+	host.Spec.Image = &metal3v1alpha1.Image{
+		URL:         "https://example.com/ubuntu-22.04.qcow2",
+		ChecksumURL: server.URL + "/SHA256SUMS",
+	}
+	host.Spec.Online = true
+	r := newTestReconciler(host)
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.Provisioning.State == metal3v1alpha1.StateProvisioned
+		},
+	)
+	assert.Equal(t, 1, reads)
+	assert.Equal(t, testImageChecksum, host.Status.Provisioning.Image.Checksum)
+}
+
+func TestStoredImageChecksum(t *testing.T) {
+	spec := metal3v1alpha1.Image{
+		URL:         "https://example.com/ubuntu-22.04.qcow2",
+		ChecksumURL: "https://example.com/SHA256SUMS",
+	}
+	resolved := spec
+	resolved.Checksum = testImageChecksum
+	resolved.ChecksumType = metal3v1alpha1.SHA256
+
+	testCases := []struct {
+		Scenario string
+		Spec     metal3v1alpha1.Image
+		Stored   metal3v1alpha1.Image
+		Expected bool
+	}{
+		{
+			Scenario: "not resolved",
+			Spec:     spec,
+		},
+		{
+			Scenario: "resolved",
+			Spec:     spec,
+			Stored:   resolved,
+			Expected: true,
+		},
+		{
+			Scenario: "image changed",
+			Spec: metal3v1alpha1.Image{
+				URL:         "https://example.com/ubuntu-24.04.qcow2",
+				ChecksumURL: spec.ChecksumURL,
+			},
+			Stored: resolved,
+		},
+		{
+			Scenario: "literal checksum",
+			Spec:     resolved,
+			Stored:   resolved,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			host := newDefaultHost(t)
+			host.Spec.Image = tc.Spec.DeepCopy()
+			host.Status.Provisioning.Image = tc.Stored
+
+			image, found := storedImageChecksum(host)
+			assert.Equal(t, tc.Expected, found)
+			if tc.Expected {
+				assert.Equal(t, tc.Stored, image)
+			}
+		})
+	}
+}
+
+// TestProvisionImageVerificationError ensures that a checksum file not
+// matching its signature fails provisioning with an image verification
+// error.
+func TestProvisionImageVerificationError(t *testing.T) {
+	server := newChecksumServer(t)
+	host := newDefaultHost(t)
+	host.Spec.Image = &metal3v1alpha1.Image{
+		URL:         "https://example.com/ubuntu-22.04.qcow2",
+		ChecksumURL: server.URL + "/SHA256SUMS.tampered",
+		Signature: &metal3v1alpha1.ImageSignature{
+			URL:       server.URL + "/SHA256SUMS.rsa.asc",
+			Type:      metal3v1alpha1.GPGSignature,
+			PublicKey: corev1.SecretReference{Name: "image-key"},
+		},
+	}
+	host.Spec.Online = true
+	r := newTestReconciler(host, newImageKeySecret(t, "gpg-rsa.pub"))
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.ErrorType == metal3v1alpha1.ImageVerificationError
+		},
+	)
+	assert.Contains(t, host.Status.ErrorMessage, "no valid OpenPGP signature by the public key")
+	assert.Empty(t, host.Status.Provisioning.Image.URL)
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/secretutils"
+)
+
+// dockerConfig is the content of a kubernetes.io/dockerconfigjson
+// secret.
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"auths"`
+}
+
+// registryHost returns the host of a registry in the auths of a docker
+// config, which may be given as a URL such as https://index.docker.io/v1/.
+func registryHost(key string) string {
+	if _, rest, found := strings.Cut(key, "://"); found {
+		key = rest
+	}
+	host, _, _ := strings.Cut(key, "/")
+	return host
+}
+
+// registryAuth returns the base64-encoded "user:password" credentials
+// for the registry from a kubernetes.io/dockerconfigjson secret.
+func registryAuth(secret *corev1.Secret, registry string) (string, error) {
+	if secret.Type != corev1.SecretTypeDockerConfigJson {
+		return "", fmt.Errorf("image pull secret %s is of type %s, expected %s",
+			secret.Name, secret.Type, corev1.SecretTypeDockerConfigJson)
+	}
+	data, ok := secret.Data[corev1.DockerConfigJsonKey]
+	if !ok {
+		return "", NoDataInSecretError{secret: secret.Name, key: corev1.DockerConfigJsonKey}
+	}
+
+	config := dockerConfig{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", errors.Wrapf(err, "failed to parse image pull secret %s", secret.Name)
+	}
+	for key, auth := range config.Auths {
+		if registryHost(key) != registry {
+			continue
+		}
+		if auth.Auth != "" {
+			return auth.Auth, nil
+		}
+		if auth.Username != "" {
+			return base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password)), nil
+		}
+	}
+	return "", fmt.Errorf("image pull secret %s has no credentials for registry %s", secret.Name, registry)
+}
+
+// imagePullSecret returns the credentials for pulling an OCI image
+// from its registry, or an empty string if the image needs none.
+func imagePullSecret(secretManager secretutils.SecretManager, host *metal3v1alpha1.BareMetalHost, image *metal3v1alpha1.Image) (string, error) {
+	if !image.IsOCI() || image.ImagePullSecret == nil {
+		return "", nil
+	}
+	ref, err := metal3v1alpha1.ParseOCIReference(image.URL)
+	if err != nil {
+		return "", err
+	}
+
+	secretName, err := localSecretName(host, *image.ImagePullSecret)
+	if err != nil {
+		return "", err
+	}
+	secret, err := secretManager.ObtainSecret(secretName)
+	if err != nil {
+		return "", err
+	}
+	return registryAuth(secret, ref.Registry)
+}
+
+// localSecretName returns the name of a secret referenced by a host,
+// refusing secrets outside of the namespace of the host so that a host
+// cannot use the credentials of another namespace.
+func localSecretName(host *metal3v1alpha1.BareMetalHost, ref corev1.SecretReference) (types.NamespacedName, error) {
+	if ref.Namespace != "" && ref.Namespace != host.Namespace {
+		return types.NamespacedName{}, fmt.Errorf("secret %s/%s is not in the namespace of the host", ref.Namespace, ref.Name)
+	}
+	return types.NamespacedName{Name: ref.Name, Namespace: host.Namespace}, nil
+}
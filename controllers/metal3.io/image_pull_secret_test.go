@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/secretutils"
+)
+
+const testOCIImage = "oci://quay.io/metal3/ubuntu:22.04@sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+func newPullSecret(config string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "pull-secret", Namespace: namespace},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(config)},
+	}
+}
+
+func TestRegistryAuth(t *testing.T) {
+	testCases := []struct {
+		Scenario      string
+		Secret        *corev1.Secret
+		Expected      string
+		ExpectedError string
+	}{
+		{
+			Scenario: "auth",
+			Secret:   newPullSecret(`{"auths": {"quay.io": {"auth": "dXNlcjpwYXNzd29yZA=="}}}`),
+			Expected: "dXNlcjpwYXNzd29yZA==",
+		},
+		{
+			Scenario: "username and password",
+			Secret:   newPullSecret(`{"auths": {"quay.io": {"username": "user", "password": "password"}}}`),
+			Expected: "dXNlcjpwYXNzd29yZA==",
+		},
+		{
+			Scenario: "registry URL",
+			Secret:   newPullSecret(`{"auths": {"https://quay.io/v1/": {"auth": "dXNlcjpwYXNzd29yZA=="}}}`),
+			Expected: "dXNlcjpwYXNzd29yZA==",
+		},
+		{
+			Scenario:      "other registry",
+			Secret:        newPullSecret(`{"auths": {"registry.example.com": {"auth": "dXNlcjpwYXNzd29yZA=="}}}`),
+			ExpectedError: "image pull secret pull-secret has no credentials for registry quay.io",
+		},
+		{
+			Scenario: "opaque secret",
+			Secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "pull-secret", Namespace: namespace},
+				Type:       corev1.SecretTypeOpaque,
+			},
+			ExpectedError: "image pull secret pull-secret is of type Opaque, expected kubernetes.io/dockerconfigjson",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			auth, err := registryAuth(tc.Secret, "quay.io")
+			if tc.ExpectedError != "" {
+				assert.EqualError(t, err, tc.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.Expected, auth)
+		})
+	}
+}
+
+func TestImagePullSecret(t *testing.T) {
+	secret := newPullSecret(`{"auths": {"quay.io": {"auth": "dXNlcjpwYXNzd29yZA=="}}}`)
+	c := fakeclient.NewClientBuilder().WithObjects(secret).Build()
+	secretManager := secretutils.NewSecretManager(logr.Discard(), c, c)
+	host := newDefaultHost(t)
+
+	testCases := []struct {
+		Scenario      string
+		Image         *metal3v1alpha1.Image
+		Expected      string
+		ExpectedError bool
+	}{
+		{
+			Scenario: "OCI image",
+			Image: &metal3v1alpha1.Image{
+				URL:             testOCIImage,
+				ImagePullSecret: &corev1.SecretReference{Name: "pull-secret"},
+			},
+			Expected: "dXNlcjpwYXNzd29yZA==",
+		},
+		{
+			Scenario: "OCI image without pull secret",
+			Image:    &metal3v1alpha1.Image{URL: testOCIImage},
+		},
+		{
+			Scenario: "HTTP image",
+			Image:    &metal3v1alpha1.Image{URL: "http://example.com/ubuntu.qcow2"},
+		},
+		{
+			Scenario: "missing secret",
+			Image: &metal3v1alpha1.Image{
+				URL:             testOCIImage,
+				ImagePullSecret: &corev1.SecretReference{Name: "missing"},
+			},
+			ExpectedError: true,
+		},
+		{
+			Scenario: "secret in another namespace",
+			Image: &metal3v1alpha1.Image{
+				URL:             testOCIImage,
+				ImagePullSecret: &corev1.SecretReference{Name: "pull-secret", Namespace: "other-namespace"},
+			},
+			ExpectedError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			auth, err := imagePullSecret(secretManager, host, tc.Image)
+			if tc.ExpectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.Expected, auth)
+		})
+	}
+}
@@ -0,0 +1,335 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+// IPPoolReconciler allocates the addresses of IPPools to the
+// BareMetalHosts whose network configuration references them
+type IPPoolReconciler struct {
+	client.Client
+	Log logr.Logger
+}
+
+//+kubebuilder:rbac:groups=metal3.io,resources=ippools,verbs=get;list;watch
+//+kubebuilder:rbac:groups=metal3.io,resources=ippools/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=metal3.io,resources=baremetalhosts,verbs=get;list;watch
+
+// Reconcile allocates an address of the pool to each host being
+// provisioned with it, and releases the addresses of the hosts that
+// are deprovisioned or deleted. Changes to the network configuration
+// of a host or to the pool only apply to the next provisioning. The
+// allocations are only recorded in the status of the pool.
+func (r *IPPoolReconciler) Reconcile(ctx context.Context, request ctrl.Request) (result ctrl.Result, err error) {
+	reqLogger := r.Log.WithValues("ippool", request.NamespacedName)
+	reqLogger.Info("start")
+
+	pool := &metal3v1alpha1.IPPool{}
+	if err = r.Get(ctx, request.NamespacedName, pool); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return ctrl.Result{}, errors.Wrap(err, "could not load IP pool")
+	}
+
+	addresses, err := parsePool(&pool.Spec)
+	if err != nil {
+		reqLogger.Info("invalid IP pool", "reason", err.Error())
+		return ctrl.Result{}, r.setStatus(ctx, pool, pool.Status.Allocations, err.Error())
+	}
+
+	hosts := &metal3v1alpha1.BareMetalHostList{}
+	if err = r.List(ctx, hosts, client.InNamespace(pool.Namespace)); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to list hosts")
+	}
+	var pending []string
+	needed := map[string]bool{}
+	holding := map[string]bool{}
+	for _, host := range hosts.Items {
+		if hostHoldsPoolAddress(&host) {
+			holding[host.Name] = true
+		}
+		if hostNeedsPoolAddress(&host, pool.Name) {
+			needed[host.Name] = true
+			pending = append(pending, host.Name)
+		}
+	}
+	sort.Strings(pending)
+
+	// Keep the existing allocations of the hosts until they are
+	// deprovisioned or deleted, even if their network configuration or
+	// the pool changed since, as the hosts were deployed with them
+	allocations := []metal3v1alpha1.IPAllocation{}
+	for _, allocation := range pool.Status.Allocations {
+		ip, err := netip.ParseAddr(allocation.Address)
+		if !holding[allocation.Host] || err != nil || addresses.allocated[ip] {
+			reqLogger.Info("releasing address", "host", allocation.Host, "address", allocation.Address)
+			continue
+		}
+		allocations = append(allocations, allocation)
+		addresses.allocated[ip] = true
+		delete(needed, allocation.Host)
+	}
+
+	message := ""
+	for _, hostName := range pending {
+		if !needed[hostName] {
+			continue
+		}
+		ip, found := addresses.next(hostName)
+		if !found {
+			message = fmt.Sprintf("no address left to allocate to host %s", hostName)
+			reqLogger.Info("IP pool exhausted", "host", hostName)
+			continue
+		}
+		reqLogger.Info("allocating address", "host", hostName, "address", ip.String())
+		allocations = append(allocations, metal3v1alpha1.IPAllocation{Host: hostName, Address: ip.String()})
+		addresses.allocated[ip] = true
+	}
+	sort.Slice(allocations, func(i, j int) bool {
+		return allocations[i].Host < allocations[j].Host
+	})
+
+	return ctrl.Result{}, r.setStatus(ctx, pool, allocations, message)
+}
+
+// hostHoldsPoolAddress returns whether the host is provisioned, or about
+// to be, and so keeps the addresses allocated to it.
+func hostHoldsPoolAddress(host *metal3v1alpha1.BareMetalHost) bool {
+	switch host.Status.Provisioning.State {
+	case metal3v1alpha1.StateProvisioning, metal3v1alpha1.StateProvisioned, metal3v1alpha1.StateDeprovisioning:
+		return true
+	}
+	return false
+}
+
+// hostNeedsPoolAddress returns whether the network configuration of the
+// host references the pool and the host is provisioned, or about to be.
+func hostNeedsPoolAddress(host *metal3v1alpha1.BareMetalHost, poolName string) bool {
+	if !hostHoldsPoolAddress(host) || host.Spec.Network == nil {
+		return false
+	}
+	for _, address := range host.Spec.Network.Addresses {
+		if address.IPPool == poolName {
+			return true
+		}
+	}
+	return false
+}
+
+// poolAddresses holds the range of addresses of a pool and those that
+// can not be allocated to any host.
+type poolAddresses struct {
+	prefix           netip.Prefix
+	start, end       netip.Addr
+	excluded         map[netip.Addr]bool
+	reserved         map[netip.Addr]string
+	allocated        map[netip.Addr]bool
+	hostReservations map[string]netip.Addr
+}
+
+// parsePool parses the subnet, range, gateway and reservations of a
+// pool.
+func parsePool(spec *metal3v1alpha1.IPPoolSpec) (*poolAddresses, error) {
+	prefix, err := netip.ParsePrefix(spec.CIDR)
+	if err != nil {
+		return nil, errors.Errorf("invalid cidr %q", spec.CIDR)
+	}
+	prefix = prefix.Masked()
+	addresses := &poolAddresses{
+		prefix:           prefix,
+		start:            prefix.Addr(),
+		end:              lastAddr(prefix),
+		excluded:         map[netip.Addr]bool{},
+		reserved:         map[netip.Addr]string{},
+		allocated:        map[netip.Addr]bool{},
+		hostReservations: map[string]netip.Addr{},
+	}
+	if prefix.Addr().Is4() && prefix.Bits() < 31 {
+		// The network and broadcast addresses
+		addresses.excluded[addresses.start] = true
+		addresses.excluded[addresses.end] = true
+	}
+
+	parse := func(field, value string) (netip.Addr, error) {
+		ip, err := netip.ParseAddr(value)
+		if err != nil || !prefix.Contains(ip) {
+			return ip, errors.Errorf("%s %q is not an address of %s", field, value, prefix)
+		}
+		return ip, nil
+	}
+	if spec.Start != "" {
+		if addresses.start, err = parse("start", spec.Start); err != nil {
+			return nil, err
+		}
+	}
+	if spec.End != "" {
+		if addresses.end, err = parse("end", spec.End); err != nil {
+			return nil, err
+		}
+	}
+	if addresses.end.Less(addresses.start) {
+		return nil, errors.Errorf("end %s is before start %s", addresses.end, addresses.start)
+	}
+	if spec.Gateway != "" {
+		gateway, err := parse("gateway", spec.Gateway)
+		if err != nil {
+			return nil, err
+		}
+		addresses.excluded[gateway] = true
+	}
+	for _, reservation := range spec.Reservations {
+		ip, err := parse("reserved address", reservation.Address)
+		if err != nil {
+			return nil, err
+		}
+		addresses.reserved[ip] = reservation.Host
+		if reservation.Host != "" {
+			addresses.hostReservations[reservation.Host] = ip
+		}
+	}
+	return addresses, nil
+}
+
+// lastAddr returns the last address of a subnet.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().AsSlice()
+	for i := range bytes {
+		hostBits := len(bytes)*8 - prefix.Bits() - (len(bytes)-1-i)*8
+		switch {
+		case hostBits >= 8:
+			bytes[i] = 0xff
+		case hostBits > 0:
+			bytes[i] |= byte(1<<hostBits - 1)
+		}
+	}
+	last, _ := netip.AddrFromSlice(bytes)
+	return last
+}
+
+// allocatable returns whether an address can be allocated to the host.
+func (a *poolAddresses) allocatable(ip netip.Addr, hostName string) bool {
+	if !a.prefix.Contains(ip) || a.excluded[ip] || a.allocated[ip] {
+		return false
+	}
+	if reservedFor, reserved := a.reserved[ip]; reserved {
+		return reservedFor == hostName
+	}
+	if reservedIP, reserved := a.hostReservations[hostName]; reserved {
+		// The host must get the address reserved for it
+		return ip == reservedIP
+	}
+	return !ip.Less(a.start) && !a.end.Less(ip)
+}
+
+// next returns the address reserved for the host, or else the first
+// free address of the range.
+func (a *poolAddresses) next(hostName string) (netip.Addr, bool) {
+	if ip, reserved := a.hostReservations[hostName]; reserved {
+		return ip, a.allocatable(ip, hostName)
+	}
+	for ip := a.start; ip.IsValid() && !a.end.Less(ip); ip = ip.Next() {
+		if a.allocatable(ip, hostName) {
+			return ip, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+func (r *IPPoolReconciler) setStatus(ctx context.Context, pool *metal3v1alpha1.IPPool, allocations []metal3v1alpha1.IPAllocation, message string) error {
+	if len(allocations) == 0 {
+		allocations = nil
+	}
+	newStatus := metal3v1alpha1.IPPoolStatus{
+		Allocations: allocations,
+		Message:     message,
+	}
+	if reflect.DeepEqual(pool.Status, newStatus) {
+		return nil
+	}
+	pool.Status = newStatus
+	if err := r.Status().Update(ctx, pool); err != nil {
+		return errors.Wrap(err, "failed to update IP pool status")
+	}
+	return nil
+}
+
+// poolsForHost enqueues the pools referenced by the network
+// configuration of a host, and those holding an address of the host so
+// that it is released once the host is gone.
+func (r *IPPoolReconciler) poolsForHost(obj client.Object) []reconcile.Request {
+	host, ok := obj.(*metal3v1alpha1.BareMetalHost)
+	if !ok {
+		return nil
+	}
+
+	names := map[string]bool{}
+	if host.Spec.Network != nil {
+		for _, address := range host.Spec.Network.Addresses {
+			if address.IPPool != "" {
+				names[address.IPPool] = true
+			}
+		}
+	}
+
+	pools := &metal3v1alpha1.IPPoolList{}
+	if err := r.List(context.Background(), pools, client.InNamespace(host.Namespace)); err != nil {
+		r.Log.Error(err, "failed to list IP pools", "namespace", host.Namespace)
+	}
+	for _, pool := range pools.Items {
+		if pool.AllocatedAddress(host.Name) != "" {
+			names[pool.Name] = true
+		}
+	}
+
+	var requests []reconcile.Request
+	for name := range names {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: host.Namespace, Name: name},
+		})
+	}
+	return requests
+}
+
+// SetupWithManager registers the reconciler to be run by the manager
+func (r *IPPoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&metal3v1alpha1.IPPool{}).
+		Watches(&source.Kind{Type: &metal3v1alpha1.BareMetalHost{}},
+			handler.EnqueueRequestsFromMapFunc(r.poolsForHost)).
+		Complete(r)
+}
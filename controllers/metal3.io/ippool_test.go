@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+func newIPPool(name string, spec metal3v1alpha1.IPPoolSpec, allocations ...metal3v1alpha1.IPAllocation) *metal3v1alpha1.IPPool {
+	return &metal3v1alpha1.IPPool{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "IPPool",
+			APIVersion: "metal3.io/v1alpha1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec:   spec,
+		Status: metal3v1alpha1.IPPoolStatus{Allocations: allocations},
+	}
+}
+
+func newPoolHost(name, poolName string, state metal3v1alpha1.ProvisioningState) *metal3v1alpha1.BareMetalHost {
+	host := newHost(name, &metal3v1alpha1.BareMetalHostSpec{
+		Network: &metal3v1alpha1.NetworkConfig{
+			Links:     []metal3v1alpha1.NetworkLink{{Name: "eth0", NIC: "eno1"}},
+			Addresses: []metal3v1alpha1.NetworkAddress{{Interface: "eth0", IPPool: poolName}},
+		},
+	})
+	host.Status.Provisioning.State = state
+	return host
+}
+
+func reconcileIPPool(t *testing.T, initObjs ...runtime.Object) *metal3v1alpha1.IPPool {
+	r := &IPPoolReconciler{
+		Client: fakeclient.NewClientBuilder().WithRuntimeObjects(initObjs...).Build(),
+		Log:    ctrl.Log.WithName("controllers").WithName("IPPool"),
+	}
+	key := types.NamespacedName{Namespace: namespace, Name: "pool"}
+	_, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	pool := &metal3v1alpha1.IPPool{}
+	require.NoError(t, r.Get(context.TODO(), key, pool))
+	return pool
+}
+
+func TestIPPoolAllocate(t *testing.T) {
+	spec := metal3v1alpha1.IPPoolSpec{
+		CIDR:    "192.168.111.0/24",
+		Start:   "192.168.111.1",
+		End:     "192.168.111.5",
+		Gateway: "192.168.111.1",
+		Reservations: []metal3v1alpha1.IPReservation{
+			{Address: "192.168.111.2"},
+			{Address: "192.168.111.100", Host: "host-reserved"},
+		},
+	}
+
+	testCases := []struct {
+		Scenario    string
+		Spec        metal3v1alpha1.IPPoolSpec
+		Allocations []metal3v1alpha1.IPAllocation
+		Hosts       []runtime.Object
+		Expected    []metal3v1alpha1.IPAllocation
+		Message     string
+	}{
+		{
+			Scenario: "first free addresses",
+			Spec:     spec,
+			Hosts: []runtime.Object{
+				newPoolHost("host-1", "pool", metal3v1alpha1.StateProvisioning),
+				newPoolHost("host-0", "pool", metal3v1alpha1.StateProvisioned),
+			},
+			Expected: []metal3v1alpha1.IPAllocation{
+				{Host: "host-0", Address: "192.168.111.3"},
+				{Host: "host-1", Address: "192.168.111.4"},
+			},
+		},
+		{
+			Scenario: "reservation",
+			Spec:     spec,
+			Hosts: []runtime.Object{
+				newPoolHost("host-reserved", "pool", metal3v1alpha1.StateProvisioning),
+			},
+			Expected: []metal3v1alpha1.IPAllocation{
+				{Host: "host-reserved", Address: "192.168.111.100"},
+			},
+		},
+		{
+			Scenario: "existing allocations are kept",
+			Spec:     spec,
+			Allocations: []metal3v1alpha1.IPAllocation{
+				{Host: "host-1", Address: "192.168.111.3"},
+			},
+			Hosts: []runtime.Object{
+				newPoolHost("host-0", "pool", metal3v1alpha1.StateProvisioning),
+				newPoolHost("host-1", "pool", metal3v1alpha1.StateProvisioned),
+			},
+			Expected: []metal3v1alpha1.IPAllocation{
+				{Host: "host-0", Address: "192.168.111.4"},
+				{Host: "host-1", Address: "192.168.111.3"},
+			},
+		},
+		{
+			Scenario: "release",
+			Spec:     spec,
+			Allocations: []metal3v1alpha1.IPAllocation{
+				{Host: "host-0", Address: "192.168.111.3"},
+				{Host: "host-1", Address: "192.168.111.4"},
+				{Host: "host-deleted", Address: "192.168.111.5"},
+			},
+			Hosts: []runtime.Object{
+				newPoolHost("host-0", "pool", metal3v1alpha1.StateDeprovisioning),
+				newPoolHost("host-1", "pool", metal3v1alpha1.StateAvailable),
+			},
+			Expected: []metal3v1alpha1.IPAllocation{
+				{Host: "host-0", Address: "192.168.111.3"},
+			},
+		},
+		{
+			Scenario: "provisioned hosts keep their addresses",
+			Spec: metal3v1alpha1.IPPoolSpec{
+				CIDR:  "192.168.111.0/24",
+				Start: "192.168.111.10",
+				End:   "192.168.111.20",
+				Reservations: []metal3v1alpha1.IPReservation{
+					{Address: "192.168.111.4", Host: "host-1"},
+				},
+			},
+			Allocations: []metal3v1alpha1.IPAllocation{
+				{Host: "host-0", Address: "192.168.111.3"},
+				{Host: "host-1", Address: "192.168.111.5"},
+				{Host: "host-2", Address: "192.168.112.3"},
+			},
+			Hosts: []runtime.Object{
+				newPoolHost("host-0", "other-pool", metal3v1alpha1.StateProvisioned),
+				newPoolHost("host-1", "pool", metal3v1alpha1.StateProvisioned),
+				newPoolHost("host-2", "pool", metal3v1alpha1.StateDeprovisioning),
+			},
+			Expected: []metal3v1alpha1.IPAllocation{
+				{Host: "host-0", Address: "192.168.111.3"},
+				{Host: "host-1", Address: "192.168.111.5"},
+				{Host: "host-2", Address: "192.168.112.3"},
+			},
+		},
+		{
+			Scenario: "other pool",
+			Spec:     spec,
+			Hosts: []runtime.Object{
+				newPoolHost("host-0", "other-pool", metal3v1alpha1.StateProvisioning),
+			},
+		},
+		{
+			Scenario: "exhausted",
+			Spec:     spec,
+			Hosts: []runtime.Object{
+				newPoolHost("host-0", "pool", metal3v1alpha1.StateProvisioning),
+				newPoolHost("host-1", "pool", metal3v1alpha1.StateProvisioning),
+				newPoolHost("host-2", "pool", metal3v1alpha1.StateProvisioning),
+				newPoolHost("host-3", "pool", metal3v1alpha1.StateProvisioning),
+			},
+			Expected: []metal3v1alpha1.IPAllocation{
+				{Host: "host-0", Address: "192.168.111.3"},
+				{Host: "host-1", Address: "192.168.111.4"},
+				{Host: "host-2", Address: "192.168.111.5"},
+			},
+			Message: "no address left to allocate to host host-3",
+		},
+		{
+			Scenario: "network and broadcast addresses",
+			Spec:     metal3v1alpha1.IPPoolSpec{CIDR: "192.168.111.0/30"},
+			Hosts: []runtime.Object{
+				newPoolHost("host-0", "pool", metal3v1alpha1.StateProvisioning),
+				newPoolHost("host-1", "pool", metal3v1alpha1.StateProvisioning),
+				newPoolHost("host-2", "pool", metal3v1alpha1.StateProvisioning),
+			},
+			Expected: []metal3v1alpha1.IPAllocation{
+				{Host: "host-0", Address: "192.168.111.1"},
+				{Host: "host-1", Address: "192.168.111.2"},
+			},
+			Message: "no address left to allocate to host host-2",
+		},
+		{
+			Scenario: "ipv6",
+			Spec:     metal3v1alpha1.IPPoolSpec{CIDR: "fd2e:6f44:5dd8::/64", Start: "fd2e:6f44:5dd8::ff"},
+			Hosts: []runtime.Object{
+				newPoolHost("host-0", "pool", metal3v1alpha1.StateProvisioning),
+				newPoolHost("host-1", "pool", metal3v1alpha1.StateProvisioning),
+			},
+			Expected: []metal3v1alpha1.IPAllocation{
+				{Host: "host-0", Address: "fd2e:6f44:5dd8::ff"},
+				{Host: "host-1", Address: "fd2e:6f44:5dd8::100"},
+			},
+		},
+		{
+			Scenario: "invalid range",
+			Spec:     metal3v1alpha1.IPPoolSpec{CIDR: "192.168.111.0/24", Start: "192.168.112.1"},
+			Hosts: []runtime.Object{
+				newPoolHost("host-0", "pool", metal3v1alpha1.StateProvisioning),
+			},
+			Message: `start "192.168.112.1" is not an address of 192.168.111.0/24`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			objs := append([]runtime.Object{newIPPool("pool", tc.Spec, tc.Allocations...)}, tc.Hosts...)
+			pool := reconcileIPPool(t, objs...)
+			assert.Equal(t, tc.Expected, pool.Status.Allocations)
+			assert.Equal(t, tc.Message, pool.Status.Message)
+		})
+	}
+}
+
+func TestIPPoolsForHost(t *testing.T) {
+	host := newPoolHost("host-0", "pool", metal3v1alpha1.StateAvailable)
+	r := &IPPoolReconciler{
+		Client: fakeclient.NewClientBuilder().WithRuntimeObjects(
+			newIPPool("pool", metal3v1alpha1.IPPoolSpec{}),
+			newIPPool("old-pool", metal3v1alpha1.IPPoolSpec{},
+				metal3v1alpha1.IPAllocation{Host: "host-0", Address: "10.0.0.3"}),
+			newIPPool("other-pool", metal3v1alpha1.IPPoolSpec{}),
+		).Build(),
+		Log: ctrl.Log.WithName("controllers").WithName("IPPool"),
+	}
+
+	var names []string
+	for _, request := range r.poolsForHost(host) {
+		names = append(names, request.Name)
+	}
+	assert.ElementsMatch(t, []string{"pool", "old-pool"}, names)
+}
@@ -17,6 +17,9 @@ const (
 	labelPrevState     = "prev_state"
 	labelNewState      = "new_state"
 	labelHostDataType  = "host_data_type"
+	labelState         = "state"
+	labelBMCEventKind  = "kind"
+	labelComponent     = "component"
 )
 
 var reconcileCounters = prometheus.NewCounterVec(prometheus.CounterOpts{
@@ -86,6 +89,11 @@ var stateTime = map[metal3v1alpha1.ProvisioningState]*prometheus.HistogramVec{
 		Help:    "Length of time per hardware inspection per host",
 		Buckets: slowOperationBuckets,
 	}, []string{labelHostNamespace, labelHostName}),
+	metal3v1alpha1.StatePreparing: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "metal3_operation_prepare_duration_seconds",
+		Help:    "Length of time per hardware preparation per host",
+		Buckets: slowOperationBuckets,
+	}, []string{labelHostNamespace, labelHostName}),
 	metal3v1alpha1.StateProvisioning: prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "metal3_operation_provision_duration_seconds",
 		Help:    "Length of time per hardware provision operation per host",
@@ -128,6 +136,26 @@ var deleteDelayedForDetached = prometheus.NewCounter(prometheus.CounterOpts{
 	Help: "Number of times a host delete action was delayed due to the detached annotation",
 })
 
+var hostsNeedingOperator = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "metal3_host_needs_operator_total",
+	Help: "Number of times a host has exhausted its retry policy and needs an operator",
+}, []string{labelErrorType})
+
+var stateTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "metal3_state_timeout_total",
+	Help: "Number of times a host has stayed in a provisioning state for longer than allowed",
+}, []string{labelState})
+
+var bmcEventsReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "metal3_bmc_events_received_total",
+	Help: "Number of events received from BMCs",
+}, []string{labelBMCEventKind})
+
+var hardwareHealth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "metal3_host_component_health",
+	Help: "Health of the hardware components of hosts as reported by their BMC (0 OK, 1 Warning, 2 Critical)",
+}, []string{labelHostNamespace, labelHostName, labelComponent})
+
 func init() {
 	metrics.Registry.MustRegister(
 		reconcileCounters,
@@ -155,7 +183,11 @@ func init() {
 		hostUnmanaged,
 		deleteWithoutDeprov,
 		provisionerNotReady,
-		deleteDelayedForDetached)
+		deleteDelayedForDetached,
+		stateTimeouts,
+		hostsNeedingOperator,
+		bmcEventsReceived,
+		hardwareHealth)
 }
 
 func hostMetricLabels(request ctrl.Request) prometheus.Labels {
@@ -165,6 +197,14 @@ func hostMetricLabels(request ctrl.Request) prometheus.Labels {
 	}
 }
 
+// healthMetricValue is the value of the hardwareHealth gauge for the
+// health of a component.
+var healthMetricValue = map[metal3v1alpha1.HealthState]float64{
+	metal3v1alpha1.HealthOK:       0,
+	metal3v1alpha1.HealthWarning:  1,
+	metal3v1alpha1.HealthCritical: 2,
+}
+
 func stateChangeMetricLabels(prevState, newState metal3v1alpha1.ProvisioningState) prometheus.Labels {
 	return prometheus.Labels{
 		labelPrevState: string(prevState),
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/networkdata"
+	"github.com/metal3-io/baremetal-operator/pkg/secretutils"
+)
+
+// networkDataSecretName returns the name of the Secret holding the
+// network data rendered from the network configuration of a host, which
+// its preprovisioning image is built with.
+func networkDataSecretName(host *metal3v1alpha1.BareMetalHost) string {
+	return host.Name + "-network-data"
+}
+
+// renderNetworkData renders the network configuration of a host into
+// network_data.json, using the NICs found by inspection and the
+// addresses allocated to the host from IP pools.
+func renderNetworkData(host *metal3v1alpha1.BareMetalHost, poolAddresses map[string]networkdata.PoolAddress) (string, error) {
+	var nics []metal3v1alpha1.NIC
+	if host.Status.HardwareDetails != nil {
+		nics = host.Status.HardwareDetails.NIC
+	}
+	data, err := networkdata.Render(host.Spec.Network, nics, poolAddresses)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// preprovisioningNetworkDataName returns the name of the Secret holding
+// the network data of the preprovisioning image of a host. Unless
+// overridden by PreprovisioningNetworkDataName, the network
+// configuration of the host is rendered into a Secret owned by the
+// host. The image gets no network data while the configuration can not
+// be rendered, such as when it references NICs by name before the host
+// is inspected or addresses of IP pools, which are only allocated once
+// the host is provisioned.
+func (r *BareMetalHostReconciler) preprovisioningNetworkDataName(info *reconcileInfo) (string, error) {
+	host := info.host
+	if host.Spec.PreprovisioningNetworkDataName != "" || host.Spec.Network == nil {
+		return host.Spec.PreprovisioningNetworkDataName, nil
+	}
+
+	data, err := renderNetworkData(host, nil)
+	if err != nil {
+		info.log.Info("not building the network configuration into the preprovisioning image", "reason", err.Error())
+		return "", nil
+	}
+
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{
+		Name:      networkDataSecretName(host),
+		Namespace: host.Namespace,
+	}
+	err = r.Get(context.TODO(), key, secret)
+	if k8serrors.IsNotFound(err) {
+		info.log.Info("creating network data secret", "secret", key.Name)
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+				Labels: map[string]string{
+					secretutils.LabelEnvironmentName: secretutils.LabelEnvironmentValue,
+				},
+			},
+			Data: map[string][]byte{"networkData": []byte(data)},
+		}
+		if err := controllerutil.SetControllerReference(host, secret, r.Scheme()); err != nil {
+			return "", err
+		}
+		return key.Name, errors.Wrap(r.Create(context.TODO(), secret), "failed to create network data secret")
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read network data secret")
+	}
+
+	if !metav1.IsControlledBy(secret, host) {
+		return "", fmt.Errorf("secret %s already exists and is not owned by the host", key.Name)
+	}
+	if string(secret.Data["networkData"]) != data {
+		info.log.Info("updating network data secret", "secret", key.Name)
+		secret.Data = map[string][]byte{"networkData": []byte(data)}
+		if err := r.Update(context.TODO(), secret); err != nil {
+			return "", errors.Wrap(err, "failed to update network data secret")
+		}
+	}
+	return key.Name, nil
+}
+
+// poolAddresses returns the addresses allocated to a host from the IP
+// pools its network configuration references, by pool name. It returns
+// false when an address is not allocated yet.
+func (r *BareMetalHostReconciler) poolAddresses(host *metal3v1alpha1.BareMetalHost) (map[string]networkdata.PoolAddress, bool, error) {
+	addresses := map[string]networkdata.PoolAddress{}
+	if host.Spec.Network == nil {
+		return addresses, true, nil
+	}
+	for _, address := range host.Spec.Network.Addresses {
+		if address.IPPool == "" {
+			continue
+		}
+		if _, exists := addresses[address.IPPool]; exists {
+			continue
+		}
+		pool := &metal3v1alpha1.IPPool{}
+		key := client.ObjectKey{
+			Name:      address.IPPool,
+			Namespace: host.Namespace,
+		}
+		if err := r.Get(context.TODO(), key, pool); err != nil {
+			return nil, false, errors.Wrapf(err, "failed to read IP pool %s", address.IPPool)
+		}
+		allocated := pool.AllocatedAddress(host.Name)
+		if allocated == "" {
+			return nil, false, nil
+		}
+		_, subnet, err := net.ParseCIDR(pool.Spec.CIDR)
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "invalid CIDR of IP pool %s", address.IPPool)
+		}
+		ones, _ := subnet.Mask.Size()
+		addresses[address.IPPool] = networkdata.PoolAddress{
+			Address:    fmt.Sprintf("%s/%d", allocated, ones),
+			Gateway:    pool.Spec.Gateway,
+			DNSServers: pool.Spec.DNSServers,
+		}
+	}
+	return addresses, true, nil
+}
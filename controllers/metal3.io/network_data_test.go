@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	goctx "context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/networkdata"
+)
+
+func TestGetPreprovImageNetworkConfig(t *testing.T) {
+	host := newDefaultHost(t)
+	host.Spec.Network = &metal3v1alpha1.NetworkConfig{
+		Links:     []metal3v1alpha1.NetworkLink{{Name: "eth0", MACAddress: "00:11:22:33:44:55"}},
+		Addresses: []metal3v1alpha1.NetworkAddress{{Interface: "eth0", Type: metal3v1alpha1.DHCP4Address}},
+	}
+	r := newTestReconciler(host)
+	i := makeReconcileInfo(host)
+
+	getImage := func() metal3v1alpha1.PreprovisioningImage {
+		_, err := r.getPreprovImage(i, []metal3v1alpha1.ImageFormat{"iso"})
+		require.NoError(t, err)
+		img := metal3v1alpha1.PreprovisioningImage{}
+		require.NoError(t, r.Client.Get(goctx.TODO(), client.ObjectKeyFromObject(host), &img))
+		return img
+	}
+	getSecret := func() corev1.Secret {
+		secret := corev1.Secret{}
+		require.NoError(t, r.Client.Get(goctx.TODO(), client.ObjectKey{
+			Name:      host.Name + "-network-data",
+			Namespace: host.Namespace,
+		}, &secret))
+		return secret
+	}
+
+	img := getImage()
+	assert.Equal(t, host.Name+"-network-data", img.Spec.NetworkDataName)
+	secret := getSecret()
+	assert.True(t, metav1.IsControlledBy(&secret, host))
+	assert.JSONEq(t, `{
+		"links": [{"id": "eth0", "type": "phy", "ethernet_mac_address": "00:11:22:33:44:55"}],
+		"networks": [{"id": "network0", "type": "ipv4_dhcp", "link": "eth0", "network_id": "network0"}]
+	}`, string(secret.Data["networkData"]))
+
+	// The secret follows the changes of the configuration
+	host.Spec.Network.Links[0].MTU = 9000
+	getImage()
+	assert.Contains(t, string(getSecret().Data["networkData"]), `"mtu":9000`)
+
+	// NICs given by name are only known once inspected
+	host.Spec.Network.Links[0] = metal3v1alpha1.NetworkLink{Name: "eth0", NIC: "nic-1"}
+	img = getImage()
+	assert.Empty(t, img.Spec.NetworkDataName)
+
+	host.Status.HardwareDetails = &metal3v1alpha1.HardwareDetails{
+		NIC: []metal3v1alpha1.NIC{{Name: "nic-1", MAC: "ab:cd:12:34:56:78"}},
+	}
+	img = getImage()
+	assert.Equal(t, host.Name+"-network-data", img.Spec.NetworkDataName)
+	assert.Contains(t, string(getSecret().Data["networkData"]), `"ethernet_mac_address":"ab:cd:12:34:56:78"`)
+
+	// An explicit secret overrides the configuration
+	host.Spec.PreprovisioningNetworkDataName = "net-data"
+	img = getImage()
+	assert.Equal(t, "net-data", img.Spec.NetworkDataName)
+}
+
+// TestProvisionNetworkConfigError ensures that provisioning fails when
+// the network configuration references NICs the host does not have.
+func TestProvisionNetworkConfigError(t *testing.T) {
+	host := newDefaultHost(t)
+	host.Spec.Image = &metal3v1alpha1.Image{
+		URL:      "https://example.com/image-name",
+		Checksum: "12345",
+	}
+	host.Spec.Online = true
+	host.Spec.Network = &metal3v1alpha1.NetworkConfig{
+		Links: []metal3v1alpha1.NetworkLink{{Name: "eth0", NIC: "nic-3"}},
+	}
+	r := newTestReconciler(host)
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.ErrorType == metal3v1alpha1.ProvisioningError
+		},
+	)
+	assert.Equal(t, `network link "eth0" references NIC "nic-3", which the host does not have`, host.Status.ErrorMessage)
+	assert.Empty(t, host.Status.Provisioning.Image.URL)
+}
+
+func TestPoolAddresses(t *testing.T) {
+	host := newDefaultHost(t)
+	host.Spec.Network = &metal3v1alpha1.NetworkConfig{
+		Links: []metal3v1alpha1.NetworkLink{{Name: "eth0", NIC: "nic-1"}},
+		Addresses: []metal3v1alpha1.NetworkAddress{
+			{Interface: "eth0", Type: metal3v1alpha1.DHCP6Address},
+			{Interface: "eth0", IPPool: "pool"},
+		},
+	}
+	pool := newIPPool("pool", metal3v1alpha1.IPPoolSpec{
+		CIDR:       "192.168.111.0/24",
+		Gateway:    "192.168.111.1",
+		DNSServers: []string{"192.168.111.1"},
+	})
+	r := newTestReconciler(host, pool)
+
+	_, allocated, err := r.poolAddresses(host)
+	require.NoError(t, err)
+	assert.False(t, allocated)
+
+	pool.Status.Allocations = []metal3v1alpha1.IPAllocation{{Host: host.Name, Address: "192.168.111.20"}}
+	require.NoError(t, r.Client.Update(goctx.TODO(), pool))
+	addresses, allocated, err := r.poolAddresses(host)
+	require.NoError(t, err)
+	assert.True(t, allocated)
+	assert.Equal(t, map[string]networkdata.PoolAddress{
+		"pool": {Address: "192.168.111.20/24", Gateway: "192.168.111.1", DNSServers: []string{"192.168.111.1"}},
+	}, addresses)
+}
+
+// TestProvisionUnknownIPPool ensures that provisioning fails when the
+// network configuration references an IP pool that does not exist.
+func TestProvisionUnknownIPPool(t *testing.T) {
+	host := newDefaultHost(t)
+	host.Spec.Image = &metal3v1alpha1.Image{
+		URL:      "https://example.com/image-name",
+		Checksum: "12345",
+	}
+	host.Spec.Online = true
+	host.Spec.Network = &metal3v1alpha1.NetworkConfig{
+		Links:     []metal3v1alpha1.NetworkLink{{Name: "eth0", NIC: "nic-1"}},
+		Addresses: []metal3v1alpha1.NetworkAddress{{Interface: "eth0", IPPool: "pool"}},
+	}
+	r := newTestReconciler(host)
+
+	tryReconcile(t, r, host,
+		func(host *metal3v1alpha1.BareMetalHost, result reconcile.Result) bool {
+			return host.Status.ErrorType == metal3v1alpha1.ProvisioningError
+		},
+	)
+	assert.Contains(t, host.Status.ErrorMessage, "failed to read IP pool pool")
+	assert.Empty(t, host.Status.Provisioning.Image.URL)
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+const (
+	// retryAnnotation asks the operator to resume retrying a host that
+	// needs an operator.
+	retryAnnotation = "retry.metal3.io"
+)
+
+// RetryPolicyList is a list of retry policies that can be set with a
+// command line flag. Each occurrence of the flag adds a policy, given
+// as comma-separated key=value pairs, e.g.
+// "errorType=inspection error,maxAttempts=5,backoffBase=30s,backoffCap=1h".
+type RetryPolicyList []metal3v1alpha1.RetryPolicy
+
+func (l *RetryPolicyList) String() string {
+	policies := make([]string, 0, len(*l))
+	for _, policy := range *l {
+		fields := []string{}
+		if policy.ErrorType != "" {
+			fields = append(fields, fmt.Sprintf("errorType=%s", policy.ErrorType))
+		}
+		if policy.MaxAttempts != 0 {
+			fields = append(fields, fmt.Sprintf("maxAttempts=%d", policy.MaxAttempts))
+		}
+		if policy.BackoffBase != nil {
+			fields = append(fields, fmt.Sprintf("backoffBase=%s", policy.BackoffBase.Duration))
+		}
+		if policy.BackoffCap != nil {
+			fields = append(fields, fmt.Sprintf("backoffCap=%s", policy.BackoffCap.Duration))
+		}
+		policies = append(policies, strings.Join(fields, ","))
+	}
+	return strings.Join(policies, ";")
+}
+
+// Set parses a retry policy and adds it to the list.
+func (l *RetryPolicyList) Set(value string) error {
+	policy := metal3v1alpha1.RetryPolicy{}
+
+	for _, field := range strings.Split(value, ",") {
+		key, val, found := strings.Cut(field, "=")
+		if !found {
+			return fmt.Errorf("invalid retry policy field %q, expected key=value", field)
+		}
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+
+		switch key {
+		case "errorType":
+			policy.ErrorType = metal3v1alpha1.ErrorType(val)
+		case "maxAttempts":
+			attempts, err := strconv.Atoi(val)
+			if err != nil || attempts < 0 {
+				return fmt.Errorf("invalid maxAttempts %q in retry policy", val)
+			}
+			policy.MaxAttempts = attempts
+		case "backoffBase", "backoffCap":
+			duration, err := time.ParseDuration(val)
+			if err != nil || duration <= 0 {
+				return fmt.Errorf("invalid %s %q in retry policy", key, val)
+			}
+			if key == "backoffBase" {
+				policy.BackoffBase = &metav1.Duration{Duration: duration}
+			} else {
+				policy.BackoffCap = &metav1.Duration{Duration: duration}
+			}
+		default:
+			return fmt.Errorf("unknown retry policy field %q", key)
+		}
+	}
+
+	*l = append(*l, policy)
+	return nil
+}
+
+// findRetryPolicy returns the policy for the error type from the list,
+// falling back to a policy without an error type.
+func findRetryPolicy(policies []metal3v1alpha1.RetryPolicy, errType metal3v1alpha1.ErrorType) *metal3v1alpha1.RetryPolicy {
+	var fallback *metal3v1alpha1.RetryPolicy
+	for i := range policies {
+		switch policies[i].ErrorType {
+		case errType:
+			return &policies[i]
+		case "":
+			fallback = &policies[i]
+		}
+	}
+	return fallback
+}
+
+// retryPolicyFor returns the retry policy that applies to the error
+// type for the host. Policies set on the host take precedence over the
+// operator-wide ones.
+func retryPolicyFor(host *metal3v1alpha1.BareMetalHost, defaults []metal3v1alpha1.RetryPolicy, errType metal3v1alpha1.ErrorType) (policy metal3v1alpha1.RetryPolicy) {
+	if p := findRetryPolicy(host.Spec.RetryPolicies, errType); p != nil {
+		policy = *p
+	} else if p := findRetryPolicy(defaults, errType); p != nil {
+		policy = *p
+	}
+
+	if policy.BackoffBase == nil {
+		policy.BackoffBase = &metav1.Duration{Duration: defaultBackoffBase}
+	}
+	if policy.BackoffCap == nil {
+		policy.BackoffCap = &metav1.Duration{Duration: defaultBackoffCap}
+	}
+	return
+}
+
+// retriesExhausted returns true if the host has failed more times than
+// the policy allows.
+func retriesExhausted(policy metal3v1alpha1.RetryPolicy, errorCount int) bool {
+	return policy.MaxAttempts > 0 && errorCount >= policy.MaxAttempts
+}
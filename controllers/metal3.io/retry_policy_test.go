@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+func TestRetryPolicyListSet(t *testing.T) {
+	testCases := []struct {
+		Scenario      string
+		Value         string
+		Expected      metal3v1alpha1.RetryPolicy
+		ExpectedError string
+	}{
+		{
+			Scenario: "all-fields",
+			Value:    "errorType=inspection error, maxAttempts=5,backoffBase=30s,backoffCap=1h",
+			Expected: metal3v1alpha1.RetryPolicy{
+				ErrorType:   metal3v1alpha1.InspectionError,
+				MaxAttempts: 5,
+				BackoffBase: &metav1.Duration{Duration: time.Second * 30},
+				BackoffCap:  &metav1.Duration{Duration: time.Hour},
+			},
+		},
+		{
+			Scenario: "default-policy",
+			Value:    "maxAttempts=10",
+			Expected: metal3v1alpha1.RetryPolicy{
+				MaxAttempts: 10,
+			},
+		},
+		{
+			Scenario:      "missing-value",
+			Value:         "maxAttempts",
+			ExpectedError: "invalid retry policy field \"maxAttempts\", expected key=value",
+		},
+		{
+			Scenario:      "negative-attempts",
+			Value:         "maxAttempts=-1",
+			ExpectedError: "invalid maxAttempts \"-1\" in retry policy",
+		},
+		{
+			Scenario:      "invalid-duration",
+			Value:         "backoffCap=later",
+			ExpectedError: "invalid backoffCap \"later\" in retry policy",
+		},
+		{
+			Scenario:      "unknown-field",
+			Value:         "attempts=3",
+			ExpectedError: "unknown retry policy field \"attempts\"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			var policies RetryPolicyList
+			err := policies.Set(tc.Value)
+			if tc.ExpectedError != "" {
+				assert.EqualError(t, err, tc.ExpectedError)
+				assert.Empty(t, policies)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, RetryPolicyList{tc.Expected}, policies)
+		})
+	}
+}
+
+func TestRetryPolicyFor(t *testing.T) {
+	defaults := []metal3v1alpha1.RetryPolicy{
+		{MaxAttempts: 10},
+		{ErrorType: metal3v1alpha1.InspectionError, MaxAttempts: 5},
+	}
+
+	host := host(metal3v1alpha1.StateInspecting).build()
+	assert.Equal(t, 5, retryPolicyFor(host, defaults, metal3v1alpha1.InspectionError).MaxAttempts)
+	assert.Equal(t, 10, retryPolicyFor(host, defaults, metal3v1alpha1.ProvisioningError).MaxAttempts)
+	assert.Equal(t, 0, retryPolicyFor(host, nil, metal3v1alpha1.ProvisioningError).MaxAttempts)
+
+	// Policies on the host take precedence
+	host.Spec.RetryPolicies = []metal3v1alpha1.RetryPolicy{
+		{MaxAttempts: 2, BackoffCap: &metav1.Duration{Duration: time.Minute * 10}},
+	}
+	policy := retryPolicyFor(host, defaults, metal3v1alpha1.InspectionError)
+	assert.Equal(t, 2, policy.MaxAttempts)
+	assert.Equal(t, defaultBackoffBase, policy.BackoffBase.Duration)
+	assert.Equal(t, time.Minute*10, policy.BackoffCap.Duration)
+}
+
+func TestRecordActionFailureRetryPolicy(t *testing.T) {
+	host := host(metal3v1alpha1.StateInspecting).build()
+	host.Spec.RetryPolicies = []metal3v1alpha1.RetryPolicy{
+		{ErrorType: metal3v1alpha1.InspectionError, MaxAttempts: 2},
+	}
+	info := makeDefaultReconcileInfo(host)
+
+	result := recordActionFailure(info, metal3v1alpha1.InspectionError, "first")
+	assert.False(t, result.needsOperator)
+	assert.Equal(t, metal3v1alpha1.OperationalStatusError, host.OperationalStatus())
+	res, _ := result.Result()
+	assert.NotZero(t, res.RequeueAfter)
+
+	result = recordActionFailure(info, metal3v1alpha1.InspectionError, "second")
+	assert.True(t, result.needsOperator)
+	assert.True(t, result.Dirty())
+	assert.Equal(t, metal3v1alpha1.OperationalStatusNeedsOperator, host.OperationalStatus())
+	res, _ = result.Result()
+	assert.Zero(t, res.RequeueAfter)
+	assert.False(t, res.Requeue)
+}
+
+func TestCheckNeedsOperator(t *testing.T) {
+	host := host(metal3v1alpha1.StateInspecting).
+		SetStatusError(metal3v1alpha1.OperationalStatusNeedsOperator, metal3v1alpha1.InspectionError, "boom", 5).
+		build()
+	prov := newMockProvisioner()
+	reconciler := testNewReconciler(host)
+	hsm := newHostStateMachine(host, reconciler, prov, true)
+
+	result := hsm.ReconcileState(makeDefaultReconcileInfo(host))
+	assert.False(t, result.Dirty())
+	assert.False(t, prov.calledNoError("InspectHardware"))
+	assert.Equal(t, metal3v1alpha1.OperationalStatusNeedsOperator, host.OperationalStatus())
+
+	// Ask for a retry
+	err := reconciler.Get(context.TODO(), client.ObjectKeyFromObject(host), host)
+	assert.NoError(t, err)
+	host.Annotations = map[string]string{retryAnnotation: ""}
+	assert.NoError(t, reconciler.Update(context.TODO(), host))
+
+	result = hsm.ReconcileState(makeDefaultReconcileInfo(host))
+	assert.True(t, result.Dirty())
+	assert.Equal(t, metal3v1alpha1.OperationalStatusError, host.OperationalStatus())
+	assert.Equal(t, metal3v1alpha1.InspectionError, host.Status.ErrorType)
+	assert.Equal(t, 0, host.Status.ErrorCount)
+	assert.NotContains(t, host.Annotations, retryAnnotation)
+}
@@ -16,28 +16,39 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"crypto/x509"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
 
+	"github.com/go-logr/logr"
 	"go.uber.org/zap/zapcore"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	metal3iov1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
 	metal3iocontroller "github.com/metal3-io/baremetal-operator/controllers/metal3.io"
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/imagecache"
 	"github.com/metal3-io/baremetal-operator/pkg/imageprovider"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner/demo"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner/fixture"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish"
 	"github.com/metal3-io/baremetal-operator/pkg/secretutils"
 	"github.com/metal3-io/baremetal-operator/pkg/version"
 	// +kubebuilder:scaffold:imports
@@ -75,10 +86,37 @@ func setupChecks(mgr ctrl.Manager) {
 	}
 }
 
-func setupWebhooks(mgr ctrl.Manager) {
-	var bmh webhook.Validator = &metal3iov1alpha1.BareMetalHost{}
+// loadCertPool reads a bundle of PEM-encoded CA certificates.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path) // #nosec
+	if err != nil {
+		return nil, err
+	}
+	certs, err := bmc.ParseCABundle(data)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
+
+func setupWebhooks(mgr ctrl.Manager, provisioners []string) {
+	apiReader := mgr.GetAPIReader()
+	getObject := func(ctx context.Context, namespace, name string, obj k8sruntime.Object) error {
+		return apiReader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, obj.(client.Object))
+	}
+	validator := &metal3iov1alpha1.BareMetalHostValidator{
+		Provisioners:                 provisioners,
+		TrustedCertificatesGetter:    getObject,
+		HostFirmwareComponentsGetter: getObject,
+	}
+
 	if err := ctrl.NewWebhookManagedBy(mgr).
-		For(bmh).
+		For(&metal3iov1alpha1.BareMetalHost{}).
+		WithValidator(validator).
 		Complete(); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "BareMetalHost")
 		os.Exit(1)
@@ -93,6 +131,46 @@ func setupWebhooks(mgr ctrl.Manager) {
 	}
 }
 
+func newProvisionerFactory(name string, provLog logr.Logger, preprovImgEnable bool) (provisioner.Factory, error) {
+	switch name {
+	case "ironic":
+		return ironic.NewProvisionerFactory(provLog, preprovImgEnable), nil
+	case "redfish":
+		return redfish.NewProvisionerFactory(provLog), nil
+	case "fixture":
+		return &fixture.Fixture{}, nil
+	case "demo":
+		return &demo.Demo{}, nil
+	}
+	return nil, fmt.Errorf("unknown provisioner %q", name)
+}
+
+func setupProvisioners(defaultName string, extraNames string, provLog logr.Logger, preprovImgEnable bool) (*provisioner.Registry, error) {
+	registry := provisioner.NewRegistry()
+	names := []string{defaultName}
+	if extraNames != "" {
+		names = append(names, strings.Split(extraNames, ",")...)
+	}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, err := registry.Get(name); err == nil {
+			// already registered
+			continue
+		}
+		factory, err := newProvisionerFactory(name, provLog, preprovImgEnable)
+		if err != nil {
+			return nil, err
+		}
+		if err := registry.Register(name, factory); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
 func main() {
 	var watchNamespace string
 	var metricsBindAddr string
@@ -101,9 +179,30 @@ func main() {
 	var devLogging bool
 	var runInTestMode bool
 	var runInDemoMode bool
+	var runInRedfishMode bool
+	var extraProvisioners string
 	var webhookPort int
 	var restConfigQPS float64
 	var restConfigBurst int
+	var inspectTimeout time.Duration
+	var prepareTimeout time.Duration
+	var provisionTimeout time.Duration
+	var deprovisionTimeout time.Duration
+	var retryPolicies metal3iocontroller.RetryPolicyList
+	credentialsProviders := secretutils.CredentialsProviders{}
+	var discoveryNetworks metal3iocontroller.DiscoveryNetworkList
+	var discoveryNamespace string
+	var discoveryInterval time.Duration
+	var discoveryPort int
+	var discoveryHTTP bool
+	var discoveryCredentials string
+	var discoveryCABundle string
+	var eventReceiverAddr string
+	var eventReceiverCertDir string
+	var eventReceiverKeyFile string
+	var imageCacheDir string
+	var imageCacheAddr string
+	var imageCacheURL string
 
 	// From CAPI point of view, BMO should be able to watch all namespaces
 	// in case of a deployment that is not multi-tenant. If the deployment
@@ -121,6 +220,11 @@ func main() {
 	flag.BoolVar(&runInTestMode, "test-mode", false, "disable ironic communication")
 	flag.BoolVar(&runInDemoMode, "demo-mode", false,
 		"use the demo provisioner to set host states")
+	flag.BoolVar(&runInRedfishMode, "redfish-mode", false,
+		"manage hosts directly through their Redfish BMC instead of using ironic")
+	flag.StringVar(&extraProvisioners, "provisioners", "",
+		"Comma-separated list of additional provisioners (ironic, redfish, fixture, demo) "+
+			"that hosts may select besides the default one")
 	flag.StringVar(&healthAddr, "health-addr", ":9440",
 		"The address the health endpoint binds to.")
 	flag.IntVar(&webhookPort, "webhook-port", 9443,
@@ -129,6 +233,48 @@ func main() {
 		"Maximum queries per second from the controller client to the Kubernetes API server. Default 20")
 	flag.IntVar(&restConfigBurst, "kube-api-burst", 30,
 		"Maximum number of queries that should be allowed in one burst from the controller client to the Kubernetes API server. Default 30")
+	flag.DurationVar(&inspectTimeout, "inspect-timeout", 0,
+		"Maximum time a host may spend inspecting before it is marked as failed (0 to disable)")
+	flag.DurationVar(&prepareTimeout, "prepare-timeout", 0,
+		"Maximum time a host may spend preparing before it is marked as failed (0 to disable)")
+	flag.DurationVar(&provisionTimeout, "provision-timeout", 0,
+		"Maximum time a host may spend provisioning before it is marked as failed (0 to disable)")
+	flag.DurationVar(&deprovisionTimeout, "deprovision-timeout", 0,
+		"Maximum time a host may spend deprovisioning before it is marked as failed (0 to disable)")
+	flag.Var(&retryPolicies, "retry-policy",
+		"Policy for retrying failed operations, as comma-separated key=value pairs for errorType, maxAttempts, backoffBase "+
+			"and backoffCap. May be repeated, once per error type.")
+	flag.Var(credentialsProviders, "credentials-provider",
+		"Provider of BMC credentials that hosts may select instead of a Secret, as name=URL with a file:// URL "+
+			"for a directory of credential files or an http(s):// URL for a credentials service, optionally "+
+			"followed by ,tokenFile=PATH. May be repeated.")
+	flag.Var(&discoveryNetworks, "bmc-discovery-network",
+		"Management network, in CIDR notation, to scan for Redfish BMCs. Hosts without credentials are created "+
+			"for the BMCs found. May be repeated. Discovery is disabled when not set.")
+	flag.StringVar(&discoveryNamespace, "bmc-discovery-namespace", "",
+		"Namespace of the hosts created by BMC discovery. Defaults to the watched namespace.")
+	flag.DurationVar(&discoveryInterval, "bmc-discovery-interval", time.Hour,
+		"Interval between scans of the BMC discovery networks")
+	flag.IntVar(&discoveryPort, "bmc-discovery-port", 443,
+		"Port of the Redfish service of the discovered BMCs")
+	flag.BoolVar(&discoveryHTTP, "bmc-discovery-http", false,
+		"Use HTTP instead of HTTPS to discover BMCs, such as Redfish emulators")
+	flag.StringVar(&discoveryCredentials, "bmc-discovery-credentials", "",
+		"Name of a Secret in the discovery namespace holding credentials used to read the systems of the discovered BMCs")
+	flag.StringVar(&discoveryCABundle, "bmc-discovery-ca-bundle", "",
+		"File holding the PEM-encoded CA certificates verifying the discovered BMCs, instead of the system trust store")
+	flag.StringVar(&eventReceiverAddr, "bmc-event-receiver-addr", "",
+		"The address the receiver of the events of BMC event subscriptions binds to. The receiver is disabled when not set.")
+	flag.StringVar(&eventReceiverCertDir, "bmc-event-receiver-cert-dir", "",
+		"Directory holding the tls.crt and tls.key files served by the BMC event receiver. It serves plain HTTP when not set.")
+	flag.StringVar(&eventReceiverKeyFile, "bmc-event-receiver-key-file", "",
+		"File holding the key deriving the token the BMC of each host sends with its events. Required by the BMC event receiver.")
+	flag.StringVar(&imageCacheDir, "image-cache-dir", "",
+		"Directory the images deployed to the hosts are cached in. The image cache is disabled when not set.")
+	flag.StringVar(&imageCacheAddr, "image-cache-addr", ":8090",
+		"The address the image cache serves the cached images on")
+	flag.StringVar(&imageCacheURL, "image-cache-url", "",
+		"The URL the hosts reach the image cache at, such as http://192.168.111.2:8090")
 	flag.Parse()
 
 	logOpts := zap.Options{}
@@ -169,16 +315,74 @@ func main() {
 		os.Exit(1)
 	}
 
-	var provisionerFactory provisioner.Factory
+	defaultProvisioner := "ironic"
 	if runInTestMode {
-		ctrl.Log.Info("using test provisioner")
-		provisionerFactory = &fixture.Fixture{}
+		defaultProvisioner = "fixture"
 	} else if runInDemoMode {
-		ctrl.Log.Info("using demo provisioner")
-		provisionerFactory = &demo.Demo{}
-	} else {
-		provLog := zap.New(zap.UseFlagOptions(&logOpts)).WithName("provisioner")
-		provisionerFactory = ironic.NewProvisionerFactory(provLog, preprovImgEnable)
+		defaultProvisioner = "demo"
+	} else if runInRedfishMode {
+		defaultProvisioner = "redfish"
+	}
+	provLog := zap.New(zap.UseFlagOptions(&logOpts)).WithName("provisioner")
+	provisionerFactory, err := setupProvisioners(defaultProvisioner, extraProvisioners, provLog, preprovImgEnable)
+	if err != nil {
+		setupLog.Error(err, "unable to set up provisioners")
+		os.Exit(1)
+	}
+	ctrl.Log.Info("using provisioners", "default", provisionerFactory.Default(), "available", provisionerFactory.Names())
+
+	var bmcEvents chan event.GenericEvent
+	var eventReceiverKey []byte
+	if eventReceiverAddr != "" {
+		if eventReceiverKeyFile == "" {
+			setupLog.Error(nil, "--bmc-event-receiver-key-file is required by the BMC event receiver")
+			os.Exit(1)
+		}
+		key, err := os.ReadFile(eventReceiverKeyFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read the BMC event receiver key")
+			os.Exit(1)
+		}
+		eventReceiverKey = []byte(strings.TrimSpace(string(key)))
+		if len(eventReceiverKey) < 32 {
+			setupLog.Error(nil, "the BMC event receiver key must hold at least 32 characters")
+			os.Exit(1)
+		}
+
+		bmcEvents = make(chan event.GenericEvent, 100)
+		receiver := &metal3iocontroller.BMCEventReceiver{
+			Client: mgr.GetClient(),
+			Log:    ctrl.Log.WithName("controllers").WithName("BMCEventReceiver"),
+			Addr:   eventReceiverAddr,
+			Key:    eventReceiverKey,
+			Events: bmcEvents,
+		}
+		if eventReceiverCertDir != "" {
+			receiver.CertFile = filepath.Join(eventReceiverCertDir, "tls.crt")
+			receiver.KeyFile = filepath.Join(eventReceiverCertDir, "tls.key")
+		}
+		if err = mgr.Add(receiver); err != nil {
+			setupLog.Error(err, "unable to set up the BMC event receiver")
+			os.Exit(1)
+		}
+	}
+
+	var imageCache *imagecache.Cache
+	if imageCacheDir != "" {
+		if imageCacheURL == "" {
+			setupLog.Error(nil, "--image-cache-url is required when the image cache is enabled")
+			os.Exit(1)
+		}
+		imageCache, err = imagecache.NewCache(imageCacheDir, imageCacheAddr, imageCacheURL,
+			mgr.GetClient(), ctrl.Log.WithName("image-cache"))
+		if err != nil {
+			setupLog.Error(err, "unable to set up the image cache")
+			os.Exit(1)
+		}
+		if err = mgr.Add(imageCache); err != nil {
+			setupLog.Error(err, "unable to set up the image cache")
+			os.Exit(1)
+		}
 	}
 
 	if err = (&metal3iocontroller.BareMetalHostReconciler{
@@ -186,6 +390,16 @@ func main() {
 		Log:                ctrl.Log.WithName("controllers").WithName("BareMetalHost"),
 		ProvisionerFactory: provisionerFactory,
 		APIReader:          mgr.GetAPIReader(),
+		StateTimeouts: map[metal3iov1alpha1.ProvisioningState]time.Duration{
+			metal3iov1alpha1.StateInspecting:     inspectTimeout,
+			metal3iov1alpha1.StatePreparing:      prepareTimeout,
+			metal3iov1alpha1.StateProvisioning:   provisionTimeout,
+			metal3iov1alpha1.StateDeprovisioning: deprovisionTimeout,
+		},
+		RetryPolicies:        retryPolicies,
+		CredentialsProviders: credentialsProviders,
+		BMCEvents:            bmcEvents,
+		ImageCache:           imageCache,
 	}).SetupWithManager(mgr, preprovImgEnable); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "BareMetalHost")
 		os.Exit(1)
@@ -212,24 +426,88 @@ func main() {
 		Client:             mgr.GetClient(),
 		Log:                ctrl.Log.WithName("controllers").WithName("HostFirmwareSettings"),
 		ProvisionerFactory: provisionerFactory,
+		APIReader:          mgr.GetAPIReader(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "HostFirmwareSettings")
 		os.Exit(1)
 	}
 
+	if err = (&metal3iocontroller.HostFirmwareComponentsReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("HostFirmwareComponents"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "HostFirmwareComponents")
+		os.Exit(1)
+	}
+
+	if err = (&metal3iocontroller.BareMetalHostClaimReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("BareMetalHostClaim"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BareMetalHostClaim")
+		os.Exit(1)
+	}
+
+	if err = (&metal3iocontroller.IPPoolReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("IPPool"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "IPPool")
+		os.Exit(1)
+	}
+
 	if err = (&metal3iocontroller.BMCEventSubscriptionReconciler{
 		Client:             mgr.GetClient(),
 		Log:                ctrl.Log.WithName("controllers").WithName("BMCEventSubscription"),
 		ProvisionerFactory: provisionerFactory,
+		APIReader:          mgr.GetAPIReader(),
+		EventReceiverKey:   eventReceiverKey,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "BMCEventSubscription")
 		os.Exit(1)
 	}
 
+	if len(discoveryNetworks) != 0 {
+		if discoveryNamespace == "" {
+			discoveryNamespace = watchNamespace
+		}
+		if discoveryNamespace == "" {
+			setupLog.Error(nil, "--bmc-discovery-namespace is required when watching all namespaces")
+			os.Exit(1)
+		}
+		if discoveryHTTP && discoveryCredentials != "" {
+			setupLog.Error(nil, "--bmc-discovery-credentials can not be sent over --bmc-discovery-http")
+			os.Exit(1)
+		}
+		var discoveryCACertificates *x509.CertPool
+		if discoveryCABundle != "" {
+			discoveryCACertificates, err = loadCertPool(discoveryCABundle)
+			if err != nil {
+				setupLog.Error(err, "unable to read the BMC discovery CA bundle")
+				os.Exit(1)
+			}
+		}
+		if err = mgr.Add(&metal3iocontroller.BMCDiscoverer{
+			Client:          mgr.GetClient(),
+			Log:             ctrl.Log.WithName("controllers").WithName("BMCDiscovery"),
+			APIReader:       mgr.GetAPIReader(),
+			Namespace:       discoveryNamespace,
+			Networks:        discoveryNetworks,
+			Port:            discoveryPort,
+			HTTP:            discoveryHTTP,
+			CACertificates:  discoveryCACertificates,
+			Interval:        discoveryInterval,
+			CredentialsName: discoveryCredentials,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up BMC discovery")
+			os.Exit(1)
+		}
+	}
+
 	setupChecks(mgr)
 
 	if enableWebhook {
-		setupWebhooks(mgr)
+		setupWebhooks(mgr, provisionerFactory.Names())
 	}
 
 	setupLog.Info("starting manager")
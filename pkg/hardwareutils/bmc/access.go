@@ -40,6 +40,19 @@ type FirmwareConfig struct {
 	SriovEnabled *bool
 }
 
+// FirmwareUpdate describes a firmware image to flash onto a component
+// of the host.
+type FirmwareUpdate struct {
+	// The component to update, such as bios, bmc or nic:<id>.
+	Component string
+
+	// The location of the firmware image.
+	URL string
+
+	// The checksum of the firmware image, if known.
+	Checksum string
+}
+
 // AccessDetails contains the information about how to get to a BMC.
 //
 // NOTE(dhellmann): This structure is very likely to change as we
@@ -74,7 +87,8 @@ type AccessDetails interface {
 	RAIDInterface() string
 	VendorInterface() string
 
-	// Whether the driver supports changing secure boot state.
+	// Whether the driver supports changing secure boot state, as
+	// reported by Capabilities().SecureBoot.
 	SupportsSecureBoot() bool
 
 	// Whether the driver supports booting a preprovisioning image in ISO format
@@ -85,6 +99,17 @@ type AccessDetails interface {
 
 	// Build bios clean steps for ironic
 	BuildBIOSSettings(firmwareConfig *FirmwareConfig) (settings []map[string]string, err error)
+
+	// Whether the driver supports updating the firmware of the host's
+	// components, as reported by Capabilities().FirmwareUpdate.
+	SupportsFirmwareUpdate() bool
+
+	// Build the firmware images for the ironic update_firmware clean step
+	BuildFirmwareUpdates(updates []FirmwareUpdate) (images []map[string]string, err error)
+
+	// Capabilities returns the features of the host the driver can
+	// manage.
+	Capabilities() Capabilities
 }
 
 func GetParsedURL(address string) (parsedURL *url.URL, err error) {
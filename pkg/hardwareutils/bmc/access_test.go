@@ -398,6 +398,46 @@ func TestParse(t *testing.T) {
 			Hostname: "192.168.122.1",
 			Path:     "",
 		},
+
+		{
+			Scenario: "supermicro url path",
+			Address:  "supermicro-redfish://192.168.122.1/redfish/v1/Systems/1",
+			Type:     "supermicro-redfish",
+			Port:     "",
+			Host:     "192.168.122.1",
+			Hostname: "192.168.122.1",
+			Path:     "/redfish/v1/Systems/1",
+		},
+
+		{
+			Scenario: "supermicro virtual media url ipv6",
+			Address:  "supermicro-virtualmedia+https://[fe80::fc33:62ff:fe83:8a76]:8443/redfish/v1/Systems/1",
+			Type:     "supermicro-virtualmedia+https",
+			Port:     "8443",
+			Host:     "fe80::fc33:62ff:fe83:8a76",
+			Hostname: "[fe80::fc33:62ff:fe83:8a76]:8443",
+			Path:     "/redfish/v1/Systems/1",
+		},
+
+		{
+			Scenario: "lenovo url path",
+			Address:  "lenovo-redfish://192.168.122.1/redfish/v1/Systems/1",
+			Type:     "lenovo-redfish",
+			Port:     "",
+			Host:     "192.168.122.1",
+			Hostname: "192.168.122.1",
+			Path:     "/redfish/v1/Systems/1",
+		},
+
+		{
+			Scenario: "lenovo virtual media url, no sep",
+			Address:  "lenovo-virtualmedia:192.168.122.1",
+			Type:     "lenovo-virtualmedia",
+			Port:     "",
+			Host:     "192.168.122.1",
+			Hostname: "192.168.122.1",
+			Path:     "",
+		},
 	} {
 		t.Run(tc.Scenario, func(t *testing.T) {
 			url, err := GetParsedURL(tc.Address)
@@ -631,6 +671,60 @@ func TestStaticDriverInfo(t *testing.T) {
 			vendor:     "idrac-redfish",
 		},
 
+		{
+			Scenario: "supermicro redfish",
+			input:    "supermicro-redfish://192.168.122.1",
+			needsMac: true,
+			driver:   "redfish",
+			bios:     "redfish",
+			boot:     "ipxe",
+		},
+
+		{
+			Scenario: "supermicro virtual media",
+			input:    "supermicro-virtualmedia://192.168.122.1",
+			needsMac: true,
+			driver:   "redfish",
+			bios:     "redfish",
+			boot:     "redfish-virtual-media",
+		},
+
+		{
+			Scenario: "supermicro virtual media HTTP",
+			input:    "supermicro-virtualmedia+http://192.168.122.1",
+			needsMac: true,
+			driver:   "redfish",
+			bios:     "redfish",
+			boot:     "redfish-virtual-media",
+		},
+
+		{
+			Scenario: "lenovo redfish",
+			input:    "lenovo-redfish://192.168.122.1",
+			needsMac: true,
+			driver:   "redfish",
+			bios:     "redfish",
+			boot:     "ipxe",
+		},
+
+		{
+			Scenario: "lenovo virtual media",
+			input:    "lenovo-virtualmedia://192.168.122.1",
+			needsMac: true,
+			driver:   "redfish",
+			bios:     "redfish",
+			boot:     "redfish-virtual-media",
+		},
+
+		{
+			Scenario: "lenovo virtual media HTTPS",
+			input:    "lenovo-virtualmedia+https://192.168.122.1",
+			needsMac: true,
+			driver:   "redfish",
+			bios:     "redfish",
+			boot:     "redfish-virtual-media",
+		},
+
 		{
 			Scenario:   "ibmc",
 			input:      "ibmc://192.168.122.1:6233",
@@ -985,6 +1079,54 @@ func TestDriverInfo(t *testing.T) {
 			},
 		},
 
+		{
+			Scenario: "supermicro redfish",
+			input:    "supermicro-redfish://192.168.122.1/foo/bar",
+			expects: map[string]interface{}{
+				"redfish_address":   "https://192.168.122.1",
+				"redfish_system_id": "/foo/bar",
+				"redfish_password":  "",
+				"redfish_username":  "",
+				"redfish_verify_ca": false,
+			},
+		},
+
+		{
+			Scenario: "supermicro virtual media http",
+			input:    "supermicro-virtualmedia+http://192.168.122.1/foo/bar",
+			expects: map[string]interface{}{
+				"redfish_address":   "http://192.168.122.1",
+				"redfish_system_id": "/foo/bar",
+				"redfish_password":  "",
+				"redfish_username":  "",
+				"redfish_verify_ca": false,
+			},
+		},
+
+		{
+			Scenario: "lenovo redfish",
+			input:    "lenovo-redfish://192.168.122.1/foo/bar",
+			expects: map[string]interface{}{
+				"redfish_address":   "https://192.168.122.1",
+				"redfish_system_id": "/foo/bar",
+				"redfish_password":  "",
+				"redfish_username":  "",
+				"redfish_verify_ca": false,
+			},
+		},
+
+		{
+			Scenario: "lenovo virtual media",
+			input:    "lenovo-virtualmedia://192.168.122.1/foo/bar",
+			expects: map[string]interface{}{
+				"redfish_address":   "https://192.168.122.1",
+				"redfish_system_id": "/foo/bar",
+				"redfish_password":  "",
+				"redfish_username":  "",
+				"redfish_verify_ca": false,
+			},
+		},
+
 		// ibmc driver testcases
 		{
 			Scenario: "ibmc",
@@ -1288,6 +1430,114 @@ func TestBuildBIOSCleanSteps(t *testing.T) {
 			firmware: &FirmwareConfig{},
 			expected: nil,
 		},
+		// supermicro
+		{
+			name:    "supermicro-redfish",
+			address: "supermicro-redfish://192.168.122.1",
+			firmware: &FirmwareConfig{
+				VirtualizationEnabled:             &True,
+				SimultaneousMultithreadingEnabled: &False,
+				SriovEnabled:                      &True,
+			},
+			expected: []map[string]string{
+				{
+					"name":  "IntelVirtualizationTechnology",
+					"value": "Enable",
+				},
+				{
+					"name":  "HyperThreading",
+					"value": "Disable",
+				},
+				{
+					"name":  "SR_IOVSupport",
+					"value": "Enable",
+				},
+			},
+		},
+		{
+			name:    "supermicro-virtualmedia",
+			address: "supermicro-virtualmedia://192.168.122.1",
+			firmware: &FirmwareConfig{
+				SriovEnabled: &False,
+			},
+			expected: []map[string]string{
+				{
+					"name":  "SR_IOVSupport",
+					"value": "Disable",
+				},
+			},
+		},
+		{
+			name:     "supermicro-redfish, firmware is nil",
+			address:  "supermicro-redfish://192.168.122.1",
+			firmware: nil,
+			expected: nil,
+		},
+		{
+			name:     "supermicro-virtualmedia, firmware is empty",
+			address:  "supermicro-virtualmedia://192.168.122.1",
+			firmware: &FirmwareConfig{},
+			expected: nil,
+		},
+		// lenovo
+		{
+			name:    "lenovo-redfish",
+			address: "lenovo-redfish://192.168.122.1",
+			firmware: &FirmwareConfig{
+				VirtualizationEnabled:             &False,
+				SimultaneousMultithreadingEnabled: &True,
+				SriovEnabled:                      &True,
+			},
+			expected: []map[string]string{
+				{
+					"name":  "Processors_IntelVirtualizationTechnology",
+					"value": "Disable",
+				},
+				{
+					"name":  "Processors_HyperThreading",
+					"value": "Enable",
+				},
+				{
+					"name":  "DevicesandIOPorts_SRIOV",
+					"value": "Enable",
+				},
+			},
+		},
+		{
+			name:    "lenovo-virtualmedia",
+			address: "lenovo-virtualmedia://192.168.122.1",
+			firmware: &FirmwareConfig{
+				VirtualizationEnabled: &True,
+			},
+			expected: []map[string]string{
+				{
+					"name":  "Processors_IntelVirtualizationTechnology",
+					"value": "Enable",
+				},
+			},
+		},
+		{
+			name:     "lenovo-redfish, firmware is nil",
+			address:  "lenovo-redfish://192.168.122.1",
+			firmware: nil,
+			expected: nil,
+		},
+		{
+			name:     "lenovo-virtualmedia, firmware is empty",
+			address:  "lenovo-virtualmedia://192.168.122.1",
+			firmware: &FirmwareConfig{},
+			expected: nil,
+		},
+		// redfish
+		{
+			name:    "redfish, firmware settings are not supported",
+			address: "redfish://192.168.122.1",
+			firmware: &FirmwareConfig{
+				VirtualizationEnabled: &True,
+			},
+			expected:      nil,
+			expectedError: true,
+		},
 	}
 
 	for _, c := range cases {
@@ -1308,3 +1558,182 @@ func TestBuildBIOSCleanSteps(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildFirmwareUpdates(t *testing.T) {
+	updates := []FirmwareUpdate{
+		{
+			Component: "bios",
+			URL:       "http://example.com/bios.bin",
+			Checksum:  "sha256:abcd",
+		},
+		{
+			Component: "bmc",
+			URL:       "http://example.com/bmc.bin",
+		},
+	}
+	images := []map[string]string{
+		{
+			"url":      "http://example.com/bios.bin",
+			"checksum": "sha256:abcd",
+		},
+		{
+			"url": "http://example.com/bmc.bin",
+		},
+	}
+
+	cases := []struct {
+		name          string
+		address       string
+		updates       []FirmwareUpdate
+		supported     bool
+		expected      []map[string]string
+		expectedError bool
+	}{
+		{
+			name:      "redfish",
+			address:   "redfish://192.168.122.1",
+			updates:   updates,
+			supported: true,
+			expected:  images,
+		},
+		{
+			name:      "redfish-virtualmedia",
+			address:   "redfish-virtualmedia://192.168.122.1",
+			updates:   updates,
+			supported: true,
+			expected:  images,
+		},
+		{
+			name:      "idrac-redfish",
+			address:   "idrac-redfish://192.168.122.1",
+			updates:   updates,
+			supported: true,
+			expected:  images,
+		},
+		{
+			name:      "idrac-virtualmedia",
+			address:   "idrac-virtualmedia://192.168.122.1",
+			updates:   updates,
+			supported: true,
+			expected:  images,
+		},
+		{
+			name:      "supermicro-redfish",
+			address:   "supermicro-redfish://192.168.122.1",
+			updates:   updates,
+			supported: true,
+			expected:  images,
+		},
+		{
+			name:      "lenovo-virtualmedia",
+			address:   "lenovo-virtualmedia://192.168.122.1",
+			updates:   updates,
+			supported: true,
+			expected:  images,
+		},
+		{
+			name:      "redfish, no updates",
+			address:   "redfish://192.168.122.1",
+			updates:   nil,
+			supported: true,
+			expected:  nil,
+		},
+		{
+			name:          "ipmi",
+			address:       "ipmi://192.168.122.1",
+			updates:       updates,
+			expectedError: true,
+		},
+		{
+			name:     "ipmi, no updates",
+			address:  "ipmi://192.168.122.1",
+			updates:  nil,
+			expected: nil,
+		},
+		{
+			name:          "ilo5",
+			address:       "ilo5://192.168.122.1",
+			updates:       updates,
+			expectedError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			acc, err := NewAccessDetails(c.address, false)
+			if err != nil {
+				t.Fatalf("new AccessDetails failed: %v", err)
+			}
+
+			if acc.SupportsFirmwareUpdate() != c.supported {
+				t.Errorf("expected firmware update support: %v, got: %v", c.supported, acc.SupportsFirmwareUpdate())
+			}
+
+			images, err := acc.BuildFirmwareUpdates(c.updates)
+			if (err != nil) != c.expectedError {
+				t.Fatalf("got unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(c.expected, images) {
+				t.Errorf("expected images: %v, got: %v", c.expected, images)
+			}
+		})
+	}
+}
+
+func TestVendorRedfishCapabilities(t *testing.T) {
+	for _, tc := range []struct {
+		Scenario        string
+		input           string
+		secureBoot      bool
+		isoImage        bool
+		provisioningNet bool
+	}{
+		{
+			Scenario:        "supermicro redfish",
+			input:           "supermicro-redfish://192.168.122.1",
+			secureBoot:      false,
+			isoImage:        false,
+			provisioningNet: true,
+		},
+		{
+			Scenario:        "supermicro virtual media",
+			input:           "supermicro-virtualmedia://192.168.122.1",
+			secureBoot:      false,
+			isoImage:        true,
+			provisioningNet: false,
+		},
+		{
+			Scenario:        "lenovo redfish",
+			input:           "lenovo-redfish://192.168.122.1",
+			secureBoot:      true,
+			isoImage:        false,
+			provisioningNet: true,
+		},
+		{
+			Scenario:        "lenovo virtual media",
+			input:           "lenovo-virtualmedia://192.168.122.1",
+			secureBoot:      true,
+			isoImage:        true,
+			provisioningNet: false,
+		},
+	} {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			acc, err := NewAccessDetails(tc.input, false)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if acc.SupportsSecureBoot() != tc.secureBoot {
+				t.Errorf("secure boot support: %v, expected %v", acc.SupportsSecureBoot(), tc.secureBoot)
+			}
+			if acc.SupportsISOPreprovisioningImage() != tc.isoImage {
+				t.Errorf("ISO preprovisioning image support: %v, expected %v",
+					acc.SupportsISOPreprovisioningImage(), tc.isoImage)
+			}
+			if acc.RequiresProvisioningNetwork() != tc.provisioningNet {
+				t.Errorf("requires provisioning network: %v, expected %v",
+					acc.RequiresProvisioningNetwork(), tc.provisioningNet)
+			}
+		})
+	}
+}
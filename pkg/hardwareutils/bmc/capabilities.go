@@ -0,0 +1,64 @@
+package bmc
+
+// Capability is a feature of the host that a BMC driver can manage.
+type Capability string
+
+const (
+	// CapabilityVirtualMedia is booting the host from images attached
+	// as virtual media, without a provisioning network.
+	CapabilityVirtualMedia Capability = "VirtualMedia"
+
+	// CapabilitySecureBoot is changing the UEFI secure boot state.
+	CapabilitySecureBoot Capability = "SecureBoot"
+
+	// CapabilityHardwareRAID is configuring the RAID controllers of
+	// the host.
+	CapabilityHardwareRAID Capability = "HardwareRAID"
+
+	// CapabilityBIOSSettings is applying BIOS settings.
+	CapabilityBIOSSettings Capability = "BIOSSettings"
+
+	// CapabilityFirmwareUpdate is updating the firmware of the
+	// components of the host.
+	CapabilityFirmwareUpdate Capability = "FirmwareUpdate"
+
+	// CapabilityEventSubscriptions is subscribing to the events of
+	// the BMC.
+	CapabilityEventSubscriptions Capability = "EventSubscriptions"
+
+	// CapabilitySoftPowerOff is shutting the host down gracefully.
+	CapabilitySoftPowerOff Capability = "SoftPowerOff"
+)
+
+// Capabilities is the set of features of the host that a BMC driver
+// can manage.
+type Capabilities struct {
+	VirtualMedia       bool
+	SecureBoot         bool
+	HardwareRAID       bool
+	BIOSSettings       bool
+	FirmwareUpdate     bool
+	EventSubscriptions bool
+	SoftPowerOff       bool
+}
+
+// List returns the capabilities in the set, in a stable order.
+func (c Capabilities) List() (capabilities []Capability) {
+	for _, capability := range []struct {
+		name    Capability
+		enabled bool
+	}{
+		{CapabilityVirtualMedia, c.VirtualMedia},
+		{CapabilitySecureBoot, c.SecureBoot},
+		{CapabilityHardwareRAID, c.HardwareRAID},
+		{CapabilityBIOSSettings, c.BIOSSettings},
+		{CapabilityFirmwareUpdate, c.FirmwareUpdate},
+		{CapabilityEventSubscriptions, c.EventSubscriptions},
+		{CapabilitySoftPowerOff, c.SoftPowerOff},
+	} {
+		if capability.enabled {
+			capabilities = append(capabilities, capability.name)
+		}
+	}
+	return
+}
@@ -0,0 +1,159 @@
+package bmc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	for _, tc := range []struct {
+		Scenario string
+		input    string
+		expected []Capability
+	}{
+		{
+			Scenario: "ipmi",
+			input:    "ipmi://192.168.122.1",
+			expected: []Capability{CapabilitySoftPowerOff},
+		},
+		{
+			Scenario: "libvirt",
+			input:    "libvirt://192.168.122.1",
+			expected: []Capability{CapabilitySoftPowerOff},
+		},
+		{
+			Scenario: "redfish",
+			input:    "redfish://192.168.122.1/redfish/v1/Systems/1",
+			expected: []Capability{
+				CapabilitySecureBoot, CapabilityFirmwareUpdate,
+				CapabilityEventSubscriptions, CapabilitySoftPowerOff,
+			},
+		},
+		{
+			Scenario: "redfish virtual media",
+			input:    "redfish-virtualmedia+http://192.168.122.1/redfish/v1/Systems/1",
+			expected: []Capability{
+				CapabilityVirtualMedia, CapabilitySecureBoot, CapabilityFirmwareUpdate,
+				CapabilityEventSubscriptions, CapabilitySoftPowerOff,
+			},
+		},
+		{
+			Scenario: "idrac",
+			input:    "idrac://192.168.122.1",
+			expected: []Capability{CapabilityHardwareRAID, CapabilityBIOSSettings},
+		},
+		{
+			Scenario: "idrac redfish",
+			input:    "idrac-redfish://192.168.122.1/redfish/v1/Systems/1",
+			expected: []Capability{
+				CapabilitySecureBoot, CapabilityHardwareRAID, CapabilityFirmwareUpdate,
+				CapabilityEventSubscriptions, CapabilitySoftPowerOff,
+			},
+		},
+		{
+			Scenario: "idrac virtual media",
+			input:    "idrac-virtualmedia://192.168.122.1/redfish/v1/Systems/1",
+			expected: []Capability{
+				CapabilityVirtualMedia, CapabilitySecureBoot, CapabilityHardwareRAID,
+				CapabilityFirmwareUpdate, CapabilityEventSubscriptions, CapabilitySoftPowerOff,
+			},
+		},
+		{
+			Scenario: "ilo4",
+			input:    "ilo4://192.168.122.1",
+			expected: []Capability{CapabilitySecureBoot, CapabilityBIOSSettings, CapabilitySoftPowerOff},
+		},
+		{
+			Scenario: "ilo4 virtual media",
+			input:    "ilo4-virtualmedia://192.168.122.1",
+			expected: []Capability{
+				CapabilityVirtualMedia, CapabilitySecureBoot, CapabilityBIOSSettings,
+				CapabilitySoftPowerOff,
+			},
+		},
+		{
+			Scenario: "ilo5",
+			input:    "ilo5://192.168.122.1",
+			expected: []Capability{
+				CapabilitySecureBoot, CapabilityHardwareRAID, CapabilityBIOSSettings,
+				CapabilitySoftPowerOff,
+			},
+		},
+		{
+			Scenario: "irmc",
+			input:    "irmc://192.168.122.1",
+			expected: []Capability{
+				CapabilitySecureBoot, CapabilityHardwareRAID, CapabilityBIOSSettings,
+				CapabilitySoftPowerOff,
+			},
+		},
+		{
+			Scenario: "ibmc",
+			input:    "ibmc://192.168.122.1",
+			expected: []Capability{CapabilitySoftPowerOff},
+		},
+		{
+			Scenario: "supermicro virtual media",
+			input:    "supermicro-virtualmedia://192.168.122.1/redfish/v1/Systems/1",
+			expected: []Capability{
+				CapabilityVirtualMedia, CapabilityBIOSSettings, CapabilityFirmwareUpdate,
+				CapabilityEventSubscriptions, CapabilitySoftPowerOff,
+			},
+		},
+		{
+			Scenario: "lenovo redfish",
+			input:    "lenovo-redfish://192.168.122.1/redfish/v1/Systems/1",
+			expected: []Capability{
+				CapabilitySecureBoot, CapabilityBIOSSettings, CapabilityFirmwareUpdate,
+				CapabilityEventSubscriptions, CapabilitySoftPowerOff,
+			},
+		},
+	} {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			acc, err := NewAccessDetails(tc.input, false)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if capabilities := acc.Capabilities().List(); !reflect.DeepEqual(capabilities, tc.expected) {
+				t.Errorf("expected capabilities %v, got %v", tc.expected, capabilities)
+			}
+		})
+	}
+}
+
+// TestCapabilitiesMatchDrivers checks that the capabilities of every
+// registered driver agree with the rest of its AccessDetails.
+func TestCapabilitiesMatchDrivers(t *testing.T) {
+	enabled := true
+	for name := range factories {
+		t.Run(name, func(t *testing.T) {
+			acc, err := NewAccessDetails(name+"://192.168.122.1/redfish/v1/Systems/1", false)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			capabilities := acc.Capabilities()
+
+			if capabilities.VirtualMedia != acc.SupportsISOPreprovisioningImage() {
+				t.Errorf("virtual media: %v, but ISO preprovisioning image support: %v",
+					capabilities.VirtualMedia, acc.SupportsISOPreprovisioningImage())
+			}
+			if capabilities.SecureBoot != acc.SupportsSecureBoot() {
+				t.Errorf("secure boot: %v, but driver support: %v",
+					capabilities.SecureBoot, acc.SupportsSecureBoot())
+			}
+			if capabilities.HardwareRAID != (acc.RAIDInterface() != "no-raid") {
+				t.Errorf("hardware RAID: %v, but RAID interface: %q",
+					capabilities.HardwareRAID, acc.RAIDInterface())
+			}
+			if capabilities.FirmwareUpdate != acc.SupportsFirmwareUpdate() {
+				t.Errorf("firmware update: %v, but driver support: %v",
+					capabilities.FirmwareUpdate, acc.SupportsFirmwareUpdate())
+			}
+			_, err = acc.BuildBIOSSettings(&FirmwareConfig{VirtualizationEnabled: &enabled})
+			if capabilities.BIOSSettings != (err == nil) {
+				t.Errorf("BIOS settings: %v, but building settings returned: %v",
+					capabilities.BIOSSettings, err)
+			}
+		})
+	}
+}
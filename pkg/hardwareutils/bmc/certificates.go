@@ -0,0 +1,125 @@
+package bmc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// caCertificatesFielder is implemented by the AccessDetails of the
+// drivers connecting to the BMC over HTTPS.
+type caCertificatesFielder interface {
+	// verifyCAField returns the name of the DriverInfo field holding
+	// the path of the CA certificates the certificate of the BMC is
+	// verified against.
+	verifyCAField() string
+}
+
+// CACertificatesField returns the name of the DriverInfo field setting
+// how the certificate of the BMC is verified, or an empty string when
+// the driver does not connect to the BMC over HTTPS.
+func CACertificatesField(access AccessDetails) string {
+	if fielder, ok := access.(caCertificatesFielder); ok {
+		return fielder.verifyCAField()
+	}
+	return ""
+}
+
+type caAccessDetails struct {
+	AccessDetails
+	path string
+}
+
+// WithCACertificates returns AccessDetails whose DriverInfo requests
+// the certificate of the BMC to be verified against the CA
+// certificates in the file at path, instead of the system trust store.
+func WithCACertificates(access AccessDetails, path string) (AccessDetails, error) {
+	if CACertificatesField(access) == "" {
+		return nil, fmt.Errorf("BMC driver %s does not support CA certificates", access.Type())
+	}
+	return &caAccessDetails{AccessDetails: access, path: path}, nil
+}
+
+func (a *caAccessDetails) DriverInfo(bmcCreds Credentials) map[string]interface{} {
+	result := a.AccessDetails.DriverInfo(bmcCreds)
+	result[CACertificatesField(a.AccessDetails)] = a.path
+	return result
+}
+
+// ParseCABundle parses a bundle of PEM-encoded certificates. It fails
+// when the bundle holds no certificate, or anything but certificates.
+func ParseCABundle(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := bytes.TrimSpace(data)
+	for len(rest) > 0 {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, errors.New("CA bundle holds data that is not PEM-encoded")
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("CA bundle holds a %s block instead of a certificate", block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "CA bundle holds an invalid certificate")
+		}
+		certs = append(certs, cert)
+		rest = bytes.TrimSpace(rest)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("CA bundle holds no certificate")
+	}
+	return certs, nil
+}
+
+// ParseCertificateFingerprint parses the SHA-256 fingerprint of a
+// certificate, given as hexadecimal digits optionally separated by
+// colons, as printed by "openssl x509 -fingerprint -sha256".
+func ParseCertificateFingerprint(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(strings.ToLower(value), "sha256 fingerprint=")
+	fingerprint, err := hex.DecodeString(strings.ReplaceAll(value, ":", ""))
+	if err != nil || len(fingerprint) != sha256.Size {
+		return nil, fmt.Errorf("invalid SHA-256 certificate fingerprint %q", value)
+	}
+	return fingerprint, nil
+}
+
+// CertificateFingerprint returns the SHA-256 fingerprint of a
+// certificate.
+func CertificateFingerprint(cert *x509.Certificate) []byte {
+	sum := sha256.Sum256(cert.Raw)
+	return sum[:]
+}
+
+// PinnedTLSConfig returns the configuration used to connect to a BMC
+// whose certificate is pinned by its SHA-256 fingerprint. The
+// certificate is only trusted because of the fingerprint, so its chain,
+// validity and names are not verified, which allows self-signed
+// certificates not valid for the address of the BMC.
+func PinnedTLSConfig(fingerprint []byte) *tls.Config {
+	return &tls.Config{
+		// The certificate is verified by VerifyPeerCertificate instead
+		InsecureSkipVerify: true, // #nosec
+		MinVersion:         tls.VersionTLS12,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.New("the BMC presented no certificate")
+			}
+			sum := sha256.Sum256(rawCerts[0])
+			if !bytes.Equal(sum[:], fingerprint) {
+				return fmt.Errorf("the BMC certificate does not match the pinned fingerprint, its fingerprint is %s",
+					hex.EncodeToString(sum[:]))
+			}
+			return nil
+		},
+	}
+}
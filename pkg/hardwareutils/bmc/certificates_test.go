@@ -0,0 +1,184 @@
+package bmc
+
+import (
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testServerPEM(server *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+}
+
+func TestParseCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	cert := testServerPEM(server)
+
+	testCases := []struct {
+		Scenario      string
+		Data          []byte
+		ExpectedCount int
+		ExpectedError string
+	}{
+		{
+			Scenario:      "single",
+			Data:          cert,
+			ExpectedCount: 1,
+		},
+		{
+			Scenario:      "bundle",
+			Data:          append(append([]byte{}, cert...), cert...),
+			ExpectedCount: 2,
+		},
+		{
+			Scenario:      "empty",
+			Data:          []byte("\n"),
+			ExpectedError: "CA bundle holds no certificate",
+		},
+		{
+			Scenario:      "not PEM",
+			Data:          append(append([]byte{}, cert...), []byte("garbage")...),
+			ExpectedError: "CA bundle holds data that is not PEM-encoded",
+		},
+		{
+			Scenario:      "private key",
+			Data:          pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")}),
+			ExpectedError: "CA bundle holds a PRIVATE KEY block instead of a certificate",
+		},
+		{
+			Scenario:      "invalid certificate",
+			Data:          pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("cert")}),
+			ExpectedError: "CA bundle holds an invalid certificate",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			certs, err := ParseCABundle(tc.Data)
+			if tc.ExpectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.ExpectedError) {
+					t.Fatalf("expected error %q but got %v", tc.ExpectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(certs) != tc.ExpectedCount {
+				t.Fatalf("expected %d certificates but got %d", tc.ExpectedCount, len(certs))
+			}
+		})
+	}
+}
+
+func TestParseCertificateFingerprint(t *testing.T) {
+	digits := strings.Repeat("ab", 32)
+	colons := strings.TrimSuffix(strings.Repeat("AB:", 32), ":")
+
+	testCases := []struct {
+		Scenario string
+		Value    string
+		Valid    bool
+	}{
+		{Scenario: "hex", Value: digits, Valid: true},
+		{Scenario: "colons", Value: colons, Valid: true},
+		{Scenario: "openssl output", Value: "sha256 Fingerprint=" + colons + "\n", Valid: true},
+		{Scenario: "too short", Value: "abcd"},
+		{Scenario: "sha1", Value: strings.Repeat("ab", 20)},
+		{Scenario: "not hex", Value: strings.Repeat("zz", 32)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			fingerprint, err := ParseCertificateFingerprint(tc.Value)
+			if !tc.Valid {
+				if err == nil {
+					t.Fatal("Expected error, did not get one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if hex.EncodeToString(fingerprint) != digits {
+				t.Fatalf("expected fingerprint %s but got %x", digits, fingerprint)
+			}
+		})
+	}
+}
+
+func TestWithCACertificates(t *testing.T) {
+	testCases := []struct {
+		Address       string
+		ExpectedField string
+	}{
+		{Address: "redfish://192.168.122.1/redfish/v1/Systems/1", ExpectedField: "redfish_verify_ca"},
+		{Address: "redfish-virtualmedia://192.168.122.1/redfish/v1/Systems/1", ExpectedField: "redfish_verify_ca"},
+		{Address: "idrac-virtualmedia://192.168.122.1/redfish/v1/Systems/1", ExpectedField: "redfish_verify_ca"},
+		{Address: "idrac://192.168.122.1", ExpectedField: "drac_verify_ca"},
+		{Address: "ilo5://192.168.122.1", ExpectedField: "ilo_verify_ca"},
+		{Address: "irmc://192.168.122.1", ExpectedField: "irmc_verify_ca"},
+		{Address: "ipmi://192.168.122.1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Address, func(t *testing.T) {
+			access, err := NewAccessDetails(tc.Address, false)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if field := CACertificatesField(access); field != tc.ExpectedField {
+				t.Fatalf("expected field %q but got %q", tc.ExpectedField, field)
+			}
+
+			withCA, err := WithCACertificates(access, "/certs/bmc.pem")
+			if tc.ExpectedField == "" {
+				if err == nil {
+					t.Fatal("Expected error, did not get one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if withCA.Driver() != access.Driver() {
+				t.Fatalf("expected driver %q but got %q", access.Driver(), withCA.Driver())
+			}
+			driverInfo := withCA.DriverInfo(Credentials{Username: "admin", Password: "password"})
+			if driverInfo[tc.ExpectedField] != "/certs/bmc.pem" {
+				t.Fatalf("expected %s to be the CA path but got %v", tc.ExpectedField, driverInfo[tc.ExpectedField])
+			}
+		})
+	}
+}
+
+func TestPinnedTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	// The certificate of the test server is not valid for localhost
+	url := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	get := func(config *tls.Config) error {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	fingerprint := CertificateFingerprint(server.Certificate())
+	if err := get(PinnedTLSConfig(fingerprint)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := get(PinnedTLSConfig(make([]byte, len(fingerprint))))
+	if err == nil || !strings.Contains(err.Error(), "the BMC certificate does not match the pinned fingerprint") {
+		t.Fatalf("expected a fingerprint mismatch but got %v", err)
+	}
+}
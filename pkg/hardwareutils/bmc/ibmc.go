@@ -46,6 +46,10 @@ func (a *ibmcAccessDetails) DisableCertificateVerification() bool {
 	return a.disableCertificateVerification
 }
 
+func (a *ibmcAccessDetails) verifyCAField() string {
+	return "ibmc_verify_ca"
+}
+
 const ibmcDefaultScheme = "https"
 
 // DriverInfo returns a data structure to pass as the DriverInfo
@@ -104,7 +108,11 @@ func (a *ibmcAccessDetails) VendorInterface() string {
 }
 
 func (a *ibmcAccessDetails) SupportsSecureBoot() bool {
-	return false
+	return a.Capabilities().SecureBoot
+}
+
+func (a *ibmcAccessDetails) SupportsFirmwareUpdate() bool {
+	return a.Capabilities().FirmwareUpdate
 }
 
 func (a *ibmcAccessDetails) SupportsISOPreprovisioningImage() bool {
@@ -121,3 +129,16 @@ func (a *ibmcAccessDetails) BuildBIOSSettings(firmwareConfig *FirmwareConfig) (s
 	}
 	return nil, nil
 }
+
+func (a *ibmcAccessDetails) BuildFirmwareUpdates(updates []FirmwareUpdate) (images []map[string]string, err error) {
+	if len(updates) != 0 {
+		return nil, fmt.Errorf("firmware updates for %s are not supported", a.Driver())
+	}
+	return nil, nil
+}
+
+func (a *ibmcAccessDetails) Capabilities() Capabilities {
+	return Capabilities{
+		SoftPowerOff: true,
+	}
+}
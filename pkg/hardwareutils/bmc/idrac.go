@@ -1,6 +1,7 @@
 package bmc
 
 import (
+	"fmt"
 	"net/url"
 	"strings"
 )
@@ -45,6 +46,10 @@ func (a *iDracAccessDetails) DisableCertificateVerification() bool {
 	return a.disableCertificateVerification
 }
 
+func (a *iDracAccessDetails) verifyCAField() string {
+	return "drac_verify_ca"
+}
+
 // DriverInfo returns a data structure to pass as the DriverInfo
 // parameter when creating a node in Ironic. The structure is
 // pre-populated with the access information, and the caller is
@@ -101,7 +106,11 @@ func (a *iDracAccessDetails) VendorInterface() string {
 // NOTE(dtantsur): change to true if we switch to redfish-based implementations
 // by default.
 func (a *iDracAccessDetails) SupportsSecureBoot() bool {
-	return false
+	return a.Capabilities().SecureBoot
+}
+
+func (a *iDracAccessDetails) SupportsFirmwareUpdate() bool {
+	return a.Capabilities().FirmwareUpdate
 }
 
 func (a *iDracAccessDetails) SupportsISOPreprovisioningImage() bool {
@@ -160,3 +169,17 @@ func (a *iDracAccessDetails) BuildBIOSSettings(firmwareConfig *FirmwareConfig) (
 
 	return
 }
+
+func (a *iDracAccessDetails) BuildFirmwareUpdates(updates []FirmwareUpdate) (images []map[string]string, err error) {
+	if len(updates) != 0 {
+		return nil, fmt.Errorf("firmware updates for %s are not supported", a.Driver())
+	}
+	return nil, nil
+}
+
+func (a *iDracAccessDetails) Capabilities() Capabilities {
+	return Capabilities{
+		HardwareRAID: true,
+		BIOSSettings: true,
+	}
+}
@@ -42,6 +42,10 @@ func (a *redfishiDracVirtualMediaAccessDetails) DisableCertificateVerification()
 	return a.disableCertificateVerification
 }
 
+func (a *redfishiDracVirtualMediaAccessDetails) verifyCAField() string {
+	return "redfish_verify_ca"
+}
+
 // DriverInfo returns a data structure to pass as the DriverInfo
 // parameter when creating a node in Ironic. The structure is
 // pre-populated with the access information, and the caller is
@@ -94,7 +98,11 @@ func (a *redfishiDracVirtualMediaAccessDetails) VendorInterface() string {
 }
 
 func (a *redfishiDracVirtualMediaAccessDetails) SupportsSecureBoot() bool {
-	return true
+	return a.Capabilities().SecureBoot
+}
+
+func (a *redfishiDracVirtualMediaAccessDetails) SupportsFirmwareUpdate() bool {
+	return a.Capabilities().FirmwareUpdate
 }
 
 func (a *redfishiDracVirtualMediaAccessDetails) SupportsISOPreprovisioningImage() bool {
@@ -111,3 +119,18 @@ func (a *redfishiDracVirtualMediaAccessDetails) BuildBIOSSettings(firmwareConfig
 	}
 	return nil, nil
 }
+
+func (a *redfishiDracVirtualMediaAccessDetails) BuildFirmwareUpdates(updates []FirmwareUpdate) (images []map[string]string, err error) {
+	return buildRedfishFirmwareUpdates(updates), nil
+}
+
+func (a *redfishiDracVirtualMediaAccessDetails) Capabilities() Capabilities {
+	return Capabilities{
+		VirtualMedia:       true,
+		SecureBoot:         true,
+		HardwareRAID:       true,
+		FirmwareUpdate:     true,
+		EventSubscriptions: true,
+		SoftPowerOff:       true,
+	}
+}
@@ -3,6 +3,7 @@
 package bmc
 
 import (
+	"fmt"
 	"net/url"
 )
 
@@ -58,6 +59,10 @@ func (a *iLOAccessDetails) DisableCertificateVerification() bool {
 	return a.disableCertificateVerification
 }
 
+func (a *iLOAccessDetails) verifyCAField() string {
+	return "ilo_verify_ca"
+}
+
 // DriverInfo returns a data structure to pass as the DriverInfo
 // parameter when creating a node in Ironic. The structure is
 // pre-populated with the access information, and the caller is
@@ -111,7 +116,11 @@ func (a *iLOAccessDetails) VendorInterface() string {
 }
 
 func (a *iLOAccessDetails) SupportsSecureBoot() bool {
-	return true
+	return a.Capabilities().SecureBoot
+}
+
+func (a *iLOAccessDetails) SupportsFirmwareUpdate() bool {
+	return a.Capabilities().FirmwareUpdate
 }
 
 func (a *iLOAccessDetails) SupportsISOPreprovisioningImage() bool {
@@ -170,3 +179,19 @@ func (a *iLOAccessDetails) BuildBIOSSettings(firmwareConfig *FirmwareConfig) (se
 
 	return
 }
+
+func (a *iLOAccessDetails) BuildFirmwareUpdates(updates []FirmwareUpdate) (images []map[string]string, err error) {
+	if len(updates) != 0 {
+		return nil, fmt.Errorf("firmware updates for %s are not supported", a.Driver())
+	}
+	return nil, nil
+}
+
+func (a *iLOAccessDetails) Capabilities() Capabilities {
+	return Capabilities{
+		VirtualMedia: a.useVirtualMedia,
+		SecureBoot:   true,
+		BIOSSettings: true,
+		SoftPowerOff: true,
+	}
+}
@@ -3,6 +3,7 @@
 package bmc
 
 import (
+	"fmt"
 	"net/url"
 )
 
@@ -46,6 +47,10 @@ func (a *iLO5AccessDetails) DisableCertificateVerification() bool {
 	return a.disableCertificateVerification
 }
 
+func (a *iLO5AccessDetails) verifyCAField() string {
+	return "ilo_verify_ca"
+}
+
 // DriverInfo returns a data structure to pass as the DriverInfo
 // parameter when creating a node in Ironic. The structure is
 // pre-populated with the access information, and the caller is
@@ -95,7 +100,11 @@ func (a *iLO5AccessDetails) VendorInterface() string {
 }
 
 func (a *iLO5AccessDetails) SupportsSecureBoot() bool {
-	return true
+	return a.Capabilities().SecureBoot
+}
+
+func (a *iLO5AccessDetails) SupportsFirmwareUpdate() bool {
+	return a.Capabilities().FirmwareUpdate
 }
 
 func (a *iLO5AccessDetails) SupportsISOPreprovisioningImage() bool {
@@ -154,3 +163,19 @@ func (a *iLO5AccessDetails) BuildBIOSSettings(firmwareConfig *FirmwareConfig) (s
 
 	return
 }
+
+func (a *iLO5AccessDetails) BuildFirmwareUpdates(updates []FirmwareUpdate) (images []map[string]string, err error) {
+	if len(updates) != 0 {
+		return nil, fmt.Errorf("firmware updates for %s are not supported", a.Driver())
+	}
+	return nil, nil
+}
+
+func (a *iLO5AccessDetails) Capabilities() Capabilities {
+	return Capabilities{
+		SecureBoot:   true,
+		HardwareRAID: true,
+		BIOSSettings: true,
+		SoftPowerOff: true,
+	}
+}
@@ -114,7 +114,11 @@ func (a *ipmiAccessDetails) VendorInterface() string {
 }
 
 func (a *ipmiAccessDetails) SupportsSecureBoot() bool {
-	return false
+	return a.Capabilities().SecureBoot
+}
+
+func (a *ipmiAccessDetails) SupportsFirmwareUpdate() bool {
+	return a.Capabilities().FirmwareUpdate
 }
 
 func (a *ipmiAccessDetails) SupportsISOPreprovisioningImage() bool {
@@ -131,3 +135,16 @@ func (a *ipmiAccessDetails) BuildBIOSSettings(firmwareConfig *FirmwareConfig) (s
 	}
 	return nil, nil
 }
+
+func (a *ipmiAccessDetails) BuildFirmwareUpdates(updates []FirmwareUpdate) (images []map[string]string, err error) {
+	if len(updates) != 0 {
+		return nil, fmt.Errorf("firmware updates for %s are not supported", a.Driver())
+	}
+	return nil, nil
+}
+
+func (a *ipmiAccessDetails) Capabilities() Capabilities {
+	return Capabilities{
+		SoftPowerOff: true,
+	}
+}
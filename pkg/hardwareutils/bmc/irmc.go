@@ -1,6 +1,7 @@
 package bmc
 
 import (
+	"fmt"
 	"net/url"
 )
 
@@ -44,6 +45,10 @@ func (a *iRMCAccessDetails) DisableCertificateVerification() bool {
 	return a.disableCertificateVerification
 }
 
+func (a *iRMCAccessDetails) verifyCAField() string {
+	return "irmc_verify_ca"
+}
+
 // DriverInfo returns a data structure to pass as the DriverInfo
 // parameter when creating a node in Ironic. The structure is
 // pre-populated with the access information, and the caller is
@@ -95,7 +100,11 @@ func (a *iRMCAccessDetails) VendorInterface() string {
 }
 
 func (a *iRMCAccessDetails) SupportsSecureBoot() bool {
-	return true
+	return a.Capabilities().SecureBoot
+}
+
+func (a *iRMCAccessDetails) SupportsFirmwareUpdate() bool {
+	return a.Capabilities().FirmwareUpdate
 }
 
 func (a *iRMCAccessDetails) SupportsISOPreprovisioningImage() bool {
@@ -154,3 +163,19 @@ func (a *iRMCAccessDetails) BuildBIOSSettings(firmwareConfig *FirmwareConfig) (s
 
 	return
 }
+
+func (a *iRMCAccessDetails) BuildFirmwareUpdates(updates []FirmwareUpdate) (images []map[string]string, err error) {
+	if len(updates) != 0 {
+		return nil, fmt.Errorf("firmware updates for %s are not supported", a.Driver())
+	}
+	return nil, nil
+}
+
+func (a *iRMCAccessDetails) Capabilities() Capabilities {
+	return Capabilities{
+		SecureBoot:   true,
+		HardwareRAID: true,
+		BIOSSettings: true,
+		SoftPowerOff: true,
+	}
+}
@@ -0,0 +1,73 @@
+package bmc
+
+import (
+	"net/url"
+)
+
+func init() {
+	schemes := []string{"http", "https"}
+	RegisterFactory("lenovo-redfish", newLenovoRedfishAccessDetails, schemes)
+	RegisterFactory("lenovo-virtualmedia", newLenovoVirtualMediaAccessDetails, schemes)
+}
+
+// lenovoBIOSAttributes are the names of the BIOS attributes of the
+// Redfish service of Lenovo XClarity Controllers.
+var lenovoBIOSAttributes = redfishBIOSAttributes{
+	virtualization: "Processors_IntelVirtualizationTechnology",
+	smt:            "Processors_HyperThreading",
+	sriov:          "DevicesandIOPorts_SRIOV",
+	enabled:        "Enable",
+	disabled:       "Disable",
+}
+
+func newLenovoRedfishAccessDetails(parsedURL *url.URL, disableCertificateVerification bool) (AccessDetails, error) {
+	return &lenovoRedfishAccessDetails{
+		*redfishDetails(parsedURL, disableCertificateVerification),
+	}, nil
+}
+
+func newLenovoVirtualMediaAccessDetails(parsedURL *url.URL, disableCertificateVerification bool) (AccessDetails, error) {
+	return &lenovoVirtualMediaAccessDetails{
+		*redfishVirtualMediaDetails(parsedURL, disableCertificateVerification),
+	}, nil
+}
+
+type lenovoRedfishAccessDetails struct {
+	redfishAccessDetails
+}
+
+type lenovoVirtualMediaAccessDetails struct {
+	redfishVirtualMediaAccessDetails
+}
+
+// Lenovo XClarity Redfish Overrides
+
+func (a *lenovoRedfishAccessDetails) BIOSInterface() string {
+	return "redfish"
+}
+
+func (a *lenovoRedfishAccessDetails) BuildBIOSSettings(firmwareConfig *FirmwareConfig) (settings []map[string]string, err error) {
+	return buildRedfishBIOSSettings(firmwareConfig, lenovoBIOSAttributes), nil
+}
+
+func (a *lenovoRedfishAccessDetails) Capabilities() Capabilities {
+	capabilities := a.redfishAccessDetails.Capabilities()
+	capabilities.BIOSSettings = true
+	return capabilities
+}
+
+// Lenovo XClarity Virtual Media Overrides
+
+func (a *lenovoVirtualMediaAccessDetails) BIOSInterface() string {
+	return "redfish"
+}
+
+func (a *lenovoVirtualMediaAccessDetails) BuildBIOSSettings(firmwareConfig *FirmwareConfig) (settings []map[string]string, err error) {
+	return buildRedfishBIOSSettings(firmwareConfig, lenovoBIOSAttributes), nil
+}
+
+func (a *lenovoVirtualMediaAccessDetails) Capabilities() Capabilities {
+	capabilities := a.redfishVirtualMediaAccessDetails.Capabilities()
+	capabilities.BIOSSettings = true
+	return capabilities
+}
@@ -65,6 +65,10 @@ func (a *redfishAccessDetails) DisableCertificateVerification() bool {
 	return a.disableCertificateVerification
 }
 
+func (a *redfishAccessDetails) verifyCAField() string {
+	return "redfish_verify_ca"
+}
+
 func getRedfishAddress(bmcType, host string) string {
 	redfishAddress := []string{}
 	schemes := strings.Split(bmcType, "+")
@@ -124,7 +128,11 @@ func (a *redfishAccessDetails) VendorInterface() string {
 }
 
 func (a *redfishAccessDetails) SupportsSecureBoot() bool {
-	return true
+	return a.Capabilities().SecureBoot
+}
+
+func (a *redfishAccessDetails) SupportsFirmwareUpdate() bool {
+	return a.Capabilities().FirmwareUpdate
 }
 
 func (a *redfishAccessDetails) SupportsISOPreprovisioningImage() bool {
@@ -142,6 +150,33 @@ func (a *redfishAccessDetails) BuildBIOSSettings(firmwareConfig *FirmwareConfig)
 	return nil, nil
 }
 
+func (a *redfishAccessDetails) BuildFirmwareUpdates(updates []FirmwareUpdate) (images []map[string]string, err error) {
+	return buildRedfishFirmwareUpdates(updates), nil
+}
+
+func (a *redfishAccessDetails) Capabilities() Capabilities {
+	return Capabilities{
+		SecureBoot:         true,
+		FirmwareUpdate:     true,
+		EventSubscriptions: true,
+		SoftPowerOff:       true,
+	}
+}
+
+// buildRedfishFirmwareUpdates converts the updates to the firmware
+// images expected by the update_firmware step of the Redfish management
+// interface.
+func buildRedfishFirmwareUpdates(updates []FirmwareUpdate) (images []map[string]string) {
+	for _, update := range updates {
+		image := map[string]string{"url": update.URL}
+		if update.Checksum != "" {
+			image["checksum"] = update.Checksum
+		}
+		images = append(images, image)
+	}
+	return
+}
+
 // iDrac Redfish Overrides
 func (a *redfishiDracAccessDetails) Driver() string {
 	return "idrac"
@@ -178,3 +213,65 @@ func (a *redfishiDracAccessDetails) BuildBIOSSettings(firmwareConfig *FirmwareCo
 	}
 	return nil, nil
 }
+
+func (a *redfishiDracAccessDetails) Capabilities() Capabilities {
+	capabilities := a.redfishAccessDetails.Capabilities()
+	capabilities.HardwareRAID = true
+	return capabilities
+}
+
+// redfishBIOSAttributes holds the vendor specific names of the Redfish
+// BIOS attributes matching the fields of FirmwareConfig, and the values
+// enabling and disabling them.
+type redfishBIOSAttributes struct {
+	virtualization string
+	smt            string
+	sriov          string
+	enabled        string
+	disabled       string
+}
+
+func (attrs redfishBIOSAttributes) value(enabled bool) string {
+	if enabled {
+		return attrs.enabled
+	}
+	return attrs.disabled
+}
+
+// buildRedfishBIOSSettings converts the firmware config to the settings
+// expected by the apply_configuration step of the Redfish BIOS
+// interface.
+func buildRedfishBIOSSettings(firmwareConfig *FirmwareConfig, attrs redfishBIOSAttributes) (settings []map[string]string) {
+	if firmwareConfig == nil {
+		return nil
+	}
+
+	if firmwareConfig.VirtualizationEnabled != nil {
+		settings = append(settings,
+			map[string]string{
+				"name":  attrs.virtualization,
+				"value": attrs.value(*firmwareConfig.VirtualizationEnabled),
+			},
+		)
+	}
+
+	if firmwareConfig.SimultaneousMultithreadingEnabled != nil {
+		settings = append(settings,
+			map[string]string{
+				"name":  attrs.smt,
+				"value": attrs.value(*firmwareConfig.SimultaneousMultithreadingEnabled),
+			},
+		)
+	}
+
+	if firmwareConfig.SriovEnabled != nil {
+		settings = append(settings,
+			map[string]string{
+				"name":  attrs.sriov,
+				"value": attrs.value(*firmwareConfig.SriovEnabled),
+			},
+		)
+	}
+
+	return
+}
@@ -11,13 +11,17 @@ func init() {
 	RegisterFactory("ilo5-virtualmedia", newRedfishVirtualMediaAccessDetails, schemes)
 }
 
-func newRedfishVirtualMediaAccessDetails(parsedURL *url.URL, disableCertificateVerification bool) (AccessDetails, error) {
+func redfishVirtualMediaDetails(parsedURL *url.URL, disableCertificateVerification bool) *redfishVirtualMediaAccessDetails {
 	return &redfishVirtualMediaAccessDetails{
 		bmcType:                        parsedURL.Scheme,
 		host:                           parsedURL.Host,
 		path:                           parsedURL.Path,
 		disableCertificateVerification: disableCertificateVerification,
-	}, nil
+	}
+}
+
+func newRedfishVirtualMediaAccessDetails(parsedURL *url.URL, disableCertificateVerification bool) (AccessDetails, error) {
+	return redfishVirtualMediaDetails(parsedURL, disableCertificateVerification), nil
 }
 
 type redfishVirtualMediaAccessDetails struct {
@@ -47,6 +51,10 @@ func (a *redfishVirtualMediaAccessDetails) DisableCertificateVerification() bool
 	return a.disableCertificateVerification
 }
 
+func (a *redfishVirtualMediaAccessDetails) verifyCAField() string {
+	return "redfish_verify_ca"
+}
+
 // DriverInfo returns a data structure to pass as the DriverInfo
 // parameter when creating a node in Ironic. The structure is
 // pre-populated with the access information, and the caller is
@@ -92,7 +100,11 @@ func (a *redfishVirtualMediaAccessDetails) VendorInterface() string {
 }
 
 func (a *redfishVirtualMediaAccessDetails) SupportsSecureBoot() bool {
-	return true
+	return a.Capabilities().SecureBoot
+}
+
+func (a *redfishVirtualMediaAccessDetails) SupportsFirmwareUpdate() bool {
+	return a.Capabilities().FirmwareUpdate
 }
 
 func (a *redfishVirtualMediaAccessDetails) SupportsISOPreprovisioningImage() bool {
@@ -109,3 +121,17 @@ func (a *redfishVirtualMediaAccessDetails) BuildBIOSSettings(firmwareConfig *Fir
 	}
 	return nil, nil
 }
+
+func (a *redfishVirtualMediaAccessDetails) BuildFirmwareUpdates(updates []FirmwareUpdate) (images []map[string]string, err error) {
+	return buildRedfishFirmwareUpdates(updates), nil
+}
+
+func (a *redfishVirtualMediaAccessDetails) Capabilities() Capabilities {
+	return Capabilities{
+		VirtualMedia:       true,
+		SecureBoot:         true,
+		FirmwareUpdate:     true,
+		EventSubscriptions: true,
+		SoftPowerOff:       true,
+	}
+}
@@ -0,0 +1,85 @@
+package bmc
+
+import (
+	"net/url"
+)
+
+func init() {
+	schemes := []string{"http", "https"}
+	RegisterFactory("supermicro-redfish", newSupermicroRedfishAccessDetails, schemes)
+	RegisterFactory("supermicro-virtualmedia", newSupermicroVirtualMediaAccessDetails, schemes)
+}
+
+// supermicroBIOSAttributes are the names of the BIOS attributes of the
+// Redfish service of Supermicro BMCs.
+var supermicroBIOSAttributes = redfishBIOSAttributes{
+	virtualization: "IntelVirtualizationTechnology",
+	smt:            "HyperThreading",
+	sriov:          "SR_IOVSupport",
+	enabled:        "Enable",
+	disabled:       "Disable",
+}
+
+func newSupermicroRedfishAccessDetails(parsedURL *url.URL, disableCertificateVerification bool) (AccessDetails, error) {
+	return &supermicroRedfishAccessDetails{
+		*redfishDetails(parsedURL, disableCertificateVerification),
+	}, nil
+}
+
+func newSupermicroVirtualMediaAccessDetails(parsedURL *url.URL, disableCertificateVerification bool) (AccessDetails, error) {
+	return &supermicroVirtualMediaAccessDetails{
+		*redfishVirtualMediaDetails(parsedURL, disableCertificateVerification),
+	}, nil
+}
+
+type supermicroRedfishAccessDetails struct {
+	redfishAccessDetails
+}
+
+type supermicroVirtualMediaAccessDetails struct {
+	redfishVirtualMediaAccessDetails
+}
+
+// Supermicro Redfish Overrides
+
+func (a *supermicroRedfishAccessDetails) BIOSInterface() string {
+	return "redfish"
+}
+
+func (a *supermicroRedfishAccessDetails) SupportsSecureBoot() bool {
+	return a.Capabilities().SecureBoot
+}
+
+func (a *supermicroRedfishAccessDetails) BuildBIOSSettings(firmwareConfig *FirmwareConfig) (settings []map[string]string, err error) {
+	return buildRedfishBIOSSettings(firmwareConfig, supermicroBIOSAttributes), nil
+}
+
+func (a *supermicroRedfishAccessDetails) Capabilities() Capabilities {
+	capabilities := a.redfishAccessDetails.Capabilities()
+	capabilities.BIOSSettings = true
+	// Supermicro BMCs do not expose the SecureBoot resource of the
+	// system consistently across generations.
+	capabilities.SecureBoot = false
+	return capabilities
+}
+
+// Supermicro Virtual Media Overrides
+
+func (a *supermicroVirtualMediaAccessDetails) BIOSInterface() string {
+	return "redfish"
+}
+
+func (a *supermicroVirtualMediaAccessDetails) SupportsSecureBoot() bool {
+	return a.Capabilities().SecureBoot
+}
+
+func (a *supermicroVirtualMediaAccessDetails) BuildBIOSSettings(firmwareConfig *FirmwareConfig) (settings []map[string]string, err error) {
+	return buildRedfishBIOSSettings(firmwareConfig, supermicroBIOSAttributes), nil
+}
+
+func (a *supermicroVirtualMediaAccessDetails) Capabilities() Capabilities {
+	capabilities := a.redfishVirtualMediaAccessDetails.Capabilities()
+	capabilities.BIOSSettings = true
+	capabilities.SecureBoot = false
+	return capabilities
+}
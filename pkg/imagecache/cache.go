@@ -0,0 +1,398 @@
+// Package imagecache downloads the images deployed to the hosts once,
+// and serves them to the hosts from the cluster, so that provisioning
+// many hosts does not download the same image many times from its
+// upstream location.
+package imagecache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/imageverify"
+)
+
+const (
+	// ImagesPath is the path under which the cache serves the images,
+	// as ImagesPath<key>/<name>.
+	ImagesPath = "/images/"
+
+	// metadataFile holds the entry of an image, next to the image, in
+	// the directory of the image.
+	metadataFile = "image.json"
+	tempPrefix   = ".download-"
+)
+
+var (
+	evictionInterval = time.Minute * 10
+
+	// failureRetryDelay is how long a failed download is reported
+	// before the image is downloaded again.
+	failureRetryDelay = time.Minute * 5
+)
+
+// entry is an image in the cache.
+type entry struct {
+	URL          string `json:"url"`
+	Checksum     string `json:"checksum"`
+	ChecksumType string `json:"checksumType"`
+	Name         string `json:"name"`
+
+	downloading bool
+	ready       bool
+	err         error
+	failed      time.Time
+}
+
+// Cache downloads and verifies the images deployed to the hosts, and
+// serves them over HTTP. Each image is stored in a directory named
+// after the hash of its URL, and is evicted once no host references
+// its URL any longer.
+type Cache struct {
+	dir        string
+	addr       string
+	url        string
+	client     client.Reader
+	log        logr.Logger
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// NewCache returns a cache storing the images in dir, serving them on
+// addr, and reachable by the hosts at baseURL. The images downloaded
+// by a previous run are served again without being downloaded.
+func NewCache(dir, addr, baseURL string, reader client.Reader, log logr.Logger) (*Cache, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = time.Minute
+	c := &Cache{
+		dir:    dir,
+		addr:   addr,
+		url:    strings.TrimSuffix(baseURL, "/"),
+		client: reader,
+		log:    log,
+		// Images take long to download, so only waiting for the
+		// server to answer is limited.
+		httpClient: &http.Client{Transport: transport},
+		entries:    map[string]*entry{},
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// cacheKey returns the name of the directory of the image at the URL
+// with the checksum, so that the image is never served to a host that
+// expects another checksum for the same URL.
+func cacheKey(imageURL, checksum, checksumType string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{imageURL, checksumType, checksum}, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// imageKey returns the cache key of the image, or an empty string when
+// it cannot be cached.
+func imageKey(image *metal3v1alpha1.Image) string {
+	if !cacheable(image) {
+		return ""
+	}
+	checksum, checksumType, ok := image.GetChecksum()
+	if !ok {
+		return ""
+	}
+	return cacheKey(image.URL, checksum, checksumType)
+}
+
+// imageName returns the name of the file the image at the URL is stored
+// in, which is the name of the file at the URL when it has one.
+func imageName(imageURL string) string {
+	name := "image"
+	if u, err := url.Parse(imageURL); err == nil {
+		if base := path.Base(u.Path); base != "/" && base != "." && !strings.HasPrefix(base, ".") && base != metadataFile {
+			name = base
+		}
+	}
+	return name
+}
+
+// cacheable returns true if the image can be cached: it must be
+// downloaded over http or https, and have a checksum to verify it
+// with.
+func cacheable(image *metal3v1alpha1.Image) bool {
+	if image == nil || image.IsLiveISO() || image.IsOCI() || image.Checksum == "" {
+		return false
+	}
+	u, err := url.Parse(image.URL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// load reads the images downloaded by a previous run, and removes the
+// incomplete downloads.
+func (c *Cache) load() error {
+	if err := os.MkdirAll(c.dir, 0o750); err != nil {
+		return errors.Wrap(err, "failed to create the image cache directory")
+	}
+	dirs, err := os.ReadDir(c.dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to read the image cache directory")
+	}
+
+	for _, d := range dirs {
+		key := d.Name()
+		dir := filepath.Join(c.dir, key)
+		e, err := readEntry(dir)
+		if err != nil || cacheKey(e.URL, e.Checksum, e.ChecksumType) != key {
+			c.log.Info("removing incomplete cached image", "dir", dir)
+			if err := os.RemoveAll(dir); err != nil {
+				return errors.Wrap(err, "failed to remove incomplete cached image")
+			}
+			continue
+		}
+		temps, _ := filepath.Glob(filepath.Join(dir, tempPrefix+"*"))
+		for _, temp := range temps {
+			os.Remove(temp)
+		}
+		e.ready = true
+		c.entries[key] = e
+	}
+	c.log.Info("loaded cached images", "count", len(c.entries))
+	return nil
+}
+
+func readEntry(dir string) (*entry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, metadataFile)) // #nosec
+	if err != nil {
+		return nil, err
+	}
+	e := &entry{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, e.Name)); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Lookup returns the URL the cache serves the image at, once it has
+// been downloaded and verified. The download of images not in the cache
+// yet is started, and pending is set while it runs. An empty URL is
+// returned for images that cannot be cached, which are downloaded from
+// their own URL. A download that failed is reported by the next
+// lookups, with an imageverify.VerificationError when the checksum of
+// the image did not match.
+func (c *Cache) Lookup(image *metal3v1alpha1.Image) (cachedURL string, pending bool, err error) {
+	key := imageKey(image)
+	if key == "" {
+		return "", false, nil
+	}
+	checksum, checksumType, _ := image.GetChecksum()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, exists := c.entries[key]
+	switch {
+	case !exists:
+		// An image whose checksum changed is downloaded again, and the
+		// previous one evicted once no host uses it
+		e = &entry{
+			URL:          image.URL,
+			Checksum:     checksum,
+			ChecksumType: checksumType,
+			Name:         imageName(image.URL),
+		}
+		c.entries[key] = e
+		c.startDownload(key, e)
+		return "", true, nil
+	case e.downloading:
+		return "", true, nil
+	case e.err != nil:
+		if time.Since(e.failed) < failureRetryDelay {
+			return "", false, e.err
+		}
+		c.startDownload(key, e)
+		return "", true, nil
+	}
+	return fmt.Sprintf("%s%s%s/%s", c.url, ImagesPath, key, url.PathEscape(e.Name)), false, nil
+}
+
+// startDownload downloads an image in the background. It must be called
+// with the lock held.
+func (c *Cache) startDownload(key string, e *entry) {
+	e.downloading = true
+	e.err = nil
+	go func() {
+		log := c.log.WithValues("url", e.URL)
+		log.Info("downloading image")
+		err := c.download(key, e)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		e.downloading = false
+		if err != nil {
+			log.Error(err, "failed to download image")
+			e.err = err
+			e.failed = time.Now()
+			return
+		}
+		log.Info("downloaded image")
+		e.ready = true
+	}()
+}
+
+// download fetches an image into its directory and verifies its
+// checksum. The image is only moved to its final name once verified.
+func (c *Cache) download(key string, e *entry) error {
+	dir := filepath.Join(c.dir, key)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return errors.Wrap(err, "failed to create the directory of the image")
+	}
+	temp, err := os.CreateTemp(dir, tempPrefix+"*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create the image file")
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	resp, err := c.httpClient.Get(e.URL)
+	if err != nil {
+		return errors.Wrapf(err, "failed to download %s", e.URL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", e.URL, resp.Status)
+	}
+
+	err = imageverify.VerifyChecksum(io.TeeReader(resp.Body, temp), e.Checksum, metal3v1alpha1.ChecksumType(e.ChecksumType))
+	if err != nil {
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return errors.Wrap(err, "failed to write the image file")
+	}
+	if err := os.Rename(temp.Name(), filepath.Join(dir, e.Name)); err != nil {
+		return errors.Wrap(err, "failed to write the image file")
+	}
+
+	metadata, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(os.WriteFile(filepath.Join(dir, metadataFile), metadata, 0o600),
+		"failed to write the image metadata")
+}
+
+// remove deletes an image from the cache. It must be called with the
+// lock held.
+func (c *Cache) remove(key string) {
+	delete(c.entries, key)
+	if err := os.RemoveAll(filepath.Join(c.dir, key)); err != nil {
+		c.log.Error(err, "failed to remove cached image", "key", key)
+	}
+}
+
+// evict removes the images no host references any longer, with the
+// same checksum, except those being downloaded.
+func (c *Cache) evict(ctx context.Context) error {
+	hosts := &metal3v1alpha1.BareMetalHostList{}
+	if err := c.client.List(ctx, hosts); err != nil {
+		return errors.Wrap(err, "failed to list hosts")
+	}
+	referenced := map[string]bool{}
+	for i := range hosts.Items {
+		host := &hosts.Items[i]
+		// The checksum read from a ChecksumURL is only in the status
+		referenced[imageKey(host.Spec.Image)] = true
+		referenced[imageKey(&host.Status.Provisioning.Image)] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		if referenced[key] || e.downloading {
+			continue
+		}
+		c.log.Info("evicting image no longer used by any host", "url", e.URL)
+		c.remove(key)
+	}
+	return nil
+}
+
+// ServeHTTP serves the images that have been downloaded and verified.
+func (c *Cache) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key, name, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, ImagesPath), "/")
+	c.mu.Lock()
+	e, exists := c.entries[key]
+	ready := exists && e.ready && !e.downloading && e.Name == name
+	c.mu.Unlock()
+	if !ready {
+		http.NotFound(w, req)
+		return
+	}
+	http.ServeFile(w, req, filepath.Join(c.dir, key, name))
+}
+
+// NeedLeaderElection makes the cache run only on the leader, whose
+// controller looks the images up.
+func (c *Cache) NeedLeaderElection() bool {
+	return true
+}
+
+// Start serves the images and evicts those no longer used until the
+// context is done.
+func (c *Cache) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle(ImagesPath, c)
+	server := &http.Server{
+		Addr:              c.addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		c.log.Info("starting image cache", "address", c.addr, "url", c.url)
+		errs <- server.ListenAndServe()
+	}()
+
+	ticker := time.NewTicker(evictionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-errs:
+			return err
+		case <-ticker.C:
+			if err := c.evict(ctx); err != nil {
+				c.log.Error(err, "failed to evict cached images")
+			}
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			return server.Shutdown(shutdownCtx)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package imagecache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/imageverify"
+)
+
+const (
+	testCacheURL = "http://image-cache:8090"
+	testImage    = "the content of the image"
+)
+
+var testImageChecksum = func() string {
+	sum := sha256.Sum256([]byte(testImage))
+	return hex.EncodeToString(sum[:])
+}()
+
+// newUpstream serves the test image, counting the downloads.
+func newUpstream(t *testing.T) (*httptest.Server, *int32) {
+	downloads := new(int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ubuntu-22.04.qcow2" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(downloads, 1)
+		w.Write([]byte(testImage))
+	}))
+	t.Cleanup(server.Close)
+	return server, downloads
+}
+
+func newTestCache(t *testing.T, dir string, objs ...client.Object) *Cache {
+	scheme := runtime.NewScheme()
+	require.NoError(t, metal3v1alpha1.AddToScheme(scheme))
+	c := fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	cache, err := NewCache(dir, "", testCacheURL, c, logr.Discard())
+	require.NoError(t, err)
+	return cache
+}
+
+// waitForImage looks the image up until its download is done.
+func waitForImage(t *testing.T, cache *Cache, image *metal3v1alpha1.Image) (cachedURL string, err error) {
+	require.Eventually(t, func() bool {
+		var pending bool
+		cachedURL, pending, err = cache.Lookup(image)
+		return !pending
+	}, 5*time.Second, 10*time.Millisecond)
+	return
+}
+
+func TestLookup(t *testing.T) {
+	upstream, downloads := newUpstream(t)
+	cache := newTestCache(t, t.TempDir())
+	image := &metal3v1alpha1.Image{
+		URL:          upstream.URL + "/ubuntu-22.04.qcow2",
+		Checksum:     testImageChecksum,
+		ChecksumType: metal3v1alpha1.SHA256,
+	}
+
+	cachedURL, pending, err := cache.Lookup(image)
+	assert.NoError(t, err)
+	assert.True(t, pending)
+	assert.Empty(t, cachedURL)
+
+	cachedURL, err = waitForImage(t, cache, image)
+	require.NoError(t, err)
+	assert.Equal(t, testCacheURL+ImagesPath+imageKey(image)+"/ubuntu-22.04.qcow2", cachedURL)
+
+	// Other hosts get the same image without downloading it again
+	again, pending, err := cache.Lookup(image.DeepCopy())
+	assert.NoError(t, err)
+	assert.False(t, pending)
+	assert.Equal(t, cachedURL, again)
+	assert.EqualValues(t, 1, atomic.LoadInt32(downloads))
+
+	w := httptest.NewRecorder()
+	cache.ServeHTTP(w, httptest.NewRequest(http.MethodGet, cachedURL, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, testImage, w.Body.String())
+
+	w = httptest.NewRecorder()
+	cache.ServeHTTP(w, httptest.NewRequest(http.MethodGet, testCacheURL+ImagesPath+cacheKey("other", testImageChecksum, "sha256")+"/image", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestLookupChecksumMismatch(t *testing.T) {
+	upstream, _ := newUpstream(t)
+	cache := newTestCache(t, t.TempDir())
+	image := &metal3v1alpha1.Image{
+		URL:          upstream.URL + "/ubuntu-22.04.qcow2",
+		Checksum:     "d41d8cd98f00b204e9800998ecf8427e",
+		ChecksumType: metal3v1alpha1.MD5,
+	}
+
+	cachedURL, err := waitForImage(t, cache, image)
+	assert.Empty(t, cachedURL)
+	assert.ErrorAs(t, err, &imageverify.VerificationError{})
+
+	// The image is not served, and the failure is reported until the
+	// download is retried
+	entries, _ := os.ReadDir(filepath.Join(cache.dir, imageKey(image)))
+	assert.Empty(t, entries)
+	_, pending, err := cache.Lookup(image)
+	assert.False(t, pending)
+	assert.Error(t, err)
+
+	// The image is cached apart for the hosts with the right checksum,
+	// and never served to the hosts expecting another one
+	good := image.DeepCopy()
+	good.Checksum = testImageChecksum
+	good.ChecksumType = metal3v1alpha1.SHA256
+	cachedURL, err = waitForImage(t, cache, good)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, cachedURL)
+	_, _, err = cache.Lookup(image)
+	assert.ErrorAs(t, err, &imageverify.VerificationError{})
+}
+
+func TestLookupNotCacheable(t *testing.T) {
+	liveISO := "live-iso"
+	testCases := []struct {
+		Scenario string
+		Image    *metal3v1alpha1.Image
+	}{
+		{
+			Scenario: "no checksum",
+			Image:    &metal3v1alpha1.Image{URL: "http://example.com/ubuntu-22.04.qcow2"},
+		},
+		{
+			Scenario: "live ISO",
+			Image:    &metal3v1alpha1.Image{URL: "http://example.com/live.iso", Checksum: testImageChecksum, DiskFormat: &liveISO},
+		},
+		{
+			Scenario: "OCI",
+			Image:    &metal3v1alpha1.Image{URL: "oci://quay.io/metal3/ubuntu@sha256:" + testImageChecksum},
+		},
+		{
+			Scenario: "local file",
+			Image:    &metal3v1alpha1.Image{URL: "file:///images/ubuntu-22.04.qcow2", Checksum: testImageChecksum},
+		},
+	}
+	cache := newTestCache(t, t.TempDir())
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			cachedURL, pending, err := cache.Lookup(tc.Image)
+			assert.NoError(t, err)
+			assert.False(t, pending)
+			assert.Empty(t, cachedURL)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	upstream, downloads := newUpstream(t)
+	dir := t.TempDir()
+	image := &metal3v1alpha1.Image{
+		URL:          upstream.URL + "/ubuntu-22.04.qcow2",
+		Checksum:     testImageChecksum,
+		ChecksumType: metal3v1alpha1.SHA256,
+	}
+	cachedURL, err := waitForImage(t, newTestCache(t, dir), image)
+	require.NoError(t, err)
+
+	// An interrupted download is removed
+	incomplete := filepath.Join(dir, cacheKey("http://example.com/other.qcow2", testImageChecksum, "sha256"))
+	require.NoError(t, os.Mkdir(incomplete, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(incomplete, tempPrefix+"1"), []byte("partial"), 0o600))
+
+	cache := newTestCache(t, dir)
+	again, pending, err := cache.Lookup(image)
+	assert.NoError(t, err)
+	assert.False(t, pending)
+	assert.Equal(t, cachedURL, again)
+	assert.EqualValues(t, 1, atomic.LoadInt32(downloads))
+	assert.NoDirExists(t, incomplete)
+
+	// A changed checksum downloads the image again
+	image.Checksum = "d41d8cd98f00b204e9800998ecf8427e"
+	image.ChecksumType = metal3v1alpha1.MD5
+	_, pending, _ = cache.Lookup(image)
+	assert.True(t, pending)
+}
+
+func TestEvict(t *testing.T) {
+	upstream, _ := newUpstream(t)
+	used := &metal3v1alpha1.Image{
+		URL:          upstream.URL + "/ubuntu-22.04.qcow2",
+		Checksum:     testImageChecksum,
+		ChecksumType: metal3v1alpha1.SHA256,
+	}
+	unused := used.DeepCopy()
+	unused.URL += "?version=2"
+	resolved := used.DeepCopy()
+	resolved.URL += "?version=3"
+	host := &metal3v1alpha1.BareMetalHost{
+		ObjectMeta: metav1.ObjectMeta{Name: "host", Namespace: "test-namespace"},
+		Spec:       metal3v1alpha1.BareMetalHostSpec{Image: used.DeepCopy()},
+	}
+	// The checksum of an image read from its ChecksumURL is in the status
+	resolvedHost := &metal3v1alpha1.BareMetalHost{
+		ObjectMeta: metav1.ObjectMeta{Name: "resolved", Namespace: "test-namespace"},
+		Spec: metal3v1alpha1.BareMetalHostSpec{Image: &metal3v1alpha1.Image{
+			URL:         resolved.URL,
+			ChecksumURL: resolved.URL + ".sha256sum",
+		}},
+		Status: metal3v1alpha1.BareMetalHostStatus{
+			Provisioning: metal3v1alpha1.ProvisionStatus{Image: *resolved.DeepCopy()},
+		},
+	}
+	cache := newTestCache(t, t.TempDir(), host, resolvedHost)
+
+	for _, image := range []*metal3v1alpha1.Image{used, unused, resolved} {
+		_, err := waitForImage(t, cache, image)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, cache.evict(context.TODO()))
+	assert.Contains(t, cache.entries, imageKey(used))
+	assert.Contains(t, cache.entries, imageKey(resolved))
+	assert.NotContains(t, cache.entries, imageKey(unused))
+	assert.DirExists(t, filepath.Join(cache.dir, imageKey(used)))
+	assert.NoDirExists(t, filepath.Join(cache.dir, imageKey(unused)))
+}
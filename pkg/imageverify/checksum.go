@@ -0,0 +1,200 @@
+package imageverify
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5" // #nosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+var (
+	fetchTimeout = time.Second * 30
+
+	// maxFetchSize limits the size of the checksum files and signatures
+	// read, which are small.
+	maxFetchSize int64 = 1 << 20
+)
+
+// checksumLengths holds the length of the hex-encoded checksums of the
+// supported algorithms, by which the algorithm is inferred when the
+// image does not give it.
+var checksumLengths = map[metal3v1alpha1.ChecksumType]int{
+	metal3v1alpha1.MD5:    32,
+	metal3v1alpha1.SHA256: 64,
+	metal3v1alpha1.SHA512: 128,
+}
+
+var (
+	hexRegexp = regexp.MustCompile(`^[A-Fa-f0-9]+$`)
+	// bsdLineRegexp matches the lines written by the BSD tools and by
+	// the --tag option of the GNU ones, such as
+	// SHA256 (image.qcow2) = <hex>.
+	bsdLineRegexp = regexp.MustCompile(`^([A-Za-z0-9-]+) \((.+)\) = ([A-Fa-f0-9]+)$`)
+)
+
+// VerificationError is returned when the content of a checksum file or
+// its signature does not verify the image, as opposed to failing to
+// fetch them.
+type VerificationError struct {
+	message string
+}
+
+func (e VerificationError) Error() string {
+	return e.message
+}
+
+func verificationErrorf(format string, args ...interface{}) VerificationError {
+	return VerificationError{message: fmt.Sprintf(format, args...)}
+}
+
+var httpClient = &http.Client{
+	Timeout: fetchTimeout,
+}
+
+// Fetch returns the content of a checksum file or of a signature at an
+// http or https URL.
+func Fetch(location string) ([]byte, error) {
+	resp, err := httpClient.Get(location)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch %s", location)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", location, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchSize+1))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", location)
+	}
+	if int64(len(data)) > maxFetchSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", location, maxFetchSize)
+	}
+	return data, nil
+}
+
+// imageName returns the name of the file at the image URL, as listed in
+// checksum files.
+func imageName(imageURL string) string {
+	name := imageURL
+	if u, err := url.Parse(imageURL); err == nil {
+		name = u.Path
+	}
+	return path.Base(name)
+}
+
+// SelectChecksum returns the checksum of the image at imageURL from a
+// checksum file, such as the SHA256SUMS files written by sha256sum, or
+// a file holding nothing but the checksum of the image. The line of the
+// image is selected by the name of the file at its URL. The algorithm
+// is inferred from the length of the checksum when checksumType is
+// empty.
+func SelectChecksum(checksums []byte, imageURL string, checksumType metal3v1alpha1.ChecksumType) (string, metal3v1alpha1.ChecksumType, error) {
+	name := imageName(imageURL)
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", verificationErrorf("invalid checksum file: %s", err)
+	}
+
+	if len(lines) == 1 && hexRegexp.MatchString(lines[0]) {
+		return checksumOfType(lines[0], checksumType)
+	}
+
+	for _, line := range lines {
+		if match := bsdLineRegexp.FindStringSubmatch(line); match != nil {
+			if path.Base(match[2]) != name {
+				continue
+			}
+			algorithm := metal3v1alpha1.ChecksumType(strings.ToLower(match[1]))
+			if checksumType != "" && algorithm != checksumType {
+				return "", "", verificationErrorf("the checksum of %s is a %s checksum, expected %s", name, algorithm, checksumType)
+			}
+			return checksumOfType(match[3], algorithm)
+		}
+
+		checksum, file, found := strings.Cut(line, " ")
+		if !found {
+			continue
+		}
+		// A star marks files read in binary mode
+		file = strings.TrimPrefix(strings.TrimLeft(file, " "), "*")
+		if path.Base(file) == name {
+			return checksumOfType(checksum, checksumType)
+		}
+	}
+	return "", "", verificationErrorf("no checksum for %s in the checksum file", name)
+}
+
+// checksumOfType checks a hex-encoded checksum against its algorithm,
+// or infers the algorithm from its length when not given.
+func checksumOfType(checksum string, checksumType metal3v1alpha1.ChecksumType) (string, metal3v1alpha1.ChecksumType, error) {
+	if !hexRegexp.MatchString(checksum) {
+		return "", "", verificationErrorf("invalid checksum %q", checksum)
+	}
+	checksum = strings.ToLower(checksum)
+
+	if checksumType == "" {
+		for algorithm, length := range checksumLengths {
+			if len(checksum) == length {
+				return checksum, algorithm, nil
+			}
+		}
+		return "", "", verificationErrorf("cannot infer the algorithm of a checksum of %d characters", len(checksum))
+	}
+
+	length, supported := checksumLengths[checksumType]
+	if !supported {
+		return "", "", verificationErrorf("unsupported checksum algorithm %s", checksumType)
+	}
+	if len(checksum) != length {
+		return "", "", verificationErrorf("invalid %s checksum %q", checksumType, checksum)
+	}
+	return checksum, checksumType, nil
+}
+
+// VerifyChecksum reads an image and returns a VerificationError if its
+// checksum does not match the expected one.
+func VerifyChecksum(image io.Reader, checksum string, checksumType metal3v1alpha1.ChecksumType) error {
+	var h hash.Hash
+	switch checksumType {
+	case metal3v1alpha1.MD5:
+		h = md5.New() // #nosec
+	case metal3v1alpha1.SHA256:
+		h = sha256.New()
+	case metal3v1alpha1.SHA512:
+		h = sha512.New()
+	default:
+		return verificationErrorf("unsupported checksum algorithm %s", checksumType)
+	}
+
+	if _, err := io.Copy(h, image); err != nil {
+		return errors.Wrap(err, "failed to read the image")
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, checksum) {
+		return verificationErrorf("the %s checksum of the image is %s, expected %s", checksumType, actual, checksum)
+	}
+	return nil
+}
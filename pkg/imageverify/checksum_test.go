@@ -0,0 +1,193 @@
+package imageverify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+const (
+	testSHA256 = "4a5c6b2e1d8f7e3a9b0c1d2e3f405162738495a6b7c8d9e0f1a2b3c4d5e6f708"
+	testMD5    = "d41d8cd98f00b204e9800998ecf8427e"
+)
+
+func TestSelectChecksum(t *testing.T) {
+	testCases := []struct {
+		Scenario     string
+		Checksums    string
+		ImageURL     string
+		ChecksumType metal3v1alpha1.ChecksumType
+		ExpectedSum  string
+		ExpectedType metal3v1alpha1.ChecksumType
+		ExpectedErr  string
+	}{
+		{
+			Scenario:     "sha256sum",
+			Checksums:    testSHA256 + "  ubuntu-22.04.qcow2\n" + strings.Repeat("b", 64) + "  centos-9.qcow2\n",
+			ImageURL:     "https://example.com/images/ubuntu-22.04.qcow2",
+			ExpectedSum:  testSHA256,
+			ExpectedType: metal3v1alpha1.SHA256,
+		},
+		{
+			Scenario:     "binary mode",
+			Checksums:    strings.Repeat("b", 64) + "  centos-9.qcow2\n" + testSHA256 + " *ubuntu-22.04.qcow2\n",
+			ImageURL:     "https://example.com/images/ubuntu-22.04.qcow2",
+			ExpectedSum:  testSHA256,
+			ExpectedType: metal3v1alpha1.SHA256,
+		},
+		{
+			Scenario:     "path and query",
+			Checksums:    "# comment\n\n" + testSHA256 + "  ./images/ubuntu-22.04.qcow2\n",
+			ImageURL:     "https://example.com/images/ubuntu-22.04.qcow2?version=1",
+			ExpectedSum:  testSHA256,
+			ExpectedType: metal3v1alpha1.SHA256,
+		},
+		{
+			Scenario:     "upper case",
+			Checksums:    strings.ToUpper(testSHA256) + "  ubuntu-22.04.qcow2\n",
+			ImageURL:     "https://example.com/ubuntu-22.04.qcow2",
+			ExpectedSum:  testSHA256,
+			ExpectedType: metal3v1alpha1.SHA256,
+		},
+		{
+			Scenario:     "explicit type",
+			Checksums:    testMD5 + "  ubuntu-22.04.qcow2\n",
+			ImageURL:     "https://example.com/ubuntu-22.04.qcow2",
+			ChecksumType: metal3v1alpha1.MD5,
+			ExpectedSum:  testMD5,
+			ExpectedType: metal3v1alpha1.MD5,
+		},
+		{
+			Scenario:     "type mismatch",
+			Checksums:    testSHA256 + "  ubuntu-22.04.qcow2\n",
+			ImageURL:     "https://example.com/ubuntu-22.04.qcow2",
+			ChecksumType: metal3v1alpha1.SHA512,
+			ExpectedErr:  "invalid sha512 checksum",
+		},
+		{
+			Scenario:     "BSD format",
+			Checksums:    "SHA512 (centos-9.qcow2) = " + strings.Repeat("c", 128) + "\nSHA256 (ubuntu-22.04.qcow2) = " + testSHA256 + "\n",
+			ImageURL:     "https://example.com/ubuntu-22.04.qcow2",
+			ExpectedSum:  testSHA256,
+			ExpectedType: metal3v1alpha1.SHA256,
+		},
+		{
+			Scenario:     "BSD format type mismatch",
+			Checksums:    "SHA256 (ubuntu-22.04.qcow2) = " + testSHA256 + "\n",
+			ImageURL:     "https://example.com/ubuntu-22.04.qcow2",
+			ChecksumType: metal3v1alpha1.MD5,
+			ExpectedErr:  "the checksum of ubuntu-22.04.qcow2 is a sha256 checksum, expected md5",
+		},
+		{
+			Scenario:     "bare checksum",
+			Checksums:    testSHA256 + "\n",
+			ImageURL:     "https://example.com/ubuntu-22.04.qcow2",
+			ExpectedSum:  testSHA256,
+			ExpectedType: metal3v1alpha1.SHA256,
+		},
+		{
+			Scenario:    "missing image",
+			Checksums:   testSHA256 + "  ubuntu-20.04.qcow2\n",
+			ImageURL:    "https://example.com/ubuntu-22.04.qcow2",
+			ExpectedErr: "no checksum for ubuntu-22.04.qcow2 in the checksum file",
+		},
+		{
+			Scenario:    "unknown length",
+			Checksums:   "abcdef  ubuntu-22.04.qcow2\n",
+			ImageURL:    "https://example.com/ubuntu-22.04.qcow2",
+			ExpectedErr: "cannot infer the algorithm of a checksum of 6 characters",
+		},
+		{
+			Scenario:    "not hex",
+			Checksums:   "<html>  ubuntu-22.04.qcow2\n",
+			ImageURL:    "https://example.com/ubuntu-22.04.qcow2",
+			ExpectedErr: "invalid checksum",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			checksum, checksumType, err := SelectChecksum([]byte(tc.Checksums), tc.ImageURL, tc.ChecksumType)
+			if tc.ExpectedErr != "" {
+				assert.ErrorContains(t, err, tc.ExpectedErr)
+				assert.ErrorAs(t, err, &VerificationError{})
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.ExpectedSum, checksum)
+			assert.Equal(t, tc.ExpectedType, checksumType)
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/SHA256SUMS":
+			w.Write([]byte(testSHA256 + "  ubuntu-22.04.qcow2\n"))
+		case "/large":
+			w.Write([]byte(strings.Repeat("a", int(maxFetchSize)+1)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	data, err := Fetch(server.URL + "/SHA256SUMS")
+	require.NoError(t, err)
+	assert.Equal(t, testSHA256+"  ubuntu-22.04.qcow2\n", string(data))
+
+	_, err = Fetch(server.URL + "/missing")
+	assert.ErrorContains(t, err, "404 Not Found")
+
+	_, err = Fetch(server.URL + "/large")
+	assert.ErrorContains(t, err, "is larger than")
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	testCases := []struct {
+		Scenario     string
+		Checksum     string
+		ChecksumType metal3v1alpha1.ChecksumType
+		ExpectedErr  string
+	}{
+		{
+			Scenario:     "md5",
+			Checksum:     testMD5,
+			ChecksumType: metal3v1alpha1.MD5,
+		},
+		{
+			Scenario:     "sha256",
+			Checksum:     "E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855",
+			ChecksumType: metal3v1alpha1.SHA256,
+		},
+		{
+			Scenario:     "mismatch",
+			Checksum:     testSHA256,
+			ChecksumType: metal3v1alpha1.SHA256,
+			ExpectedErr:  "the sha256 checksum of the image is e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855, expected " + testSHA256,
+		},
+		{
+			Scenario:     "unknown algorithm",
+			Checksum:     testSHA256,
+			ChecksumType: "sha1",
+			ExpectedErr:  "unsupported checksum algorithm sha1",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			err := VerifyChecksum(strings.NewReader(""), tc.Checksum, tc.ChecksumType)
+			if tc.ExpectedErr != "" {
+				assert.EqualError(t, err, tc.ExpectedErr)
+				assert.ErrorAs(t, err, &VerificationError{})
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
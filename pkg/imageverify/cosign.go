@@ -0,0 +1,50 @@
+package imageverify
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+)
+
+// verifyCosign verifies a signature created by cosign sign-blob with a
+// key pair: the base64 encoding of the signature of the SHA-256 digest
+// of the data, or of the data itself for Ed25519 keys. The public key
+// is the PEM-encoded key written by cosign generate-key-pair.
+func verifyCosign(data, signature, publicKey []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	if err != nil {
+		return verificationErrorf("invalid cosign signature: %s", err)
+	}
+
+	block, _ := pem.Decode(publicKey)
+	if block == nil {
+		return verificationErrorf("the public key is not PEM-encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return verificationErrorf("invalid public key: %s", err)
+	}
+
+	digest := sha256.Sum256(data)
+	valid := false
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, digest[:], sig)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, data, sig)
+	default:
+		return verificationErrorf("unsupported public key type %T", key)
+	}
+	if !valid {
+		return verificationErrorf("invalid cosign signature")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package imageverify
+
+import (
+	"bytes"
+	"crypto"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// gpgHashes holds the hash algorithms accepted in signatures. SHA-1
+// and MD5 signatures are rejected.
+var gpgHashes = []crypto.Hash{
+	crypto.SHA224,
+	crypto.SHA256,
+	crypto.SHA384,
+	crypto.SHA512,
+}
+
+// unarmor returns a reader of the binary content of OpenPGP data,
+// which may be ASCII armored.
+func unarmor(data []byte) (io.Reader, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN PGP")) {
+		return bytes.NewReader(data), nil
+	}
+	block, err := armor.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, verificationErrorf("invalid OpenPGP armor: %s", err)
+	}
+	return block.Body, nil
+}
+
+// verifyGPG verifies a detached OpenPGP signature of data with an
+// OpenPGP public key, either of which may be ASCII armored.
+func verifyGPG(data, signature, publicKey []byte) error {
+	keyReader, err := unarmor(publicKey)
+	if err != nil {
+		return err
+	}
+	keyring, err := openpgp.ReadKeyRing(keyReader)
+	if err != nil {
+		return verificationErrorf("the public key is not an OpenPGP message: %s", err)
+	}
+
+	signatureReader, err := unarmor(signature)
+	if err != nil {
+		return err
+	}
+	_, _, err = openpgp.VerifyDetachedSignatureAndHash(keyring,
+		bytes.NewReader(data), signatureReader, gpgHashes, nil)
+	if err != nil {
+		return verificationErrorf("no valid OpenPGP signature by the public key: %s", err)
+	}
+	return nil
+}
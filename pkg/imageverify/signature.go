@@ -0,0 +1,21 @@
+// Package imageverify reads the checksum of images from the checksum
+// files published with them, and verifies the detached signatures of
+// those files.
+package imageverify
+
+import (
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+// VerifySignature verifies the detached signature of data with the
+// public key. A VerificationError is returned when the signature does
+// not match.
+func VerifySignature(data, signature []byte, signatureType metal3v1alpha1.ImageSignatureType, publicKey []byte) error {
+	switch signatureType {
+	case metal3v1alpha1.GPGSignature:
+		return verifyGPG(data, signature, publicKey)
+	case metal3v1alpha1.CosignSignature:
+		return verifyCosign(data, signature, publicKey)
+	}
+	return verificationErrorf("unknown signature type %q", signatureType)
+}
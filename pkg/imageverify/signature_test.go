@@ -0,0 +1,129 @@
+package imageverify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+// The keys and signatures in testdata were created with gpg
+// --detach-sign and with openssl, which signs like cosign sign-blob.
+
+func readTestData(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
+
+func TestVerifySignature(t *testing.T) {
+	testCases := []struct {
+		Scenario      string
+		Signature     string
+		SignatureType metal3v1alpha1.ImageSignatureType
+		PublicKey     string
+		Tampered      bool
+		ExpectedErr   string
+	}{
+		{
+			Scenario:      "gpg rsa armored",
+			Signature:     "SHA256SUMS.rsa.asc",
+			SignatureType: metal3v1alpha1.GPGSignature,
+			PublicKey:     "gpg-rsa.pub",
+		},
+		{
+			Scenario:      "gpg ed25519 binary",
+			Signature:     "SHA256SUMS.ed25519.sig",
+			SignatureType: metal3v1alpha1.GPGSignature,
+			PublicKey:     "gpg-ed25519.pub",
+		},
+		{
+			Scenario:      "gpg ecdsa text mode sha384",
+			Signature:     "SHA256SUMS.ecdsa.asc",
+			SignatureType: metal3v1alpha1.GPGSignature,
+			PublicKey:     "gpg-ecdsa.pub",
+		},
+		{
+			Scenario:      "gpg tampered",
+			Signature:     "SHA256SUMS.rsa.asc",
+			SignatureType: metal3v1alpha1.GPGSignature,
+			PublicKey:     "gpg-rsa.pub",
+			Tampered:      true,
+			ExpectedErr:   "no valid OpenPGP signature by the public key",
+		},
+		{
+			Scenario:      "gpg other key",
+			Signature:     "SHA256SUMS.rsa.asc",
+			SignatureType: metal3v1alpha1.GPGSignature,
+			PublicKey:     "gpg-ed25519.pub",
+			ExpectedErr:   "no valid OpenPGP signature by the public key",
+		},
+		{
+			Scenario:      "gpg with cosign key",
+			Signature:     "SHA256SUMS.rsa.asc",
+			SignatureType: metal3v1alpha1.GPGSignature,
+			PublicKey:     "cosign.pub",
+			ExpectedErr:   "not an OpenPGP message",
+		},
+		{
+			Scenario:      "cosign ecdsa",
+			Signature:     "SHA256SUMS.cosign.sig",
+			SignatureType: metal3v1alpha1.CosignSignature,
+			PublicKey:     "cosign.pub",
+		},
+		{
+			Scenario:      "cosign ed25519",
+			Signature:     "SHA256SUMS.cosign-ed25519.sig",
+			SignatureType: metal3v1alpha1.CosignSignature,
+			PublicKey:     "cosign-ed25519.pub",
+		},
+		{
+			Scenario:      "cosign tampered",
+			Signature:     "SHA256SUMS.cosign.sig",
+			SignatureType: metal3v1alpha1.CosignSignature,
+			PublicKey:     "cosign.pub",
+			Tampered:      true,
+			ExpectedErr:   "invalid cosign signature",
+		},
+		{
+			Scenario:      "cosign other key",
+			Signature:     "SHA256SUMS.cosign.sig",
+			SignatureType: metal3v1alpha1.CosignSignature,
+			PublicKey:     "cosign-ed25519.pub",
+			ExpectedErr:   "invalid cosign signature",
+		},
+		{
+			Scenario:      "cosign with gpg key",
+			Signature:     "SHA256SUMS.cosign.sig",
+			SignatureType: metal3v1alpha1.CosignSignature,
+			PublicKey:     "gpg-rsa.pub",
+			ExpectedErr:   "the public key is not PEM-encoded",
+		},
+		{
+			Scenario:      "unknown type",
+			Signature:     "SHA256SUMS.cosign.sig",
+			SignatureType: "x509",
+			PublicKey:     "cosign.pub",
+			ExpectedErr:   "unknown signature type",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			data := readTestData(t, "SHA256SUMS")
+			if tc.Tampered {
+				data[0] ^= 1
+			}
+			err := VerifySignature(data, readTestData(t, tc.Signature), tc.SignatureType, readTestData(t, tc.PublicKey))
+			if tc.ExpectedErr != "" {
+				assert.ErrorContains(t, err, tc.ExpectedErr)
+				assert.ErrorAs(t, err, &VerificationError{})
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,304 @@
+// Package networkdata renders the network configuration of a host into
+// the OpenStack network_data.json format read by cloud-init and
+// Ignition from the config drive.
+package networkdata
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+// ConfigError is returned when the network configuration of a host can
+// not be rendered, such as when it references NICs the host does not
+// have.
+type ConfigError struct {
+	message string
+}
+
+func (e ConfigError) Error() string {
+	return e.message
+}
+
+func configErrorf(format string, args ...interface{}) ConfigError {
+	return ConfigError{message: fmt.Sprintf(format, args...)}
+}
+
+// networkData is the content of network_data.json.
+type networkData struct {
+	Links    []link    `json:"links"`
+	Networks []network `json:"networks"`
+	Services []service `json:"services,omitempty"`
+}
+
+type link struct {
+	ID                 string   `json:"id"`
+	Type               string   `json:"type"`
+	EthernetMACAddress string   `json:"ethernet_mac_address,omitempty"`
+	MTU                int      `json:"mtu,omitempty"`
+	BondLinks          []string `json:"bond_links,omitempty"`
+	BondMode           string   `json:"bond_mode,omitempty"`
+	BondMIIMon         int      `json:"bond_miimon,omitempty"`
+	BondXmitHashPolicy string   `json:"bond_xmit_hash_policy,omitempty"`
+	VLANLink           string   `json:"vlan_link,omitempty"`
+	VLANID             int      `json:"vlan_id,omitempty"`
+	VLANMACAddress     string   `json:"vlan_mac_address,omitempty"`
+}
+
+type network struct {
+	ID        string   `json:"id"`
+	Type      string   `json:"type"`
+	Link      string   `json:"link"`
+	NetworkID string   `json:"network_id"`
+	IPAddress string   `json:"ip_address,omitempty"`
+	Netmask   string   `json:"netmask,omitempty"`
+	Routes    []route  `json:"routes,omitempty"`
+	DNSSearch []string `json:"dns_search,omitempty"`
+}
+
+type route struct {
+	Network string `json:"network"`
+	Netmask string `json:"netmask"`
+	Gateway string `json:"gateway"`
+}
+
+type service struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+}
+
+// PoolAddress is an address allocated to the host from an IP pool.
+type PoolAddress struct {
+	// Address is the allocated address in CIDR notation.
+	Address string
+	// Gateway is the default gateway of the pool.
+	Gateway string
+	// DNSServers are the DNS servers of the pool.
+	DNSServers []string
+}
+
+// networkTypes maps the dynamic address types to the types of the
+// networks in network_data.json.
+var networkTypes = map[metal3v1alpha1.NetworkAddressType]string{
+	metal3v1alpha1.DHCP4Address: "ipv4_dhcp",
+	metal3v1alpha1.DHCP6Address: "ipv6_dhcp",
+	metal3v1alpha1.SLAACAddress: "ipv6_slaac",
+}
+
+// Render returns the network_data.json of a network configuration. The
+// MAC addresses of the links given by NIC name are read from the NICs
+// found by inspection, which the links given by MAC address must also
+// be among once the host has been inspected. The addresses allocated
+// from IP pools are given by pool name, and the gateway of a pool is
+// the default route of its address unless the configuration has one.
+func Render(config *metal3v1alpha1.NetworkConfig, nics []metal3v1alpha1.NIC, poolAddresses map[string]PoolAddress) ([]byte, error) {
+	data := networkData{
+		Links:    []link{},
+		Networks: []network{},
+	}
+	// macs holds the MAC address of each interface, which bonds and
+	// VLANs inherit
+	macs := map[string]string{}
+
+	for _, l := range config.Links {
+		mac, err := linkMAC(l, nics)
+		if err != nil {
+			return nil, err
+		}
+		macs[l.Name] = mac
+		data.Links = append(data.Links, link{
+			ID:                 l.Name,
+			Type:               "phy",
+			EthernetMACAddress: mac,
+			MTU:                l.MTU,
+		})
+	}
+
+	for _, bond := range config.Bonds {
+		if len(bond.Links) == 0 {
+			return nil, configErrorf("network bond %q has no links", bond.Name)
+		}
+		for _, l := range bond.Links {
+			if _, exists := macs[l]; !exists {
+				return nil, configErrorf("network bond %q references unknown link %q", bond.Name, l)
+			}
+		}
+		mac := strings.ToLower(bond.MACAddress)
+		if mac == "" {
+			mac = macs[bond.Links[0]]
+		}
+		macs[bond.Name] = mac
+		data.Links = append(data.Links, link{
+			ID:                 bond.Name,
+			Type:               "bond",
+			EthernetMACAddress: mac,
+			MTU:                bond.MTU,
+			BondLinks:          bond.Links,
+			BondMode:           string(bond.Mode),
+			BondMIIMon:         bond.MIIMon,
+			BondXmitHashPolicy: bond.XmitHashPolicy,
+		})
+	}
+
+	for _, vlan := range config.VLANs {
+		parent, exists := macs[vlan.Link]
+		if !exists {
+			return nil, configErrorf("network vlan %q references unknown link or bond %q", vlan.Name, vlan.Link)
+		}
+		mac := strings.ToLower(vlan.MACAddress)
+		if mac == "" {
+			mac = parent
+		}
+		macs[vlan.Name] = mac
+		data.Links = append(data.Links, link{
+			ID:             vlan.Name,
+			Type:           "vlan",
+			MTU:            vlan.MTU,
+			VLANLink:       vlan.Link,
+			VLANID:         vlan.ID,
+			VLANMACAddress: mac,
+		})
+	}
+
+	var search []string
+	if config.DNS != nil {
+		search = config.DNS.Search
+	}
+	routed := make([]bool, len(config.Routes))
+	// defaultRoutes records whether the configuration has a default
+	// route, by whether it is an IPv4 one
+	defaultRoutes := map[bool]bool{}
+	for _, r := range config.Routes {
+		if _, destination, err := net.ParseCIDR(r.Destination); err == nil {
+			if ones, _ := destination.Mask.Size(); ones == 0 {
+				defaultRoutes[destination.IP.To4() != nil] = true
+			}
+		}
+	}
+	var dnsServers []string
+	if config.DNS != nil {
+		dnsServers = append(dnsServers, config.DNS.Nameservers...)
+	}
+	for i, address := range config.Addresses {
+		if _, exists := macs[address.Interface]; !exists {
+			return nil, configErrorf("network address references unknown interface %q", address.Interface)
+		}
+		n := network{
+			ID:        fmt.Sprintf("network%d", i),
+			Link:      address.Interface,
+			NetworkID: fmt.Sprintf("network%d", i),
+			DNSSearch: search,
+		}
+
+		if address.Type != "" && address.Type != metal3v1alpha1.StaticAddress {
+			n.Type = networkTypes[address.Type]
+			if n.Type == "" {
+				return nil, configErrorf("unknown network address type %q", address.Type)
+			}
+			data.Networks = append(data.Networks, n)
+			continue
+		}
+
+		cidr := address.Address
+		var pool *PoolAddress
+		if address.IPPool != "" {
+			poolAddress, allocated := poolAddresses[address.IPPool]
+			if !allocated {
+				return nil, configErrorf("no address of IP pool %q is allocated to the host", address.IPPool)
+			}
+			cidr = poolAddress.Address
+			pool = &poolAddress
+		}
+		ip, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, configErrorf("static network address %q of interface %q is not in CIDR notation", cidr, address.Interface)
+		}
+		ipv4 := ip.To4() != nil
+		n.Type = "ipv6"
+		if ipv4 {
+			n.Type = "ipv4"
+		}
+		n.IPAddress = ip.String()
+		n.Netmask = net.IP(subnet.Mask).String()
+		for j, r := range config.Routes {
+			gateway := net.ParseIP(r.Gateway)
+			if routed[j] || gateway == nil || !subnet.Contains(gateway) {
+				continue
+			}
+			_, destination, err := net.ParseCIDR(r.Destination)
+			if err != nil {
+				return nil, configErrorf("network route destination %q is not in CIDR notation", r.Destination)
+			}
+			n.Routes = append(n.Routes, route{
+				Network: destination.IP.String(),
+				Netmask: net.IP(destination.Mask).String(),
+				Gateway: gateway.String(),
+			})
+			routed[j] = true
+		}
+		if pool != nil {
+			if gateway := net.ParseIP(pool.Gateway); gateway != nil && !defaultRoutes[ipv4] {
+				zero := net.IPv6zero.String()
+				if ipv4 {
+					zero = net.IPv4zero.String()
+				}
+				n.Routes = append(n.Routes, route{Network: zero, Netmask: zero, Gateway: gateway.String()})
+				defaultRoutes[ipv4] = true
+			}
+			dnsServers = append(dnsServers, pool.DNSServers...)
+		}
+		data.Networks = append(data.Networks, n)
+	}
+	for j, r := range config.Routes {
+		if !routed[j] {
+			return nil, configErrorf("network route gateway %s is not in the subnet of a static address", r.Gateway)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, nameserver := range dnsServers {
+		if !seen[nameserver] {
+			data.Services = append(data.Services, service{Type: "dns", Address: nameserver})
+			seen[nameserver] = true
+		}
+	}
+
+	return json.Marshal(data)
+}
+
+// linkMAC returns the MAC address of a link, from the NIC of the same
+// name when the link is given by NIC name.
+func linkMAC(l metal3v1alpha1.NetworkLink, nics []metal3v1alpha1.NIC) (string, error) {
+	mac := strings.ToLower(l.MACAddress)
+	if l.NIC == "" {
+		if mac == "" {
+			return "", configErrorf("network link %q has no nic or macAddress", l.Name)
+		}
+		if len(nics) == 0 {
+			return mac, nil
+		}
+		for _, nic := range nics {
+			if strings.EqualFold(nic.MAC, mac) {
+				return mac, nil
+			}
+		}
+		return "", configErrorf("network link %q references MAC address %s, which is not the address of a NIC of the host", l.Name, mac)
+	}
+
+	if len(nics) == 0 {
+		return "", configErrorf("network link %q references NIC %q, but the NICs of the host have not been inspected", l.Name, l.NIC)
+	}
+	for _, nic := range nics {
+		if nic.Name != l.NIC {
+			continue
+		}
+		if mac != "" && !strings.EqualFold(nic.MAC, mac) {
+			return "", configErrorf("network link %q references NIC %q, whose MAC address is %s, not %s", l.Name, l.NIC, strings.ToLower(nic.MAC), mac)
+		}
+		return strings.ToLower(nic.MAC), nil
+	}
+	return "", configErrorf("network link %q references NIC %q, which the host does not have", l.Name, l.NIC)
+}
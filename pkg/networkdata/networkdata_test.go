@@ -0,0 +1,217 @@
+package networkdata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+var testNICs = []metal3v1alpha1.NIC{
+	{Name: "eno1", MAC: "00:11:22:33:44:55", IP: "192.168.111.20"},
+	{Name: "eno1", MAC: "00:11:22:33:44:55", IP: "fd2e:6f44:5dd8::20"},
+	{Name: "eno2", MAC: "00:11:22:33:44:56"},
+}
+
+func TestRender(t *testing.T) {
+	testCases := []struct {
+		Scenario      string
+		Config        metal3v1alpha1.NetworkConfig
+		NICs          []metal3v1alpha1.NIC
+		PoolAddresses map[string]PoolAddress
+		Expected      string
+	}{
+		{
+			Scenario: "dhcp",
+			Config: metal3v1alpha1.NetworkConfig{
+				Links:     []metal3v1alpha1.NetworkLink{{Name: "eth0", NIC: "eno1"}},
+				Addresses: []metal3v1alpha1.NetworkAddress{{Interface: "eth0", Type: metal3v1alpha1.DHCP4Address}},
+			},
+			NICs: testNICs,
+			Expected: `{
+				"links": [{"id": "eth0", "type": "phy", "ethernet_mac_address": "00:11:22:33:44:55"}],
+				"networks": [{"id": "network0", "type": "ipv4_dhcp", "link": "eth0", "network_id": "network0"}]
+			}`,
+		},
+		{
+			Scenario: "mac address before inspection",
+			Config: metal3v1alpha1.NetworkConfig{
+				Links:     []metal3v1alpha1.NetworkLink{{Name: "eth0", MACAddress: "00:11:22:33:44:AA", MTU: 9000}},
+				Addresses: []metal3v1alpha1.NetworkAddress{{Interface: "eth0", Type: metal3v1alpha1.SLAACAddress}},
+			},
+			Expected: `{
+				"links": [{"id": "eth0", "type": "phy", "ethernet_mac_address": "00:11:22:33:44:aa", "mtu": 9000}],
+				"networks": [{"id": "network0", "type": "ipv6_slaac", "link": "eth0", "network_id": "network0"}]
+			}`,
+		},
+		{
+			Scenario: "bond, vlan and static addresses",
+			Config: metal3v1alpha1.NetworkConfig{
+				Links: []metal3v1alpha1.NetworkLink{
+					{Name: "eth0", NIC: "eno1"},
+					{Name: "eth1", MACAddress: "00:11:22:33:44:56"},
+				},
+				Bonds: []metal3v1alpha1.NetworkBond{{
+					Name:           "bond0",
+					Links:          []string{"eth0", "eth1"},
+					Mode:           "802.3ad",
+					MIIMon:         100,
+					XmitHashPolicy: "layer3+4",
+				}},
+				VLANs: []metal3v1alpha1.NetworkVLAN{{Name: "bond0.100", Link: "bond0", ID: 100}},
+				Addresses: []metal3v1alpha1.NetworkAddress{
+					{Interface: "bond0.100", Address: "192.168.111.20/24"},
+					{Interface: "bond0.100", Type: metal3v1alpha1.StaticAddress, Address: "fd2e:6f44:5dd8::20/64"},
+				},
+				Routes: []metal3v1alpha1.NetworkRoute{
+					{Destination: "0.0.0.0/0", Gateway: "192.168.111.1"},
+					{Destination: "10.0.0.0/8", Gateway: "192.168.111.254"},
+					{Destination: "::/0", Gateway: "fd2e:6f44:5dd8::1"},
+				},
+				DNS: &metal3v1alpha1.NetworkDNS{
+					Nameservers: []string{"192.168.111.1"},
+					Search:      []string{"example.com"},
+				},
+			},
+			NICs: testNICs,
+			Expected: `{
+				"links": [
+					{"id": "eth0", "type": "phy", "ethernet_mac_address": "00:11:22:33:44:55"},
+					{"id": "eth1", "type": "phy", "ethernet_mac_address": "00:11:22:33:44:56"},
+					{"id": "bond0", "type": "bond", "ethernet_mac_address": "00:11:22:33:44:55",
+					 "bond_links": ["eth0", "eth1"], "bond_mode": "802.3ad", "bond_miimon": 100,
+					 "bond_xmit_hash_policy": "layer3+4"},
+					{"id": "bond0.100", "type": "vlan", "vlan_link": "bond0", "vlan_id": 100,
+					 "vlan_mac_address": "00:11:22:33:44:55"}
+				],
+				"networks": [
+					{"id": "network0", "type": "ipv4", "link": "bond0.100", "network_id": "network0",
+					 "ip_address": "192.168.111.20", "netmask": "255.255.255.0",
+					 "routes": [
+						{"network": "0.0.0.0", "netmask": "0.0.0.0", "gateway": "192.168.111.1"},
+						{"network": "10.0.0.0", "netmask": "255.0.0.0", "gateway": "192.168.111.254"}
+					 ],
+					 "dns_search": ["example.com"]},
+					{"id": "network1", "type": "ipv6", "link": "bond0.100", "network_id": "network1",
+					 "ip_address": "fd2e:6f44:5dd8::20", "netmask": "ffff:ffff:ffff:ffff::",
+					 "routes": [{"network": "::", "netmask": "::", "gateway": "fd2e:6f44:5dd8::1"}],
+					 "dns_search": ["example.com"]}
+				],
+				"services": [{"type": "dns", "address": "192.168.111.1"}]
+			}`,
+		},
+		{
+			Scenario: "ip pools",
+			Config: metal3v1alpha1.NetworkConfig{
+				Links: []metal3v1alpha1.NetworkLink{
+					{Name: "eth0", NIC: "eno1"},
+					{Name: "eth1", NIC: "eno2"},
+				},
+				Addresses: []metal3v1alpha1.NetworkAddress{
+					{Interface: "eth0", IPPool: "provisioning"},
+					{Interface: "eth1", IPPool: "storage"},
+				},
+				DNS: &metal3v1alpha1.NetworkDNS{Nameservers: []string{"192.168.111.1"}},
+			},
+			NICs: testNICs,
+			PoolAddresses: map[string]PoolAddress{
+				"provisioning": {Address: "192.168.111.20/24", Gateway: "192.168.111.1", DNSServers: []string{"192.168.111.1", "8.8.8.8"}},
+				"storage":      {Address: "10.0.0.20/16", Gateway: "10.0.0.1"},
+			},
+			Expected: `{
+				"links": [
+					{"id": "eth0", "type": "phy", "ethernet_mac_address": "00:11:22:33:44:55"},
+					{"id": "eth1", "type": "phy", "ethernet_mac_address": "00:11:22:33:44:56"}
+				],
+				"networks": [
+					{"id": "network0", "type": "ipv4", "link": "eth0", "network_id": "network0",
+					 "ip_address": "192.168.111.20", "netmask": "255.255.255.0",
+					 "routes": [{"network": "0.0.0.0", "netmask": "0.0.0.0", "gateway": "192.168.111.1"}]},
+					{"id": "network1", "type": "ipv4", "link": "eth1", "network_id": "network1",
+					 "ip_address": "10.0.0.20", "netmask": "255.255.0.0"}
+				],
+				"services": [
+					{"type": "dns", "address": "192.168.111.1"},
+					{"type": "dns", "address": "8.8.8.8"}
+				]
+			}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			data, err := Render(&tc.Config, tc.NICs, tc.PoolAddresses)
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.Expected, string(data))
+		})
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	testCases := []struct {
+		Scenario      string
+		Config        metal3v1alpha1.NetworkConfig
+		NICs          []metal3v1alpha1.NIC
+		PoolAddresses map[string]PoolAddress
+		Expected      string
+	}{
+		{
+			Scenario: "not inspected",
+			Config: metal3v1alpha1.NetworkConfig{
+				Links: []metal3v1alpha1.NetworkLink{{Name: "eth0", NIC: "eno1"}},
+			},
+			Expected: `network link "eth0" references NIC "eno1", but the NICs of the host have not been inspected`,
+		},
+		{
+			Scenario: "unknown NIC",
+			Config: metal3v1alpha1.NetworkConfig{
+				Links: []metal3v1alpha1.NetworkLink{{Name: "eth0", NIC: "eno3"}},
+			},
+			NICs:     testNICs,
+			Expected: `network link "eth0" references NIC "eno3", which the host does not have`,
+		},
+		{
+			Scenario: "unknown MAC address",
+			Config: metal3v1alpha1.NetworkConfig{
+				Links: []metal3v1alpha1.NetworkLink{{Name: "eth0", MACAddress: "00:11:22:33:44:99"}},
+			},
+			NICs:     testNICs,
+			Expected: `network link "eth0" references MAC address 00:11:22:33:44:99, which is not the address of a NIC of the host`,
+		},
+		{
+			Scenario: "NIC and MAC address mismatch",
+			Config: metal3v1alpha1.NetworkConfig{
+				Links: []metal3v1alpha1.NetworkLink{{Name: "eth0", NIC: "eno1", MACAddress: "00:11:22:33:44:56"}},
+			},
+			NICs:     testNICs,
+			Expected: `network link "eth0" references NIC "eno1", whose MAC address is 00:11:22:33:44:55, not 00:11:22:33:44:56`,
+		},
+		{
+			Scenario: "unreachable gateway",
+			Config: metal3v1alpha1.NetworkConfig{
+				Links:     []metal3v1alpha1.NetworkLink{{Name: "eth0", NIC: "eno1"}},
+				Addresses: []metal3v1alpha1.NetworkAddress{{Interface: "eth0", Address: "192.168.111.20/24"}},
+				Routes:    []metal3v1alpha1.NetworkRoute{{Destination: "0.0.0.0/0", Gateway: "10.0.0.1"}},
+			},
+			NICs:     testNICs,
+			Expected: "network route gateway 10.0.0.1 is not in the subnet of a static address",
+		},
+		{
+			Scenario: "no pool address",
+			Config: metal3v1alpha1.NetworkConfig{
+				Links:     []metal3v1alpha1.NetworkLink{{Name: "eth0", NIC: "eno1"}},
+				Addresses: []metal3v1alpha1.NetworkAddress{{Interface: "eth0", IPPool: "provisioning"}},
+			},
+			NICs:     testNICs,
+			Expected: `no address of IP pool "provisioning" is allocated to the host`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			_, err := Render(&tc.Config, tc.NICs, tc.PoolAddresses)
+			assert.EqualError(t, err, tc.Expected)
+			assert.ErrorAs(t, err, &ConfigError{})
+		})
+	}
+}
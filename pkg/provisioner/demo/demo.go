@@ -216,6 +216,13 @@ func (p *demoProvisioner) Prepare(data provisioner.PrepareData, unprepared bool,
 	return
 }
 
+// Service applies new configuration to a provisioned host
+func (p *demoProvisioner) Service(data provisioner.PrepareData, unprepared bool, restartOnFailure bool) (result provisioner.Result, started bool, err error) {
+	p.log.Info("servicing host")
+	started = unprepared
+	return
+}
+
 // Adopt notifies the provisioner that the state machine believes the host
 // to be currently provisioned, and that it should be managed as such.
 func (p *demoProvisioner) Adopt(data provisioner.AdoptData, restartOnFailure bool) (result provisioner.Result, err error) {
@@ -307,6 +314,12 @@ func (p *demoProvisioner) Detach() (result provisioner.Result, err error) {
 	return result, nil
 }
 
+// Abort stops the operation currently waiting on the host.
+func (p *demoProvisioner) Abort() (result provisioner.Result, err error) {
+	p.log.Info("aborting operation")
+	return result, nil
+}
+
 // PowerOn ensures the server is powered on independently of any image
 // provisioning operation.
 func (p *demoProvisioner) PowerOn(force bool) (result provisioner.Result, err error) {
@@ -367,3 +380,15 @@ func (p *demoProvisioner) AddBMCEventSubscriptionForNode(subscription *metal3v1a
 func (p *demoProvisioner) RemoveBMCEventSubscriptionForNode(subscription metal3v1alpha1.BMCEventSubscription) (result provisioner.Result, err error) {
 	return result, nil
 }
+
+func (p *demoProvisioner) GetFirmwareComponents() (versions map[string]string, err error) {
+	return nil, nil
+}
+
+func (p *demoProvisioner) CreateBMCAccount(creds bmc.Credentials) (result provisioner.Result, err error) {
+	return result, nil
+}
+
+func (p *demoProvisioner) DisableBMCAccount(username string) (result provisioner.Result, err error) {
+	return result, nil
+}
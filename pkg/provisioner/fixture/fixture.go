@@ -67,11 +67,26 @@ type Fixture struct {
 	adopted bool
 	// state to manage provisioning
 	image metal3v1alpha1.Image
+	// the URL of the image cache the image was provisioned from
+	CachedImageURL string
 	// state to manage power
 	poweredOn bool
+	// the health returned by UpdateHardwareState
+	Health []metal3v1alpha1.ComponentHealth
 
 	validateError string
 
+	// the passwords of the enabled BMC accounts created by
+	// CreateBMCAccount, by user name
+	BMCAccounts map[string]string
+	// the user names of the accounts disabled by DisableBMCAccount
+	DisabledBMCAccounts []string
+	// the error returned by CreateBMCAccount
+	createAccountError string
+
+	// the versions returned by GetFirmwareComponents
+	FirmwareVersions map[string]string
+
 	customDeploy *metal3v1alpha1.CustomDeploy
 }
 
@@ -91,6 +106,11 @@ func (f *Fixture) SetValidateError(message string) {
 	f.validateError = message
 }
 
+// SetCreateAccountError makes CreateBMCAccount fail with the message
+func (f *Fixture) SetCreateAccountError(message string) {
+	f.createAccountError = message
+}
+
 func (p *fixtureProvisioner) HasCapacity() (result bool, err error) {
 	return true, nil
 }
@@ -186,6 +206,7 @@ func (p *fixtureProvisioner) InspectHardware(data provisioner.InspectData, resta
 // reading from a cache.
 func (p *fixtureProvisioner) UpdateHardwareState() (hwState provisioner.HardwareState, err error) {
 	hwState.PoweredOn = &p.state.poweredOn
+	hwState.Health = p.state.Health
 	p.log.Info("updating hardware state")
 	return
 }
@@ -197,6 +218,13 @@ func (p *fixtureProvisioner) Prepare(data provisioner.PrepareData, unprepared bo
 	return
 }
 
+// Service applies new configuration to a provisioned host
+func (p *fixtureProvisioner) Service(data provisioner.PrepareData, unprepared bool, restartOnFailure bool) (result provisioner.Result, started bool, err error) {
+	p.log.Info("servicing host")
+	started = unprepared
+	return
+}
+
 // Adopt notifies the provisioner that the state machine believes the host
 // to be currently provisioned, and that it should be managed as such.
 func (p *fixtureProvisioner) Adopt(data provisioner.AdoptData, restartOnFailure bool) (result provisioner.Result, err error) {
@@ -228,6 +256,7 @@ func (p *fixtureProvisioner) Provision(data provisioner.ProvisionData, forceRebo
 		p.publisher("ProvisioningComplete", "Image provisioning completed")
 		p.log.Info("moving to done")
 		p.state.image = data.Image
+		p.state.CachedImageURL = data.CachedImageURL
 		result.Dirty = true
 		result.RequeueAfter = provisionRequeueDelay
 	}
@@ -293,6 +322,12 @@ func (p *fixtureProvisioner) Detach() (result provisioner.Result, err error) {
 	return p.Delete()
 }
 
+// Abort stops the operation currently waiting on the host.
+func (p *fixtureProvisioner) Abort() (result provisioner.Result, err error) {
+	p.log.Info("aborting operation")
+	return result, nil
+}
+
 // PowerOn ensures the server is powered on independently of any image
 // provisioning operation.
 func (p *fixtureProvisioner) PowerOn(force bool) (result provisioner.Result, err error) {
@@ -348,3 +383,25 @@ func (p *fixtureProvisioner) AddBMCEventSubscriptionForNode(subscription *metal3
 func (p *fixtureProvisioner) RemoveBMCEventSubscriptionForNode(subscription metal3v1alpha1.BMCEventSubscription) (result provisioner.Result, err error) {
 	return result, nil
 }
+
+func (p *fixtureProvisioner) GetFirmwareComponents() (versions map[string]string, err error) {
+	return p.state.FirmwareVersions, nil
+}
+
+func (p *fixtureProvisioner) CreateBMCAccount(creds bmc.Credentials) (result provisioner.Result, err error) {
+	if p.state.createAccountError != "" {
+		result.ErrorMessage = p.state.createAccountError
+		return result, nil
+	}
+	if p.state.BMCAccounts == nil {
+		p.state.BMCAccounts = map[string]string{}
+	}
+	p.state.BMCAccounts[creds.Username] = creds.Password
+	return result, nil
+}
+
+func (p *fixtureProvisioner) DisableBMCAccount(username string) (result provisioner.Result, err error) {
+	delete(p.state.BMCAccounts, username)
+	p.state.DisabledBMCAccounts = append(p.state.DisabledBMCAccounts, username)
+	return result, nil
+}
@@ -0,0 +1,91 @@
+package ironic
+
+import (
+	"testing"
+
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/nodes"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/clients"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/testserver"
+)
+
+func TestAbort(t *testing.T) {
+
+	nodeUUID := "33ce8659-7400-4c68-9535-d10766f07a58"
+	cases := []struct {
+		name   string
+		ironic *testserver.IronicMock
+
+		expectedDirty  bool
+		expectedTarget nodes.TargetProvisionState
+	}{
+		{
+			name: "inspect-wait",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				ProvisionState: string(nodes.InspectWait),
+				UUID:           nodeUUID,
+			}),
+			expectedDirty:  true,
+			expectedTarget: nodes.TargetAbort,
+		},
+		{
+			name: "clean-wait",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				ProvisionState: string(nodes.CleanWait),
+				UUID:           nodeUUID,
+			}),
+			expectedDirty:  true,
+			expectedTarget: nodes.TargetAbort,
+		},
+		{
+			name: "deploy-wait",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				ProvisionState: string(nodes.DeployWait),
+				UUID:           nodeUUID,
+			}),
+			expectedDirty: false,
+		},
+		{
+			name: "inspect-failed",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				ProvisionState: string(nodes.InspectFail),
+				UUID:           nodeUUID,
+			}),
+			expectedDirty: false,
+		},
+		{
+			name: "deploying",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				ProvisionState: string(nodes.Deploying),
+				UUID:           nodeUUID,
+			}),
+			expectedDirty: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.ironic.Start()
+			defer tc.ironic.Stop()
+
+			host := makeHost()
+			host.Status.Provisioning.ID = nodeUUID
+			auth := clients.AuthConfig{Type: clients.NoAuth}
+			prov, err := newProvisionerWithSettings(host, bmc.Credentials{}, nullEventPublisher,
+				tc.ironic.Endpoint(), auth, testserver.NewInspector(t).Endpoint(), auth,
+			)
+			if err != nil {
+				t.Fatalf("could not create provisioner: %s", err)
+			}
+
+			result, err := prov.Abort()
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedDirty, result.Dirty)
+			lastProvOp := tc.ironic.GetLastNodeStatesProvisionUpdateRequestFor(nodeUUID)
+			assert.Equal(t, tc.expectedTarget, lastProvOp.Target)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package ironic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// storeBMCCACertificates writes the CA certificates of the BMC to the
+// directory shared with Ironic and returns the path of the file. Files
+// are named after the checksum of their content, so that they never
+// change once written and hosts trusting the same certificates share
+// a file.
+func (p *ironicProvisioner) storeBMCCACertificates() (string, error) {
+	if p.config.bmcCACertificatesDir == "" {
+		return "", errors.New("BMC_CA_CERTIFICATES_DIR must be set to verify BMC certificates against trusted certificates")
+	}
+
+	sum := sha256.Sum256(p.bmcCACertificates)
+	path := filepath.Join(p.config.bmcCACertificatesDir, hex.EncodeToString(sum[:])+".pem")
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	tmp, err := os.CreateTemp(p.config.bmcCACertificatesDir, ".bmc-ca-")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to store BMC CA certificates")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(p.bmcCACertificates); err != nil {
+		tmp.Close()
+		return "", errors.Wrap(err, "failed to store BMC CA certificates")
+	}
+	if err := tmp.Close(); err != nil {
+		return "", errors.Wrap(err, "failed to store BMC CA certificates")
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil { // #nosec
+		return "", errors.Wrap(err, "failed to store BMC CA certificates")
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", errors.Wrap(err, "failed to store BMC CA certificates")
+	}
+	p.log.Info("stored BMC CA certificates", "path", path)
+	return path, nil
+}
@@ -0,0 +1,63 @@
+package ironic
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+)
+
+func TestBMCAccessWithCACertificates(t *testing.T) {
+	certs := []byte("-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n")
+	dir := t.TempDir()
+
+	p := &ironicProvisioner{
+		config:            ironicConfig{bmcCACertificatesDir: dir},
+		bmcAddress:        "redfish://192.168.122.1/redfish/v1/Systems/1",
+		bmcCACertificates: certs,
+		log:               logr.Discard(),
+	}
+
+	bmcAccess, err := p.bmcAccess()
+	assert.NoError(t, err)
+	path, _ := bmcAccess.DriverInfo(bmc.Credentials{})["redfish_verify_ca"].(string)
+	assert.Equal(t, dir, filepath.Dir(path))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, certs, data)
+
+	// The same certificates are stored once.
+	_, err = p.bmcAccess()
+	assert.NoError(t, err)
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	p.config.bmcCACertificatesDir = ""
+	_, err = p.bmcAccess()
+	assert.EqualError(t, err, "BMC_CA_CERTIFICATES_DIR must be set to verify BMC certificates against trusted certificates")
+
+	p.bmcAddress = "ipmi://192.168.122.1"
+	p.config.bmcCACertificatesDir = dir
+	_, err = p.bmcAccess()
+	assert.EqualError(t, err, "BMC driver ipmi does not support CA certificates")
+}
+
+func TestBMCAccessWithPinnedCertificate(t *testing.T) {
+	p := &ironicProvisioner{
+		bmcAddress:                "redfish://192.168.122.1/redfish/v1/Systems/1",
+		bmcCertificateFingerprint: make([]byte, 32),
+		log:                       logr.Discard(),
+	}
+
+	_, err := p.bmcAccess()
+	assert.EqualError(t, err, "pinned BMC certificates are not supported by the ironic provisioner, trust a CA bundle instead")
+
+	p.disableCertVerification = true
+	_, err = p.bmcAccess()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,66 @@
+package ironic
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/clients"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/testserver"
+	redfishtestserver "github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish/testserver"
+)
+
+func TestBMCAccounts(t *testing.T) {
+	cases := []struct {
+		name          string
+		ipmi          bool
+		expectedError string
+	}{
+		{
+			name: "redfish",
+		},
+		{
+			name:          "ipmi",
+			ipmi:          true,
+			expectedError: "managing BMC accounts is not supported by BMC driver ipmi",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			bmcMock := redfishtestserver.NewRedfish(t).WithCredentials("admin", "password").Start()
+			defer bmcMock.Stop()
+			ironic := testserver.NewIronic(t).WithDefaultResponses().Start()
+			defer ironic.Stop()
+
+			host := makeHost()
+			host.Spec.BMC.Address = bmcMock.BMCAddress()
+			if tc.ipmi {
+				host.Spec.BMC.Address = "ipmi://192.0.2.1"
+			}
+			auth := clients.AuthConfig{Type: clients.NoAuth}
+			prov, err := newProvisionerWithSettings(host,
+				bmc.Credentials{Username: "admin", Password: bmcMock.Password()}, nullEventPublisher,
+				ironic.Endpoint(), auth, testserver.NewInspector(t).Endpoint(), auth,
+			)
+			if err != nil {
+				t.Fatalf("could not create provisioner: %s", err)
+			}
+
+			result, err := prov.CreateBMCAccount(bmc.Credentials{Username: "admin-r", Password: "n3wPassw0rd"})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedError, result.ErrorMessage)
+			account, found := bmcMock.Account("admin-r")
+			assert.Equal(t, !tc.ipmi, found && account.Enabled)
+
+			result, err = prov.DisableBMCAccount("admin-r")
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedError, result.ErrorMessage)
+			account, _ = bmcMock.Account("admin-r")
+			assert.False(t, account.Enabled)
+		})
+	}
+}
@@ -92,18 +92,20 @@ func (f ironicProvisionerFactory) ironicProvisioner(hostData provisioner.HostDat
 	provisionerLogger := f.log.WithValues("host", ironicNodeName(hostData.ObjectMeta))
 
 	p := &ironicProvisioner{
-		config:                  f.config,
-		objectMeta:              hostData.ObjectMeta,
-		nodeID:                  hostData.ProvisionerID,
-		bmcCreds:                hostData.BMCCredentials,
-		bmcAddress:              hostData.BMCAddress,
-		disableCertVerification: hostData.DisableCertificateVerification,
-		bootMACAddress:          hostData.BootMACAddress,
-		client:                  f.clientIronic,
-		inspector:               f.clientInspector,
-		log:                     provisionerLogger,
-		debugLog:                provisionerLogger.V(1),
-		publisher:               publisher,
+		config:                    f.config,
+		objectMeta:                hostData.ObjectMeta,
+		nodeID:                    hostData.ProvisionerID,
+		bmcCreds:                  hostData.BMCCredentials,
+		bmcAddress:                hostData.BMCAddress,
+		disableCertVerification:   hostData.DisableCertificateVerification,
+		bmcCACertificates:         hostData.BMCCACertificates,
+		bmcCertificateFingerprint: hostData.BMCCertificateFingerprint,
+		bootMACAddress:            hostData.BootMACAddress,
+		client:                    f.clientIronic,
+		inspector:                 f.clientInspector,
+		log:                       provisionerLogger,
+		debugLog:                  provisionerLogger.V(1),
+		publisher:                 publisher,
 	}
 
 	return p, nil
@@ -153,6 +155,10 @@ func loadConfigFromEnv(havePreprovImgBuilder bool) (ironicConfig, error) {
 		c.liveISOForcePersistentBootDevice = forcePersistentBootDevice
 	}
 
+	// The directory must be shared with the Ironic conductor, which
+	// reads the CA certificates of the BMCs from it.
+	c.bmcCACertificatesDir = os.Getenv("BMC_CA_CERTIFICATES_DIR")
+
 	c.externalURL = os.Getenv("IRONIC_EXTERNAL_URL_V6")
 
 	// Let's see if externalURL looks like a URL
@@ -1,6 +1,7 @@
 package ironic
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"strings"
@@ -23,6 +24,7 @@ import (
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/devicehints"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/hardwaredetails"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish"
 )
 
 var (
@@ -43,6 +45,13 @@ const (
 	nameSeparator        = "~"
 	customDeployPriority = 80
 
+	// Servicing is not known to gophercloud and needs a newer API version
+	serviceMicroversion                            = "1.87"
+	serviceTarget       nodes.TargetProvisionState = "service"
+	serviceServicing    nodes.ProvisionState       = "servicing"
+	serviceWait         nodes.ProvisionState       = "service wait"
+	serviceFail         nodes.ProvisionState       = "service failed"
+
 	deployKernelKey  = "deploy_kernel"
 	deployRamdiskKey = "deploy_ramdisk"
 	deployISOKey     = "deploy_iso"
@@ -77,6 +86,7 @@ type ironicConfig struct {
 	liveISOForcePersistentBootDevice string
 	maxBusyHosts                     int
 	externalURL                      string
+	bmcCACertificatesDir             string
 }
 
 // Provisioner implements the provisioning.Provisioner interface
@@ -92,6 +102,11 @@ type ironicProvisioner struct {
 	bmcAddress string
 	// whether to disable SSL certificate verification
 	disableCertVerification bool
+	// the CA certificates to verify the BMC certificate against
+	bmcCACertificates []byte
+	// the pinned SHA-256 fingerprint of the BMC certificate, which
+	// Ironic can not check
+	bmcCertificateFingerprint []byte
 	// credentials to log in to the BMC
 	bmcCreds bmc.Credentials
 	// the MAC address of the PXE boot interface
@@ -113,7 +128,20 @@ func (p *ironicProvisioner) bmcAccess() (bmc.AccessDetails, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse BMC address information")
 	}
-	return bmcAccess, nil
+	if len(p.bmcCertificateFingerprint) != 0 && !p.disableCertVerification {
+		// Ironic verifies the certificate of the BMC against CA
+		// certificates only, including its name.
+		return nil, errors.New("pinned BMC certificates are not supported by the ironic provisioner, trust a CA bundle instead")
+	}
+	if len(p.bmcCACertificates) == 0 || p.disableCertVerification {
+		return bmcAccess, nil
+	}
+
+	path, err := p.storeBMCCACertificates()
+	if err != nil {
+		return nil, err
+	}
+	return bmc.WithCACertificates(bmcAccess, path)
 }
 
 func (p *ironicProvisioner) validateNode(ironicNode *nodes.Node) (errorMessage string, err error) {
@@ -360,7 +388,7 @@ func (p *ironicProvisioner) ValidateManagementAccess(data provisioner.Management
 	if ironicNode == nil {
 		p.log.Info("registering host in ironic")
 
-		if data.BootMode == metal3v1alpha1.UEFISecureBoot && !bmcAccess.SupportsSecureBoot() {
+		if data.BootMode == metal3v1alpha1.UEFISecureBoot && !bmcAccess.Capabilities().SecureBoot {
 			msg := fmt.Sprintf("BMC driver %s does not support secure boot", bmcAccess.Type())
 			p.log.Info(msg)
 			result, err = operationFailed(msg)
@@ -452,6 +480,9 @@ func (p *ironicProvisioner) ValidateManagementAccess(data provisioner.Management
 		// otherwise we will be writing on every call to this function.
 		if credentialsChanged {
 			updater.SetTopLevelOpt("driver_info", driverInfo, ironicNode.DriverInfo)
+		} else if field := bmc.CACertificatesField(bmcAccess); field != "" {
+			// The trusted certificates may change independently.
+			updater.SetDriverInfoOpts(optionsData{field: driverInfo[field]}, ironicNode)
 		}
 
 		// We don't return here because we also have to set the
@@ -531,7 +562,7 @@ func (p *ironicProvisioner) configureImages(data provisioner.ManagementAccessDat
 	updater.SetDriverInfoOpts(deployImageInfo, ironicNode)
 
 	if data.CurrentImage != nil || data.HasCustomDeploy {
-		p.getImageUpdateOptsForNode(ironicNode, data.CurrentImage, data.BootMode, data.HasCustomDeploy, updater)
+		p.getImageUpdateOptsForNode(ironicNode, data.CurrentImage, "", data.BootMode, data.HasCustomDeploy, updater)
 	}
 	updater.SetTopLevelOpt("automated_clean",
 		data.AutomatedCleaningMode != metal3v1alpha1.CleaningModeDisabled,
@@ -710,10 +741,14 @@ func (p *ironicProvisioner) tryUpdateNode(ironicNode *nodes.Node, updater *nodeU
 }
 
 func (p *ironicProvisioner) tryChangeNodeProvisionState(ironicNode *nodes.Node, opts nodes.ProvisionStateOpts) (success bool, result provisioner.Result, err error) {
+	return p.tryChangeNodeProvisionStateWith(p.client, ironicNode, opts.Target, opts)
+}
+
+func (p *ironicProvisioner) tryChangeNodeProvisionStateWith(client *gophercloud.ServiceClient, ironicNode *nodes.Node, target nodes.TargetProvisionState, opts nodes.ProvisionStateOptsBuilder) (success bool, result provisioner.Result, err error) {
 	p.log.Info("changing provisioning state",
 		"current", ironicNode.ProvisionState,
 		"existing target", ironicNode.TargetProvisionState,
-		"new target", opts.Target,
+		"new target", target,
 	)
 
 	// Changing provision state in maintenance mode will not work.
@@ -728,7 +763,7 @@ func (p *ironicProvisioner) tryChangeNodeProvisionState(ironicNode *nodes.Node,
 		return
 	}
 
-	changeResult := nodes.ChangeProvisionState(p.client, ironicNode.UUID, opts)
+	changeResult := nodes.ChangeProvisionState(client, ironicNode.UUID, opts)
 	switch changeResult.Err.(type) {
 	case nil:
 		success = true
@@ -738,7 +773,7 @@ func (p *ironicProvisioner) tryChangeNodeProvisionState(ironicNode *nodes.Node,
 		return
 	default:
 		result, err = transientError(errors.Wrap(changeResult.Err,
-			fmt.Sprintf("failed to change provisioning state to %q", opts.Target)))
+			fmt.Sprintf("failed to change provisioning state to %q", target)))
 		return
 	}
 
@@ -773,6 +808,7 @@ func (p *ironicProvisioner) InspectHardware(data provisioner.InspectData, restar
 		result, err = transientError(err)
 		return
 	}
+	defer func() { result.Progress = operationProgress(result, ironicNode) }()
 
 	status, err := introspection.GetIntrospectionStatus(p.inspector, ironicNode.UUID).Extract()
 	if status != nil && strings.Contains(status.Error, "Canceled") {
@@ -870,7 +906,8 @@ func (p *ironicProvisioner) InspectHardware(data provisioner.InspectData, restar
 // UpdateHardwareState fetches the latest hardware state of the server
 // and updates the HardwareDetails field of the host with details. It
 // is expected to do this in the least expensive way possible, such as
-// reading from a cache.
+// reading from a cache. Ironic does not track the health of the
+// hardware, so no health is reported whatever the BMC driver.
 func (p *ironicProvisioner) UpdateHardwareState() (hwState provisioner.HardwareState, err error) {
 	p.debugLog.Info("updating hardware state")
 
@@ -888,6 +925,7 @@ func (p *ironicProvisioner) UpdateHardwareState() (hwState provisioner.HardwareS
 	default:
 		p.log.Info("unknown power state", "value", ironicNode.PowerState)
 	}
+
 	return
 }
 
@@ -914,7 +952,16 @@ func (p *ironicProvisioner) setLiveIsoUpdateOptsForNode(ironicNode *nodes.Node,
 	updater.SetDriverInfoOpts(driverOptValues, ironicNode)
 }
 
-func (p *ironicProvisioner) setDirectDeployUpdateOptsForNode(ironicNode *nodes.Node, imageData *metal3v1alpha1.Image, updater *nodeUpdater) {
+// imageSource returns the URL Ironic downloads the image from, which is
+// the URL of the image cache when the operator caches it.
+func imageSource(imageData *metal3v1alpha1.Image, cachedImageURL string) string {
+	if cachedImageURL != "" {
+		return cachedImageURL
+	}
+	return imageData.URL
+}
+
+func (p *ironicProvisioner) setDirectDeployUpdateOptsForNode(ironicNode *nodes.Node, imageData *metal3v1alpha1.Image, cachedImageURL string, updater *nodeUpdater) {
 	checksum, checksumType, ok := imageData.GetChecksum()
 	if !ok {
 		p.log.Info("image/checksum not found for host")
@@ -936,12 +983,18 @@ func (p *ironicProvisioner) setDirectDeployUpdateOptsForNode(ironicNode *nodes.N
 		// Remove any boot_iso field
 		"boot_iso": nil,
 
-		"image_source":        imageData.URL,
+		"image_source":        imageSource(imageData, cachedImageURL),
 		"image_os_hash_algo":  checksumType,
 		"image_os_hash_value": checksum,
 		"image_checksum":      legacyChecksum,
 		"image_disk_format":   imageData.DiskFormat,
 	}
+	if imageData.IsOCI() {
+		// The digest identifies the manifest rather than the disk
+		// image, whose checksum Ironic reads from the manifest
+		optValues["image_os_hash_algo"] = nil
+		optValues["image_os_hash_value"] = nil
+	}
 	updater.
 		SetInstanceInfoOpts(optValues, ironicNode)
 
@@ -996,7 +1049,7 @@ func (p *ironicProvisioner) setCustomDeployUpdateOptsForNode(ironicNode *nodes.N
 		SetTopLevelOpt("deploy_interface", "custom-agent", ironicNode.DeployInterface)
 }
 
-func (p *ironicProvisioner) getImageUpdateOptsForNode(ironicNode *nodes.Node, imageData *metal3v1alpha1.Image, bootMode metal3v1alpha1.BootMode, hasCustomDeploy bool, updater *nodeUpdater) {
+func (p *ironicProvisioner) getImageUpdateOptsForNode(ironicNode *nodes.Node, imageData *metal3v1alpha1.Image, cachedImageURL string, bootMode metal3v1alpha1.BootMode, hasCustomDeploy bool, updater *nodeUpdater) {
 	// instance_uuid
 	updater.SetTopLevelOpt("instance_uuid", string(p.objectMeta.UID), ironicNode.InstanceUUID)
 
@@ -1022,7 +1075,7 @@ func (p *ironicProvisioner) getImageUpdateOptsForNode(ironicNode *nodes.Node, im
 		p.setLiveIsoUpdateOptsForNode(ironicNode, imageData, updater)
 	} else {
 		// Set deploy_interface direct options when not booting a live-iso
-		p.setDirectDeployUpdateOptsForNode(ironicNode, imageData, updater)
+		p.setDirectDeployUpdateOptsForNode(ironicNode, imageData, cachedImageURL, updater)
 	}
 }
 
@@ -1030,7 +1083,13 @@ func (p *ironicProvisioner) getUpdateOptsForNode(ironicNode *nodes.Node, data pr
 	updater := updateOptsBuilder(p.debugLog)
 
 	hasCustomDeploy := data.CustomDeploy != nil && data.CustomDeploy.Method != ""
-	p.getImageUpdateOptsForNode(ironicNode, &data.Image, data.BootMode, hasCustomDeploy, updater)
+	p.getImageUpdateOptsForNode(ironicNode, &data.Image, data.CachedImageURL, data.BootMode, hasCustomDeploy, updater)
+
+	var pullSecret *string
+	if data.Image.IsOCI() && data.ImagePullSecret != "" {
+		pullSecret = &data.ImagePullSecret
+	}
+	updater.SetInstanceInfoOpts(optionsData{"image_pull_secret": pullSecret}, ironicNode)
 
 	opts := optionsData{
 		"root_device": devicehints.MakeHintMap(data.RootDeviceHints),
@@ -1230,7 +1289,7 @@ func (p *ironicProvisioner) Adopt(data provisioner.AdoptData, restartOnFailure b
 	return operationComplete()
 }
 
-func (p *ironicProvisioner) ironicHasSameImage(ironicNode *nodes.Node, image metal3v1alpha1.Image) (sameImage bool) {
+func (p *ironicProvisioner) ironicHasSameImage(ironicNode *nodes.Node, image metal3v1alpha1.Image, cachedImageURL string) (sameImage bool) {
 	// To make it easier to test if ironic is configured with
 	// the same image we are trying to provision to the host.
 	if image.IsLiveISO() {
@@ -1239,9 +1298,16 @@ func (p *ironicProvisioner) ironicHasSameImage(ironicNode *nodes.Node, image met
 			"boot_iso", ironicNode.InstanceInfo["boot_iso"],
 			"same", sameImage,
 			"provisionState", ironicNode.ProvisionState)
+	} else if image.IsOCI() {
+		// The URL holds the digest of the image
+		sameImage = (ironicNode.InstanceInfo["image_source"] == image.URL)
+		p.log.Info("checking image settings",
+			"source", ironicNode.InstanceInfo["image_source"],
+			"same", sameImage,
+			"provisionState", ironicNode.ProvisionState)
 	} else {
 		checksum, checksumType, _ := image.GetChecksum()
-		sameImage = (ironicNode.InstanceInfo["image_source"] == image.URL &&
+		sameImage = (ironicNode.InstanceInfo["image_source"] == imageSource(&image, cachedImageURL) &&
 			ironicNode.InstanceInfo["image_os_hash_algo"] == checksumType &&
 			ironicNode.InstanceInfo["image_os_hash_value"] == checksum)
 		p.log.Info("checking image settings",
@@ -1255,6 +1321,13 @@ func (p *ironicProvisioner) ironicHasSameImage(ironicNode *nodes.Node, image met
 }
 
 func (p *ironicProvisioner) buildManualCleaningSteps(bmcAccess bmc.AccessDetails, data provisioner.PrepareData) (cleanSteps []nodes.CleanStep, err error) {
+	// Flash the firmware first, as new firmware may change the available settings
+	firmwareCleanSteps, err := buildFirmwareUpdateCleanSteps(bmcAccess, data.TargetFirmwareComponents)
+	if err != nil {
+		return nil, err
+	}
+	cleanSteps = append(cleanSteps, firmwareCleanSteps...)
+
 	// Build raid clean steps
 	raidCleanSteps, err := BuildRAIDCleanSteps(bmcAccess.RAIDInterface(), data.TargetRAIDConfig, data.ActualRAIDConfig)
 	if err != nil {
@@ -1327,6 +1400,37 @@ func (p *ironicProvisioner) buildManualCleaningSteps(bmcAccess bmc.AccessDetails
 	return
 }
 
+func buildFirmwareUpdateCleanSteps(bmcAccess bmc.AccessDetails, target []metal3v1alpha1.FirmwareUpdate) (cleanSteps []nodes.CleanStep, err error) {
+	if len(target) == 0 {
+		return nil, nil
+	}
+
+	updates := make([]bmc.FirmwareUpdate, 0, len(target))
+	for _, update := range target {
+		updates = append(updates, bmc.FirmwareUpdate{
+			Component: update.Component,
+			URL:       update.URL,
+			Checksum:  update.Checksum,
+		})
+	}
+	images, err := bmcAccess.BuildFirmwareUpdates(updates)
+	if err != nil {
+		return nil, err
+	}
+
+	cleanSteps = append(
+		cleanSteps,
+		nodes.CleanStep{
+			Interface: nodes.InterfaceManagement,
+			Step:      "update_firmware",
+			Args: map[string]interface{}{
+				"firmware_images": images,
+			},
+		},
+	)
+	return
+}
+
 func buildFirmwareSettings(settings []map[string]interface{}, name string, value intstr.IntOrString) []map[string]interface{} {
 	// if name already exists, don't add it
 	for _, setting := range settings {
@@ -1454,6 +1558,113 @@ func (p *ironicProvisioner) Prepare(data provisioner.PrepareData, unprepared boo
 	return
 }
 
+// serviceOpts requests servicing of an active node with the given steps.
+type serviceOpts struct {
+	ServiceSteps []nodes.CleanStep
+}
+
+// ToProvisionStateMap assembles a request body for servicing a node.
+func (opts serviceOpts) ToProvisionStateMap() (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"target":        serviceTarget,
+		"service_steps": opts.ServiceSteps,
+	}, nil
+}
+
+func (p *ironicProvisioner) startServicing(bmcAccess bmc.AccessDetails, ironicNode *nodes.Node, data provisioner.PrepareData, forcePowerOff bool) (success bool, result provisioner.Result, err error) {
+	// Build the steps the same way as for manual cleaning, servicing
+	// does not erase the disks
+	serviceSteps, err := p.buildManualCleaningSteps(bmcAccess, data)
+	if err != nil {
+		result, err = operationFailed(err.Error())
+		return
+	}
+	if len(serviceSteps) == 0 {
+		// nothing to do
+		success = true
+		result, err = operationComplete()
+		return
+	}
+
+	// Give the operating system a chance to shut down cleanly, unless
+	// a previous attempt has failed
+	if ironicNode.PowerState != powerOff {
+		p.log.Info("powering off the host before servicing")
+		result, err = p.PowerOff(metal3v1alpha1.RebootModeSoft, forcePowerOff)
+		return
+	}
+
+	result, err = setTargetRAIDCfg(p, bmcAccess.RAIDInterface(), ironicNode, data)
+	if result.Dirty || result.ErrorMessage != "" || err != nil {
+		return
+	}
+
+	p.log.Info("servicing the node", "service steps", serviceSteps)
+	serviceClient := *p.client
+	serviceClient.Microversion = serviceMicroversion
+	return p.tryChangeNodeProvisionStateWith(&serviceClient, ironicNode, serviceTarget,
+		serviceOpts{ServiceSteps: serviceSteps})
+}
+
+// Service applies new configuration to a provisioned host without
+// deprovisioning it. If `started` is true, it means that servicing of
+// the node was successfully requested.
+func (p *ironicProvisioner) Service(data provisioner.PrepareData, unprepared bool, restartOnFailure bool) (result provisioner.Result, started bool, err error) {
+	bmcAccess, err := p.bmcAccess()
+	if err != nil {
+		result, err = transientError(err)
+		return
+	}
+
+	ironicNode, err := p.getNode()
+	if err != nil {
+		result, err = transientError(err)
+		return
+	}
+
+	switch nodes.ProvisionState(ironicNode.ProvisionState) {
+	case nodes.Active:
+		if unprepared {
+			started, result, err = p.startServicing(bmcAccess, ironicNode, data, restartOnFailure)
+			return
+		}
+		// Servicing finished
+		result, err = operationComplete()
+
+	case serviceFail:
+		// When servicing failed, the host settings have to be cleared
+		// before it can be retried.
+		if !restartOnFailure {
+			result, err = operationFailed(ironicNode.LastError)
+			return
+		}
+		if ironicNode.Maintenance {
+			p.log.Info("clearing maintenance flag")
+			result, err = p.setMaintenanceFlag(ironicNode, false, "")
+			return
+		}
+		if unprepared {
+			started, result, err = p.startServicing(bmcAccess, ironicNode, data, restartOnFailure)
+			return
+		}
+		// Nothing left to apply, return the node to active
+		result, err = p.changeNodeProvisionState(
+			ironicNode,
+			nodes.ProvisionStateOpts{Target: nodes.TargetAbort},
+		)
+
+	case serviceServicing, serviceWait:
+		p.log.Info("waiting for host to be serviced",
+			"state", ironicNode.ProvisionState,
+			"service step", ironicNode.CleanStep)
+		result, err = operationContinuing(provisionRequeueDelay)
+
+	default:
+		result, err = transientError(fmt.Errorf("Have unexpected ironic node state %s", ironicNode.ProvisionState))
+	}
+	return
+}
+
 func (p *ironicProvisioner) getConfigDrive(data provisioner.ProvisionData) (configDrive nodes.ConfigDrive, err error) {
 	// In theory, Ironic can support configdrive with live ISO by attaching
 	// it to another virtual media slot. However, some hardware does not
@@ -1529,10 +1740,11 @@ func (p *ironicProvisioner) Provision(data provisioner.ProvisionData, forceReboo
 	if err != nil {
 		return transientError(err)
 	}
+	defer func() { result.Progress = operationProgress(result, ironicNode) }()
 
 	p.log.Info("provisioning image to host", "state", ironicNode.ProvisionState)
 
-	ironicHasSameImage := p.ironicHasSameImage(ironicNode, data.Image)
+	ironicHasSameImage := p.ironicHasSameImage(ironicNode, data.Image, data.CachedImageURL)
 
 	// Ironic has the settings it needs, see if it finds any issues
 	// with them.
@@ -1669,6 +1881,7 @@ func (p *ironicProvisioner) Deprovision(restartOnFailure bool) (result provision
 	if err != nil {
 		return transientError(err)
 	}
+	defer func() { result.Progress = operationProgress(result, ironicNode) }()
 
 	p.log.Info("deprovisioning host",
 		"ID", ironicNode.UUID,
@@ -1836,6 +2049,35 @@ func (p *ironicProvisioner) Detach() (result provisioner.Result, err error) {
 	return p.Delete()
 }
 
+// Abort stops the operation currently waiting on the host, such as
+// inspection, cleaning or deployment, leaving it failed. It may be
+// called multiple times, and should return true for its dirty flag
+// until the operation has been stopped.
+func (p *ironicProvisioner) Abort() (result provisioner.Result, err error) {
+	ironicNode, err := p.getNode()
+	if err != nil {
+		return transientError(err)
+	}
+
+	switch nodes.ProvisionState(ironicNode.ProvisionState) {
+	case nodes.InspectWait, nodes.CleanWait:
+		p.log.Info("aborting operation", "current", ironicNode.ProvisionState)
+		p.publisher("OperationAborted", fmt.Sprintf("Aborted operation in state %s", ironicNode.ProvisionState))
+		return p.changeNodeProvisionState(
+			ironicNode,
+			nodes.ProvisionStateOpts{Target: nodes.TargetAbort},
+		)
+	case nodes.DeployWait:
+		// Ironic can not abort deployments, which fail on their own
+		// when the agent does not call back in time.
+		p.log.Info("deployment can not be aborted", "current", ironicNode.ProvisionState)
+		return operationComplete()
+	default:
+		p.log.Info("no operation to abort", "current", ironicNode.ProvisionState)
+		return operationComplete()
+	}
+}
+
 // softPowerOffUnsupportedError is returned when the BMC does not
 // support soft power off.
 type softPowerOffUnsupportedError struct {
@@ -2069,3 +2311,82 @@ func (p *ironicProvisioner) RemoveBMCEventSubscriptionForNode(subscription metal
 	}
 	return operationComplete()
 }
+
+// redfishEndpoint returns the address of the Redfish API of the BMC,
+// the path of the system and the TLS configuration to reach it, for
+// the BMC drivers with a Redfish API. The address is empty for the
+// other drivers.
+func (p *ironicProvisioner) redfishEndpoint() (address, systemPath string, tlsConfig *tls.Config, err error) {
+	bmcAccess, err := p.bmcAccess()
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	driverInfo := bmcAccess.DriverInfo(p.bmcCreds)
+	address, _ = driverInfo["redfish_address"].(string)
+	systemPath, _ = driverInfo["redfish_system_id"].(string)
+	if address == "" || systemPath == "" {
+		return "", "", nil, nil
+	}
+
+	tlsConfig, err = redfish.TLSConfig(p.disableCertVerification, p.bmcCACertificates, nil)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return address, systemPath, tlsConfig, nil
+}
+
+// GetFirmwareComponents reads the installed firmware versions from the
+// Redfish API of the BMC, as Ironic does not track them. No versions
+// are returned for the BMC drivers without a Redfish API.
+func (p *ironicProvisioner) GetFirmwareComponents() (versions map[string]string, err error) {
+	address, systemPath, tlsConfig, err := p.redfishEndpoint()
+	if err != nil || address == "" {
+		return nil, err
+	}
+	return redfish.FirmwareVersions(address, systemPath, p.bmcCreds, tlsConfig)
+}
+
+// CreateBMCAccount creates the BMC account through its Redfish
+// AccountService, which Ironic does not manage. The new credentials
+// are sent to Ironic when they are validated.
+func (p *ironicProvisioner) CreateBMCAccount(creds bmc.Credentials) (result provisioner.Result, err error) {
+	return p.changeBMCAccounts(func(address, systemPath string, tlsConfig *tls.Config) error {
+		p.log.Info("creating BMC account", "username", creds.Username)
+		return redfish.CreateAccount(address, systemPath, p.bmcCreds, creds, tlsConfig)
+	})
+}
+
+// DisableBMCAccount disables the BMC account through its Redfish
+// AccountService.
+func (p *ironicProvisioner) DisableBMCAccount(username string) (result provisioner.Result, err error) {
+	return p.changeBMCAccounts(func(address, systemPath string, tlsConfig *tls.Config) error {
+		p.log.Info("disabling BMC account", "username", username)
+		return redfish.DisableAccount(address, systemPath, p.bmcCreds, username, tlsConfig)
+	})
+}
+
+func (p *ironicProvisioner) changeBMCAccounts(change func(address, systemPath string, tlsConfig *tls.Config) error) (result provisioner.Result, err error) {
+	bmcAccess, err := p.bmcAccess()
+	if err != nil {
+		return operationFailed(err.Error())
+	}
+
+	address, systemPath, tlsConfig, err := p.redfishEndpoint()
+	if err != nil {
+		return operationFailed(err.Error())
+	}
+	if address == "" {
+		return operationFailed(fmt.Sprintf("managing BMC accounts is not supported by BMC driver %s", bmcAccess.Driver()))
+	}
+
+	err = change(address, systemPath, tlsConfig)
+	var accountErr redfish.AccountError
+	if errors.As(err, &accountErr) {
+		return operationFailed(accountErr.Error())
+	}
+	if err != nil {
+		return transientError(err)
+	}
+	return operationComplete()
+}
@@ -32,9 +32,16 @@ func (r *RAIDTestBMC) RAIDInterface() string                                 { r
 func (r *RAIDTestBMC) VendorInterface() string                               { return "" }
 func (r *RAIDTestBMC) SupportsSecureBoot() bool                              { return false }
 func (r *RAIDTestBMC) RequiresProvisioningNetwork() bool                     { return true }
+func (r *RAIDTestBMC) SupportsFirmwareUpdate() bool                          { return false }
 func (r *RAIDTestBMC) BuildBIOSSettings(fwConf *bmc.FirmwareConfig) ([]map[string]string, error) {
 	return nil, nil
 }
+func (r *RAIDTestBMC) BuildFirmwareUpdates(updates []bmc.FirmwareUpdate) ([]map[string]string, error) {
+	return nil, nil
+}
+func (r *RAIDTestBMC) Capabilities() bmc.Capabilities {
+	return bmc.Capabilities{HardwareRAID: true}
+}
 
 func TestPrepare(t *testing.T) {
 	bmc.RegisterFactory("raid-test", func(u *url.URL, dcv bool) (bmc.AccessDetails, error) {
@@ -220,3 +227,72 @@ func TestPrepare(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildFirmwareUpdateCleanSteps(t *testing.T) {
+	updates := []metal3v1alpha1.FirmwareUpdate{
+		{
+			Component: "bios",
+			Version:   "2.15.1",
+			URL:       "http://example.com/bios.bin",
+			Checksum:  "sha256:abcd",
+		},
+		{
+			Component: "bmc",
+			Version:   "6.10",
+			URL:       "http://example.com/bmc.bin",
+		},
+	}
+
+	cases := []struct {
+		name          string
+		address       string
+		updates       []metal3v1alpha1.FirmwareUpdate
+		expected      []nodes.CleanStep
+		expectedError string
+	}{
+		{
+			name:    "redfish",
+			address: "redfish://example.test/redfish/v1/Systems/1",
+			updates: updates,
+			expected: []nodes.CleanStep{
+				{
+					Interface: nodes.InterfaceManagement,
+					Step:      "update_firmware",
+					Args: map[string]interface{}{
+						"firmware_images": []map[string]string{
+							{"url": "http://example.com/bios.bin", "checksum": "sha256:abcd"},
+							{"url": "http://example.com/bmc.bin"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:    "no updates",
+			address: "ipmi://example.test",
+		},
+		{
+			name:          "unsupported driver",
+			address:       "ipmi://example.test",
+			updates:       updates,
+			expectedError: "firmware updates for ipmi are not supported",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			bmcAccess, err := bmc.NewAccessDetails(tc.address, false)
+			if err != nil {
+				t.Fatalf("could not create access details: %s", err)
+			}
+
+			steps, err := buildFirmwareUpdateCleanSteps(bmcAccess, tc.updates)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, steps)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package ironic
+
+import (
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/nodes"
+
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+)
+
+// heartbeatLayouts are the formats ironic uses for the time of the
+// last agent heartbeat, which is stored in UTC.
+var heartbeatLayouts = []string{
+	"2006-01-02T15:04:05.999999",
+	time.RFC3339Nano,
+}
+
+// operationProgress returns the progress of an operation that is still
+// running on the node, as reported by ironic.
+func operationProgress(result provisioner.Result, ironicNode *nodes.Node) *provisioner.Progress {
+	if !result.Dirty || result.ErrorMessage != "" || ironicNode == nil {
+		return nil
+	}
+
+	progress := &provisioner.Progress{
+		Step:        ironicNode.ProvisionState,
+		CurrentStep: stepName(ironicNode.DeployStep),
+	}
+	if progress.CurrentStep == "" {
+		progress.CurrentStep = stepName(ironicNode.CleanStep)
+	}
+
+	if heartbeat, ok := ironicNode.DriverInternalInfo["agent_last_heartbeat"].(string); ok {
+		for _, layout := range heartbeatLayouts {
+			if t, err := time.ParseInLocation(layout, heartbeat, time.UTC); err == nil {
+				progress.LastHeartbeat = &t
+				break
+			}
+		}
+	}
+	return progress
+}
+
+// stepName returns the name of a deploy or clean step as
+// interface.step, e.g. deploy.write_image
+func stepName(step map[string]interface{}) string {
+	name, _ := step["step"].(string)
+	if name == "" {
+		return ""
+	}
+	if iface, _ := step["interface"].(string); iface != "" {
+		return iface + "." + name
+	}
+	return name
+}
@@ -0,0 +1,105 @@
+package ironic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/nodes"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+)
+
+func TestOperationProgress(t *testing.T) {
+	heartbeat := time.Date(2023, 5, 4, 10, 11, 12, 345678000, time.UTC)
+
+	cases := []struct {
+		name     string
+		result   provisioner.Result
+		node     *nodes.Node
+		expected *provisioner.Progress
+	}{
+		{
+			name:   "not-dirty",
+			result: provisioner.Result{},
+			node:   &nodes.Node{ProvisionState: string(nodes.Active)},
+		},
+		{
+			name:   "error",
+			result: provisioner.Result{Dirty: true, ErrorMessage: "failed"},
+			node:   &nodes.Node{ProvisionState: string(nodes.DeployFail)},
+		},
+		{
+			name:   "no-node",
+			result: provisioner.Result{Dirty: true},
+		},
+		{
+			name:   "deploying",
+			result: provisioner.Result{Dirty: true},
+			node: &nodes.Node{
+				ProvisionState: string(nodes.Deploying),
+				DeployStep: map[string]interface{}{
+					"interface": "deploy",
+					"step":      "write_image",
+				},
+				DriverInternalInfo: map[string]interface{}{
+					"agent_last_heartbeat": "2023-05-04T10:11:12.345678",
+				},
+			},
+			expected: &provisioner.Progress{
+				Step:          string(nodes.Deploying),
+				CurrentStep:   "deploy.write_image",
+				LastHeartbeat: &heartbeat,
+			},
+		},
+		{
+			name:   "cleaning",
+			result: provisioner.Result{Dirty: true},
+			node: &nodes.Node{
+				ProvisionState: string(nodes.CleanWait),
+				CleanStep: map[string]interface{}{
+					"interface": "deploy",
+					"step":      "erase_devices_metadata",
+				},
+				DriverInternalInfo: map[string]interface{}{
+					"agent_last_heartbeat": "2023-05-04T10:11:12.345678+00:00",
+				},
+			},
+			expected: &provisioner.Progress{
+				Step:          string(nodes.CleanWait),
+				CurrentStep:   "deploy.erase_devices_metadata",
+				LastHeartbeat: &heartbeat,
+			},
+		},
+		{
+			name:   "no-heartbeat",
+			result: provisioner.Result{Dirty: true},
+			node: &nodes.Node{
+				ProvisionState: string(nodes.InspectWait),
+				DriverInternalInfo: map[string]interface{}{
+					"agent_last_heartbeat": "yesterday",
+				},
+			},
+			expected: &provisioner.Progress{
+				Step: string(nodes.InspectWait),
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			progress := operationProgress(tc.result, tc.node)
+			if tc.expected == nil {
+				assert.Nil(t, progress)
+				return
+			}
+			assert.Equal(t, tc.expected.Step, progress.Step)
+			assert.Equal(t, tc.expected.CurrentStep, progress.CurrentStep)
+			if tc.expected.LastHeartbeat == nil {
+				assert.Nil(t, progress.LastHeartbeat)
+			} else {
+				assert.True(t, tc.expected.LastHeartbeat.Equal(*progress.LastHeartbeat))
+			}
+		})
+	}
+}
@@ -344,6 +344,7 @@ func TestIronicHasSameImage(t *testing.T) {
 		hostImage        string
 		hostChecksum     string
 		hostChecksumType v1alpha1.ChecksumType
+		cachedImageURL   string
 	}{
 		{
 			name:      "image same",
@@ -405,6 +406,36 @@ func TestIronicHasSameImage(t *testing.T) {
 			hostChecksum:     "thechecksum",
 			hostChecksumType: v1alpha1.SHA512,
 		},
+		{
+			name:     "cached image same",
+			expected: true,
+			node: nodes.Node{
+				InstanceInfo: map[string]interface{}{
+					"image_source":        "http://image-cache:8090/images/theimage",
+					"image_os_hash_value": "thechecksum",
+					"image_os_hash_algo":  "md5",
+				},
+			},
+			hostImage:        "theimage",
+			hostChecksum:     "thechecksum",
+			hostChecksumType: v1alpha1.MD5,
+			cachedImageURL:   "http://image-cache:8090/images/theimage",
+		},
+		{
+			name:     "image not from the cache",
+			expected: false,
+			node: nodes.Node{
+				InstanceInfo: map[string]interface{}{
+					"image_source":        "theimage",
+					"image_os_hash_value": "thechecksum",
+					"image_os_hash_algo":  "md5",
+				},
+			},
+			hostImage:        "theimage",
+			hostChecksum:     "thechecksum",
+			hostChecksumType: v1alpha1.MD5,
+			cachedImageURL:   "http://image-cache:8090/images/theimage",
+		},
 		{
 			name:      "live image same",
 			liveImage: true,
@@ -427,6 +458,26 @@ func TestIronicHasSameImage(t *testing.T) {
 			},
 			hostImage: "different",
 		},
+		{
+			name:     "OCI image same",
+			expected: true,
+			node: nodes.Node{
+				InstanceInfo: map[string]interface{}{
+					"image_source": "oci://quay.io/metal3/ubuntu@sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+				},
+			},
+			hostImage: "oci://quay.io/metal3/ubuntu@sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+		},
+		{
+			name:     "OCI image different digest",
+			expected: false,
+			node: nodes.Node{
+				InstanceInfo: map[string]interface{}{
+					"image_source": "oci://quay.io/metal3/ubuntu@sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+				},
+			},
+			hostImage: "oci://quay.io/metal3/ubuntu@sha256:fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9",
+		},
 	}
 
 	for _, tc := range cases {
@@ -461,7 +512,7 @@ func TestIronicHasSameImage(t *testing.T) {
 				t.Fatalf("could not create provisioner: %s", err)
 			}
 
-			sameImage := prov.ironicHasSameImage(&tc.node, *host.Spec.Image)
+			sameImage := prov.ironicHasSameImage(&tc.node, *host.Spec.Image, tc.cachedImageURL)
 			assert.Equal(t, tc.expected, sameImage)
 		})
 	}
@@ -0,0 +1,177 @@
+package ironic
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/openstack/baremetal/v1/nodes"
+	"github.com/stretchr/testify/assert"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/clients"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/testserver"
+)
+
+func TestService(t *testing.T) {
+	bmc.RegisterFactory("raid-test", func(u *url.URL, dcv bool) (bmc.AccessDetails, error) {
+		return &RAIDTestBMC{}, nil
+	}, []string{})
+
+	nodeUUID := "33ce8659-7400-4c68-9535-d10766f07a58"
+	cases := []struct {
+		name                 string
+		ironic               *testserver.IronicMock
+		unprepared           bool
+		restartOnFailure     bool
+		existRaidConfig      bool
+		expectedStarted      bool
+		expectedDirty        bool
+		expectedError        bool
+		expectedRequestAfter int
+		expectedTarget       nodes.TargetProvisionState
+	}{
+		{
+			name: "active state(haven't service steps)",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				ProvisionState: string(nodes.Active),
+				UUID:           nodeUUID,
+			}),
+			unprepared:      true,
+			expectedStarted: true,
+		},
+		{
+			name: "active state(powered on)",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				ProvisionState: string(nodes.Active),
+				PowerState:     powerOn,
+				UUID:           nodeUUID,
+			}).WithNodeStatesPowerUpdate(nodeUUID, http.StatusAccepted),
+			unprepared:      true,
+			existRaidConfig: true,
+			expectedDirty:   true,
+		},
+		{
+			name: "active state(powered off)",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				ProvisionState: string(nodes.Active),
+				PowerState:     powerOff,
+				UUID:           nodeUUID,
+			}),
+			unprepared:           true,
+			existRaidConfig:      true,
+			expectedStarted:      true,
+			expectedRequestAfter: 10,
+			expectedDirty:        true,
+			expectedTarget:       serviceTarget,
+		},
+		{
+			name: "active state(servicing finished)",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				ProvisionState: string(nodes.Active),
+				UUID:           nodeUUID,
+			}),
+			existRaidConfig: true,
+		},
+		{
+			name: "servicing state",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				ProvisionState: string(serviceServicing),
+				UUID:           nodeUUID,
+			}),
+			existRaidConfig:      true,
+			expectedRequestAfter: 10,
+			expectedDirty:        true,
+		},
+		{
+			name: "serviceWait state",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				ProvisionState: string(serviceWait),
+				UUID:           nodeUUID,
+			}),
+			existRaidConfig:      true,
+			expectedRequestAfter: 10,
+			expectedDirty:        true,
+		},
+		{
+			name: "serviceFail state(report failure)",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				ProvisionState: string(serviceFail),
+				LastError:      "update_firmware failed",
+				UUID:           nodeUUID,
+			}),
+			existRaidConfig: true,
+			expectedError:   true,
+		},
+		{
+			name: "serviceFail state(retry)",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				ProvisionState: string(serviceFail),
+				PowerState:     powerOff,
+				UUID:           nodeUUID,
+			}),
+			unprepared:           true,
+			restartOnFailure:     true,
+			existRaidConfig:      true,
+			expectedStarted:      true,
+			expectedRequestAfter: 10,
+			expectedDirty:        true,
+			expectedTarget:       serviceTarget,
+		},
+		{
+			name: "serviceFail state(return to active)",
+			ironic: testserver.NewIronic(t).WithDefaultResponses().Node(nodes.Node{
+				ProvisionState: string(serviceFail),
+				UUID:           nodeUUID,
+			}),
+			restartOnFailure:     true,
+			existRaidConfig:      true,
+			expectedRequestAfter: 10,
+			expectedDirty:        true,
+			expectedTarget:       nodes.TargetAbort,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.ironic.Start()
+			defer tc.ironic.Stop()
+
+			host := makeHost()
+			host.Status.Provisioning.ID = nodeUUID
+			prepData := provisioner.PrepareData{}
+			if tc.existRaidConfig {
+				host.Spec.BMC.Address = "raid-test://test.bmc/"
+				prepData.TargetRAIDConfig = &metal3v1alpha1.RAIDConfig{
+					HardwareRAIDVolumes: []metal3v1alpha1.HardwareRAIDVolume{
+						{
+							Name:  "root",
+							Level: "1",
+						},
+					},
+				}
+			}
+
+			auth := clients.AuthConfig{Type: clients.NoAuth}
+			prov, err := newProvisionerWithSettings(host, bmc.Credentials{}, nullEventPublisher,
+				tc.ironic.Endpoint(), auth, testserver.NewInspector(t).Endpoint(), auth,
+			)
+			if err != nil {
+				t.Fatalf("could not create provisioner: %s", err)
+			}
+
+			result, started, err := prov.Service(prepData, tc.unprepared, tc.restartOnFailure)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStarted, started)
+			assert.Equal(t, tc.expectedDirty, result.Dirty)
+			assert.Equal(t, tc.expectedError, result.ErrorMessage != "")
+			assert.Equal(t, time.Second*time.Duration(tc.expectedRequestAfter), result.RequeueAfter)
+			lastProvOp := tc.ironic.GetLastNodeStatesProvisionUpdateRequestFor(nodeUUID)
+			assert.Equal(t, tc.expectedTarget, lastProvOp.Target)
+		})
+	}
+}
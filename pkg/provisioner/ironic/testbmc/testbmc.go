@@ -87,7 +87,11 @@ func (a *testAccessDetails) VendorInterface() string {
 }
 
 func (a *testAccessDetails) SupportsSecureBoot() bool {
-	return false
+	return a.Capabilities().SecureBoot
+}
+
+func (a *testAccessDetails) SupportsFirmwareUpdate() bool {
+	return a.Capabilities().FirmwareUpdate
 }
 
 func (a *testAccessDetails) SupportsISOPreprovisioningImage() bool {
@@ -148,3 +152,17 @@ func (a *testAccessDetails) BuildBIOSSettings(firmwareConfig *bmc.FirmwareConfig
 
 	return
 }
+
+func (a *testAccessDetails) BuildFirmwareUpdates(updates []bmc.FirmwareUpdate) (images []map[string]string, err error) {
+	for _, update := range updates {
+		images = append(images, map[string]string{"url": update.URL})
+	}
+	return
+}
+
+func (a *testAccessDetails) Capabilities() bmc.Capabilities {
+	return bmc.Capabilities{
+		BIOSSettings:   true,
+		FirmwareUpdate: true,
+	}
+}
@@ -101,13 +101,17 @@ func getUpdateOperation(name string, currentData map[string]interface{}, desired
 	if desiredValue != nil {
 		if !(present && optionValueEqual(deref(current), desiredValue)) {
 			if log.GetSink() != nil {
+				logValue, logCurrent := sanitisedValue(desiredValue), current
+				if strings.Contains(name, "secret") {
+					logValue, logCurrent = "<redacted>", "<redacted>"
+				}
 				if present {
 					log.Info("updating option data",
-						"value", sanitisedValue(desiredValue),
-						"old_value", current)
+						"value", logValue,
+						"old_value", logCurrent)
 				} else {
 					log.Info("adding option data",
-						"value", sanitisedValue(desiredValue))
+						"value", logValue)
 				}
 			}
 			return &nodes.UpdateOperation{
@@ -10,6 +10,7 @@ import (
 	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/clients"
 	"github.com/metal3-io/baremetal-operator/pkg/provisioner/ironic/testserver"
+	redfishtestserver "github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish/testserver"
 )
 
 func TestUpdateHardwareState(t *testing.T) {
@@ -152,3 +153,35 @@ func TestUpdateHardwareState(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateHardwareStateHealth(t *testing.T) {
+	nodeUUID := "33ce8659-7400-4c68-9535-d10766f07a58"
+
+	// The BMC is never contacted, as the state is read from Ironic
+	bmcMock := redfishtestserver.NewRedfish(t).WithChassisHealth("Critical").Start()
+	bmcMock.Stop()
+	ironic := testserver.NewIronic(t).Ready().Node(nodes.Node{
+		UUID:       nodeUUID,
+		PowerState: "power on",
+	}).Start()
+	defer ironic.Stop()
+
+	host := makeHost()
+	host.Status.Provisioning.ID = nodeUUID
+	host.Spec.BMC.Address = bmcMock.BMCAddress()
+	auth := clients.AuthConfig{Type: clients.NoAuth}
+	prov, err := newProvisionerWithSettings(host, bmc.Credentials{}, nullEventPublisher,
+		ironic.Endpoint(), auth, testserver.NewInspector(t).Endpoint(), auth,
+	)
+	if err != nil {
+		t.Fatalf("could not create provisioner: %s", err)
+	}
+
+	hwState, err := prov.UpdateHardwareState()
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, hwState.PoweredOn) {
+		assert.True(t, *hwState.PoweredOn)
+	}
+	assert.Nil(t, hwState.Health)
+}
@@ -950,6 +950,141 @@ func TestGetUpdateOptsForNodeLiveIsoToImage(t *testing.T) {
 	}
 }
 
+func TestGetUpdateOptsForNodeOCIImage(t *testing.T) {
+	eventPublisher := func(reason, message string) {}
+	auth := clients.AuthConfig{Type: clients.NoAuth}
+
+	host := makeHost()
+	host.Spec.Image = &metal3v1alpha1.Image{
+		URL: "oci://quay.io/metal3/ubuntu:22.04@sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+	}
+	prov, err := newProvisionerWithSettings(host, bmc.Credentials{}, eventPublisher,
+		"https://ironic.test", auth, "https://ironic.test", auth,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ironicNode := &nodes.Node{
+		InstanceInfo: map[string]interface{}{
+			"image_source":        "http://example.com/ubuntu.qcow2",
+			"image_os_hash_algo":  "sha256",
+			"image_os_hash_value": "thechecksum",
+		},
+	}
+
+	provData := provisioner.ProvisionData{
+		Image:           *host.Spec.Image,
+		BootMode:        metal3v1alpha1.DefaultBootMode,
+		ImagePullSecret: "dXNlcjpwYXNzd29yZA==",
+	}
+	patches := prov.getUpdateOptsForNode(ironicNode, provData).Updates
+
+	t.Logf("patches: %v", patches)
+
+	expected := []struct {
+		Path  string         // the node property path
+		Value interface{}    // the value being passed to ironic
+		Op    nodes.UpdateOp // The operation add/replace/remove
+	}{
+		{
+			Path:  "/instance_info/image_source",
+			Value: host.Spec.Image.URL,
+			Op:    nodes.AddOp,
+		},
+		{
+			Path: "/instance_info/image_os_hash_algo",
+			Op:   nodes.RemoveOp,
+		},
+		{
+			Path: "/instance_info/image_os_hash_value",
+			Op:   nodes.RemoveOp,
+		},
+		{
+			Path:  "/instance_info/image_pull_secret",
+			Value: "dXNlcjpwYXNzd29yZA==",
+			Op:    nodes.AddOp,
+		},
+	}
+
+	for _, e := range expected {
+		t.Run(e.Path, func(t *testing.T) {
+			var update nodes.UpdateOperation
+			for _, patch := range patches {
+				update = patch.(nodes.UpdateOperation)
+				if update.Path == e.Path {
+					break
+				}
+			}
+			if update.Path != e.Path {
+				t.Errorf("did not find %q in updates", e.Path)
+				return
+			}
+			assert.Equal(t, e.Op, update.Op, fmt.Sprintf("%s operation does not match", e.Path))
+			assert.Equal(t, e.Value, update.Value, fmt.Sprintf("%s does not match", e.Path))
+		})
+	}
+}
+
+func TestGetUpdateOptsForNodeCachedImage(t *testing.T) {
+	eventPublisher := func(reason, message string) {}
+	auth := clients.AuthConfig{Type: clients.NoAuth}
+
+	host := makeHost()
+	host.Spec.Image = &metal3v1alpha1.Image{
+		URL:          "http://example.com/image.qcow2",
+		Checksum:     "thechecksum",
+		ChecksumType: metal3v1alpha1.SHA256,
+	}
+	prov, err := newProvisionerWithSettings(host, bmc.Credentials{}, eventPublisher,
+		"https://ironic.test", auth, "https://ironic.test", auth,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ironicNode := &nodes.Node{}
+
+	cachedImageURL := "http://image-cache:8090/images/0123/image.qcow2"
+	provData := provisioner.ProvisionData{
+		Image:          *host.Spec.Image,
+		BootMode:       metal3v1alpha1.DefaultBootMode,
+		CachedImageURL: cachedImageURL,
+	}
+	patches := prov.getUpdateOptsForNode(ironicNode, provData).Updates
+
+	t.Logf("patches: %v", patches)
+
+	expected := []struct {
+		Path  string      // the node property path
+		Value interface{} // the value being passed to ironic
+	}{
+		{
+			Path:  "/instance_info/image_source",
+			Value: cachedImageURL,
+		},
+		{
+			Path:  "/instance_info/image_os_hash_value",
+			Value: host.Spec.Image.Checksum,
+		},
+	}
+
+	for _, e := range expected {
+		t.Run(e.Path, func(t *testing.T) {
+			var update nodes.UpdateOperation
+			for _, patch := range patches {
+				update = patch.(nodes.UpdateOperation)
+				if update.Path == e.Path {
+					break
+				}
+			}
+			if update.Path != e.Path {
+				t.Errorf("did not find %q in updates", e.Path)
+				return
+			}
+			assert.Equal(t, e.Value, update.Value, fmt.Sprintf("%s does not match", e.Path))
+		})
+	}
+}
+
 func TestGetUpdateOptsForNodeCustomDeploy(t *testing.T) {
 	eventPublisher := func(reason, message string) {}
 	auth := clients.AuthConfig{Type: clients.NoAuth}
@@ -25,8 +25,11 @@ type HostData struct {
 	BMCAddress                     string
 	BMCCredentials                 bmc.Credentials
 	DisableCertificateVerification bool
+	BMCCACertificates              []byte
+	BMCCertificateFingerprint      []byte
 	BootMACAddress                 string
 	ProvisionerID                  string
+	Provisioner                    string
 }
 
 func BuildHostData(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials) HostData {
@@ -37,6 +40,7 @@ func BuildHostData(host metal3v1alpha1.BareMetalHost, bmcCreds bmc.Credentials)
 		DisableCertificateVerification: host.Spec.BMC.DisableCertificateVerification,
 		BootMACAddress:                 host.Spec.BootMACAddress,
 		ProvisionerID:                  host.Status.Provisioning.ID,
+		Provisioner:                    host.Spec.Provisioner,
 	}
 }
 
@@ -45,6 +49,7 @@ func BuildHostDataNoBMC(host metal3v1alpha1.BareMetalHost) HostData {
 	return HostData{
 		ObjectMeta:    *host.ObjectMeta.DeepCopy(),
 		ProvisionerID: host.Status.Provisioning.ID,
+		Provisioner:   host.Spec.Provisioner,
 	}
 }
 
@@ -53,6 +58,30 @@ type Factory interface {
 	NewProvisioner(hostData HostData, publish EventPublisher) (Provisioner, error)
 }
 
+// BMCDetailsFactory is implemented by the factories of provisioners
+// managing hosts directly through their BMC, rather than through a
+// node held by the provisioning backend. Their provisioners need the
+// BMC address and credentials of the host even in the controllers that
+// do not manage the BMC.
+type BMCDetailsFactory interface {
+	RequiresBMCDetails() bool
+}
+
+// RequiresBMCDetails reports whether the provisioner the factory
+// returns for the host data needs the BMC address and credentials of
+// the host.
+func RequiresBMCDetails(factory Factory, hostData HostData) bool {
+	if registry, ok := factory.(*Registry); ok {
+		selected, err := registry.Get(hostData.Provisioner)
+		if err != nil {
+			return false
+		}
+		factory = selected
+	}
+	detailsFactory, ok := factory.(BMCDetailsFactory)
+	return ok && detailsFactory.RequiresBMCDetails()
+}
+
 // HostConfigData retrieves host configuration data
 type HostConfigData interface {
 	// UserData is the interface for a function to retrieve user
@@ -96,13 +125,16 @@ type InspectData struct {
 // ActualFirmwareSettings are the complete settings retrieved from the BMC, the names and
 // values are vendor specific.
 // TargetFirmwareSettings contains values that the user has changed.
+// TargetFirmwareComponents contains the firmware images to flash onto
+// the host components.
 type PrepareData struct {
-	TargetRAIDConfig       *metal3v1alpha1.RAIDConfig
-	ActualRAIDConfig       *metal3v1alpha1.RAIDConfig
-	RootDeviceHints        *metal3v1alpha1.RootDeviceHints
-	FirmwareConfig         *metal3v1alpha1.FirmwareConfig
-	TargetFirmwareSettings metal3v1alpha1.DesiredSettingsMap
-	ActualFirmwareSettings metal3v1alpha1.SettingsMap
+	TargetRAIDConfig         *metal3v1alpha1.RAIDConfig
+	ActualRAIDConfig         *metal3v1alpha1.RAIDConfig
+	RootDeviceHints          *metal3v1alpha1.RootDeviceHints
+	FirmwareConfig           *metal3v1alpha1.FirmwareConfig
+	TargetFirmwareSettings   metal3v1alpha1.DesiredSettingsMap
+	ActualFirmwareSettings   metal3v1alpha1.SettingsMap
+	TargetFirmwareComponents []metal3v1alpha1.FirmwareUpdate
 }
 
 type ProvisionData struct {
@@ -112,6 +144,12 @@ type ProvisionData struct {
 	HardwareProfile profile.Profile
 	RootDeviceHints *metal3v1alpha1.RootDeviceHints
 	CustomDeploy    *metal3v1alpha1.CustomDeploy
+	// ImagePullSecret holds the base64-encoded "user:password"
+	// credentials for the registry of an OCI image, if any.
+	ImagePullSecret string
+	// CachedImageURL is the URL the image cache of the operator serves
+	// the image at, to be deployed instead of the URL of the image.
+	CachedImageURL string
 }
 
 type HTTPHeaders []map[string]string
@@ -151,6 +189,11 @@ type Provisioner interface {
 	// Prepare remove existing configuration and set new configuration
 	Prepare(data PrepareData, unprepared bool, restartOnFailure bool) (result Result, started bool, err error)
 
+	// Service applies new configuration to a provisioned host without
+	// deprovisioning it. If `started` is true, servicing of the host
+	// was requested from the provisioner.
+	Service(data PrepareData, unprepared bool, restartOnFailure bool) (result Result, started bool, err error)
+
 	// Provision writes the image from the host spec to the host. It
 	// may be called multiple times, and should return true for its
 	// dirty flag until the provisioning operation is completed.
@@ -173,6 +216,13 @@ type Provisioner interface {
 	// deletion operation is completed.
 	Detach() (result Result, err error)
 
+	// Abort stops the operation currently waiting on the host, such
+	// as inspection or cleaning, leaving it failed. Operations that
+	// cannot be stopped are left to fail on their own. It may be
+	// called multiple times, and should return true for its dirty flag
+	// until the operation has been stopped.
+	Abort() (result Result, err error)
+
 	// PowerOn ensures the server is powered on independently of any image
 	// provisioning operation.
 	PowerOn(force bool) (result Result, err error)
@@ -192,11 +242,29 @@ type Provisioner interface {
 	// GetFirmwareSettings gets the BIOS settings and optional schema from the host and returns maps
 	GetFirmwareSettings(includeSchema bool) (settings metal3v1alpha1.SettingsMap, schema map[string]metal3v1alpha1.SettingSchema, err error)
 
+	// GetFirmwareComponents returns the installed version of the
+	// firmware components of the host by component name: bios, bmc and
+	// nic:<id> for network interfaces. Components whose version cannot
+	// be read are left out.
+	GetFirmwareComponents() (versions map[string]string, err error)
+
 	// AddBMCEventSubscriptionForNode creates the subscription, and updates Status.SubscriptionID
 	AddBMCEventSubscriptionForNode(subscription *metal3v1alpha1.BMCEventSubscription, httpHeaders HTTPHeaders) (result Result, err error)
 
 	// RemoveBMCEventSubscriptionForNode delete the subscription
 	RemoveBMCEventSubscriptionForNode(subscription metal3v1alpha1.BMCEventSubscription) (result Result, err error)
+
+	// CreateBMCAccount creates the BMC account of the credentials,
+	// with the role of the account used to manage the host, or
+	// enables it and sets its password when it exists. It may be
+	// called again with the same credentials after a creation was
+	// interrupted. An error message in the result means the account
+	// was not created.
+	CreateBMCAccount(creds bmc.Credentials) (result Result, err error)
+
+	// DisableBMCAccount disables the BMC account with the user name,
+	// which must not be the account used to manage the host.
+	DisableBMCAccount(username string) (result Result, err error)
 }
 
 // Result holds the response from a call in the Provsioner API.
@@ -209,6 +277,19 @@ type Result struct {
 	RequeueAfter time.Duration
 	// Any error message produced by the provisioner.
 	ErrorMessage string
+	// Progress of the operation, when the provisioner reports it.
+	Progress *Progress
+}
+
+// Progress describes how far a long running operation on the host got.
+type Progress struct {
+	// Step is the state of the host in the provisioning backend.
+	Step string
+	// CurrentStep is the name of the deploy or clean step being run.
+	CurrentStep string
+	// LastHeartbeat is the time the agent running on the host last
+	// reported to the provisioning backend.
+	LastHeartbeat *time.Time
 }
 
 // HardwareState holds the response from an UpdateHardwareState call
@@ -216,6 +297,10 @@ type HardwareState struct {
 	// PoweredOn is a pointer to a bool indicating whether the Host is currently
 	// powered on. The value is nil if the power state cannot be determined.
 	PoweredOn *bool
+	// Health holds the health of the hardware components of the Host.
+	// It is nil if the health cannot be determined or is not tracked
+	// by the backend.
+	Health []metal3v1alpha1.ComponentHealth
 }
 
 // ErrNeedsRegistration is returned if the host is not registered
@@ -0,0 +1,157 @@
+package redfish
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+)
+
+const accountServicePath = "/redfish/v1/AccountService"
+
+type accountService struct {
+	Accounts odataID `json:"Accounts"`
+}
+
+type managerAccount struct {
+	ID       string `json:"Id"`
+	UserName string `json:"UserName"`
+	RoleID   string `json:"RoleId"`
+	Enabled  *bool  `json:"Enabled"`
+
+	path string
+}
+
+// AccountError is returned by CreateAccount and DisableAccount when
+// the BMC refused the change, which was therefore not made.
+type AccountError struct {
+	message string
+}
+
+func (e AccountError) Error() string {
+	return e.message
+}
+
+// CreateAccount creates the BMC account of newCreds, with the role of
+// the account creds belong to, through the AccountService of the
+// Redfish API at the address, and checks that the new credentials are
+// accepted. An existing account with the user name is enabled and its
+// password set instead. The certificate of the BMC is verified
+// according to tlsConfig. Nothing is changed when the BMC already
+// accepts the new credentials, so that an interrupted creation can be
+// completed by calling it again.
+func CreateAccount(address, systemPath string, creds, newCreds bmc.Credentials, tlsConfig *tls.Config) error {
+	created := newClient(address, systemPath, newCreds.Username, newCreds.Password, tlsConfig)
+	if _, err := created.system(); err == nil {
+		return nil
+	}
+
+	c := newClient(address, systemPath, creds.Username, creds.Password, tlsConfig)
+	collection, accounts, err := c.accounts()
+	if err != nil {
+		return err
+	}
+	current := findAccount(accounts, creds.Username)
+	if current == nil {
+		return AccountError{message: fmt.Sprintf("no BMC account found for user %s", creds.Username)}
+	}
+
+	settings := map[string]interface{}{
+		"UserName": newCreds.Username,
+		"Password": newCreds.Password,
+		"RoleId":   current.RoleID,
+		"Enabled":  true,
+	}
+	if existing := findAccount(accounts, newCreds.Username); existing != nil {
+		err = c.patch(existing.path, settings)
+	} else {
+		err = c.post(collection, settings)
+		// BMCs with a fixed number of accounts do not allow creating
+		// one, but accept naming an unused account
+		var httpErr httpError
+		if free := findAccount(accounts, ""); errors.As(err, &httpErr) && free != nil {
+			err = c.patch(free.path, settings)
+		}
+	}
+	if err != nil {
+		var httpErr httpError
+		if errors.As(err, &httpErr) {
+			return AccountError{message: fmt.Sprintf("the BMC refused to create account %s: %s", newCreds.Username, httpErr)}
+		}
+		return errors.Wrap(err, "failed to create the BMC account")
+	}
+
+	if _, err := created.system(); err != nil {
+		return errors.Wrap(err, "the BMC does not accept the new credentials")
+	}
+	return nil
+}
+
+// DisableAccount disables the BMC account with the user name, using
+// the credentials of another account, so that its credentials are no
+// longer accepted. Nothing is changed when the account does not exist
+// or is already disabled.
+func DisableAccount(address, systemPath string, creds bmc.Credentials, username string, tlsConfig *tls.Config) error {
+	if username == creds.Username {
+		return AccountError{message: fmt.Sprintf("cannot disable BMC account %s with its own credentials", username)}
+	}
+
+	c := newClient(address, systemPath, creds.Username, creds.Password, tlsConfig)
+	_, accounts, err := c.accounts()
+	if err != nil {
+		return err
+	}
+	account := findAccount(accounts, username)
+	if account == nil || (account.Enabled != nil && !*account.Enabled) {
+		return nil
+	}
+
+	if err := c.patch(account.path, map[string]bool{"Enabled": false}); err != nil {
+		var httpErr httpError
+		if errors.As(err, &httpErr) {
+			return AccountError{message: fmt.Sprintf("the BMC refused to disable account %s: %s", username, httpErr)}
+		}
+		return errors.Wrap(err, "failed to disable the BMC account")
+	}
+	return nil
+}
+
+// accounts returns the path of the account collection of the
+// AccountService and the accounts it holds
+func (c *client) accounts() (string, []managerAccount, error) {
+	service := &accountService{}
+	if err := c.get(accountServicePath, service); err != nil {
+		var httpErr httpError
+		if errors.As(err, &httpErr) {
+			return "", nil, AccountError{message: fmt.Sprintf("failed to access the BMC accounts: %s", httpErr)}
+		}
+		return "", nil, errors.Wrap(err, "failed to access the BMC accounts")
+	}
+
+	links, err := c.members(service.Accounts)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to list the BMC accounts")
+	}
+	accounts := make([]managerAccount, 0, len(links))
+	for _, link := range links {
+		account := managerAccount{}
+		if err := c.get(link.ID, &account); err != nil {
+			return "", nil, errors.Wrap(err, "failed to get BMC account")
+		}
+		account.path = link.ID
+		accounts = append(accounts, account)
+	}
+	return service.Accounts.ID, accounts, nil
+}
+
+// findAccount returns the account with the user name, or nil
+func findAccount(accounts []managerAccount, username string) *managerAccount {
+	for i := range accounts {
+		if accounts[i].UserName == username {
+			return &accounts[i]
+		}
+	}
+	return nil
+}
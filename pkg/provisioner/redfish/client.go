@@ -0,0 +1,327 @@
+package redfish
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+)
+
+var requestTimeout = time.Second * 30
+
+// odataID is a link to another Redfish resource
+type odataID struct {
+	ID string `json:"@odata.id"`
+}
+
+type collection struct {
+	Members []odataID `json:"Members"`
+}
+
+type action struct {
+	Target string `json:"target"`
+}
+
+// status is the state and health of a resource
+type status struct {
+	State        string `json:"State"`
+	Health       string `json:"Health"`
+	HealthRollup string `json:"HealthRollup"`
+}
+
+type computerSystem struct {
+	ID           string `json:"Id"`
+	UUID         string `json:"UUID"`
+	HostName     string `json:"HostName"`
+	Manufacturer string `json:"Manufacturer"`
+	Model        string `json:"Model"`
+	SerialNumber string `json:"SerialNumber"`
+	PowerState   string `json:"PowerState"`
+	BiosVersion  string `json:"BiosVersion"`
+	Status       status `json:"Status"`
+
+	Boot struct {
+		BootSourceOverrideTarget  string `json:"BootSourceOverrideTarget,omitempty"`
+		BootSourceOverrideEnabled string `json:"BootSourceOverrideEnabled,omitempty"`
+		BootSourceOverrideMode    string `json:"BootSourceOverrideMode,omitempty"`
+	} `json:"Boot"`
+
+	ProcessorSummary struct {
+		Count  int    `json:"Count"`
+		Model  string `json:"Model"`
+		Status status `json:"Status"`
+	} `json:"ProcessorSummary"`
+
+	MemorySummary struct {
+		TotalSystemMemoryGiB float64 `json:"TotalSystemMemoryGiB"`
+		Status               status  `json:"Status"`
+	} `json:"MemorySummary"`
+
+	Processors         odataID `json:"Processors"`
+	EthernetInterfaces odataID `json:"EthernetInterfaces"`
+	Storage            odataID `json:"Storage"`
+	Bios               odataID `json:"Bios"`
+
+	Links struct {
+		ManagedBy []odataID `json:"ManagedBy"`
+		Chassis   []odataID `json:"Chassis"`
+	} `json:"Links"`
+
+	Actions struct {
+		Reset action `json:"#ComputerSystem.Reset"`
+	} `json:"Actions"`
+}
+
+type processor struct {
+	Model                 string  `json:"Model"`
+	MaxSpeedMHz           float64 `json:"MaxSpeedMHz"`
+	TotalCores            int     `json:"TotalCores"`
+	TotalThreads          int     `json:"TotalThreads"`
+	ProcessorArchitecture string  `json:"ProcessorArchitecture"`
+	InstructionSet        string  `json:"InstructionSet"`
+}
+
+type ethernetInterface struct {
+	ID            string `json:"Id"`
+	Name          string `json:"Name"`
+	MACAddress    string `json:"MACAddress"`
+	SpeedMbps     int    `json:"SpeedMbps"`
+	IPv4Addresses []struct {
+		Address string `json:"Address"`
+	} `json:"IPv4Addresses"`
+}
+
+type storage struct {
+	Drives []odataID `json:"Drives"`
+}
+
+type drive struct {
+	Name          string `json:"Name"`
+	Model         string `json:"Model"`
+	Manufacturer  string `json:"Manufacturer"`
+	SerialNumber  string `json:"SerialNumber"`
+	MediaType     string `json:"MediaType"`
+	Protocol      string `json:"Protocol"`
+	CapacityBytes int64  `json:"CapacityBytes"`
+}
+
+type chassis struct {
+	ID              string  `json:"Id"`
+	Status          status  `json:"Status"`
+	NetworkAdapters odataID `json:"NetworkAdapters"`
+}
+
+type networkAdapter struct {
+	ID          string `json:"Id"`
+	Controllers []struct {
+		FirmwarePackageVersion string `json:"FirmwarePackageVersion"`
+	} `json:"Controllers"`
+}
+
+type manager struct {
+	FirmwareVersion string  `json:"FirmwareVersion"`
+	VirtualMedia    odataID `json:"VirtualMedia"`
+}
+
+type virtualMedia struct {
+	ID         string   `json:"Id"`
+	Image      string   `json:"Image"`
+	Inserted   bool     `json:"Inserted"`
+	MediaTypes []string `json:"MediaTypes"`
+
+	Actions struct {
+		InsertMedia action `json:"#VirtualMedia.InsertMedia"`
+		EjectMedia  action `json:"#VirtualMedia.EjectMedia"`
+	} `json:"Actions"`
+}
+
+type bios struct {
+	Attributes map[string]interface{} `json:"Attributes"`
+	Settings   struct {
+		SettingsObject odataID `json:"SettingsObject"`
+	} `json:"@Redfish.Settings"`
+}
+
+// httpError is returned when the BMC answers a request with an error
+// status code
+type httpError struct {
+	method     string
+	path       string
+	statusCode int
+}
+
+func (e httpError) Error() string {
+	return fmt.Sprintf("%s %s returned %d %s", e.method, e.path,
+		e.statusCode, http.StatusText(e.statusCode))
+}
+
+// client is a minimal client for the Redfish API of a single system
+type client struct {
+	endpoint   string
+	systemPath string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// TLSConfig returns the configuration used to connect to a BMC, which
+// checks its certificate against the pinned SHA-256 fingerprint when
+// given, or verifies it against the PEM-encoded CA certificates when
+// given, or the system trust store otherwise.
+func TLSConfig(insecure bool, caCertificates, fingerprint []byte) (*tls.Config, error) {
+	if insecure {
+		return &tls.Config{InsecureSkipVerify: true}, nil // #nosec
+	}
+	if len(fingerprint) != 0 {
+		return bmc.PinnedTLSConfig(fingerprint), nil
+	}
+	if len(caCertificates) == 0 {
+		return nil, nil
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCertificates) {
+		return nil, errors.New("no valid BMC CA certificate found")
+	}
+	return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
+}
+
+func newClient(endpoint, systemPath, username, password string, tlsConfig *tls.Config) *client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &client{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		systemPath: systemPath,
+		username:   username,
+		password:   password,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   requestTimeout,
+		},
+	}
+}
+
+func (c *client) do(method, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.endpoint+path, reader)
+	if err != nil {
+		return err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return httpError{method: method, path: path, statusCode: resp.StatusCode}
+	}
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func (c *client) get(path string, result interface{}) error {
+	return c.do(http.MethodGet, path, nil, result)
+}
+
+func (c *client) patch(path string, body interface{}) error {
+	return c.do(http.MethodPatch, path, body, nil)
+}
+
+func (c *client) post(path string, body interface{}) error {
+	return c.do(http.MethodPost, path, body, nil)
+}
+
+func (c *client) system() (*computerSystem, error) {
+	system := &computerSystem{}
+	if err := c.get(c.systemPath, system); err != nil {
+		return nil, err
+	}
+	return system, nil
+}
+
+func (c *client) reset(system *computerSystem, resetType string) error {
+	target := system.Actions.Reset.Target
+	if target == "" {
+		target = c.systemPath + "/Actions/ComputerSystem.Reset"
+	}
+	return c.post(target, map[string]string{"ResetType": resetType})
+}
+
+// members returns the links of the members of a collection
+func (c *client) members(link odataID) ([]odataID, error) {
+	if link.ID == "" {
+		return nil, nil
+	}
+	coll := &collection{}
+	if err := c.get(link.ID, coll); err != nil {
+		return nil, err
+	}
+	return coll.Members, nil
+}
+
+// virtualMedia returns the virtual CD or DVD drive of the manager of
+// the system
+func (c *client) virtualMedia(system *computerSystem) (*virtualMedia, string, error) {
+	for _, managerLink := range system.Links.ManagedBy {
+		mgr := &manager{}
+		if err := c.get(managerLink.ID, mgr); err != nil {
+			return nil, "", err
+		}
+		links, err := c.members(mgr.VirtualMedia)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, link := range links {
+			media := &virtualMedia{}
+			if err := c.get(link.ID, media); err != nil {
+				return nil, "", err
+			}
+			for _, mediaType := range media.MediaTypes {
+				if mediaType == "CD" || mediaType == "DVD" {
+					return media, link.ID, nil
+				}
+			}
+		}
+	}
+	return nil, "", fmt.Errorf("no virtual CD drive found for system %s", c.systemPath)
+}
+
+func (c *client) bios(system *computerSystem) (*bios, error) {
+	if system.Bios.ID == "" {
+		return nil, fmt.Errorf("system %s does not expose BIOS settings", c.systemPath)
+	}
+	result := &bios{}
+	if err := c.get(system.Bios.ID, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,131 @@
+package redfish
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+)
+
+const serviceRootPath = "/redfish/v1/"
+
+// serviceRoot is the entry point of the Redfish API, which BMCs serve
+// without authentication
+type serviceRoot struct {
+	RedfishVersion string  `json:"RedfishVersion"`
+	UUID           string  `json:"UUID"`
+	Vendor         string  `json:"Vendor"`
+	Product        string  `json:"Product"`
+	Systems        odataID `json:"Systems"`
+}
+
+// DiscoveredSystem is a system managed by a discovered BMC
+type DiscoveredSystem struct {
+	// Path is the path of the system in the Redfish API
+	Path string
+	// HardwareDetails holds the manufacturer, model, serial number
+	// and NICs of the system
+	HardwareDetails *metal3v1alpha1.HardwareDetails
+}
+
+// DiscoveredBMC is a BMC found by Discover
+type DiscoveredBMC struct {
+	// Endpoint is the URL of the BMC
+	Endpoint string
+	// UntrustedCertificate is true when the certificate of the BMC
+	// could not be verified against the trusted CA certificates
+	UntrustedCertificate bool
+	Vendor               string
+	Product              string
+	// Systems holds the systems of the BMC, which is empty when the
+	// BMC requires credentials to list them, or when the connection to
+	// the BMC can not be trusted with the credentials
+	Systems []DiscoveredSystem
+}
+
+// Discover reads the Redfish service root at the endpoint, and the
+// systems it manages using the credentials, when given. The
+// credentials are only sent over HTTPS to a BMC whose certificate is
+// verified against rootCAs, or the system trust store when nil. An
+// error is returned when no Redfish service answers.
+func Discover(endpoint string, creds bmc.Credentials, rootCAs *x509.CertPool, timeout time.Duration) (*DiscoveredBMC, error) {
+	found := &DiscoveredBMC{Endpoint: endpoint}
+
+	// The service root does not require authentication, so it is read
+	// even when the certificate of the BMC is not trusted
+	tlsConfig := &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+	root := &serviceRoot{}
+	err := newDiscoveryClient(endpoint, bmc.Credentials{}, tlsConfig, timeout).get(serviceRootPath, root)
+	if isCertificateError(err) {
+		found.UntrustedCertificate = true
+		tlsConfig = &tls.Config{InsecureSkipVerify: true} // #nosec
+		err = newDiscoveryClient(endpoint, bmc.Credentials{}, tlsConfig, timeout).get(serviceRootPath, root)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if root.RedfishVersion == "" {
+		return nil, errors.Errorf("%s is not a Redfish service", endpoint)
+	}
+	found.Vendor = root.Vendor
+	found.Product = root.Product
+
+	// Any device on the network may answer, so the credentials are
+	// never sent to a BMC that has not proved its identity
+	if found.UntrustedCertificate || !strings.HasPrefix(endpoint, "https://") {
+		creds = bmc.Credentials{}
+	}
+
+	c := newDiscoveryClient(endpoint, creds, tlsConfig, timeout)
+	links, err := c.members(root.Systems)
+	if err != nil {
+		var httpErr httpError
+		if errors.As(err, &httpErr) &&
+			(httpErr.statusCode == http.StatusUnauthorized || httpErr.statusCode == http.StatusForbidden) {
+			return found, nil
+		}
+		return nil, err
+	}
+	for _, link := range links {
+		system := &computerSystem{}
+		if err := c.get(link.ID, system); err != nil {
+			return nil, err
+		}
+		nics, err := c.getNICs(system)
+		if err != nil {
+			return nil, err
+		}
+		found.Systems = append(found.Systems, DiscoveredSystem{
+			Path: link.ID,
+			HardwareDetails: &metal3v1alpha1.HardwareDetails{
+				SystemVendor: metal3v1alpha1.HardwareSystemVendor{
+					Manufacturer: system.Manufacturer,
+					ProductName:  system.Model,
+					SerialNumber: system.SerialNumber,
+				},
+				Hostname: system.HostName,
+				NIC:      nics,
+			},
+		})
+	}
+	return found, nil
+}
+
+func newDiscoveryClient(endpoint string, creds bmc.Credentials, tlsConfig *tls.Config, timeout time.Duration) *client {
+	c := newClient(endpoint, "", creds.Username, creds.Password, tlsConfig)
+	c.httpClient.Timeout = timeout
+	return c
+}
+
+func isCertificateError(err error) bool {
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
@@ -0,0 +1,46 @@
+package redfish
+
+import (
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+)
+
+const nameSeparator = "~"
+
+type redfishProvisionerFactory struct {
+	log logr.Logger
+}
+
+// NewProvisionerFactory returns a factory for provisioners managing
+// hosts directly through the Redfish API of their BMC, without Ironic.
+func NewProvisionerFactory(logger logr.Logger) provisioner.Factory {
+	return redfishProvisionerFactory{
+		log: logger.WithName("redfish"),
+	}
+}
+
+func hostName(objMeta metav1.ObjectMeta) string {
+	return objMeta.Namespace + nameSeparator + objMeta.Name
+}
+
+// RequiresBMCDetails implements provisioner.BMCDetailsFactory, as
+// every host is managed through its own BMC.
+func (f redfishProvisionerFactory) RequiresBMCDetails() bool {
+	return true
+}
+
+// NewProvisioner returns a new Redfish Provisioner for the host.
+func (f redfishProvisionerFactory) NewProvisioner(hostData provisioner.HostData, publisher provisioner.EventPublisher) (provisioner.Provisioner, error) {
+	return &redfishProvisioner{
+		objectMeta:              hostData.ObjectMeta,
+		bmcAddress:              hostData.BMCAddress,
+		bmcCreds:                hostData.BMCCredentials,
+		disableCertVerification: hostData.DisableCertificateVerification,
+		caCertificates:          hostData.BMCCACertificates,
+		certificateFingerprint:  hostData.BMCCertificateFingerprint,
+		log:                     f.log.WithValues("host", hostName(hostData.ObjectMeta)),
+		publisher:               publisher,
+	}, nil
+}
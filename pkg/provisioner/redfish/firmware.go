@@ -0,0 +1,76 @@
+package redfish
+
+import (
+	"crypto/tls"
+
+	"github.com/pkg/errors"
+
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+)
+
+// Names of the firmware components, as used in HostFirmwareComponents
+const (
+	biosComponent      = "bios"
+	bmcComponent       = "bmc"
+	nicComponentPrefix = "nic:"
+)
+
+// firmwareVersions returns the installed version of the firmware
+// components of the system: the BIOS of the system, the firmware of
+// the manager of the system and the firmware of the network adapters
+// of its chassis, named nic:<adapter id>. Components whose version is
+// not reported are left out.
+func (c *client) firmwareVersions(system *computerSystem) (map[string]string, error) {
+	versions := map[string]string{}
+	if system.BiosVersion != "" {
+		versions[biosComponent] = system.BiosVersion
+	}
+
+	for _, link := range system.Links.ManagedBy {
+		mgr := &manager{}
+		if err := c.get(link.ID, mgr); err != nil {
+			return nil, errors.Wrap(err, "failed to get the manager of the system")
+		}
+		if mgr.FirmwareVersion != "" {
+			versions[bmcComponent] = mgr.FirmwareVersion
+			break
+		}
+	}
+
+	for _, link := range system.Links.Chassis {
+		result := &chassis{}
+		if err := c.get(link.ID, result); err != nil {
+			return nil, errors.Wrap(err, "failed to get the chassis of the system")
+		}
+		adapters, err := c.members(result.NetworkAdapters)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list the network adapters")
+		}
+		for _, adapterLink := range adapters {
+			adapter := &networkAdapter{}
+			if err := c.get(adapterLink.ID, adapter); err != nil {
+				return nil, errors.Wrap(err, "failed to get network adapter")
+			}
+			for _, controller := range adapter.Controllers {
+				if controller.FirmwarePackageVersion != "" {
+					versions[nicComponentPrefix+adapter.ID] = controller.FirmwarePackageVersion
+					break
+				}
+			}
+		}
+	}
+	return versions, nil
+}
+
+// FirmwareVersions returns the installed version of the firmware
+// components of the system at systemPath, read through the Redfish API
+// at the address, by component name: bios, bmc and nic:<id>. The
+// certificate of the BMC is verified according to tlsConfig.
+func FirmwareVersions(address, systemPath string, creds bmc.Credentials, tlsConfig *tls.Config) (map[string]string, error) {
+	c := newClient(address, systemPath, creds.Username, creds.Password, tlsConfig)
+	system, err := c.system()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the system from the BMC")
+	}
+	return c.firmwareVersions(system)
+}
@@ -0,0 +1,51 @@
+package redfish
+
+import (
+	"github.com/pkg/errors"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+// health returns the health of the resource and the resources it
+// contains, or an empty string when the BMC does not report it.
+func (s status) health() metal3v1alpha1.HealthState {
+	health := s.HealthRollup
+	if health == "" {
+		health = s.Health
+	}
+	switch state := metal3v1alpha1.HealthState(health); state {
+	case metal3v1alpha1.HealthOK, metal3v1alpha1.HealthWarning, metal3v1alpha1.HealthCritical:
+		return state
+	}
+	return ""
+}
+
+// componentHealth returns the health of the components of the system,
+// read from the summaries of the system and from the chassis
+// containing it, whose health includes its power supplies, fans and
+// drives. Components without a health are left out.
+func (c *client) componentHealth(system *computerSystem) ([]metal3v1alpha1.ComponentHealth, error) {
+	components := []metal3v1alpha1.ComponentHealth{}
+	add := func(name string, s status) {
+		if health := s.health(); health != "" {
+			components = append(components, metal3v1alpha1.ComponentHealth{Name: name, Health: health})
+		}
+	}
+
+	add("System", system.Status)
+	add("Processors", system.ProcessorSummary.Status)
+	add("Memory", system.MemorySummary.Status)
+
+	for _, link := range system.Links.Chassis {
+		result := &chassis{}
+		if err := c.get(link.ID, result); err != nil {
+			return nil, errors.Wrap(err, "failed to get the chassis of the system")
+		}
+		name := "Chassis"
+		if len(system.Links.Chassis) > 1 {
+			name = "Chassis " + result.ID
+		}
+		add(name, result.Status)
+	}
+	return components, nil
+}
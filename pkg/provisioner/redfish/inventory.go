@@ -0,0 +1,158 @@
+package redfish
+
+import (
+	"strings"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+)
+
+// getHardwareDetails builds the hardware details of the host from the
+// Redfish system inventory
+func (c *client) getHardwareDetails(system *computerSystem) (*metal3v1alpha1.HardwareDetails, error) {
+	details := &metal3v1alpha1.HardwareDetails{
+		SystemVendor: metal3v1alpha1.HardwareSystemVendor{
+			Manufacturer: system.Manufacturer,
+			ProductName:  system.Model,
+			SerialNumber: system.SerialNumber,
+		},
+		Firmware: metal3v1alpha1.Firmware{
+			BIOS: metal3v1alpha1.BIOS{
+				Version: system.BiosVersion,
+			},
+		},
+		RAMMebibytes: int(system.MemorySummary.TotalSystemMemoryGiB * 1024),
+		Hostname:     system.HostName,
+	}
+
+	cpu, err := c.getCPU(system)
+	if err != nil {
+		return nil, err
+	}
+	details.CPU = cpu
+
+	details.NIC, err = c.getNICs(system)
+	if err != nil {
+		return nil, err
+	}
+
+	details.Storage, err = c.getStorage(system)
+	if err != nil {
+		return nil, err
+	}
+
+	return details, nil
+}
+
+func (c *client) getCPU(system *computerSystem) (cpu metal3v1alpha1.CPU, err error) {
+	cpu.Model = system.ProcessorSummary.Model
+
+	links, err := c.members(system.Processors)
+	if err != nil {
+		return
+	}
+	for _, link := range links {
+		proc := &processor{}
+		if err = c.get(link.ID, proc); err != nil {
+			return
+		}
+		if proc.TotalCores == 0 {
+			// not a populated CPU socket
+			continue
+		}
+		if cpu.Model == "" {
+			cpu.Model = proc.Model
+		}
+		if cpu.Arch == "" {
+			cpu.Arch = cpuArch(proc)
+		}
+		if proc.MaxSpeedMHz > float64(cpu.ClockMegahertz) {
+			cpu.ClockMegahertz = metal3v1alpha1.ClockSpeed(proc.MaxSpeedMHz)
+		}
+		threads := proc.TotalThreads
+		if threads == 0 {
+			threads = proc.TotalCores
+		}
+		cpu.Count += threads
+	}
+	return
+}
+
+// cpuArch converts the Redfish instruction set of a processor to the
+// architecture names used in the hardware details
+func cpuArch(proc *processor) string {
+	switch proc.InstructionSet {
+	case "x86-64":
+		return "x86_64"
+	case "x86":
+		return "i686"
+	case "ARM-A64":
+		return "aarch64"
+	case "PowerISA":
+		return "ppc64le"
+	}
+	return strings.ToLower(proc.ProcessorArchitecture)
+}
+
+func (c *client) getNICs(system *computerSystem) (nics []metal3v1alpha1.NIC, err error) {
+	links, err := c.members(system.EthernetInterfaces)
+	if err != nil {
+		return
+	}
+	for _, link := range links {
+		iface := &ethernetInterface{}
+		if err = c.get(link.ID, iface); err != nil {
+			return
+		}
+		nic := metal3v1alpha1.NIC{
+			Name:      iface.ID,
+			MAC:       strings.ToLower(iface.MACAddress),
+			SpeedGbps: iface.SpeedMbps / 1000,
+		}
+		if len(iface.IPv4Addresses) != 0 {
+			nic.IP = iface.IPv4Addresses[0].Address
+		}
+		nics = append(nics, nic)
+	}
+	return
+}
+
+func (c *client) getStorage(system *computerSystem) (disks []metal3v1alpha1.Storage, err error) {
+	links, err := c.members(system.Storage)
+	if err != nil {
+		return
+	}
+	for _, link := range links {
+		controller := &storage{}
+		if err = c.get(link.ID, controller); err != nil {
+			return
+		}
+		for _, driveLink := range controller.Drives {
+			d := &drive{}
+			if err = c.get(driveLink.ID, d); err != nil {
+				return
+			}
+			disks = append(disks, metal3v1alpha1.Storage{
+				Name:         d.Name,
+				Type:         diskType(d),
+				Rotational:   d.MediaType == "HDD",
+				SizeBytes:    metal3v1alpha1.Capacity(d.CapacityBytes),
+				Vendor:       d.Manufacturer,
+				Model:        d.Model,
+				SerialNumber: d.SerialNumber,
+			})
+		}
+	}
+	return
+}
+
+func diskType(d *drive) metal3v1alpha1.DiskType {
+	switch {
+	case d.Protocol == "NVMe":
+		return metal3v1alpha1.NVME
+	case d.MediaType == "SSD":
+		return metal3v1alpha1.SSD
+	case d.MediaType == "HDD":
+		return metal3v1alpha1.HDD
+	}
+	return ""
+}
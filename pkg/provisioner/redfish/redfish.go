@@ -0,0 +1,667 @@
+package redfish
+
+import (
+	"crypto/tls"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+)
+
+var (
+	powerRequeueDelay     = time.Second * 10
+	provisionRequeueDelay = time.Second * 10
+)
+
+const (
+	powerOn          = "On"
+	powerOff         = "Off"
+	powerPoweringOn  = "PoweringOn"
+	powerPoweringOff = "PoweringOff"
+
+	resetOn               = "On"
+	resetForceOff         = "ForceOff"
+	resetForceRestart     = "ForceRestart"
+	resetGracefulShutdown = "GracefulShutdown"
+
+	bootTargetCd       = "Cd"
+	bootContinuous     = "Continuous"
+	bootDisabled       = "Disabled"
+	bootModeUEFI       = "UEFI"
+	bootModeLegacyBIOS = "Legacy"
+)
+
+// redfishProvisioner implements the provisioner.Provisioner interface
+// by talking directly to the Redfish API of the host BMC.
+type redfishProvisioner struct {
+	// the object metadata of the BareMetalHost resource
+	objectMeta metav1.ObjectMeta
+	// the address of the BMC
+	bmcAddress string
+	// the bmc credentials
+	bmcCreds bmc.Credentials
+	// whether to verify the BMC certificate
+	disableCertVerification bool
+	// the CA certificates to verify the BMC certificate against
+	caCertificates []byte
+	// the pinned SHA-256 fingerprint of the BMC certificate
+	certificateFingerprint []byte
+	// a logger configured for this host
+	log logr.Logger
+	// an event publisher for recording significant events
+	publisher provisioner.EventPublisher
+}
+
+// bmcAccess returns the access details of the BMC and a client for its
+// Redfish API
+func (p *redfishProvisioner) bmcAccess() (bmc.AccessDetails, *client, error) {
+	bmcAccess, err := bmc.NewAccessDetails(p.bmcAddress, p.disableCertVerification)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse BMC address information")
+	}
+
+	driverInfo := bmcAccess.DriverInfo(p.bmcCreds)
+	address, _ := driverInfo["redfish_address"].(string)
+	systemPath, _ := driverInfo["redfish_system_id"].(string)
+	if address == "" || systemPath == "" {
+		return nil, nil, fmt.Errorf("BMC driver %s is not supported by the redfish provisioner", bmcAccess.Driver())
+	}
+
+	tlsConfig, err := TLSConfig(p.disableCertVerification, p.caCertificates, p.certificateFingerprint)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return bmcAccess, newClient(address, systemPath,
+		p.bmcCreds.Username, p.bmcCreds.Password, tlsConfig), nil
+}
+
+func (p *redfishProvisioner) system() (*client, *computerSystem, error) {
+	_, c, err := p.bmcAccess()
+	if err != nil {
+		return nil, nil, err
+	}
+	system, err := c.system()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get the system from the BMC")
+	}
+	return c, system, nil
+}
+
+// ValidateManagementAccess checks that the BMC can be reached with the
+// credentials of the host. The ID of the Redfish system is used as the
+// provisioning ID.
+func (p *redfishProvisioner) ValidateManagementAccess(data provisioner.ManagementAccessData, credentialsChanged, restartOnFailure bool) (result provisioner.Result, provID string, err error) {
+	p.log.Info("validating management access")
+
+	_, c, err := p.bmcAccess()
+	if err != nil {
+		result, err = operationFailed(err.Error())
+		return
+	}
+
+	system, err := c.system()
+	if err != nil {
+		var httpErr httpError
+		if errors.As(err, &httpErr) {
+			// The BMC answered, but does not accept the request
+			result, err = operationFailed(fmt.Sprintf("failed to access the BMC: %s", httpErr))
+			return
+		}
+		result, err = transientError(errors.Wrap(err, "failed to connect to the BMC"))
+		return
+	}
+
+	provID = system.UUID
+	if provID == "" {
+		provID = c.systemPath
+	}
+	result, err = operationComplete()
+	return
+}
+
+// PreprovisioningImageFormats returns a list of acceptable formats for a
+// pre-provisioning image to be built by a PreprovisioningImage object.
+// No pre-provisioning image is used by this provisioner.
+func (p *redfishProvisioner) PreprovisioningImageFormats() ([]metal3v1alpha1.ImageFormat, error) {
+	return nil, nil
+}
+
+// InspectHardware reads the inventory of the system from the BMC. The
+// host is not booted, so the inspection completes immediately.
+func (p *redfishProvisioner) InspectHardware(data provisioner.InspectData, restartOnFailure, refresh, forceReboot bool) (result provisioner.Result, started bool, details *metal3v1alpha1.HardwareDetails, err error) {
+	p.log.Info("inspecting hardware")
+
+	c, system, err := p.system()
+	if err != nil {
+		result, err = transientError(err)
+		return
+	}
+
+	details, err = c.getHardwareDetails(system)
+	if err != nil {
+		result, err = transientError(errors.Wrap(err, "failed to read the system inventory"))
+		return
+	}
+
+	p.publisher("InspectionComplete", "Hardware inventory read from the BMC")
+	started = true
+	result, err = operationComplete()
+	return
+}
+
+// UpdateHardwareState fetches the latest power state of the server and
+// the health of the components of the system and of its chassis. The
+// health is left unknown when a chassis cannot be read.
+func (p *redfishProvisioner) UpdateHardwareState() (hwState provisioner.HardwareState, err error) {
+	c, system, err := p.system()
+	if err != nil {
+		return
+	}
+
+	switch system.PowerState {
+	case powerOn:
+		poweredOn := true
+		hwState.PoweredOn = &poweredOn
+	case powerOff:
+		poweredOn := false
+		hwState.PoweredOn = &poweredOn
+	default:
+		p.log.Info("unknown power state", "state", system.PowerState)
+	}
+
+	health, healthErr := c.componentHealth(system)
+	if healthErr != nil {
+		p.log.Info("could not determine hardware health", "error", healthErr.Error())
+		return hwState, nil
+	}
+	hwState.Health = health
+	return
+}
+
+// Adopt brings an externally-provisioned host under management. There
+// is nothing to register with the BMC.
+func (p *redfishProvisioner) Adopt(data provisioner.AdoptData, restartOnFailure bool) (result provisioner.Result, err error) {
+	return operationComplete()
+}
+
+// buildBIOSAttributes returns the BIOS attributes that have to change
+// to apply the firmware configuration and settings.
+func buildBIOSAttributes(bmcAccess bmc.AccessDetails, data provisioner.PrepareData) (attributes map[string]interface{}, err error) {
+	var firmwareConfig *bmc.FirmwareConfig
+	if data.FirmwareConfig != nil {
+		bmcConfig := bmc.FirmwareConfig(*data.FirmwareConfig)
+		firmwareConfig = &bmcConfig
+	}
+	fwConfigSettings, err := bmcAccess.BuildBIOSSettings(firmwareConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes = make(map[string]interface{})
+	for _, setting := range fwConfigSettings {
+		if data.ActualFirmwareSettings[setting["name"]] != setting["value"] {
+			attributes[setting["name"]] = setting["value"]
+		}
+	}
+	for name, value := range data.TargetFirmwareSettings {
+		if _, exists := attributes[name]; exists {
+			// Settings converted by the BMC driver take precedence
+			continue
+		}
+		if data.ActualFirmwareSettings[name] == value.String() {
+			continue
+		}
+		if value.Type == intstr.Int {
+			attributes[name] = value.IntValue()
+		} else {
+			attributes[name] = value.String()
+		}
+	}
+	return attributes, nil
+}
+
+// biosSettingsPending returns true if BIOS settings have been requested
+// but not applied yet
+func (c *client) biosSettingsPending(system *computerSystem) (bool, error) {
+	current, err := c.bios(system)
+	if err != nil {
+		return false, err
+	}
+	if current.Settings.SettingsObject.ID == "" {
+		return false, nil
+	}
+	pending := &bios{}
+	if err := c.get(current.Settings.SettingsObject.ID, pending); err != nil {
+		return false, err
+	}
+	for name, value := range pending.Attributes {
+		if fmt.Sprint(current.Attributes[name]) != fmt.Sprint(value) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// configure applies the BIOS settings to the host and restarts it so
+// that they take effect.
+func (p *redfishProvisioner) configure(data provisioner.PrepareData, unprepared bool) (result provisioner.Result, started bool, err error) {
+	bmcAccess, c, err := p.bmcAccess()
+	if err != nil {
+		result, err = transientError(err)
+		return
+	}
+	system, err := c.system()
+	if err != nil {
+		result, err = transientError(errors.Wrap(err, "failed to get the system from the BMC"))
+		return
+	}
+
+	if !unprepared {
+		pending, err := c.biosSettingsPending(system)
+		if err != nil {
+			result, err = transientError(errors.Wrap(err, "failed to get the BIOS settings"))
+			return result, started, err
+		}
+		if pending {
+			p.log.Info("waiting for BIOS settings to be applied")
+			result, err = operationContinuing(provisionRequeueDelay)
+			return result, started, err
+		}
+		result, err = operationComplete()
+		return result, started, err
+	}
+
+	if len(data.TargetFirmwareComponents) != 0 {
+		result, err = operationFailed("firmware updates are not supported by the redfish provisioner")
+		return
+	}
+	if data.TargetRAIDConfig != nil && !reflect.DeepEqual(data.TargetRAIDConfig, data.ActualRAIDConfig) &&
+		(len(data.TargetRAIDConfig.HardwareRAIDVolumes) != 0 || len(data.TargetRAIDConfig.SoftwareRAIDVolumes) != 0) {
+		result, err = operationFailed("RAID configuration is not supported by the redfish provisioner")
+		return
+	}
+
+	attributes, err := buildBIOSAttributes(bmcAccess, data)
+	if err != nil {
+		result, err = operationFailed(err.Error())
+		return
+	}
+	started = true
+	if len(attributes) == 0 {
+		result, err = operationComplete()
+		return
+	}
+
+	current, err := c.bios(system)
+	if err != nil {
+		result, err = transientError(errors.Wrap(err, "failed to get the BIOS settings"))
+		return
+	}
+	settingsPath := current.Settings.SettingsObject.ID
+	if settingsPath == "" {
+		settingsPath = system.Bios.ID + "/Settings"
+	}
+	p.log.Info("applying BIOS settings", "settings", attributes)
+	if err = c.patch(settingsPath, map[string]interface{}{"Attributes": attributes}); err != nil {
+		started = false
+		result, err = transientError(errors.Wrap(err, "failed to change the BIOS settings"))
+		return
+	}
+
+	// The settings are applied by the BIOS on the next boot
+	resetType := resetOn
+	if system.PowerState == powerOn {
+		resetType = resetForceRestart
+	}
+	if err = c.reset(system, resetType); err != nil {
+		result, err = transientError(errors.Wrap(err, "failed to restart the host"))
+		return
+	}
+	result, err = operationContinuing(provisionRequeueDelay)
+	return
+}
+
+// Prepare applies the BIOS settings of the host. RAID configuration and
+// firmware updates are not supported.
+func (p *redfishProvisioner) Prepare(data provisioner.PrepareData, unprepared bool, restartOnFailure bool) (result provisioner.Result, started bool, err error) {
+	p.log.Info("preparing host")
+	return p.configure(data, unprepared)
+}
+
+// Service applies the BIOS settings of a provisioned host.
+func (p *redfishProvisioner) Service(data provisioner.PrepareData, unprepared bool, restartOnFailure bool) (result provisioner.Result, started bool, err error) {
+	p.log.Info("servicing host")
+	return p.configure(data, unprepared)
+}
+
+func bootSourceOverrideMode(bootMode metal3v1alpha1.BootMode) string {
+	if bootMode == metal3v1alpha1.Legacy {
+		return bootModeLegacyBIOS
+	}
+	return bootModeUEFI
+}
+
+// Provision boots the host from the live ISO in the host spec using
+// virtual media. Only live ISO images are supported.
+func (p *redfishProvisioner) Provision(data provisioner.ProvisionData, forceReboot bool) (result provisioner.Result, err error) {
+	p.log.Info("provisioning image to host")
+
+	if !data.Image.IsLiveISO() {
+		return operationFailed("only live-iso images are supported by the redfish provisioner")
+	}
+
+	c, system, err := p.system()
+	if err != nil {
+		return transientError(err)
+	}
+
+	media, mediaPath, err := c.virtualMedia(system)
+	if err != nil {
+		return operationFailed(err.Error())
+	}
+
+	if media.Inserted && media.Image != data.Image.URL {
+		p.log.Info("ejecting virtual media", "image", media.Image)
+		if err = c.ejectMedia(media, mediaPath); err != nil {
+			return transientError(errors.Wrap(err, "failed to eject virtual media"))
+		}
+		return operationContinuing(0)
+	}
+	if !media.Inserted {
+		p.log.Info("inserting virtual media", "image", data.Image.URL)
+		if err = c.insertMedia(media, mediaPath, data.Image.URL); err != nil {
+			return transientError(errors.Wrap(err, "failed to insert virtual media"))
+		}
+		return operationContinuing(0)
+	}
+
+	mode := bootSourceOverrideMode(data.BootMode)
+	if system.Boot.BootSourceOverrideTarget != bootTargetCd || system.Boot.BootSourceOverrideEnabled != bootContinuous {
+		p.log.Info("setting boot device to virtual media")
+		err = c.patch(c.systemPath, map[string]interface{}{
+			"Boot": map[string]string{
+				"BootSourceOverrideTarget":  bootTargetCd,
+				"BootSourceOverrideEnabled": bootContinuous,
+				"BootSourceOverrideMode":    mode,
+			},
+		})
+		if err != nil {
+			return transientError(errors.Wrap(err, "failed to set the boot device"))
+		}
+
+		resetType := resetOn
+		if system.PowerState == powerOn {
+			resetType = resetForceRestart
+		}
+		if err = c.reset(system, resetType); err != nil {
+			return transientError(errors.Wrap(err, "failed to boot the host"))
+		}
+		p.publisher("ProvisioningStarted", fmt.Sprintf("Booting host from %s", data.Image.URL))
+		return operationContinuing(provisionRequeueDelay)
+	}
+
+	switch system.PowerState {
+	case powerOn:
+		p.publisher("ProvisioningComplete", fmt.Sprintf("Host booted from %s", data.Image.URL))
+		return operationComplete()
+	case powerPoweringOn:
+		return operationContinuing(provisionRequeueDelay)
+	}
+	if err = c.reset(system, resetOn); err != nil {
+		return transientError(errors.Wrap(err, "failed to boot the host"))
+	}
+	return operationContinuing(provisionRequeueDelay)
+}
+
+func (c *client) insertMedia(media *virtualMedia, mediaPath, image string) error {
+	target := media.Actions.InsertMedia.Target
+	if target == "" {
+		target = mediaPath + "/Actions/VirtualMedia.InsertMedia"
+	}
+	return c.post(target, map[string]interface{}{
+		"Image":          image,
+		"Inserted":       true,
+		"WriteProtected": true,
+	})
+}
+
+func (c *client) ejectMedia(media *virtualMedia, mediaPath string) error {
+	target := media.Actions.EjectMedia.Target
+	if target == "" {
+		target = mediaPath + "/Actions/VirtualMedia.EjectMedia"
+	}
+	return c.post(target, map[string]interface{}{})
+}
+
+// Deprovision ejects the live ISO, removes the boot device override
+// and powers the host off.
+func (p *redfishProvisioner) Deprovision(restartOnFailure bool) (result provisioner.Result, err error) {
+	p.log.Info("deprovisioning host")
+
+	c, system, err := p.system()
+	if err != nil {
+		return transientError(err)
+	}
+
+	media, mediaPath, err := c.virtualMedia(system)
+	if err != nil {
+		return operationFailed(err.Error())
+	}
+	if media.Inserted {
+		p.log.Info("ejecting virtual media", "image", media.Image)
+		if err = c.ejectMedia(media, mediaPath); err != nil {
+			return transientError(errors.Wrap(err, "failed to eject virtual media"))
+		}
+		return operationContinuing(0)
+	}
+
+	if system.Boot.BootSourceOverrideEnabled != "" && system.Boot.BootSourceOverrideEnabled != bootDisabled {
+		p.log.Info("removing boot device override")
+		err = c.patch(c.systemPath, map[string]interface{}{
+			"Boot": map[string]string{
+				"BootSourceOverrideEnabled": bootDisabled,
+			},
+		})
+		if err != nil {
+			return transientError(errors.Wrap(err, "failed to remove the boot device override"))
+		}
+		return operationContinuing(0)
+	}
+
+	return p.PowerOff(metal3v1alpha1.RebootModeHard, true)
+}
+
+// Delete removes the host from the provisioning system. Nothing is
+// stored outside of the BMC, so there is nothing to remove.
+func (p *redfishProvisioner) Delete() (result provisioner.Result, err error) {
+	return operationComplete()
+}
+
+// Detach removes the host from the provisioning system. Nothing is
+// stored outside of the BMC, so there is nothing to remove.
+func (p *redfishProvisioner) Detach() (result provisioner.Result, err error) {
+	return operationComplete()
+}
+
+// Abort stops the operation currently waiting on the host. The
+// operations of this provisioner are not run in the background, so
+// there is nothing to stop.
+func (p *redfishProvisioner) Abort() (result provisioner.Result, err error) {
+	return operationComplete()
+}
+
+// PowerOn ensures the server is powered on independently of any image
+// provisioning operation.
+func (p *redfishProvisioner) PowerOn(force bool) (result provisioner.Result, err error) {
+	p.log.Info("ensuring host is powered on")
+
+	c, system, err := p.system()
+	if err != nil {
+		return transientError(err)
+	}
+
+	switch system.PowerState {
+	case powerOn:
+		return operationComplete()
+	case powerPoweringOn:
+		p.log.Info("waiting for power status to change")
+		return operationContinuing(powerRequeueDelay)
+	}
+
+	if err = c.reset(system, resetOn); err != nil {
+		return transientError(errors.Wrap(err, "failed to power on the host"))
+	}
+	p.publisher("PowerOn", "Host powered on")
+	return operationContinuing(powerRequeueDelay)
+}
+
+// PowerOff ensures the server is powered off independently of any image
+// provisioning operation. A soft power off asks the operating system to
+// shut down, unless force is set.
+func (p *redfishProvisioner) PowerOff(rebootMode metal3v1alpha1.RebootMode, force bool) (result provisioner.Result, err error) {
+	p.log.Info(fmt.Sprintf("ensuring host is powered off (mode: %s)", rebootMode))
+
+	c, system, err := p.system()
+	if err != nil {
+		return transientError(err)
+	}
+
+	switch system.PowerState {
+	case powerOff:
+		return operationComplete()
+	case powerPoweringOff:
+		p.log.Info("waiting for power status to change")
+		return operationContinuing(powerRequeueDelay)
+	}
+
+	resetType := resetForceOff
+	reason := "Host powered off"
+	if rebootMode == metal3v1alpha1.RebootModeSoft && !force {
+		resetType = resetGracefulShutdown
+		reason = "Host soft powered off"
+	}
+	if err = c.reset(system, resetType); err != nil {
+		return transientError(errors.Wrap(err, "failed to power off the host"))
+	}
+	p.publisher("PowerOff", reason)
+	return operationContinuing(powerRequeueDelay)
+}
+
+// IsReady checks if the provisioning backend is available. The BMCs are
+// contacted directly, so it always is.
+func (p *redfishProvisioner) IsReady() (result bool, err error) {
+	return true, nil
+}
+
+// HasCapacity checks if the backend has a free (de)provisioning slot
+// for the current host. Every host is managed through its own BMC.
+func (p *redfishProvisioner) HasCapacity() (result bool, err error) {
+	return true, nil
+}
+
+// GetFirmwareSettings gets the BIOS attributes of the host. The schema
+// is derived from the type of the current values.
+func (p *redfishProvisioner) GetFirmwareSettings(includeSchema bool) (settings metal3v1alpha1.SettingsMap, schema map[string]metal3v1alpha1.SettingSchema, err error) {
+	c, system, err := p.system()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current, err := c.bios(system)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not get BIOS settings")
+	}
+	p.log.Info("retrieved BIOS settings", "size", len(current.Attributes))
+
+	settings = make(metal3v1alpha1.SettingsMap)
+	schema = make(map[string]metal3v1alpha1.SettingSchema)
+	for name, value := range current.Attributes {
+		settings[name] = fmt.Sprint(value)
+
+		if includeSchema {
+			schema[name] = metal3v1alpha1.SettingSchema{
+				AttributeType: attributeType(value),
+			}
+		}
+	}
+	return settings, schema, nil
+}
+
+func attributeType(value interface{}) string {
+	switch value.(type) {
+	case bool:
+		return "Boolean"
+	case float64:
+		return "Integer"
+	}
+	return "String"
+}
+
+// AddBMCEventSubscriptionForNode is not supported by this provisioner.
+func (p *redfishProvisioner) AddBMCEventSubscriptionForNode(subscription *metal3v1alpha1.BMCEventSubscription, httpHeaders provisioner.HTTPHeaders) (result provisioner.Result, err error) {
+	return operationFailed("BMC event subscriptions are not supported by the redfish provisioner")
+}
+
+// RemoveBMCEventSubscriptionForNode is not supported by this provisioner.
+func (p *redfishProvisioner) RemoveBMCEventSubscriptionForNode(subscription metal3v1alpha1.BMCEventSubscription) (result provisioner.Result, err error) {
+	return operationComplete()
+}
+
+// GetFirmwareComponents reads the installed firmware versions from the
+// system, its manager and its network adapters.
+func (p *redfishProvisioner) GetFirmwareComponents() (versions map[string]string, err error) {
+	c, system, err := p.system()
+	if err != nil {
+		return nil, err
+	}
+	return c.firmwareVersions(system)
+}
+
+// CreateBMCAccount creates the BMC account through the Redfish
+// AccountService.
+func (p *redfishProvisioner) CreateBMCAccount(creds bmc.Credentials) (result provisioner.Result, err error) {
+	return p.changeAccounts(func(c *client, tlsConfig *tls.Config) error {
+		p.log.Info("creating BMC account", "username", creds.Username)
+		return CreateAccount(c.endpoint, c.systemPath, p.bmcCreds, creds, tlsConfig)
+	})
+}
+
+// DisableBMCAccount disables the BMC account through the Redfish
+// AccountService.
+func (p *redfishProvisioner) DisableBMCAccount(username string) (result provisioner.Result, err error) {
+	return p.changeAccounts(func(c *client, tlsConfig *tls.Config) error {
+		p.log.Info("disabling BMC account", "username", username)
+		return DisableAccount(c.endpoint, c.systemPath, p.bmcCreds, username, tlsConfig)
+	})
+}
+
+func (p *redfishProvisioner) changeAccounts(change func(*client, *tls.Config) error) (result provisioner.Result, err error) {
+	_, c, err := p.bmcAccess()
+	if err != nil {
+		return operationFailed(err.Error())
+	}
+
+	tlsConfig, err := TLSConfig(p.disableCertVerification, p.caCertificates, p.certificateFingerprint)
+	if err != nil {
+		return operationFailed(err.Error())
+	}
+
+	err = change(c, tlsConfig)
+	var accountErr AccountError
+	if errors.As(err, &accountErr) {
+		return operationFailed(accountErr.Error())
+	}
+	if err != nil {
+		return transientError(err)
+	}
+	return operationComplete()
+}
@@ -0,0 +1,691 @@
+package redfish
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	metal3v1alpha1 "github.com/metal3-io/baremetal-operator/apis/metal3.io/v1alpha1"
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner/redfish/testserver"
+)
+
+func nullEventPublisher(reason, message string) {}
+
+func newTestProvisioner(t *testing.T, mock *testserver.RedfishMock) provisioner.Provisioner {
+	factory := NewProvisionerFactory(logr.Discard())
+	prov, err := factory.NewProvisioner(provisioner.HostData{
+		ObjectMeta:     metav1.ObjectMeta{Name: "myhost", Namespace: "myns"},
+		BMCAddress:     mock.BMCAddress(),
+		BMCCredentials: bmc.Credentials{Username: "admin", Password: "password"},
+	}, nullEventPublisher)
+	if err != nil {
+		t.Fatalf("could not create provisioner: %s", err)
+	}
+	return prov
+}
+
+func TestValidateManagementAccess(t *testing.T) {
+	cases := []struct {
+		name           string
+		username       string
+		bmcAddress     string
+		expectedError  bool
+		expectedProvID string
+	}{
+		{
+			name:           "valid credentials",
+			username:       "admin",
+			expectedProvID: "e4a1f2b0-6f4c-4f60-9a3b-3c2b1f0d9e11",
+		},
+		{
+			name:          "invalid credentials",
+			username:      "root",
+			expectedError: true,
+		},
+		{
+			name:          "unsupported driver",
+			username:      "admin",
+			bmcAddress:    "ipmi://192.0.2.1",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := testserver.NewRedfish(t).WithCredentials(tc.username, "password").Start()
+			defer mock.Stop()
+
+			prov := newTestProvisioner(t, mock)
+			if tc.bmcAddress != "" {
+				prov.(*redfishProvisioner).bmcAddress = tc.bmcAddress
+			}
+
+			result, provID, err := prov.ValidateManagementAccess(provisioner.ManagementAccessData{}, false, false)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedError, result.ErrorMessage != "")
+			assert.Equal(t, tc.expectedProvID, provID)
+		})
+	}
+}
+
+func TestPinnedCertificate(t *testing.T) {
+	mock := testserver.NewRedfish(t).WithCredentials("admin", "password").StartTLS()
+	defer mock.Stop()
+	caCertificates := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: mock.Certificate().Raw})
+	fingerprint := bmc.CertificateFingerprint(mock.Certificate())
+
+	cases := []struct {
+		name           string
+		caCertificates []byte
+		fingerprint    []byte
+		expectedError  bool
+	}{
+		{
+			name:        "pinned certificate",
+			fingerprint: fingerprint,
+		},
+		{
+			name:          "other pinned certificate",
+			fingerprint:   make([]byte, len(fingerprint)),
+			expectedError: true,
+		},
+		{
+			name:           "certificate trusted as a CA",
+			caCertificates: caCertificates,
+			expectedError:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			prov := newTestProvisioner(t, mock).(*redfishProvisioner)
+			// The certificate of the mock is not valid for localhost
+			prov.bmcAddress = strings.Replace(mock.BMCAddress(), "127.0.0.1", "localhost", 1)
+			prov.caCertificates = tc.caCertificates
+			prov.certificateFingerprint = tc.fingerprint
+
+			result, provID, err := prov.ValidateManagementAccess(provisioner.ManagementAccessData{}, false, false)
+
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Empty(t, result.ErrorMessage)
+			assert.NotEmpty(t, provID)
+		})
+	}
+}
+
+func TestPower(t *testing.T) {
+	cases := []struct {
+		name           string
+		powerState     string
+		powerOn        bool
+		rebootMode     metal3v1alpha1.RebootMode
+		force          bool
+		expectedDirty  bool
+		expectedResets []string
+	}{
+		{
+			name:           "power on",
+			powerState:     "Off",
+			powerOn:        true,
+			expectedDirty:  true,
+			expectedResets: []string{"On"},
+		},
+		{
+			name:       "already on",
+			powerState: "On",
+			powerOn:    true,
+		},
+		{
+			name:          "powering on",
+			powerState:    "PoweringOn",
+			powerOn:       true,
+			expectedDirty: true,
+		},
+		{
+			name:           "soft power off",
+			powerState:     "On",
+			rebootMode:     metal3v1alpha1.RebootModeSoft,
+			expectedDirty:  true,
+			expectedResets: []string{"GracefulShutdown"},
+		},
+		{
+			name:           "forced soft power off",
+			powerState:     "On",
+			rebootMode:     metal3v1alpha1.RebootModeSoft,
+			force:          true,
+			expectedDirty:  true,
+			expectedResets: []string{"ForceOff"},
+		},
+		{
+			name:           "hard power off",
+			powerState:     "On",
+			rebootMode:     metal3v1alpha1.RebootModeHard,
+			expectedDirty:  true,
+			expectedResets: []string{"ForceOff"},
+		},
+		{
+			name:       "already off",
+			powerState: "Off",
+			rebootMode: metal3v1alpha1.RebootModeHard,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := testserver.NewRedfish(t).WithPowerState(tc.powerState).Start()
+			defer mock.Stop()
+			prov := newTestProvisioner(t, mock)
+
+			var result provisioner.Result
+			var err error
+			if tc.powerOn {
+				result, err = prov.PowerOn(false)
+			} else {
+				result, err = prov.PowerOff(tc.rebootMode, tc.force)
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedDirty, result.Dirty)
+			assert.Equal(t, tc.expectedResets, mock.Resets)
+		})
+	}
+}
+
+func TestUpdateHardwareState(t *testing.T) {
+	mock := testserver.NewRedfish(t).WithPowerState("On").Start()
+	defer mock.Stop()
+	prov := newTestProvisioner(t, mock)
+
+	hwState, err := prov.UpdateHardwareState()
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, hwState.PoweredOn) {
+		assert.True(t, *hwState.PoweredOn)
+	}
+}
+
+func TestUpdateHardwareStateHealth(t *testing.T) {
+	testCases := []struct {
+		name           string
+		memoryHealth   string
+		chassisHealth  string
+		expectedHealth []metal3v1alpha1.ComponentHealth
+	}{
+		{
+			name:          "healthy",
+			memoryHealth:  "OK",
+			chassisHealth: "OK",
+			expectedHealth: []metal3v1alpha1.ComponentHealth{
+				{Name: "System", Health: metal3v1alpha1.HealthOK},
+				{Name: "Processors", Health: metal3v1alpha1.HealthOK},
+				{Name: "Memory", Health: metal3v1alpha1.HealthOK},
+				{Name: "Chassis", Health: metal3v1alpha1.HealthOK},
+			},
+		},
+		{
+			name:          "failed power supply",
+			memoryHealth:  "OK",
+			chassisHealth: "Critical",
+			expectedHealth: []metal3v1alpha1.ComponentHealth{
+				{Name: "System", Health: metal3v1alpha1.HealthOK},
+				{Name: "Processors", Health: metal3v1alpha1.HealthOK},
+				{Name: "Memory", Health: metal3v1alpha1.HealthOK},
+				{Name: "Chassis", Health: metal3v1alpha1.HealthCritical},
+			},
+		},
+		{
+			name:          "memory health not reported",
+			chassisHealth: "Warning",
+			expectedHealth: []metal3v1alpha1.ComponentHealth{
+				{Name: "System", Health: metal3v1alpha1.HealthOK},
+				{Name: "Processors", Health: metal3v1alpha1.HealthOK},
+				{Name: "Chassis", Health: metal3v1alpha1.HealthWarning},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := testserver.NewRedfish(t).WithPowerState("On").WithChassisHealth(tc.chassisHealth)
+			mock.MemoryHealth = tc.memoryHealth
+			mock.Start()
+			defer mock.Stop()
+			prov := newTestProvisioner(t, mock)
+
+			hwState, err := prov.UpdateHardwareState()
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedHealth, hwState.Health)
+		})
+	}
+}
+
+func TestInspectHardware(t *testing.T) {
+	mock := testserver.NewRedfish(t).Start()
+	defer mock.Stop()
+	prov := newTestProvisioner(t, mock)
+
+	result, started, details, err := prov.InspectHardware(provisioner.InspectData{}, false, false, false)
+
+	assert.NoError(t, err)
+	assert.True(t, started)
+	assert.False(t, result.Dirty)
+	assert.Equal(t, &metal3v1alpha1.HardwareDetails{
+		SystemVendor: metal3v1alpha1.HardwareSystemVendor{
+			Manufacturer: "Dell Inc.",
+			ProductName:  "PowerEdge R640",
+			SerialNumber: "CN7475189J0123",
+		},
+		Firmware: metal3v1alpha1.Firmware{
+			BIOS: metal3v1alpha1.BIOS{Version: "2.12.2"},
+		},
+		RAMMebibytes: 196608,
+		NIC: []metal3v1alpha1.NIC{
+			{
+				Name:      "NIC1",
+				MAC:       "00:5c:52:31:3a:9c",
+				IP:        "192.0.2.10",
+				SpeedGbps: 25,
+			},
+		},
+		Storage: []metal3v1alpha1.Storage{
+			{
+				Name:         "Solid State Drive",
+				Type:         metal3v1alpha1.SSD,
+				SizeBytes:    480103981056,
+				Vendor:       "SAMSUNG",
+				Model:        "MZ7LH480HAHQ",
+				SerialNumber: "S45PNA0M",
+			},
+		},
+		CPU: metal3v1alpha1.CPU{
+			Arch:           "x86_64",
+			Model:          "Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz",
+			ClockMegahertz: 3900,
+			Count:          80,
+		},
+		Hostname: "edge-01",
+	}, details)
+}
+
+func TestProvision(t *testing.T) {
+	liveISO := "live-iso"
+	image := metal3v1alpha1.Image{
+		URL:        "http://images.test/live.iso",
+		DiskFormat: &liveISO,
+	}
+
+	mock := testserver.NewRedfish(t).WithMedia("http://images.test/old.iso").Start()
+	defer mock.Stop()
+	prov := newTestProvisioner(t, mock)
+	data := provisioner.ProvisionData{Image: image, BootMode: metal3v1alpha1.UEFI}
+
+	// The old image is ejected and the new one inserted
+	result, err := prov.Provision(data, false)
+	assert.NoError(t, err)
+	assert.True(t, result.Dirty)
+	assert.False(t, mock.MediaInserted)
+
+	result, err = prov.Provision(data, false)
+	assert.NoError(t, err)
+	assert.True(t, result.Dirty)
+	assert.True(t, mock.MediaInserted)
+	assert.Equal(t, image.URL, mock.MediaImage)
+
+	// The host is booted from the virtual media
+	result, err = prov.Provision(data, false)
+	assert.NoError(t, err)
+	assert.True(t, result.Dirty)
+	assert.Equal(t, "Cd", mock.BootTarget)
+	assert.Equal(t, "Continuous", mock.BootEnabled)
+	assert.Equal(t, "UEFI", mock.BootMode)
+	assert.Equal(t, []string{"On"}, mock.Resets)
+
+	result, err = prov.Provision(data, false)
+	assert.NoError(t, err)
+	assert.False(t, result.Dirty)
+	assert.Empty(t, result.ErrorMessage)
+
+	// Deprovisioning ejects the image and powers the host off
+	for i := 0; i < 5; i++ {
+		result, err = prov.Deprovision(false)
+		assert.NoError(t, err)
+		if !result.Dirty {
+			break
+		}
+	}
+	assert.False(t, result.Dirty)
+	assert.False(t, mock.MediaInserted)
+	assert.Equal(t, "Disabled", mock.BootEnabled)
+	assert.Equal(t, "Off", mock.PowerState)
+}
+
+func TestProvisionUnsupportedImage(t *testing.T) {
+	mock := testserver.NewRedfish(t).Start()
+	defer mock.Stop()
+	prov := newTestProvisioner(t, mock)
+
+	result, err := prov.Provision(provisioner.ProvisionData{
+		Image: metal3v1alpha1.Image{URL: "http://images.test/image.qcow2"},
+	}, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "only live-iso images are supported by the redfish provisioner", result.ErrorMessage)
+	assert.Empty(t, mock.Requests)
+}
+
+func TestPrepare(t *testing.T) {
+	cases := []struct {
+		name               string
+		data               provisioner.PrepareData
+		powerState         string
+		expectedStarted    bool
+		expectedDirty      bool
+		expectedError      string
+		expectedResets     []string
+		expectedAttributes map[string]interface{}
+	}{
+		{
+			name:            "no changes",
+			powerState:      "Off",
+			expectedStarted: true,
+			expectedAttributes: map[string]interface{}{
+				"ProcVirtualization": "Enabled",
+				"BootDelay":          float64(5),
+			},
+		},
+		{
+			name: "change settings",
+			data: provisioner.PrepareData{
+				ActualFirmwareSettings: metal3v1alpha1.SettingsMap{
+					"ProcVirtualization": "Enabled",
+					"BootDelay":          "5",
+				},
+				TargetFirmwareSettings: metal3v1alpha1.DesiredSettingsMap{
+					"ProcVirtualization": intstr.FromString("Disabled"),
+					"BootDelay":          intstr.FromInt(5),
+				},
+			},
+			powerState:      "On",
+			expectedStarted: true,
+			expectedDirty:   true,
+			expectedResets:  []string{"ForceRestart"},
+			expectedAttributes: map[string]interface{}{
+				"ProcVirtualization": "Disabled",
+				"BootDelay":          float64(5),
+			},
+		},
+		{
+			name: "firmware updates",
+			data: provisioner.PrepareData{
+				TargetFirmwareComponents: []metal3v1alpha1.FirmwareUpdate{
+					{Component: "bios", Version: "2.15.1", URL: "http://images.test/bios.bin"},
+				},
+			},
+			expectedError: "firmware updates are not supported by the redfish provisioner",
+		},
+		{
+			name: "RAID",
+			data: provisioner.PrepareData{
+				TargetRAIDConfig: &metal3v1alpha1.RAIDConfig{
+					HardwareRAIDVolumes: []metal3v1alpha1.HardwareRAIDVolume{{Level: "1"}},
+				},
+			},
+			expectedError: "RAID configuration is not supported by the redfish provisioner",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := testserver.NewRedfish(t).WithPowerState(tc.powerState).Start()
+			defer mock.Stop()
+			prov := newTestProvisioner(t, mock)
+
+			result, started, err := prov.Prepare(tc.data, true, false)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStarted, started)
+			assert.Equal(t, tc.expectedDirty, result.Dirty)
+			assert.Equal(t, tc.expectedError, result.ErrorMessage)
+			assert.Equal(t, tc.expectedResets, mock.Resets)
+			if tc.expectedAttributes != nil {
+				assert.Equal(t, tc.expectedAttributes, mock.BIOSAttributes)
+			}
+
+			// Once the settings are applied, preparing completes
+			result, started, err = prov.Prepare(tc.data, false, false)
+			assert.NoError(t, err)
+			assert.False(t, started)
+			assert.False(t, result.Dirty)
+		})
+	}
+}
+
+func TestGetFirmwareSettings(t *testing.T) {
+	mock := testserver.NewRedfish(t).Start()
+	defer mock.Stop()
+	prov := newTestProvisioner(t, mock)
+
+	settings, schema, err := prov.GetFirmwareSettings(true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, metal3v1alpha1.SettingsMap{
+		"ProcVirtualization": "Enabled",
+		"BootDelay":          "5",
+	}, settings)
+	assert.Equal(t, map[string]metal3v1alpha1.SettingSchema{
+		"ProcVirtualization": {AttributeType: "String"},
+		"BootDelay":          {AttributeType: "Integer"},
+	}, schema)
+}
+
+func TestGetFirmwareComponents(t *testing.T) {
+	mock := testserver.NewRedfish(t).Start()
+	defer mock.Stop()
+
+	prov := newTestProvisioner(t, mock)
+	versions, err := prov.GetFirmwareComponents()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"bios":           "2.12.2",
+		"bmc":            "5.10.00.00",
+		"nic:NIC.Slot.1": "22.31.6",
+	}, versions)
+}
+
+func TestCreateBMCAccount(t *testing.T) {
+	cases := []struct {
+		name          string
+		username      string
+		password      string
+		fixed         bool
+		existing      bool
+		expectedError bool
+	}{
+		{
+			name:     "created",
+			username: "admin",
+			password: "password",
+		},
+		{
+			name:     "fixed accounts",
+			username: "admin",
+			password: "password",
+			fixed:    true,
+		},
+		{
+			name:     "already created",
+			username: "admin",
+			password: "password",
+			existing: true,
+		},
+		{
+			name:          "invalid credentials",
+			username:      "root",
+			password:      "password",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := testserver.NewRedfish(t).WithCredentials(tc.username, tc.password)
+			if tc.fixed {
+				mock.WithFixedAccounts()
+			}
+			mock.Start()
+			defer mock.Stop()
+
+			prov := newTestProvisioner(t, mock)
+			newCreds := bmc.Credentials{Username: "admin-r", Password: "n3wPassw0rd"}
+			if tc.existing {
+				result, err := prov.CreateBMCAccount(newCreds)
+				require.NoError(t, err)
+				require.Empty(t, result.ErrorMessage)
+			}
+
+			result, err := prov.CreateBMCAccount(newCreds)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedError, result.ErrorMessage != "", result.ErrorMessage)
+
+			account, found := mock.Account("admin-r")
+			assert.Equal(t, !tc.expectedError, found)
+			if found {
+				assert.Equal(t, testserver.Account{
+					UserName: "admin-r",
+					Password: "n3wPassw0rd",
+					RoleID:   "Administrator",
+					Enabled:  true,
+				}, account)
+			}
+			assert.Equal(t, tc.password, mock.Password())
+		})
+	}
+}
+
+func TestDisableBMCAccount(t *testing.T) {
+	mock := testserver.NewRedfish(t).WithCredentials("admin", "password").Start()
+	defer mock.Stop()
+
+	prov := newTestProvisioner(t, mock)
+	result, err := prov.CreateBMCAccount(bmc.Credentials{Username: "admin-r", Password: "n3wPassw0rd"})
+	require.NoError(t, err)
+	require.Empty(t, result.ErrorMessage)
+
+	result, err = prov.DisableBMCAccount("admin-r")
+	assert.NoError(t, err)
+	assert.Empty(t, result.ErrorMessage)
+	account, _ := mock.Account("admin-r")
+	assert.False(t, account.Enabled)
+
+	result, err = prov.DisableBMCAccount("admin")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, result.ErrorMessage)
+	account, _ = mock.Account("admin")
+	assert.True(t, account.Enabled)
+
+	result, err = prov.DisableBMCAccount("unknown")
+	assert.NoError(t, err)
+	assert.Empty(t, result.ErrorMessage)
+}
+
+func TestDiscover(t *testing.T) {
+	cases := []struct {
+		name                 string
+		credentials          bmc.Credentials
+		tls                  bool
+		trusted              bool
+		expectedSystems      int
+		expectedUntrustedTLS bool
+		expectedCredentials  bool
+	}{
+		{
+			name:                "trusted certificate",
+			credentials:         bmc.Credentials{Username: "admin", Password: "password"},
+			tls:                 true,
+			trusted:             true,
+			expectedSystems:     1,
+			expectedCredentials: true,
+		},
+		{
+			name:    "no credentials",
+			tls:     true,
+			trusted: true,
+		},
+		{
+			name:                 "self-signed certificate",
+			credentials:          bmc.Credentials{Username: "admin", Password: "password"},
+			tls:                  true,
+			expectedUntrustedTLS: true,
+		},
+		{
+			name:        "plain HTTP",
+			credentials: bmc.Credentials{Username: "admin", Password: "password"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := testserver.NewRedfish(t).WithCredentials("admin", "password")
+			if tc.tls {
+				mock.StartTLS()
+			} else {
+				mock.Start()
+			}
+			defer mock.Stop()
+			var rootCAs *x509.CertPool
+			if tc.trusted {
+				rootCAs = mock.CertPool()
+			}
+
+			found, err := Discover(mock.Endpoint(), tc.credentials, rootCAs, time.Second)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, mock.Endpoint(), found.Endpoint)
+			assert.Equal(t, "Dell", found.Vendor)
+			assert.Equal(t, tc.expectedUntrustedTLS, found.UntrustedCertificate)
+			assert.Equal(t, tc.expectedCredentials, mock.CredentialsReceived)
+			if !assert.Len(t, found.Systems, tc.expectedSystems) || tc.expectedSystems == 0 {
+				return
+			}
+			system := found.Systems[0]
+			assert.Equal(t, testserver.SystemPath, system.Path)
+			assert.Equal(t, metal3v1alpha1.HardwareSystemVendor{
+				Manufacturer: "Dell Inc.",
+				ProductName:  "PowerEdge R640",
+				SerialNumber: "CN7475189J0123",
+			}, system.HardwareDetails.SystemVendor)
+			if assert.Len(t, system.HardwareDetails.NIC, 1) {
+				assert.Equal(t, "00:5c:52:31:3a:9c", system.HardwareDetails.NIC[0].MAC)
+			}
+		})
+	}
+}
+
+func TestDiscoverNotRedfish(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	_, err := Discover(server.URL, bmc.Credentials{}, nil, time.Second)
+	assert.EqualError(t, err, server.URL+" is not a Redfish service")
+}
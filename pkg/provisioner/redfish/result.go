@@ -0,0 +1,26 @@
+package redfish
+
+import (
+	"time"
+
+	"github.com/metal3-io/baremetal-operator/pkg/provisioner"
+)
+
+func operationContinuing(delay time.Duration) (provisioner.Result, error) {
+	return provisioner.Result{
+		Dirty:        true,
+		RequeueAfter: delay,
+	}, nil
+}
+
+func operationComplete() (provisioner.Result, error) {
+	return provisioner.Result{}, nil
+}
+
+func operationFailed(message string) (provisioner.Result, error) {
+	return provisioner.Result{ErrorMessage: message}, nil
+}
+
+func transientError(err error) (provisioner.Result, error) {
+	return provisioner.Result{}, err
+}
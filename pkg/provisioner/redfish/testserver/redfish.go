@@ -0,0 +1,573 @@
+package testserver
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+const (
+	// SystemPath is the path of the system served by the mock
+	SystemPath   = "/redfish/v1/Systems/1"
+	serviceRoot  = "/redfish/v1/"
+	managerPath  = "/redfish/v1/Managers/1"
+	chassisPath  = "/redfish/v1/Chassis/1"
+	mediaPath    = managerPath + "/VirtualMedia/Cd"
+	accountsPath = "/redfish/v1/AccountService/Accounts"
+)
+
+// Account is an account of the BMC
+type Account struct {
+	UserName string
+	Password string
+	RoleID   string
+	Enabled  bool
+}
+
+// RedfishMock is a stateful Redfish BMC serving a single system
+type RedfishMock struct {
+	t      *testing.T
+	mux    *http.ServeMux
+	server *httptest.Server
+	lock   sync.Mutex
+
+	// the account slots of the BMC, the second one being used by
+	// WithCredentials
+	accounts      [4]Account
+	fixedAccounts bool
+
+	// Requests holds the method and path of the requests received
+	Requests []string
+	// Resets holds the reset types requested
+	Resets []string
+	// CredentialsReceived is true once a request carried credentials
+	CredentialsReceived bool
+
+	PowerState     string
+	BootTarget     string
+	BootEnabled    string
+	BootMode       string
+	MediaImage     string
+	MediaInserted  bool
+	BIOSAttributes map[string]interface{}
+	// PendingBIOSAttributes are applied on the next boot
+	PendingBIOSAttributes map[string]interface{}
+
+	// The installed versions of the firmware components
+	BIOSVersion string
+	BMCVersion  string
+	NICVersion  string
+
+	// The health reported for the components of the system
+	ProcessorHealth string
+	MemoryHealth    string
+	ChassisHealth   string
+}
+
+// NewRedfish returns a RedfishMock for a powered off system
+func NewRedfish(t *testing.T) *RedfishMock {
+	m := &RedfishMock{
+		t:           t,
+		mux:         http.NewServeMux(),
+		PowerState:  "Off",
+		BootEnabled: "Disabled",
+		BIOSAttributes: map[string]interface{}{
+			"ProcVirtualization": "Enabled",
+			"BootDelay":          float64(5),
+		},
+		PendingBIOSAttributes: map[string]interface{}{},
+		BIOSVersion:           "2.12.2",
+		BMCVersion:            "5.10.00.00",
+		NICVersion:            "22.31.6",
+		ProcessorHealth:       "OK",
+		MemoryHealth:          "OK",
+		ChassisHealth:         "OK",
+	}
+	m.routes()
+	t.Logf("redfish: new server created")
+	return m
+}
+
+// WithCredentials makes the mock require basic authentication
+func (m *RedfishMock) WithCredentials(username, password string) *RedfishMock {
+	m.accounts[1] = Account{
+		UserName: username,
+		Password: password,
+		RoleID:   "Administrator",
+		Enabled:  true,
+	}
+	return m
+}
+
+// WithFixedAccounts makes the mock refuse to create accounts, and list
+// its unused account slots instead
+func (m *RedfishMock) WithFixedAccounts() *RedfishMock {
+	m.fixedAccounts = true
+	return m
+}
+
+// Password returns the password of the BMC account
+func (m *RedfishMock) Password() string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.accounts[1].Password
+}
+
+// Account returns the BMC account with the user name
+func (m *RedfishMock) Account(username string) (Account, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, account := range m.accounts {
+		if account.UserName == username {
+			return account, true
+		}
+	}
+	return Account{}, false
+}
+
+// authorized returns whether the request carries the credentials of an
+// enabled account
+func (m *RedfishMock) authorized(r *http.Request) bool {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	for _, account := range m.accounts {
+		if account.Enabled && account.UserName == username && account.Password == password {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPowerState sets the initial power state of the system
+func (m *RedfishMock) WithPowerState(state string) *RedfishMock {
+	m.PowerState = state
+	return m
+}
+
+// WithMedia sets the image inserted in the virtual CD drive
+func (m *RedfishMock) WithMedia(image string) *RedfishMock {
+	m.MediaImage = image
+	m.MediaInserted = image != ""
+	return m
+}
+
+// WithBootOverride sets the boot source override of the system
+func (m *RedfishMock) WithBootOverride(target, enabled string) *RedfishMock {
+	m.BootTarget = target
+	m.BootEnabled = enabled
+	return m
+}
+
+// WithChassisHealth sets the health of the chassis of the system
+func (m *RedfishMock) WithChassisHealth(health string) *RedfishMock {
+	m.ChassisHealth = health
+	return m
+}
+
+// Start runs the server
+func (m *RedfishMock) Start() *RedfishMock {
+	m.server = httptest.NewServer(m.mux)
+	return m
+}
+
+// StartTLS runs the server with a self-signed certificate
+func (m *RedfishMock) StartTLS() *RedfishMock {
+	m.server = httptest.NewTLSServer(m.mux)
+	return m
+}
+
+// Stop shuts down the server
+func (m *RedfishMock) Stop() {
+	m.server.Close()
+}
+
+// CertPool returns a pool holding the self-signed certificate of a
+// server started with StartTLS
+func (m *RedfishMock) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(m.server.Certificate())
+	return pool
+}
+
+// Certificate returns the self-signed certificate of a server started
+// with StartTLS, which is valid for 127.0.0.1 but not localhost
+func (m *RedfishMock) Certificate() *x509.Certificate {
+	return m.server.Certificate()
+}
+
+// Endpoint returns the URL of the server
+func (m *RedfishMock) Endpoint() string {
+	return m.server.URL
+}
+
+// BMCAddress returns a BMC address for the system served by the mock
+func (m *RedfishMock) BMCAddress() string {
+	return "redfish+" + m.server.URL + SystemPath
+}
+
+func (m *RedfishMock) handle(path string, handlers map[string]func(body map[string]interface{}) interface{}) {
+	m.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+
+		// The service root pattern matches all the unknown paths
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+
+		m.Requests = append(m.Requests, r.Method+" "+r.URL.Path)
+		m.t.Logf("redfish: %s %s", r.Method, r.URL.Path)
+		if _, _, ok := r.BasicAuth(); ok {
+			m.CredentialsReceived = true
+		}
+
+		// The service root is served without authentication
+		if m.accounts[1].UserName != "" && path != serviceRoot && !m.authorized(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		handler, ok := handlers[r.Method]
+		if !ok {
+			http.Error(w, fmt.Sprintf("%s not handled for %s", r.Method, r.URL), http.StatusMethodNotAllowed)
+			return
+		}
+
+		body := map[string]interface{}{}
+		if raw, _ := io.ReadAll(r.Body); len(raw) != 0 {
+			if err := json.Unmarshal(raw, &body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		response := handler(body)
+		if code, ok := response.(statusCode); ok {
+			http.Error(w, http.StatusText(int(code)), int(code))
+			return
+		}
+		if response == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			m.t.Errorf("redfish: failed to encode response: %s", err)
+		}
+	})
+}
+
+// statusCode is returned by a handler to answer with an error
+type statusCode int
+
+func link(path string) map[string]string {
+	return map[string]string{"@odata.id": path}
+}
+
+func members(paths ...string) map[string]interface{} {
+	links := []map[string]string{}
+	for _, path := range paths {
+		links = append(links, link(path))
+	}
+	return map[string]interface{}{"Members": links}
+}
+
+func static(response interface{}) map[string]func(map[string]interface{}) interface{} {
+	return map[string]func(map[string]interface{}) interface{}{
+		http.MethodGet: func(map[string]interface{}) interface{} { return response },
+	}
+}
+
+func (m *RedfishMock) routes() {
+	m.handle(serviceRoot, static(map[string]interface{}{
+		"RedfishVersion": "1.11.0",
+		"UUID":           "92384634-2938-2342-8820-489239905423",
+		"Vendor":         "Dell",
+		"Product":        "Integrated Dell Remote Access Controller",
+		"Systems":        link("/redfish/v1/Systems"),
+	}))
+	m.handle("/redfish/v1/Systems", static(members(SystemPath)))
+
+	m.handle(SystemPath, map[string]func(map[string]interface{}) interface{}{
+		http.MethodGet: func(map[string]interface{}) interface{} {
+			return m.system()
+		},
+		http.MethodPatch: func(body map[string]interface{}) interface{} {
+			boot, _ := body["Boot"].(map[string]interface{})
+			if target, ok := boot["BootSourceOverrideTarget"].(string); ok {
+				m.BootTarget = target
+			}
+			if enabled, ok := boot["BootSourceOverrideEnabled"].(string); ok {
+				m.BootEnabled = enabled
+			}
+			if mode, ok := boot["BootSourceOverrideMode"].(string); ok {
+				m.BootMode = mode
+			}
+			return nil
+		},
+	})
+	m.handle(SystemPath+"/Actions/ComputerSystem.Reset", map[string]func(map[string]interface{}) interface{}{
+		http.MethodPost: func(body map[string]interface{}) interface{} {
+			resetType, _ := body["ResetType"].(string)
+			m.Resets = append(m.Resets, resetType)
+			switch resetType {
+			case "On", "ForceRestart", "GracefulRestart":
+				m.PowerState = "On"
+				m.boot()
+			case "ForceOff", "GracefulShutdown":
+				m.PowerState = "Off"
+			}
+			return nil
+		},
+	})
+
+	m.handle(SystemPath+"/Processors", static(members(SystemPath+"/Processors/CPU1", SystemPath+"/Processors/CPU2")))
+	for _, id := range []string{"CPU1", "CPU2"} {
+		m.handle(SystemPath+"/Processors/"+id, static(map[string]interface{}{
+			"Id":                    id,
+			"Model":                 "Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz",
+			"MaxSpeedMHz":           3900,
+			"TotalCores":            20,
+			"TotalThreads":          40,
+			"ProcessorArchitecture": "x86",
+			"InstructionSet":        "x86-64",
+		}))
+	}
+
+	m.handle(SystemPath+"/EthernetInterfaces", static(members(SystemPath+"/EthernetInterfaces/NIC1")))
+	m.handle(SystemPath+"/EthernetInterfaces/NIC1", static(map[string]interface{}{
+		"Id":            "NIC1",
+		"Name":          "Ethernet Interface",
+		"MACAddress":    "00:5C:52:31:3A:9C",
+		"SpeedMbps":     25000,
+		"IPv4Addresses": []map[string]string{{"Address": "192.0.2.10"}},
+	}))
+
+	m.handle(SystemPath+"/Storage", static(members(SystemPath+"/Storage/1")))
+	m.handle(SystemPath+"/Storage/1", static(map[string]interface{}{
+		"Drives": []map[string]string{link(SystemPath + "/Storage/1/Drives/1")},
+	}))
+	m.handle(SystemPath+"/Storage/1/Drives/1", static(map[string]interface{}{
+		"Name":          "Solid State Drive",
+		"Model":         "MZ7LH480HAHQ",
+		"Manufacturer":  "SAMSUNG",
+		"SerialNumber":  "S45PNA0M",
+		"MediaType":     "SSD",
+		"Protocol":      "SATA",
+		"CapacityBytes": 480103981056,
+	}))
+
+	m.handle(SystemPath+"/Bios", map[string]func(map[string]interface{}) interface{}{
+		http.MethodGet: func(map[string]interface{}) interface{} {
+			return map[string]interface{}{
+				"Attributes": m.BIOSAttributes,
+				"@Redfish.Settings": map[string]interface{}{
+					"SettingsObject": link(SystemPath + "/Bios/Settings"),
+				},
+			}
+		},
+	})
+	m.handle(SystemPath+"/Bios/Settings", map[string]func(map[string]interface{}) interface{}{
+		http.MethodGet: func(map[string]interface{}) interface{} {
+			return map[string]interface{}{"Attributes": m.PendingBIOSAttributes}
+		},
+		http.MethodPatch: func(body map[string]interface{}) interface{} {
+			attributes, _ := body["Attributes"].(map[string]interface{})
+			for name, value := range attributes {
+				m.PendingBIOSAttributes[name] = value
+			}
+			return nil
+		},
+	})
+
+	m.handle("/redfish/v1/AccountService", static(map[string]interface{}{
+		"Accounts": link(accountsPath),
+	}))
+	m.handle(accountsPath, map[string]func(map[string]interface{}) interface{}{
+		http.MethodGet: func(map[string]interface{}) interface{} {
+			paths := []string{}
+			for i, account := range m.accounts {
+				if account.UserName != "" || m.fixedAccounts {
+					paths = append(paths, accountPath(i))
+				}
+			}
+			return members(paths...)
+		},
+		http.MethodPost: func(body map[string]interface{}) interface{} {
+			for i := range m.accounts {
+				if m.accounts[i].UserName == "" && !m.fixedAccounts {
+					m.updateAccount(i, body)
+					return nil
+				}
+			}
+			return statusCode(http.StatusMethodNotAllowed)
+		},
+	})
+	for i := range m.accounts {
+		i := i
+		m.handle(accountPath(i), map[string]func(map[string]interface{}) interface{}{
+			http.MethodGet: func(map[string]interface{}) interface{} {
+				return map[string]interface{}{
+					"Id":       fmt.Sprint(i + 1),
+					"UserName": m.accounts[i].UserName,
+					"RoleId":   m.accounts[i].RoleID,
+					"Enabled":  m.accounts[i].Enabled,
+				}
+			},
+			http.MethodPatch: func(body map[string]interface{}) interface{} {
+				m.updateAccount(i, body)
+				return nil
+			},
+		})
+	}
+
+	m.handle(chassisPath, map[string]func(map[string]interface{}) interface{}{
+		http.MethodGet: func(map[string]interface{}) interface{} {
+			return map[string]interface{}{
+				"Id": "1",
+				"Status": map[string]string{
+					"State":        "Enabled",
+					"Health":       "OK",
+					"HealthRollup": m.ChassisHealth,
+				},
+				"NetworkAdapters": link(chassisPath + "/NetworkAdapters"),
+			}
+		},
+	})
+	m.handle(chassisPath+"/NetworkAdapters", static(members(chassisPath+"/NetworkAdapters/NIC.Slot.1")))
+	m.handle(chassisPath+"/NetworkAdapters/NIC.Slot.1", map[string]func(map[string]interface{}) interface{}{
+		http.MethodGet: func(map[string]interface{}) interface{} {
+			return map[string]interface{}{
+				"Id": "NIC.Slot.1",
+				"Controllers": []map[string]string{
+					{"FirmwarePackageVersion": m.NICVersion},
+				},
+			}
+		},
+	})
+
+	m.handle(managerPath, map[string]func(map[string]interface{}) interface{}{
+		http.MethodGet: func(map[string]interface{}) interface{} {
+			return map[string]interface{}{
+				"Id":              "1",
+				"FirmwareVersion": m.BMCVersion,
+				"VirtualMedia":    link(managerPath + "/VirtualMedia"),
+			}
+		},
+	})
+	m.handle(managerPath+"/VirtualMedia", static(members(managerPath+"/VirtualMedia/Floppy", mediaPath)))
+	m.handle(managerPath+"/VirtualMedia/Floppy", static(map[string]interface{}{
+		"Id":         "Floppy",
+		"MediaTypes": []string{"Floppy", "USBStick"},
+	}))
+	m.handle(mediaPath, map[string]func(map[string]interface{}) interface{}{
+		http.MethodGet: func(map[string]interface{}) interface{} {
+			return map[string]interface{}{
+				"Id":         "Cd",
+				"Image":      m.MediaImage,
+				"Inserted":   m.MediaInserted,
+				"MediaTypes": []string{"CD", "DVD"},
+			}
+		},
+	})
+	m.handle(mediaPath+"/Actions/VirtualMedia.InsertMedia", map[string]func(map[string]interface{}) interface{}{
+		http.MethodPost: func(body map[string]interface{}) interface{} {
+			m.MediaImage, _ = body["Image"].(string)
+			m.MediaInserted = true
+			return nil
+		},
+	})
+	m.handle(mediaPath+"/Actions/VirtualMedia.EjectMedia", map[string]func(map[string]interface{}) interface{}{
+		http.MethodPost: func(map[string]interface{}) interface{} {
+			m.MediaImage = ""
+			m.MediaInserted = false
+			return nil
+		},
+	})
+}
+
+// boot applies the pending BIOS settings
+func accountPath(i int) string {
+	return fmt.Sprintf("%s/%d", accountsPath, i+1)
+}
+
+func (m *RedfishMock) updateAccount(i int, settings map[string]interface{}) {
+	account := &m.accounts[i]
+	if username, ok := settings["UserName"].(string); ok {
+		account.UserName = username
+	}
+	if password, ok := settings["Password"].(string); ok {
+		account.Password = password
+	}
+	if role, ok := settings["RoleId"].(string); ok {
+		account.RoleID = role
+	}
+	if enabled, ok := settings["Enabled"].(bool); ok {
+		account.Enabled = enabled
+	}
+}
+
+func (m *RedfishMock) boot() {
+	for name, value := range m.PendingBIOSAttributes {
+		m.BIOSAttributes[name] = value
+	}
+	m.PendingBIOSAttributes = map[string]interface{}{}
+}
+
+func (m *RedfishMock) system() map[string]interface{} {
+	return map[string]interface{}{
+		"Id":           "1",
+		"UUID":         "e4a1f2b0-6f4c-4f60-9a3b-3c2b1f0d9e11",
+		"HostName":     "edge-01",
+		"Manufacturer": "Dell Inc.",
+		"Model":        "PowerEdge R640",
+		"SerialNumber": "CN7475189J0123",
+		"PowerState":   m.PowerState,
+		"BiosVersion":  m.BIOSVersion,
+		"Status": map[string]string{
+			"State":  "Enabled",
+			"Health": "OK",
+		},
+		"Boot": map[string]string{
+			"BootSourceOverrideTarget":  m.BootTarget,
+			"BootSourceOverrideEnabled": m.BootEnabled,
+			"BootSourceOverrideMode":    m.BootMode,
+		},
+		"ProcessorSummary": map[string]interface{}{
+			"Count": 2,
+			"Model": "Intel(R) Xeon(R) Gold 6230 CPU @ 2.10GHz",
+			"Status": map[string]string{
+				"State":        "Enabled",
+				"HealthRollup": m.ProcessorHealth,
+			},
+		},
+		"MemorySummary": map[string]interface{}{
+			"TotalSystemMemoryGiB": 192,
+			"Status": map[string]string{
+				"State":        "Enabled",
+				"HealthRollup": m.MemoryHealth,
+			},
+		},
+		"Processors":         link(SystemPath + "/Processors"),
+		"EthernetInterfaces": link(SystemPath + "/EthernetInterfaces"),
+		"Storage":            link(SystemPath + "/Storage"),
+		"Bios":               link(SystemPath + "/Bios"),
+		"Links": map[string]interface{}{
+			"ManagedBy": []map[string]string{link(managerPath)},
+			"Chassis":   []map[string]string{link(chassisPath)},
+		},
+		"Actions": map[string]interface{}{
+			"#ComputerSystem.Reset": map[string]string{
+				"target": SystemPath + "/Actions/ComputerSystem.Reset",
+			},
+		},
+	}
+}
@@ -0,0 +1,96 @@
+package provisioner
+
+import (
+	"fmt"
+	"sort"
+)
+
+// UnknownProvisionerError is returned when a host selects a
+// provisioner that is not registered.
+type UnknownProvisionerError struct {
+	Name string
+}
+
+func (e UnknownProvisionerError) Error() string {
+	return fmt.Sprintf("provisioner %q is not registered", e.Name)
+}
+
+// Registry holds the provisioner factories available to the operator,
+// indexed by name. It implements Factory by handing each host to the
+// factory of the provisioner it selects, or to the default one when
+// the host does not select any.
+type Registry struct {
+	factories   map[string]Factory
+	defaultName string
+}
+
+// NewRegistry returns an empty Registry. The first factory registered
+// becomes the default.
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: map[string]Factory{},
+	}
+}
+
+// Register adds a factory under the given name.
+func (r *Registry) Register(name string, factory Factory) error {
+	if name == "" {
+		return fmt.Errorf("provisioner name can not be empty")
+	}
+	if _, exists := r.factories[name]; exists {
+		return fmt.Errorf("provisioner %q is already registered", name)
+	}
+	r.factories[name] = factory
+	if r.defaultName == "" {
+		r.defaultName = name
+	}
+	return nil
+}
+
+// SetDefault selects the provisioner used for hosts that do not
+// select one.
+func (r *Registry) SetDefault(name string) error {
+	if _, exists := r.factories[name]; !exists {
+		return UnknownProvisionerError{Name: name}
+	}
+	r.defaultName = name
+	return nil
+}
+
+// Default returns the name of the default provisioner.
+func (r *Registry) Default() string {
+	return r.defaultName
+}
+
+// Names returns the sorted names of the registered provisioners.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the factory registered under the given name, or the
+// default factory when the name is empty.
+func (r *Registry) Get(name string) (Factory, error) {
+	if name == "" {
+		name = r.defaultName
+	}
+	factory, exists := r.factories[name]
+	if !exists {
+		return nil, UnknownProvisionerError{Name: name}
+	}
+	return factory, nil
+}
+
+// NewProvisioner returns a Provisioner for the host from the factory
+// of the provisioner it selects.
+func (r *Registry) NewProvisioner(hostData HostData, publish EventPublisher) (Provisioner, error) {
+	factory, err := r.Get(hostData.Provisioner)
+	if err != nil {
+		return nil, err
+	}
+	return factory.NewProvisioner(hostData, publish)
+}
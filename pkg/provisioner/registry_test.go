@@ -0,0 +1,96 @@
+package provisioner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type namedFactory struct {
+	Provisioner
+	name string
+}
+
+func (f *namedFactory) NewProvisioner(hostData HostData, publish EventPublisher) (Provisioner, error) {
+	return f, nil
+}
+
+func TestRegistry(t *testing.T) {
+	ironic := &namedFactory{name: "ironic"}
+	redfish := &namedFactory{name: "redfish"}
+
+	registry := NewRegistry()
+	require.NoError(t, registry.Register("ironic", ironic))
+	require.NoError(t, registry.Register("redfish", redfish))
+
+	assert.Equal(t, "ironic", registry.Default())
+	assert.Equal(t, []string{"ironic", "redfish"}, registry.Names())
+
+	assert.Error(t, registry.Register("redfish", redfish))
+	assert.Error(t, registry.Register("", redfish))
+
+	testCases := []struct {
+		name        string
+		provisioner string
+		expected    Factory
+		expectedErr string
+	}{
+		{
+			name:     "default",
+			expected: ironic,
+		},
+		{
+			name:        "selected",
+			provisioner: "redfish",
+			expected:    redfish,
+		},
+		{
+			name:        "unknown",
+			provisioner: "fake",
+			expectedErr: `provisioner "fake" is not registered`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prov, err := registry.NewProvisioner(HostData{Provisioner: tc.provisioner}, nil)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, prov)
+		})
+	}
+
+	require.NoError(t, registry.SetDefault("redfish"))
+	prov, err := registry.NewProvisioner(HostData{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, redfish, prov)
+
+	assert.Error(t, registry.SetDefault("fake"))
+}
+
+type bmcDetailsFactory struct {
+	namedFactory
+}
+
+func (f *bmcDetailsFactory) RequiresBMCDetails() bool {
+	return true
+}
+
+func TestRequiresBMCDetails(t *testing.T) {
+	ironic := &namedFactory{name: "ironic"}
+	redfish := &bmcDetailsFactory{namedFactory{name: "redfish"}}
+
+	registry := NewRegistry()
+	require.NoError(t, registry.Register("ironic", ironic))
+	require.NoError(t, registry.Register("redfish", redfish))
+
+	assert.False(t, RequiresBMCDetails(ironic, HostData{}))
+	assert.True(t, RequiresBMCDetails(redfish, HostData{}))
+	assert.False(t, RequiresBMCDetails(registry, HostData{}))
+	assert.True(t, RequiresBMCDetails(registry, HostData{Provisioner: "redfish"}))
+	assert.False(t, RequiresBMCDetails(registry, HostData{Provisioner: "fake"}))
+}
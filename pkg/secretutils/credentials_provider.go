@@ -0,0 +1,217 @@
+package secretutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+)
+
+var credentialsRequestTimeout = time.Second * 30
+
+// CredentialsProvider fetches BMC credentials kept outside of the
+// cluster, so that they are never stored in a Secret.
+type CredentialsProvider interface {
+	// Credentials returns the credentials of a host, identified by
+	// its namespace and the CredentialsName of its BMC, and a version
+	// that changes whenever the credentials do.
+	Credentials(namespace, name string) (creds bmc.Credentials, version string, err error)
+}
+
+// CredentialsNotFoundError is returned when a provider has no
+// credentials for a host.
+type CredentialsNotFoundError struct {
+	Namespace string
+	Name      string
+}
+
+func (e CredentialsNotFoundError) Error() string {
+	return fmt.Sprintf("no credentials %s/%s found", e.Namespace, e.Name)
+}
+
+// fileCredentialsProvider reads credentials from a directory holding a
+// <namespace>/<name> directory per host, with a username and a
+// password file, as mounted by the Vault agent or a CSI secrets store
+// driver.
+type fileCredentialsProvider struct {
+	dir string
+}
+
+// NewFileCredentialsProvider returns a provider reading the credentials
+// from files under dir.
+func NewFileCredentialsProvider(dir string) CredentialsProvider {
+	return fileCredentialsProvider{dir: dir}
+}
+
+func (p fileCredentialsProvider) Credentials(namespace, name string) (creds bmc.Credentials, version string, err error) {
+	// Never read credentials from outside of the directory of the
+	// namespace, whatever the name holds.
+	if namespace == "" || namespace == "." || namespace == ".." || strings.ContainsRune(namespace, filepath.Separator) {
+		return creds, "", fmt.Errorf("invalid namespace %q", namespace)
+	}
+	namespaceDir := filepath.Join(p.dir, namespace)
+
+	var modTime time.Time
+	values := map[string]string{}
+	for _, key := range []string{"username", "password"} {
+		path := filepath.Join(namespaceDir, name, key)
+		if filepath.Dir(filepath.Dir(path)) != namespaceDir {
+			return creds, "", fmt.Errorf("invalid credentials name %q", name)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return creds, "", CredentialsNotFoundError{Namespace: namespace, Name: name}
+			}
+			return creds, "", errors.Wrap(err, "failed to read credentials")
+		}
+		data, err := os.ReadFile(path) // #nosec
+		if err != nil {
+			return creds, "", errors.Wrap(err, "failed to read credentials")
+		}
+		values[key] = strings.TrimSpace(string(data))
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+
+	// The files are replaced when the credentials change, so their
+	// modification time serves as a version without exposing anything
+	// derived from the password.
+	creds = bmc.Credentials{Username: values["username"], Password: values["password"]}
+	return creds, strconv.FormatInt(modTime.UnixNano(), 10), nil
+}
+
+// httpCredentialsProvider fetches credentials from an HTTP service
+// answering GET <url>/<namespace>/<name> with a JSON document holding
+// the username, password and version of the credentials.
+type httpCredentialsProvider struct {
+	url        string
+	tokenFile  string
+	httpClient *http.Client
+}
+
+type httpCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Version  string `json:"version"`
+}
+
+// NewHTTPCredentialsProvider returns a provider fetching the credentials
+// from the service at the URL. When tokenFile is set, the token it
+// contains is sent as a bearer token. The file is read for each
+// request, so that the token can be renewed.
+func NewHTTPCredentialsProvider(serviceURL, tokenFile string) CredentialsProvider {
+	return httpCredentialsProvider{
+		url:       strings.TrimSuffix(serviceURL, "/"),
+		tokenFile: tokenFile,
+		httpClient: &http.Client{
+			Timeout: credentialsRequestTimeout,
+		},
+	}
+}
+
+func (p httpCredentialsProvider) Credentials(namespace, name string) (creds bmc.Credentials, version string, err error) {
+	req, err := http.NewRequest(http.MethodGet,
+		fmt.Sprintf("%s/%s/%s", p.url, url.PathEscape(namespace), url.PathEscape(name)), nil)
+	if err != nil {
+		return creds, "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.tokenFile != "" {
+		token, err := os.ReadFile(p.tokenFile)
+		if err != nil {
+			return creds, "", errors.Wrap(err, "failed to read credentials provider token")
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return creds, "", errors.Wrap(err, "failed to fetch credentials")
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return creds, "", CredentialsNotFoundError{Namespace: namespace, Name: name}
+	case resp.StatusCode >= http.StatusBadRequest:
+		return creds, "", fmt.Errorf("failed to fetch credentials: %s", resp.Status)
+	}
+
+	result := httpCredentials{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return creds, "", errors.Wrap(err, "failed to decode credentials")
+	}
+	if result.Version == "" {
+		return creds, "", fmt.Errorf("credentials %s/%s have no version", namespace, name)
+	}
+	creds = bmc.Credentials{Username: result.Username, Password: result.Password}
+	return creds, result.Version, nil
+}
+
+// CredentialsProviders holds the credentials providers hosts may
+// select by name. It can be set with a command line flag, each
+// occurrence of which adds a provider given as name=URL. file://
+// URLs select a directory of credential files, and http:// or
+// https:// URLs a credentials service, optionally followed by
+// ",tokenFile=PATH".
+type CredentialsProviders map[string]CredentialsProvider
+
+func (p CredentialsProviders) String() string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// Set parses a credentials provider and adds it.
+func (p CredentialsProviders) Set(value string) error {
+	name, spec, found := strings.Cut(value, "=")
+	name = strings.TrimSpace(name)
+	if !found || name == "" {
+		return fmt.Errorf("invalid credentials provider %q, expected name=URL", value)
+	}
+	if _, exists := p[name]; exists {
+		return fmt.Errorf("duplicate credentials provider %q", name)
+	}
+
+	fields := strings.Split(spec, ",")
+	location, err := url.Parse(strings.TrimSpace(fields[0]))
+	if err != nil {
+		return errors.Wrapf(err, "invalid URL for credentials provider %q", name)
+	}
+	tokenFile := ""
+	for _, field := range fields[1:] {
+		key, val, _ := strings.Cut(field, "=")
+		if strings.TrimSpace(key) != "tokenFile" {
+			return fmt.Errorf("unknown option %q for credentials provider %q", key, name)
+		}
+		tokenFile = strings.TrimSpace(val)
+	}
+
+	switch location.Scheme {
+	case "file":
+		if tokenFile != "" {
+			return fmt.Errorf("tokenFile is not supported by file credentials provider %q", name)
+		}
+		p[name] = NewFileCredentialsProvider(location.Path)
+	case "http", "https":
+		p[name] = NewHTTPCredentialsProvider(location.String(), tokenFile)
+	default:
+		return fmt.Errorf("unsupported URL scheme %q for credentials provider %q", location.Scheme, name)
+	}
+	return nil
+}
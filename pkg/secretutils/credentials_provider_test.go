@@ -0,0 +1,157 @@
+package secretutils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/metal3-io/baremetal-operator/pkg/hardwareutils/bmc"
+)
+
+func writeCredentials(t *testing.T, dir, username, password string, modTime time.Time) {
+	hostDir := filepath.Join(dir, "myns", "myhost-bmc")
+	require.NoError(t, os.MkdirAll(hostDir, 0o700))
+	for name, value := range map[string]string{"username": username, "password": password} {
+		path := filepath.Join(hostDir, name)
+		require.NoError(t, os.WriteFile(path, []byte(value+"\n"), 0o600))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+}
+
+func TestFileCredentialsProvider(t *testing.T) {
+	dir := t.TempDir()
+	provider := NewFileCredentialsProvider(dir)
+
+	_, _, err := provider.Credentials("myns", "myhost-bmc")
+	assert.ErrorAs(t, err, &CredentialsNotFoundError{})
+
+	modTime := time.Date(2023, 5, 4, 10, 0, 0, 0, time.UTC)
+	writeCredentials(t, dir, "admin", "password", modTime)
+	creds, version, err := provider.Credentials("myns", "myhost-bmc")
+	assert.NoError(t, err)
+	assert.Equal(t, bmc.Credentials{Username: "admin", Password: "password"}, creds)
+
+	writeCredentials(t, dir, "admin", "n3wPassw0rd", modTime.Add(time.Hour))
+	creds, newVersion, err := provider.Credentials("myns", "myhost-bmc")
+	assert.NoError(t, err)
+	assert.Equal(t, "n3wPassw0rd", creds.Password)
+	assert.NotEqual(t, version, newVersion)
+}
+
+func TestFileCredentialsProviderOutsideNamespace(t *testing.T) {
+	dir := t.TempDir()
+	writeCredentials(t, dir, "admin", "password", time.Now())
+	provider := NewFileCredentialsProvider(dir)
+
+	for _, name := range []string{"../myns/myhost-bmc", "../tenant/../myns/myhost-bmc", "..", "a/b"} {
+		_, _, err := provider.Credentials("tenant", name)
+		assert.EqualError(t, err, `invalid credentials name "`+name+`"`)
+	}
+	_, _, err := provider.Credentials("..", "myns/myhost-bmc")
+	assert.EqualError(t, err, `invalid namespace ".."`)
+}
+
+func TestHTTPCredentialsProvider(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("s3cr3t\n"), 0o600))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/bmc/myns/myhost-bmc":
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"username": "admin",
+				"password": "password",
+				"version":  "7",
+			})
+		case "/v1/bmc/myns/no-version":
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"username": "admin",
+				"password": "password",
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewHTTPCredentialsProvider(server.URL+"/v1/bmc/", tokenFile)
+
+	creds, version, err := provider.Credentials("myns", "myhost-bmc")
+	assert.NoError(t, err)
+	assert.Equal(t, bmc.Credentials{Username: "admin", Password: "password"}, creds)
+	assert.Equal(t, "7", version)
+
+	_, _, err = provider.Credentials("myns", "missing")
+	assert.ErrorAs(t, err, &CredentialsNotFoundError{})
+
+	_, _, err = provider.Credentials("myns", "no-version")
+	assert.EqualError(t, err, "credentials myns/no-version have no version")
+
+	_, _, err = NewHTTPCredentialsProvider(server.URL+"/v1/bmc", "").Credentials("myns", "myhost-bmc")
+	assert.EqualError(t, err, "failed to fetch credentials: 403 Forbidden")
+}
+
+func TestCredentialsProvidersSet(t *testing.T) {
+	testCases := []struct {
+		Scenario      string
+		Value         string
+		ExpectedError string
+	}{
+		{
+			Scenario: "file",
+			Value:    "vault=file:///var/run/bmc-credentials",
+		},
+		{
+			Scenario: "http",
+			Value:    "vault=https://127.0.0.1:8200/v1/bmc,tokenFile=/var/run/secrets/token",
+		},
+		{
+			Scenario:      "no name",
+			Value:         "file:///var/run/bmc-credentials",
+			ExpectedError: "invalid credentials provider \"file:///var/run/bmc-credentials\", expected name=URL",
+		},
+		{
+			Scenario:      "unknown scheme",
+			Value:         "vault=ftp://127.0.0.1/bmc",
+			ExpectedError: "unsupported URL scheme \"ftp\" for credentials provider \"vault\"",
+		},
+		{
+			Scenario:      "unknown option",
+			Value:         "vault=https://127.0.0.1:8200/v1/bmc,timeout=5s",
+			ExpectedError: "unknown option \"timeout\" for credentials provider \"vault\"",
+		},
+		{
+			Scenario:      "token for files",
+			Value:         "vault=file:///var/run/bmc-credentials,tokenFile=/token",
+			ExpectedError: "tokenFile is not supported by file credentials provider \"vault\"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Scenario, func(t *testing.T) {
+			providers := CredentialsProviders{}
+			err := providers.Set(tc.Value)
+			if tc.ExpectedError != "" {
+				assert.EqualError(t, err, tc.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "vault", providers.String())
+		})
+	}
+
+	providers := CredentialsProviders{}
+	assert.NoError(t, providers.Set("vault=file:///a"))
+	assert.EqualError(t, providers.Set("vault=file:///b"), "duplicate credentials provider \"vault\"")
+}